@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/eval"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+)
+
+const evalUsage = `usage:
+  infraplane eval run [-corpus dir] [-replay fixtures-dir] [-out report.json]
+  infraplane eval compare [-fail-on-regression] base.json head.json`
+
+// runEval scores the LLM over the evaluation corpus, or compares two runs.
+// The LLM backend is configured as for the server; -replay answers from
+// recorded fixtures instead, so runs are offline and deterministic.
+func runEval(args []string) error {
+	if len(args) == 0 {
+		return errors.New(evalUsage)
+	}
+	switch args[0] {
+	case "run":
+		fs := flag.NewFlagSet("eval run", flag.ContinueOnError)
+		corpus := fs.String("corpus", "eval/corpus", "directory of corpus cases")
+		replay := fs.String("replay", "", "answer from the LLM fixtures in this directory")
+		out := fs.String("out", "", "write the JSON report to this file")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		cases, err := eval.LoadCorpus(*corpus)
+		if err != nil {
+			return err
+		}
+		cfg := llmConfig()
+		if *replay != "" {
+			cfg = llm.Config{Backend: llm.BackendReplay, FixturesDir: *replay}
+		}
+		client, err := newLLMClient(cfg)
+		if err != nil {
+			return fmt.Errorf("build LLM client: %w", err)
+		}
+
+		report := eval.NewRunner(client, compliance.NewRegistry()).Run(context.Background(), cases)
+		if *out != "" {
+			if err := eval.WriteReport(*out, report); err != nil {
+				return err
+			}
+		}
+		return report.Format(os.Stdout)
+
+	case "compare":
+		fs := flag.NewFlagSet("eval compare", flag.ContinueOnError)
+		failOnRegression := fs.Bool("fail-on-regression", false, "exit non-zero if a summary metric got worse")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 2 {
+			return errors.New(evalUsage)
+		}
+		base, err := eval.LoadReport(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		head, err := eval.LoadReport(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		cmp := eval.Compare(base, head)
+		if err := cmp.Format(os.Stdout); err != nil {
+			return err
+		}
+		if *failOnRegression && cmp.Regressed() {
+			return errors.New("scores regressed")
+		}
+		return nil
+
+	default:
+		return errors.New(evalUsage)
+	}
+}
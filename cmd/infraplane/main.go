@@ -6,20 +6,27 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/matthewdriscoll/infraplane/internal/api"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
 	gcpcloud "github.com/matthewdriscoll/infraplane/internal/cloud/gcp"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
 	"github.com/matthewdriscoll/infraplane/internal/llm"
 	mcpserver "github.com/matthewdriscoll/infraplane/internal/mcp"
+	"github.com/matthewdriscoll/infraplane/internal/notify"
 	"github.com/matthewdriscoll/infraplane/internal/provider"
 	awsadapter "github.com/matthewdriscoll/infraplane/internal/provider/aws"
 	gcpadapter "github.com/matthewdriscoll/infraplane/internal/provider/gcp"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
 	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
 	"github.com/matthewdriscoll/infraplane/internal/repository/postgres"
 	"github.com/matthewdriscoll/infraplane/internal/service"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
 )
 
 func main() {
@@ -32,21 +39,25 @@ func main() {
 		fmt.Println("Migration support coming soon")
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		if err := runEval(os.Args[2:]); err != nil {
+			log.Fatalf("eval: %v", err)
+		}
+		return
+	}
 
 	databaseURL := os.Getenv("DATABASE_URL")
-	anthropicKey := os.Getenv("ANTHROPIC_API_KEY")
 	mode := os.Getenv("MCP_MODE") // "mcp" (default) or "http"
 	port := os.Getenv("PORT")
 
-	if anthropicKey == "" {
-		log.Println("WARNING: ANTHROPIC_API_KEY is not set — LLM features will fail")
-	}
 	if port == "" {
 		port = "8080"
 	}
 
-	// Build LLM client
-	llmClient := llm.NewAnthropicClient(anthropicKey)
+	llmClient, err := newLLMClient(llmConfig())
+	if err != nil {
+		log.Fatalf("build LLM client: %v", err)
+	}
 
 	// Build GCP Cloud Asset Inventory client (optional — works without it)
 	var assetClient *gcpcloud.AssetClient
@@ -75,6 +86,28 @@ func main() {
 	var infraSvc *service.InfraService
 	var graphSvc *service.GraphService
 	var discSvc *service.DiscoveryService
+	var llmCache *llm.CachedClient
+	var llmUsageSvc *service.LLMUsageService
+	var schedRepo repository.ScheduleRepo
+	var freezeRepo repository.FreezeWindowRepo
+	var channelRepo repository.NotificationChannelRepo
+	var deliveryRepo repository.NotificationDeliveryRepo
+	var webhookSubRepo repository.WebhookSubscriptionRepo
+	var webhookDeliveryRepo repository.WebhookDeliveryRepo
+	var deploymentRepo repository.DeploymentRepo
+	var apiKeyRepo repository.APIKeyRepo
+	var roleBindingRepo repository.RoleBindingRepo
+	var orgRepo repository.OrganizationRepo
+	var auditRepo repository.AuditLogRepo
+	var transactor repository.Transactor
+	var leaderLock repository.LeaderLock
+	var webhookLeader repository.LeaderLock
+
+	// Domain events raised by service writes go through an outbox and are
+	// published on this bus
+	eventBus := service.NewEventBus()
+	var outbox *service.Outbox
+	var dispatcher *service.EventDispatcher
 
 	if databaseURL != "" {
 		// PostgreSQL mode
@@ -89,14 +122,39 @@ func main() {
 		depRepo := postgres.NewDeploymentRepo(pool)
 		planRepo := postgres.NewPlanRepo(pool)
 		graphRepo := postgres.NewGraphRepo(pool)
+		schedRepo = postgres.NewScheduleRepo(pool)
+		freezeRepo = postgres.NewFreezeWindowRepo(pool)
+		channelRepo = postgres.NewNotificationChannelRepo(pool)
+		deliveryRepo = postgres.NewNotificationDeliveryRepo(pool)
+		webhookSubRepo = postgres.NewWebhookSubscriptionRepo(pool)
+		webhookDeliveryRepo = postgres.NewWebhookDeliveryRepo(pool)
+		deploymentRepo = depRepo
+		apiKeyRepo = postgres.NewAPIKeyRepo(pool)
+		roleBindingRepo = postgres.NewRoleBindingRepo(pool)
+		orgRepo = postgres.NewOrganizationRepo(pool)
+		auditRepo = postgres.NewAuditLogRepo(pool)
+		transactor = postgres.NewTransactor(pool)
+		leaderLock = postgres.NewAdvisoryLock(pool, postgres.SchedulerLockKey)
+		webhookLeader = postgres.NewAdvisoryLock(pool, postgres.WebhookRetryLockKey)
+
+		// Events commit with the writes that raise them; one replica
+		// dispatches them from the outbox table
+		outboxRepo := postgres.NewEventOutboxRepo(pool)
+		outbox = service.NewOutbox(transactor, outboxRepo)
+		dispatcher = service.NewEventDispatcher(outboxRepo, eventBus, postgres.NewAdvisoryLock(pool, postgres.OutboxLockKey))
+
+		// Every LLM call is metered against its application's and
+		// organization's monthly token budgets; cache hits cost nothing
+		llmUsageSvc = service.NewLLMUsageService(postgres.NewLLMUsageRepo(pool), postgres.NewLLMBudgetRepo(pool), appRepo)
+		llmClient, llmCache = cacheLLM(llm.NewMeteredClient(llmClient, llmUsageSvc), postgres.NewLLMCacheRepo(pool))
 
 		appSvc = service.NewApplicationService(appRepo, resRepo, llmClient, complianceRegistry)
 		resSvc = service.NewResourceService(resRepo, appRepo, llmClient, complianceRegistry)
 		planSvc = service.NewPlannerService(planRepo, appRepo, resRepo, llmClient, complianceRegistry)
-		depSvc = service.NewDeploymentService(depRepo, appRepo)
+		depSvc = service.NewDeploymentService(depRepo, appRepo, planRepo, resRepo, freezeRepo)
 		infraSvc = service.NewInfraService(appRepo, resRepo, depRepo, providerRegistry)
 		graphSvc = service.NewGraphService(graphRepo, appRepo, resRepo, llmClient)
-		discSvc = service.NewDiscoveryService(appRepo, llmClient, assetClient)
+		discSvc = service.NewDiscoveryService(appRepo, resRepo, llmClient, assetClient)
 
 		log.Println("Using PostgreSQL storage")
 	} else {
@@ -106,31 +164,251 @@ func main() {
 		depRepo := mock.NewDeploymentRepo()
 		planRepo := mock.NewPlanRepo()
 		graphRepo := mock.NewGraphRepo()
+		schedRepo = mock.NewScheduleRepo()
+		freezeRepo = mock.NewFreezeWindowRepo()
+		channelRepo = mock.NewNotificationChannelRepo()
+		deliveryRepo = mock.NewNotificationDeliveryRepo()
+		webhookSubRepo = mock.NewWebhookSubscriptionRepo()
+		webhookDeliveryRepo = mock.NewWebhookDeliveryRepo()
+		deploymentRepo = depRepo
+		apiKeyRepo = mock.NewAPIKeyRepo()
+		roleBindingRepo = mock.NewRoleBindingRepo()
+		orgRepo = mock.NewOrganizationRepo()
+		auditRepo = mock.NewAuditLogRepo()
+		transactor = mock.NewTransactor()
+		leaderLock = mock.NewLeaderLock()
+		webhookLeader = mock.NewLeaderLock()
+		outbox = service.NewSyncOutbox(eventBus)
+
+		llmUsageSvc = service.NewLLMUsageService(mock.NewLLMUsageRepo(), mock.NewLLMBudgetRepo(), appRepo)
+		llmClient, llmCache = cacheLLM(llm.NewMeteredClient(llmClient, llmUsageSvc), mock.NewLLMCacheRepo())
 
 		appSvc = service.NewApplicationService(appRepo, resRepo, llmClient, complianceRegistry)
 		resSvc = service.NewResourceService(resRepo, appRepo, llmClient, complianceRegistry)
 		planSvc = service.NewPlannerService(planRepo, appRepo, resRepo, llmClient, complianceRegistry)
-		depSvc = service.NewDeploymentService(depRepo, appRepo)
+		depSvc = service.NewDeploymentService(depRepo, appRepo, planRepo, resRepo, freezeRepo)
 		infraSvc = service.NewInfraService(appRepo, resRepo, depRepo, providerRegistry)
 		graphSvc = service.NewGraphService(graphRepo, appRepo, resRepo, llmClient)
-		discSvc = service.NewDiscoveryService(appRepo, llmClient, assetClient)
+		discSvc = service.NewDiscoveryService(appRepo, resRepo, llmClient, assetClient)
 
 		log.Println("Using in-memory storage (set DATABASE_URL for PostgreSQL)")
 	}
 
+	// Deployment outcomes, drift and compliance violations go to each
+	// application's notification channels; email needs SMTP_ADDR
+	notifySvc := service.NewNotificationService(channelRepo, deliveryRepo, infraSvc.Apps(), deploymentRepo, complianceRegistry, notify.SMTPConfig{
+		Addr:     os.Getenv("SMTP_ADDR"),
+		From:     os.Getenv("SMTP_FROM"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	})
+
+	// Domain events (registrations, resource and plan changes, deployment
+	// status, drift) drive notifications and are delivered to webhook
+	// subscriptions
+	appSvc.SetOutbox(outbox)
+	resSvc.SetOutbox(outbox)
+	planSvc.SetOutbox(outbox)
+	depSvc.SetOutbox(outbox)
+	infraSvc.SetOutbox(outbox)
+	discSvc.SetOutbox(outbox)
+	webhookSvc := service.NewWebhookService(webhookSubRepo, webhookDeliveryRepo, infraSvc.Apps())
+	eventBus.Subscribe(notifySvc.HandleEvent)
+	eventBus.Subscribe(webhookSvc.HandleEvent)
+	if dispatcher != nil {
+		go dispatcher.Run(context.Background(), service.DefaultDispatchInterval)
+	}
+
+	// Callers need a role binding (viewer, editor, deployer or admin, per
+	// application or global) for what they do, whether through the REST API
+	// or MCP tools
+	rbacSvc := service.NewRBACService(roleBindingRepo, infraSvc.Apps())
+	appSvc.SetRBAC(rbacSvc)
+	resSvc.SetRBAC(rbacSvc)
+	planSvc.SetRBAC(rbacSvc)
+	depSvc.SetRBAC(rbacSvc)
+	infraSvc.SetRBAC(rbacSvc)
+	graphSvc.SetRBAC(rbacSvc)
+	discSvc.SetRBAC(rbacSvc)
+	notifySvc.SetRBAC(rbacSvc)
+	webhookSvc.SetRBAC(rbacSvc)
+
+	// Applications and everything under them are scoped to an organization;
+	// the REST API resolves it from /api/orgs/{org} routes
+	orgSvc := service.NewOrganizationService(orgRepo)
+	orgSvc.SetRBAC(rbacSvc)
+
+	// Every change made through the REST API, MCP tools or the scheduler is
+	// recorded in the append-only audit log, in the same transaction
+	auditSvc := service.NewAuditService(auditRepo, transactor, infraSvc.Apps())
+	auditSvc.SetRBAC(rbacSvc)
+	appSvc.SetAudit(auditSvc)
+	resSvc.SetAudit(auditSvc)
+	planSvc.SetAudit(auditSvc)
+	depSvc.SetAudit(auditSvc)
+	infraSvc.SetAudit(auditSvc)
+	graphSvc.SetAudit(auditSvc)
+	notifySvc.SetAudit(auditSvc)
+	webhookSvc.SetAudit(auditSvc)
+	rbacSvc.SetAudit(auditSvc)
+	orgSvc.SetAudit(auditSvc)
+
+	// LLM responses are cached by prompt; admins can read the hit rate
+	llmCacheSvc := service.NewLLMCacheService(llmCache)
+	llmCacheSvc.SetRBAC(rbacSvc)
+	llmUsageSvc.SetRBAC(rbacSvc)
+	llmUsageSvc.SetAudit(auditSvc)
+
+	// Callers authenticate with an API key or, when AUTH_JWKS is set, a
+	// bearer JWT from the identity provider. INFRAPLANE_ADMIN_KEY is an
+	// admin credential for creating the first API keys and role bindings
+	authSvc := service.NewAuthService(apiKeyRepo)
+	authSvc.SetBootstrapKey(os.Getenv("INFRAPLANE_ADMIN_KEY"))
+	authSvc.SetRBAC(rbacSvc)
+	authSvc.SetAudit(auditSvc)
+	if jwks := os.Getenv("AUTH_JWKS"); jwks != "" {
+		verifier, err := auth.NewVerifier(context.Background(), auth.VerifierConfig{
+			JWKS:     jwks,
+			Issuer:   os.Getenv("AUTH_JWT_ISSUER"),
+			Audience: os.Getenv("AUTH_JWT_AUDIENCE"),
+		})
+		if err != nil {
+			log.Fatalf("load JWT verifier: %v", err)
+		}
+		authSvc.SetJWTVerifier(verifier)
+	}
+
 	if mode == "http" {
 		// HTTP REST API mode for the dashboard
-		router := api.NewRouter(appSvc, resSvc, planSvc, depSvc, infraSvc, graphSvc, discSvc, complianceRegistry)
+		// Git push webhooks are rejected until their secrets are set
+		gitPushSvc := service.NewGitPushService(appSvc, depSvc, infraSvc, webhook.Secrets{
+			GitHub: os.Getenv("GITHUB_WEBHOOK_SECRET"),
+			GitLab: os.Getenv("GITLAB_WEBHOOK_TOKEN"),
+		})
+
+		// Scheduled jobs run on whichever replica holds the leader lock
+		schedulerSvc := service.NewSchedulerService(schedRepo, infraSvc.Apps(), leaderLock, service.SchedulerJobs{
+			Discovery:   discSvc,
+			Planner:     planSvc,
+			Graphs:      graphSvc,
+			Deployments: depSvc,
+			Infra:       infraSvc,
+		})
+		schedulerSvc.SetRBAC(rbacSvc)
+		schedulerSvc.SetAudit(auditSvc)
+		go schedulerSvc.Run(context.Background(), service.DefaultSchedulerInterval)
+
+		// Deployments left in progress by a server that stopped mid-run are
+		// failed, degrading their applications, so they can be deployed again
+		go depSvc.RunRecovery(context.Background(), infraSvc, service.DefaultRecoveryInterval)
+
+		// Failed webhook deliveries are retried until they are dead-lettered,
+		// by whichever replica holds the retry lock
+		webhookSvc.SetLeader(webhookLeader)
+		go webhookSvc.Run(context.Background(), service.DefaultWebhookRetryInterval)
+
+		freezeSvc := service.NewFreezeService(freezeRepo, infraSvc.Apps())
+		freezeSvc.SetRBAC(rbacSvc)
+		freezeSvc.SetAudit(auditSvc)
+
+		// Every API request must authenticate
+		if os.Getenv("AUTH_DISABLED") == "true" {
+			log.Println("WARNING: AUTH_DISABLED is set — unauthenticated requests have full access")
+			authSvc.SetAllowAnonymous(true)
+		}
+
+		router := api.NewRouter(appSvc, resSvc, planSvc, depSvc, infraSvc, graphSvc, discSvc, gitPushSvc, schedulerSvc, freezeSvc, notifySvc, webhookSvc, authSvc, rbacSvc, orgSvc, auditSvc, llmCacheSvc, llmUsageSvc, complianceRegistry)
 		log.Printf("Infraplane REST API starting on :%s...", port)
 		if err := http.ListenAndServe(":"+port, router); err != nil {
 			log.Fatalf("HTTP server error: %v", err)
 		}
 	} else {
-		// Default: MCP server on stdio for Claude Code
-		mcpSrv := mcpserver.NewServer(appSvc, resSvc, planSvc, depSvc, graphSvc, discSvc, complianceRegistry)
+		// Default: MCP server on stdio for Claude Code. Tool calls act as
+		// INFRAPLANE_API_KEY (an API key or bearer JWT), checked on every
+		// call; without it the local user has full access
+		credential := os.Getenv("INFRAPLANE_API_KEY")
+		authenticate := func(ctx context.Context) (context.Context, error) {
+			if credential == "" {
+				return auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalAnonymous, Name: "local", Superuser: true}), nil
+			}
+			p, err := authSvc.Authenticate(ctx, credential)
+			if err != nil {
+				return nil, err
+			}
+			return auth.WithPrincipal(ctx, p), nil
+		}
+		mcpSrv := mcpserver.NewServer(appSvc, resSvc, planSvc, depSvc, graphSvc, discSvc, orgSvc, complianceRegistry, authenticate)
 		log.Println("Infraplane MCP server starting on stdio...")
 		if err := server.ServeStdio(mcpSrv); err != nil {
 			log.Fatalf("MCP server error: %v", err)
 		}
 	}
 }
+
+// llmConfig reads the LLM backend settings: Anthropic by default;
+// LLM_BACKEND=openai or ollama runs against an OpenAI-compatible server, e.g.
+// a self-hosted model. LLM_FIXTURES_DIR records every response, or with
+// LLM_BACKEND=replay serves recorded ones.
+func llmConfig() llm.Config {
+	cfg := llm.Config{
+		Backend:     llm.Backend(strings.ToLower(os.Getenv("LLM_BACKEND"))),
+		Model:       os.Getenv("LLM_MODEL"),
+		BaseURL:     os.Getenv("LLM_BASE_URL"),
+		APIKey:      os.Getenv("OPENAI_API_KEY"),
+		FixturesDir: os.Getenv("LLM_FIXTURES_DIR"),
+	}
+	if cfg.Backend == "" || cfg.Backend == llm.BackendAnthropic {
+		cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+		if cfg.APIKey == "" {
+			log.Println("WARNING: ANTHROPIC_API_KEY is not set — LLM features will fail")
+		}
+	}
+	return cfg
+}
+
+// newLLMClient builds the client for cfg. System prompts are embedded;
+// LLM_PROMPTS_DIR overrides them by name.
+func newLLMClient(cfg llm.Config) (llm.Client, error) {
+	client, err := llm.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if dir := os.Getenv("LLM_PROMPTS_DIR"); dir != "" {
+		if err := llm.LoadPrompts(dir); err != nil {
+			return nil, fmt.Errorf("load prompts: %w", err)
+		}
+		for _, p := range llm.ActivePrompts() {
+			if p.Source != "embedded" {
+				log.Printf("LLM prompt %s overridden from %s", p.ID(), p.Source)
+			}
+		}
+	}
+	return client, nil
+}
+
+// cacheLLM wraps client in a response cache. Entries live in store (the
+// database, or memory) unless LLM_CACHE_DIR names a directory, and expire
+// after LLM_CACHE_TTL (default 24h); LLM_CACHE_TTL=0 turns caching off.
+func cacheLLM(client llm.Client, store llm.Cache) (llm.Client, *llm.CachedClient) {
+	ttl := llm.DefaultCacheTTL
+	if v := os.Getenv("LLM_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("parse LLM_CACHE_TTL: %v", err)
+		}
+		if d <= 0 {
+			log.Println("LLM response cache disabled (LLM_CACHE_TTL=0)")
+			return client, nil
+		}
+		ttl = d
+	}
+	if dir := os.Getenv("LLM_CACHE_DIR"); dir != "" {
+		dc, err := llm.NewDirCache(dir)
+		if err != nil {
+			log.Fatalf("open LLM cache directory: %v", err)
+		}
+		store = dc
+	}
+	cached := llm.NewCachedClient(client, store, ttl)
+	return cached, cached
+}
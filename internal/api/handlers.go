@@ -4,14 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
 	"github.com/matthewdriscoll/infraplane/internal/service"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
 )
 
 // Handlers holds references to all services and provides HTTP handlers.
@@ -23,6 +31,17 @@ type Handlers struct {
 	infra       *service.InfraService
 	graphs      *service.GraphService
 	discovery   *service.DiscoveryService
+	gitPush     *service.GitPushService
+	scheduler   *service.SchedulerService
+	freezes     *service.FreezeService
+	notifier    *service.NotificationService
+	webhooks    *service.WebhookService
+	auth        *service.AuthService
+	rbac        *service.RBACService
+	orgs        *service.OrganizationService
+	audit       *service.AuditService
+	llmCache    *service.LLMCacheService
+	llmUsage    *service.LLMUsageService
 	compliance  *compliance.Registry
 }
 
@@ -35,6 +54,17 @@ func NewHandlers(
 	infra *service.InfraService,
 	graphs *service.GraphService,
 	discovery *service.DiscoveryService,
+	gitPush *service.GitPushService,
+	scheduler *service.SchedulerService,
+	freezes *service.FreezeService,
+	notifier *service.NotificationService,
+	webhooks *service.WebhookService,
+	authSvc *service.AuthService,
+	rbacSvc *service.RBACService,
+	orgSvc *service.OrganizationService,
+	auditSvc *service.AuditService,
+	llmCacheSvc *service.LLMCacheService,
+	llmUsageSvc *service.LLMUsageService,
 	complianceRegistry *compliance.Registry,
 ) *Handlers {
 	return &Handlers{
@@ -45,6 +75,17 @@ func NewHandlers(
 		infra:       infra,
 		graphs:      graphs,
 		discovery:   discovery,
+		gitPush:     gitPush,
+		scheduler:   scheduler,
+		freezes:     freezes,
+		notifier:    notifier,
+		webhooks:    webhooks,
+		auth:        authSvc,
+		rbac:        rbacSvc,
+		orgs:        orgSvc,
+		audit:       auditSvc,
+		llmCache:    llmCacheSvc,
+		llmUsage:    llmUsageSvc,
 		compliance:  complianceRegistry,
 	}
 }
@@ -74,10 +115,83 @@ type migrationPlanRequest struct {
 	ToProvider   string `json:"to_provider"`
 }
 
+type refineRequest struct {
+	Instruction string `json:"instruction"`
+}
+
 type deployRequest struct {
+	GitBranch  string `json:"git_branch"`
+	GitCommit  string `json:"git_commit"`
+	PlanID     string `json:"plan_id"`
+	Force      bool   `json:"force"`       // deploy a plan even if resources changed since it was generated
+	BreakGlass bool   `json:"break_glass"` // override active freeze windows (recorded and audit-logged)
+}
+
+type destroyRequest struct {
+	Confirm    string `json:"confirm"`
+	BreakGlass bool   `json:"break_glass"`
+}
+
+type protectionRequest struct {
+	PreventDestroy bool `json:"prevent_destroy"`
+}
+
+type deployBranchRequest struct {
+	DeployBranch string `json:"deploy_branch"`
+}
+
+type createScheduleRequest struct {
+	Job       string `json:"job"`
+	Cron      string `json:"cron"`
+	Timezone  string `json:"timezone"`
 	GitBranch string `json:"git_branch"`
-	GitCommit string `json:"git_commit"`
-	PlanID    string `json:"plan_id"`
+}
+
+type scheduleEnabledRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type createFreezeWindowRequest struct {
+	Name        string `json:"name"`
+	Reason      string `json:"reason"`
+	Application string `json:"application"` // application name; empty for a global freeze
+	Environment string `json:"environment"` // git branch; empty for all branches
+	domain.FreezeRule
+}
+
+type createNotificationChannelRequest struct {
+	Kind   string   `json:"kind"`   // webhook, slack or email
+	Target string   `json:"target"` // URL, or comma-separated email addresses
+	Events []string `json:"events"` // empty subscribes to every event
+}
+
+type createWebhookSubscriptionRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`      // generated when empty
+	Events      []string `json:"events"`      // empty subscribes to every event
+	Application string   `json:"application"` // application name; empty for every application
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // omit for a key that never expires
+}
+
+type createAPIKeyResponse struct {
+	APIKey domain.APIKey `json:"api_key"`
+	Key    string        `json:"key"` // shown only once
+}
+
+type createRoleBindingRequest struct {
+	Subject     string `json:"subject"`     // API key ID or JWT subject
+	Role        string `json:"role"`        // viewer, editor, deployer or admin
+	Application string `json:"application"` // application name; empty for every application
+}
+
+type createOrganizationRequest struct {
+	Slug string `json:"slug"` // lowercase letters, digits and hyphens; used in /api/orgs/{org} routes
+	Name string `json:"name"` // display name; defaults to the slug
 }
 
 type onboardRequest struct {
@@ -89,6 +203,11 @@ type onboardRequest struct {
 	Files                []analyzer.FileContent `json:"files,omitempty"`
 }
 
+type setLLMBudgetRequest struct {
+	Application   string `json:"application"` // application name; empty for the whole organization
+	MonthlyTokens int64  `json:"monthly_tokens"`
+}
+
 type errorResponse struct {
 	Error string `json:"error"`
 }
@@ -219,6 +338,56 @@ func (h *Handlers) DeleteApplication(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handlers) SetDestroyProtection(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req protectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	app, err = h.apps.SetPreventDestroy(r.Context(), app.ID, req.PreventDestroy)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, app)
+}
+
+// SetDeployBranch configures which branch's pushes (received on the git
+// webhook endpoints) create deployments for an application.
+func (h *Handlers) SetDeployBranch(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req deployBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	app, err = h.apps.SetDeployBranch(r.Context(), app.ID, req.DeployBranch)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, app)
+}
+
 // --- Reanalyze Handler ---
 
 func (h *Handlers) ReanalyzeSource(w http.ResponseWriter, r *http.Request) {
@@ -336,6 +505,81 @@ func (h *Handlers) RemoveResource(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RefineResource revises a resource to follow the instruction in the body
+// and returns the revision it recorded.
+func (h *Handlers) RefineResource(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid resource ID")
+		return
+	}
+
+	var req refineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	rev, err := h.resources.Refine(r.Context(), id, req.Instruction)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rev)
+}
+
+func (h *Handlers) ListResourceRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid resource ID")
+		return
+	}
+
+	revisions, err := h.resources.ListRevisions(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if revisions == nil {
+		revisions = []domain.ResourceRevision{}
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+// DiffResourceRevision compares a revision with the version in the against
+// query parameter, or with the revision it was refined from.
+func (h *Handlers) DiffResourceRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid resource ID")
+		return
+	}
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+	var against *int
+	if v := r.URL.Query().Get("against"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid against version")
+			return
+		}
+		against = &n
+	}
+
+	diff, err := h.resources.DiffRevision(r.Context(), id, version, against)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
+
 // --- Plan Handlers ---
 
 func (h *Handlers) GenerateHostingPlan(w http.ResponseWriter, r *http.Request) {
@@ -380,6 +624,161 @@ func (h *Handlers) GenerateMigrationPlan(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusCreated, plan)
 }
 
+// StreamHostingPlan generates a hosting plan and streams its markdown via SSE
+// as the model writes it, ending with the saved plan.
+func (h *Handlers) StreamHostingPlan(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	stream, ok := newPlanStream(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	plan, err := h.planner.StreamHostingPlan(r.Context(), app.ID, stream.text)
+	stream.finish(plan, err)
+}
+
+// StreamMigrationPlan is StreamHostingPlan for a migration plan between the
+// from_provider and to_provider query parameters.
+func (h *Handlers) StreamMigrationPlan(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	from := domain.CloudProvider(r.URL.Query().Get("from_provider"))
+	to := domain.CloudProvider(r.URL.Query().Get("to_provider"))
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	stream, ok := newPlanStream(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	plan, err := h.planner.StreamMigrationPlan(r.Context(), app.ID, from, to, stream.text)
+	stream.finish(plan, err)
+}
+
+// planStreamEvent is an SSE event of a plan generation: the markdown written
+// since the previous event ("delta"), all of it again after the model started
+// over ("reset"), the saved plan ("plan"), or why generation failed ("error").
+type planStreamEvent struct {
+	Type  string                     `json:"type"`
+	Text  string                     `json:"text,omitempty"`
+	Plan  *domain.InfrastructurePlan `json:"plan,omitempty"`
+	Error string                     `json:"error,omitempty"`
+}
+
+// planStream writes a plan generation as SSE. The headers go out with the
+// first event, so a request that fails before the model writes anything
+// still gets a plain error response with the right status.
+type planStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+	sent    string
+}
+
+func newPlanStream(w http.ResponseWriter) (*planStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	return &planStream{w: w, flusher: flusher}, true
+}
+
+// text is the llm.StreamFunc of the generation.
+func (s *planStream) text(text string) {
+	if rest, ok := strings.CutPrefix(text, s.sent); ok {
+		s.send(planStreamEvent{Type: "delta", Text: rest})
+	} else {
+		s.send(planStreamEvent{Type: "reset", Text: text})
+	}
+	s.sent = text
+}
+
+func (s *planStream) finish(plan domain.InfrastructurePlan, err error) {
+	switch {
+	case err != nil && !s.started:
+		handleServiceError(s.w, err)
+	case err != nil:
+		s.send(planStreamEvent{Type: "error", Error: err.Error()})
+	default:
+		s.send(planStreamEvent{Type: "plan", Plan: &plan})
+	}
+}
+
+func (s *planStream) send(event planStreamEvent) {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("Connection", "keep-alive")
+		s.w.Header().Set("X-Accel-Buffering", "no")
+	}
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(s.w, "data: %s\n\n", data)
+	s.flusher.Flush()
+}
+
+// RefinePlan rewrites a plan to follow the instruction in the body and
+// returns the new plan, linked to the one it was refined from.
+func (h *Handlers) RefinePlan(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid plan ID")
+		return
+	}
+
+	var req refineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	plan, err := h.planner.RefinePlan(r.Context(), id, req.Instruction)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, plan)
+}
+
+// DiffPlan compares a plan with the plan in the against query parameter, or
+// with the plan it was refined from.
+func (h *Handlers) DiffPlan(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid plan ID")
+		return
+	}
+	var against *uuid.UUID
+	if v := r.URL.Query().Get("against"); v != "" {
+		other, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid against plan ID")
+			return
+		}
+		against = &other
+	}
+
+	diff, err := h.planner.DiffPlan(r.Context(), id, against)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
+
 func (h *Handlers) ListPlans(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 
@@ -428,7 +827,37 @@ func (h *Handlers) Deploy(w http.ResponseWriter, r *http.Request) {
 		planID = &id
 	}
 
-	d, err := h.deployments.Deploy(r.Context(), app.ID, req.GitCommit, req.GitBranch, planID)
+	d, err := h.deployments.Deploy(r.Context(), app.ID, req.GitCommit, req.GitBranch, planID, service.DeployOptions{
+		Force:      req.Force,
+		BreakGlass: req.BreakGlass,
+	})
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, d)
+}
+
+// Destroy creates a pending destroy deployment for an application. The
+// request must echo the application name in "confirm". Execute it via the
+// deployment stream endpoint like any other deployment.
+func (h *Handlers) Destroy(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req destroyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	d, err := h.deployments.Destroy(r.Context(), app.ID, req.Confirm, service.DeployOptions{BreakGlass: req.BreakGlass})
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -493,7 +922,31 @@ func (h *Handlers) GetDeploymentStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, d)
 }
 
+// RollbackDeployment creates a new pending deployment that re-applies the
+// configuration of a previous succeeded deployment. Execute it via the
+// deployment stream endpoint like any other deployment. ?break_glass=true
+// overrides freeze windows that would otherwise refuse it.
+func (h *Handlers) RollbackDeployment(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid deployment ID")
+		return
+	}
+
+	d, err := h.deployments.Rollback(r.Context(), id, service.DeployOptions{
+		BreakGlass: r.URL.Query().Get("break_glass") == "true",
+	})
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, d)
+}
+
 // DeployStream executes a pending deployment and streams real-time log events via SSE.
+// ?break_glass=true overrides freeze windows that would otherwise keep it pending.
 func (h *Handlers) DeployStream(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -529,7 +982,8 @@ func (h *Handlers) DeployStream(w http.ResponseWriter, r *http.Request) {
 	events := make(chan domain.DeploymentEvent, 32)
 
 	// Execute deployment in background goroutine
-	go h.deployments.Execute(r.Context(), id, h.infra, events)
+	breakGlass := r.URL.Query().Get("break_glass") == "true"
+	go h.deployments.Execute(r.Context(), id, h.infra, breakGlass, events)
 
 	// Stream events to client
 	for event := range events {
@@ -626,22 +1080,903 @@ func (h *Handlers) GetLiveResources(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, result)
 }
 
-// --- Compliance Handlers ---
+// --- Schedule Handlers ---
 
-func (h *Handlers) ListComplianceFrameworks(w http.ResponseWriter, r *http.Request) {
-	providerFilter := r.URL.Query().Get("provider")
+func (h *Handlers) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
 
-	var frameworks []compliance.FrameworkInfo
-	if providerFilter != "" {
-		frameworks = h.compliance.ListFrameworksForProvider(domain.CloudProvider(providerFilter))
-	} else {
-		frameworks = h.compliance.ListFrameworks()
+	var req createScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
 	}
-	if frameworks == nil {
-		frameworks = []compliance.FrameworkInfo{}
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
 	}
 
-	writeJSON(w, http.StatusOK, frameworks)
+	sched, err := h.scheduler.Create(r.Context(), app.ID, domain.JobKind(req.Job), req.Cron, req.Timezone, req.GitBranch)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sched)
+}
+
+func (h *Handlers) ListApplicationSchedules(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	schedules, err := h.scheduler.ListByApplication(r.Context(), app.ID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if schedules == nil {
+		schedules = []domain.Schedule{}
+	}
+
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (h *Handlers) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.scheduler.List(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if schedules == nil {
+		schedules = []domain.Schedule{}
+	}
+
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (h *Handlers) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid schedule ID")
+		return
+	}
+
+	sched, err := h.scheduler.Get(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sched)
+}
+
+// SetScheduleEnabled pauses or resumes a schedule.
+func (h *Handlers) SetScheduleEnabled(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid schedule ID")
+		return
+	}
+
+	var req scheduleEnabledRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sched, err := h.scheduler.SetEnabled(r.Context(), id, req.Enabled)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sched)
+}
+
+func (h *Handlers) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid schedule ID")
+		return
+	}
+
+	if err := h.scheduler.Delete(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunSchedule runs a schedule's job immediately and returns the schedule
+// with the run's outcome recorded. A failed job still returns 200; check
+// last_status and last_result.
+func (h *Handlers) RunSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid schedule ID")
+		return
+	}
+
+	sched, err := h.scheduler.RunNow(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sched)
+}
+
+// --- Freeze Window Handlers ---
+
+func (h *Handlers) CreateFreezeWindow(w http.ResponseWriter, r *http.Request) {
+	var req createFreezeWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var appID *uuid.UUID
+	if req.Application != "" {
+		app, err := h.apps.GetByName(r.Context(), req.Application)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		appID = &app.ID
+	}
+
+	fw, err := h.freezes.Create(r.Context(), req.Name, req.Reason, appID, req.Environment, req.FreezeRule)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, fw)
+}
+
+func (h *Handlers) ListFreezeWindows(w http.ResponseWriter, r *http.Request) {
+	windows, err := h.freezes.List(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if windows == nil {
+		windows = []domain.FreezeWindow{}
+	}
+
+	writeJSON(w, http.StatusOK, windows)
+}
+
+func (h *Handlers) GetFreezeWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid freeze window ID")
+		return
+	}
+
+	fw, err := h.freezes.Get(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fw)
+}
+
+func (h *Handlers) DeleteFreezeWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid freeze window ID")
+		return
+	}
+
+	if err := h.freezes.Delete(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// FreezeCalendar lists freeze periods between ?from and ?to (RFC 3339,
+// defaulting to the next 30 days), optionally limited to the windows that
+// affect ?application.
+func (h *Handlers) FreezeCalendar(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	from := time.Now().UTC()
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from: "+v)
+			return
+		}
+		from = t
+	}
+	to := from.AddDate(0, 0, 30)
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid to: "+v)
+			return
+		}
+		to = t
+	}
+
+	var appID *uuid.UUID
+	if name := q.Get("application"); name != "" {
+		app, err := h.apps.GetByName(r.Context(), name)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		appID = &app.ID
+	}
+
+	occurrences, err := h.freezes.Calendar(r.Context(), appID, from, to)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, occurrences)
+}
+
+// --- Notification Handlers ---
+
+func (h *Handlers) CreateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req createNotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	events := make([]domain.NotificationEvent, len(req.Events))
+	for i, e := range req.Events {
+		events[i] = domain.NotificationEvent(e)
+	}
+
+	ch, err := h.notifier.CreateChannel(r.Context(), app.ID, domain.ChannelKind(req.Kind), req.Target, events)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ch)
+}
+
+func (h *Handlers) ListNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	channels, err := h.notifier.ListChannels(r.Context(), app.ID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if channels == nil {
+		channels = []domain.NotificationChannel{}
+	}
+
+	writeJSON(w, http.StatusOK, channels)
+}
+
+func (h *Handlers) DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid notification channel ID")
+		return
+	}
+
+	if err := h.notifier.DeleteChannel(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListNotificationDeliveries returns the application's delivery log, newest
+// first, capped at ?limit (default 50).
+func (h *Handlers) ListNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid limit: "+v)
+			return
+		}
+		limit = n
+	}
+
+	app, err := h.apps.GetByName(r.Context(), name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	deliveries, err := h.notifier.ListDeliveries(r.Context(), app.ID, limit)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []domain.NotificationDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// --- Webhook Subscription Handlers ---
+
+// CreateWebhookSubscription subscribes a URL to domain events. The response
+// is the only one that includes the signing secret.
+func (h *Handlers) CreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	var req createWebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var appID *uuid.UUID
+	if req.Application != "" {
+		app, err := h.apps.GetByName(r.Context(), req.Application)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		appID = &app.ID
+	}
+
+	events := make([]domain.EventType, len(req.Events))
+	for i, e := range req.Events {
+		events[i] = domain.EventType(e)
+	}
+
+	sub, err := h.webhooks.CreateSubscription(r.Context(), req.URL, req.Secret, events, appID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (h *Handlers) ListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhooks.ListSubscriptions(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if subs == nil {
+		subs = []domain.WebhookSubscription{}
+	}
+
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *Handlers) GetWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook subscription ID")
+		return
+	}
+
+	sub, err := h.webhooks.GetSubscription(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sub)
+}
+
+func (h *Handlers) DeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook subscription ID")
+		return
+	}
+
+	if err := h.webhooks.DeleteSubscription(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries returns a subscription's deliveries, newest first,
+// optionally filtered by ?status (pending, delivered or dead).
+func (h *Handlers) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook subscription ID")
+		return
+	}
+
+	status := domain.WebhookDeliveryStatus(r.URL.Query().Get("status"))
+	deliveries, err := h.webhooks.ListDeliveries(r.Context(), id, status)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []domain.WebhookDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook sends a delivery again immediately, typically a dead
+// letter, and returns it after the attempt.
+func (h *Handlers) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook delivery ID")
+		return
+	}
+
+	d, err := h.webhooks.Redeliver(r.Context(), id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+// --- Auth Handlers ---
+
+// GetPrincipal returns the caller's identity and scopes.
+func (h *Handlers) GetPrincipal(w http.ResponseWriter, r *http.Request) {
+	p, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthenticated")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	scopes := make([]domain.Scope, len(req.Scopes))
+	for i, s := range req.Scopes {
+		scopes[i] = domain.Scope(s)
+	}
+
+	k, secret, err := h.auth.CreateAPIKey(r.Context(), req.Name, scopes, req.ExpiresAt)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createAPIKeyResponse{APIKey: k, Key: secret})
+}
+
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.auth.ListAPIKeys(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey revokes an API key. Revoked keys stay listed.
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid API key ID")
+		return
+	}
+
+	if err := h.auth.RevokeAPIKey(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Role Binding Handlers ---
+
+func (h *Handlers) CreateRoleBinding(w http.ResponseWriter, r *http.Request) {
+	var req createRoleBindingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var appID *uuid.UUID
+	if req.Application != "" {
+		app, err := h.apps.GetByName(r.Context(), req.Application)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		appID = &app.ID
+	}
+
+	b, err := h.rbac.Grant(r.Context(), req.Subject, domain.Role(req.Role), appID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// ListRoleBindings lists role bindings, optionally only those that apply to
+// ?application= (including global ones) or belong to ?subject=.
+func (h *Handlers) ListRoleBindings(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var appID *uuid.UUID
+	if name := q.Get("application"); name != "" {
+		app, err := h.apps.GetByName(r.Context(), name)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		appID = &app.ID
+	}
+
+	bindings, err := h.rbac.ListRoleBindings(r.Context(), appID, q.Get("subject"))
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if bindings == nil {
+		bindings = []domain.RoleBinding{}
+	}
+
+	writeJSON(w, http.StatusOK, bindings)
+}
+
+func (h *Handlers) DeleteRoleBinding(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid role binding ID")
+		return
+	}
+
+	if err := h.rbac.RevokeRoleBinding(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Organization Handlers ---
+
+func (h *Handlers) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req createOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	org, err := h.orgs.Create(r.Context(), req.Slug, req.Name)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, org)
+}
+
+func (h *Handlers) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.orgs.List(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+// GetOrganization returns the organization the request is scoped to, which
+// TenantMiddleware resolved from the URL.
+func (h *Handlers) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _ := tenant.OrgFrom(r.Context())
+	writeJSON(w, http.StatusOK, org)
+}
+
+// --- Audit Log Handlers ---
+
+// defaultAuditLimit caps ListAuditLog when no limit is given; exports are
+// not capped.
+const defaultAuditLimit = 100
+
+func (h *Handlers) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.auditFilter(w, r, defaultAuditLimit)
+	if !ok {
+		return
+	}
+
+	entries, err := h.audit.List(r.Context(), filter)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []domain.AuditEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// ExportAuditLog streams the matching audit entries as JSON Lines, one entry
+// per line, newest first.
+func (h *Handlers) ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.auditFilter(w, r, 0)
+	if !ok {
+		return
+	}
+
+	entries, err := h.audit.List(r.Context(), filter)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return
+		}
+	}
+}
+
+// auditFilter parses the audit log query: application (a name, or the ID of
+// a deleted application), from and to (RFC 3339) and limit. It writes the
+// error response and returns false when the query is invalid.
+func (h *Handlers) auditFilter(w http.ResponseWriter, r *http.Request, limit int) (domain.AuditFilter, bool) {
+	q := r.URL.Query()
+	filter := domain.AuditFilter{Limit: limit}
+
+	if v := q.Get("application"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.ApplicationID = &id
+		} else {
+			app, err := h.apps.GetByName(r.Context(), v)
+			if err != nil {
+				handleServiceError(w, err)
+				return filter, false
+			}
+			filter.ApplicationID = &app.ID
+		}
+	}
+	for param, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid "+param+": "+v)
+				return filter, false
+			}
+			*dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid limit: "+v)
+			return filter, false
+		}
+		filter.Limit = n
+	}
+	return filter, true
+}
+
+// --- LLM Cache Handlers ---
+
+func (h *Handlers) GetLLMCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.llmCache.Stats(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// ListLLMPrompts lists the system prompts in use with their versions and
+// where they were loaded from.
+func (h *Handlers) ListLLMPrompts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, llm.ActivePrompts())
+}
+
+// --- LLM Usage Handlers ---
+
+// GetLLMUsage reports token usage and estimated cost in total, per
+// application and per day, for ?application (a name, or the ID of a deleted
+// application) between ?from and ?to (RFC 3339).
+func (h *Handlers) GetLLMUsage(w http.ResponseWriter, r *http.Request) {
+	// The query parameters are the audit log's, without the limit.
+	q, ok := h.auditFilter(w, r, 0)
+	if !ok {
+		return
+	}
+
+	report, err := h.llmUsage.Report(r.Context(), domain.LLMUsageFilter{
+		ApplicationID: q.ApplicationID,
+		From:          q.From,
+		To:            q.To,
+	})
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// SetLLMBudget sets the monthly token budget of an application, or of the
+// organization when no application is given.
+func (h *Handlers) SetLLMBudget(w http.ResponseWriter, r *http.Request) {
+	var req setLLMBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var appID *uuid.UUID
+	if req.Application != "" {
+		app, err := h.apps.GetByName(r.Context(), req.Application)
+		if err != nil {
+			handleServiceError(w, err)
+			return
+		}
+		appID = &app.ID
+	}
+
+	budget, err := h.llmUsage.SetBudget(r.Context(), appID, req.MonthlyTokens)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, budget)
+}
+
+func (h *Handlers) ListLLMBudgets(w http.ResponseWriter, r *http.Request) {
+	budgets, err := h.llmUsage.ListBudgets(r.Context())
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, budgets)
+}
+
+func (h *Handlers) DeleteLLMBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid budget ID")
+		return
+	}
+
+	if err := h.llmUsage.DeleteBudget(r.Context(), id); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Compliance Handlers ---
+
+func (h *Handlers) ListComplianceFrameworks(w http.ResponseWriter, r *http.Request) {
+	providerFilter := r.URL.Query().Get("provider")
+
+	var frameworks []compliance.FrameworkInfo
+	if providerFilter != "" {
+		frameworks = h.compliance.ListFrameworksForProvider(domain.CloudProvider(providerFilter))
+	} else {
+		frameworks = h.compliance.ListFrameworks()
+	}
+	if frameworks == nil {
+		frameworks = []compliance.FrameworkInfo{}
+	}
+
+	writeJSON(w, http.StatusOK, frameworks)
+}
+
+// --- Git Webhook Handlers ---
+
+// maxWebhookBody caps the size of git push payloads read by the webhook handlers.
+const maxWebhookBody = 5 << 20
+
+// GitHubWebhook receives GitHub push deliveries, verifies X-Hub-Signature-256,
+// and creates deployments for matching applications.
+func (h *Handlers) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read request body: "+err.Error())
+		return
+	}
+
+	result, err := h.gitPush.HandleGitHub(r.Context(),
+		r.Header.Get("X-GitHub-Event"), r.Header.Get("X-Hub-Signature-256"),
+		body, r.URL.Query().Get("reanalyze") == "true")
+	if err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+// GitLabWebhook receives GitLab push deliveries, verifies X-Gitlab-Token, and
+// creates deployments for matching applications.
+func (h *Handlers) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read request body: "+err.Error())
+		return
+	}
+
+	result, err := h.gitPush.HandleGitLab(r.Context(),
+		r.Header.Get("X-Gitlab-Event"), r.Header.Get("X-Gitlab-Token"),
+		body, r.URL.Query().Get("reanalyze") == "true")
+	if err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+// handleWebhookError maps webhook verification and parsing errors to HTTP
+// responses. Ignored events get a 200 so git hosts don't retry them.
+func handleWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrNotConfigured):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, webhook.ErrInvalidSignature):
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, webhook.ErrMalformedPayload):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, webhook.ErrIgnoredEvent):
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "reason": err.Error()})
+	default:
+		handleServiceError(w, err)
+	}
 }
 
 // --- Helpers ---
@@ -659,14 +1994,31 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 }
 
 func handleServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrUnauthenticated) {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if errors.Is(err, domain.ErrForbidden) {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
 	if errors.Is(err, domain.ErrNotFound) {
 		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
-	if errors.Is(err, domain.ErrConflict) {
+	if errors.Is(err, domain.ErrConflict) || errors.Is(err, domain.ErrDeploymentInProgress) || errors.Is(err, domain.ErrPlanStale) ||
+		errors.Is(err, domain.ErrDeploymentFrozen) {
 		writeError(w, http.StatusConflict, err.Error())
 		return
 	}
+	if errors.Is(err, domain.ErrBudgetExceeded) {
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if errors.Is(err, llm.ErrProviderUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
 	if domain.IsValidationError(err) {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
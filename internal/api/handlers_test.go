@@ -2,27 +2,43 @@ package api
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
 	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/notify"
 	"github.com/matthewdriscoll/infraplane/internal/provider"
 	awsadapter "github.com/matthewdriscoll/infraplane/internal/provider/aws"
 	gcpadapter "github.com/matthewdriscoll/infraplane/internal/provider/gcp"
 	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
 	"github.com/matthewdriscoll/infraplane/internal/service"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
 )
 
 func setupTestRouter() http.Handler {
+	authSvc := service.NewAuthService(mock.NewAPIKeyRepo())
+	authSvc.SetAllowAnonymous(true)
+	return setupTestRouterWithAuth(authSvc)
+}
+
+// setupTestRouterWithAuth builds the test router around authSvc, so tests
+// can require credentials.
+func setupTestRouterWithAuth(authSvc *service.AuthService) http.Handler {
 	appRepo := mock.NewApplicationRepo()
 	resRepo := mock.NewResourceRepo()
 	depRepo := mock.NewDeploymentRepo()
 	planRepo := mock.NewPlanRepo()
-	mockLLM := &llm.MockClient{}
+	llmUsageSvc := service.NewLLMUsageService(mock.NewLLMUsageRepo(), mock.NewLLMBudgetRepo(), appRepo)
+	llmClient := llm.NewCachedClient(llm.NewMeteredClient(&llm.MockClient{}, llmUsageSvc), mock.NewLLMCacheRepo(), 0)
 
 	graphRepo := mock.NewGraphRepo()
 
@@ -35,17 +51,61 @@ func setupTestRouter() http.Handler {
 		Project: "test-project", Region: "us-central1", CredentialsFile: "/dev/null",
 	}))
 
-	appSvc := service.NewApplicationService(appRepo, resRepo, mockLLM, nil)
-	resSvc := service.NewResourceService(resRepo, appRepo, mockLLM, nil)
-	planSvc := service.NewPlannerService(planRepo, appRepo, resRepo, mockLLM, nil)
-	depSvc := service.NewDeploymentService(depRepo, appRepo)
+	appSvc := service.NewApplicationService(appRepo, resRepo, llmClient, nil)
+	resSvc := service.NewResourceService(resRepo, appRepo, llmClient, nil)
+	planSvc := service.NewPlannerService(planRepo, appRepo, resRepo, llmClient, nil)
+	freezeRepo := mock.NewFreezeWindowRepo()
+	depSvc := service.NewDeploymentService(depRepo, appRepo, planRepo, resRepo, freezeRepo)
 	infraSvc := service.NewInfraService(appRepo, resRepo, depRepo, providerRegistry)
-	graphSvc := service.NewGraphService(graphRepo, appRepo, resRepo, mockLLM)
-	discSvc := service.NewDiscoveryService(appRepo, mockLLM, nil)
-
-	return NewRouter(appSvc, resSvc, planSvc, depSvc, infraSvc, graphSvc, discSvc, nil)
+	graphSvc := service.NewGraphService(graphRepo, appRepo, resRepo, llmClient)
+	discSvc := service.NewDiscoveryService(appRepo, resRepo, llmClient, nil)
+	gitPushSvc := service.NewGitPushService(appSvc, depSvc, infraSvc, webhook.Secrets{GitHub: testWebhookSecret})
+	schedulerSvc := service.NewSchedulerService(mock.NewScheduleRepo(), appRepo, mock.NewLeaderLock(), service.SchedulerJobs{Graphs: graphSvc})
+
+	freezeSvc := service.NewFreezeService(freezeRepo, appRepo)
+	notifySvc := service.NewNotificationService(mock.NewNotificationChannelRepo(), mock.NewNotificationDeliveryRepo(), appRepo, depRepo, nil, notify.SMTPConfig{})
+
+	webhookSvc := service.NewWebhookService(mock.NewWebhookSubscriptionRepo(), mock.NewWebhookDeliveryRepo(), appRepo)
+	eventBus := service.NewEventBus()
+	eventBus.Subscribe(notifySvc.HandleEvent)
+	eventBus.Subscribe(webhookSvc.HandleEvent)
+	outbox := service.NewSyncOutbox(eventBus)
+	appSvc.SetOutbox(outbox)
+	infraSvc.SetOutbox(outbox)
+
+	rbacSvc := service.NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	appSvc.SetRBAC(rbacSvc)
+	resSvc.SetRBAC(rbacSvc)
+	planSvc.SetRBAC(rbacSvc)
+	depSvc.SetRBAC(rbacSvc)
+	infraSvc.SetRBAC(rbacSvc)
+	graphSvc.SetRBAC(rbacSvc)
+	discSvc.SetRBAC(rbacSvc)
+	schedulerSvc.SetRBAC(rbacSvc)
+	freezeSvc.SetRBAC(rbacSvc)
+	notifySvc.SetRBAC(rbacSvc)
+	webhookSvc.SetRBAC(rbacSvc)
+	authSvc.SetRBAC(rbacSvc)
+	orgSvc := service.NewOrganizationService(mock.NewOrganizationRepo())
+	orgSvc.SetRBAC(rbacSvc)
+
+	auditSvc := service.NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	auditSvc.SetRBAC(rbacSvc)
+	appSvc.SetAudit(auditSvc)
+	resSvc.SetAudit(auditSvc)
+	depSvc.SetAudit(auditSvc)
+	rbacSvc.SetAudit(auditSvc)
+
+	llmCacheSvc := service.NewLLMCacheService(llmClient)
+	llmCacheSvc.SetRBAC(rbacSvc)
+	llmUsageSvc.SetRBAC(rbacSvc)
+	llmUsageSvc.SetAudit(auditSvc)
+
+	return NewRouter(appSvc, resSvc, planSvc, depSvc, infraSvc, graphSvc, discSvc, gitPushSvc, schedulerSvc, freezeSvc, notifySvc, webhookSvc, authSvc, rbacSvc, orgSvc, auditSvc, llmCacheSvc, llmUsageSvc, nil)
 }
 
+const testWebhookSecret = "test-webhook-secret"
+
 func doRequest(router http.Handler, method, path string, body any) *httptest.ResponseRecorder {
 	var buf bytes.Buffer
 	if body != nil {
@@ -242,6 +302,73 @@ func TestRemoveResource(t *testing.T) {
 	})
 }
 
+func TestRefineResource(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "refine-res-app", Provider: "aws"})
+	addW := doRequest(router, "POST", "/api/applications/refine-res-app/resources", addResourceRequest{Description: "database"})
+
+	var resource domain.Resource
+	json.NewDecoder(addW.Body).Decode(&resource)
+	base := "/api/resources/" + resource.ID.String()
+
+	t.Run("refine", func(t *testing.T) {
+		w := doRequest(router, "POST", base+"/refine", refineRequest{Instruction: "use Aurora Serverless instead"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+		}
+		var rev domain.ResourceRevision
+		json.NewDecoder(w.Body).Decode(&rev)
+		if rev.Version != 2 || rev.ParentID == nil {
+			t.Errorf("revision = %d (parent %v), want 2 with a parent", rev.Version, rev.ParentID)
+		}
+	})
+
+	t.Run("list revisions", func(t *testing.T) {
+		w := doRequest(router, "GET", base+"/revisions", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var revisions []domain.ResourceRevision
+		json.NewDecoder(w.Body).Decode(&revisions)
+		if len(revisions) != 2 {
+			t.Errorf("len = %d, want 2", len(revisions))
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		w := doRequest(router, "GET", base+"/revisions/2/diff", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var diff domain.ResourceDiff
+		json.NewDecoder(w.Body).Decode(&diff)
+		if diff.FromVersion != 1 || diff.ToVersion != 2 || !strings.Contains(diff.Diff, "use Aurora Serverless instead") {
+			t.Errorf("diff = %+v", diff)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			method, path string
+			body         any
+			want         int
+		}{
+			{"POST", base + "/refine", refineRequest{Instruction: "  "}, http.StatusBadRequest},
+			{"POST", "/api/resources/not-a-uuid/refine", refineRequest{Instruction: "smaller"}, http.StatusBadRequest},
+			{"POST", "/api/resources/" + uuid.New().String() + "/refine", refineRequest{Instruction: "smaller"}, http.StatusNotFound},
+			{"GET", base + "/revisions/1/diff", nil, http.StatusBadRequest},
+			{"GET", base + "/revisions/9/diff", nil, http.StatusNotFound},
+			{"GET", base + "/revisions/two/diff", nil, http.StatusBadRequest},
+		}
+		for _, tc := range cases {
+			if w := doRequest(router, tc.method, tc.path, tc.body); w.Code != tc.want {
+				t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, w.Code, tc.want)
+			}
+		}
+	})
+}
+
 func TestGenerateHostingPlan(t *testing.T) {
 	router := setupTestRouter()
 
@@ -316,6 +443,42 @@ func TestDeploy(t *testing.T) {
 	})
 }
 
+func TestDeployStalePlan(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "stale-app", Provider: "aws"})
+
+	w := doRequest(router, "POST", "/api/applications/stale-app/hosting-plan", nil)
+	var plan domain.InfrastructurePlan
+	json.NewDecoder(w.Body).Decode(&plan)
+
+	// Adding a resource after the plan was generated makes it stale.
+	doRequest(router, "POST", "/api/applications/stale-app/resources", addResourceRequest{
+		Description: "I need a PostgreSQL database",
+	})
+
+	t.Run("refused without force", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/applications/stale-app/deploy", deployRequest{
+			GitBranch: "main",
+			PlanID:    plan.ID.String(),
+		})
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("accepted with force", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/applications/stale-app/deploy", deployRequest{
+			GitBranch: "main",
+			PlanID:    plan.ID.String(),
+			Force:     true,
+		})
+		if w.Code != http.StatusCreated {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+		}
+	})
+}
+
 func TestGetLatestDeployment(t *testing.T) {
 	router := setupTestRouter()
 
@@ -356,6 +519,57 @@ func TestListPlans(t *testing.T) {
 	}
 }
 
+func TestRefinePlan(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "refine-plan-app", Provider: "aws"})
+	planW := doRequest(router, "POST", "/api/applications/refine-plan-app/hosting-plan", nil)
+
+	var original domain.InfrastructurePlan
+	json.NewDecoder(planW.Body).Decode(&original)
+
+	w := doRequest(router, "POST", "/api/plans/"+original.ID.String()+"/refine", refineRequest{Instruction: "cut cost under $200/mo"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var refined domain.InfrastructurePlan
+	json.NewDecoder(w.Body).Decode(&refined)
+	if refined.ParentID == nil || *refined.ParentID != original.ID {
+		t.Errorf("ParentID = %v, want %s", refined.ParentID, original.ID)
+	}
+
+	t.Run("diff against parent", func(t *testing.T) {
+		w := doRequest(router, "GET", "/api/plans/"+refined.ID.String()+"/diff", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var diff domain.PlanDiff
+		json.NewDecoder(w.Body).Decode(&diff)
+		if diff.FromPlanID != original.ID || !strings.Contains(diff.Diff, "+cut cost under $200/mo") {
+			t.Errorf("diff = %+v", diff)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			method, path string
+			body         any
+			want         int
+		}{
+			{"POST", "/api/plans/" + original.ID.String() + "/refine", refineRequest{}, http.StatusBadRequest},
+			{"POST", "/api/plans/" + uuid.New().String() + "/refine", refineRequest{Instruction: "cheaper"}, http.StatusNotFound},
+			{"GET", "/api/plans/" + original.ID.String() + "/diff", nil, http.StatusBadRequest},
+			{"GET", "/api/plans/" + original.ID.String() + "/diff?against=" + refined.ID.String(), nil, http.StatusOK},
+			{"GET", "/api/plans/" + refined.ID.String() + "/diff?against=nope", nil, http.StatusBadRequest},
+		}
+		for _, tc := range cases {
+			if w := doRequest(router, tc.method, tc.path, tc.body); w.Code != tc.want {
+				t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, w.Code, tc.want)
+			}
+		}
+	})
+}
+
 func TestOnboardApplication(t *testing.T) {
 	router := setupTestRouter()
 
@@ -417,6 +631,58 @@ func TestOnboardApplication(t *testing.T) {
 	})
 }
 
+func TestStreamHostingPlan(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "stream-plan-app", Provider: "aws"})
+
+	t.Run("streams markdown then the saved plan", func(t *testing.T) {
+		w := doRequest(router, "GET", "/api/applications/stream-plan-app/hosting-plan/stream", nil)
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Content-Type = %q, want text/event-stream; body = %s", ct, w.Body.String())
+		}
+
+		var events []planStreamEvent
+		for _, line := range strings.Split(w.Body.String(), "\n") {
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var e planStreamEvent
+				if err := json.Unmarshal([]byte(data), &e); err != nil {
+					t.Fatalf("decode event %q: %v", data, err)
+				}
+				events = append(events, e)
+			}
+		}
+		if len(events) != 2 || events[0].Type != "delta" || events[1].Type != "plan" {
+			t.Fatalf("events = %+v, want a delta then the plan", events)
+		}
+		plan := events[1].Plan
+		if plan == nil || plan.PlanType != domain.PlanTypeHosting || plan.Content != events[0].Text {
+			t.Errorf("plan = %+v, want the hosting plan of the streamed markdown %q", plan, events[0].Text)
+		}
+
+		lw := doRequest(router, "GET", "/api/applications/stream-plan-app/plans", nil)
+		var plans []domain.InfrastructurePlan
+		json.NewDecoder(lw.Body).Decode(&plans)
+		if len(plans) != 1 || plans[0].ID != plan.ID {
+			t.Errorf("plans = %+v, want the streamed plan saved", plans)
+		}
+	})
+
+	t.Run("failure before streaming is a plain error", func(t *testing.T) {
+		w := doRequest(router, "GET", "/api/applications/stream-plan-app/migration-plan/stream?from_provider=aws&to_provider=aws", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d; body = %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	t.Run("unknown application", func(t *testing.T) {
+		w := doRequest(router, "GET", "/api/applications/nope/hosting-plan/stream", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
 func TestDeployStream(t *testing.T) {
 	router := setupTestRouter()
 
@@ -464,3 +730,802 @@ func TestDeployStream(t *testing.T) {
 		}
 	})
 }
+
+func TestRollbackDeployment(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "rollback-app", Provider: "aws"})
+	depW := doRequest(router, "POST", "/api/applications/rollback-app/deploy", deployRequest{GitBranch: "main", GitCommit: "abc"})
+	var d domain.Deployment
+	json.NewDecoder(depW.Body).Decode(&d)
+
+	t.Run("pending deployment returns 400", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/deployments/"+d.ID.String()+"/rollback", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d; body = %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	t.Run("unknown deployment returns 404", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/deployments/00000000-0000-0000-0000-000000000001/rollback", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("invalid deployment ID returns 400", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/deployments/not-a-uuid/rollback", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestDestroy(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "destroy-app", Provider: "aws"})
+
+	t.Run("nothing deployed returns 400", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/applications/destroy-app/destroy", destroyRequest{Confirm: "destroy-app"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d; body = %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	t.Run("unknown application returns 404", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/applications/missing/destroy", destroyRequest{Confirm: "missing"})
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestSetDestroyProtection(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "protected-app", Provider: "gcp"})
+
+	w := doRequest(router, "PUT", "/api/applications/protected-app/protection", protectionRequest{PreventDestroy: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var app domain.Application
+	json.NewDecoder(w.Body).Decode(&app)
+	if !app.PreventDestroy {
+		t.Error("expected prevent_destroy to be true")
+	}
+}
+
+func TestSetDeployBranch(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "branch-app", Provider: "aws", GitRepoURL: "https://github.com/acme/shop"})
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "no-repo-app", Provider: "aws"})
+
+	w := doRequest(router, "PUT", "/api/applications/branch-app/deploy-branch", deployBranchRequest{DeployBranch: "main"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var app domain.Application
+	json.NewDecoder(w.Body).Decode(&app)
+	if app.DeployBranch != "main" {
+		t.Errorf("DeployBranch = %q, want %q", app.DeployBranch, "main")
+	}
+
+	w = doRequest(router, "PUT", "/api/applications/no-repo-app/deploy-branch", deployBranchRequest{DeployBranch: "main"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("without git repo: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "hook-app", Provider: "aws", GitRepoURL: "https://github.com/acme/shop"})
+	doRequest(router, "PUT", "/api/applications/hook-app/deploy-branch", deployBranchRequest{DeployBranch: "main"})
+
+	body := []byte(`{"ref": "refs/heads/main", "after": "abc123", "repository": {"html_url": "https://github.com/acme/shop"}}`)
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	post := func(event, sig string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/webhooks/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", sig)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("signed push creates deployment", func(t *testing.T) {
+		w := post("push", signature)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+		}
+		var result service.PushResult
+		json.NewDecoder(w.Body).Decode(&result)
+		if len(result.Deployments) != 1 || result.Deployments[0].GitCommit != "abc123" {
+			t.Errorf("deployments = %+v, want one for abc123", result.Deployments)
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		if w := post("push", "sha256=00"); w.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("ping is acknowledged", func(t *testing.T) {
+		if w := post("ping", signature); w.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("gitlab not configured", func(t *testing.T) {
+		w := doRequest(router, "POST", "/api/webhooks/gitlab", map[string]string{})
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
+
+func TestSchedules(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "sched-app", Provider: "gcp"})
+
+	w := doRequest(router, "POST", "/api/applications/sched-app/schedules", createScheduleRequest{Job: "graph", Cron: "0 3 * * *", Timezone: "UTC"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var sched domain.Schedule
+	json.NewDecoder(w.Body).Decode(&sched)
+	if sched.NextRunAt == nil || sched.NextRunAt.Hour() != 3 {
+		t.Errorf("NextRunAt = %v, want 03:00", sched.NextRunAt)
+	}
+
+	w = doRequest(router, "POST", "/api/applications/sched-app/schedules", createScheduleRequest{Job: "deploy", Cron: "@daily"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("deploy without branch: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doRequest(router, "GET", "/api/applications/sched-app/schedules", nil)
+	var list []domain.Schedule
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 {
+		t.Errorf("list: len = %d, want 1", len(list))
+	}
+
+	w = doRequest(router, "POST", "/api/schedules/"+sched.ID.String()+"/run", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	json.NewDecoder(w.Body).Decode(&sched)
+	if sched.LastStatus != domain.ScheduleRunSucceeded || sched.LastRunAt == nil {
+		t.Errorf("after run: LastStatus = %q, LastRunAt = %v", sched.LastStatus, sched.LastRunAt)
+	}
+
+	w = doRequest(router, "PUT", "/api/schedules/"+sched.ID.String()+"/enabled", scheduleEnabledRequest{Enabled: false})
+	var disabled domain.Schedule
+	json.NewDecoder(w.Body).Decode(&disabled)
+	if w.Code != http.StatusOK || disabled.Enabled || disabled.NextRunAt != nil {
+		t.Errorf("disable: status = %d, Enabled = %v, NextRunAt = %v", w.Code, disabled.Enabled, disabled.NextRunAt)
+	}
+
+	w = doRequest(router, "DELETE", "/api/schedules/"+sched.ID.String(), nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	w = doRequest(router, "GET", "/api/schedules/"+sched.ID.String(), nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestFreezeWindows(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "freeze-app", Provider: "aws"})
+
+	start := time.Now().UTC().Add(-time.Hour)
+	end := start.Add(2 * time.Hour)
+	w := doRequest(router, "POST", "/api/freeze-windows", createFreezeWindowRequest{
+		Name:        "launch",
+		Reason:      "launch week",
+		Application: "freeze-app",
+		Environment: "production",
+		FreezeRule:  domain.FreezeRule{StartsAt: &start, EndsAt: &end},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var window domain.FreezeWindow
+	json.NewDecoder(w.Body).Decode(&window)
+
+	w = doRequest(router, "POST", "/api/freeze-windows", createFreezeWindowRequest{Name: "nothing"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("create without rule: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doRequest(router, "POST", "/api/applications/freeze-app/deploy", deployRequest{GitBranch: "production", GitCommit: "abc"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("deploy while frozen: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = doRequest(router, "POST", "/api/applications/freeze-app/deploy", deployRequest{GitBranch: "production", GitCommit: "abc", BreakGlass: true})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("break-glass deploy: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var d domain.Deployment
+	json.NewDecoder(w.Body).Decode(&d)
+	if !d.BreakGlass {
+		t.Error("break-glass deploy should record BreakGlass")
+	}
+
+	w = doRequest(router, "GET", "/api/freeze-windows/calendar?application=freeze-app", nil)
+	var calendar []domain.FreezeOccurrence
+	json.NewDecoder(w.Body).Decode(&calendar)
+	if w.Code != http.StatusOK || len(calendar) != 1 || calendar[0].WindowID != window.ID {
+		t.Errorf("calendar: status = %d, occurrences = %+v", w.Code, calendar)
+	}
+
+	w = doRequest(router, "GET", "/api/freeze-windows/calendar?from=not-a-time", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("calendar with bad from: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doRequest(router, "DELETE", "/api/freeze-windows/"+window.ID.String(), nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	w = doRequest(router, "POST", "/api/applications/freeze-app/deploy", deployRequest{GitBranch: "production", GitCommit: "def"})
+	if w.Code != http.StatusCreated {
+		t.Errorf("deploy after delete: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+}
+
+func TestNotificationChannels(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "notify-app", Provider: "aws"})
+
+	w := doRequest(router, "POST", "/api/applications/notify-app/notification-channels", createNotificationChannelRequest{
+		Kind:   "slack",
+		Target: "https://hooks.slack.com/services/T000/B000/XXXX",
+		Events: []string{"deployment.failed", "drift.detected"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var ch domain.NotificationChannel
+	json.NewDecoder(w.Body).Decode(&ch)
+	if len(ch.Events) != 2 || !ch.Enabled {
+		t.Errorf("channel = %+v", ch)
+	}
+
+	w = doRequest(router, "POST", "/api/applications/notify-app/notification-channels", createNotificationChannelRequest{
+		Kind: "email", Target: "ops@example.com", Events: []string{"app.exploded"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown event: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doRequest(router, "GET", "/api/applications/notify-app/notification-channels", nil)
+	var channels []domain.NotificationChannel
+	json.NewDecoder(w.Body).Decode(&channels)
+	if len(channels) != 1 {
+		t.Errorf("list: len = %d, want 1", len(channels))
+	}
+
+	w = doRequest(router, "GET", "/api/applications/notify-app/notifications", nil)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("deliveries: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	w = doRequest(router, "GET", "/api/applications/notify-app/notifications?limit=0", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("deliveries with limit=0: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doRequest(router, "DELETE", "/api/notification-channels/"+ch.ID.String(), nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	w = doRequest(router, "DELETE", "/api/notification-channels/"+ch.ID.String(), nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	router := setupTestRouter()
+
+	received := make(chan domain.Event, 1)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e domain.Event
+		json.NewDecoder(r.Body).Decode(&e)
+		received <- e
+	}))
+	defer endpoint.Close()
+
+	w := doRequest(router, "POST", "/api/webhook-subscriptions", createWebhookSubscriptionRequest{
+		URL: endpoint.URL, Events: []string{"application.registered"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var sub domain.WebhookSubscription
+	json.NewDecoder(w.Body).Decode(&sub)
+	if sub.Secret == "" {
+		t.Error("create response should include the generated secret")
+	}
+
+	w = doRequest(router, "POST", "/api/webhook-subscriptions", createWebhookSubscriptionRequest{
+		URL: endpoint.URL, Events: []string{"app.exploded"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown event: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	w = doRequest(router, "POST", "/api/webhook-subscriptions", createWebhookSubscriptionRequest{
+		URL: endpoint.URL, Application: "missing-app",
+	})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown application: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = doRequest(router, "GET", "/api/webhook-subscriptions/"+sub.ID.String(), nil)
+	var got domain.WebhookSubscription
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Secret != "" {
+		t.Errorf("get: status = %d, secret = %q, want redacted", w.Code, got.Secret)
+	}
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "hooked-app", Provider: "aws"})
+	select {
+	case e := <-received:
+		if e.Type != domain.EventApplicationRegistered {
+			t.Errorf("received %s, want application.registered", e.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook endpoint received nothing")
+	}
+
+	var deliveries []domain.WebhookDelivery
+	for i := 0; i < 50; i++ { // the delivery is recorded just after the endpoint responds
+		w = doRequest(router, "GET", "/api/webhook-subscriptions/"+sub.ID.String()+"/deliveries?status=delivered", nil)
+		deliveries = nil
+		json.NewDecoder(w.Body).Decode(&deliveries)
+		if len(deliveries) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("delivered deliveries = %d, want 1", len(deliveries))
+	}
+
+	w = doRequest(router, "GET", "/api/webhook-subscriptions/"+sub.ID.String()+"/deliveries?status=lost", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid status filter: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doRequest(router, "POST", "/api/webhook-deliveries/"+deliveries[0].ID.String()+"/redeliver", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("redeliver: status = %d: %s", w.Code, w.Body.String())
+	}
+	if e := <-received; e.ID != deliveries[0].EventID {
+		t.Errorf("redelivered event %s, want %s", e.ID, deliveries[0].EventID)
+	}
+
+	w = doRequest(router, "DELETE", "/api/webhook-subscriptions/"+sub.ID.String(), nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	w = doRequest(router, "GET", "/api/webhook-subscriptions", nil)
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("list after delete = %s, want []", w.Body.String())
+	}
+}
+
+func doAuthRequest(router http.Handler, method, path, credential string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthentication(t *testing.T) {
+	authSvc := service.NewAuthService(mock.NewAPIKeyRepo())
+	authSvc.SetBootstrapKey("test-admin-key")
+	router := setupTestRouterWithAuth(authSvc)
+
+	w := doAuthRequest(router, "GET", "/api/applications", "", nil)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("no credentials: status = %d, want %d with a challenge", w.Code, http.StatusUnauthorized)
+	}
+	if w := doAuthRequest(router, "GET", "/api/applications", "ipk_forged", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := doAuthRequest(router, "GET", "/health", "", nil); w.Code != http.StatusOK {
+		t.Errorf("health: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// The bootstrap key creates a read-only key.
+	w = doAuthRequest(router, "POST", "/api/api-keys", "test-admin-key", createAPIKeyRequest{Name: "dashboard", Scopes: []string{"read"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create key: status = %d: %s", w.Code, w.Body.String())
+	}
+	var created createAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Key == "" || created.APIKey.Hash != "" || strings.Contains(w.Body.String(), domain.HashAPIKey(created.Key)) {
+		t.Errorf("create response should show the key once and never its hash: %s", w.Body.String())
+	}
+	readKey := created.Key
+
+	if w := doAuthRequest(router, "GET", "/api/applications", readKey, nil); w.Code != http.StatusOK {
+		t.Errorf("read with read key: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	w = doAuthRequest(router, "GET", "/api/me", readKey, nil)
+	var me domain.Principal
+	json.NewDecoder(w.Body).Decode(&me)
+	if me.Kind != domain.PrincipalAPIKey || me.Name != "dashboard" {
+		t.Errorf("me = %+v", me)
+	}
+	forbidden := []struct{ method, path string }{
+		{"POST", "/api/applications"},
+		{"GET", "/api/api-keys"},
+		{"GET", "/api/deployments/" + created.APIKey.ID.String() + "/stream"}, // runs a deployment
+	}
+	for _, f := range forbidden {
+		if w := doAuthRequest(router, f.method, f.path, readKey, registerAppRequest{Name: "x", Provider: "aws"}); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s with read key: status = %d, want %d", f.method, f.path, w.Code, http.StatusForbidden)
+		}
+	}
+
+	// EventSource streams pass the credential as a query parameter.
+	req := httptest.NewRequest("GET", "/api/deployments/"+created.APIKey.ID.String()+"/stream?access_token="+readKey, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("stream with access_token read key: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// Revoked keys are listed but rejected.
+	if w := doAuthRequest(router, "DELETE", "/api/api-keys/"+created.APIKey.ID.String(), "test-admin-key", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "GET", "/api/applications", readKey, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	w = doAuthRequest(router, "GET", "/api/api-keys", "test-admin-key", nil)
+	var keys []domain.APIKey
+	json.NewDecoder(w.Body).Decode(&keys)
+	if len(keys) != 1 || keys[0].RevokedAt == nil || keys[0].LastUsedAt == nil {
+		t.Errorf("list keys = %+v, want the revoked key with its last use", keys)
+	}
+}
+
+func TestRoleBasedAccess(t *testing.T) {
+	authSvc := service.NewAuthService(mock.NewAPIKeyRepo())
+	authSvc.SetBootstrapKey("test-admin-key")
+	router := setupTestRouterWithAuth(authSvc)
+	const admin = "test-admin-key"
+
+	for _, name := range []string{"checkout", "billing"} {
+		if w := doAuthRequest(router, "POST", "/api/applications", admin, registerAppRequest{Name: name, Provider: "aws"}); w.Code != http.StatusCreated {
+			t.Fatalf("register %s: status = %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+	w := doAuthRequest(router, "POST", "/api/api-keys", admin, createAPIKeyRequest{Name: "ci", Scopes: []string{"write"}})
+	var ci createAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&ci)
+
+	// Without role bindings the key sees nothing.
+	w = doAuthRequest(router, "GET", "/api/applications", ci.Key, nil)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("list without roles: %d %s, want an empty list", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "GET", "/api/applications/checkout", ci.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("get without roles: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// An editor on checkout can change it but not deploy it or touch billing.
+	w = doAuthRequest(router, "POST", "/api/role-bindings", admin, createRoleBindingRequest{Subject: ci.APIKey.ID.String(), Role: "editor", Application: "checkout"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("grant: status = %d: %s", w.Code, w.Body.String())
+	}
+	var binding domain.RoleBinding
+	json.NewDecoder(w.Body).Decode(&binding)
+
+	var apps []domain.Application
+	json.NewDecoder(doAuthRequest(router, "GET", "/api/applications", ci.Key, nil).Body).Decode(&apps)
+	if len(apps) != 1 || apps[0].Name != "checkout" {
+		t.Errorf("list as checkout editor = %+v, want only checkout", apps)
+	}
+	if w := doAuthRequest(router, "POST", "/api/applications/checkout/resources", ci.Key, addResourceRequest{Description: "a postgres database"}); w.Code != http.StatusCreated {
+		t.Errorf("add resource as editor: status = %d: %s", w.Code, w.Body.String())
+	}
+	w = doAuthRequest(router, "POST", "/api/applications/checkout/deploy", ci.Key, deployRequest{GitBranch: "main"})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "deployer role on application checkout") {
+		t.Errorf("deploy as editor: %d %s, want 403 naming the deployer role", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "DELETE", "/api/applications/checkout", ci.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("delete as editor: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := doAuthRequest(router, "GET", "/api/applications/billing", ci.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("get billing: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := doAuthRequest(router, "POST", "/api/role-bindings", ci.Key, createRoleBindingRequest{Subject: ci.APIKey.ID.String(), Role: "admin"}); w.Code != http.StatusForbidden {
+		t.Errorf("self-grant: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// Revoking the binding takes the access away again.
+	if w := doAuthRequest(router, "DELETE", "/api/role-bindings/"+binding.ID.String(), admin, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "GET", "/api/applications/checkout", ci.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("get after revoke: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	w = doAuthRequest(router, "GET", "/api/role-bindings?application=checkout", admin, nil)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("list bindings: %d %s, want none", w.Code, w.Body.String())
+	}
+
+	// Other organizations are closed to callers without a binding in them,
+	// and their bindings stay out of the default organization's list.
+	if w := doAuthRequest(router, "POST", "/api/orgs", admin, createOrganizationRequest{Slug: "acme"}); w.Code != http.StatusCreated {
+		t.Fatalf("create org: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "GET", "/api/orgs/acme/applications", ci.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("enter acme as non-member: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := doAuthRequest(router, "POST", "/api/orgs/acme/role-bindings", admin, createRoleBindingRequest{Subject: ci.APIKey.ID.String(), Role: "viewer"}); w.Code != http.StatusCreated {
+		t.Fatalf("grant in acme: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "GET", "/api/orgs/acme/applications", ci.Key, nil); w.Code != http.StatusOK {
+		t.Errorf("enter acme as member: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "GET", "/api/applications/checkout", ci.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("acme binding on default-org app: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	var bindings []domain.RoleBinding
+	json.NewDecoder(doAuthRequest(router, "GET", "/api/role-bindings", admin, nil).Body).Decode(&bindings)
+	for _, b := range bindings {
+		if b.OrgID != domain.DefaultOrgID {
+			t.Errorf("default-org bindings include %+v", b)
+		}
+	}
+
+	// API keys belong to an organization, and the admin scope alone does not
+	// let a key manage them.
+	w = doAuthRequest(router, "POST", "/api/orgs/acme/api-keys", admin, createAPIKeyRequest{Name: "acme-admin", Scopes: []string{"admin"}})
+	var acmeKey createAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&acmeKey)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create acme key: status = %d: %s", w.Code, w.Body.String())
+	}
+	var keys []domain.APIKey
+	json.NewDecoder(doAuthRequest(router, "GET", "/api/api-keys", admin, nil).Body).Decode(&keys)
+	for _, k := range keys {
+		if k.ID == acmeKey.APIKey.ID {
+			t.Error("default-org keys include acme's key")
+		}
+	}
+	if w := doAuthRequest(router, "GET", "/api/orgs/acme/api-keys", acmeKey.Key, nil); w.Code != http.StatusForbidden {
+		t.Errorf("list keys without the admin role: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := doAuthRequest(router, "DELETE", "/api/api-keys/"+acmeKey.APIKey.ID.String(), admin, nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoke acme key in default org: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	var orgs []domain.Organization
+	json.NewDecoder(doAuthRequest(router, "GET", "/api/orgs", ci.Key, nil).Body).Decode(&orgs)
+	if len(orgs) != 2 {
+		t.Errorf("orgs of a member of acme = %+v, want acme and default", orgs)
+	}
+	json.NewDecoder(doAuthRequest(router, "GET", "/api/orgs", acmeKey.Key, nil).Body).Decode(&orgs)
+	if len(orgs) != 2 {
+		t.Errorf("orgs of an acme key = %+v, want acme and default", orgs)
+	}
+}
+
+func TestOrganizations(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/api/orgs", createOrganizationRequest{Slug: "acme", Name: "Acme Corp"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create org: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "POST", "/api/orgs", createOrganizationRequest{Slug: "acme"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate org: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	var orgs []domain.Organization
+	json.NewDecoder(doRequest(router, "GET", "/api/orgs", nil).Body).Decode(&orgs)
+	if len(orgs) != 2 || orgs[0].Slug != "acme" || orgs[1].Slug != domain.DefaultOrgSlug {
+		t.Errorf("list orgs = %+v, want acme and default", orgs)
+	}
+	w = doRequest(router, "GET", "/api/orgs/acme", nil)
+	var acme domain.Organization
+	json.NewDecoder(w.Body).Decode(&acme)
+	if w.Code != http.StatusOK || acme.Name != "Acme Corp" {
+		t.Errorf("get org: %d %+v", w.Code, acme)
+	}
+	if w := doRequest(router, "GET", "/api/orgs/ghost/applications", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown org: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// The same name can be registered once per organization.
+	if w := doRequest(router, "POST", "/api/orgs/acme/applications", registerAppRequest{Name: "checkout", Provider: "aws"}); w.Code != http.StatusCreated {
+		t.Fatalf("register in acme: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "checkout", Provider: "gcp"}); w.Code != http.StatusCreated {
+		t.Fatalf("register in default: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "POST", "/api/orgs/acme/applications", registerAppRequest{Name: "checkout", Provider: "aws"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate in acme: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	var apps []domain.Application
+	json.NewDecoder(doRequest(router, "GET", "/api/orgs/acme/applications", nil).Body).Decode(&apps)
+	if len(apps) != 1 || apps[0].OrgID != acme.ID || apps[0].Provider != domain.ProviderAWS {
+		t.Errorf("acme applications = %+v, want its checkout only", apps)
+	}
+	json.NewDecoder(doRequest(router, "GET", "/api/orgs/default/applications", nil).Body).Decode(&apps)
+	if len(apps) != 1 || apps[0].OrgID != domain.DefaultOrgID || apps[0].Provider != domain.ProviderGCP {
+		t.Errorf("default applications = %+v, want its checkout only", apps)
+	}
+
+	// Deleting in one organization leaves the other's application alone.
+	if w := doRequest(router, "DELETE", "/api/orgs/acme/applications/checkout", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete in acme: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "GET", "/api/orgs/acme/applications/checkout", nil); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := doRequest(router, "GET", "/api/applications/checkout", nil); w.Code != http.StatusOK {
+		t.Errorf("get in default: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "audited", Provider: "aws"})
+	addW := doRequest(router, "POST", "/api/applications/audited/resources", addResourceRequest{Description: "database"})
+	var resource domain.Resource
+	json.NewDecoder(addW.Body).Decode(&resource)
+	w := doRequest(router, "DELETE", "/api/resources/"+resource.ID.String(), nil)
+	requestID := w.Header().Get("X-Request-Id")
+	if requestID == "" {
+		t.Error("response has no X-Request-Id")
+	}
+
+	w = doRequest(router, "GET", "/api/audit-log?application=audited", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d: %s", w.Code, w.Body.String())
+	}
+	var entries []domain.AuditEntry
+	json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 3 || entries[0].Action != "resource.remove" || entries[2].Action != "application.register" {
+		t.Fatalf("entries = %+v, want register, add and remove, newest first", entries)
+	}
+	if e := entries[0]; e.Source != domain.AuditSourceAPI || e.RequestID != requestID || e.Actor != string(domain.PrincipalAnonymous) || e.TargetID != resource.ID.String() {
+		t.Errorf("remove entry = %+v", e)
+	}
+	if w := doRequest(router, "GET", "/api/audit-log?limit=1", nil); !strings.Contains(w.Body.String(), "resource.remove") || strings.Contains(w.Body.String(), "resource.add") {
+		t.Errorf("limit 1 = %s, want only the newest entry", w.Body.String())
+	}
+	if w := doRequest(router, "GET", "/api/audit-log?from="+time.Now().UTC().Add(time.Hour).Format(time.RFC3339), nil); strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("from the future = %s, want []", w.Body.String())
+	}
+	if w := doRequest(router, "GET", "/api/audit-log?to=yesterday", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("bad to: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// Deleted applications are still queryable by ID.
+	appID := *entries[2].ApplicationID
+	doRequest(router, "DELETE", "/api/applications/audited", nil)
+	w = doRequest(router, "GET", "/api/audit-log/export?application="+appID.String(), nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("export: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("export = %d lines, want 4", len(lines))
+	}
+	var deleted domain.AuditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &deleted); err != nil || deleted.Action != "application.delete" || deleted.Before == nil {
+		t.Errorf("first export line = %s, want the application's deletion", lines[0])
+	}
+}
+
+func TestLLMCache(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "cache-app", Provider: "aws"})
+	doRequest(router, "POST", "/api/applications/cache-app/hosting-plan", nil)
+	doRequest(router, "POST", "/api/applications/cache-app/hosting-plan", nil)
+	doRequest(router, "POST", "/api/applications/cache-app/hosting-plan?no_cache=true", nil)
+
+	w := doRequest(router, "GET", "/api/llm/cache", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var stats llm.CacheStats
+	json.NewDecoder(w.Body).Decode(&stats)
+	op := stats.Operations["GenerateHostingPlan"]
+	if !stats.Enabled || op.Misses != 1 || op.Hits != 1 || op.Bypassed != 1 {
+		t.Errorf("stats = %+v, GenerateHostingPlan = %+v, want 1 miss, 1 hit and 1 bypass", stats, op)
+	}
+}
+
+func TestLLMPrompts(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "GET", "/api/llm/prompts", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var prompts []llm.Prompt
+	json.NewDecoder(w.Body).Decode(&prompts)
+	if len(prompts) != 10 || prompts[0].Name != llm.PromptCodebaseAnalysis || prompts[0].Source != "embedded" || prompts[0].Version == "" {
+		t.Errorf("prompts = %+v", prompts)
+	}
+
+	// Plans record the version of the prompt that produced them.
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "prompt-app", Provider: "aws"})
+	w = doRequest(router, "POST", "/api/applications/prompt-app/hosting-plan", nil)
+	var plan domain.InfrastructurePlan
+	json.NewDecoder(w.Body).Decode(&plan)
+	if want := llm.PromptVersion(llm.PromptHostingPlan); plan.PromptVersion != want {
+		t.Errorf("plan prompt_version = %q, want %q", plan.PromptVersion, want)
+	}
+}
+
+func TestLLMUsageAndBudgets(t *testing.T) {
+	router := setupTestRouter()
+
+	doRequest(router, "POST", "/api/applications", registerAppRequest{Name: "usage-app", Provider: "aws"})
+	doRequest(router, "POST", "/api/applications/usage-app/graph", nil)
+
+	w := doRequest(router, "GET", "/api/llm/usage?application=usage-app", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("usage status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var report domain.LLMUsageReport
+	json.NewDecoder(w.Body).Decode(&report)
+	if report.Calls != 1 || len(report.ByApplication) != 1 || report.ByApplication[0].ApplicationName != "usage-app" || len(report.ByDay) != 1 {
+		t.Errorf("usage = %+v", report)
+	}
+
+	if w := doRequest(router, "PUT", "/api/llm/budgets", setLLMBudgetRequest{Application: "usage-app"}); w.Code != http.StatusBadRequest {
+		t.Errorf("zero budget status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	w = doRequest(router, "PUT", "/api/llm/budgets", setLLMBudgetRequest{MonthlyTokens: 1000})
+	if w.Code != http.StatusOK {
+		t.Fatalf("set budget status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var budget domain.LLMBudget
+	json.NewDecoder(w.Body).Decode(&budget)
+
+	w = doRequest(router, "GET", "/api/llm/budgets", nil)
+	var budgets []domain.LLMBudgetStatus
+	json.NewDecoder(w.Body).Decode(&budgets)
+	if len(budgets) != 1 || budgets[0].ID != budget.ID || budgets[0].ApplicationID != nil {
+		t.Errorf("budgets = %+v", budgets)
+	}
+
+	if w := doRequest(router, "DELETE", "/api/llm/budgets/"+budget.ID.String(), nil); w.Code != http.StatusNoContent {
+		t.Errorf("delete budget status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
@@ -1,25 +1,149 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/matthewdriscoll/infraplane/internal/audit"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/service"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 // CORSMiddleware returns a CORS handler that allows the React frontend.
+// Credentials travel in the Authorization header, so cookies are not
+// allowed cross-origin.
 func CORSMiddleware() func(http.Handler) http.Handler {
 	return cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:3000"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Cache-Control"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Cache-Control", "X-API-Key", "X-Request-Id"},
+		ExposedHeaders:   []string{"Link", "X-Request-Id"},
+		AllowCredentials: false,
 		MaxAge:           300,
 	})
 }
 
+// AuthMiddleware authenticates every request with authSvc and attaches the
+// principal to its context. The credential is an API key or JWT sent as
+// "Authorization: Bearer <credential>" or in X-API-Key; EventSource streams,
+// which cannot set headers, may pass it as the access_token query parameter.
+// Safe methods need the read scope and everything else the write scope;
+// RequireScope raises that for individual routes.
+func AuthMiddleware(authSvc *service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := authSvc.Authenticate(r.Context(), credential(r))
+			if errors.Is(err, domain.ErrUnauthenticated) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="infraplane"`)
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			required := domain.ScopeWrite
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				required = domain.ScopeRead
+			}
+			if !p.Can(required) {
+				writeError(w, http.StatusForbidden, "this credential lacks the "+string(required)+" scope")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// RequireScope rejects requests whose principal lacks scope. It must run
+// after AuthMiddleware.
+func RequireScope(scope domain.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := auth.PrincipalFrom(r.Context()); !ok || !p.Can(scope) {
+				writeError(w, http.StatusForbidden, "this credential lacks the "+string(scope)+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuditMiddleware marks each request as coming through the REST API and
+// carries its request ID, set by middleware.RequestID, into the audit log
+// entries of the changes it makes. The ID is echoed in the X-Request-Id
+// response header.
+func AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		if id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		next.ServeHTTP(w, r.WithContext(audit.WithRequest(r.Context(), domain.AuditSourceAPI, id)))
+	})
+}
+
+// NoCacheMiddleware makes requests with ?no_cache=true bypass the LLM
+// response cache, so the operations they trigger ask the model afresh.
+func NoCacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("no_cache") == "true" {
+			r = r.WithContext(llm.WithoutCache(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TenantMiddleware scopes each request to the organization named by the
+// {org} URL parameter or, on routes without one, to the default
+// organization, refusing callers that do not belong to it. It must run after
+// AuthMiddleware.
+func TenantMiddleware(orgSvc *service.OrganizationService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug := chi.URLParam(r, "org")
+			if slug == "" {
+				slug = domain.DefaultOrgSlug
+			}
+			org, err := orgSvc.Enter(r.Context(), slug)
+			if errors.Is(err, domain.ErrNotFound) {
+				writeError(w, http.StatusNotFound, "organization '"+slug+"' not found")
+				return
+			}
+			if err != nil {
+				handleServiceError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(tenant.WithOrg(r.Context(), org)))
+		})
+	}
+}
+
+func credential(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, token, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
 // LoggingMiddleware logs each HTTP request with method, path, status, and duration.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -6,6 +6,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
 	"github.com/matthewdriscoll/infraplane/internal/service"
 )
 
@@ -18,59 +19,68 @@ func NewRouter(
 	infraSvc *service.InfraService,
 	graphSvc *service.GraphService,
 	discSvc *service.DiscoveryService,
+	gitPushSvc *service.GitPushService,
+	schedulerSvc *service.SchedulerService,
+	freezeSvc *service.FreezeService,
+	notifySvc *service.NotificationService,
+	webhookSvc *service.WebhookService,
+	authSvc *service.AuthService,
+	rbacSvc *service.RBACService,
+	orgSvc *service.OrganizationService,
+	auditSvc *service.AuditService,
+	llmCacheSvc *service.LLMCacheService,
+	llmUsageSvc *service.LLMUsageService,
 	complianceRegistry *compliance.Registry,
 ) http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(CORSMiddleware())
+	r.Use(middleware.RequestID)
+	r.Use(AuditMiddleware)
+	r.Use(NoCacheMiddleware)
 	r.Use(LoggingMiddleware)
 	r.Use(middleware.Recoverer)
 
-	h := NewHandlers(appSvc, resSvc, planSvc, depSvc, infraSvc, graphSvc, discSvc, complianceRegistry)
+	h := NewHandlers(appSvc, resSvc, planSvc, depSvc, infraSvc, graphSvc, discSvc, gitPushSvc, schedulerSvc, freezeSvc, notifySvc, webhookSvc, authSvc, rbacSvc, orgSvc, auditSvc, llmCacheSvc, llmUsageSvc, complianceRegistry)
 
 	// Routes
 	r.Route("/api", func(r chi.Router) {
-		// Compliance
-		r.Get("/compliance/frameworks", h.ListComplianceFrameworks)
-
-		// Applications
-		r.Post("/applications/onboard", h.OnboardApplication)
-		r.Post("/applications", h.RegisterApplication)
-		r.Get("/applications", h.ListApplications)
-		r.Get("/applications/{name}", h.GetApplication)
-		r.Delete("/applications/{name}", h.DeleteApplication)
-
-		// Reanalyze
-		r.Post("/applications/{name}/reanalyze", h.ReanalyzeSource)
-		r.Post("/applications/{name}/analyze-upload", h.AnalyzeUpload)
-
-		// Resources
-		r.Post("/applications/{name}/resources", h.AddResource)
-		r.Get("/applications/{name}/resources", h.ListResources)
-		r.Delete("/resources/{id}", h.RemoveResource)
-		r.Post("/resources/{id}/terraform", h.GenerateTerraformHCL)
-
-		// Plans
-		r.Post("/applications/{name}/hosting-plan", h.GenerateHostingPlan)
-		r.Post("/applications/{name}/migration-plan", h.GenerateMigrationPlan)
-		r.Get("/applications/{name}/plans", h.ListPlans)
-
-		// Graphs
-		r.Post("/applications/{name}/graph", h.GenerateGraph)
-		r.Get("/applications/{name}/graph", h.GetLatestGraph)
-
-		// Live Resources (POST because discovery actively queries cloud APIs)
-		r.Post("/applications/{name}/live-resources", h.GetLiveResources)
-
-		// Deployments
-		r.Post("/applications/{name}/deploy", h.Deploy)
-		r.Get("/applications/{name}/deployments", h.ListDeployments)
-		r.Get("/applications/{name}/deployments/latest", h.GetLatestDeployment)
-		r.Get("/deployments/{id}", h.GetDeploymentStatus)
-
-		// Deployment SSE stream (real-time execution logs)
-		r.Get("/deployments/{id}/stream", h.DeployStream)
+		// Git push webhooks authenticate with their own signatures
+		// (?reanalyze=true re-runs codebase analysis first)
+		r.Post("/webhooks/github", h.GitHubWebhook)
+		r.Post("/webhooks/gitlab", h.GitLabWebhook)
+
+		// Everything else needs an API key or bearer token
+		r.Group(func(r chi.Router) {
+			r.Use(AuthMiddleware(authSvc))
+
+			// Caller identity
+			r.Get("/me", h.GetPrincipal)
+
+			// Compliance
+			r.Get("/compliance/frameworks", h.ListComplianceFrameworks)
+
+			// LLM response cache hit/miss statistics
+			r.Get("/llm/cache", h.GetLLMCacheStats)
+
+			// Active LLM system prompt versions
+			r.Get("/llm/prompts", h.ListLLMPrompts)
+
+			// Organizations; /api/orgs/{org}/... serves the tenantRoutes
+			// scoped to that organization, and /api/... to the default one
+			r.Post("/orgs", h.CreateOrganization)
+			r.Get("/orgs", h.ListOrganizations)
+			r.Route("/orgs/{org}", func(r chi.Router) {
+				r.Use(TenantMiddleware(orgSvc))
+				r.Get("/", h.GetOrganization)
+				tenantRoutes(r, h)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(TenantMiddleware(orgSvc))
+				tenantRoutes(r, h)
+			})
+		})
 	})
 
 	// Health check
@@ -80,3 +90,108 @@ func NewRouter(
 
 	return r
 }
+
+// tenantRoutes registers the routes scoped to an organization by
+// TenantMiddleware.
+func tenantRoutes(r chi.Router, h *Handlers) {
+	// API keys of the organization
+	r.With(RequireScope(domain.ScopeAdmin)).Post("/api-keys", h.CreateAPIKey)
+	r.With(RequireScope(domain.ScopeAdmin)).Get("/api-keys", h.ListAPIKeys)
+	r.With(RequireScope(domain.ScopeAdmin)).Delete("/api-keys/{id}", h.RevokeAPIKey)
+
+	// Role bindings (viewer, editor, deployer, admin per application or globally)
+	r.Post("/role-bindings", h.CreateRoleBinding)
+	r.Get("/role-bindings", h.ListRoleBindings)
+	r.Delete("/role-bindings/{id}", h.DeleteRoleBinding)
+
+	// Applications
+	r.Post("/applications/onboard", h.OnboardApplication)
+	r.Post("/applications", h.RegisterApplication)
+	r.Get("/applications", h.ListApplications)
+	r.Get("/applications/{name}", h.GetApplication)
+	r.Delete("/applications/{name}", h.DeleteApplication)
+	r.Put("/applications/{name}/protection", h.SetDestroyProtection)
+	r.Put("/applications/{name}/deploy-branch", h.SetDeployBranch)
+
+	// Reanalyze
+	r.Post("/applications/{name}/reanalyze", h.ReanalyzeSource)
+	r.Post("/applications/{name}/analyze-upload", h.AnalyzeUpload)
+
+	// Resources
+	r.Post("/applications/{name}/resources", h.AddResource)
+	r.Get("/applications/{name}/resources", h.ListResources)
+	r.Delete("/resources/{id}", h.RemoveResource)
+	r.Post("/resources/{id}/terraform", h.GenerateTerraformHCL)
+	r.Post("/resources/{id}/refine", h.RefineResource)
+	r.Get("/resources/{id}/revisions", h.ListResourceRevisions)
+	r.Get("/resources/{id}/revisions/{version}/diff", h.DiffResourceRevision)
+
+	// Plans
+	r.Post("/applications/{name}/hosting-plan", h.GenerateHostingPlan)
+	r.Post("/applications/{name}/migration-plan", h.GenerateMigrationPlan)
+	r.Get("/applications/{name}/plans", h.ListPlans)
+	r.Post("/plans/{id}/refine", h.RefinePlan)
+	r.Get("/plans/{id}/diff", h.DiffPlan)
+
+	// Plan SSE streams (markdown as the model writes it)
+	r.With(RequireScope(domain.ScopeWrite)).Get("/applications/{name}/hosting-plan/stream", h.StreamHostingPlan)
+	r.With(RequireScope(domain.ScopeWrite)).Get("/applications/{name}/migration-plan/stream", h.StreamMigrationPlan)
+
+	// Graphs
+	r.Post("/applications/{name}/graph", h.GenerateGraph)
+	r.Get("/applications/{name}/graph", h.GetLatestGraph)
+
+	// Live Resources (POST because discovery actively queries cloud APIs)
+	r.Post("/applications/{name}/live-resources", h.GetLiveResources)
+
+	// Deployments
+	r.Post("/applications/{name}/deploy", h.Deploy)
+	r.Post("/applications/{name}/destroy", h.Destroy)
+	r.Get("/applications/{name}/deployments", h.ListDeployments)
+	r.Get("/applications/{name}/deployments/latest", h.GetLatestDeployment)
+	r.Get("/deployments/{id}", h.GetDeploymentStatus)
+	r.Post("/deployments/{id}/rollback", h.RollbackDeployment)
+
+	// Deployment SSE stream (real-time execution logs)
+	r.With(RequireScope(domain.ScopeWrite)).Get("/deployments/{id}/stream", h.DeployStream)
+
+	// Schedules (recurring discovery, plan, graph and deploy jobs)
+	r.Post("/applications/{name}/schedules", h.CreateSchedule)
+	r.Get("/applications/{name}/schedules", h.ListApplicationSchedules)
+	r.Get("/schedules", h.ListSchedules)
+	r.Get("/schedules/{id}", h.GetSchedule)
+	r.Put("/schedules/{id}/enabled", h.SetScheduleEnabled)
+	r.Delete("/schedules/{id}", h.DeleteSchedule)
+	r.Post("/schedules/{id}/run", h.RunSchedule)
+
+	// Deployment freeze windows and change calendar
+	r.Post("/freeze-windows", h.CreateFreezeWindow)
+	r.Get("/freeze-windows", h.ListFreezeWindows)
+	r.Get("/freeze-windows/calendar", h.FreezeCalendar)
+	r.Get("/freeze-windows/{id}", h.GetFreezeWindow)
+	r.Delete("/freeze-windows/{id}", h.DeleteFreezeWindow)
+
+	// Notification channels and delivery log
+	r.Post("/applications/{name}/notification-channels", h.CreateNotificationChannel)
+	r.Get("/applications/{name}/notification-channels", h.ListNotificationChannels)
+	r.Delete("/notification-channels/{id}", h.DeleteNotificationChannel)
+	r.Get("/applications/{name}/notifications", h.ListNotificationDeliveries)
+
+	// Audit log of mutating operations (?application=, ?from=, ?to=, ?limit=)
+	r.Get("/audit-log", h.ListAuditLog)
+	r.Get("/audit-log/export", h.ExportAuditLog)
+
+	// LLM token usage (?application=, ?from=, ?to=) and monthly budgets
+	r.Get("/llm/usage", h.GetLLMUsage)
+	r.Put("/llm/budgets", h.SetLLMBudget)
+	r.Get("/llm/budgets", h.ListLLMBudgets)
+	r.Delete("/llm/budgets/{id}", h.DeleteLLMBudget)
+
+	// Outbound webhook subscriptions for domain events
+	r.Post("/webhook-subscriptions", h.CreateWebhookSubscription)
+	r.Get("/webhook-subscriptions", h.ListWebhookSubscriptions)
+	r.Get("/webhook-subscriptions/{id}", h.GetWebhookSubscription)
+	r.Delete("/webhook-subscriptions/{id}", h.DeleteWebhookSubscription)
+	r.Get("/webhook-subscriptions/{id}/deliveries", h.ListWebhookDeliveries)
+	r.Post("/webhook-deliveries/{id}/redeliver", h.RedeliverWebhook)
+}
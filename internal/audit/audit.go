@@ -0,0 +1,38 @@
+// Package audit carries where a request came from in its context: the
+// interface (REST API or MCP) and the request ID. The service layer copies
+// both into the audit log entries of the operations it performs. Contexts
+// without them, such as scheduler ticks and event dispatch, are recorded as
+// coming from the system.
+package audit
+
+import (
+	"context"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+type sourceKey struct{}
+
+type requestIDKey struct{}
+
+// WithRequest returns a copy of ctx recording that it serves the request
+// requestID, which came in through source.
+func WithRequest(ctx context.Context, source domain.AuditSource, requestID string) context.Context {
+	ctx = context.WithValue(ctx, sourceKey{}, source)
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// SourceFrom returns the interface ctx's request came through, or
+// domain.AuditSourceSystem when it carries none.
+func SourceFrom(ctx context.Context) domain.AuditSource {
+	if s, ok := ctx.Value(sourceKey{}).(domain.AuditSource); ok {
+		return s
+	}
+	return domain.AuditSourceSystem
+}
+
+// RequestIDFrom returns the ID of ctx's request, if any.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,28 @@
+// Package auth verifies bearer JWTs issued by an external identity provider
+// against its JSON Web Key Set, and carries the authenticated principal of a
+// request in its context.
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// ErrInvalidToken is returned when a JWT is malformed, has a bad signature,
+// or fails its issuer, audience or time checks.
+var ErrInvalidToken = errors.New("invalid token")
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p domain.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal carried by ctx, if any.
+func PrincipalFrom(ctx context.Context) (domain.Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(domain.Principal)
+	return p, ok
+}
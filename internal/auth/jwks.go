@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksTTL is how long keys fetched from a URL are trusted before the set
+	// is fetched again.
+	jwksTTL = time.Hour
+
+	// jwksMinRefresh rate-limits refetches triggered by unknown key IDs, so
+	// tokens with made-up kids cannot hammer the identity provider.
+	jwksMinRefresh = time.Minute
+)
+
+// KeySet is a JSON Web Key Set loaded from a file or an http(s) URL. Sets
+// loaded from a URL are refetched hourly and when a token names a key ID the
+// set does not contain, so the identity provider can rotate keys.
+type KeySet struct {
+	source string
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewKeySet loads the key set at source, a file path or an http(s) URL.
+func NewKeySet(ctx context.Context, source string) (*KeySet, error) {
+	ks := &KeySet{
+		source: source,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+	if err := ks.load(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) remote() bool {
+	return strings.HasPrefix(ks.source, "https://") || strings.HasPrefix(ks.source, "http://")
+}
+
+// Key returns the public key with the given ID. An empty kid matches the
+// only key of a single-key set.
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.remote() && ks.now().Sub(ks.fetchedAt) > jwksTTL {
+		if err := ks.loadLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	if ks.remote() && ks.now().Sub(ks.fetchedAt) > jwksMinRefresh {
+		if err := ks.loadLocked(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := ks.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+func (ks *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (ks *KeySet) load(ctx context.Context) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.loadLocked(ctx)
+}
+
+func (ks *KeySet) loadLocked(ctx context.Context) error {
+	var data []byte
+	var err error
+	if ks.remote() {
+		data, err = ks.fetch(ctx)
+	} else {
+		data, err = os.ReadFile(ks.source)
+	}
+	if err != nil {
+		return fmt.Errorf("load JWKS %s: %w", ks.source, err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("load JWKS %s: %w", ks.source, err)
+	}
+	ks.keys, ks.fetchedAt = keys, ks.now()
+	return nil
+}
+
+func (ks *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// jwk is one key of a JSON Web Key Set (RFC 7517). Only RSA and EC
+// signature keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode key set: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA or EC signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, or returns nil for key types that cannot verify
+// supported signatures.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid base64url value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // hashes for the supported algorithms
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far token times may be off from the local clock.
+const clockSkew = time.Minute
+
+// Claims are the verified claims of a JWT that Infraplane uses.
+type Claims struct {
+	Subject   string
+	Name      string // "name", falling back to "email" then the subject
+	Issuer    string
+	Audience  []string
+	Scopes    []string // "scope" (space-separated) or "scp"
+	ExpiresAt time.Time
+}
+
+// VerifierConfig configures a Verifier.
+type VerifierConfig struct {
+	JWKS     string // file path or http(s) URL of the identity provider's key set
+	Issuer   string // required "iss"; empty accepts any issuer
+	Audience string // required member of "aud"; empty accepts any audience
+}
+
+// Verifier checks RS256/384/512 and ES256/384/512 signed JWTs against a key
+// set and validates their issuer, audience, expiry and not-before times.
+type Verifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewVerifier creates a Verifier, loading the configured key set.
+func NewVerifier(ctx context.Context, cfg VerifierConfig) (*Verifier, error) {
+	keys, err := NewKeySet(ctx, cfg.JWKS)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{keys: keys, issuer: cfg.Issuer, audience: cfg.Audience, now: time.Now}, nil
+}
+
+// Verify checks token's signature and claims and returns the claims. Every
+// failure wraps ErrInvalidToken.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature encoding", ErrInvalidToken)
+	}
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var raw rawClaims
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	return v.check(raw)
+}
+
+// rawClaims is the JWT payload as sent; "aud" and "scp" may be a string or
+// an array.
+type rawClaims struct {
+	Sub   string       `json:"sub"`
+	Iss   string       `json:"iss"`
+	Aud   stringOrList `json:"aud"`
+	Exp   *json.Number `json:"exp"`
+	Nbf   *json.Number `json:"nbf"`
+	Scope string       `json:"scope"`
+	Scp   stringOrList `json:"scp"`
+	Name  string       `json:"name"`
+	Email string       `json:"email"`
+}
+
+func (v *Verifier) check(raw rawClaims) (Claims, error) {
+	now := v.now()
+	if raw.Sub == "" {
+		return Claims{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	if raw.Exp == nil {
+		return Claims{}, fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	}
+	exp, err := numericDate(*raw.Exp)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: exp: %v", ErrInvalidToken, err)
+	}
+	if !now.Before(exp.Add(clockSkew)) {
+		return Claims{}, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if raw.Nbf != nil {
+		nbf, err := numericDate(*raw.Nbf)
+		if err != nil {
+			return Claims{}, fmt.Errorf("%w: nbf: %v", ErrInvalidToken, err)
+		}
+		if now.Add(clockSkew).Before(nbf) {
+			return Claims{}, fmt.Errorf("%w: not yet valid", ErrInvalidToken)
+		}
+	}
+	if v.issuer != "" && raw.Iss != v.issuer {
+		return Claims{}, fmt.Errorf("%w: issuer %q", ErrInvalidToken, raw.Iss)
+	}
+	if v.audience != "" && !contains(raw.Aud, v.audience) {
+		return Claims{}, fmt.Errorf("%w: audience %v", ErrInvalidToken, []string(raw.Aud))
+	}
+
+	scopes := strings.Fields(raw.Scope)
+	scopes = append(scopes, raw.Scp...)
+	name := raw.Name
+	if name == "" {
+		name = raw.Email
+	}
+	if name == "" {
+		name = raw.Sub
+	}
+	return Claims{
+		Subject:   raw.Sub,
+		Name:      name,
+		Issuer:    raw.Iss,
+		Audience:  raw.Aud,
+		Scopes:    scopes,
+		ExpiresAt: exp,
+	}, nil
+}
+
+// algorithms maps the supported JWS algorithms to their hash. "none" and
+// the HMAC algorithms are deliberately absent.
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// ecdsaCurveBits is the curve size each ECDSA algorithm requires.
+var ecdsaCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	hash, ok := algorithms[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, sig); err != nil {
+			return fmt.Errorf("bad signature")
+		}
+		return nil
+
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an EC key", alg)
+		}
+		bits := pub.Curve.Params().BitSize
+		if bits != ecdsaCurveBits[alg] {
+			return fmt.Errorf("%s does not match curve %s", alg, pub.Curve.Params().Name)
+		}
+		size := (bits + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("bad signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("bad signature")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func numericDate(n json.Number) (time.Time, error) {
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(f), 0), nil
+}
+
+// stringOrList decodes a JSON string or array of strings.
+type stringOrList []string
+
+func (l *stringOrList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*l = strings.Fields(s)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig",
+		"n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+}
+
+func ecJWK(kid string, pub *ecdsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(pub.X.FillBytes(make([]byte, 32))), "y": b64(pub.Y.FillBytes(make([]byte, 32)))}
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// signJWT builds a token signed with an RSA (RS256) or EC (ES256) key.
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64(sig)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(path, jwksJSON(t, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey)), 0o600)
+
+	v, err := NewVerifier(context.Background(), VerifierConfig{JWKS: path, Issuer: "https://idp.example.com", Audience: "infraplane"})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "user-1", "email": "dev@example.com", "iss": "https://idp.example.com",
+			"aud": []string{"infraplane", "other"}, "exp": now.Add(time.Hour).Unix(), "scope": "read write",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	t.Run("valid RS256", func(t *testing.T) {
+		got, err := v.Verify(context.Background(), signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil)))
+		if err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+		if got.Subject != "user-1" || got.Name != "dev@example.com" || strings.Join(got.Scopes, " ") != "read write" {
+			t.Errorf("claims = %+v", got)
+		}
+	})
+
+	t.Run("valid ES256 with scp list", func(t *testing.T) {
+		token := signJWT(t, "ES256", "ec-1", ecKey, claims(map[string]any{"scope": nil, "scp": []string{"admin"}, "aud": "infraplane"}))
+		got, err := v.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+		if len(got.Scopes) != 1 || got.Scopes[0] != string(domain.ScopeAdmin) {
+			t.Errorf("scopes = %v", got.Scopes)
+		}
+	})
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	invalid := map[string]string{
+		"expired":          signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})),
+		"no expiry":        signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"exp": nil})),
+		"not yet valid":    signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})),
+		"wrong issuer":     signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"iss": "https://evil.example.com"})),
+		"wrong audience":   signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"aud": "someone-else"})),
+		"wrong key":        signJWT(t, "RS256", "rsa-1", otherKey, claims(nil)),
+		"unknown kid":      signJWT(t, "RS256", "rsa-2", rsaKey, claims(nil)),
+		"alg/key mismatch": signJWT(t, "ES256", "rsa-1", ecKey, claims(nil)),
+		"alg none":         b64([]byte(`{"alg":"none","kid":"rsa-1"}`)) + "." + b64([]byte(`{"sub":"x","exp":9999999999}`)) + ".",
+		"not a JWT":        "ipk_abc",
+	}
+	tampered := signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil))
+	parts := strings.Split(tampered, ".")
+	parts[1] = b64([]byte(`{"sub":"admin","exp":9999999999,"iss":"https://idp.example.com","aud":"infraplane"}`))
+	invalid["tampered claims"] = strings.Join(parts, ".")
+
+	for name, token := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestKeySet_RotatesFromURL(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var mu sync.Mutex
+	served := jwksJSON(t, rsaJWK("old", &oldKey.PublicKey))
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		w.Write(served)
+	}))
+	defer srv.Close()
+
+	v, err := NewVerifier(context.Background(), VerifierConfig{JWKS: srv.URL})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	clock := time.Now()
+	v.now = func() time.Time { return clock }
+	v.keys.now = func() time.Time { return clock }
+	claims := map[string]any{"sub": "svc", "exp": clock.Add(time.Hour).Unix()}
+
+	if _, err := v.Verify(context.Background(), signJWT(t, "RS256", "old", oldKey, claims)); err != nil {
+		t.Fatalf("Verify(old key) error = %v", err)
+	}
+
+	// The provider rotates keys. Unknown kids only trigger a refetch once
+	// the minimum refresh interval has passed.
+	mu.Lock()
+	served = jwksJSON(t, rsaJWK("new", &newKey.PublicKey))
+	mu.Unlock()
+	rotated := signJWT(t, "RS256", "new", newKey, claims)
+	if _, err := v.Verify(context.Background(), rotated); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(new key) right after load: error = %v, want ErrInvalidToken", err)
+	}
+	clock = clock.Add(2 * jwksMinRefresh)
+	if _, err := v.Verify(context.Background(), rotated); err != nil {
+		t.Errorf("Verify(new key) after refresh interval: error = %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("fetches = %d, want 2", fetches)
+	}
+}
@@ -0,0 +1,145 @@
+package compliance
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// Violation is a Terraform resource that does not satisfy a rule's
+// configuration requirement.
+type Violation struct {
+	Framework    Framework `json:"framework"`
+	RuleID       string    `json:"rule_id"`
+	Title        string    `json:"title"`
+	ResourceType string    `json:"resource_type"`
+	ResourceName string    `json:"resource_name"`
+	Attribute    string    `json:"attribute"`
+	Expected     string    `json:"expected"`
+	Actual       string    `json:"actual"` // empty when the attribute is not set
+}
+
+var (
+	resourceHeader = regexp.MustCompile(`(?m)^\s*resource\s+"([^"]+)"\s+"([^"]+)"\s*\{`)
+
+	// Only requirements naming a plain attribute path and a literal value
+	// can be checked mechanically; the rest are guidance for the LLM.
+	checkableAttribute = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+	checkableValue     = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// CheckTerraform statically checks generated Terraform against the rules of
+// the given frameworks for a provider. Requirements whose attribute or value
+// is descriptive rather than literal are skipped.
+func (r *Registry) CheckTerraform(frameworks []string, provider domain.CloudProvider, hcl string) []Violation {
+	_, violations := r.CoverTerraform(frameworks, provider, hcl)
+	return violations
+}
+
+// CoverTerraform is CheckTerraform that also returns how many requirements
+// applied to the resources in hcl, so that the share satisfied can be
+// measured.
+func (r *Registry) CoverTerraform(frameworks []string, provider domain.CloudProvider, hcl string) (checked int, violations []Violation) {
+	type requirement struct {
+		rule Rule
+		tc   TerraformConfig
+	}
+	var checks []requirement
+	for _, rule := range r.GetRules(frameworks) {
+		if rule.Provider != provider {
+			continue
+		}
+		for _, tc := range rule.TerraformConfigs {
+			if checkableAttribute.MatchString(tc.Attribute) && checkableValue.MatchString(tc.Value) {
+				checks = append(checks, requirement{rule, tc})
+			}
+		}
+	}
+	if len(checks) == 0 {
+		return 0, nil
+	}
+
+	for _, m := range resourceHeader.FindAllStringSubmatchIndex(hcl, -1) {
+		resType, resName := hcl[m[2]:m[3]], hcl[m[4]:m[5]]
+		body := blockBody(hcl[m[1]:])
+
+		for _, c := range checks {
+			if c.tc.ResourceType != resType {
+				continue
+			}
+			checked++
+			actual, _ := lookupAttribute(body, strings.Split(c.tc.Attribute, "."))
+			if strings.EqualFold(actual, c.tc.Value) {
+				continue
+			}
+			violations = append(violations, Violation{
+				Framework:    c.rule.Framework,
+				RuleID:       c.rule.ID,
+				Title:        c.rule.Title,
+				ResourceType: resType,
+				ResourceName: resName,
+				Attribute:    c.tc.Attribute,
+				Expected:     c.tc.Value,
+				Actual:       actual,
+			})
+		}
+	}
+	return checked, violations
+}
+
+// blockBody returns the text up to the brace closing a block whose opening
+// brace has just been consumed.
+func blockBody(s string) string {
+	depth := 1
+	inString := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return s[:i]
+			}
+		}
+	}
+	return s
+}
+
+// lookupAttribute follows path through nested blocks (or map literals) in a
+// block body and returns the unquoted value of the final attribute.
+func lookupAttribute(body string, path []string) (string, bool) {
+	key := regexp.QuoteMeta(path[0])
+	if len(path) == 1 {
+		re := regexp.MustCompile(`(?m)^\s*"?` + key + `"?\s*=\s*(.+?)\s*$`)
+		for _, m := range re.FindAllStringSubmatchIndex(body, -1) {
+			if topLevel(body, m[0]) {
+				return strings.Trim(body[m[2]:m[3]], `"`), true
+			}
+		}
+		return "", false
+	}
+
+	re := regexp.MustCompile(`(?m)^\s*` + key + `\s*=?\s*\{`)
+	for _, m := range re.FindAllStringIndex(body, -1) {
+		if !topLevel(body, m[0]) {
+			continue
+		}
+		if v, ok := lookupAttribute(blockBody(body[m[1]:]), path[1:]); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// topLevel reports whether offset i of body is outside any nested block.
+func topLevel(body string, i int) bool {
+	prefix := body[:i]
+	return strings.Count(prefix, "{") == strings.Count(prefix, "}")
+}
@@ -253,3 +253,77 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+func TestCheckTerraform(t *testing.T) {
+	reg := NewRegistry()
+	frameworks := []string{string(FrameworkCISGCP)}
+
+	hcl := `
+resource "google_sql_database_instance" "orders" {
+  name             = "orders-db"
+  database_version = "POSTGRES_15"
+
+  settings {
+    tier = "db-f1-micro"
+
+    ip_configuration {
+      ipv4_enabled = true
+      require_ssl  = "true"
+    }
+
+    backup_configuration {
+      enabled                        = true
+      point_in_time_recovery_enabled = true
+    }
+  }
+}
+
+resource "google_compute_network" "vpc" {
+  name                    = "main"
+  auto_create_subnetworks = false
+}
+`
+
+	violations := reg.CheckTerraform(frameworks, domain.ProviderGCP, hcl)
+
+	got := make(map[string]Violation)
+	for _, v := range violations {
+		got[v.RuleID+" "+v.Attribute] = v
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d violations, want 1: %+v", len(violations), violations)
+	}
+	v, ok := got["6.6 settings.ip_configuration.ipv4_enabled"]
+	if !ok {
+		t.Fatalf("missing ipv4_enabled violation: %+v", violations)
+	}
+	if v.ResourceName != "orders" || v.Expected != "false" || v.Actual != "true" {
+		t.Errorf("violation = %+v", v)
+	}
+
+	t.Run("coverage counts applicable requirements", func(t *testing.T) {
+		checked, cv := reg.CoverTerraform(frameworks, domain.ProviderGCP, hcl)
+		if checked != 5 || len(cv) != len(violations) {
+			t.Errorf("CoverTerraform() = %d checked, %d violations; want 5 checked, %d violations", checked, len(cv), len(violations))
+		}
+	})
+
+	t.Run("unset attribute is a violation", func(t *testing.T) {
+		hcl := `resource "google_compute_network" "vpc" {
+  name = "main"
+}`
+		violations := reg.CheckTerraform(frameworks, domain.ProviderGCP, hcl)
+		if len(violations) != 1 || violations[0].Actual != "" {
+			t.Errorf("violations = %+v, want one with empty Actual", violations)
+		}
+	})
+
+	t.Run("other provider or no frameworks", func(t *testing.T) {
+		if v := reg.CheckTerraform(frameworks, domain.ProviderAWS, hcl); len(v) != 0 {
+			t.Errorf("AWS: got %d violations, want 0", len(v))
+		}
+		if v := reg.CheckTerraform(nil, domain.ProviderGCP, hcl); len(v) != 0 {
+			t.Errorf("no frameworks: got %d violations, want 0", len(v))
+		}
+	})
+}
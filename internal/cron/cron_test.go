@@ -0,0 +1,98 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"0 2 * * *", false},
+		{"*/15 9-17 * * mon-fri", false},
+		{"0 0 1,15 jan,jul *", false},
+		{"30 4 * * 7", false},
+		{"@daily", false},
+		{"@Weekly", false},
+		{"* * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+		{"@sometimes", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC) // a Wednesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 31, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2026, 3, 5, 2, 0, 0, 0, time.UTC)},
+		{"45 10 * * *", time.Date(2026, 3, 4, 10, 45, 0, 0, time.UTC)},
+		{"0 0 * * sun", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2026, 3, 4, 10, 40, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match (the 10th or a Friday).
+		{"0 0 10 * fri", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := s.Next(base, time.UTC); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_NextInLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	s, _ := Parse("0 2 * * *")
+
+	// 02:00 New York is 07:00 UTC in winter (EST).
+	got := s.Next(time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), ny)
+	if want := time.Date(2026, 1, 11, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got.UTC(), want)
+	}
+}
+
+func TestSchedule_NextNever(t *testing.T) {
+	s, _ := Parse("0 0 30 2 *")
+	if got := s.Next(time.Now(), time.UTC); !got.IsZero() {
+		t.Errorf("Next() = %v, want zero time", got)
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	s, _ := Parse("* 22-23 * * fri")
+	if !s.Matches(time.Date(2026, 3, 6, 22, 15, 0, 0, time.UTC), time.UTC) {
+		t.Error("expected Friday 22:15 to match")
+	}
+	if s.Matches(time.Date(2026, 3, 6, 21, 59, 0, 0, time.UTC), time.UTC) {
+		t.Error("expected Friday 21:59 not to match")
+	}
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,11 +14,43 @@ const (
 	AppStatusDraft       AppStatus = "draft"
 	AppStatusProvisioned AppStatus = "provisioned"
 	AppStatusDeployed    AppStatus = "deployed"
+	AppStatusDegraded    AppStatus = "degraded"   // last deployment or destroy failed against live infrastructure
+	AppStatusDestroying  AppStatus = "destroying" // a destroy deployment is running
 )
 
+// appTransitions lists the statuses each status may move to. Staying in the
+// same status is always allowed.
+var appTransitions = map[AppStatus][]AppStatus{
+	AppStatusDraft:       {AppStatusProvisioned, AppStatusDeployed},
+	AppStatusProvisioned: {AppStatusDraft, AppStatusDeployed, AppStatusDegraded, AppStatusDestroying},
+	AppStatusDeployed:    {AppStatusProvisioned, AppStatusDegraded, AppStatusDestroying},
+	AppStatusDegraded:    {AppStatusDeployed, AppStatusDestroying},
+	AppStatusDestroying:  {AppStatusDraft, AppStatusDegraded},
+}
+
+// IsValid checks whether the status is a known lifecycle state.
+func (s AppStatus) IsValid() bool {
+	_, ok := appTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an application may move from s to next.
+func (s AppStatus) CanTransitionTo(next AppStatus) bool {
+	if s == next {
+		return next.IsValid()
+	}
+	for _, allowed := range appTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 // Application represents a registered application in Infraplane.
 type Application struct {
 	ID                   uuid.UUID     `json:"id"`
+	OrgID                uuid.UUID     `json:"org_id"` // tenant; names are unique per organization
 	Name                 string        `json:"name"`
 	Description          string        `json:"description"`
 	GitRepoURL           string        `json:"git_repo_url"`
@@ -25,15 +58,19 @@ type Application struct {
 	Provider             CloudProvider `json:"provider"`
 	Status               AppStatus     `json:"status"`
 	ComplianceFrameworks []string      `json:"compliance_frameworks"`
+	PreventDestroy       bool          `json:"prevent_destroy"`
+	DeployBranch         string        `json:"deploy_branch"` // pushes to this branch of GitRepoURL create deployments; empty disables
 	CreatedAt            time.Time     `json:"created_at"`
 	UpdatedAt            time.Time     `json:"updated_at"`
 }
 
-// NewApplication creates a new Application in draft status.
+// NewApplication creates a new Application in draft status, in the default
+// organization.
 func NewApplication(name, description, gitRepoURL, sourcePath string, provider CloudProvider) Application {
 	now := time.Now().UTC()
 	return Application{
 		ID:          uuid.New(),
+		OrgID:       DefaultOrgID,
 		Name:        name,
 		Description: description,
 		GitRepoURL:  gitRepoURL,
@@ -55,3 +92,17 @@ func (a Application) Validate() error {
 	}
 	return nil
 }
+
+// TransitionTo moves the application to next, returning a ValidationError if
+// the lifecycle does not allow it.
+func (a *Application) TransitionTo(next AppStatus, at time.Time) error {
+	if !next.IsValid() {
+		return ErrValidation("invalid application status: " + string(next))
+	}
+	if !a.Status.CanTransitionTo(next) {
+		return ErrValidation(fmt.Sprintf("application %q cannot move from %s to %s", a.Name, a.Status, next))
+	}
+	a.Status = next
+	a.UpdatedAt = at
+	return nil
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditSource is the interface a mutating operation came through.
+type AuditSource string
+
+const (
+	AuditSourceAPI    AuditSource = "api"
+	AuditSourceMCP    AuditSource = "mcp"
+	AuditSourceSystem AuditSource = "system" // scheduler ticks, event dispatch
+)
+
+// SystemActor is the actor recorded for operations made without a principal.
+const SystemActor = "system"
+
+// AuditEntry records one mutating operation: who did what to which target,
+// and the target as it was before and after. Entries are append-only.
+type AuditEntry struct {
+	ID            uuid.UUID       `json:"id"`
+	OrgID         *uuid.UUID      `json:"org_id,omitempty"`
+	ApplicationID *uuid.UUID      `json:"application_id,omitempty"` // nil for operations outside any application
+	Actor         string          `json:"actor"`                    // API key ID, JWT subject or "system"
+	ActorName     string          `json:"actor_name,omitempty"`
+	Action        string          `json:"action"` // e.g. "resource.remove"
+	TargetType    string          `json:"target_type"`
+	TargetID      string          `json:"target_id"`
+	Before        json.RawMessage `json:"before,omitempty"` // nil for creations
+	After         json.RawMessage `json:"after,omitempty"`  // nil for deletions
+	RequestID     string          `json:"request_id,omitempty"`
+	Source        AuditSource     `json:"source"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// NewAuditEntry creates an audit entry for action on a target, marshalling
+// before and after. A nil before or after is left empty.
+func NewAuditEntry(action, targetType, targetID string, appID *uuid.UUID, before, after any) (AuditEntry, error) {
+	e := AuditEntry{
+		ID:            uuid.New(),
+		ApplicationID: appID,
+		Actor:         SystemActor,
+		Action:        action,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		Source:        AuditSourceSystem,
+		CreatedAt:     time.Now().UTC(),
+	}
+	var err error
+	if e.Before, err = marshalAuditState(before); err != nil {
+		return AuditEntry{}, fmt.Errorf("marshal %s audit state: %w", action, err)
+	}
+	if e.After, err = marshalAuditState(after); err != nil {
+		return AuditEntry{}, fmt.Errorf("marshal %s audit state: %w", action, err)
+	}
+	return e, nil
+}
+
+func marshalAuditState(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// AuditFilter selects audit entries. Zero fields match everything; From is
+// inclusive and To exclusive. Limit caps the result, newest first, when
+// positive.
+type AuditFilter struct {
+	ApplicationID *uuid.UUID
+	From          time.Time
+	To            time.Time
+	Limit         int
+}
+
+// Matches reports whether e passes the filter's application and time range.
+func (f AuditFilter) Matches(e AuditEntry) bool {
+	if f.ApplicationID != nil && (e.ApplicationID == nil || *e.ApplicationID != *f.ApplicationID) {
+		return false
+	}
+	if !f.From.IsZero() && e.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scope limits what an API caller may do. Each scope includes the ones
+// below it: admin ⊃ write ⊃ read.
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"  // read applications, plans, deployments, ...
+	ScopeWrite Scope = "write" // change them and run deployments
+	ScopeAdmin Scope = "admin" // manage API keys
+)
+
+// ValidScopes returns all scopes, weakest first.
+func ValidScopes() []Scope {
+	return []Scope{ScopeRead, ScopeWrite, ScopeAdmin}
+}
+
+// IsValid checks whether the scope is supported.
+func (s Scope) IsValid() bool {
+	return s.rank() > 0
+}
+
+// Includes reports whether holding s grants other.
+func (s Scope) Includes(other Scope) bool {
+	return s.IsValid() && s.rank() >= other.rank()
+}
+
+func (s Scope) rank() int {
+	for i, valid := range ValidScopes() {
+		if s == valid {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// APIKeyPrefix starts every API key, so keys are recognisable in configs and
+// secret scanners and can be told apart from JWTs.
+const APIKeyPrefix = "ipk_"
+
+// APIKey authenticates a machine client of one organization. Only the
+// SHA-256 hash of the key is stored; the key itself is shown once, when it is
+// created.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	OrgID      uuid.UUID  `json:"org_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the key, to identify it in listings
+	Hash       string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil never expires
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewAPIKey creates the record for key, storing only its hash.
+func NewAPIKey(name, key string, scopes []Scope, orgID uuid.UUID, expiresAt *time.Time) APIKey {
+	prefix := key
+	if len(prefix) > len(APIKeyPrefix)+8 {
+		prefix = prefix[:len(APIKeyPrefix)+8]
+	}
+	return APIKey{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    prefix,
+		Hash:      HashAPIKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Validate checks the key's name, scopes and expiry.
+func (k APIKey) Validate() error {
+	if k.Name == "" {
+		return ErrValidation("API key name is required")
+	}
+	if len(k.Scopes) == 0 {
+		return ErrValidation("API key needs at least one scope")
+	}
+	for _, s := range k.Scopes {
+		if !s.IsValid() {
+			return ErrValidation("invalid scope: " + string(s) + " (use read, write or admin)")
+		}
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(k.CreatedAt) {
+		return ErrValidation("API key expiry must be in the future")
+	}
+	return nil
+}
+
+// Active reports whether the key can authenticate at now.
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 digest of an API key. Keys are
+// long random strings, so a fast hash is enough to make a leaked table
+// useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// PrincipalKind says how a principal authenticated.
+type PrincipalKind string
+
+const (
+	PrincipalAPIKey    PrincipalKind = "api_key"
+	PrincipalUser      PrincipalKind = "user" // bearer JWT from the identity provider
+	PrincipalAnonymous PrincipalKind = "anonymous"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Kind    PrincipalKind `json:"kind"`
+	Subject string        `json:"subject"` // API key ID or JWT subject
+	Name    string        `json:"name"`
+	Scopes  []Scope       `json:"scopes"`
+
+	// OrgID is the organization of an API key's principal, which MCP tool
+	// calls made with the key are scoped to. It is nil for other principals.
+	OrgID *uuid.UUID `json:"org_id,omitempty"`
+
+	// Superuser principals hold every role on every application without
+	// role bindings: the bootstrap key and anonymous local development.
+	Superuser bool `json:"superuser,omitempty"`
+}
+
+// Can reports whether the principal holds a scope that includes required.
+func (p Principal) Can(required Scope) bool {
+	for _, s := range p.Scopes {
+		if s.Includes(required) {
+			return true
+		}
+	}
+	return false
+}
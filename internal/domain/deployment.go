@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,18 +18,35 @@ const (
 	DeploymentFailed     DeploymentStatus = "failed"
 )
 
+// DeploymentType distinguishes deployments that create or update
+// infrastructure from those that tear it down.
+type DeploymentType string
+
+const (
+	DeploymentTypeApply   DeploymentType = "apply"
+	DeploymentTypeDestroy DeploymentType = "destroy"
+)
+
 // Deployment represents a deployment event for an application.
 type Deployment struct {
 	ID            uuid.UUID        `json:"id"`
 	ApplicationID uuid.UUID        `json:"application_id"`
+	Type          DeploymentType   `json:"type"`
 	PlanID        *uuid.UUID       `json:"plan_id,omitempty"`
+	RollbackOf    *uuid.UUID       `json:"rollback_of,omitempty"`
+	BreakGlass    bool             `json:"break_glass,omitempty"` // deployed with freeze windows overridden
 	Provider      CloudProvider    `json:"provider"`
 	GitCommit     string           `json:"git_commit"`
 	GitBranch     string           `json:"git_branch"`
 	Status        DeploymentStatus `json:"status"`
-	TerraformPlan string           `json:"terraform_plan,omitempty"`
+	TerraformPlan string           `json:"terraform_plan,omitempty"` // HCL that was (or will be) applied
+	ConfigHash    string           `json:"config_hash,omitempty"`    // SHA-256 of TerraformPlan, set when it is applied
+	ApplyOutput   string           `json:"apply_output,omitempty"`   // provider output from terraform apply/destroy
+	FailureReason string           `json:"failure_reason,omitempty"`
+	Resources     []Resource       `json:"resources,omitempty"` // resource set the HCL was generated from (the plan snapshot when PlanID is set)
 	StartedAt     time.Time        `json:"started_at"`
 	CompletedAt   *time.Time       `json:"completed_at,omitempty"`
+	DurationMs    int64            `json:"duration_ms"`
 }
 
 // NewDeployment creates a new pending deployment.
@@ -35,6 +54,7 @@ func NewDeployment(appID uuid.UUID, provider CloudProvider, gitCommit, gitBranch
 	return Deployment{
 		ID:            uuid.New(),
 		ApplicationID: appID,
+		Type:          DeploymentTypeApply,
 		PlanID:        planID,
 		Provider:      provider,
 		GitCommit:     gitCommit,
@@ -44,6 +64,42 @@ func NewDeployment(appID uuid.UUID, provider CloudProvider, gitCommit, gitBranch
 	}
 }
 
+// NewRollbackDeployment creates a pending deployment that re-applies the exact
+// Terraform configuration (and linked plan) of a previous deployment.
+func NewRollbackDeployment(source Deployment) Deployment {
+	d := NewDeployment(source.ApplicationID, source.Provider, source.GitCommit, source.GitBranch, source.PlanID)
+	d.TerraformPlan = source.TerraformPlan
+	d.Resources = source.Resources
+	d.RollbackOf = &source.ID
+	return d
+}
+
+// NewDestroyDeployment creates a pending deployment that tears down the
+// infrastructure described by the Terraform configuration of source, which
+// should be the application's most recent succeeded apply.
+func NewDestroyDeployment(source Deployment) Deployment {
+	d := NewDeployment(source.ApplicationID, source.Provider, source.GitCommit, source.GitBranch, source.PlanID)
+	d.Type = DeploymentTypeDestroy
+	d.TerraformPlan = source.TerraformPlan
+	d.Resources = source.Resources
+	return d
+}
+
+// Finish moves the deployment to a terminal status at the given time and
+// records how long it ran since StartedAt.
+func (d *Deployment) Finish(status DeploymentStatus, at time.Time) {
+	d.Status = status
+	d.CompletedAt = &at
+	d.DurationMs = at.Sub(d.StartedAt).Milliseconds()
+}
+
+// Summary returns a copy of the deployment without its Terraform, apply
+// output and resource snapshot, for sending outside Infraplane.
+func (d Deployment) Summary() Deployment {
+	d.TerraformPlan, d.ApplyOutput, d.Resources = "", "", nil
+	return d
+}
+
 // DeploymentStep represents a named stage in the deployment pipeline.
 type DeploymentStep string
 
@@ -52,6 +108,7 @@ const (
 	StepGeneratingTerraform DeploymentStep = "generating_terraform"
 	StepValidating          DeploymentStep = "validating"
 	StepApplying            DeploymentStep = "applying"
+	StepDestroying          DeploymentStep = "destroying"
 	StepComplete            DeploymentStep = "complete"
 	StepFailed              DeploymentStep = "failed"
 )
@@ -78,3 +135,26 @@ func (d Deployment) Validate() error {
 	}
 	return nil
 }
+
+// HashConfig returns the hex-encoded SHA-256 digest of a Terraform configuration.
+func HashConfig(hcl string) string {
+	sum := sha256.Sum256([]byte(hcl))
+	return hex.EncodeToString(sum[:])
+}
+
+// AppStatusAfter returns the status an application in current should move to
+// once d has finished. Successful applies deploy the application and
+// successful destroys return it to draft. Failures degrade an application
+// that already has infrastructure; a failed first deploy leaves it unchanged.
+func (d Deployment) AppStatusAfter(current AppStatus) AppStatus {
+	switch {
+	case d.Status == DeploymentSucceeded && d.Type == DeploymentTypeDestroy:
+		return AppStatusDraft
+	case d.Status == DeploymentSucceeded:
+		return AppStatusDeployed
+	case d.Status == DeploymentFailed && current != AppStatusDraft:
+		return AppStatusDegraded
+	default:
+		return current
+	}
+}
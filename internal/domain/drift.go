@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DriftIssue classifies how a declared resource differs from what is running.
+type DriftIssue string
+
+const (
+	DriftMissing   DriftIssue = "missing"   // no live resource matches the declared one
+	DriftUnhealthy DriftIssue = "unhealthy" // the matching live resource is stopped or in error
+)
+
+// DriftFinding is a declared resource whose live counterpart is absent or
+// not running.
+type DriftFinding struct {
+	Resource string       `json:"resource"`
+	Kind     ResourceKind `json:"kind"`
+	Issue    DriftIssue   `json:"issue"`
+	Detail   string       `json:"detail"`
+}
+
+// DetectDrift compares an application's declared resources with the live
+// resources discovered for it. A live resource matches a declared one when
+// its name equals or contains the declared name (case-insensitively), since
+// providers often prefix or suffix resource names. Live resources with no
+// declared counterpart are not reported.
+func DetectDrift(declared []Resource, live []LiveResource) []DriftFinding {
+	var findings []DriftFinding
+	for _, r := range declared {
+		name := strings.ToLower(r.Name)
+
+		var match *LiveResource
+		for i := range live {
+			if strings.Contains(strings.ToLower(live[i].Name), name) {
+				match = &live[i]
+				break
+			}
+		}
+
+		switch {
+		case match == nil:
+			findings = append(findings, DriftFinding{
+				Resource: r.Name,
+				Kind:     r.Kind,
+				Issue:    DriftMissing,
+				Detail:   fmt.Sprintf("%s %q was not found among live resources", r.Kind, r.Name),
+			})
+		case match.Status == LiveResourceError || match.Status == LiveResourceStopped:
+			findings = append(findings, DriftFinding{
+				Resource: r.Name,
+				Kind:     r.Kind,
+				Issue:    DriftUnhealthy,
+				Detail:   fmt.Sprintf("%s %q is %s", match.ResourceType, match.Name, match.Status),
+			})
+		}
+	}
+	return findings
+}
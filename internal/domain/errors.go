@@ -8,6 +8,30 @@ import (
 var (
 	ErrNotFound = errors.New("not found")
 	ErrConflict = errors.New("already exists")
+
+	// ErrDeploymentInProgress is returned when an application already has a
+	// deployment executing and another one cannot start.
+	ErrDeploymentInProgress = errors.New("a deployment is already in progress")
+
+	// ErrPlanStale is returned when a deployment references a plan whose
+	// resource snapshot no longer matches the application's resources.
+	ErrPlanStale = errors.New("plan is stale")
+
+	// ErrDeploymentFrozen is returned when a deployment falls inside an
+	// active freeze window and break-glass was not requested.
+	ErrDeploymentFrozen = errors.New("deployments are frozen")
+
+	// ErrUnauthenticated is returned when a request carries no valid
+	// credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrForbidden is returned when the caller lacks the role an operation
+	// needs.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrBudgetExceeded is returned when an LLM-backed operation would
+	// exceed the monthly token budget of its organization or application.
+	ErrBudgetExceeded = errors.New("LLM token budget exceeded")
 )
 
 // ValidationError represents a validation failure.
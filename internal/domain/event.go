@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies a change to Infraplane's state that other systems
+// can react to.
+type EventType string
+
+const (
+	EventApplicationRegistered   EventType = "application.registered"
+	EventApplicationDeleted      EventType = "application.deleted"
+	EventResourceAdded           EventType = "resource.added"
+	EventResourceRemoved         EventType = "resource.removed"
+	EventResourceRefined         EventType = "resource.refined"
+	EventPlanGenerated           EventType = "plan.generated"
+	EventDeploymentStatusChanged EventType = "deployment.status_changed"
+	EventLiveDriftDetected       EventType = "drift.detected"
+)
+
+// ValidEventTypes returns all domain event types.
+func ValidEventTypes() []EventType {
+	return []EventType{
+		EventApplicationRegistered, EventApplicationDeleted,
+		EventResourceAdded, EventResourceRemoved, EventResourceRefined,
+		EventPlanGenerated, EventDeploymentStatusChanged,
+		EventLiveDriftDetected,
+	}
+}
+
+// IsValid checks whether the event type is supported.
+func (t EventType) IsValid() bool {
+	for _, valid := range ValidEventTypes() {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a domain event. Data holds the affected entity as it was when
+// the event occurred: the application, resource, plan or deployment summary.
+type Event struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          EventType       `json:"type"`
+	ApplicationID uuid.UUID       `json:"application_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// NewEvent creates an event, marshalling data as its payload.
+func NewEvent(typ EventType, appID uuid.UUID, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal %s event data: %w", typ, err)
+	}
+	return Event{
+		ID:            uuid.New(),
+		Type:          typ,
+		ApplicationID: appID,
+		OccurredAt:    time.Now().UTC(),
+		Data:          raw,
+	}, nil
+}
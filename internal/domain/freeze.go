@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/cron"
+)
+
+// maxFreezeOccurrences bounds how many occurrences of a recurring window are
+// expanded for a single calendar query.
+const maxFreezeOccurrences = 1000
+
+// FreezeRule describes when a freeze window is in effect: either a one-off
+// period (StartsAt to EndsAt) or a recurring one that begins every time Cron
+// fires and lasts DurationMinutes.
+type FreezeRule struct {
+	StartsAt        *time.Time `json:"starts_at,omitempty"`
+	EndsAt          *time.Time `json:"ends_at,omitempty"`
+	Cron            string     `json:"cron,omitempty"` // start times of a recurring window, e.g. "0 17 * * fri"
+	DurationMinutes int        `json:"duration_minutes,omitempty"`
+	Timezone        string     `json:"timezone,omitempty"` // IANA zone Cron is evaluated in; defaults to UTC
+}
+
+// IsRecurring reports whether the rule repeats on a cron schedule.
+func (r FreezeRule) IsRecurring() bool {
+	return r.Cron != ""
+}
+
+// FreezeWindow blocks deployments while it is in effect. A window is global
+// unless scoped to an application and/or an environment.
+type FreezeWindow struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Reason        string     `json:"reason"`
+	OrgID         uuid.UUID  `json:"org_id"`
+	ApplicationID *uuid.UUID `json:"application_id,omitempty"` // nil freezes every application of the organization
+	// Environment limits the freeze to deployments of one git branch (the
+	// branch a deployment targets is its environment); empty means all.
+	Environment string `json:"environment,omitempty"`
+	FreezeRule
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FreezeOccurrence is one period during which a freeze window is in effect.
+type FreezeOccurrence struct {
+	WindowID uuid.UUID `json:"window_id"`
+	Name     string    `json:"name"`
+	Reason   string    `json:"reason"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// NewFreezeWindow creates a new freeze window in the organization orgID.
+func NewFreezeWindow(name, reason string, orgID uuid.UUID, appID *uuid.UUID, environment string, rule FreezeRule) FreezeWindow {
+	if rule.Timezone == "" {
+		rule.Timezone = "UTC"
+	}
+	return FreezeWindow{
+		ID:            uuid.New(),
+		Name:          name,
+		Reason:        reason,
+		OrgID:         orgID,
+		ApplicationID: appID,
+		Environment:   environment,
+		FreezeRule:    rule,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+// Validate checks that the window has a name and exactly one well-formed rule.
+func (w FreezeWindow) Validate() error {
+	if w.Name == "" {
+		return ErrValidation("freeze window name is required")
+	}
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return ErrValidation("invalid timezone: " + w.Timezone)
+	}
+
+	oneOff := w.StartsAt != nil || w.EndsAt != nil
+	switch {
+	case oneOff && w.IsRecurring():
+		return ErrValidation("a freeze window is either one-off (starts_at/ends_at) or recurring (cron), not both")
+	case oneOff:
+		if w.StartsAt == nil || w.EndsAt == nil {
+			return ErrValidation("one-off freeze windows need both starts_at and ends_at")
+		}
+		if !w.EndsAt.After(*w.StartsAt) {
+			return ErrValidation("ends_at must be after starts_at")
+		}
+	case w.IsRecurring():
+		if _, err := cron.Parse(w.Cron); err != nil {
+			return ErrValidation(err.Error())
+		}
+		if w.DurationMinutes <= 0 {
+			return ErrValidation("recurring freeze windows need a positive duration_minutes")
+		}
+	default:
+		return ErrValidation("freeze window needs starts_at/ends_at or cron/duration_minutes")
+	}
+	return nil
+}
+
+// AppliesTo reports whether the window covers deployments of app from
+// branch.
+func (w FreezeWindow) AppliesTo(app Application, branch string) bool {
+	if w.OrgID != app.OrgID || (w.ApplicationID != nil && *w.ApplicationID != app.ID) {
+		return false
+	}
+	return w.Environment == "" || w.Environment == branch
+}
+
+// Occurrences returns the periods of the window that overlap [from, to),
+// in chronological order. Recurring windows are evaluated in their timezone.
+func (w FreezeWindow) Occurrences(from, to time.Time) []FreezeOccurrence {
+	occurrence := func(start, end time.Time) FreezeOccurrence {
+		return FreezeOccurrence{WindowID: w.ID, Name: w.Name, Reason: w.Reason, StartsAt: start.UTC(), EndsAt: end.UTC()}
+	}
+
+	if !w.IsRecurring() {
+		if w.StartsAt == nil || w.EndsAt == nil || !w.StartsAt.Before(to) || !w.EndsAt.After(from) {
+			return nil
+		}
+		return []FreezeOccurrence{occurrence(*w.StartsAt, *w.EndsAt)}
+	}
+
+	expr, err := cron.Parse(w.Cron)
+	if err != nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return nil
+	}
+	duration := time.Duration(w.DurationMinutes) * time.Minute
+
+	var out []FreezeOccurrence
+	// Start one duration early so a period already running at from is included.
+	cursor := from.Add(-duration)
+	for len(out) < maxFreezeOccurrences {
+		start := expr.Next(cursor, loc)
+		if start.IsZero() || !start.Before(to) {
+			break
+		}
+		if end := start.Add(duration); end.After(from) {
+			out = append(out, occurrence(start, end))
+		}
+		cursor = start
+	}
+	return out
+}
+
+// ActiveAt returns the occurrence of the window in effect at t, if any.
+func (w FreezeWindow) ActiveAt(t time.Time) (FreezeOccurrence, bool) {
+	occ := w.Occurrences(t, t.Add(time.Nanosecond))
+	if len(occ) == 0 {
+		return FreezeOccurrence{}, false
+	}
+	return occ[0], true
+}
+
+// FrozenError describes the freeze windows blocking a deployment. It wraps
+// ErrDeploymentFrozen.
+func FrozenError(active []FreezeOccurrence) error {
+	parts := make([]string, len(active))
+	for i, o := range active {
+		parts[i] = fmt.Sprintf("%q until %s", o.Name, o.EndsAt.Format(time.RFC3339))
+		if o.Reason != "" {
+			parts[i] += " (" + o.Reason + ")"
+		}
+	}
+	return fmt.Errorf("%w by freeze window %s; wait for it to end or use break-glass to override",
+		ErrDeploymentFrozen, strings.Join(parts, ", "))
+}
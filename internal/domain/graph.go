@@ -44,6 +44,7 @@ type InfraGraph struct {
 	ApplicationID uuid.UUID   `json:"application_id"`
 	Nodes         []GraphNode `json:"nodes"`
 	Edges         []GraphEdge `json:"edges"`
+	PromptVersion string      `json:"prompt_version,omitempty"` // system prompt that produced it, e.g. "graph@1"
 	CreatedAt     time.Time   `json:"created_at"`
 }
 
@@ -57,5 +57,6 @@ type LiveResource struct {
 type LiveResourceResult struct {
 	Resources []LiveResource `json:"resources"`
 	Errors    []string       `json:"errors,omitempty"` // non-fatal errors (e.g. CLI not found)
+	Drift     []DriftFinding `json:"drift,omitempty"`  // declared resources missing or unhealthy
 	Timestamp time.Time      `json:"timestamp"`
 }
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LLMCacheEntry is a stored LLM response, keyed by a hash of the operation,
+// the model and the normalized prompt that produced it.
+type LLMCacheEntry struct {
+	Key       string          `json:"key"`
+	Operation string          `json:"operation"` // e.g. "GenerateHostingPlan"
+	Model     string          `json:"model"`
+	Response  json.RawMessage `json:"response"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// Expired reports whether the entry is past its TTL at now.
+func (e LLMCacheEntry) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
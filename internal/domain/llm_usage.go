@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LLMUsage records the tokens one LLM-backed operation spent, summed over
+// its attempts, and what they are estimated to cost.
+type LLMUsage struct {
+	ID            uuid.UUID  `json:"id"`
+	OrgID         *uuid.UUID `json:"org_id,omitempty"`
+	ApplicationID *uuid.UUID `json:"application_id,omitempty"` // nil for operations outside any application
+	Operation     string     `json:"operation"`                // e.g. "GenerateHostingPlan"
+	Model         string     `json:"model"`
+	InputTokens   int64      `json:"input_tokens"`
+	OutputTokens  int64      `json:"output_tokens"`
+	LatencyMs     int64      `json:"latency_ms"`
+	CostUSD       float64    `json:"cost_usd"` // estimate from list prices; 0 for unknown models
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// LLMUsageFilter selects usage records. Zero fields match everything; From
+// is inclusive and To exclusive.
+type LLMUsageFilter struct {
+	OrgID         *uuid.UUID
+	ApplicationID *uuid.UUID
+	From          time.Time
+	To            time.Time
+}
+
+// Matches reports whether u passes the filter.
+func (f LLMUsageFilter) Matches(u LLMUsage) bool {
+	if f.OrgID != nil && (u.OrgID == nil || *u.OrgID != *f.OrgID) {
+		return false
+	}
+	if f.ApplicationID != nil && (u.ApplicationID == nil || *u.ApplicationID != *f.ApplicationID) {
+		return false
+	}
+	if !f.From.IsZero() && u.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !u.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// LLMUsageTotals sums the usage of a set of operations.
+type LLMUsageTotals struct {
+	Calls        int64   `json:"calls"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Add adds o to t.
+func (t *LLMUsageTotals) Add(o LLMUsageTotals) {
+	t.Calls += o.Calls
+	t.InputTokens += o.InputTokens
+	t.OutputTokens += o.OutputTokens
+	t.CostUSD += o.CostUSD
+}
+
+// Tokens returns the input and output tokens together, which is what
+// budgets count.
+func (t LLMUsageTotals) Tokens() int64 {
+	return t.InputTokens + t.OutputTokens
+}
+
+// LLMUsageAggregate is the usage of one application on one UTC day.
+type LLMUsageAggregate struct {
+	ApplicationID *uuid.UUID `json:"application_id,omitempty"`
+	Day           time.Time  `json:"day"`
+	LLMUsageTotals
+}
+
+// LLMApplicationUsage is an application's share of a usage report.
+type LLMApplicationUsage struct {
+	ApplicationID   *uuid.UUID `json:"application_id,omitempty"` // nil for operations outside any application
+	ApplicationName string     `json:"application_name,omitempty"`
+	LLMUsageTotals
+}
+
+// LLMDailyUsage is one UTC day of a usage report.
+type LLMDailyUsage struct {
+	Day string `json:"day"` // YYYY-MM-DD
+	LLMUsageTotals
+}
+
+// LLMUsageReport is the usage over a period, in total, per application
+// (most tokens first) and per day (oldest first).
+type LLMUsageReport struct {
+	LLMUsageTotals
+	ByApplication []LLMApplicationUsage `json:"by_application"`
+	ByDay         []LLMDailyUsage       `json:"by_day"`
+}
+
+// LLMBudget caps the tokens an organization, or one of its applications,
+// may spend on LLM calls per calendar month (UTC). Once the budget is spent,
+// LLM-backed operations fail with ErrBudgetExceeded until the month ends.
+type LLMBudget struct {
+	ID            uuid.UUID  `json:"id"`
+	OrgID         uuid.UUID  `json:"org_id"`
+	ApplicationID *uuid.UUID `json:"application_id,omitempty"` // nil caps the whole organization
+	MonthlyTokens int64      `json:"monthly_tokens"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// NewLLMBudget creates a monthly token budget for the organization orgID,
+// or for its application appID when set.
+func NewLLMBudget(orgID uuid.UUID, appID *uuid.UUID, monthlyTokens int64) LLMBudget {
+	now := time.Now().UTC()
+	return LLMBudget{
+		ID:            uuid.New(),
+		OrgID:         orgID,
+		ApplicationID: appID,
+		MonthlyTokens: monthlyTokens,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Validate checks that the budget allows a positive number of tokens.
+func (b LLMBudget) Validate() error {
+	if b.MonthlyTokens <= 0 {
+		return ErrValidation("monthly_tokens must be positive")
+	}
+	return nil
+}
+
+// LLMBudgetStatus is a budget with the tokens spent against it this month.
+type LLMBudgetStatus struct {
+	LLMBudget
+	UsedTokens int64 `json:"used_tokens"`
+}
+
+// MonthStart returns the start of t's calendar month in UTC, when monthly
+// budgets reset.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
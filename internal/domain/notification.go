@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationEvent identifies something that happened which channels can
+// subscribe to.
+type NotificationEvent string
+
+const (
+	EventDeploymentStarted   NotificationEvent = "deployment.started"
+	EventDeploymentSucceeded NotificationEvent = "deployment.succeeded"
+	EventDeploymentFailed    NotificationEvent = "deployment.failed"
+	EventDriftDetected       NotificationEvent = "drift.detected"
+	EventComplianceViolation NotificationEvent = "compliance.violation"
+)
+
+// ValidNotificationEvents returns all events channels can subscribe to.
+func ValidNotificationEvents() []NotificationEvent {
+	return []NotificationEvent{
+		EventDeploymentStarted, EventDeploymentSucceeded, EventDeploymentFailed,
+		EventDriftDetected, EventComplianceViolation,
+	}
+}
+
+// IsValid checks whether the event is supported.
+func (e NotificationEvent) IsValid() bool {
+	for _, valid := range ValidNotificationEvents() {
+		if e == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelKind is the type of sink a notification channel delivers to.
+type ChannelKind string
+
+const (
+	ChannelWebhook ChannelKind = "webhook" // generic JSON POST
+	ChannelSlack   ChannelKind = "slack"   // Slack-compatible incoming webhook
+	ChannelEmail   ChannelKind = "email"   // SMTP
+)
+
+// NotificationChannel is where an application's notifications are sent.
+type NotificationChannel struct {
+	ID            uuid.UUID           `json:"id"`
+	ApplicationID uuid.UUID           `json:"application_id"`
+	Kind          ChannelKind         `json:"kind"`
+	Target        string              `json:"target"`           // webhook URL, or comma-separated email addresses
+	Events        []NotificationEvent `json:"events,omitempty"` // empty subscribes to every event
+	Enabled       bool                `json:"enabled"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// NewNotificationChannel creates a new enabled notification channel.
+func NewNotificationChannel(appID uuid.UUID, kind ChannelKind, target string, events []NotificationEvent) NotificationChannel {
+	return NotificationChannel{
+		ID:            uuid.New(),
+		ApplicationID: appID,
+		Kind:          kind,
+		Target:        strings.TrimSpace(target),
+		Events:        events,
+		Enabled:       true,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+// Validate checks the channel kind, its target and its event subscriptions.
+func (c NotificationChannel) Validate() error {
+	if c.ApplicationID == uuid.Nil {
+		return ErrValidation("application ID is required")
+	}
+	if c.Target == "" {
+		return ErrValidation("notification target is required")
+	}
+
+	switch c.Kind {
+	case ChannelWebhook, ChannelSlack:
+		u, err := url.Parse(c.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrValidation("notification target must be an http(s) URL for " + string(c.Kind) + " channels")
+		}
+	case ChannelEmail:
+		if _, err := mail.ParseAddressList(c.Target); err != nil {
+			return ErrValidation("notification target must be a comma-separated list of email addresses")
+		}
+	default:
+		return ErrValidation("invalid channel kind: " + string(c.Kind) + " (use webhook, slack or email)")
+	}
+
+	for _, e := range c.Events {
+		if !e.IsValid() {
+			return ErrValidation("invalid notification event: " + string(e))
+		}
+	}
+	return nil
+}
+
+// Wants reports whether the channel should receive the event.
+func (c NotificationChannel) Wants(e NotificationEvent) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, sub := range c.Events {
+		if sub == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the channel with the credentials in its target masked.
+// Webhook and Slack URLs carry their token in the path or query, so only
+// the scheme and host are kept; email addresses are left as they are.
+func (c NotificationChannel) Redacted() NotificationChannel {
+	if c.Kind != ChannelWebhook && c.Kind != ChannelSlack {
+		return c
+	}
+	u, err := url.Parse(c.Target)
+	if err != nil || u.Host == "" {
+		c.Target = "***"
+		return c
+	}
+	c.Target = u.Scheme + "://" + u.Host
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		c.Target += "/***"
+	}
+	return c
+}
+
+// Notification is a message about an event, delivered to every channel of
+// the application that subscribes to it.
+type Notification struct {
+	Event           NotificationEvent `json:"event"`
+	ApplicationID   uuid.UUID         `json:"application_id"`
+	ApplicationName string            `json:"application_name"`
+	Subject         string            `json:"subject"`
+	Message         string            `json:"message"`
+	Data            any               `json:"data,omitempty"` // the deployment, drift findings or violations
+	Timestamp       time.Time         `json:"timestamp"`
+}
+
+// DeliveryStatus is the outcome of delivering a notification to a channel.
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// NotificationDelivery records one notification sent to one channel,
+// after all retries.
+type NotificationDelivery struct {
+	ID            uuid.UUID         `json:"id"`
+	ChannelID     uuid.UUID         `json:"channel_id"`
+	ApplicationID uuid.UUID         `json:"application_id"`
+	Event         NotificationEvent `json:"event"`
+	Subject       string            `json:"subject"`
+	Status        DeliveryStatus    `json:"status"`
+	Attempts      int               `json:"attempts"`
+	Error         string            `json:"error,omitempty"` // last attempt's error if delivery failed
+	CreatedAt     time.Time         `json:"created_at"`
+}
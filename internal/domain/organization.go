@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultOrgSlug names the organization that applications registered before
+// multi-tenancy, and requests to the unscoped /api routes, belong to.
+const DefaultOrgSlug = "default"
+
+// DefaultOrgID is the ID of the default organization, created by migration.
+var DefaultOrgID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Organization is a tenant. Applications, and the plans, graphs and
+// deployments that belong to them, are scoped to one organization, and
+// application names are unique within it.
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Slug      string    `json:"slug"` // used in /api/orgs/{org} routes
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewOrganization creates a new Organization. The name defaults to the slug.
+func NewOrganization(slug, name string) Organization {
+	slug = strings.TrimSpace(slug)
+	if name = strings.TrimSpace(name); name == "" {
+		name = slug
+	}
+	return Organization{
+		ID:        uuid.New(),
+		Slug:      slug,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Validate checks the organization's slug.
+func (o Organization) Validate() error {
+	if o.Slug == "" {
+		return ErrValidation("organization slug is required")
+	}
+	if !orgSlugPattern.MatchString(o.Slug) {
+		return ErrValidation("invalid organization slug: " + o.Slug + " (use lowercase letters, digits and hyphens)")
+	}
+	return nil
+}
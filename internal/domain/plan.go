@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,6 +32,9 @@ type InfrastructurePlan struct {
 	Content       string         `json:"content"`
 	Resources     []Resource     `json:"resources"`
 	EstimatedCost *CostEstimate  `json:"estimated_cost,omitempty"`
+	PromptVersion string         `json:"prompt_version,omitempty"` // system prompt that produced it, e.g. "hosting_plan@1"
+	ParentID      *uuid.UUID     `json:"parent_id,omitempty"`      // plan it was refined from
+	Instruction   string         `json:"instruction,omitempty"`    // what the refinement asked for
 	CreatedAt     time.Time      `json:"created_at"`
 }
 
@@ -61,6 +66,29 @@ func NewMigrationPlan(appID uuid.UUID, from, to CloudProvider, content string, r
 	}
 }
 
+// NewRefinedPlan creates the version of parent rewritten by a refinement
+// following instruction. It keeps the parent's type and providers but has no
+// resource snapshot: the resources the refinement describes do not exist
+// yet, so the plan cannot be deployed (see HasSnapshot).
+func NewRefinedPlan(parent InfrastructurePlan, instruction, content string, cost *CostEstimate) InfrastructurePlan {
+	plan := parent
+	plan.ID = uuid.New()
+	plan.Resources = nil
+	plan.Content = content
+	plan.EstimatedCost = cost
+	plan.ParentID = &parent.ID
+	plan.Instruction = instruction
+	plan.PromptVersion = ""
+	plan.CreatedAt = time.Now().UTC()
+	return plan
+}
+
+// HasSnapshot reports whether the plan records the resources it was
+// generated from, which deploying it applies. Refined plans do not.
+func (p InfrastructurePlan) HasSnapshot() bool {
+	return p.ParentID == nil
+}
+
 // Validate checks that the plan has valid required fields.
 func (p InfrastructurePlan) Validate() error {
 	if p.ApplicationID == uuid.Nil {
@@ -76,3 +104,56 @@ func (p InfrastructurePlan) Validate() error {
 	}
 	return nil
 }
+
+// Drift compares the plan's resource snapshot with an application's current
+// resources and describes each resource that was added, removed or changed
+// since the plan was generated. An empty result means the plan is current.
+func (p InfrastructurePlan) Drift(current []Resource) []string {
+	planned := make(map[string]Resource, len(p.Resources))
+	for _, r := range p.Resources {
+		planned[r.ID.String()] = r
+	}
+
+	var drift []string
+	seen := make(map[string]bool, len(current))
+	for _, r := range current {
+		id := r.ID.String()
+		seen[id] = true
+		old, ok := planned[id]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("resource %q added", r.Name))
+		case resourceFingerprint(old) != resourceFingerprint(r):
+			drift = append(drift, fmt.Sprintf("resource %q changed", r.Name))
+		}
+	}
+	for _, r := range p.Resources {
+		if !seen[r.ID.String()] {
+			drift = append(drift, fmt.Sprintf("resource %q removed", r.Name))
+		}
+	}
+	return drift
+}
+
+// resourceFingerprint returns a canonical encoding of the parts of a resource
+// that affect generated Terraform. The spec is decoded first so key order and
+// whitespace differences (e.g. after a JSONB round trip) are ignored.
+func resourceFingerprint(r Resource) string {
+	var spec any
+	if len(r.Spec) > 0 {
+		if err := json.Unmarshal(r.Spec, &spec); err != nil {
+			spec = string(r.Spec)
+		}
+	}
+	mappings := r.ProviderMappings
+	if len(mappings) == 0 {
+		mappings = nil
+	}
+	data, _ := json.Marshal(struct {
+		Kind             ResourceKind
+		Name             string
+		Spec             any
+		ProviderMappings map[CloudProvider]ProviderResource
+	}{r.Kind, r.Name, spec, mappings})
+	return string(data)
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role is what a principal may do to applications. Each role includes the
+// ones below it: admin ⊃ deployer ⊃ editor ⊃ viewer.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // read applications, plans, graphs and deployments
+	RoleEditor   Role = "editor"   // change resources, plans, graphs and schedules
+	RoleDeployer Role = "deployer" // deploy, roll back and destroy
+	RoleAdmin    Role = "admin"    // delete applications, destroy protection, role bindings
+)
+
+// ValidRoles returns all roles, weakest first.
+func ValidRoles() []Role {
+	return []Role{RoleViewer, RoleEditor, RoleDeployer, RoleAdmin}
+}
+
+// IsValid checks whether the role is supported.
+func (r Role) IsValid() bool {
+	return r.rank() > 0
+}
+
+// Includes reports whether holding r grants other.
+func (r Role) Includes(other Role) bool {
+	return r.IsValid() && r.rank() >= other.rank()
+}
+
+func (r Role) rank() int {
+	for i, valid := range ValidRoles() {
+		if r == valid {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// RoleBinding grants a role to a subject, either on one application or, when
+// ApplicationID is nil, on every application of its organization.
+type RoleBinding struct {
+	ID            uuid.UUID  `json:"id"`
+	OrgID         uuid.UUID  `json:"org_id"`
+	Subject       string     `json:"subject"` // API key ID or JWT subject
+	Role          Role       `json:"role"`
+	ApplicationID *uuid.UUID `json:"application_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NewRoleBinding creates a new RoleBinding in the organization orgID, on its
+// application appID or, when nil, on all of its applications.
+func NewRoleBinding(subject string, role Role, orgID uuid.UUID, appID *uuid.UUID) RoleBinding {
+	return RoleBinding{
+		ID:            uuid.New(),
+		OrgID:         orgID,
+		Subject:       strings.TrimSpace(subject),
+		Role:          role,
+		ApplicationID: appID,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+// Validate checks the binding's subject and role.
+func (b RoleBinding) Validate() error {
+	if b.Subject == "" {
+		return ErrValidation("role binding subject is required")
+	}
+	if !b.Role.IsValid() {
+		return ErrValidation("invalid role: " + string(b.Role) + " (use viewer, editor, deployer or admin)")
+	}
+	return nil
+}
+
+// Global reports whether the binding applies to every application of its
+// organization.
+func (b RoleBinding) Global() bool {
+	return b.ApplicationID == nil
+}
+
+// Grants reports whether the binding gives role on the application appID of
+// the organization orgID. A nil appID asks for role on every application of
+// orgID, which only its global bindings give.
+func (b RoleBinding) Grants(role Role, orgID uuid.UUID, appID *uuid.UUID) bool {
+	if b.OrgID != orgID || !b.Role.Includes(role) {
+		return false
+	}
+	return b.Global() || (appID != nil && *b.ApplicationID == *appID)
+}
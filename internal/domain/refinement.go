@@ -0,0 +1,284 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResourceRevision is a version of a resource's definition. Resources are
+// refined in place; the first refinement records the definition it started
+// from as version 1, and each refinement records the result as the next
+// version, linked to the one it was refined from. A definition changed by
+// anything other than a refinement is recorded, with no instruction, before
+// the next refinement of it.
+type ResourceRevision struct {
+	ID            uuid.UUID  `json:"id"`
+	ResourceID    uuid.UUID  `json:"resource_id"`
+	ApplicationID uuid.UUID  `json:"application_id"`
+	Version       int        `json:"version"`
+	ParentID      *uuid.UUID `json:"parent_id,omitempty"`   // revision it was refined from; nil for version 1
+	Instruction   string     `json:"instruction,omitempty"` // what the refinement asked for
+	Resource      Resource   `json:"resource"`              // the definition at this version
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NewResourceRevision records r as the revision following parent, or as
+// version 1 if parent is nil.
+func NewResourceRevision(r Resource, parent *ResourceRevision, instruction string) ResourceRevision {
+	rev := ResourceRevision{
+		ID:            uuid.New(),
+		ResourceID:    r.ID,
+		ApplicationID: r.ApplicationID,
+		Version:       1,
+		Instruction:   instruction,
+		Resource:      r,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if parent != nil {
+		rev.Version = parent.Version + 1
+		rev.ParentID = &parent.ID
+	}
+	return rev
+}
+
+// SameDefinition reports whether a and b define the resource the same way:
+// the same kind, name, spec, provider mappings and prompt version. Spec and
+// mappings are compared as JSON values, so formatting and key order, which
+// change when they are stored, do not count.
+func (a Resource) SameDefinition(b Resource) bool {
+	if a.Kind != b.Kind || a.Name != b.Name || a.PromptVersion != b.PromptVersion {
+		return false
+	}
+	if len(a.ProviderMappings) == 0 && len(b.ProviderMappings) == 0 {
+		return sameJSON(a.Spec, b.Spec)
+	}
+	return sameJSON(a.Spec, b.Spec) && sameJSON(a.ProviderMappings, b.ProviderMappings)
+}
+
+func sameJSON(a, b any) bool {
+	normalize := func(v any) (any, bool) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		var out any
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, false
+		}
+		return out, true
+	}
+	na, okA := normalize(a)
+	nb, okB := normalize(b)
+	return okA && okB && reflect.DeepEqual(na, nb)
+}
+
+// PlanDiff compares a plan with an earlier version of it, usually the plan
+// it was refined from.
+type PlanDiff struct {
+	FromPlanID  uuid.UUID     `json:"from_plan_id"`
+	ToPlanID    uuid.UUID     `json:"to_plan_id"`
+	Instruction string        `json:"instruction,omitempty"` // that produced the later plan
+	Diff        string        `json:"diff"`                  // unified diff of the markdown; empty if unchanged
+	FromCost    *CostEstimate `json:"from_cost,omitempty"`
+	ToCost      *CostEstimate `json:"to_cost,omitempty"`
+}
+
+// DiffPlans compares plan to with plan from.
+func DiffPlans(from, to InfrastructurePlan) PlanDiff {
+	return PlanDiff{
+		FromPlanID:  from.ID,
+		ToPlanID:    to.ID,
+		Instruction: to.Instruction,
+		Diff:        unifiedDiff("plan/"+from.ID.String(), "plan/"+to.ID.String(), from.Content, to.Content),
+		FromCost:    from.EstimatedCost,
+		ToCost:      to.EstimatedCost,
+	}
+}
+
+// ResourceDiff compares two revisions of a resource.
+type ResourceDiff struct {
+	ResourceID  uuid.UUID `json:"resource_id"`
+	FromVersion int       `json:"from_version"`
+	ToVersion   int       `json:"to_version"`
+	Instruction string    `json:"instruction,omitempty"` // that produced the later revision
+	Diff        string    `json:"diff"`                  // unified diff of the definitions; empty if unchanged
+}
+
+// DiffRevisions compares revision to with revision from.
+func DiffRevisions(from, to ResourceRevision) ResourceDiff {
+	return ResourceDiff{
+		ResourceID:  to.ResourceID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Instruction: to.Instruction,
+		Diff: unifiedDiff(fmt.Sprintf("%s@%d", from.Resource.Name, from.Version), fmt.Sprintf("%s@%d", to.Resource.Name, to.Version),
+			resourceText(from.Resource), resourceText(to.Resource)),
+	}
+}
+
+// resourceText renders the parts of a resource a refinement can change as
+// text to diff line by line: JSON indented, Terraform as written rather than
+// as one JSON string.
+func resourceText(r Resource) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "kind: %s\nname: %s\nspec: %s\n", r.Kind, r.Name, indentJSON(r.Spec))
+
+	providers := make([]CloudProvider, 0, len(r.ProviderMappings))
+	for p := range r.ProviderMappings {
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i] < providers[j] })
+	for _, p := range providers {
+		m := r.ProviderMappings[p]
+		config, _ := json.Marshal(m.Config)
+		fmt.Fprintf(&b, "\n%s service: %s\n%s config: %s\n", p, m.ServiceName, p, indentJSON(config))
+		if m.TerraformHCL != "" {
+			fmt.Fprintf(&b, "%s terraform:\n%s\n", p, strings.TrimRight(m.TerraformHCL, "\n"))
+		}
+	}
+	return b.String()
+}
+
+func indentJSON(data []byte) string {
+	if len(data) == 0 {
+		return "{}"
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffLine is a line of a diff: kept (' '), removed ('-') or added ('+').
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff compares b with a line by line and returns the differences in
+// unified diff format, or "" if there are none.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	// Lines of a and b before each diff line, for the hunk headers.
+	aBefore := make([]int, len(lines)+1)
+	bBefore := make([]int, len(lines)+1)
+	for i, l := range lines {
+		aBefore[i+1], bBefore[i+1] = aBefore[i], bBefore[i]
+		if l.op != '+' {
+			aBefore[i+1]++
+		}
+		if l.op != '-' {
+			bBefore[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(lines); {
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		// Extend the hunk over changes whose context would touch.
+		last := first
+		for {
+			for last < len(lines) && lines[last].op != ' ' {
+				last++
+			}
+			next := last
+			for next < len(lines) && lines[next].op == ' ' && next-last < 2*diffContext {
+				next++
+			}
+			if next == len(lines) || lines[next].op == ' ' {
+				break
+			}
+			last = next
+		}
+		lo, hi := max(start, first-diffContext), min(len(lines), last+diffContext)
+
+		aLen, bLen := aBefore[hi]-aBefore[lo], bBefore[hi]-bBefore[lo]
+		aStart, bStart := aBefore[lo], bBefore[lo]
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, l := range lines[lo:hi] {
+			out.WriteByte(l.op)
+			out.WriteString(l.text)
+			out.WriteByte('\n')
+		}
+		start = hi
+	}
+	return out.String()
+}
+
+// diffLines aligns a and b on their longest common subsequence of lines.
+func diffLines(a, b []string) []diffLine {
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	common := make([][]int32, len(a)+1)
+	for i := range common {
+		common[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -56,6 +56,7 @@ type Resource struct {
 	Name            string                           `json:"name"`
 	Spec            json.RawMessage                  `json:"spec"`
 	ProviderMappings map[CloudProvider]ProviderResource `json:"provider_mappings"`
+	PromptVersion   string                           `json:"prompt_version,omitempty"` // system prompt that detected it; empty if added by hand
 	CreatedAt       time.Time                        `json:"created_at"`
 }
 
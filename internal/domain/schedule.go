@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/cron"
+)
+
+// JobKind identifies the operation a schedule runs.
+type JobKind string
+
+const (
+	JobDiscovery   JobKind = "discovery"    // discover live cloud resources
+	JobHostingPlan JobKind = "hosting_plan" // regenerate the hosting plan
+	JobGraph       JobKind = "graph"        // regenerate the topology graph
+	JobDeploy      JobKind = "deploy"       // create and execute a deployment
+)
+
+// IsValid checks whether the job kind is supported.
+func (k JobKind) IsValid() bool {
+	switch k {
+	case JobDiscovery, JobHostingPlan, JobGraph, JobDeploy:
+		return true
+	}
+	return false
+}
+
+// ScheduleRunStatus is the outcome of a schedule's most recent run.
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunSucceeded ScheduleRunStatus = "succeeded"
+	ScheduleRunFailed    ScheduleRunStatus = "failed"
+)
+
+// Schedule runs a job against an application on a recurring cron schedule.
+type Schedule struct {
+	ID            uuid.UUID         `json:"id"`
+	ApplicationID uuid.UUID         `json:"application_id"`
+	Job           JobKind           `json:"job"`
+	CronExpr      string            `json:"cron"`                 // five-field cron expression or descriptor such as "@daily"
+	Timezone      string            `json:"timezone"`             // IANA zone the cron expression is evaluated in
+	GitBranch     string            `json:"git_branch,omitempty"` // branch recorded on deployments created by deploy jobs
+	Enabled       bool              `json:"enabled"`
+	NextRunAt     *time.Time        `json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time        `json:"last_run_at,omitempty"`
+	LastStatus    ScheduleRunStatus `json:"last_status,omitempty"`
+	LastResult    json.RawMessage   `json:"last_result,omitempty"` // job-specific summary, or {"error": ...} on failure
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// NewSchedule creates a new enabled schedule. Call Validate before use;
+// NextRunAt is computed by ScheduleNext.
+func NewSchedule(appID uuid.UUID, job JobKind, cronExpr, timezone, gitBranch string) Schedule {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	now := time.Now().UTC()
+	return Schedule{
+		ID:            uuid.New(),
+		ApplicationID: appID,
+		Job:           job,
+		CronExpr:      cronExpr,
+		Timezone:      timezone,
+		GitBranch:     gitBranch,
+		Enabled:       true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Validate checks that the schedule has the required fields and a parseable
+// cron expression and timezone.
+func (s Schedule) Validate() error {
+	if s.ApplicationID == uuid.Nil {
+		return ErrValidation("application ID is required")
+	}
+	if !s.Job.IsValid() {
+		return ErrValidation("invalid job: " + string(s.Job))
+	}
+	if _, err := cron.Parse(s.CronExpr); err != nil {
+		return ErrValidation(err.Error())
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return ErrValidation("invalid timezone: " + s.Timezone)
+	}
+	if s.Job == JobDeploy && s.GitBranch == "" {
+		return ErrValidation("git branch is required for deploy jobs")
+	}
+	return nil
+}
+
+// ScheduleNext sets NextRunAt to the first time after t the cron expression
+// fires. It clears NextRunAt if the schedule is disabled or never fires.
+func (s *Schedule) ScheduleNext(t time.Time) error {
+	expr, err := cron.Parse(s.CronExpr)
+	if err != nil {
+		return ErrValidation(err.Error())
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return ErrValidation("invalid timezone: " + s.Timezone)
+	}
+
+	s.NextRunAt = nil
+	if !s.Enabled {
+		return nil
+	}
+	if next := expr.Next(t, loc); !next.IsZero() {
+		next = next.UTC()
+		s.NextRunAt = &next
+	}
+	return nil
+}
+
+// Due reports whether an enabled schedule should run at t.
+func (s Schedule) Due(t time.Time) bool {
+	return s.Enabled && s.NextRunAt != nil && !s.NextRunAt.After(t)
+}
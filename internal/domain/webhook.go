@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription delivers domain events to an external HTTP endpoint.
+// Each delivery is signed with the subscription's secret.
+type WebhookSubscription struct {
+	ID            uuid.UUID   `json:"id"`
+	OrgID         uuid.UUID   `json:"org_id"`
+	URL           string      `json:"url"`
+	Secret        string      `json:"secret,omitempty"`         // only returned when the subscription is created
+	Events        []EventType `json:"events,omitempty"`         // empty subscribes to every event
+	ApplicationID *uuid.UUID  `json:"application_id,omitempty"` // nil receives events for every application of the organization
+	Enabled       bool        `json:"enabled"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// NewWebhookSubscription creates a new enabled webhook subscription in the
+// organization orgID.
+func NewWebhookSubscription(rawURL, secret string, events []EventType, orgID uuid.UUID, appID *uuid.UUID) WebhookSubscription {
+	return WebhookSubscription{
+		ID:            uuid.New(),
+		URL:           strings.TrimSpace(rawURL),
+		Secret:        secret,
+		Events:        events,
+		OrgID:         orgID,
+		ApplicationID: appID,
+		Enabled:       true,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+// Validate checks the subscription's URL, secret and event types.
+func (s WebhookSubscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if s.URL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrValidation("webhook URL must be an http(s) URL")
+	}
+	if s.Secret == "" {
+		return ErrValidation("webhook secret is required")
+	}
+	for _, e := range s.Events {
+		if !e.IsValid() {
+			return ErrValidation("invalid event type: " + string(e))
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the subscription should receive the event, whose
+// application belongs to the organization orgID.
+func (s WebhookSubscription) Matches(e Event, orgID uuid.UUID) bool {
+	if !s.Enabled || s.OrgID != orgID {
+		return false
+	}
+	if s.ApplicationID != nil && *s.ApplicationID != e.ApplicationID {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, sub := range s.Events {
+		if sub == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the subscription without its secret.
+func (s WebhookSubscription) Redacted() WebhookSubscription {
+	s.Secret = ""
+	return s
+}
+
+// WebhookDeliveryStatus is where a webhook delivery is in its lifecycle.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"   // waiting for its first attempt or a retry
+	WebhookDelivered WebhookDeliveryStatus = "delivered" // the endpoint returned 2xx
+	WebhookDead      WebhookDeliveryStatus = "dead"      // retries exhausted; kept until redelivered
+)
+
+// IsValid checks whether the delivery status is supported.
+func (s WebhookDeliveryStatus) IsValid() bool {
+	switch s {
+	case WebhookPending, WebhookDelivered, WebhookDead:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to one subscription. Failed attempts
+// are retried at NextAttemptAt until the attempts run out, after which the
+// delivery is dead-lettered.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id"`
+	SubscriptionID uuid.UUID             `json:"subscription_id"`
+	EventID        uuid.UUID             `json:"event_id"`
+	EventType      EventType             `json:"event_type"`
+	Payload        json.RawMessage       `json:"payload"` // the signed request body
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	LastError      string                `json:"last_error,omitempty"`
+	ResponseCode   int                   `json:"response_code,omitempty"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
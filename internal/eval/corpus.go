@@ -0,0 +1,94 @@
+// Package eval measures the quality of LLM output over a corpus of sample
+// repositories with known infrastructure, so that prompt and model changes
+// can be compared run against run.
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// caseFile is the file describing a corpus case; the sample repository sits
+// next to it in repoDir.
+const (
+	caseFile = "case.json"
+	repoDir  = "repo"
+)
+
+// Case is a sample repository and the resources it is known to need.
+type Case struct {
+	Name                 string               `json:"-"` // the case directory's name
+	Description          string               `json:"description"`
+	Provider             domain.CloudProvider `json:"provider"`
+	ComplianceFrameworks []string             `json:"compliance_frameworks,omitempty"`
+	Expected             []ExpectedResource   `json:"expected"`
+
+	dir string
+}
+
+// ExpectedResource is a resource a case's repository should be found to need.
+// The name documents the resource; only its kind is scored.
+type ExpectedResource struct {
+	Kind domain.ResourceKind `json:"kind"`
+	Name string              `json:"name"`
+}
+
+// RepoPath returns the path of the case's sample repository.
+func (c Case) RepoPath() string { return filepath.Join(c.dir, repoDir) }
+
+// LoadCorpus reads the cases in dir, one per subdirectory holding a
+// case.json, ordered by name.
+func LoadCorpus(dir string) ([]Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read corpus: %w", err)
+	}
+	var cases []Case
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		c, err := loadCase(filepath.Join(dir, e.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("corpus %s has no cases", dir)
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+func loadCase(dir string) (Case, error) {
+	data, err := os.ReadFile(filepath.Join(dir, caseFile))
+	if err != nil {
+		return Case{}, err
+	}
+	var c Case
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Case{}, fmt.Errorf("decode %s: %w", filepath.Join(dir, caseFile), err)
+	}
+	c.Name, c.dir = filepath.Base(dir), dir
+	if !c.Provider.IsValid() {
+		return Case{}, fmt.Errorf("case %s: invalid provider %q", c.Name, c.Provider)
+	}
+	for _, r := range c.Expected {
+		if !r.Kind.IsValid() {
+			return Case{}, fmt.Errorf("case %s: invalid resource kind %q", c.Name, r.Kind)
+		}
+	}
+	if info, err := os.Stat(c.RepoPath()); err != nil || !info.IsDir() {
+		return Case{}, fmt.Errorf("case %s: no %s directory", c.Name, repoDir)
+	}
+	return c, nil
+}
@@ -0,0 +1,195 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+)
+
+// Report is the outcome of running the corpus once.
+type Report struct {
+	Model     string            `json:"model"`
+	Prompts   map[string]string `json:"prompts"` // prompt name to the version in use
+	StartedAt time.Time         `json:"started_at"`
+	Cases     []CaseResult      `json:"cases"`
+	Summary   Summary           `json:"summary"`
+}
+
+// CaseResult is the outcome of one corpus case. A case whose codebase
+// analysis fails scores nothing; a failed graph or Terraform generation
+// scores as empty output.
+type CaseResult struct {
+	Name       string                       `json:"name"`
+	Error      string                       `json:"error,omitempty"`
+	Detected   []llm.ResourceRecommendation `json:"detected"`
+	Kinds      KindScore                    `json:"kinds"`
+	GraphError string                       `json:"graph_error,omitempty"`
+	Graph      GraphScore                   `json:"graph"`
+	Terraform  []TerraformScore             `json:"terraform"`
+}
+
+// Summary aggregates the scores of a run over all its cases. Ratios are
+// computed over the pooled counts, so larger cases weigh more.
+type Summary struct {
+	Cases              int     `json:"cases"`
+	Failed             int     `json:"failed"`
+	Precision          float64 `json:"precision"`
+	Recall             float64 `json:"recall"`
+	GraphCoverage      float64 `json:"graph_coverage"`
+	DanglingEdges      int     `json:"dangling_edges"`
+	HCLValidity        float64 `json:"hcl_validity"`
+	ComplianceCoverage float64 `json:"compliance_coverage"`
+}
+
+// Runner runs corpus cases through the LLM operations that turn a codebase
+// into infrastructure: codebase analysis, graph generation and Terraform
+// generation, the same way the services chain them.
+type Runner struct {
+	llm        llm.Client
+	compliance *compliance.Registry
+}
+
+// NewRunner creates a runner sending requests to client. Terraform is
+// checked against the rules in registry for the frameworks each case names.
+func NewRunner(client llm.Client, registry *compliance.Registry) *Runner {
+	return &Runner{llm: client, compliance: registry}
+}
+
+// Run scores every case. Failures are recorded in the report rather than
+// returned, so one broken case does not hide the scores of the others.
+func (r *Runner) Run(ctx context.Context, cases []Case) Report {
+	report := Report{Model: modelOf(r.llm), Prompts: make(map[string]string), StartedAt: time.Now().UTC()}
+	for _, p := range llm.ActivePrompts() {
+		report.Prompts[p.Name] = p.ID()
+	}
+	for _, c := range cases {
+		log.Printf("[eval] %s", c.Name)
+		report.Cases = append(report.Cases, r.runCase(ctx, c))
+	}
+	report.Summary = summarize(report.Cases)
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case) CaseResult {
+	result := CaseResult{Name: c.Name}
+	expected := make([]domain.ResourceKind, len(c.Expected))
+	for i, e := range c.Expected {
+		expected[i] = e.Kind
+	}
+
+	codeCtx, err := analyzer.Analyze(c.RepoPath())
+	if err != nil {
+		result.Error = fmt.Sprintf("analyze repository: %v", err)
+		result.Kinds = scoreKinds(expected, nil)
+		return result
+	}
+
+	recs, err := r.llm.AnalyzeCodebase(ctx, codeCtx, c.Provider)
+	if err != nil {
+		result.Error = err.Error()
+		result.Kinds = scoreKinds(expected, nil)
+		return result
+	}
+	result.Detected = recs
+
+	app := domain.NewApplication(c.Name, c.Description, "", "", c.Provider)
+	app.ComplianceFrameworks = c.ComplianceFrameworks
+	detected := make([]domain.ResourceKind, len(recs))
+	resources := make([]domain.Resource, len(recs))
+	for i, rec := range recs {
+		detected[i] = rec.Kind
+		resources[i] = domain.NewResource(app.ID, rec.Kind, rec.Name, rec.Spec)
+		resources[i].ProviderMappings = rec.Mappings
+	}
+	result.Kinds = scoreKinds(expected, detected)
+
+	graph, err := r.llm.GenerateGraph(ctx, app, resources)
+	if err != nil {
+		result.GraphError = err.Error()
+	}
+	result.Graph = scoreGraph(graph.Nodes, graph.Edges, resources)
+
+	for _, res := range resources {
+		result.Terraform = append(result.Terraform, r.scoreTerraform(ctx, app, res))
+	}
+	return result
+}
+
+// scoreTerraform generates the Terraform of one resource with the compliance
+// context ResourceService gives the model, and checks what comes back.
+func (r *Runner) scoreTerraform(ctx context.Context, app domain.Application, res domain.Resource) TerraformScore {
+	s := TerraformScore{Resource: res.Name, Kind: res.Kind}
+	var complianceContext string
+	if len(app.ComplianceFrameworks) > 0 {
+		var serviceName string
+		if mapping, ok := res.ProviderMappings[app.Provider]; ok {
+			serviceName = mapping.ServiceName
+		}
+		if rules := r.compliance.GetRulesForResource(app.ComplianceFrameworks, app.Provider, res.Kind, serviceName); len(rules) > 0 {
+			complianceContext = r.compliance.FormatRulesForPrompt(rules)
+		}
+	}
+
+	out, err := r.llm.GenerateTerraformHCL(ctx, res, app.Provider, complianceContext)
+	if err != nil {
+		s.Error = err.Error()
+		s.Invalid = "not generated"
+		return s
+	}
+	if err := ValidateHCL(out.HCL); err != nil {
+		s.Invalid = err.Error()
+	} else {
+		s.Valid = true
+	}
+	checked, violations := r.compliance.CoverTerraform(app.ComplianceFrameworks, app.Provider, out.HCL)
+	s.Checked, s.Satisfied = checked, checked-len(violations)
+	for _, v := range violations {
+		s.Violations = append(s.Violations, v.RuleID+" "+v.Attribute)
+	}
+	return s
+}
+
+func summarize(cases []CaseResult) Summary {
+	s := Summary{Cases: len(cases)}
+	var kinds KindScore
+	var graph GraphScore
+	var hcl, valid, checked, satisfied int
+	for _, c := range cases {
+		if c.Error != "" {
+			s.Failed++
+		}
+		kinds.Expected += c.Kinds.Expected
+		kinds.Detected += c.Kinds.Detected
+		kinds.Matched += c.Kinds.Matched
+		graph.Resources += c.Graph.Resources
+		graph.Shown += c.Graph.Shown
+		s.DanglingEdges += c.Graph.DanglingEdges
+		for _, t := range c.Terraform {
+			hcl++
+			if t.Valid {
+				valid++
+			}
+			checked += t.Checked
+			satisfied += t.Satisfied
+		}
+	}
+	s.Precision, s.Recall = kinds.Precision(), kinds.Recall()
+	s.GraphCoverage = graph.Coverage()
+	s.HCLValidity = ratio(valid, hcl)
+	s.ComplianceCoverage = ratio(satisfied, checked)
+	return s
+}
+
+// modelOf names the model behind client, if it says.
+func modelOf(client llm.Client) string {
+	if m, ok := client.(interface{ Model() string }); ok {
+		return m.Model()
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+)
+
+// The sample corpus replays against its recorded fixtures; record them again
+// whenever a prompt changes.
+func TestRunner_SampleCorpus(t *testing.T) {
+	cases, err := LoadCorpus("../../eval/corpus")
+	if err != nil {
+		t.Fatalf("LoadCorpus() error = %v", err)
+	}
+	report := NewRunner(llm.NewReplayClient("../../eval/fixtures"), compliance.NewRegistry()).Run(context.Background(), cases)
+
+	for _, c := range report.Cases {
+		if c.Error != "" {
+			t.Fatalf("case %s: %s", c.Name, c.Error)
+		}
+	}
+	want := Summary{Cases: 2, Precision: 0.875, Recall: 0.875, GraphCoverage: 1, DanglingEdges: 1, HCLValidity: 0.75, ComplianceCoverage: 0.75}
+	if report.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", report.Summary, want)
+	}
+	orders := report.Cases[0]
+	if orders.Name != "orders-api" || len(orders.Kinds.Missing) != 1 || orders.Kinds.Missing[0] != domain.ResourceStorage {
+		t.Errorf("orders-api kinds = %+v, want storage missing", orders.Kinds)
+	}
+	if report.Prompts[llm.PromptGraph] != llm.PromptVersion(llm.PromptGraph) {
+		t.Errorf("Prompts = %v, want the versions in use", report.Prompts)
+	}
+}
+
+func TestScoreKinds(t *testing.T) {
+	s := scoreKinds(
+		[]domain.ResourceKind{domain.ResourceCompute, domain.ResourceCompute, domain.ResourceDatabase},
+		[]domain.ResourceKind{domain.ResourceCompute, domain.ResourceDatabase, domain.ResourceCache},
+	)
+	if s.Matched != 2 || s.Precision() != 2.0/3 || s.Recall() != 2.0/3 {
+		t.Errorf("scoreKinds() = %+v", s)
+	}
+	if len(s.Missing) != 1 || s.Missing[0] != domain.ResourceCompute || len(s.Unexpected) != 1 || s.Unexpected[0] != domain.ResourceCache {
+		t.Errorf("missing %v, unexpected %v", s.Missing, s.Unexpected)
+	}
+	if empty := scoreKinds(nil, nil); empty.Precision() != 1 || empty.Recall() != 1 {
+		t.Errorf("scoreKinds(nil, nil) = %+v, want perfect scores", empty)
+	}
+}
+
+func TestValidateHCL(t *testing.T) {
+	tests := []struct {
+		name, hcl, err string
+	}{
+		{"valid", `# storage
+resource "aws_s3_bucket" "b" {
+  bucket = "b-${var.env}" // suffixed
+  tags   = { Name = "${lookup(var.names, "b", "x")}" }
+  policy = <<-EOT
+    { "Version": "2012-10-17"
+  EOT
+  /* a { comment */
+}
+`, ""},
+		{"fenced", "```hcl\nresource \"a\" \"b\" {}\n```", "Markdown"},
+		{"unclosed block", "resource \"a\" \"b\" {\n  x = 1\n", "line 1: '{' is never closed"},
+		{"mismatched", "resource \"a\" \"b\" {\n  x = [1, 2)\n}", "line 2: ')' does not close '['"},
+		{"unterminated string", "resource \"a\" \"b\" {\n  x = \"open\n}", "line 2: unterminated string"},
+		{"unterminated heredoc", "resource \"a\" \"b\" {\n  x = <<EOT\nbody\n}", "unterminated heredoc EOT"},
+		{"no blocks", "x = 1\n", "no resource"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHCL(tt.hcl)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("ValidateHCL() error = %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("ValidateHCL() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := Report{
+		Prompts: map[string]string{"graph": "graph@1"},
+		Cases: []CaseResult{
+			{Name: "a", Kinds: KindScore{Expected: 2, Detected: 2, Matched: 2}},
+			{Name: "b", Kinds: KindScore{Expected: 1, Detected: 1, Matched: 1}},
+		},
+		Summary: Summary{Precision: 1, Recall: 1, DanglingEdges: 1},
+	}
+	head := Report{
+		Prompts: map[string]string{"graph": "graph@2"},
+		Cases: []CaseResult{
+			{Name: "a", Kinds: KindScore{Expected: 2, Detected: 2, Matched: 1}},
+			{Name: "b", Kinds: KindScore{Expected: 1, Detected: 1, Matched: 1}},
+		},
+		Summary: Summary{Precision: 0.5, Recall: 0.5},
+	}
+
+	c := Compare(base, head)
+	if !c.Regressed() {
+		t.Error("Regressed() = false, want true")
+	}
+	if len(c.Cases) != 1 || c.Cases[0].Name != "a" {
+		t.Errorf("Cases = %+v, want only a", c.Cases)
+	}
+	if len(c.Prompts) != 1 || c.Prompts[0] != (PromptChange{"graph", "graph@1", "graph@2"}) {
+		t.Errorf("Prompts = %+v", c.Prompts)
+	}
+	for _, m := range c.Metrics {
+		if m.Metric == "dangling edges" && m.Regressed() {
+			t.Error("fewer dangling edges counted as a regression")
+		}
+	}
+	if Compare(base, base).Regressed() {
+		t.Error("a run compared with itself regressed")
+	}
+
+	var out strings.Builder
+	if err := c.Format(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "graph@1") || !strings.Contains(out.String(), "worse") {
+		t.Errorf("Format() =\n%s", out.String())
+	}
+}
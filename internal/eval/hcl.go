@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	topLevelBlock  = regexp.MustCompile(`(?m)^\s*(resource|data|module)\s+"`)
+	heredocMarker  = regexp.MustCompile(`^<<-?([A-Za-z_][A-Za-z0-9_]*)[ \t]*\r?\n`)
+	closingBracket = map[byte]byte{')': '(', ']': '[', '}': '{'}
+)
+
+// ValidateHCL checks that generated Terraform is structurally sound: no
+// Markdown fences, every string, heredoc, comment and bracket closed, and at
+// least one resource, data or module block. It is no substitute for
+// terraform validate, but catches the ways model output usually breaks,
+// without needing Terraform or provider plugins.
+func ValidateHCL(src string) error {
+	if strings.TrimSpace(src) == "" {
+		return errors.New("empty")
+	}
+	if strings.Contains(src, "```") {
+		return errors.New("contains a Markdown code fence")
+	}
+
+	// Open brackets, strings ('"') and string interpolations ('$'), with
+	// the line each was opened on.
+	type open struct {
+		c    byte
+		line int
+	}
+	var stack []open
+	top := func() byte {
+		if len(stack) == 0 {
+			return 0
+		}
+		return stack[len(stack)-1].c
+	}
+	line := 1
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if c == '\n' {
+			line++
+		}
+
+		if top() == '"' {
+			switch {
+			case c == '\\':
+				i++
+			case c == '"':
+				stack = stack[:len(stack)-1]
+			case c == '\n':
+				return fmt.Errorf("line %d: unterminated string", line-1)
+			case strings.HasPrefix(src[i:], "${"), strings.HasPrefix(src[i:], "%{"):
+				stack = append(stack, open{'$', line})
+				i++
+			}
+			continue
+		}
+
+		switch c {
+		case '#':
+			i = endOfLine(src, i) - 1
+		case '/':
+			switch {
+			case strings.HasPrefix(src[i:], "//"):
+				i = endOfLine(src, i) - 1
+			case strings.HasPrefix(src[i:], "/*"):
+				end := strings.Index(src[i+2:], "*/")
+				if end < 0 {
+					return fmt.Errorf("line %d: unterminated comment", line)
+				}
+				line += strings.Count(src[i:i+2+end], "\n")
+				i += end + 3
+			}
+		case '"':
+			stack = append(stack, open{'"', line})
+		case '<':
+			m := heredocMarker.FindStringSubmatch(src[i:])
+			if m == nil {
+				continue
+			}
+			start := line
+			i += len(m[0])
+			line++
+			closed := false
+			for i < len(src) {
+				eol := endOfLine(src, i)
+				if strings.TrimSpace(src[i:eol]) == m[1] {
+					closed = true
+					i = eol - 1
+					break
+				}
+				line++
+				i = eol + 1
+			}
+			if !closed {
+				return fmt.Errorf("line %d: unterminated heredoc %s", start, m[1])
+			}
+		case '(', '[', '{':
+			stack = append(stack, open{c, line})
+		case ')', ']', '}':
+			switch want := closingBracket[c]; {
+			case c == '}' && top() == '$':
+				stack = stack[:len(stack)-1]
+			case top() == want:
+				stack = stack[:len(stack)-1]
+			case len(stack) == 0:
+				return fmt.Errorf("line %d: unexpected %q", line, c)
+			default:
+				o := stack[len(stack)-1]
+				return fmt.Errorf("line %d: %q does not close %q opened on line %d", line, c, o.c, o.line)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		o := stack[len(stack)-1]
+		if o.c == '"' {
+			return fmt.Errorf("line %d: unterminated string", o.line)
+		}
+		return fmt.Errorf("line %d: %q is never closed", o.line, o.c)
+	}
+	if !topLevelBlock.MatchString(src) {
+		return errors.New("no resource, data or module block")
+	}
+	return nil
+}
+
+// endOfLine returns the index of the newline ending the line that contains
+// index i, or len(s) on the last line.
+func endOfLine(s string, i int) int {
+	if n := strings.IndexByte(s[i:], '\n'); n >= 0 {
+		return i + n
+	}
+	return len(s)
+}
@@ -0,0 +1,274 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// LoadReport reads a report written by WriteReport.
+func LoadReport(path string) (Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Report{}, fmt.Errorf("read report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Report{}, fmt.Errorf("decode report %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// WriteReport writes r to path as JSON.
+func WriteReport(path string, r Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// Format writes a human-readable summary of r: a line per case, then the
+// totals.
+func (r Report) Format(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "model %s, %d cases\n\n", orUnknown(r.Model), r.Summary.Cases)
+	fmt.Fprintln(tw, "case\tprecision\trecall\tgraph\tvalid hcl\tcompliance\tnotes")
+	for _, c := range r.Cases {
+		var valid, hcl, checked, satisfied int
+		for _, t := range c.Terraform {
+			hcl++
+			if t.Valid {
+				valid++
+			}
+			checked += t.Checked
+			satisfied += t.Satisfied
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d/%d\t%d/%d\t%s\n", c.Name,
+			percent(c.Kinds.Precision()), percent(c.Kinds.Recall()), percent(c.Graph.Coverage()),
+			valid, hcl, satisfied, checked, caseNotes(c))
+	}
+	s := r.Summary
+	fmt.Fprintf(tw, "total\t%s\t%s\t%s\t%s\t%s\t%d failed, %d dangling edges\n",
+		percent(s.Precision), percent(s.Recall), percent(s.GraphCoverage),
+		percent(s.HCLValidity), percent(s.ComplianceCoverage), s.Failed, s.DanglingEdges)
+	return tw.Flush()
+}
+
+// caseNotes lists what went wrong in a case, briefly.
+func caseNotes(c CaseResult) string {
+	if c.Error != "" {
+		return firstLine(c.Error)
+	}
+	var notes []string
+	if len(c.Kinds.Missing) > 0 {
+		notes = append(notes, fmt.Sprintf("missing %v", c.Kinds.Missing))
+	}
+	if len(c.Kinds.Unexpected) > 0 {
+		notes = append(notes, fmt.Sprintf("unexpected %v", c.Kinds.Unexpected))
+	}
+	if c.GraphError != "" {
+		notes = append(notes, "graph: "+firstLine(c.GraphError))
+	}
+	for _, t := range c.Terraform {
+		if !t.Valid {
+			notes = append(notes, fmt.Sprintf("%s: %s", t.Resource, t.Invalid))
+		}
+	}
+	return strings.Join(notes, "; ")
+}
+
+// Comparison sets a run's scores against those of a baseline run.
+type Comparison struct {
+	Base, Head Report
+	Metrics    []MetricDelta
+	Cases      []CaseDelta
+	Prompts    []PromptChange
+}
+
+// MetricDelta is a summary metric in both runs. Higher is better, except
+// for the counts of dangling edges and failed cases.
+type MetricDelta struct {
+	Metric     string
+	Base, Head float64
+	lowerWins  bool
+}
+
+// Regressed reports whether the metric got worse.
+func (m MetricDelta) Regressed() bool {
+	if m.lowerWins {
+		return m.Head > m.Base
+	}
+	return m.Head < m.Base
+}
+
+// CaseDelta is a case whose scores differ between the runs, or which only
+// one of them ran.
+type CaseDelta struct {
+	Name       string
+	Base, Head *CaseResult // nil for the run without the case
+}
+
+// PromptChange is a prompt whose version differs between the runs.
+type PromptChange struct {
+	Name, Base, Head string
+}
+
+// Compare compares head with base.
+func Compare(base, head Report) Comparison {
+	c := Comparison{Base: base, Head: head}
+	b, h := base.Summary, head.Summary
+	c.Metrics = []MetricDelta{
+		{Metric: "precision", Base: b.Precision, Head: h.Precision},
+		{Metric: "recall", Base: b.Recall, Head: h.Recall},
+		{Metric: "graph coverage", Base: b.GraphCoverage, Head: h.GraphCoverage},
+		{Metric: "dangling edges", Base: float64(b.DanglingEdges), Head: float64(h.DanglingEdges), lowerWins: true},
+		{Metric: "hcl validity", Base: b.HCLValidity, Head: h.HCLValidity},
+		{Metric: "compliance coverage", Base: b.ComplianceCoverage, Head: h.ComplianceCoverage},
+		{Metric: "failed cases", Base: float64(b.Failed), Head: float64(h.Failed), lowerWins: true},
+	}
+
+	baseCases := make(map[string]*CaseResult)
+	for i := range base.Cases {
+		baseCases[base.Cases[i].Name] = &base.Cases[i]
+	}
+	seen := make(map[string]bool)
+	for i := range head.Cases {
+		hc := &head.Cases[i]
+		seen[hc.Name] = true
+		if bc := baseCases[hc.Name]; bc == nil || caseSignature(*bc) != caseSignature(*hc) {
+			c.Cases = append(c.Cases, CaseDelta{Name: hc.Name, Base: bc, Head: hc})
+		}
+	}
+	for i := range base.Cases {
+		if !seen[base.Cases[i].Name] {
+			c.Cases = append(c.Cases, CaseDelta{Name: base.Cases[i].Name, Base: &base.Cases[i]})
+		}
+	}
+	sort.Slice(c.Cases, func(i, j int) bool { return c.Cases[i].Name < c.Cases[j].Name })
+
+	names := make(map[string]bool)
+	for n := range base.Prompts {
+		names[n] = true
+	}
+	for n := range head.Prompts {
+		names[n] = true
+	}
+	for n := range names {
+		if base.Prompts[n] != head.Prompts[n] {
+			c.Prompts = append(c.Prompts, PromptChange{Name: n, Base: base.Prompts[n], Head: head.Prompts[n]})
+		}
+	}
+	sort.Slice(c.Prompts, func(i, j int) bool { return c.Prompts[i].Name < c.Prompts[j].Name })
+	return c
+}
+
+// Regressed reports whether any summary metric got worse.
+func (c Comparison) Regressed() bool {
+	for _, m := range c.Metrics {
+		if m.Regressed() {
+			return true
+		}
+	}
+	return false
+}
+
+// caseSignature condenses the scores of a case, to tell whether they changed.
+func caseSignature(c CaseResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%d/%d/%d|%d/%d/%d", c.Error, c.Kinds.Matched, c.Kinds.Detected, c.Kinds.Expected,
+		c.Graph.Shown, c.Graph.Resources, c.Graph.DanglingEdges)
+	for _, t := range c.Terraform {
+		fmt.Fprintf(&b, "|%s:%t:%d/%d", t.Resource, t.Valid, t.Satisfied, t.Checked)
+	}
+	return b.String()
+}
+
+// Format writes the comparison: changed prompts, the summary metrics side
+// by side, and the cases whose scores moved.
+func (c Comparison) Format(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "base: model %s, %s\nhead: model %s, %s\n\n",
+		orUnknown(c.Base.Model), c.Base.StartedAt.Format("2006-01-02 15:04"),
+		orUnknown(c.Head.Model), c.Head.StartedAt.Format("2006-01-02 15:04"))
+
+	if len(c.Prompts) > 0 {
+		fmt.Fprintln(tw, "prompt\tbase\thead")
+		for _, p := range c.Prompts {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, orNone(p.Base), orNone(p.Head))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	fmt.Fprintln(tw, "metric\tbase\thead\tdelta\t")
+	for _, m := range c.Metrics {
+		base, head, delta := percent(m.Base), percent(m.Head), fmt.Sprintf("%+.1f pts", 100*(m.Head-m.Base))
+		if m.lowerWins {
+			base, head, delta = fmt.Sprint(m.Base), fmt.Sprint(m.Head), fmt.Sprintf("%+g", m.Head-m.Base)
+		}
+		var mark string
+		if m.Regressed() {
+			mark = "worse"
+		} else if m.Head != m.Base {
+			mark = "better"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Metric, base, head, delta, mark)
+	}
+
+	if len(c.Cases) > 0 {
+		fmt.Fprintln(tw, "\ncase\tbase\thead\t")
+		for _, d := range c.Cases {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", d.Name, caseLine(d.Base), caseLine(d.Head))
+		}
+	}
+	return tw.Flush()
+}
+
+// caseLine summarizes a case's scores in one cell.
+func caseLine(c *CaseResult) string {
+	if c == nil {
+		return "(not run)"
+	}
+	if c.Error != "" {
+		return "failed"
+	}
+	var valid, checked, satisfied int
+	for _, t := range c.Terraform {
+		if t.Valid {
+			valid++
+		}
+		checked += t.Checked
+		satisfied += t.Satisfied
+	}
+	return fmt.Sprintf("P %s R %s, hcl %d/%d, compliance %d/%d",
+		percent(c.Kinds.Precision()), percent(c.Kinds.Recall()), valid, len(c.Terraform), satisfied, checked)
+}
+
+func percent(f float64) string { return fmt.Sprintf("%.1f%%", 100*f) }
+
+// firstLine cuts s at its first newline; replay errors quote the prompt.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package eval
+
+import (
+	"sort"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// KindScore compares the resource kinds detected in a repository with the
+// expected ones, counting each kind as often as it occurs.
+type KindScore struct {
+	Expected   int                   `json:"expected"`
+	Detected   int                   `json:"detected"`
+	Matched    int                   `json:"matched"`
+	Missing    []domain.ResourceKind `json:"missing,omitempty"`    // expected but not detected
+	Unexpected []domain.ResourceKind `json:"unexpected,omitempty"` // detected but not expected
+}
+
+// Precision is the share of detected kinds that were expected; 1 when
+// nothing was detected.
+func (s KindScore) Precision() float64 { return ratio(s.Matched, s.Detected) }
+
+// Recall is the share of expected kinds that were detected; 1 when nothing
+// was expected.
+func (s KindScore) Recall() float64 { return ratio(s.Matched, s.Expected) }
+
+func scoreKinds(expected, detected []domain.ResourceKind) KindScore {
+	s := KindScore{Expected: len(expected), Detected: len(detected)}
+	remaining := make(map[domain.ResourceKind]int)
+	for _, k := range expected {
+		remaining[k]++
+	}
+	for _, k := range detected {
+		if remaining[k] > 0 {
+			remaining[k]--
+			s.Matched++
+		} else {
+			s.Unexpected = append(s.Unexpected, k)
+		}
+	}
+	for k, n := range remaining {
+		for range n {
+			s.Missing = append(s.Missing, k)
+		}
+	}
+	sortKinds(s.Missing)
+	sortKinds(s.Unexpected)
+	return s
+}
+
+// GraphScore describes a generated topology graph: whether every detected
+// resource appears in it as a node of its kind, and whether every edge
+// connects existing nodes.
+type GraphScore struct {
+	Nodes         int `json:"nodes"`
+	Edges         int `json:"edges"`
+	Resources     int `json:"resources"`
+	Shown         int `json:"shown"`          // resources with a node of their kind
+	DanglingEdges int `json:"dangling_edges"` // edges naming a node that does not exist
+}
+
+// Coverage is the share of resources shown in the graph.
+func (s GraphScore) Coverage() float64 { return ratio(s.Shown, s.Resources) }
+
+func scoreGraph(nodes []domain.GraphNode, edges []domain.GraphEdge, resources []domain.Resource) GraphScore {
+	s := GraphScore{Nodes: len(nodes), Edges: len(edges), Resources: len(resources)}
+	ids := make(map[string]bool, len(nodes))
+	kinds := make(map[string]int)
+	for _, n := range nodes {
+		ids[n.ID] = true
+		kinds[string(n.Kind)]++
+	}
+	for _, r := range resources {
+		if kinds[string(r.Kind)] > 0 {
+			kinds[string(r.Kind)]--
+			s.Shown++
+		}
+	}
+	for _, e := range edges {
+		if !ids[e.Source] || !ids[e.Target] {
+			s.DanglingEdges++
+		}
+	}
+	return s
+}
+
+// TerraformScore describes the Terraform generated for one resource.
+type TerraformScore struct {
+	Resource   string              `json:"resource"`
+	Kind       domain.ResourceKind `json:"kind"`
+	Error      string              `json:"error,omitempty"` // generation failed
+	Valid      bool                `json:"valid"`
+	Invalid    string              `json:"invalid,omitempty"` // why the HCL is not valid
+	Checked    int                 `json:"checked"`           // compliance requirements that applied
+	Satisfied  int                 `json:"satisfied"`
+	Violations []string            `json:"violations,omitempty"` // "<rule> <attribute>" of the unsatisfied ones
+}
+
+// ratio returns n/d, or 1 for an empty denominator: nothing to get wrong.
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+func sortKinds(kinds []domain.ResourceKind) {
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Backend names the API an LLM client talks to.
+type Backend string
+
+const (
+	BackendAnthropic Backend = "anthropic"
+	BackendOpenAI    Backend = "openai" // the OpenAI API or any compatible server (vLLM, llama.cpp)
+	BackendOllama    Backend = "ollama" // a local Ollama server's OpenAI-compatible endpoint
+	BackendReplay    Backend = "replay" // fixtures recorded from another backend, for offline tests
+)
+
+// Config selects and configures the LLM backend.
+type Config struct {
+	Backend Backend // defaults to BackendAnthropic
+	Model   string  // defaults to the backend's default; required for Ollama
+	BaseURL string  // OpenAI-compatible backends only; defaults to the backend's public or local endpoint
+	APIKey  string  // for Anthropic, the SDK reads ANTHROPIC_API_KEY when empty
+	// FixturesDir is where the replay backend reads its fixtures; with any
+	// other backend, every response is recorded there.
+	FixturesDir string
+}
+
+// New creates the client cfg selects.
+func New(cfg Config) (Client, error) {
+	c, err := newBackend(cfg)
+	if err != nil || cfg.FixturesDir == "" || strings.EqualFold(string(cfg.Backend), string(BackendReplay)) {
+		return c, err
+	}
+	return NewRecordingClient(c, cfg.FixturesDir)
+}
+
+func newBackend(cfg Config) (Client, error) {
+	switch Backend(strings.ToLower(string(cfg.Backend))) {
+	case "", BackendAnthropic:
+		return NewAnthropicClient(cfg.APIKey, cfg.Model), nil
+	case BackendOpenAI:
+		baseURL, model := cfg.BaseURL, cfg.Model
+		if baseURL == "" {
+			baseURL = DefaultOpenAIBaseURL
+		}
+		if model == "" {
+			model = DefaultOpenAIModel
+		}
+		return NewOpenAIClient(baseURL, cfg.APIKey, model), nil
+	case BackendOllama:
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("the ollama backend needs a model (e.g. llama3.1)")
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = DefaultOllamaBaseURL
+		}
+		return NewOpenAIClient(baseURL, cfg.APIKey, cfg.Model), nil
+	case BackendReplay:
+		if cfg.FixturesDir == "" {
+			return nil, fmt.Errorf("the replay backend needs a fixtures directory")
+		}
+		return NewReplayClient(cfg.FixturesDir), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM backend %q (want anthropic, openai, ollama or replay)", cfg.Backend)
+	}
+}
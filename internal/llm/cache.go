@@ -0,0 +1,356 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// DefaultCacheTTL is how long cached responses are served when no TTL is
+// configured.
+const DefaultCacheTTL = 24 * time.Hour
+
+// cachePurgeInterval is how often CachedClient removes expired entries.
+const cachePurgeInterval = time.Hour
+
+// Cache stores LLM responses by key. Get returns domain.ErrNotFound for an
+// unknown key; expired entries may still be returned and are ignored by
+// CachedClient.
+type Cache interface {
+	Get(ctx context.Context, key string) (domain.LLMCacheEntry, error)
+	Put(ctx context.Context, e domain.LLMCacheEntry) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a copy of ctx whose LLM calls bypass the cache: the
+// model is always asked, and its fresh answer replaces the cached one.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+// CacheCounts counts cache lookups.
+type CacheCounts struct {
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Bypassed int64 `json:"bypassed"` // calls made WithoutCache
+}
+
+// CacheStats reports how well the cache is doing since the process started.
+type CacheStats struct {
+	Enabled     bool                   `json:"enabled"`
+	TTLSeconds  int64                  `json:"ttl_seconds,omitempty"`
+	Model       string                 `json:"model,omitempty"`
+	CacheCounts                        // totals across operations
+	HitRate     float64                `json:"hit_rate"` // hits / (hits + misses)
+	Operations  map[string]CacheCounts `json:"operations,omitempty"`
+}
+
+// CachedClient is a Client decorator that serves repeated requests from a
+// cache, keyed by a hash of the operation, the model and the normalized
+// prompt. Re-running an operation on unchanged inputs then costs nothing
+// and returns the same answer. Cache failures are logged and the request
+// goes to the model.
+type CachedClient struct {
+	inner Client
+	cache Cache
+	model string
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	counts    map[string]*CacheCounts // by operation
+	lastPurge time.Time
+}
+
+// NewCachedClient wraps inner with cache. Entries expire after ttl; zero
+// means DefaultCacheTTL. Keys include inner's model, so changing models
+// misses the cache.
+func NewCachedClient(inner Client, cache Cache, ttl time.Duration) *CachedClient {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	var model string
+	if m, ok := inner.(interface{ Model() string }); ok {
+		model = m.Model()
+	}
+	return &CachedClient{
+		inner:     inner,
+		cache:     cache,
+		model:     model,
+		ttl:       ttl,
+		counts:    make(map[string]*CacheCounts),
+		lastPurge: time.Now(),
+	}
+}
+
+// Stats returns the hit and miss counts so far. A nil client reports a
+// disabled cache.
+func (c *CachedClient) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CacheStats{
+		Enabled:    true,
+		TTLSeconds: int64(c.ttl / time.Second),
+		Model:      c.model,
+		Operations: make(map[string]CacheCounts, len(c.counts)),
+	}
+	for op, counts := range c.counts {
+		stats.Operations[op] = *counts
+		stats.Hits += counts.Hits
+		stats.Misses += counts.Misses
+		stats.Bypassed += counts.Bypassed
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+func (c *CachedClient) count(op string, f func(*CacheCounts)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts, ok := c.counts[op]
+	if !ok {
+		counts = &CacheCounts{}
+		c.counts[op] = counts
+	}
+	f(counts)
+}
+
+// cacheKey hashes the operation, the model and the normalized prompts.
+func cacheKey(op, model, system, prompt string) string {
+	h := sha256.New()
+	for _, part := range []string{op, model, normalizePrompt(system), normalizePrompt(prompt)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizePrompt drops line-ending and trailing-whitespace differences,
+// which do not change what the model is asked. Indentation is kept; it
+// matters in the YAML and code quoted in prompts.
+func normalizePrompt(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// cached serves op from the cache, calling call on a miss and storing its
+// result.
+func cached[T any](ctx context.Context, c *CachedClient, op, system, prompt string, call func() (T, error)) (T, error) {
+	key := cacheKey(op, c.model, system, prompt)
+	now := time.Now().UTC()
+
+	if cacheBypassed(ctx) {
+		c.count(op, func(n *CacheCounts) { n.Bypassed++ })
+	} else {
+		e, err := c.cache.Get(ctx, key)
+		switch {
+		case err == nil && !e.Expired(now):
+			var result T
+			err := json.Unmarshal(e.Response, &result)
+			if err == nil {
+				c.count(op, func(n *CacheCounts) { n.Hits++ })
+				log.Printf("[llm] cache hit: operation=%s key=%.12s", op, key)
+				return result, nil
+			}
+			log.Printf("[llm] cache entry %.12s unreadable, ignoring it: %v", key, err)
+		case err != nil && !errors.Is(err, domain.ErrNotFound):
+			log.Printf("[llm] cache lookup failed: %v", err)
+		}
+		c.count(op, func(n *CacheCounts) { n.Misses++ })
+	}
+
+	result, err := call()
+	if err != nil {
+		return result, err
+	}
+	response, err := json.Marshal(result)
+	if err != nil {
+		return result, nil
+	}
+	e := domain.LLMCacheEntry{Key: key, Operation: op, Model: c.model, Response: response, CreatedAt: now, ExpiresAt: now.Add(c.ttl)}
+	if err := c.cache.Put(ctx, e); err != nil {
+		log.Printf("[llm] cache store failed: %v", err)
+	}
+	c.purgeExpired(ctx, now)
+	return result, nil
+}
+
+// purgeExpired removes expired entries, at most once per cachePurgeInterval.
+func (c *CachedClient) purgeExpired(ctx context.Context, now time.Time) {
+	c.mu.Lock()
+	due := now.Sub(c.lastPurge) >= cachePurgeInterval
+	if due {
+		c.lastPurge = now
+	}
+	c.mu.Unlock()
+	if !due {
+		return
+	}
+	if n, err := c.cache.DeleteExpired(ctx, now); err != nil {
+		log.Printf("[llm] cache purge failed: %v", err)
+	} else if n > 0 {
+		log.Printf("[llm] cache purge removed %d expired entries", n)
+	}
+}
+
+func (c *CachedClient) AnalyzeResourceNeed(ctx context.Context, description string, provider domain.CloudProvider) (ResourceRecommendation, error) {
+	return cached(ctx, c, "AnalyzeResourceNeed", systemPrompt(PromptResourceAnalysis).Text, buildResourceAnalysisPrompt(description, provider), func() (ResourceRecommendation, error) {
+		return c.inner.AnalyzeResourceNeed(ctx, description, provider)
+	})
+}
+
+func (c *CachedClient) AnalyzeCodebase(ctx context.Context, codeCtx analyzer.CodeContext, provider domain.CloudProvider) ([]ResourceRecommendation, error) {
+	return cached(ctx, c, "AnalyzeCodebase", systemPrompt(PromptCodebaseAnalysis).Text, buildCodebaseAnalysisPrompt(codeCtx, provider), func() ([]ResourceRecommendation, error) {
+		return c.inner.AnalyzeCodebase(ctx, codeCtx, provider)
+	})
+}
+
+func (c *CachedClient) GenerateHostingPlan(ctx context.Context, app domain.Application, resources []domain.Resource, complianceContext string) (HostingPlanResult, error) {
+	return cached(ctx, c, "GenerateHostingPlan", systemPrompt(PromptHostingPlan).Text, buildHostingPlanPrompt(app, resources, complianceContext), func() (HostingPlanResult, error) {
+		return c.inner.GenerateHostingPlan(ctx, app, resources, complianceContext)
+	})
+}
+
+func (c *CachedClient) GenerateMigrationPlan(ctx context.Context, app domain.Application, resources []domain.Resource, from, to domain.CloudProvider) (MigrationPlanResult, error) {
+	return cached(ctx, c, "GenerateMigrationPlan", systemPrompt(PromptMigrationPlan).Text, buildMigrationPlanPrompt(app, resources, from, to), func() (MigrationPlanResult, error) {
+		return c.inner.GenerateMigrationPlan(ctx, app, resources, from, to)
+	})
+}
+
+func (c *CachedClient) GenerateGraph(ctx context.Context, app domain.Application, resources []domain.Resource) (GraphResult, error) {
+	return cached(ctx, c, "GenerateGraph", systemPrompt(PromptGraph).Text, buildGraphPrompt(app, resources), func() (GraphResult, error) {
+		return c.inner.GenerateGraph(ctx, app, resources)
+	})
+}
+
+func (c *CachedClient) GenerateTerraformHCL(ctx context.Context, resource domain.Resource, provider domain.CloudProvider, complianceContext string) (TerraformHCLResult, error) {
+	return cached(ctx, c, "GenerateTerraformHCL", systemPrompt(PromptTerraformHCL).Text, buildTerraformHCLPrompt(resource, provider, complianceContext), func() (TerraformHCLResult, error) {
+		return c.inner.GenerateTerraformHCL(ctx, resource, provider, complianceContext)
+	})
+}
+
+func (c *CachedClient) GenerateDiscoveryCommands(ctx context.Context, app domain.Application, codeCtx analyzer.CodeContext) (DiscoveryCommandResult, error) {
+	return cached(ctx, c, "GenerateDiscoveryCommands", systemPrompt(PromptDiscoveryCommands).Text, buildDiscoveryCommandsPrompt(app, codeCtx), func() (DiscoveryCommandResult, error) {
+		return c.inner.GenerateDiscoveryCommands(ctx, app, codeCtx)
+	})
+}
+
+func (c *CachedClient) ParseDiscoveryOutput(ctx context.Context, app domain.Application, commandOutputs []CommandOutput) (LiveResourceParseResult, error) {
+	return cached(ctx, c, "ParseDiscoveryOutput", systemPrompt(PromptDiscoveryOutputParse).Text, buildDiscoveryOutputParsePrompt(app, commandOutputs), func() (LiveResourceParseResult, error) {
+		return c.inner.ParseDiscoveryOutput(ctx, app, commandOutputs)
+	})
+}
+
+func (c *CachedClient) RefinePlan(ctx context.Context, app domain.Application, plan domain.InfrastructurePlan, instruction string) (RefinedPlanResult, error) {
+	return cached(ctx, c, "RefinePlan", systemPrompt(PromptPlanRefinement).Text, buildPlanRefinementPrompt(app, plan, instruction), func() (RefinedPlanResult, error) {
+		return c.inner.RefinePlan(ctx, app, plan, instruction)
+	})
+}
+
+func (c *CachedClient) RefineResource(ctx context.Context, resource domain.Resource, instruction string) (ResourceRecommendation, error) {
+	return cached(ctx, c, "RefineResource", systemPrompt(PromptResourceRefinement).Text, buildResourceRefinementPrompt(resource, instruction), func() (ResourceRecommendation, error) {
+		return c.inner.RefineResource(ctx, resource, instruction)
+	})
+}
+
+// DirCache implements Cache with one JSON file per entry in a local
+// directory, for single-node installs without PostgreSQL.
+type DirCache struct {
+	dir string
+}
+
+// NewDirCache creates a cache in dir, creating the directory if needed.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create llm cache directory: %w", err)
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+func (d *DirCache) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
+
+func (d *DirCache) Get(_ context.Context, key string) (domain.LLMCacheEntry, error) {
+	data, err := os.ReadFile(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.LLMCacheEntry{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.LLMCacheEntry{}, fmt.Errorf("read llm cache entry: %w", err)
+	}
+	var e domain.LLMCacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return domain.LLMCacheEntry{}, fmt.Errorf("decode llm cache entry: %w", err)
+	}
+	return e, nil
+}
+
+// Put writes e to a temporary file and renames it into place, so readers
+// never see a partial entry.
+func (d *DirCache) Put(_ context.Context, e domain.LLMCacheEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode llm cache entry: %w", err)
+	}
+	tmp, err := os.CreateTemp(d.dir, e.Key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write llm cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write llm cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write llm cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path(e.Key)); err != nil {
+		return fmt.Errorf("write llm cache entry: %w", err)
+	}
+	return nil
+}
+
+func (d *DirCache) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	files, err := filepath.Glob(filepath.Join(d.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, f := range files {
+		e, err := d.Get(ctx, strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil || !e.Expired(now) {
+			continue
+		}
+		if err := os.Remove(f); err == nil {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestCachedClient(t *testing.T) {
+	dir, err := NewDirCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirCache() error = %v", err)
+	}
+	calls := 0
+	inner := &MockClient{
+		GenerateHostingPlanFn: func(ctx context.Context, app domain.Application, resources []domain.Resource, complianceContext string) (HostingPlanResult, error) {
+			calls++
+			return HostingPlanResult{Content: fmt.Sprintf("plan %d", calls)}, nil
+		},
+	}
+	c := NewCachedClient(inner, dir, time.Hour)
+	ctx := context.Background()
+	app := domain.NewApplication("cached", "", "", "", domain.ProviderAWS)
+	resources := []domain.Resource{{
+		Name: "db", Kind: domain.ResourceDatabase,
+		ProviderMappings: map[domain.CloudProvider]domain.ProviderResource{
+			domain.ProviderAWS: {ServiceName: "RDS"}, domain.ProviderGCP: {ServiceName: "Cloud SQL"},
+		},
+	}}
+
+	first, err := c.GenerateHostingPlan(ctx, app, resources, "")
+	if err != nil {
+		t.Fatalf("GenerateHostingPlan() error = %v", err)
+	}
+	again, _ := c.GenerateHostingPlan(ctx, app, resources, "")
+	if calls != 1 || again.Content != first.Content {
+		t.Fatalf("second call: %d model calls, plan %q, want the cached %q", calls, again.Content, first.Content)
+	}
+
+	// Different inputs miss.
+	if plan, _ := c.GenerateHostingPlan(ctx, app, resources, "CIS 6.4"); calls != 2 || plan.Content != "plan 2" {
+		t.Errorf("changed prompt: %d model calls, plan %q", calls, plan.Content)
+	}
+
+	// no_cache asks the model and refreshes the entry.
+	if plan, _ := c.GenerateHostingPlan(WithoutCache(ctx), app, resources, ""); calls != 3 || plan.Content != "plan 3" {
+		t.Errorf("WithoutCache: %d model calls, plan %q", calls, plan.Content)
+	}
+	if plan, _ := c.GenerateHostingPlan(ctx, app, resources, ""); calls != 3 || plan.Content != "plan 3" {
+		t.Errorf("after refresh: %d model calls, plan %q, want the refreshed plan", calls, plan.Content)
+	}
+
+	stats := c.Stats()
+	if !stats.Enabled || stats.Hits != 2 || stats.Misses != 2 || stats.Bypassed != 1 || stats.HitRate != 0.5 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if op := stats.Operations["GenerateHostingPlan"]; op.Hits != 2 {
+		t.Errorf("Stats().Operations = %+v", stats.Operations)
+	}
+
+	// Errors are not cached.
+	inner.GenerateGraphFn = func(ctx context.Context, app domain.Application, resources []domain.Resource) (GraphResult, error) {
+		calls++
+		return GraphResult{}, errors.New("overloaded")
+	}
+	c.GenerateGraph(ctx, app, resources)
+	if _, err := c.GenerateGraph(ctx, app, resources); err == nil || calls != 5 {
+		t.Errorf("GenerateGraph() after an error: %v after %d calls, want the model asked again", err, calls)
+	}
+}
+
+func TestCachedClient_Expiry(t *testing.T) {
+	dir, _ := NewDirCache(t.TempDir())
+	calls := 0
+	inner := &MockClient{
+		GenerateTerraformHCLFn: func(ctx context.Context, resource domain.Resource, provider domain.CloudProvider, complianceContext string) (TerraformHCLResult, error) {
+			calls++
+			return TerraformHCLResult{HCL: "# hcl"}, nil
+		},
+	}
+	c := NewCachedClient(inner, dir, time.Hour)
+	ctx := context.Background()
+	res := domain.Resource{Name: "bucket", Kind: domain.ResourceStorage}
+
+	c.GenerateTerraformHCL(ctx, res, domain.ProviderAWS, "")
+	key := cacheKey("GenerateTerraformHCL", "", systemPrompt(PromptTerraformHCL).Text, buildTerraformHCLPrompt(res, domain.ProviderAWS, ""))
+	e, err := dir.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	e.ExpiresAt = time.Now().Add(-time.Minute)
+	dir.Put(ctx, e)
+
+	c.GenerateTerraformHCL(ctx, res, domain.ProviderAWS, "")
+	if calls != 2 {
+		t.Errorf("expired entry: %d model calls, want 2", calls)
+	}
+
+	e.ExpiresAt = time.Now().Add(-time.Minute)
+	dir.Put(ctx, e)
+	if n, err := dir.DeleteExpired(ctx, time.Now()); err != nil || n != 1 {
+		t.Errorf("DeleteExpired() = %d, %v, want 1", n, err)
+	}
+	if _, err := dir.Get(ctx, key); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get(deleted): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := cacheKey("GenerateGraph", "m", "system", "line one\nline two")
+	if got := cacheKey("GenerateGraph", "m", "system", "line one  \r\nline two\n"); got != base {
+		t.Error("trailing whitespace and line endings change the key")
+	}
+	if cacheKey("GenerateGraph", "m", "system", "line one\n  line two") == base {
+		t.Error("indentation does not change the key")
+	}
+	if cacheKey("GenerateGraph", "other", "system", "line one\nline two") == base {
+		t.Error("the model does not change the key")
+	}
+	if cacheKey("GenerateHostingPlan", "m", "system", "line one\nline two") == base {
+		t.Error("the operation does not change the key")
+	}
+}
@@ -28,6 +28,13 @@ type MigrationPlanResult struct {
 	EstimatedCost *domain.CostEstimate `json:"estimated_cost,omitempty"`
 }
 
+// RefinedPlanResult is the LLM's output when refining a plan: the whole
+// revised plan.
+type RefinedPlanResult struct {
+	Content       string               `json:"content"`
+	EstimatedCost *domain.CostEstimate `json:"estimated_cost,omitempty"`
+}
+
 // GraphResult is the LLM's output for infrastructure topology graph generation.
 type GraphResult struct {
 	Nodes []domain.GraphNode `json:"nodes"`
@@ -97,4 +104,12 @@ type Client interface {
 
 	// ParseDiscoveryOutput takes raw CLI output and parses it into structured LiveResource data.
 	ParseDiscoveryOutput(ctx context.Context, app domain.Application, outputs []CommandOutput) (LiveResourceParseResult, error)
+
+	// RefinePlan rewrites an existing hosting or migration plan of app to
+	// follow a user's instruction, changing as little else as possible.
+	RefinePlan(ctx context.Context, app domain.Application, plan domain.InfrastructurePlan, instruction string) (RefinedPlanResult, error)
+
+	// RefineResource revises a resource's spec and provider mappings to
+	// follow a user's instruction, changing as little else as possible.
+	RefineResource(ctx context.Context, resource domain.Resource, instruction string) (ResourceRecommendation, error)
 }
@@ -173,3 +173,7 @@ func TestMockClient_ImplementsInterface(t *testing.T) {
 func TestAnthropicClient_ImplementsInterface(t *testing.T) {
 	var _ Client = (*AnthropicClient)(nil)
 }
+
+func TestOpenAIClient_ImplementsInterface(t *testing.T) {
+	var _ Client = (*OpenAIClient)(nil)
+}
@@ -0,0 +1,371 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// tool is the function a model must call to answer a request. Its input
+// schema constrains the structured output.
+type tool struct {
+	Name        string
+	Description string
+	Schema      schema
+}
+
+// request is a single-turn prompt answered by calling Tool.
+type request struct {
+	System    string
+	Prompt    string
+	MaxTokens int64
+	Timeout   time.Duration // bounds the whole operation, retries included
+	Tool      tool
+	Stream    string // tool input field reported to the context's StreamFunc as it is written
+}
+
+// sendFunc sends a request to a model and returns the input of its call to
+// the request's tool.
+type sendFunc func(ctx context.Context, req request) (json.RawMessage, error)
+
+// codebaseAnalysis is the tool input of AnalyzeCodebase. Tool inputs must be
+// objects, so the recommendations are wrapped.
+type codebaseAnalysis struct {
+	Resources []ResourceRecommendation `json:"resources"`
+}
+
+var (
+	resourceTool = tool{
+		Name:        "record_resource",
+		Description: "Record the cloud-agnostic resource that satisfies the described need, with its AWS and GCP mappings.",
+		Schema:      schemaFor[ResourceRecommendation](),
+	}
+	codebaseTool = tool{
+		Name:        "record_resources",
+		Description: "Record every infrastructure resource the codebase needs.",
+		Schema:      schemaFor[codebaseAnalysis](),
+	}
+	hostingPlanTool = tool{
+		Name:        "record_hosting_plan",
+		Description: "Record the hosting plan and its estimated monthly cost.",
+		Schema:      schemaFor[HostingPlanResult](),
+	}
+	migrationPlanTool = tool{
+		Name:        "record_migration_plan",
+		Description: "Record the migration plan and the estimated monthly cost on the target provider.",
+		Schema:      schemaFor[MigrationPlanResult](),
+	}
+	graphTool = tool{
+		Name:        "record_graph",
+		Description: "Record the application's infrastructure topology graph.",
+		Schema:      schemaFor[GraphResult](),
+	}
+	terraformTool = tool{
+		Name:        "record_terraform",
+		Description: "Record the Terraform HCL for the resource.",
+		Schema:      schemaFor[TerraformHCLResult](),
+	}
+	discoveryCommandsTool = tool{
+		Name:        "record_discovery_commands",
+		Description: "Record the read-only CLI commands that list the application's live cloud resources.",
+		Schema:      schemaFor[DiscoveryCommandResult](),
+	}
+	liveResourcesTool = tool{
+		Name:        "record_live_resources",
+		Description: "Record the live resources found in the CLI output.",
+		Schema:      schemaFor[LiveResourceParseResult](),
+	}
+	refinedPlanTool = tool{
+		Name:        "record_refined_plan",
+		Description: "Record the whole revised plan and its estimated monthly cost.",
+		Schema:      schemaFor[RefinedPlanResult](),
+	}
+	refinedResourceTool = tool{
+		Name:        "record_refined_resource",
+		Description: "Record the revised resource definition with its AWS and GCP mappings.",
+		Schema:      schemaFor[ResourceRecommendation](),
+	}
+)
+
+// completer implements Client on top of a backend's sendFunc. Every backend
+// uses the same prompts and tools and validates responses the same way;
+// only the wire protocol differs.
+type completer struct {
+	send sendFunc
+	// maxTokens is the most output tokens the backend accepts in one
+	// response. Truncated responses are asked for again with a larger
+	// budget up to this limit; zero disables that.
+	maxTokens int64
+}
+
+// newCompleter returns a completer sending through send with retries and
+// circuit breaking.
+func newCompleter(send sendFunc, maxTokens int64) completer {
+	return completer{send: newResilience().wrap(send), maxTokens: maxTokens}
+}
+
+// complete sends req and decodes the tool input into out. A response that
+// does not match the tool's schema is retried once, telling the model what
+// was wrong with it; a truncated one is asked for again with twice the
+// output tokens while the backend allows more.
+func (c completer) complete(ctx context.Context, req request, out any) error {
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+	prompt := req.Prompt
+	var problems []string
+	for attempt := 1; attempt <= 2; attempt++ {
+		resp, err := c.send(ctx, req)
+		if errors.Is(err, errResponseTruncated) && req.MaxTokens < c.maxTokens {
+			req.MaxTokens = min(2*req.MaxTokens, c.maxTokens)
+			log.Printf("[llm] %s response truncated; asking again with max_tokens=%d", req.Tool.Name, req.MaxTokens)
+			attempt--
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		problems = validate(req.Tool.Schema, resp)
+		if len(problems) == 0 {
+			return json.Unmarshal(resp, out)
+		}
+		log.Printf("[llm] %s response does not match its schema (attempt %d): %s", req.Tool.Name, attempt, strings.Join(problems, "; "))
+		req.Prompt = retryPrompt(prompt, req.Tool.Name, resp, problems)
+	}
+	return fmt.Errorf("response does not match the %s schema: %s", req.Tool.Name, strings.Join(problems, "; "))
+}
+
+// retryPrompt asks again for prompt, quoting the rejected tool input and
+// the validation errors.
+func retryPrompt(prompt, toolName string, rejected json.RawMessage, problems []string) string {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nA previous answer to this request was rejected because it does not match the ")
+	sb.WriteString(toolName)
+	sb.WriteString(" schema:\n")
+	for _, p := range problems {
+		sb.WriteString("- " + p + "\n")
+	}
+	sb.WriteString("\nRejected input:\n")
+	sb.Write(rejected)
+	sb.WriteString("\n\nCall " + toolName + " again with input that fixes every error above.\n")
+	return sb.String()
+}
+
+func (c completer) AnalyzeResourceNeed(ctx context.Context, description string, provider domain.CloudProvider) (ResourceRecommendation, error) {
+	var result ResourceRecommendation
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptResourceAnalysis).Text,
+		Prompt:    buildResourceAnalysisPrompt(description, provider),
+		MaxTokens: 4096,
+		Timeout:   2 * time.Minute,
+		Tool:      resourceTool,
+	}, &result)
+	if err != nil {
+		return ResourceRecommendation{}, fmt.Errorf("analyze resource need: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) AnalyzeCodebase(ctx context.Context, codeCtx analyzer.CodeContext, provider domain.CloudProvider) ([]ResourceRecommendation, error) {
+	var result codebaseAnalysis
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptCodebaseAnalysis).Text,
+		Prompt:    buildCodebaseAnalysisPrompt(codeCtx, provider),
+		MaxTokens: 8192,
+		Timeout:   5 * time.Minute,
+		Tool:      codebaseTool,
+	}, &result)
+	if errors.Is(err, errResponseTruncated) && len(codeCtx.Files) > 1 {
+		// Too many resources for one answer: analyze each half of the
+		// files separately.
+		log.Printf("[llm] codebase analysis truncated; splitting %d files in two", len(codeCtx.Files))
+		half := len(codeCtx.Files) / 2
+		first, second := codeCtx, codeCtx
+		first.Files, second.Files = codeCtx.Files[:half], codeCtx.Files[half:]
+		a, err := c.AnalyzeCodebase(ctx, first, provider)
+		if err != nil {
+			return nil, err
+		}
+		b, err := c.AnalyzeCodebase(ctx, second, provider)
+		if err != nil {
+			return nil, err
+		}
+		return mergeRecommendations(a, b), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("analyze codebase: %w", err)
+	}
+	return result.Resources, nil
+}
+
+// mergeRecommendations appends b to a, skipping resources a already
+// recommends under the same kind and name.
+func mergeRecommendations(a, b []ResourceRecommendation) []ResourceRecommendation {
+	seen := make(map[string]bool, len(a))
+	for _, r := range a {
+		seen[string(r.Kind)+"/"+r.Name] = true
+	}
+	for _, r := range b {
+		if !seen[string(r.Kind)+"/"+r.Name] {
+			a = append(a, r)
+		}
+	}
+	return a
+}
+
+func (c completer) GenerateHostingPlan(ctx context.Context, app domain.Application, resources []domain.Resource, complianceContext string) (HostingPlanResult, error) {
+	var result HostingPlanResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptHostingPlan).Text,
+		Prompt:    buildHostingPlanPrompt(app, resources, complianceContext),
+		MaxTokens: 12288,
+		Timeout:   5 * time.Minute,
+		Tool:      hostingPlanTool,
+		Stream:    "content",
+	}, &result)
+	if err != nil {
+		return HostingPlanResult{}, fmt.Errorf("generate hosting plan: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) GenerateMigrationPlan(ctx context.Context, app domain.Application, resources []domain.Resource, from, to domain.CloudProvider) (MigrationPlanResult, error) {
+	var result MigrationPlanResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptMigrationPlan).Text,
+		Prompt:    buildMigrationPlanPrompt(app, resources, from, to),
+		MaxTokens: 16384,
+		Timeout:   8 * time.Minute,
+		Tool:      migrationPlanTool,
+		Stream:    "content",
+	}, &result)
+	if err != nil {
+		return MigrationPlanResult{}, fmt.Errorf("generate migration plan: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) GenerateGraph(ctx context.Context, app domain.Application, resources []domain.Resource) (GraphResult, error) {
+	var result GraphResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptGraph).Text,
+		Prompt:    buildGraphPrompt(app, resources),
+		MaxTokens: 4096,
+		Timeout:   2 * time.Minute,
+		Tool:      graphTool,
+	}, &result)
+	if err != nil {
+		return GraphResult{}, fmt.Errorf("generate graph: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) GenerateTerraformHCL(ctx context.Context, resource domain.Resource, provider domain.CloudProvider, complianceContext string) (TerraformHCLResult, error) {
+	var result TerraformHCLResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptTerraformHCL).Text,
+		Prompt:    buildTerraformHCLPrompt(resource, provider, complianceContext),
+		MaxTokens: 8192,
+		Timeout:   4 * time.Minute,
+		Tool:      terraformTool,
+	}, &result)
+	if err != nil {
+		return TerraformHCLResult{}, fmt.Errorf("generate terraform HCL: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) GenerateDiscoveryCommands(ctx context.Context, app domain.Application, codeCtx analyzer.CodeContext) (DiscoveryCommandResult, error) {
+	var result DiscoveryCommandResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptDiscoveryCommands).Text,
+		Prompt:    buildDiscoveryCommandsPrompt(app, codeCtx),
+		MaxTokens: 4096,
+		Timeout:   2 * time.Minute,
+		Tool:      discoveryCommandsTool,
+	}, &result)
+	if err != nil {
+		return DiscoveryCommandResult{}, fmt.Errorf("generate discovery commands: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) ParseDiscoveryOutput(ctx context.Context, app domain.Application, commandOutputs []CommandOutput) (LiveResourceParseResult, error) {
+	var result LiveResourceParseResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptDiscoveryOutputParse).Text,
+		Prompt:    buildDiscoveryOutputParsePrompt(app, commandOutputs),
+		MaxTokens: 8192,
+		Timeout:   4 * time.Minute,
+		Tool:      liveResourcesTool,
+	}, &result)
+	if errors.Is(err, errResponseTruncated) && len(commandOutputs) > 1 {
+		// Too many resources for one answer: parse each half of the
+		// outputs separately.
+		log.Printf("[llm] discovery output parse truncated; splitting %d outputs in two", len(commandOutputs))
+		half := len(commandOutputs) / 2
+		a, err := c.ParseDiscoveryOutput(ctx, app, commandOutputs[:half])
+		if err != nil {
+			return LiveResourceParseResult{}, err
+		}
+		b, err := c.ParseDiscoveryOutput(ctx, app, commandOutputs[half:])
+		if err != nil {
+			return LiveResourceParseResult{}, err
+		}
+		seen := make(map[string]bool, len(a.Resources))
+		for _, r := range a.Resources {
+			seen[r.ResourceType+"/"+r.Name] = true
+		}
+		for _, r := range b.Resources {
+			if !seen[r.ResourceType+"/"+r.Name] {
+				a.Resources = append(a.Resources, r)
+			}
+		}
+		return a, nil
+	}
+	if err != nil {
+		return LiveResourceParseResult{}, fmt.Errorf("parse discovery output: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) RefinePlan(ctx context.Context, app domain.Application, plan domain.InfrastructurePlan, instruction string) (RefinedPlanResult, error) {
+	var result RefinedPlanResult
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptPlanRefinement).Text,
+		Prompt:    buildPlanRefinementPrompt(app, plan, instruction),
+		MaxTokens: 16384,
+		Timeout:   8 * time.Minute,
+		Tool:      refinedPlanTool,
+		Stream:    "content",
+	}, &result)
+	if err != nil {
+		return RefinedPlanResult{}, fmt.Errorf("refine plan: %w", err)
+	}
+	return result, nil
+}
+
+func (c completer) RefineResource(ctx context.Context, resource domain.Resource, instruction string) (ResourceRecommendation, error) {
+	var result ResourceRecommendation
+	err := c.complete(ctx, request{
+		System:    systemPrompt(PromptResourceRefinement).Text,
+		Prompt:    buildResourceRefinementPrompt(resource, instruction),
+		MaxTokens: 8192,
+		Timeout:   4 * time.Minute,
+		Tool:      refinedResourceTool,
+	}, &result)
+	if err != nil {
+		return ResourceRecommendation{}, fmt.Errorf("refine resource: %w", err)
+	}
+	return result, nil
+}
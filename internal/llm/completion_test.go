@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// scriptedSend answers the nth request with responses[n] and records the
+// requests.
+func scriptedSend(requests *[]request, responses ...string) sendFunc {
+	return func(ctx context.Context, req request) (json.RawMessage, error) {
+		*requests = append(*requests, req)
+		return json.RawMessage(responses[len(*requests)-1]), nil
+	}
+}
+
+func TestCompleter_RetriesOnSchemaMismatch(t *testing.T) {
+	var requests []request
+	c := completer{send: scriptedSend(&requests,
+		`{"kind": "databse", "name": "db"}`,
+		`{"kind": "database", "name": "db", "mappings": {"aws": {"service_name": "RDS"}}}`,
+	)}
+
+	rec, err := c.AnalyzeResourceNeed(context.Background(), "a database", domain.ProviderAWS)
+	if err != nil {
+		t.Fatalf("AnalyzeResourceNeed() error = %v", err)
+	}
+	if rec.Kind != domain.ResourceDatabase || rec.Mappings[domain.ProviderAWS].ServiceName != "RDS" {
+		t.Errorf("AnalyzeResourceNeed() = %+v", rec)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want a retry", len(requests))
+	}
+	retry := requests[1].Prompt
+	if !strings.HasPrefix(retry, requests[0].Prompt) || !strings.Contains(retry, `$.kind: "databse" is not one of`) || !strings.Contains(retry, `{"kind": "databse", "name": "db"}`) {
+		t.Errorf("retry prompt = %q, want the original prompt, the errors and the rejected input", retry)
+	}
+	if requests[1].Tool.Name != resourceTool.Name || requests[1].System != systemPrompt(PromptResourceAnalysis).Text {
+		t.Errorf("retry request = %+v, want the same tool and system prompt", requests[1])
+	}
+}
+
+func TestCompleter_GivesUpAfterOneRetry(t *testing.T) {
+	var requests []request
+	c := completer{send: scriptedSend(&requests, `{"nodes": "none"}`, `{"nodes": [], "edges": [{"id": "e"}]}`)}
+
+	_, err := c.GenerateGraph(context.Background(), domain.Application{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not match the record_graph schema") || !strings.Contains(err.Error(), `$.edges[0]: missing required property "source"`) {
+		t.Errorf("GenerateGraph(): got %v, want the second attempt's schema errors", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("sent %d requests, want 2", len(requests))
+	}
+}
+
+func TestCompleter_AnalyzeCodebase(t *testing.T) {
+	var requests []request
+	c := completer{send: scriptedSend(&requests, `{"resources": []}`)}
+
+	recs, err := c.AnalyzeCodebase(context.Background(), analyzer.CodeContext{}, domain.ProviderGCP)
+	if err != nil {
+		t.Fatalf("AnalyzeCodebase() error = %v", err)
+	}
+	if len(recs) != 0 || requests[0].Tool.Name != codebaseTool.Name {
+		t.Errorf("AnalyzeCodebase() = %+v via %s", recs, requests[0].Tool.Name)
+	}
+}
+
+func TestCompleter_RefinePlan(t *testing.T) {
+	var requests []request
+	c := completer{send: scriptedSend(&requests, `{"content": "# Cheaper plan", "estimated_cost": {"monthly_cost_usd": 180, "breakdown": {}}}`)}
+
+	plan := domain.InfrastructurePlan{PlanType: domain.PlanTypeHosting, Content: "# Plan"}
+	result, err := c.RefinePlan(context.Background(), domain.Application{Name: "orders"}, plan, "cut cost under $200/mo")
+	if err != nil {
+		t.Fatalf("RefinePlan() error = %v", err)
+	}
+	if result.Content != "# Cheaper plan" || result.EstimatedCost == nil || result.EstimatedCost.MonthlyCostUSD != 180 {
+		t.Errorf("RefinePlan() = %+v", result)
+	}
+	if req := requests[0]; req.Tool.Name != "record_refined_plan" || req.System != systemPrompt(PromptPlanRefinement).Text || req.Stream != "content" {
+		t.Errorf("request = %+v, want the plan refinement tool and prompt, streaming content", req)
+	}
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNoFixture is returned by ReplayClient for a prompt that was never
+// recorded.
+var ErrNoFixture = errors.New("no recorded LLM fixture")
+
+// fixture is a recorded prompt and the model's answer, stored as one JSON
+// file. The prompt is kept so that fixture diffs can be reviewed. The answer
+// is kept verbatim as a string: its formatting flows into later prompts
+// (resource specs are quoted as returned), so re-indenting it would change
+// their hashes.
+type fixture struct {
+	Tool      string `json:"tool"`
+	Prompt    string `json:"prompt"`
+	Response  string `json:"response,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"` // the model ran out of output tokens
+}
+
+// uuidPattern matches the IDs of stored objects, which differ between runs.
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// fixturePath names the fixture of req in dir by a hash of its tool and
+// normalized prompts. IDs are masked, so a flow replays although the objects
+// it creates get new IDs; the model is left out, so fixtures outlive model
+// upgrades until they are recorded again.
+func fixturePath(dir string, req request) string {
+	h := sha256.New()
+	for _, part := range []string{req.Tool.Name, normalizePrompt(req.System), normalizePrompt(req.Prompt)} {
+		h.Write([]byte(uuidPattern.ReplaceAllString(part, "<id>")))
+		h.Write([]byte{0})
+	}
+	return filepath.Join(dir, req.Tool.Name+"-"+hex.EncodeToString(h.Sum(nil))[:16]+".json")
+}
+
+// modelBackend is implemented by the clients that send requests to a model.
+type modelBackend interface {
+	Client
+	Model() string
+	base() completer
+}
+
+func (c completer) base() completer { return c }
+
+// RecordingClient is a Client that sends requests through a model backend
+// and writes every answer to a fixture directory, for ReplayClient to serve
+// later. Fixture write failures are logged; the answer is still returned.
+type RecordingClient struct {
+	completer
+	model string
+}
+
+// NewRecordingClient wraps backend, which must talk to a model (an
+// AnthropicClient or OpenAIClient), recording into dir.
+func NewRecordingClient(backend Client, dir string) (*RecordingClient, error) {
+	b, ok := backend.(modelBackend)
+	if !ok {
+		return nil, fmt.Errorf("record llm fixtures: %T does not send requests to a model", backend)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create llm fixture directory: %w", err)
+	}
+	inner := b.base()
+	send := func(ctx context.Context, req request) (json.RawMessage, error) {
+		resp, err := inner.send(ctx, req)
+		f := fixture{Tool: req.Tool.Name, Prompt: req.Prompt, Response: string(resp)}
+		switch {
+		case errors.Is(err, errResponseTruncated):
+			f.Truncated = true
+		case err != nil:
+			return nil, err
+		}
+		if werr := writeFixture(fixturePath(dir, req), f); werr != nil {
+			log.Printf("[llm] record fixture: %v", werr)
+		}
+		return resp, err
+	}
+	return &RecordingClient{completer: completer{send: send, maxTokens: inner.maxTokens}, model: b.Model()}, nil
+}
+
+// Model returns the model requests go to.
+func (c *RecordingClient) Model() string { return c.model }
+
+func writeFixture(path string, f fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fixture: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReplayClient is a Client that answers from the fixtures a RecordingClient
+// wrote, without a model. Prompts, tool schemas, validation and decoding all
+// run as they would against a real backend, so flows can be tested offline
+// and deterministically. A prompt without a fixture fails with ErrNoFixture.
+type ReplayClient struct {
+	completer
+}
+
+// NewReplayClient serves the fixtures in dir.
+func NewReplayClient(dir string) *ReplayClient {
+	send := func(ctx context.Context, req request) (json.RawMessage, error) {
+		path := fixturePath(dir, req)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for this %s prompt (want %s); record it again with LLM_FIXTURES_DIR=%s against a real backend:\n%.500s",
+				ErrNoFixture, req.Tool.Name, path, dir, req.Prompt)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read llm fixture: %w", err)
+		}
+		var f fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("decode llm fixture %s: %w", path, err)
+		}
+		if f.Truncated {
+			return nil, fmt.Errorf("%w (recorded in %s)", errResponseTruncated, path)
+		}
+		return json.RawMessage(f.Response), nil
+	}
+	return &ReplayClient{completer: completer{send: send}}
+}
+
+// Model names the replayed model.
+func (c *ReplayClient) Model() string { return "replay" }
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	srv := newFakeChatServer(t, chatReply{args: `{"nodes": [{"id": "internet", "label": "Internet", "kind": "internet", "service": ""}], "edges": []}`})
+	rec, err := NewRecordingClient(NewOpenAIClient(srv.URL+"/v1", "", "gpt-4o"), dir)
+	if err != nil {
+		t.Fatalf("NewRecordingClient() error = %v", err)
+	}
+	app := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	db := domain.NewResource(app.ID, domain.ResourceDatabase, "db", nil)
+	recorded, err := rec.GenerateGraph(context.Background(), app, []domain.Resource{db})
+	if err != nil {
+		t.Fatalf("recording GenerateGraph() error = %v", err)
+	}
+
+	// The resource gets a new ID on the next run; the fixture still matches.
+	replay := NewReplayClient(dir)
+	db.ID = domain.NewResource(app.ID, domain.ResourceDatabase, "db", nil).ID
+	replayed, err := replay.GenerateGraph(context.Background(), app, []domain.Resource{db})
+	if err != nil {
+		t.Fatalf("replayed GenerateGraph() error = %v", err)
+	}
+	if len(replayed.Nodes) != 1 || replayed.Nodes[0].ID != recorded.Nodes[0].ID {
+		t.Errorf("replayed GenerateGraph() = %+v, want %+v", replayed, recorded)
+	}
+	if len(srv.requests) != 1 {
+		t.Errorf("replay sent requests to the model")
+	}
+
+	app.Description = "changed"
+	if _, err := replay.GenerateGraph(context.Background(), app, nil); !errors.Is(err, ErrNoFixture) {
+		t.Errorf("GenerateGraph() with an unrecorded prompt: got %v, want ErrNoFixture", err)
+	}
+}
+
+func TestNewRecordingClient_NeedsModelBackend(t *testing.T) {
+	if _, err := NewRecordingClient(&MockClient{}, t.TempDir()); err == nil {
+		t.Error("NewRecordingClient(MockClient) succeeded, want an error")
+	}
+}
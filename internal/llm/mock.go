@@ -19,6 +19,8 @@ type MockClient struct {
 	GenerateTerraformHCLFn       func(ctx context.Context, resource domain.Resource, provider domain.CloudProvider, complianceContext string) (TerraformHCLResult, error)
 	GenerateDiscoveryCommandsFn  func(ctx context.Context, app domain.Application, codeCtx analyzer.CodeContext) (DiscoveryCommandResult, error)
 	ParseDiscoveryOutputFn       func(ctx context.Context, app domain.Application, outputs []CommandOutput) (LiveResourceParseResult, error)
+	RefinePlanFn                 func(ctx context.Context, app domain.Application, plan domain.InfrastructurePlan, instruction string) (RefinedPlanResult, error)
+	RefineResourceFn             func(ctx context.Context, resource domain.Resource, instruction string) (ResourceRecommendation, error)
 }
 
 func (m *MockClient) AnalyzeResourceNeed(ctx context.Context, description string, provider domain.CloudProvider) (ResourceRecommendation, error) {
@@ -36,17 +38,33 @@ func (m *MockClient) AnalyzeCodebase(ctx context.Context, codeCtx analyzer.CodeC
 }
 
 func (m *MockClient) GenerateHostingPlan(ctx context.Context, app domain.Application, resources []domain.Resource, complianceContext string) (HostingPlanResult, error) {
+	result, err := defaultHostingPlan(), error(nil)
 	if m.GenerateHostingPlanFn != nil {
-		return m.GenerateHostingPlanFn(ctx, app, resources, complianceContext)
+		result, err = m.GenerateHostingPlanFn(ctx, app, resources, complianceContext)
 	}
-	return defaultHostingPlan(), nil
+	if err == nil {
+		mockStream(ctx, result.Content)
+	}
+	return result, err
 }
 
 func (m *MockClient) GenerateMigrationPlan(ctx context.Context, app domain.Application, resources []domain.Resource, from, to domain.CloudProvider) (MigrationPlanResult, error) {
+	result, err := defaultMigrationPlan(), error(nil)
 	if m.GenerateMigrationPlanFn != nil {
-		return m.GenerateMigrationPlanFn(ctx, app, resources, from, to)
+		result, err = m.GenerateMigrationPlanFn(ctx, app, resources, from, to)
+	}
+	if err == nil {
+		mockStream(ctx, result.Content)
+	}
+	return result, err
+}
+
+// mockStream reports a plan's markdown to the context's StreamFunc in one
+// piece, where a streaming backend would in many.
+func mockStream(ctx context.Context, content string) {
+	if fn, _ := ctx.Value(streamKey{}).(StreamFunc); fn != nil && content != "" {
+		fn(content)
 	}
-	return defaultMigrationPlan(), nil
 }
 
 func (m *MockClient) GenerateGraph(ctx context.Context, app domain.Application, resources []domain.Resource) (GraphResult, error) {
@@ -211,3 +229,39 @@ func defaultGraph() GraphResult {
 		},
 	}
 }
+
+// RefinePlan defaults to the plan with the instruction appended as a new
+// section, at the same cost.
+func (m *MockClient) RefinePlan(ctx context.Context, app domain.Application, plan domain.InfrastructurePlan, instruction string) (RefinedPlanResult, error) {
+	result := RefinedPlanResult{
+		Content:       plan.Content + "\n\n## Refinement\n\n" + instruction,
+		EstimatedCost: plan.EstimatedCost,
+	}
+	var err error
+	if m.RefinePlanFn != nil {
+		result, err = m.RefinePlanFn(ctx, app, plan, instruction)
+	}
+	if err == nil {
+		mockStream(ctx, result.Content)
+	}
+	return result, err
+}
+
+// RefineResource defaults to the resource with the instruction recorded in
+// its spec under "refinement".
+func (m *MockClient) RefineResource(ctx context.Context, resource domain.Resource, instruction string) (ResourceRecommendation, error) {
+	if m.RefineResourceFn != nil {
+		return m.RefineResourceFn(ctx, resource, instruction)
+	}
+	spec := make(map[string]any)
+	if len(resource.Spec) > 0 {
+		_ = json.Unmarshal(resource.Spec, &spec)
+	}
+	spec["refinement"] = instruction
+	specJSON, _ := json.Marshal(spec)
+	mappings := make(map[domain.CloudProvider]domain.ProviderResource, len(resource.ProviderMappings))
+	for p, m := range resource.ProviderMappings {
+		mappings[p] = m
+	}
+	return ResourceRecommendation{Kind: resource.Kind, Name: resource.Name, Spec: specJSON, Mappings: mappings}, nil
+}
@@ -0,0 +1,420 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOpenAIBaseURL is the OpenAI API endpoint.
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	// DefaultOllamaBaseURL is the OpenAI-compatible endpoint of a local Ollama server.
+	DefaultOllamaBaseURL = "http://localhost:11434/v1"
+	// DefaultOpenAIModel is the model used against the OpenAI API when none is configured.
+	DefaultOpenAIModel = "gpt-4o"
+
+	// openAIMaxTokens is the largest output budget truncated responses are
+	// asked for again with: gpt-4o's limit, which most self-hosted servers
+	// accept too.
+	openAIMaxTokens = 16384
+)
+
+// OpenAIClient implements Client against any server speaking the OpenAI
+// chat completions protocol: the OpenAI API itself, or self-hosted model
+// servers such as vLLM, llama.cpp and Ollama.
+type OpenAIClient struct {
+	completer
+	baseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+// NewOpenAIClient creates an LLM client for the chat completions endpoint
+// under baseURL (e.g. "http://localhost:11434/v1"), using model. apiKey is
+// sent as a bearer token; local servers usually need none.
+func NewOpenAIClient(baseURL, apiKey, model string) *OpenAIClient {
+	c := &OpenAIClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		// Self-hosted models can take minutes on large prompts; match the
+		// upper bound the Anthropic SDK allows.
+		http: &http.Client{Timeout: 10 * time.Minute},
+	}
+	c.completer = newCompleter(c.sendMessage, openAIMaxTokens)
+	return c
+}
+
+// Model returns the model requests go to.
+func (c *OpenAIClient) Model() string { return c.model }
+
+type chatMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+}
+
+type toolCall struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON-encoded tool input
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"` // always "function"
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  schema `json:"parameters,omitempty"`
+}
+
+type chatToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+type chatCompletionRequest struct {
+	Model         string             `json:"model"`
+	Messages      []chatMessage      `json:"messages"`
+	MaxTokens     int64              `json:"max_tokens"`
+	Tools         []chatTool         `json:"tools,omitempty"`
+	ToolChoice    *chatToolChoice    `json:"tool_choice,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	StreamOptions *chatStreamOptions `json:"stream_options,omitempty"`
+}
+
+type chatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatChoice struct {
+	Message      chatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type chatUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+type chatError struct {
+	Message string `json:"message"`
+}
+
+type chatCompletionResponse struct {
+	Choices []chatChoice `json:"choices"`
+	Usage   chatUsage    `json:"usage"`
+	Error   *chatError   `json:"error"`
+}
+
+// chatCompletionChunk is one event of a streamed chat completion.
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int `json:"index"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+	Error *chatError `json:"error"`
+}
+
+// sendMessage posts a chat completion request forcing a call to the
+// request's function and returns the call's arguments. Some self-hosted
+// servers ignore tool choice and answer in text; the JSON is then extracted
+// from the text, and validated against the schema like any other answer.
+// Operations that stream, called with a StreamFunc, ask for a streamed
+// response.
+func (c *OpenAIClient) sendMessage(ctx context.Context, req request) (json.RawMessage, error) {
+	start := time.Now()
+	log.Printf("[llm] sending request: model=%s tool=%s max_tokens=%d prompt_len=%d system_len=%d",
+		c.model, req.Tool.Name, req.MaxTokens, len(req.Prompt), len(req.System))
+
+	choice := &chatToolChoice{Type: "function"}
+	choice.Function.Name = req.Tool.Name
+	streamer := streamerFor(ctx, req)
+	chatReq := chatCompletionRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: req.System},
+			{Role: "user", Content: req.Prompt},
+		},
+		MaxTokens: req.MaxTokens,
+		Tools: []chatTool{{Type: "function", Function: chatFunction{
+			Name:        req.Tool.Name,
+			Description: req.Tool.Description,
+			Parameters:  req.Tool.Schema,
+		}}},
+		ToolChoice: choice,
+	}
+	if streamer != nil {
+		chatReq.Stream = true
+		chatReq.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+	}
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("marshal chat completion request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build chat completion request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	httpResp, err := c.http.Do(httpReq)
+	elapsed := time.Since(start)
+	if err != nil {
+		log.Printf("[llm] API error after %s: %v", elapsed.Round(time.Millisecond), err)
+		return nil, fmt.Errorf("chat completions API call: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp chatCompletionResponse
+	if streamer != nil && httpResp.StatusCode == http.StatusOK {
+		resp, err = readChatStream(httpResp.Body, streamer)
+		elapsed = time.Since(start)
+		if err != nil {
+			log.Printf("[llm] API error after %s: %v", elapsed.Round(time.Millisecond), err)
+			return nil, fmt.Errorf("chat completions API call: %w", err)
+		}
+		return c.toolInput(ctx, req, resp, elapsed)
+	}
+
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read chat completion response: %w", err)
+	}
+	decodeErr := json.Unmarshal(raw, &resp)
+	if httpResp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(raw))
+		if decodeErr == nil && resp.Error != nil {
+			msg = resp.Error.Message
+		}
+		log.Printf("[llm] API error after %s: status=%d", elapsed.Round(time.Millisecond), httpResp.StatusCode)
+		return nil, fmt.Errorf("chat completions API call: %w", &statusError{
+			StatusCode: httpResp.StatusCode,
+			RetryAfter: retryAfter(httpResp.Header),
+			Message:    msg,
+		})
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decode chat completion response: %w", decodeErr)
+	}
+	return c.toolInput(ctx, req, resp, elapsed)
+}
+
+// toolInput returns the input of the call to req's function in resp.
+func (c *OpenAIClient) toolInput(ctx context.Context, req request, resp chatCompletionResponse, elapsed time.Duration) (json.RawMessage, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+	first := resp.Choices[0]
+
+	log.Printf("[llm] response received in %s: finish_reason=%s input_tokens=%d output_tokens=%d",
+		elapsed.Round(time.Millisecond), first.FinishReason,
+		resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	countTokens(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+
+	// Check if the response was truncated
+	if first.FinishReason == "length" {
+		log.Printf("[llm] WARNING: response truncated at %d output tokens", resp.Usage.CompletionTokens)
+		return nil, fmt.Errorf("%w (hit %d token limit) — try reducing prompt complexity", errResponseTruncated, req.MaxTokens)
+	}
+
+	for _, call := range first.Message.ToolCalls {
+		if call.Function.Name == req.Tool.Name {
+			return json.RawMessage(call.Function.Arguments), nil
+		}
+	}
+	if first.Message.Content != "" {
+		return json.RawMessage(extractJSON(first.Message.Content)), nil
+	}
+	return nil, fmt.Errorf("no %s tool call in response", req.Tool.Name)
+}
+
+// readChatStream reads a streamed chat completion into the response it
+// amounts to, reporting the function call's arguments to s as they arrive.
+func readChatStream(body io.Reader, s *fieldStreamer) (chatCompletionResponse, error) {
+	var resp chatCompletionResponse
+	var choice chatChoice
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return resp, fmt.Errorf("decode chat completion chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return resp, errors.New(chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			resp.Usage = *chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			choice.Message.Content += c.Delta.Content
+			if c.FinishReason != "" {
+				choice.FinishReason = c.FinishReason
+			}
+			for _, d := range c.Delta.ToolCalls {
+				for len(choice.Message.ToolCalls) <= d.Index {
+					choice.Message.ToolCalls = append(choice.Message.ToolCalls, toolCall{Type: "function"})
+				}
+				call := &choice.Message.ToolCalls[d.Index]
+				call.Function.Name += d.Function.Name
+				call.Function.Arguments += d.Function.Arguments
+				s.update([]byte(call.Function.Arguments))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return resp, fmt.Errorf("read chat completion stream: %w", err)
+	}
+	resp.Choices = []chatChoice{choice}
+	return resp, nil
+}
+
+// extractJSON attempts to extract a JSON object from text that may contain
+// markdown fences or surrounding prose. It tries multiple strategies and
+// validates each extraction attempt with json.Valid before returning.
+func extractJSON(text string) string {
+	// Strategy 1: Try to find JSON between ```json and ``` markers.
+	// Be careful not to match ``` inside the JSON content — we look for
+	// the outermost closing ``` (searching from the end).
+	start := -1
+	for i := 0; i < len(text)-6; i++ {
+		if text[i:i+7] == "```json" {
+			start = i + 7
+			for start < len(text) && (text[start] == '\n' || text[start] == '\r') {
+				start++
+			}
+			break
+		}
+	}
+	if start >= 0 {
+		// Search backwards from the end for closing ```
+		for end := len(text) - 3; end > start; end-- {
+			if text[end:end+3] == "```" {
+				candidate := strings.TrimSpace(text[start:end])
+				if json.Valid([]byte(candidate)) {
+					return candidate
+				}
+				break
+			}
+		}
+	}
+
+	// Strategy 2: If the text is already valid JSON, return as-is.
+	trimmed := strings.TrimSpace(text)
+	if json.Valid([]byte(trimmed)) {
+		return trimmed
+	}
+
+	// Strategy 3: Find matching braces/brackets using depth tracking.
+	// Try both { and [ and pick whichever comes first.
+	firstBrace := strings.IndexByte(text, '{')
+	firstBracket := strings.IndexByte(text, '[')
+
+	type extraction struct {
+		openChar  byte
+		closeChar byte
+		startPos  int
+	}
+	var extractions []extraction
+	if firstBracket >= 0 && (firstBrace < 0 || firstBracket < firstBrace) {
+		extractions = append(extractions, extraction{'[', ']', firstBracket})
+		if firstBrace >= 0 {
+			extractions = append(extractions, extraction{'{', '}', firstBrace})
+		}
+	} else if firstBrace >= 0 {
+		extractions = append(extractions, extraction{'{', '}', firstBrace})
+		if firstBracket >= 0 {
+			extractions = append(extractions, extraction{'[', ']', firstBracket})
+		}
+	}
+
+	for _, ext := range extractions {
+		if result := matchBraces(text, ext.startPos, ext.openChar, ext.closeChar); result != "" {
+			return result
+		}
+	}
+
+	return text
+}
+
+// matchBraces extracts a balanced JSON structure from text starting at startPos.
+// It tracks string state and escape sequences to avoid false matches.
+func matchBraces(text string, startPos int, openChar, closeChar byte) string {
+	depth := 0
+	inString := false
+	escaped := false
+
+	for i := startPos; i < len(text); i++ {
+		ch := text[i]
+		if escaped {
+			escaped = false
+			continue
+		}
+		if ch == '\\' && inString {
+			escaped = true
+			continue
+		}
+		if ch == '"' {
+			inString = !inString
+			continue
+		}
+		if inString {
+			continue
+		}
+		if ch == openChar {
+			depth++
+		}
+		if ch == closeChar {
+			depth--
+			if depth == 0 {
+				candidate := text[startPos : i+1]
+				if json.Valid([]byte(candidate)) {
+					return candidate
+				}
+				// If not valid, keep looking (might be a false match)
+				return candidate // Return best effort
+			}
+		}
+	}
+
+	return ""
+}
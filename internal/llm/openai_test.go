@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// chatReply is one canned chat completion: a call to the requested
+// function with args, or a text answer when args is empty.
+type chatReply struct {
+	args, content, finishReason string
+}
+
+// fakeChatServer speaks the OpenAI chat completions protocol, answering the
+// nth request with replies[n] (repeating the last) and recording the
+// requests and their Authorization headers.
+type fakeChatServer struct {
+	*httptest.Server
+	requests []chatCompletionRequest
+	auth     []string
+}
+
+func newFakeChatServer(t *testing.T, replies ...chatReply) *fakeChatServer {
+	t.Helper()
+	f := &fakeChatServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			http.Error(w, `{"error":{"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		f.requests = append(f.requests, req)
+		f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+		reply := replies[min(len(f.requests), len(replies))-1]
+		message := map[string]any{"role": "assistant", "content": nil}
+		if reply.args != "" {
+			message["tool_calls"] = []map[string]any{{
+				"id":       "call_1",
+				"type":     "function",
+				"function": map[string]string{"name": req.ToolChoice.Function.Name, "arguments": reply.args},
+			}}
+		} else {
+			message["content"] = reply.content
+		}
+		finish := reply.finishReason
+		if finish == "" {
+			finish = "tool_calls"
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": message, "finish_reason": finish}},
+			"usage":   map[string]int{"prompt_tokens": 10, "completion_tokens": 20},
+		})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestOpenAIClient_AnalyzeResourceNeed(t *testing.T) {
+	srv := newFakeChatServer(t, chatReply{args: `{"kind": "database", "name": "main-db", "spec": {"engine": "postgres"}}`})
+
+	c := NewOpenAIClient(srv.URL+"/v1/", "sk-test", "llama3.1")
+	rec, err := c.AnalyzeResourceNeed(context.Background(), "a postgres database", domain.ProviderAWS)
+	if err != nil {
+		t.Fatalf("AnalyzeResourceNeed() error = %v", err)
+	}
+	if rec.Kind != domain.ResourceDatabase || rec.Name != "main-db" {
+		t.Errorf("AnalyzeResourceNeed() = %+v", rec)
+	}
+
+	req := srv.requests[0]
+	if req.Model != "llama3.1" || req.MaxTokens != 4096 {
+		t.Errorf("request model/max_tokens = %s/%d", req.Model, req.MaxTokens)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != systemPrompt(PromptResourceAnalysis).Text ||
+		req.Messages[1].Role != "user" || req.Messages[1].Content != buildResourceAnalysisPrompt("a postgres database", domain.ProviderAWS) {
+		t.Errorf("request messages = %+v, want the shared system and user prompts", req.Messages)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Function.Name != resourceTool.Name || req.Tools[0].Function.Parameters["type"] != "object" {
+		t.Errorf("request tools = %+v, want the record_resource function", req.Tools)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Function.Name != resourceTool.Name {
+		t.Errorf("request tool_choice = %+v, want record_resource forced", req.ToolChoice)
+	}
+	if srv.auth[0] != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", srv.auth[0])
+	}
+
+	// Local servers need no key.
+	if _, err := NewOpenAIClient(srv.URL+"/v1", "", "llama3.1").AnalyzeResourceNeed(context.Background(), "a cache", domain.ProviderGCP); err != nil {
+		t.Fatalf("AnalyzeResourceNeed(no key) error = %v", err)
+	}
+	if srv.auth[1] != "" {
+		t.Errorf("Authorization = %q, want none without a key", srv.auth[1])
+	}
+}
+
+func TestOpenAIClient_AnalyzeCodebase(t *testing.T) {
+	srv := newFakeChatServer(t, chatReply{args: `{"resources": [{"kind": "database", "name": "db"}, {"kind": "cache", "name": "cache"}]}`})
+
+	recs, err := NewOpenAIClient(srv.URL+"/v1", "", "m").AnalyzeCodebase(context.Background(), analyzer.CodeContext{Summary: "a Go API", Files: []analyzer.FileContent{{Path: "main.go", Content: "package main"}}}, domain.ProviderAWS)
+	if err != nil {
+		t.Fatalf("AnalyzeCodebase() error = %v", err)
+	}
+	if len(recs) != 2 || recs[1].Kind != domain.ResourceCache {
+		t.Errorf("AnalyzeCodebase() = %+v", recs)
+	}
+}
+
+func TestOpenAIClient_TextAnswer(t *testing.T) {
+	// Servers without tool support answer in text.
+	srv := newFakeChatServer(t, chatReply{content: "Here you go:\n```json\n{\"hcl\": \"resource \\\"aws_s3_bucket\\\" \\\"b\\\" {}\"}\n```", finishReason: "stop"})
+
+	result, err := NewOpenAIClient(srv.URL+"/v1", "", "m").GenerateTerraformHCL(context.Background(), domain.Resource{}, domain.ProviderAWS, "")
+	if err != nil {
+		t.Fatalf("GenerateTerraformHCL() error = %v", err)
+	}
+	if result.HCL != `resource "aws_s3_bucket" "b" {}` {
+		t.Errorf("GenerateTerraformHCL() = %q", result.HCL)
+	}
+}
+
+func TestOpenAIClient_Errors(t *testing.T) {
+	truncated := newFakeChatServer(t, chatReply{content: `{"nodes": [{"id": "inter`, finishReason: "length"})
+	if _, err := NewOpenAIClient(truncated.URL+"/v1", "", "m").GenerateGraph(context.Background(), domain.Application{}, nil); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("GenerateGraph(truncated): got %v, want a truncation error", err)
+	}
+	if got := truncated.requests[len(truncated.requests)-1].MaxTokens; len(truncated.requests) != 3 || got != openAIMaxTokens {
+		t.Errorf("truncated GenerateGraph sent %d requests, the last with max_tokens=%d, want 3 up to %d", len(truncated.requests), got, openAIMaxTokens)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "invalid api key"}}`))
+	}))
+	defer failing.Close()
+	_, err := NewOpenAIClient(failing.URL, "bad", "m").GenerateGraph(context.Background(), domain.Application{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 401: invalid api key") {
+		t.Errorf("GenerateGraph(unauthorized): got %v, want the API's error message", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantModel string
+		wantURL   string
+		wantErr   bool
+	}{
+		{name: "anthropic by default", cfg: Config{}},
+		{name: "openai defaults", cfg: Config{Backend: BackendOpenAI, APIKey: "sk"}, wantModel: DefaultOpenAIModel, wantURL: DefaultOpenAIBaseURL},
+		{name: "openai-compatible server", cfg: Config{Backend: "OpenAI", BaseURL: "http://vllm:8000/v1", Model: "qwen"}, wantModel: "qwen", wantURL: "http://vllm:8000/v1"},
+		{name: "ollama", cfg: Config{Backend: BackendOllama, Model: "llama3.1"}, wantModel: "llama3.1", wantURL: DefaultOllamaBaseURL},
+		{name: "ollama without a model", cfg: Config{Backend: BackendOllama}, wantErr: true},
+		{name: "unknown backend", cfg: Config{Backend: "bard"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantURL == "" {
+				if _, ok := c.(*AnthropicClient); !ok {
+					t.Fatalf("New() = %T, want *AnthropicClient", c)
+				}
+				return
+			}
+			oc, ok := c.(*OpenAIClient)
+			if !ok {
+				t.Fatalf("New() = %T, want *OpenAIClient", c)
+			}
+			if oc.model != tt.wantModel || oc.baseURL != tt.wantURL {
+				t.Errorf("New() model/base URL = %s/%s, want %s/%s", oc.model, oc.baseURL, tt.wantModel, tt.wantURL)
+			}
+		})
+	}
+}
@@ -109,3 +109,43 @@ func TestBuildMigrationPlanPrompt(t *testing.T) {
 		t.Error("prompt should contain target provider mapping")
 	}
 }
+
+func TestBuildPlanRefinementPrompt(t *testing.T) {
+	app := domain.Application{Name: "orders", Provider: domain.ProviderAWS, ComplianceFrameworks: []string{"cis_aws_v3"}}
+	plan := domain.NewHostingPlan(uuid.New(), "# Plan\n\nUse RDS PostgreSQL.", []domain.Resource{
+		{Kind: domain.ResourceDatabase, Name: "orders-db", Spec: json.RawMessage(`{"engine": "postgres"}`)},
+	}, &domain.CostEstimate{MonthlyCostUSD: 320})
+
+	prompt := buildPlanRefinementPrompt(app, plan, "cut cost under $200/mo")
+
+	for _, want := range []string{"Refine the following hosting plan", "Preferred Provider: aws", "Compliance Frameworks: cis_aws_v3",
+		"- orders-db (database)", `"monthly_cost_usd":320`, "Current Plan:\n# Plan\n\nUse RDS PostgreSQL.", "Instruction:\ncut cost under $200/mo"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt should contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestBuildResourceRefinementPrompt(t *testing.T) {
+	resource := domain.Resource{
+		Kind: domain.ResourceDatabase,
+		Name: "orders-db",
+		Spec: json.RawMessage(`{"engine": "postgres"}`),
+		ProviderMappings: map[domain.CloudProvider]domain.ProviderResource{
+			domain.ProviderAWS: {ServiceName: "RDS", TerraformHCL: `resource "aws_db_instance" "orders_db" {}`},
+			domain.ProviderGCP: {ServiceName: "Cloud SQL"},
+		},
+	}
+
+	prompt := buildResourceRefinementPrompt(resource, "use Aurora Serverless instead")
+
+	for _, want := range []string{"Resource Name: orders-db", `Spec: {"engine": "postgres"}`, "aws Service: RDS",
+		"aws Terraform:\nresource \"aws_db_instance\" \"orders_db\" {}", "gcp Service: Cloud SQL", "Instruction:\nuse Aurora Serverless instead"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt should contain %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Index(prompt, "aws Service") > strings.Index(prompt, "gcp Service") {
+		t.Error("providers should be listed in order")
+	}
+}
@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// ErrProviderUnavailable is returned without contacting the provider while
+// its circuit breaker is open, after repeated failures suggest it is down.
+var ErrProviderUnavailable = errors.New("LLM provider unavailable")
+
+// errResponseTruncated is returned by a backend when the model ran out of
+// output tokens before finishing its answer.
+var errResponseTruncated = errors.New("response truncated")
+
+// statusError is an error response from a provider's HTTP API.
+type statusError struct {
+	StatusCode int
+	RetryAfter time.Duration // zero unless the provider asked for a delay
+	Message    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d: %.200s", e.StatusCode, e.Message)
+}
+
+// retryAfter parses a Retry-After header given in seconds. HTTP dates are
+// rare from LLM APIs and are ignored.
+func retryAfter(h http.Header) time.Duration {
+	secs, err := strconv.ParseFloat(h.Get("Retry-After"), 64)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// failure classifies a send error. Retryable errors are worth trying again;
+// outages also count towards opening the circuit breaker. Rate limiting is
+// retryable but shows the provider is up.
+type failure struct {
+	retryable, outage bool
+	wait              time.Duration // the delay the provider asked for, if any
+}
+
+func classify(err error) failure {
+	status, wait := 0, time.Duration(0)
+	var se *statusError
+	var ae *anthropic.Error
+	switch {
+	case errors.As(err, &se):
+		status, wait = se.StatusCode, se.RetryAfter
+	case errors.As(err, &ae):
+		status = ae.StatusCode
+		if ae.Response != nil {
+			wait = retryAfter(ae.Response.Header)
+		}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return failure{}
+	default:
+		var ne net.Error
+		if errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return failure{retryable: true, outage: true}
+		}
+		return failure{}
+	}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return failure{retryable: true, wait: wait}
+	case status == http.StatusRequestTimeout, status >= 500: // includes Anthropic's 529 overloaded
+		return failure{retryable: true, outage: true, wait: wait}
+	}
+	return failure{}
+}
+
+// retryPolicy bounds how often and how patiently a failed request is sent
+// again.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// backoff returns the delay before retrying after the given failed attempt:
+// exponential in the attempt with full jitter, but never shorter than the
+// provider asked for, and never longer than maxDelay.
+func (p retryPolicy) backoff(attempt int, wait time.Duration) time.Duration {
+	ceiling := min(p.baseDelay<<(attempt-1), p.maxDelay)
+	delay := time.Duration(rand.Int64N(int64(ceiling) + 1))
+	return min(max(delay, wait), p.maxDelay)
+}
+
+// breaker is a circuit breaker. After threshold consecutive outages it
+// opens, failing requests immediately for cooldown; then it lets a single
+// request through, closing again if that succeeds and reopening if not.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time // zero while closed
+	probing  bool
+}
+
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	if wait := b.cooldown - time.Since(b.openedAt); wait > 0 || b.probing {
+		return fmt.Errorf("%w: %d consecutive failures, retry in %s", ErrProviderUnavailable, b.failures, max(wait, 0).Round(time.Second))
+	}
+	b.probing = true
+	return nil
+}
+
+// record notes the outcome of a request let through.
+func (b *breaker) record(outage bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !outage {
+		if !b.openedAt.IsZero() {
+			log.Printf("[llm] provider recovered; closing circuit breaker")
+		}
+		b.failures, b.openedAt, b.probing = 0, time.Time{}, false
+		return
+	}
+	b.failures++
+	if b.probing || (b.openedAt.IsZero() && b.failures >= b.threshold) {
+		log.Printf("[llm] %d consecutive provider failures; opening circuit breaker for %s", b.failures, b.cooldown)
+		b.openedAt, b.probing = time.Now(), false
+	}
+}
+
+// abandon lets another request probe the provider when one let through
+// was cancelled before it had an answer.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// resilience retries a backend's transient failures and stops calling it
+// while it is down.
+type resilience struct {
+	policy  retryPolicy
+	breaker *breaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func newResilience() *resilience {
+	return &resilience{
+		policy:  retryPolicy{maxAttempts: 4, baseDelay: time.Second, maxDelay: 30 * time.Second},
+		breaker: &breaker{threshold: 5, cooldown: 30 * time.Second},
+		sleep:   sleepCtx,
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// wrap returns send with retries and circuit breaking.
+func (r *resilience) wrap(send sendFunc) sendFunc {
+	return func(ctx context.Context, req request) (json.RawMessage, error) {
+		for attempt := 1; ; attempt++ {
+			if err := r.breaker.allow(); err != nil {
+				return nil, err
+			}
+			resp, err := send(ctx, req)
+			if err == nil {
+				r.breaker.record(false)
+				return resp, nil
+			}
+			if ctx.Err() != nil {
+				r.breaker.abandon()
+				return nil, err
+			}
+			f := classify(err)
+			r.breaker.record(f.outage)
+			if !f.retryable {
+				return nil, err
+			}
+			if attempt == r.policy.maxAttempts {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			delay := r.policy.backoff(attempt, f.wait)
+			log.Printf("[llm] %s attempt %d failed, retrying in %s: %v", req.Tool.Name, attempt, delay.Round(time.Millisecond), err)
+			if serr := r.sleep(ctx, delay); serr != nil {
+				return nil, err
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// instantResilience retries without waiting, recording the delays it was
+// asked to wait.
+func instantResilience(delays *[]time.Duration) *resilience {
+	r := newResilience()
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+	return r
+}
+
+func TestResilience_RetriesTransientErrors(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(529)
+			w.Write([]byte(`{"error": {"message": "overloaded"}}`))
+		case 2:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "rate limited"}}`))
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"choices": []map[string]any{{
+				"message":       map[string]any{"role": "assistant", "content": `{"nodes": [], "edges": []}`},
+				"finish_reason": "stop",
+			}}})
+		}
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	c := NewOpenAIClient(srv.URL+"/v1", "", "m")
+	c.completer.send = instantResilience(&delays).wrap(c.sendMessage)
+	if _, err := c.GenerateGraph(context.Background(), domain.Application{}, nil); err != nil {
+		t.Fatalf("GenerateGraph() error = %v", err)
+	}
+	if requests != 3 || len(delays) != 2 {
+		t.Fatalf("sent %d requests after %d delays, want 3 after 2", requests, len(delays))
+	}
+	if delays[0] > time.Second || delays[1] != 3*time.Second {
+		t.Errorf("delays = %v, want at most the base delay, then the 3s Retry-After", delays)
+	}
+}
+
+func TestResilience_GivesUp(t *testing.T) {
+	var delays []time.Duration
+	calls := 0
+	send := instantResilience(&delays).wrap(func(ctx context.Context, req request) (json.RawMessage, error) {
+		calls++
+		return nil, &statusError{StatusCode: http.StatusServiceUnavailable}
+	})
+	_, err := send(context.Background(), request{})
+	if err == nil || !strings.Contains(err.Error(), "giving up after 4 attempts") || calls != 4 {
+		t.Errorf("send() = %v after %d calls, want to give up after 4", err, calls)
+	}
+	for i, d := range delays {
+		if ceiling := time.Second << i; d > ceiling {
+			t.Errorf("delay %d = %s, want at most %s", i, d, ceiling)
+		}
+	}
+
+	// Client errors are not retried.
+	calls = 0
+	send = instantResilience(&delays).wrap(func(ctx context.Context, req request) (json.RawMessage, error) {
+		calls++
+		return nil, fmt.Errorf("call: %w", &statusError{StatusCode: http.StatusBadRequest})
+	})
+	if _, err := send(context.Background(), request{}); err == nil || calls != 1 {
+		t.Errorf("send() = %v after %d calls, want one failed call", err, calls)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	var delays []time.Duration
+	r := instantResilience(&delays)
+	r.policy.maxAttempts = 1
+	r.breaker = &breaker{threshold: 2, cooldown: time.Hour}
+	calls := 0
+	down := true
+	send := r.wrap(func(ctx context.Context, req request) (json.RawMessage, error) {
+		calls++
+		if down {
+			return nil, &statusError{StatusCode: 529}
+		}
+		return json.RawMessage(`{}`), nil
+	})
+	ctx := context.Background()
+
+	send(ctx, request{})
+	send(ctx, request{})
+	if _, err := send(ctx, request{}); !errors.Is(err, ErrProviderUnavailable) || calls != 2 {
+		t.Fatalf("send() with the breaker open = %v after %d calls, want ErrProviderUnavailable without a call", err, calls)
+	}
+
+	// Once the cooldown is over a single request probes the provider.
+	r.breaker.openedAt = time.Now().Add(-2 * time.Hour)
+	down = false
+	if _, err := send(ctx, request{}); err != nil || calls != 3 {
+		t.Errorf("probe = %v after %d calls, want success", err, calls)
+	}
+	if _, err := send(ctx, request{}); err != nil {
+		t.Errorf("send() after the probe succeeded = %v, want the breaker closed", err)
+	}
+
+	// A failed probe opens it again.
+	down = true
+	send(ctx, request{})
+	send(ctx, request{})
+	r.breaker.openedAt = time.Now().Add(-2 * time.Hour)
+	send(ctx, request{})
+	if _, err := send(ctx, request{}); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("send() after a failed probe = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestCompleter_SplitsTruncatedCodebaseAnalysis(t *testing.T) {
+	var requests []request
+	c := completer{send: func(ctx context.Context, req request) (json.RawMessage, error) {
+		requests = append(requests, req)
+		if strings.Contains(req.Prompt, "a.go") && strings.Contains(req.Prompt, "b.go") {
+			return nil, fmt.Errorf("%w (hit %d token limit)", errResponseTruncated, req.MaxTokens)
+		}
+		name := "jobs"
+		if strings.Contains(req.Prompt, "b.go") {
+			name = "queue"
+		}
+		return json.RawMessage(`{"resources": [{"kind": "database", "name": "db"}, {"kind": "queue", "name": "` + name + `"}]}`), nil
+	}, maxTokens: 16384}
+
+	recs, err := c.AnalyzeCodebase(context.Background(), analyzer.CodeContext{Files: []analyzer.FileContent{
+		{Path: "a.go", Content: "package a"},
+		{Path: "b.go", Content: "package b"},
+	}}, domain.ProviderAWS)
+	if err != nil {
+		t.Fatalf("AnalyzeCodebase() error = %v", err)
+	}
+	// 8192 and 16384 tokens for both files, then each file on its own.
+	if len(requests) != 4 || requests[1].MaxTokens != 16384 {
+		t.Errorf("sent %d requests, want 2 escalating and 2 split", len(requests))
+	}
+	if len(recs) != 3 {
+		t.Errorf("AnalyzeCodebase() = %+v, want the database once and both queues", recs)
+	}
+}
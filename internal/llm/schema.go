@@ -0,0 +1,308 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// schema is a JSON Schema document, in the subset schemaFor generates and
+// validate checks: type, properties, required, items, additionalProperties,
+// propertyNames and enum.
+type schema map[string]any
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// enumValues lists the allowed values of the domain's string enums, so the
+// model cannot invent a resource kind or provider.
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(domain.ResourceKind("")):  enumStrings(domain.ValidResourceKinds()),
+	reflect.TypeOf(domain.CloudProvider("")): enumStrings(domain.ValidProviders()),
+}
+
+// optionalFields lists fields that schemaFor would otherwise require.
+// Codebase analysis asks for mappings without Terraform, which is generated
+// separately, and configs the model has nothing to say about may be left out.
+var optionalFields = map[reflect.Type][]string{
+	reflect.TypeOf(domain.ProviderResource{}): {"config", "terraform_hcl"},
+}
+
+func enumStrings[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// schemaFor generates the JSON Schema of T's JSON encoding. Fields are
+// required unless they are omitempty, listed in optionalFields, or hold a
+// map, a timestamp or free-form JSON.
+func schemaFor[T any]() schema {
+	return typeSchema(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func typeSchema(t reflect.Type) schema {
+	switch {
+	case t == timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case t == rawMessageType || t.Kind() == reflect.Interface:
+		return schema{}
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(unmarshalerType):
+		// Custom decoding accepts more than the Go type suggests; only
+		// constrain the JSON type.
+		if typ := jsonType(t.Kind()); typ != "" {
+			return schema{"type": typ}
+		}
+		return schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := typeSchema(t.Elem())
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []any{typ, "null"}
+		}
+		return s
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		s := schema{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+		if values, ok := enumValues[t.Key()]; ok {
+			s["propertyNames"] = schema{"enum": values}
+		}
+		return s
+	case reflect.String:
+		s := schema{"type": "string"}
+		if values, ok := enumValues[t]; ok {
+			s["enum"] = values
+		}
+		return s
+	}
+	if typ := jsonType(t.Kind()); typ != "" {
+		return schema{"type": typ}
+	}
+	return schema{}
+}
+
+func structSchema(t reflect.Type) schema {
+	props := schema{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = typeSchema(f.Type)
+		if !strings.Contains(opts, "omitempty") && isRequired(t, name, f.Type) {
+			required = append(required, name)
+		}
+	}
+	s := schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func isRequired(parent reflect.Type, name string, t reflect.Type) bool {
+	for _, optional := range optionalFields[parent] {
+		if name == optional {
+			return false
+		}
+	}
+	return t != timeType && t != rawMessageType && t.Kind() != reflect.Map && t.Kind() != reflect.Interface
+}
+
+func jsonType(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return ""
+}
+
+// validate checks the JSON document data against s and returns one message
+// per violation, each prefixed with the path of the offending value.
+func validate(s schema, data []byte) []string {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	var problems []string
+	validateValue(s, v, "$", &problems)
+	return problems
+}
+
+func validateValue(s schema, v any, path string, problems *[]string) {
+	if typ, ok := s["type"]; ok && !matchesType(typ, v) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, describeType(typ), valueType(v)))
+		return
+	}
+	if values, ok := s["enum"].([]string); ok {
+		str, _ := v.(string)
+		if !contains(values, str) {
+			*problems = append(*problems, fmt.Sprintf("%s: %q is not one of %s", path, str, strings.Join(values, ", ")))
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if props, ok := s["properties"].(schema); ok {
+			for _, name := range requiredOf(s) {
+				if _, present := v[name]; !present {
+					*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+				}
+			}
+			names := make([]string, 0, len(props))
+			for name := range props {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if pv, present := v[name]; present {
+					validateValue(props[name].(schema), pv, path+"."+name, problems)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if names, ok := s["propertyNames"].(schema); ok {
+			if values, ok := names["enum"].([]string); ok {
+				for _, k := range keys {
+					if !contains(values, k) {
+						*problems = append(*problems, fmt.Sprintf("%s: key %q is not one of %s", path, k, strings.Join(values, ", ")))
+					}
+				}
+			}
+		}
+		if additional, ok := s["additionalProperties"].(schema); ok {
+			for _, k := range keys {
+				validateValue(additional, v[k], path+"."+k, problems)
+			}
+		}
+	case []any:
+		if items, ok := s["items"].(schema); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+}
+
+func requiredOf(s schema) []string {
+	required, _ := s["required"].([]string)
+	return required
+}
+
+func matchesType(typ any, v any) bool {
+	switch typ := typ.(type) {
+	case string:
+		return matchesOne(typ, v)
+	case []any:
+		for _, t := range typ {
+			if name, ok := t.(string); ok && matchesOne(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesOne(typ string, v any) bool {
+	switch typ {
+	case "null":
+		return v == nil
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+func describeType(typ any) string {
+	if types, ok := typ.([]any); ok {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = fmt.Sprint(t)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(typ)
+}
+
+func valueType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaFor(t *testing.T) {
+	s := schemaFor[ResourceRecommendation]()
+	if s["type"] != "object" {
+		t.Fatalf("type = %v, want object", s["type"])
+	}
+	if got := requiredOf(s); !reflect.DeepEqual(got, []string{"kind", "name"}) {
+		t.Errorf("required = %v, want kind and name (spec and mappings are free-form)", got)
+	}
+	props := s["properties"].(schema)
+	if kind := props["kind"].(schema); len(kind["enum"].([]string)) != 9 {
+		t.Errorf("kind enum = %v, want every resource kind", kind["enum"])
+	}
+	mappings := props["mappings"].(schema)
+	if names := mappings["propertyNames"].(schema); !reflect.DeepEqual(names["enum"], []string{"aws", "gcp"}) {
+		t.Errorf("mappings keys = %v, want the providers", names["enum"])
+	}
+	if mapping := mappings["additionalProperties"].(schema); !reflect.DeepEqual(requiredOf(mapping), []string{"service_name"}) {
+		t.Errorf("mapping required = %v, want only service_name", requiredOf(mapping))
+	}
+
+	plan := schemaFor[HostingPlanResult]()
+	if cost := plan["properties"].(schema)["estimated_cost"].(schema); !reflect.DeepEqual(cost["type"], []any{"object", "null"}) {
+		t.Errorf("estimated_cost type = %v, want a nullable object", cost["type"])
+	}
+	live := schemaFor[LiveResourceParseResult]()
+	item := live["properties"].(schema)["resources"].(schema)["items"].(schema)
+	if details := item["properties"].(schema)["details"].(schema); details["additionalProperties"] != nil {
+		t.Errorf("details = %v, want any object (StringMap decodes non-string values)", details)
+	}
+	if contains(requiredOf(item), "last_checked") {
+		t.Error("last_checked is required; timestamps are set by the server")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := schemaFor[GraphResult]()
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "valid", data: `{"nodes": [{"id": "a", "label": "A", "kind": "compute", "service": "ECS"}], "edges": []}`},
+		{name: "not JSON", data: `{"nodes": [`, want: []string{"invalid JSON"}},
+		{name: "array instead of object", data: `[]`, want: []string{"$: expected object, got array"}},
+		{name: "missing properties", data: `{"nodes": []}`, want: []string{`$: missing required property "edges"`}},
+		{name: "wrong nested type", data: `{"nodes": [{"id": 1, "label": "A", "kind": "compute", "service": "ECS"}], "edges": []}`, want: []string{"$.nodes[0].id: expected string, got number"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validate(s, []byte(tt.data))
+			if len(got) != len(tt.want) {
+				t.Fatalf("validate() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("validate()[%d] = %q, want prefix %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	rec := schemaFor[ResourceRecommendation]()
+	got := validate(rec, []byte(`{"kind": "vm", "name": "x", "mappings": {"azure": {"service_name": "VM"}, "aws": {}}}`))
+	want := []string{
+		`$.kind: "vm" is not one of database, compute, storage, cache, queue, cdn, network, secrets, policy`,
+		`$.mappings: key "azure" is not one of aws, gcp`,
+		`$.mappings.aws: missing required property "service_name"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("validate() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// StreamFunc receives the markdown of a plan as the model writes it: all of
+// the text so far, each time it grows. A retried request starts over, so
+// the text can also stop extending the previous one; it is then the start
+// of the new attempt.
+type StreamFunc func(text string)
+
+type streamKey struct{}
+
+// WithStream returns a copy of ctx whose plan generations report their
+// markdown to fn while the model writes it, on backends that can stream.
+// Cached and replayed answers arrive whole, without calls to fn. fn is called
+// on the goroutine making the LLM call.
+func WithStream(ctx context.Context, fn StreamFunc) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+// streamerFor returns the streamer of req's streamed field, or nil when the
+// operation streams nothing or ctx has no StreamFunc.
+func streamerFor(ctx context.Context, req request) *fieldStreamer {
+	fn, _ := ctx.Value(streamKey{}).(StreamFunc)
+	if fn == nil || req.Stream == "" {
+		return nil
+	}
+	return &fieldStreamer{field: req.Stream, fn: fn}
+}
+
+// fieldStreamer follows a tool input as it streams in and reports the
+// partial value of one of its string fields whenever it grows.
+type fieldStreamer struct {
+	field string
+	fn    StreamFunc
+	sent  int
+}
+
+// update takes the tool input received so far.
+func (s *fieldStreamer) update(partial []byte) {
+	if text, ok := partialString(partial, s.field); ok && len(text) > s.sent {
+		s.sent = len(text)
+		s.fn(text)
+	}
+}
+
+// partialString returns the value, as far as it has arrived, of the string
+// field of the JSON object whose beginning is data. Only top-level fields
+// are looked at.
+func partialString(data []byte, field string) (string, bool) {
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return "", false
+	}
+	i++
+	for {
+		i = skipSpace(data, i)
+		if i < len(data) && data[i] == ',' {
+			i = skipSpace(data, i+1)
+		}
+		if i >= len(data) || data[i] != '"' {
+			return "", false
+		}
+		end := stringEnd(data, i)
+		if end < 0 {
+			return "", false
+		}
+		var key string
+		if err := json.Unmarshal(data[i:end], &key); err != nil {
+			return "", false
+		}
+		i = skipSpace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return "", false
+		}
+		i = skipSpace(data, i+1)
+		if i >= len(data) {
+			return "", false
+		}
+		if key == field {
+			if data[i] != '"' {
+				return "", false
+			}
+			return partialStringValue(data[i:])
+		}
+		if i = valueEnd(data, i); i < 0 {
+			return "", false
+		}
+	}
+}
+
+// partialStringValue decodes the JSON string starting data, closing it after
+// its last complete character if it has not ended yet.
+func partialStringValue(data []byte) (string, bool) {
+	var raw []byte
+	if end := stringEnd(data, 0); end >= 0 {
+		raw = data[:end]
+	} else {
+		raw = append(append([]byte(nil), data[:completePrefix(data)]...), '"')
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// completePrefix returns the length of the longest prefix of the unfinished
+// JSON string data that does not end inside an escape sequence, counting a
+// UTF-16 surrogate pair as one.
+func completePrefix(data []byte) int {
+	n := 1
+	for i := 1; i < len(data); {
+		if data[i] != '\\' {
+			i++
+			n = i
+			continue
+		}
+		if i+1 >= len(data) {
+			break
+		}
+		if data[i+1] != 'u' {
+			i += 2
+			n = i
+			continue
+		}
+		if i+6 > len(data) {
+			break
+		}
+		// A high surrogate is only complete with its low half.
+		if hex := string(data[i+2 : i+4]); hex >= "D8" && hex <= "DB" || hex >= "d8" && hex <= "db" {
+			if i+12 > len(data) {
+				break
+			}
+			i += 12
+		} else {
+			i += 6
+		}
+		n = i
+	}
+	return n
+}
+
+// stringEnd returns the index just past the JSON string starting at data[i],
+// or -1 if it does not end within data.
+func stringEnd(data []byte, i int) int {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// valueEnd returns the index just past the JSON value starting at data[i],
+// or -1 if it does not end within data.
+func valueEnd(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		return stringEnd(data, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case '"':
+				if j = stringEnd(data, j); j < 0 {
+					return -1
+				}
+				j--
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return -1
+	default:
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return j
+			}
+		}
+		return -1
+	}
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestPartialString(t *testing.T) {
+	tests := []struct {
+		data, want string
+		ok         bool
+	}{
+		{`{"content": "# Pla`, "# Pla", true},
+		{`{"estimated_cost": {"breakdown": {"content": 1}}, "content": "ab\nc`, "ab\nc", true},
+		{`{"content": "line\`, "line", true},
+		{`{"content": "caf\u00`, "caf", true},
+		{`{"content": "\ud83d\ude0`, "", true},
+		{`{"content": "😀!`, "😀!", true},
+		{`{"content": "done", "estimated_cost": nul`, "done", true},
+		{`{"estimated_cost": {"monthly_cost_usd": 12`, "", false},
+		{`{"conte`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := partialString([]byte(tt.data), "content")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("partialString(%s) = %q, %t; want %q, %t", tt.data, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOpenAIClient_StreamsPlan(t *testing.T) {
+	args := `{"content": "# Hosting plan\n\nUse Cloud Run.", "estimated_cost": {"monthly_cost_usd": 40, "breakdown": {}}}`
+	var streamed bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		streamed = req.Stream && req.StreamOptions != nil
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"tool_calls\": [{\"index\": 0, \"function\": {\"name\": \"record_hosting_plan\"}}]}}]}\n\n")
+		for i := 0; i < len(args); i += 7 {
+			chunk := args[i:min(i+7, len(args))]
+			fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"tool_calls\": [{\"index\": 0, \"function\": {\"arguments\": %q}}]}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {}, \"finish_reason\": \"tool_calls\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 10, \"completion_tokens\": 20}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	var texts []string
+	ctx := WithStream(context.Background(), func(text string) { texts = append(texts, text) })
+	c := NewOpenAIClient(srv.URL+"/v1", "", "gpt-4o")
+	plan, err := c.GenerateHostingPlan(ctx, domain.Application{}, nil, "")
+	if err != nil {
+		t.Fatalf("GenerateHostingPlan() error = %v", err)
+	}
+	if !streamed || plan.Content != "# Hosting plan\n\nUse Cloud Run." || plan.EstimatedCost.MonthlyCostUSD != 40 {
+		t.Errorf("GenerateHostingPlan() = %+v", plan)
+	}
+	if len(texts) < 3 || texts[len(texts)-1] != plan.Content {
+		t.Fatalf("streamed %q, want the content in several growing steps", texts)
+	}
+	for i := 1; i < len(texts); i++ {
+		if !strings.HasPrefix(texts[i], texts[i-1]) {
+			t.Errorf("streamed text %q does not extend %q", texts[i], texts[i-1])
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Names of the system prompts, one per operation. A prompt named n lives in
+// prompts/n.txt and can be overridden by n.txt in the overrides directory.
+const (
+	PromptResourceAnalysis     = "resource_analysis"
+	PromptCodebaseAnalysis     = "codebase_analysis"
+	PromptHostingPlan          = "hosting_plan"
+	PromptMigrationPlan        = "migration_plan"
+	PromptGraph                = "graph"
+	PromptTerraformHCL         = "terraform_hcl"
+	PromptDiscoveryCommands    = "discovery_commands"
+	PromptDiscoveryOutputParse = "discovery_output_parse"
+	PromptPlanRefinement       = "plan_refinement"
+	PromptResourceRefinement   = "resource_refinement"
+)
+
+//go:embed prompts/*.txt
+var embeddedPrompts embed.FS
+
+// Prompt is a versioned system prompt. Its file starts with a header
+// declaring the version, separated from the text by a "---" line:
+//
+//	version: 3
+//	---
+//	You are an expert cloud infrastructure architect. ...
+type Prompt struct {
+	Name    string `json:"name"`
+	Version string `json:"version"` // as declared, or "custom-<sha256 prefix>" for an override without a header
+	Source  string `json:"source"`  // "embedded", or the path of the override file
+	SHA256  string `json:"sha256"`  // of Text
+	Text    string `json:"-"`
+}
+
+// ID identifies the prompt and its version, as recorded on what it produced,
+// e.g. "hosting_plan@3".
+func (p Prompt) ID() string { return p.Name + "@" + p.Version }
+
+// activePrompts holds the prompts in use, by name.
+var activePrompts atomic.Pointer[map[string]Prompt]
+
+func init() {
+	prompts, err := loadPrompts("")
+	if err != nil {
+		panic(err)
+	}
+	activePrompts.Store(&prompts)
+}
+
+// LoadPrompts makes the prompts in dir override the embedded defaults of the
+// same name. Files that name no known prompt are an error, so a typo cannot
+// silently leave the default in place. An empty dir restores the defaults.
+func LoadPrompts(dir string) error {
+	prompts, err := loadPrompts(dir)
+	if err != nil {
+		return err
+	}
+	activePrompts.Store(&prompts)
+	return nil
+}
+
+func loadPrompts(dir string) (map[string]Prompt, error) {
+	prompts := make(map[string]Prompt)
+	files, err := fs.Glob(embeddedPrompts, "prompts/*.txt")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		data, err := embeddedPrompts.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		p, err := parsePrompt(strings.TrimSuffix(filepath.Base(f), ".txt"), "embedded", data)
+		if err != nil {
+			return nil, err
+		}
+		if p.Version == "" {
+			return nil, fmt.Errorf("embedded prompt %s declares no version", p.Name)
+		}
+		prompts[p.Name] = p
+	}
+	if dir == "" {
+		return prompts, nil
+	}
+
+	overrides, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("list prompt overrides: %w", err)
+	}
+	for _, f := range overrides {
+		name := strings.TrimSuffix(filepath.Base(f), ".txt")
+		if _, ok := prompts[name]; !ok {
+			return nil, fmt.Errorf("prompt override %s: no prompt named %q", f, name)
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read prompt override: %w", err)
+		}
+		p, err := parsePrompt(name, f, data)
+		if err != nil {
+			return nil, err
+		}
+		if p.Version == "" {
+			p.Version = "custom-" + p.SHA256[:12]
+		}
+		prompts[name] = p
+	}
+	return prompts, nil
+}
+
+// parsePrompt splits a prompt file into its version header and text. A
+// file without a header is all text, with no version.
+func parsePrompt(name, source string, data []byte) (Prompt, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var version string
+	if header, body, ok := strings.Cut(text, "\n---\n"); ok && strings.HasPrefix(header, "version:") {
+		version = strings.TrimSpace(strings.TrimPrefix(header, "version:"))
+		if version == "" || strings.ContainsAny(version, " \t\n@") {
+			return Prompt{}, fmt.Errorf("prompt %s (%s): invalid version %q", name, source, version)
+		}
+		text = body
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Prompt{}, fmt.Errorf("prompt %s (%s) is empty", name, source)
+	}
+	sum := sha256.Sum256([]byte(text))
+	return Prompt{Name: name, Version: version, Source: source, SHA256: hex.EncodeToString(sum[:]), Text: text}, nil
+}
+
+// ActivePrompts returns the prompts in use, by name.
+func ActivePrompts() []Prompt {
+	prompts := *activePrompts.Load()
+	list := make([]Prompt, 0, len(prompts))
+	for _, p := range prompts {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// PromptVersion returns the ID of the prompt named name in use, to record
+// on what an operation produces.
+func PromptVersion(name string) string {
+	return systemPrompt(name).ID()
+}
+
+// systemPrompt returns the prompt named name in use. Names are constants,
+// so an unknown one is a programming error.
+func systemPrompt(name string) Prompt {
+	p, ok := (*activePrompts.Load())[name]
+	if !ok {
+		panic("llm: unknown prompt " + name)
+	}
+	return p
+}
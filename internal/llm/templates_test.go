@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrompts(t *testing.T) {
+	t.Cleanup(func() { LoadPrompts("") })
+
+	for _, p := range ActivePrompts() {
+		if p.Source != "embedded" || p.Version == "" || strings.HasPrefix(p.Text, "version:") {
+			t.Errorf("embedded prompt %+v, want a version and text without its header", p)
+		}
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "graph.txt"), []byte("version: 2-tuned\n---\nDraw the graph.\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "hosting_plan.txt"), []byte("Plan the hosting.\r\n"), 0o644)
+	if err := LoadPrompts(dir); err != nil {
+		t.Fatalf("LoadPrompts() error = %v", err)
+	}
+	if p := systemPrompt(PromptGraph); p.Text != "Draw the graph." || p.ID() != "graph@2-tuned" || p.Source != filepath.Join(dir, "graph.txt") {
+		t.Errorf("graph prompt = %+v", p)
+	}
+	if got := PromptVersion(PromptHostingPlan); !strings.HasPrefix(got, "hosting_plan@custom-") {
+		t.Errorf("PromptVersion(hosting_plan) without a header = %q, want a custom hash version", got)
+	}
+	if p := systemPrompt(PromptMigrationPlan); p.Source != "embedded" {
+		t.Errorf("migration plan prompt source = %q, want the embedded default", p.Source)
+	}
+
+	os.WriteFile(filepath.Join(dir, "hosting-plan.txt"), []byte("typo"), 0o644)
+	if err := LoadPrompts(dir); err == nil || !strings.Contains(err.Error(), `no prompt named "hosting-plan"`) {
+		t.Errorf("LoadPrompts() with an unknown prompt: got %v", err)
+	}
+	if p := systemPrompt(PromptGraph); p.Text != "Draw the graph." {
+		t.Errorf("a failed LoadPrompts() changed the active prompts")
+	}
+}
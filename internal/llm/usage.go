@@ -0,0 +1,216 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// Usage is what one operation spent, summed over its attempts.
+type Usage struct {
+	Operation     string
+	Model         string
+	ApplicationID *uuid.UUID // nil for operations outside any application
+	InputTokens   int64
+	OutputTokens  int64
+	Latency       time.Duration
+	CostUSD       float64
+}
+
+// Meter decides whether operations may reach the model and records what
+// they spend.
+type Meter interface {
+	// Allow returns an error, e.g. wrapping domain.ErrBudgetExceeded, when
+	// an operation for the application appID (nil for none) must not run.
+	Allow(ctx context.Context, appID *uuid.UUID) error
+	// Record stores the usage of an operation that reached the model,
+	// whether it succeeded or not.
+	Record(ctx context.Context, u Usage)
+}
+
+type applicationKey struct{}
+
+// ForApplication returns a copy of ctx whose LLM calls are accounted to the
+// application appID. Operations whose arguments name the application need
+// no such context.
+func ForApplication(ctx context.Context, appID uuid.UUID) context.Context {
+	return context.WithValue(ctx, applicationKey{}, appID)
+}
+
+func applicationFrom(ctx context.Context) *uuid.UUID {
+	id, ok := ctx.Value(applicationKey{}).(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// tokenTally sums the tokens of the responses to one operation.
+type tokenTally struct {
+	input, output atomic.Int64
+}
+
+type tallyKey struct{}
+
+// countTokens adds the token usage of a response to the tally of the
+// metered operation ctx belongs to. Backends call it for every response,
+// including truncated and rejected ones, which are billed all the same.
+func countTokens(ctx context.Context, input, output int64) {
+	if t, ok := ctx.Value(tallyKey{}).(*tokenTally); ok {
+		t.input.Add(input)
+		t.output.Add(output)
+	}
+}
+
+// modelPrice is a model's list price in US dollars per million tokens.
+type modelPrice struct {
+	input, output float64
+}
+
+// modelPrices holds list prices by model name prefix; the longest matching
+// prefix wins. Models not listed, such as self-hosted ones, cost nothing.
+var modelPrices = map[string]modelPrice{
+	"claude-opus-4":     {15, 75},
+	"claude-sonnet-4":   {3, 15},
+	"claude-haiku-4":    {1, 5},
+	"claude-3-7-sonnet": {3, 15},
+	"claude-3-5-sonnet": {3, 15},
+	"claude-3-5-haiku":  {0.8, 4},
+	"gpt-4o":            {2.5, 10},
+	"gpt-4o-mini":       {0.15, 0.6},
+	"gpt-4.1":           {2, 8},
+	"gpt-4.1-mini":      {0.4, 1.6},
+	"gpt-4.1-nano":      {0.1, 0.4},
+	"o3":                {2, 8},
+	"o4-mini":           {1.1, 4.4},
+}
+
+// EstimateCost returns what input and output tokens of model cost at list
+// prices, in US dollars.
+func EstimateCost(model string, input, output int64) float64 {
+	var price modelPrice
+	matched := ""
+	for prefix, p := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			price, matched = p, prefix
+		}
+	}
+	return (float64(input)*price.input + float64(output)*price.output) / 1e6
+}
+
+// MeteredClient is a Client decorator that asks a Meter before every
+// operation and reports the tokens, latency and estimated cost of those
+// that reach the model. Wrap it in a CachedClient so cache hits, which
+// spend nothing, are neither metered nor refused.
+type MeteredClient struct {
+	inner Client
+	meter Meter
+	model string
+}
+
+// NewMeteredClient wraps inner with meter.
+func NewMeteredClient(inner Client, meter Meter) *MeteredClient {
+	var model string
+	if m, ok := inner.(interface{ Model() string }); ok {
+		model = m.Model()
+	}
+	return &MeteredClient{inner: inner, meter: meter, model: model}
+}
+
+// Model returns the model of the wrapped client.
+func (c *MeteredClient) Model() string { return c.model }
+
+// metered runs call for op on behalf of the application appID, or the one
+// ctx is accounted to when nil, if the meter allows it.
+func metered[T any](ctx context.Context, c *MeteredClient, op string, appID *uuid.UUID, call func(ctx context.Context) (T, error)) (T, error) {
+	if appID == nil {
+		appID = applicationFrom(ctx)
+	}
+	if err := c.meter.Allow(ctx, appID); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	tally := &tokenTally{}
+	start := time.Now()
+	result, err := call(context.WithValue(ctx, tallyKey{}, tally))
+	u := Usage{
+		Operation:     op,
+		Model:         c.model,
+		ApplicationID: appID,
+		InputTokens:   tally.input.Load(),
+		OutputTokens:  tally.output.Load(),
+		Latency:       time.Since(start),
+	}
+	u.CostUSD = EstimateCost(u.Model, u.InputTokens, u.OutputTokens)
+	// Failures that spent no tokens never reached the model.
+	if err == nil || u.InputTokens > 0 || u.OutputTokens > 0 {
+		c.meter.Record(ctx, u)
+	}
+	return result, err
+}
+
+func (c *MeteredClient) AnalyzeResourceNeed(ctx context.Context, description string, provider domain.CloudProvider) (ResourceRecommendation, error) {
+	return metered(ctx, c, "AnalyzeResourceNeed", nil, func(ctx context.Context) (ResourceRecommendation, error) {
+		return c.inner.AnalyzeResourceNeed(ctx, description, provider)
+	})
+}
+
+func (c *MeteredClient) AnalyzeCodebase(ctx context.Context, codeCtx analyzer.CodeContext, provider domain.CloudProvider) ([]ResourceRecommendation, error) {
+	return metered(ctx, c, "AnalyzeCodebase", nil, func(ctx context.Context) ([]ResourceRecommendation, error) {
+		return c.inner.AnalyzeCodebase(ctx, codeCtx, provider)
+	})
+}
+
+func (c *MeteredClient) GenerateHostingPlan(ctx context.Context, app domain.Application, resources []domain.Resource, complianceContext string) (HostingPlanResult, error) {
+	return metered(ctx, c, "GenerateHostingPlan", &app.ID, func(ctx context.Context) (HostingPlanResult, error) {
+		return c.inner.GenerateHostingPlan(ctx, app, resources, complianceContext)
+	})
+}
+
+func (c *MeteredClient) GenerateMigrationPlan(ctx context.Context, app domain.Application, resources []domain.Resource, from, to domain.CloudProvider) (MigrationPlanResult, error) {
+	return metered(ctx, c, "GenerateMigrationPlan", &app.ID, func(ctx context.Context) (MigrationPlanResult, error) {
+		return c.inner.GenerateMigrationPlan(ctx, app, resources, from, to)
+	})
+}
+
+func (c *MeteredClient) GenerateGraph(ctx context.Context, app domain.Application, resources []domain.Resource) (GraphResult, error) {
+	return metered(ctx, c, "GenerateGraph", &app.ID, func(ctx context.Context) (GraphResult, error) {
+		return c.inner.GenerateGraph(ctx, app, resources)
+	})
+}
+
+func (c *MeteredClient) GenerateTerraformHCL(ctx context.Context, resource domain.Resource, provider domain.CloudProvider, complianceContext string) (TerraformHCLResult, error) {
+	return metered(ctx, c, "GenerateTerraformHCL", &resource.ApplicationID, func(ctx context.Context) (TerraformHCLResult, error) {
+		return c.inner.GenerateTerraformHCL(ctx, resource, provider, complianceContext)
+	})
+}
+
+func (c *MeteredClient) GenerateDiscoveryCommands(ctx context.Context, app domain.Application, codeCtx analyzer.CodeContext) (DiscoveryCommandResult, error) {
+	return metered(ctx, c, "GenerateDiscoveryCommands", &app.ID, func(ctx context.Context) (DiscoveryCommandResult, error) {
+		return c.inner.GenerateDiscoveryCommands(ctx, app, codeCtx)
+	})
+}
+
+func (c *MeteredClient) ParseDiscoveryOutput(ctx context.Context, app domain.Application, commandOutputs []CommandOutput) (LiveResourceParseResult, error) {
+	return metered(ctx, c, "ParseDiscoveryOutput", &app.ID, func(ctx context.Context) (LiveResourceParseResult, error) {
+		return c.inner.ParseDiscoveryOutput(ctx, app, commandOutputs)
+	})
+}
+
+func (c *MeteredClient) RefinePlan(ctx context.Context, app domain.Application, plan domain.InfrastructurePlan, instruction string) (RefinedPlanResult, error) {
+	return metered(ctx, c, "RefinePlan", &app.ID, func(ctx context.Context) (RefinedPlanResult, error) {
+		return c.inner.RefinePlan(ctx, app, plan, instruction)
+	})
+}
+
+func (c *MeteredClient) RefineResource(ctx context.Context, resource domain.Resource, instruction string) (ResourceRecommendation, error) {
+	return metered(ctx, c, "RefineResource", &resource.ApplicationID, func(ctx context.Context) (ResourceRecommendation, error) {
+		return c.inner.RefineResource(ctx, resource, instruction)
+	})
+}
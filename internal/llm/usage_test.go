@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// recordingMeter allows operations until refuse is set and records their
+// usage.
+type recordingMeter struct {
+	refuse  error
+	allowed []*uuid.UUID
+	usage   []Usage
+}
+
+func (m *recordingMeter) Allow(ctx context.Context, appID *uuid.UUID) error {
+	m.allowed = append(m.allowed, appID)
+	return m.refuse
+}
+
+func (m *recordingMeter) Record(ctx context.Context, u Usage) {
+	m.usage = append(m.usage, u)
+}
+
+func TestMeteredClient(t *testing.T) {
+	// The first answer fails validation, so the operation spends two
+	// responses' worth of tokens.
+	srv := newFakeChatServer(t,
+		chatReply{args: `{"nodes": "none"}`},
+		chatReply{args: `{"nodes": [], "edges": []}`},
+	)
+	meter := &recordingMeter{}
+	c := NewMeteredClient(NewOpenAIClient(srv.URL+"/v1", "", "gpt-4o"), meter)
+	app := domain.NewApplication("metered", "", "", "", domain.ProviderAWS)
+
+	if _, err := c.GenerateGraph(context.Background(), app, nil); err != nil {
+		t.Fatalf("GenerateGraph() error = %v", err)
+	}
+	if len(meter.usage) != 1 {
+		t.Fatalf("recorded %d usages, want 1", len(meter.usage))
+	}
+	u := meter.usage[0]
+	if u.Operation != "GenerateGraph" || u.Model != "gpt-4o" || u.ApplicationID == nil || *u.ApplicationID != app.ID {
+		t.Errorf("usage = %+v", u)
+	}
+	if u.InputTokens != 20 || u.OutputTokens != 40 {
+		t.Errorf("usage tokens = %d/%d, want 20/40 over both attempts", u.InputTokens, u.OutputTokens)
+	}
+	if want := (20*2.5 + 40*10) / 1e6; math.Abs(u.CostUSD-want) > 1e-12 {
+		t.Errorf("usage cost = %g, want %g", u.CostUSD, want)
+	}
+
+	// Operations without an application in their arguments take it from
+	// the context.
+	c.AnalyzeResourceNeed(ForApplication(context.Background(), app.ID), "a database", domain.ProviderAWS)
+	if got := meter.allowed[1]; got == nil || *got != app.ID {
+		t.Errorf("Allow() application = %v, want %s", got, app.ID)
+	}
+
+	// A refused operation never reaches the model.
+	meter.refuse = domain.ErrBudgetExceeded
+	requests := len(srv.requests)
+	if _, err := c.GenerateGraph(context.Background(), app, nil); !errors.Is(err, domain.ErrBudgetExceeded) {
+		t.Errorf("GenerateGraph() over budget: got %v, want ErrBudgetExceeded", err)
+	}
+	if len(srv.requests) != requests || len(meter.usage) != 2 {
+		t.Errorf("refused operation sent %d requests and recorded %d usages", len(srv.requests)-requests, len(meter.usage)-2)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"claude-sonnet-4-5", 3 + 15},
+		{"gpt-4o", 2.5 + 10},
+		{"gpt-4o-mini-2024-07-18", 0.15 + 0.6}, // longest prefix wins
+		{"llama3.1", 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateCost(tt.model, 1e6, 1e6); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EstimateCost(%q) = %g, want %g", tt.model, got, tt.want)
+		}
+	}
+}
@@ -1,12 +1,29 @@
 package mcp
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	gomcp "github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/matthewdriscoll/infraplane/internal/audit"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
 	"github.com/matthewdriscoll/infraplane/internal/service"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
+// Authenticator returns the context a tool call runs with, carrying the
+// caller's principal so the services can check its roles. It is called for
+// every tool call, so revoked credentials stop working without a restart.
+type Authenticator func(ctx context.Context) (context.Context, error)
+
 // NewServer creates and configures the MCP server with all tools registered.
+// A nil authenticate runs tool calls without a principal; a nil orgSvc leaves
+// them unscoped, seeing every organization.
 func NewServer(
 	appSvc *service.ApplicationService,
 	resSvc *service.ResourceService,
@@ -14,17 +31,84 @@ func NewServer(
 	depSvc *service.DeploymentService,
 	graphSvc *service.GraphService,
 	discSvc *service.DiscoveryService,
+	orgSvc *service.OrganizationService,
 	complianceRegistry *compliance.Registry,
+	authenticate Authenticator,
 ) *server.MCPServer {
-	s := server.NewMCPServer(
-		"infraplane",
-		"0.1.0",
+	opts := []server.ServerOption{
 		server.WithToolCapabilities(false),
 		server.WithRecovery(),
-	)
+		server.WithToolHandlerMiddleware(auditMiddleware),
+		server.WithToolHandlerMiddleware(cacheMiddleware),
+	}
+	if authenticate != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(authMiddleware(authenticate)))
+	}
+	if orgSvc != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(tenantMiddleware(orgSvc)))
+	}
+	s := server.NewMCPServer("infraplane", "0.1.0", opts...)
 
 	handlers := NewToolHandlers(appSvc, resSvc, planSvc, depSvc, graphSvc, discSvc, complianceRegistry)
 	handlers.RegisterAll(s)
 
 	return s
 }
+
+// auditMiddleware marks every tool call as coming through MCP, with a fresh
+// request ID, for the audit log entries of the changes it makes.
+func auditMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+		return next(audit.WithRequest(ctx, domain.AuditSourceMCP, uuid.NewString()), req)
+	}
+}
+
+// cacheMiddleware makes tool calls with no_cache set bypass the LLM response
+// cache.
+func cacheMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+		if req.GetBool("no_cache", false) {
+			ctx = llm.WithoutCache(ctx)
+		}
+		return next(ctx, req)
+	}
+}
+
+// authMiddleware runs every tool call as the principal authenticate returns,
+// failing the call when the credential is rejected.
+func authMiddleware(authenticate Authenticator) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+			ctx, err := authenticate(ctx)
+			if err != nil {
+				return toolError(err), nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// tenantMiddleware scopes every tool call to the organization named by its
+// org argument or, without one, to the caller's home organization, failing
+// the call when the caller does not belong to it. It must run after
+// authMiddleware.
+func tenantMiddleware(orgs *service.OrganizationService) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+			var org domain.Organization
+			var err error
+			if slug := req.GetString("org", ""); slug != "" {
+				org, err = orgs.Enter(ctx, slug)
+				if errors.Is(err, domain.ErrNotFound) {
+					err = fmt.Errorf("organization '%s' not found", slug)
+				}
+			} else {
+				org, err = orgs.Home(ctx)
+			}
+			if err != nil {
+				return toolError(err), nil
+			}
+			return next(tenant.WithOrg(ctx, org), req)
+		}
+	}
+}
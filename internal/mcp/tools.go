@@ -3,15 +3,17 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	gomcp "github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
 	"github.com/matthewdriscoll/infraplane/internal/service"
 )
 
@@ -47,24 +49,45 @@ func NewToolHandlers(
 	}
 }
 
-// RegisterAll registers all tools on the MCP server.
+// RegisterAll registers all tools on the MCP server, each with the org
+// argument.
 func (h *ToolHandlers) RegisterAll(s *server.MCPServer) {
-	s.AddTool(registerApplicationTool(), h.handleRegisterApplication)
-	s.AddTool(listApplicationsTool(), h.handleListApplications)
-	s.AddTool(getApplicationTool(), h.handleGetApplication)
-	s.AddTool(addResourceTool(), h.handleAddResource)
-	s.AddTool(removeResourceTool(), h.handleRemoveResource)
-	s.AddTool(getHostingPlanTool(), h.handleGetHostingPlan)
-	s.AddTool(planMigrationTool(), h.handlePlanMigration)
-	s.AddTool(deployTool(), h.handleDeploy)
-	s.AddTool(getDeploymentStatusTool(), h.handleGetDeploymentStatus)
-	s.AddTool(generateGraphTool(), h.handleGenerateGraph)
-	s.AddTool(discoverLiveResourcesTool(), h.handleDiscoverLiveResources)
-	s.AddTool(listComplianceFrameworksTool(), h.handleListComplianceFrameworks)
+	add := func(tool gomcp.Tool, handler server.ToolHandlerFunc) {
+		orgOption()(&tool)
+		s.AddTool(tool, handler)
+	}
+	add(registerApplicationTool(), h.handleRegisterApplication)
+	add(listApplicationsTool(), h.handleListApplications)
+	add(getApplicationTool(), h.handleGetApplication)
+	add(addResourceTool(), h.handleAddResource)
+	add(removeResourceTool(), h.handleRemoveResource)
+	add(getHostingPlanTool(), h.handleGetHostingPlan)
+	add(planMigrationTool(), h.handlePlanMigration)
+	add(refinePlanTool(), h.handleRefinePlan)
+	add(refineResourceTool(), h.handleRefineResource)
+	add(deployTool(), h.handleDeploy)
+	add(getDeploymentStatusTool(), h.handleGetDeploymentStatus)
+	add(destroyTool(), h.handleDestroy)
+	add(setDestroyProtectionTool(), h.handleSetDestroyProtection)
+	add(generateGraphTool(), h.handleGenerateGraph)
+	add(discoverLiveResourcesTool(), h.handleDiscoverLiveResources)
+	add(listComplianceFrameworksTool(), h.handleListComplianceFrameworks)
 }
 
 // --- Tool Definitions ---
 
+// orgOption adds the org argument naming the organization a tool call acts
+// in; see tenantMiddleware.
+func orgOption() gomcp.ToolOption {
+	return gomcp.WithString("org", gomcp.Description("Slug of the organization to act in (default: the organization of your API key, or 'default')"))
+}
+
+// noCacheOption adds the no_cache argument to tools whose answers come from
+// the LLM; see cacheMiddleware.
+func noCacheOption() gomcp.ToolOption {
+	return gomcp.WithBoolean("no_cache", gomcp.Description("Ask the LLM afresh instead of reusing its cached answer for unchanged inputs (default false)"))
+}
+
 func registerApplicationTool() gomcp.Tool {
 	return gomcp.NewTool("register_application",
 		gomcp.WithDescription("Register a new application in Infraplane. Provide a source path (local directory or git URL) to auto-detect infrastructure resources from the codebase. Optionally specify compliance frameworks to enforce."),
@@ -74,6 +97,7 @@ func registerApplicationTool() gomcp.Tool {
 		gomcp.WithString("source_path", gomcp.Description("Local filesystem path or git URL to analyze for auto-detecting infrastructure resources (e.g. '/path/to/project' or 'https://github.com/org/repo')")),
 		gomcp.WithString("provider", gomcp.Required(), gomcp.Description("Preferred cloud provider"), gomcp.Enum("aws", "gcp")),
 		gomcp.WithString("compliance_frameworks", gomcp.Description("Comma-separated list of compliance framework IDs to enforce (e.g. 'cis_gcp_v4'). Use list_compliance_frameworks to see available options.")),
+		noCacheOption(),
 	)
 }
 
@@ -95,6 +119,7 @@ func addResourceTool() gomcp.Tool {
 		gomcp.WithDescription("Add a cloud resource to an application by describing what you need in natural language. The LLM will analyze your description and create a cloud-agnostic resource with provider-specific mappings and Terraform."),
 		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name to add the resource to")),
 		gomcp.WithString("description", gomcp.Required(), gomcp.Description("Natural language description of what you need (e.g. 'a PostgreSQL database for user data', 'a Redis cache for sessions', 'object storage for file uploads')")),
+		noCacheOption(),
 	)
 }
 
@@ -109,6 +134,7 @@ func getHostingPlanTool() gomcp.Tool {
 	return gomcp.NewTool("get_hosting_plan",
 		gomcp.WithDescription("Generate an LLM-powered hosting plan for an application. Analyzes all resources and recommends optimal deployment architecture with cost estimates."),
 		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
+		noCacheOption(),
 	)
 }
 
@@ -118,6 +144,25 @@ func planMigrationTool() gomcp.Tool {
 		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
 		gomcp.WithString("from_provider", gomcp.Required(), gomcp.Description("Source cloud provider"), gomcp.Enum("aws", "gcp")),
 		gomcp.WithString("to_provider", gomcp.Required(), gomcp.Description("Target cloud provider"), gomcp.Enum("aws", "gcp")),
+		noCacheOption(),
+	)
+}
+
+func refinePlanTool() gomcp.Tool {
+	return gomcp.NewTool("refine_plan",
+		gomcp.WithDescription("Revise an existing hosting or migration plan with a follow-up instruction (e.g. 'use Aurora Serverless instead', 'cut cost under $200/mo'). The revised plan is saved as a new plan linked to the original, which is unchanged; the result includes a diff against the original."),
+		gomcp.WithString("plan_id", gomcp.Required(), gomcp.Description("UUID of the plan to refine")),
+		gomcp.WithString("instruction", gomcp.Required(), gomcp.Description("What to change in the plan")),
+		noCacheOption(),
+	)
+}
+
+func refineResourceTool() gomcp.Tool {
+	return gomcp.NewTool("refine_resource",
+		gomcp.WithDescription("Revise a resource's spec and provider mappings with a follow-up instruction (e.g. 'use Aurora Serverless instead'). The resource is updated in place and each refinement is recorded as a new revision; the result includes a diff against the previous revision."),
+		gomcp.WithString("resource_id", gomcp.Required(), gomcp.Description("UUID of the resource to refine")),
+		gomcp.WithString("instruction", gomcp.Required(), gomcp.Description("What to change in the resource")),
+		noCacheOption(),
 	)
 }
 
@@ -127,7 +172,9 @@ func deployTool() gomcp.Tool {
 		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
 		gomcp.WithString("git_branch", gomcp.Required(), gomcp.Description("Git branch to deploy from (e.g. 'main')")),
 		gomcp.WithString("git_commit", gomcp.Description("Git commit SHA (optional, defaults to latest)")),
-		gomcp.WithString("plan_id", gomcp.Description("Infrastructure plan UUID to deploy (optional)")),
+		gomcp.WithString("plan_id", gomcp.Description("Infrastructure plan UUID to deploy (optional). The plan's resource snapshot is applied exactly.")),
+		gomcp.WithBoolean("force", gomcp.Description("Deploy the plan even if the application's resources changed since it was generated (default false)")),
+		gomcp.WithBoolean("break_glass", gomcp.Description("Override active deployment freeze windows; the override is recorded on the deployment and audit-logged (default false)")),
 	)
 }
 
@@ -139,6 +186,23 @@ func getDeploymentStatusTool() gomcp.Tool {
 	)
 }
 
+func destroyTool() gomcp.Tool {
+	return gomcp.NewTool("destroy",
+		gomcp.WithDescription("Tear down all cloud infrastructure for an application, using the Terraform configuration of its most recent successful deployment. Recorded in deployment history like any deployment; on success the application returns to draft. Refused if the application has destroy protection enabled or a freeze window is active. The destroy is created pending: nothing is torn down until its stream_url is opened."),
+		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
+		gomcp.WithString("confirm", gomcp.Required(), gomcp.Description("Type the application name again to confirm the destroy")),
+		gomcp.WithBoolean("break_glass", gomcp.Description("Override active deployment freeze windows; the override is recorded on the deployment and audit-logged (default false)")),
+	)
+}
+
+func setDestroyProtectionTool() gomcp.Tool {
+	return gomcp.NewTool("set_destroy_protection",
+		gomcp.WithDescription("Enable or disable destroy protection (prevent_destroy) for an application. While enabled, destroy requests are refused."),
+		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
+		gomcp.WithBoolean("prevent_destroy", gomcp.Required(), gomcp.Description("true to block destroys, false to allow them")),
+	)
+}
+
 // --- Tool Handlers ---
 
 func (h *ToolHandlers) handleRegisterApplication(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
@@ -239,7 +303,7 @@ func (h *ToolHandlers) handleAddResource(ctx context.Context, req gomcp.CallTool
 
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
 	resource, err := h.resources.AddFromDescription(ctx, app.ID, description)
@@ -274,13 +338,15 @@ func (h *ToolHandlers) handleGetHostingPlan(ctx context.Context, req gomcp.CallT
 
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
-	plan, err := h.planner.GenerateHostingPlan(ctx, app.ID)
+	notify, flush := progressNotifier(ctx, req)
+	plan, err := h.planner.StreamHostingPlan(ctx, app.ID, notify)
 	if err != nil {
 		return toolError(err), nil
 	}
+	flush()
 
 	return toolJSON(map[string]any{
 		"plan_id":        plan.ID,
@@ -296,13 +362,15 @@ func (h *ToolHandlers) handlePlanMigration(ctx context.Context, req gomcp.CallTo
 
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
-	plan, err := h.planner.GenerateMigrationPlan(ctx, app.ID, domain.CloudProvider(fromProvider), domain.CloudProvider(toProvider))
+	notify, flush := progressNotifier(ctx, req)
+	plan, err := h.planner.StreamMigrationPlan(ctx, app.ID, domain.CloudProvider(fromProvider), domain.CloudProvider(toProvider), notify)
 	if err != nil {
 		return toolError(err), nil
 	}
+	flush()
 
 	return toolJSON(map[string]any{
 		"plan_id":        plan.ID,
@@ -313,15 +381,116 @@ func (h *ToolHandlers) handlePlanMigration(ctx context.Context, req gomcp.CallTo
 	})
 }
 
+func (h *ToolHandlers) handleRefinePlan(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	planIDStr, _ := req.RequireString("plan_id")
+	instruction, _ := req.RequireString("instruction")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		return toolError(fmt.Errorf("invalid plan ID: %s", planIDStr)), nil
+	}
+
+	notify, flush := progressNotifier(ctx, req)
+	plan, err := h.planner.StreamRefinePlan(ctx, planID, instruction, notify)
+	if err != nil {
+		return toolError(err), nil
+	}
+	flush()
+	diff, err := h.planner.DiffPlan(ctx, plan.ID, nil)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	return toolJSON(map[string]any{
+		"plan_id":        plan.ID,
+		"parent_id":      plan.ParentID,
+		"content":        plan.Content,
+		"estimated_cost": plan.EstimatedCost,
+		"diff":           diff.Diff,
+	})
+}
+
+func (h *ToolHandlers) handleRefineResource(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	resourceIDStr, _ := req.RequireString("resource_id")
+	instruction, _ := req.RequireString("instruction")
+	resourceID, err := uuid.Parse(resourceIDStr)
+	if err != nil {
+		return toolError(fmt.Errorf("invalid resource ID: %s", resourceIDStr)), nil
+	}
+
+	rev, err := h.resources.Refine(ctx, resourceID, instruction)
+	if err != nil {
+		return toolError(err), nil
+	}
+	diff, err := h.resources.DiffRevision(ctx, resourceID, rev.Version, nil)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	return toolJSON(map[string]any{
+		"resource": rev.Resource,
+		"version":  rev.Version,
+		"diff":     diff.Diff,
+		"message":  fmt.Sprintf("Refined %s resource '%s' to version %d.", rev.Resource.Kind, rev.Resource.Name, rev.Version),
+	})
+}
+
+// progressInterval is the least time between two progress notifications of
+// a plan generation.
+const progressInterval = 250 * time.Millisecond
+
+// progressNotifier returns the StreamFunc forwarding a plan's markdown to the
+// client as progress notifications while the model writes it, and the flush
+// func sending whatever the throttle held back; call flush once the plan is
+// written, before returning the tool result. Both are no-ops (the StreamFunc
+// nil) if the call asked for no progress. Each notification's message is the
+// markdown written since the previous one, or all of it when the model
+// started over; progress counts the bytes sent so far. The tool result still
+// carries the whole plan.
+func progressNotifier(ctx context.Context, req gomcp.CallToolRequest) (llm.StreamFunc, func()) {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil || req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+		return nil, func() {}
+	}
+	token := req.Params.Meta.ProgressToken
+	var sent, pending string
+	var progress int
+	var last time.Time
+	send := func() {
+		if pending == sent {
+			return
+		}
+		chunk, ok := strings.CutPrefix(pending, sent)
+		if !ok {
+			chunk = pending
+		}
+		progress += len(chunk)
+		sent, last = pending, time.Now()
+		// A client that went away only misses progress; the call goes on.
+		_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+			"message":       chunk,
+		})
+	}
+	return func(text string) {
+		pending = text
+		if time.Since(last) >= progressInterval {
+			send()
+		}
+	}, send
+}
+
 func (h *ToolHandlers) handleDeploy(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
 	appName, _ := req.RequireString("app_name")
 	gitBranch, _ := req.RequireString("git_branch")
 	gitCommit := req.GetString("git_commit", "")
 	planIDStr := req.GetString("plan_id", "")
+	force := req.GetBool("force", false)
+	breakGlass := req.GetBool("break_glass", false)
 
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
 	var planID *uuid.UUID
@@ -333,7 +502,10 @@ func (h *ToolHandlers) handleDeploy(ctx context.Context, req gomcp.CallToolReque
 		planID = &id
 	}
 
-	d, err := h.deployments.Deploy(ctx, app.ID, gitCommit, gitBranch, planID)
+	d, err := h.deployments.Deploy(ctx, app.ID, gitCommit, gitBranch, planID, service.DeployOptions{
+		Force:      force,
+		BreakGlass: breakGlass,
+	})
 	if err != nil {
 		return toolError(err), nil
 	}
@@ -367,7 +539,7 @@ func (h *ToolHandlers) handleGetDeploymentStatus(ctx context.Context, req gomcp.
 	// Get latest deployment
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
 	d, err := h.deployments.GetLatest(ctx, app.ID)
@@ -378,10 +550,66 @@ func (h *ToolHandlers) handleGetDeploymentStatus(ctx context.Context, req gomcp.
 	return toolJSON(d)
 }
 
+func (h *ToolHandlers) handleDestroy(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	appName, _ := req.RequireString("app_name")
+	confirm := req.GetString("confirm", "")
+	breakGlass := req.GetBool("break_glass", false)
+
+	app, err := h.apps.GetByName(ctx, appName)
+	if err != nil {
+		return appLookupError(appName, err), nil
+	}
+
+	d, err := h.deployments.Destroy(ctx, app.ID, confirm, service.DeployOptions{BreakGlass: breakGlass})
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	// MCP has no deployment runner; the destroy runs when its stream is opened.
+	streamURL := fmt.Sprintf("/api/deployments/%s/stream", d.ID)
+	return toolJSON(map[string]any{
+		"deployment_id": d.ID,
+		"type":          d.Type,
+		"status":        d.Status,
+		"provider":      d.Provider,
+		"stream_url":    streamURL,
+		"message":       fmt.Sprintf("Destroy of '%s' is pending. Open GET %s (SSE) to run it; nothing is torn down until then.", appName, streamURL),
+	})
+}
+
+func (h *ToolHandlers) handleSetDestroyProtection(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	appName, _ := req.RequireString("app_name")
+	preventDestroy, err := req.RequireBool("prevent_destroy")
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	app, err := h.apps.GetByName(ctx, appName)
+	if err != nil {
+		return appLookupError(appName, err), nil
+	}
+
+	app, err = h.apps.SetPreventDestroy(ctx, app.ID, preventDestroy)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	state := "disabled"
+	if app.PreventDestroy {
+		state = "enabled"
+	}
+	return toolJSON(map[string]any{
+		"name":            app.Name,
+		"prevent_destroy": app.PreventDestroy,
+		"message":         fmt.Sprintf("Destroy protection %s for '%s'.", state, app.Name),
+	})
+}
+
 func generateGraphTool() gomcp.Tool {
 	return gomcp.NewTool("generate_graph",
 		gomcp.WithDescription("Generate an infrastructure topology graph for an application. Analyzes all resources and produces a node/edge graph showing how components connect to each other and the public internet."),
 		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
+		noCacheOption(),
 	)
 }
 
@@ -390,7 +618,7 @@ func (h *ToolHandlers) handleGenerateGraph(ctx context.Context, req gomcp.CallTo
 
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
 	graph, err := h.graphs.GenerateGraph(ctx, app.ID)
@@ -410,6 +638,7 @@ func discoverLiveResourcesTool() gomcp.Tool {
 	return gomcp.NewTool("discover_live_resources",
 		gomcp.WithDescription("Discover live cloud resources for an application by analyzing deploy scripts and querying cloud provider APIs. Returns real-time status of deployed infrastructure."),
 		gomcp.WithString("app_name", gomcp.Required(), gomcp.Description("Application name")),
+		noCacheOption(),
 	)
 }
 
@@ -418,7 +647,7 @@ func (h *ToolHandlers) handleDiscoverLiveResources(ctx context.Context, req gomc
 
 	app, err := h.apps.GetByName(ctx, appName)
 	if err != nil {
-		return toolError(fmt.Errorf("application '%s' not found", appName)), nil
+		return appLookupError(appName, err), nil
 	}
 
 	result, err := h.discovery.DiscoverLiveResources(ctx, app.ID)
@@ -475,13 +704,27 @@ func toolJSON(data any) (*gomcp.CallToolResult, error) {
 }
 
 func toolError(err error) *gomcp.CallToolResult {
+	text := fmt.Sprintf("Error: %s", err.Error())
+	if errors.Is(err, domain.ErrForbidden) {
+		text = fmt.Sprintf("Permission denied: %s. Ask an admin for a role binding (viewer, editor, deployer or admin) on the application.", err.Error())
+	}
 	return &gomcp.CallToolResult{
 		Content: []gomcp.Content{
 			gomcp.TextContent{
 				Type: "text",
-				Text: fmt.Sprintf("Error: %s", err.Error()),
+				Text: text,
 			},
 		},
 		IsError: true,
 	}
 }
+
+// appLookupError reports a failed application lookup. Permission and
+// ambiguity errors are kept so the caller learns which role is missing or
+// that it must name the organization.
+func appLookupError(name string, err error) *gomcp.CallToolResult {
+	if errors.Is(err, domain.ErrForbidden) || errors.Is(err, domain.ErrConflict) {
+		return toolError(err)
+	}
+	return toolError(fmt.Errorf("application '%s' not found", name))
+}
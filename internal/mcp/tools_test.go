@@ -3,9 +3,12 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	gomcp "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
 	"github.com/matthewdriscoll/infraplane/internal/llm"
 	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
@@ -25,9 +28,9 @@ func setupTestHandlers() *ToolHandlers {
 	appSvc := service.NewApplicationService(appRepo, resRepo, mockLLM, nil)
 	resSvc := service.NewResourceService(resRepo, appRepo, mockLLM, nil)
 	planSvc := service.NewPlannerService(planRepo, appRepo, resRepo, mockLLM, nil)
-	depSvc := service.NewDeploymentService(depRepo, appRepo)
+	depSvc := service.NewDeploymentService(depRepo, appRepo, planRepo, resRepo, mock.NewFreezeWindowRepo())
 	graphSvc := service.NewGraphService(graphRepo, appRepo, resRepo, mockLLM)
-	discSvc := service.NewDiscoveryService(appRepo, mockLLM, nil)
+	discSvc := service.NewDiscoveryService(appRepo, resRepo, mockLLM, nil)
 
 	return NewToolHandlers(appSvc, resSvc, planSvc, depSvc, graphSvc, discSvc, nil)
 }
@@ -149,6 +152,69 @@ func TestHandleGetApplication(t *testing.T) {
 	})
 }
 
+func TestTenantMiddleware(t *testing.T) {
+	h := setupTestHandlers()
+	orgRepo := mock.NewOrganizationRepo()
+	orgSvc := service.NewOrganizationService(orgRepo)
+	orgSvc.SetRBAC(service.NewRBACService(mock.NewRoleBindingRepo(), mock.NewApplicationRepo()))
+	acme := domain.NewOrganization("acme", "")
+	orgRepo.Create(context.Background(), acme)
+
+	scoped := tenantMiddleware(orgSvc)
+	register := scoped(h.handleRegisterApplication)
+	get := scoped(h.handleGetApplication)
+	appIn := func(t *testing.T, result *gomcp.CallToolResult) map[string]any {
+		t.Helper()
+		text := result.Content[0].(gomcp.TextContent).Text
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", text)
+		}
+		var resp map[string]any
+		json.Unmarshal([]byte(text), &resp)
+		if app, ok := resp["application"].(map[string]any); ok {
+			return app
+		}
+		return resp
+	}
+
+	// An API key acts in its own organization; other callers in the default
+	// one unless they name another.
+	acmeKey := auth.WithPrincipal(context.Background(), domain.Principal{Kind: domain.PrincipalAPIKey, Subject: "acme-key", Name: "ci", OrgID: &acme.ID})
+	result, _ := register(acmeKey, makeRequest(map[string]any{"name": "shop", "provider": "aws"}))
+	appIn(t, result)
+	result, _ = register(context.Background(), makeRequest(map[string]any{"name": "shop", "provider": "gcp"}))
+	appIn(t, result)
+
+	result, _ = get(acmeKey, makeRequest(map[string]any{"name": "shop"}))
+	if app := appIn(t, result); app["provider"] != "aws" {
+		t.Errorf("acme key got %v, want acme's shop", app)
+	}
+	result, _ = get(context.Background(), makeRequest(map[string]any{"name": "shop", "org": "acme"}))
+	if app := appIn(t, result); app["provider"] != "aws" {
+		t.Errorf("org=acme got %v, want acme's shop", app)
+	}
+	result, _ = get(acmeKey, makeRequest(map[string]any{"name": "shop", "org": "default"}))
+	if app := appIn(t, result); app["provider"] != "gcp" {
+		t.Errorf("org=default got %v, want the default shop", app)
+	}
+
+	// Unscoped lookups refuse to pick between organizations.
+	result, _ = h.handleGetApplication(context.Background(), makeRequest(map[string]any{"name": "shop"}))
+	if text := result.Content[0].(gomcp.TextContent).Text; !result.IsError || !strings.Contains(text, "more than one organization") {
+		t.Errorf("unscoped lookup = %s, want an ambiguity error", text)
+	}
+
+	outsider := auth.WithPrincipal(context.Background(), domain.Principal{Kind: domain.PrincipalUser, Subject: "mallory", Name: "mallory"})
+	result, _ = get(outsider, makeRequest(map[string]any{"name": "shop", "org": "acme"}))
+	if text := result.Content[0].(gomcp.TextContent).Text; !result.IsError || !strings.Contains(text, "not a member of organization acme") {
+		t.Errorf("non-member = %s, want Permission denied", text)
+	}
+	result, _ = get(outsider, makeRequest(map[string]any{"name": "shop", "org": "ghost"}))
+	if text := result.Content[0].(gomcp.TextContent).Text; !result.IsError || !strings.Contains(text, "organization 'ghost' not found") {
+		t.Errorf("unknown org = %s, want not found", text)
+	}
+}
+
 func TestHandleAddResource(t *testing.T) {
 	h := setupTestHandlers()
 	ctx := context.Background()
@@ -291,6 +357,96 @@ func TestHandleGetHostingPlan(t *testing.T) {
 	}
 }
 
+// testSession is an initialized client session collecting its notifications.
+type testSession struct {
+	notifications chan gomcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()                                           {}
+func (s *testSession) Initialized() bool                                     { return true }
+func (s *testSession) NotificationChannel() chan<- gomcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                     { return "test-session" }
+
+func TestHandleGetHostingPlan_Progress(t *testing.T) {
+	h := setupTestHandlers()
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(false))
+	h.RegisterAll(srv)
+	session := &testSession{notifications: make(chan gomcp.JSONRPCNotification, 10)}
+	ctx := srv.WithContext(context.Background(), session)
+
+	h.handleRegisterApplication(ctx, makeRequest(map[string]any{
+		"name": "progress-app", "provider": "aws",
+	}))
+
+	resp := srv.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call",
+		"params":{"name":"get_hosting_plan","arguments":{"app_name":"progress-app"},"_meta":{"progressToken":"tok"}}}`))
+	data, _ := json.Marshal(resp)
+	var result struct {
+		Result gomcp.CallToolResult `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.Result.IsError || len(result.Result.Content) == 0 {
+		t.Fatalf("response = %s", data)
+	}
+	var plan map[string]any
+	json.Unmarshal([]byte(result.Result.Content[0].(gomcp.TextContent).Text), &plan)
+
+	close(session.notifications)
+	var streamed string
+	var count int
+	for n := range session.notifications {
+		fields := n.Params.AdditionalFields
+		if n.Method != "notifications/progress" || fields["progressToken"] != "tok" {
+			t.Errorf("notification = %+v, want progress for tok", n)
+		}
+		message, _ := fields["message"].(string)
+		streamed += message
+		count++
+	}
+	if count == 0 {
+		t.Fatal("no progress notification sent")
+	}
+	if streamed != plan["content"] {
+		t.Errorf("concatenated progress = %q, want the plan markdown %v", streamed, plan["content"])
+	}
+}
+
+func TestProgressNotifier_FlushesThrottledText(t *testing.T) {
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(false))
+	session := &testSession{notifications: make(chan gomcp.JSONRPCNotification, 10)}
+	ctx := srv.WithContext(context.Background(), session)
+
+	// The model writes faster than the throttle: only the first update goes
+	// out while streaming, the rest when the plan is flushed.
+	content := "# Hosting plan\n\nUse ECS with an RDS database."
+	srv.AddTool(gomcp.NewTool("write_plan"), func(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+		notify, flush := progressNotifier(ctx, req)
+		for i := 1; i < len(content); i += 8 {
+			notify(content[:i])
+		}
+		notify(content)
+		flush()
+		flush()
+		return gomcp.NewToolResultText(content), nil
+	})
+	srv.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call",
+		"params":{"name":"write_plan","_meta":{"progressToken":"tok"}}}`))
+	close(session.notifications)
+
+	var streamed string
+	var count int
+	for n := range session.notifications {
+		message, _ := n.Params.AdditionalFields["message"].(string)
+		streamed += message
+		count++
+	}
+	if streamed != content {
+		t.Errorf("concatenated progress = %q, want the plan %q", streamed, content)
+	}
+	if count != 2 {
+		t.Errorf("notifications = %d, want 2 (first update and flush)", count)
+	}
+}
+
 func TestHandlePlanMigration(t *testing.T) {
 	h := setupTestHandlers()
 	ctx := context.Background()
@@ -325,6 +481,101 @@ func TestHandlePlanMigration(t *testing.T) {
 	})
 }
 
+func TestHandleRefinePlan(t *testing.T) {
+	h := setupTestHandlers()
+	ctx := context.Background()
+
+	h.handleRegisterApplication(ctx, makeRequest(map[string]any{
+		"name": "refine-plan-app", "provider": "aws",
+	}))
+	planResult, _ := h.handleGetHostingPlan(ctx, makeRequest(map[string]any{
+		"app_name": "refine-plan-app",
+	}))
+	var planResp map[string]any
+	json.Unmarshal([]byte(planResult.Content[0].(gomcp.TextContent).Text), &planResp)
+	planID := planResp["plan_id"].(string)
+
+	t.Run("successful refine", func(t *testing.T) {
+		result, err := h.handleRefinePlan(ctx, makeRequest(map[string]any{
+			"plan_id":     planID,
+			"instruction": "cut cost under $200/mo",
+		}))
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", result.Content[0].(gomcp.TextContent).Text)
+		}
+
+		var resp map[string]any
+		json.Unmarshal([]byte(result.Content[0].(gomcp.TextContent).Text), &resp)
+		if resp["parent_id"] != planID {
+			t.Errorf("parent_id = %v, want %s", resp["parent_id"], planID)
+		}
+		if diff, _ := resp["diff"].(string); !strings.Contains(diff, "+cut cost under $200/mo") {
+			t.Errorf("diff = %q", diff)
+		}
+	})
+
+	t.Run("invalid plan ID", func(t *testing.T) {
+		result, _ := h.handleRefinePlan(ctx, makeRequest(map[string]any{
+			"plan_id":     "not-a-uuid",
+			"instruction": "cheaper",
+		}))
+		if !result.IsError {
+			t.Error("expected tool error")
+		}
+	})
+}
+
+func TestHandleRefineResource(t *testing.T) {
+	h := setupTestHandlers()
+	ctx := context.Background()
+
+	h.handleRegisterApplication(ctx, makeRequest(map[string]any{
+		"name": "refine-res-app", "provider": "aws",
+	}))
+	addResult, _ := h.handleAddResource(ctx, makeRequest(map[string]any{
+		"app_name":    "refine-res-app",
+		"description": "a database",
+	}))
+	var addResp map[string]any
+	json.Unmarshal([]byte(addResult.Content[0].(gomcp.TextContent).Text), &addResp)
+	resourceID := addResp["resource"].(map[string]any)["id"].(string)
+
+	t.Run("successful refine", func(t *testing.T) {
+		result, err := h.handleRefineResource(ctx, makeRequest(map[string]any{
+			"resource_id": resourceID,
+			"instruction": "use Aurora Serverless instead",
+		}))
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", result.Content[0].(gomcp.TextContent).Text)
+		}
+
+		var resp map[string]any
+		json.Unmarshal([]byte(result.Content[0].(gomcp.TextContent).Text), &resp)
+		if resp["version"] != float64(2) {
+			t.Errorf("version = %v, want 2", resp["version"])
+		}
+		if diff, _ := resp["diff"].(string); !strings.Contains(diff, "use Aurora Serverless instead") {
+			t.Errorf("diff = %q", diff)
+		}
+	})
+
+	t.Run("empty instruction", func(t *testing.T) {
+		result, _ := h.handleRefineResource(ctx, makeRequest(map[string]any{
+			"resource_id": resourceID,
+			"instruction": "",
+		}))
+		if !result.IsError {
+			t.Error("expected tool error")
+		}
+	})
+}
+
 func TestHandleGetDeploymentStatus(t *testing.T) {
 	h := setupTestHandlers()
 	ctx := context.Background()
@@ -360,3 +611,122 @@ func TestHandleGetDeploymentStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestHandleDestroy(t *testing.T) {
+	h := setupTestHandlers()
+	ctx := context.Background()
+
+	h.handleRegisterApplication(ctx, makeRequest(map[string]any{
+		"name": "destroy-app", "provider": "aws",
+	}))
+	app, _ := h.apps.GetByName(ctx, "destroy-app")
+
+	t.Run("no succeeded deployment", func(t *testing.T) {
+		result, _ := h.handleDestroy(ctx, makeRequest(map[string]any{
+			"app_name": "destroy-app", "confirm": "destroy-app",
+		}))
+		if !result.IsError {
+			t.Error("expected tool error when nothing has been deployed")
+		}
+	})
+
+	d, _ := h.deployments.Deploy(ctx, app.ID, "abc", "main", nil, service.DeployOptions{})
+	h.deployments.MarkSucceeded(ctx, d.ID, `resource "aws_s3_bucket" "b" {}`)
+
+	t.Run("wrong confirmation", func(t *testing.T) {
+		result, _ := h.handleDestroy(ctx, makeRequest(map[string]any{
+			"app_name": "destroy-app", "confirm": "yes",
+		}))
+		if !result.IsError {
+			t.Error("expected tool error for mismatched confirmation")
+		}
+	})
+
+	t.Run("protected application", func(t *testing.T) {
+		result, _ := h.handleSetDestroyProtection(ctx, makeRequest(map[string]any{
+			"app_name": "destroy-app", "prevent_destroy": true,
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", result.Content[0].(gomcp.TextContent).Text)
+		}
+
+		result, _ = h.handleDestroy(ctx, makeRequest(map[string]any{
+			"app_name": "destroy-app", "confirm": "destroy-app",
+		}))
+		if !result.IsError {
+			t.Error("expected tool error for protected application")
+		}
+
+		h.handleSetDestroyProtection(ctx, makeRequest(map[string]any{
+			"app_name": "destroy-app", "prevent_destroy": false,
+		}))
+	})
+
+	t.Run("successful destroy", func(t *testing.T) {
+		result, err := h.handleDestroy(ctx, makeRequest(map[string]any{
+			"app_name": "destroy-app", "confirm": "destroy-app",
+		}))
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", result.Content[0].(gomcp.TextContent).Text)
+		}
+
+		text := result.Content[0].(gomcp.TextContent).Text
+		var resp map[string]any
+		json.Unmarshal([]byte(text), &resp)
+		if resp["type"] != string(domain.DeploymentTypeDestroy) {
+			t.Errorf("type = %v, want destroy", resp["type"])
+		}
+		if resp["status"] != string(domain.DeploymentPending) {
+			t.Errorf("status = %v, want pending", resp["status"])
+		}
+		if want := "/api/deployments/" + resp["deployment_id"].(string) + "/stream"; resp["stream_url"] != want {
+			t.Errorf("stream_url = %v, want %s", resp["stream_url"], want)
+		}
+	})
+}
+
+func TestToolPermissionDenied(t *testing.T) {
+	h := setupTestHandlers()
+	ctx := context.Background()
+	h.handleRegisterApplication(ctx, makeRequest(map[string]any{"name": "locked-app", "provider": "aws"}))
+	h.apps.SetRBAC(service.NewRBACService(mock.NewRoleBindingRepo(), mock.NewApplicationRepo()))
+
+	ci := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalAPIKey, Subject: "key-1", Name: "ci"})
+	result, err := h.handleDeploy(ci, makeRequest(map[string]any{"app_name": "locked-app", "git_branch": "main"}))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	text := result.Content[0].(gomcp.TextContent).Text
+	if !result.IsError || !strings.HasPrefix(text, "Permission denied:") || !strings.Contains(text, "needs the viewer role") {
+		t.Errorf("result = %q, want a permission error naming the missing role", text)
+	}
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	calls := 0
+	inner := &llm.MockClient{
+		GenerateGraphFn: func(ctx context.Context, app domain.Application, resources []domain.Resource) (llm.GraphResult, error) {
+			calls++
+			return llm.GraphResult{}, nil
+		},
+	}
+	cached := llm.NewCachedClient(inner, mock.NewLLMCacheRepo(), 0)
+	handler := cacheMiddleware(func(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+		cached.GenerateGraph(ctx, domain.Application{Name: "app"}, nil)
+		return gomcp.NewToolResultText("ok"), nil
+	})
+
+	ctx := context.Background()
+	handler(ctx, makeRequest(map[string]any{}))
+	handler(ctx, makeRequest(map[string]any{}))
+	handler(ctx, makeRequest(map[string]any{"no_cache": true}))
+	if calls != 2 {
+		t.Errorf("model calls = %d, want 2 (one miss, one no_cache)", calls)
+	}
+	if stats := cached.Stats(); stats.Hits != 1 || stats.Bypassed != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
@@ -0,0 +1,143 @@
+// Package notify delivers notifications to external sinks: generic JSON
+// webhooks, Slack-compatible incoming webhooks and SMTP email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// Sink delivers a notification to one destination.
+type Sink interface {
+	Send(ctx context.Context, n domain.Notification) error
+}
+
+// SMTPConfig holds the mail server used by email channels.
+type SMTPConfig struct {
+	Addr     string // host:port; email channels fail when empty
+	From     string
+	Username string // PLAIN auth is used when set
+	Password string
+}
+
+// DefaultHTTPClient is used by webhook sinks when none is given.
+var DefaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// ForChannel builds the sink a channel delivers to.
+func ForChannel(ch domain.NotificationChannel, smtpCfg SMTPConfig, client *http.Client) (Sink, error) {
+	if client == nil {
+		client = DefaultHTTPClient
+	}
+	switch ch.Kind {
+	case domain.ChannelWebhook:
+		return &WebhookSink{URL: ch.Target, Client: client}, nil
+	case domain.ChannelSlack:
+		return &SlackSink{URL: ch.Target, Client: client}, nil
+	case domain.ChannelEmail:
+		addrs, err := mail.ParseAddressList(ch.Target)
+		if err != nil {
+			return nil, fmt.Errorf("parse recipients: %w", err)
+		}
+		to := make([]string, len(addrs))
+		for i, a := range addrs {
+			to[i] = a.Address
+		}
+		return &EmailSink{Config: smtpCfg, To: to}, nil
+	default:
+		return nil, fmt.Errorf("unsupported channel kind %q", ch.Kind)
+	}
+}
+
+// WebhookSink POSTs the notification as JSON.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *WebhookSink) Send(ctx context.Context, n domain.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshal notification: %w", err)
+	}
+	return post(ctx, s.Client, s.URL, body)
+}
+
+// SlackSink posts the notification's subject and message as the text of a
+// Slack incoming webhook message.
+type SlackSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *SlackSink) Send(ctx context.Context, n domain.Notification) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", n.Subject, n.Message),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal slack message: %w", err)
+	}
+	return post(ctx, s.Client, s.URL, body)
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Infraplane-Notifier")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post notification: %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// EmailSink sends the notification as a plain-text email.
+type EmailSink struct {
+	Config SMTPConfig
+	To     []string
+}
+
+func (s *EmailSink) Send(_ context.Context, n domain.Notification) error {
+	if s.Config.Addr == "" {
+		return fmt.Errorf("SMTP server not configured")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.Config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [Infraplane] %s\r\n", n.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Message, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if s.Config.Username != "" {
+		host, _, _ := strings.Cut(s.Config.Addr, ":")
+		auth = smtp.PlainAuth("", s.Config.Username, s.Config.Password, host)
+	}
+	if err := smtp.SendMail(s.Config.Addr, auth, s.Config.From, s.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func testNotification() domain.Notification {
+	return domain.Notification{
+		Event:           domain.EventDeploymentFailed,
+		ApplicationID:   uuid.New(),
+		ApplicationName: "billing",
+		Subject:         "Deployment of billing failed",
+		Message:         "Terraform apply failed: quota exceeded\nSee the deployment log.",
+		Timestamp:       time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got domain.Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	n := testNotification()
+	sink := &WebhookSink{URL: srv.URL, Client: srv.Client()}
+	if err := sink.Send(context.Background(), n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got.Event != n.Event || got.ApplicationName != "billing" {
+		t.Errorf("received %+v", got)
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := (&WebhookSink{URL: srv.URL, Client: srv.Client()}).Send(context.Background(), testNotification())
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Send() error = %v, want 502", err)
+	}
+}
+
+func TestSlackSink(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	if err := (&SlackSink{URL: srv.URL, Client: srv.Client()}).Send(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.HasPrefix(got["text"], "*Deployment of billing failed*\n") {
+		t.Errorf("text = %q", got["text"])
+	}
+}
+
+// smtpStandIn accepts a single SMTP session and returns the DATA it received.
+func smtpStandIn(t *testing.T) (addr string, received <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP stand-in")
+
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					ch <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default: // MAIL FROM, RCPT TO
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestEmailSink(t *testing.T) {
+	addr, received := smtpStandIn(t)
+
+	sink := &EmailSink{
+		Config: SMTPConfig{Addr: addr, From: "infraplane@example.com"},
+		To:     []string{"oncall@example.com", "lead@example.com"},
+	}
+	if err := sink.Send(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		for _, want := range []string{
+			"To: oncall@example.com, lead@example.com",
+			"Subject: [Infraplane] Deployment of billing failed",
+			"quota exceeded\r\nSee the deployment log.",
+		} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message missing %q:\n%s", want, msg)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP stand-in received no message")
+	}
+}
+
+func TestEmailSink_NotConfigured(t *testing.T) {
+	if err := (&EmailSink{To: []string{"a@example.com"}}).Send(context.Background(), testNotification()); err == nil {
+		t.Error("Send() without SMTP server should fail")
+	}
+}
+
+func TestForChannel(t *testing.T) {
+	appID := uuid.New()
+	tests := []struct {
+		ch   domain.NotificationChannel
+		want string
+	}{
+		{domain.NewNotificationChannel(appID, domain.ChannelWebhook, "https://hooks.example.com/x", nil), "*notify.WebhookSink"},
+		{domain.NewNotificationChannel(appID, domain.ChannelSlack, "https://hooks.slack.com/services/x", nil), "*notify.SlackSink"},
+		{domain.NewNotificationChannel(appID, domain.ChannelEmail, "Ops <ops@example.com>, dev@example.com", nil), "*notify.EmailSink"},
+	}
+	for _, tt := range tests {
+		sink, err := ForChannel(tt.ch, SMTPConfig{}, nil)
+		if err != nil {
+			t.Fatalf("ForChannel(%s) error = %v", tt.ch.Kind, err)
+		}
+		if got := fmt.Sprintf("%T", sink); got != tt.want {
+			t.Errorf("ForChannel(%s) = %s, want %s", tt.ch.Kind, got, tt.want)
+		}
+		if email, ok := sink.(*EmailSink); ok && strings.Join(email.To, ",") != "ops@example.com,dev@example.com" {
+			t.Errorf("email recipients = %v", email.To)
+		}
+	}
+}
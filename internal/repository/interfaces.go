@@ -2,12 +2,17 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
 )
 
-// ApplicationRepo defines data access for applications.
+// ApplicationRepo defines data access for applications. Reads, updates and
+// deletes are limited to the organization the context is scoped to (see
+// package tenant); Create returns domain.ErrConflict when the organization
+// already has an application with the same name, and so does GetByName in
+// an unscoped context when more than one organization does.
 type ApplicationRepo interface {
 	Create(ctx context.Context, app domain.Application) error
 	GetByID(ctx context.Context, id uuid.UUID) (domain.Application, error)
@@ -17,13 +22,22 @@ type ApplicationRepo interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
-// ResourceRepo defines data access for resources.
+// ResourceRepo defines data access for resources and their revisions. Like
+// the deployment, plan and graph repositories, it only returns records whose
+// application belongs to the organization the context is scoped to. Deleting
+// a resource deletes its revisions.
 type ResourceRepo interface {
 	Create(ctx context.Context, r domain.Resource) error
 	GetByID(ctx context.Context, id uuid.UUID) (domain.Resource, error)
+	// GetForUpdate is GetByID that also locks the resource until the
+	// transaction in ctx ends, so concurrent updates wait for it.
+	GetForUpdate(ctx context.Context, id uuid.UUID) (domain.Resource, error)
 	ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.Resource, error)
 	Update(ctx context.Context, r domain.Resource) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	CreateRevision(ctx context.Context, rev domain.ResourceRevision) error
+	// ListRevisions returns a resource's revisions, oldest first.
+	ListRevisions(ctx context.Context, resourceID uuid.UUID) ([]domain.ResourceRevision, error)
 }
 
 // DeploymentRepo defines data access for deployments.
@@ -48,3 +62,142 @@ type GraphRepo interface {
 	GetLatestByApplicationID(ctx context.Context, appID uuid.UUID) (domain.InfraGraph, error)
 	ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.InfraGraph, error)
 }
+
+// ScheduleRepo defines data access for recurring job schedules.
+type ScheduleRepo interface {
+	Create(ctx context.Context, s domain.Schedule) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.Schedule, error)
+	List(ctx context.Context) ([]domain.Schedule, error)
+	ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.Schedule, error)
+	Update(ctx context.Context, s domain.Schedule) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// FreezeWindowRepo defines data access for deployment freeze windows.
+type FreezeWindowRepo interface {
+	Create(ctx context.Context, w domain.FreezeWindow) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.FreezeWindow, error)
+	List(ctx context.Context) ([]domain.FreezeWindow, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NotificationChannelRepo defines data access for per-application
+// notification channels.
+type NotificationChannelRepo interface {
+	Create(ctx context.Context, c domain.NotificationChannel) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.NotificationChannel, error)
+	ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.NotificationChannel, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NotificationDeliveryRepo defines data access for the notification delivery
+// log. ListByApplicationID returns the newest deliveries first.
+type NotificationDeliveryRepo interface {
+	Create(ctx context.Context, d domain.NotificationDelivery) error
+	ListByApplicationID(ctx context.Context, appID uuid.UUID, limit int) ([]domain.NotificationDelivery, error)
+}
+
+// WebhookSubscriptionRepo defines data access for outbound webhook
+// subscriptions.
+type WebhookSubscriptionRepo interface {
+	Create(ctx context.Context, s domain.WebhookSubscription) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error)
+	List(ctx context.Context) ([]domain.WebhookSubscription, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// WebhookDeliveryRepo defines data access for webhook deliveries.
+// ListBySubscriptionID returns the newest deliveries first, optionally
+// filtered by status; ListDue returns pending deliveries whose next attempt
+// is at or before now, oldest first.
+type WebhookDeliveryRepo interface {
+	Create(ctx context.Context, d domain.WebhookDelivery) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.WebhookDelivery, error)
+	Update(ctx context.Context, d domain.WebhookDelivery) error
+	ListBySubscriptionID(ctx context.Context, subID uuid.UUID, status domain.WebhookDeliveryStatus) ([]domain.WebhookDelivery, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error)
+}
+
+// APIKeyRepo defines data access for API keys. GetByHash looks a key up by
+// the SHA-256 hash of its secret; List returns the newest keys first,
+// including revoked and expired ones.
+type APIKeyRepo interface {
+	Create(ctx context.Context, k domain.APIKey) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	List(ctx context.Context) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// RoleBindingRepo defines data access for role bindings. Create returns
+// domain.ErrConflict when the subject already holds the role on the same
+// application (or globally). Lists are oldest first.
+type RoleBindingRepo interface {
+	Create(ctx context.Context, b domain.RoleBinding) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.RoleBinding, error)
+	List(ctx context.Context) ([]domain.RoleBinding, error)
+	ListBySubject(ctx context.Context, subject string) ([]domain.RoleBinding, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// OrganizationRepo defines data access for organizations. Create returns
+// domain.ErrConflict when the slug is taken. List is ordered by slug.
+type OrganizationRepo interface {
+	Create(ctx context.Context, o domain.Organization) error
+	GetByID(ctx context.Context, id uuid.UUID) (domain.Organization, error)
+	GetBySlug(ctx context.Context, slug string) (domain.Organization, error)
+	List(ctx context.Context) ([]domain.Organization, error)
+}
+
+// AuditLogRepo defines data access for the append-only audit log; entries
+// are never updated or deleted. List returns the entries matching the filter
+// newest first, limited to the organization the context is scoped to.
+type AuditLogRepo interface {
+	Create(ctx context.Context, e domain.AuditEntry) error
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
+
+// LLMUsageRepo defines data access for LLM usage records. Aggregate sums
+// the records matching the filter per application and UTC day, limited to
+// the organization the context is scoped to.
+type LLMUsageRepo interface {
+	Create(ctx context.Context, u domain.LLMUsage) error
+	Aggregate(ctx context.Context, filter domain.LLMUsageFilter) ([]domain.LLMUsageAggregate, error)
+}
+
+// LLMBudgetRepo defines data access for monthly LLM token budgets. Put
+// creates or replaces the budget of an organization, or of one of its
+// applications. Get returns domain.ErrNotFound when none is set. List
+// returns the budgets of the organization the context is scoped to,
+// organization-wide first.
+type LLMBudgetRepo interface {
+	Put(ctx context.Context, b domain.LLMBudget) (domain.LLMBudget, error)
+	Get(ctx context.Context, orgID uuid.UUID, appID *uuid.UUID) (domain.LLMBudget, error)
+	GetByID(ctx context.Context, id uuid.UUID) (domain.LLMBudget, error)
+	List(ctx context.Context) ([]domain.LLMBudget, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// Transactor runs functions in a database transaction. Repository calls made
+// with the context passed to fn take part in the transaction, which commits
+// when fn returns nil and rolls back otherwise.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// EventOutboxRepo defines data access for the transactional outbox of domain
+// events. ListPending returns undispatched events oldest first.
+type EventOutboxRepo interface {
+	Create(ctx context.Context, e domain.Event) error
+	ListPending(ctx context.Context, limit int) ([]domain.Event, error)
+	MarkDispatched(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// LeaderLock elects a single scheduler leader across server replicas.
+// TryAcquire is called on every scheduler tick and must be idempotent for
+// the current holder.
+type LeaderLock interface {
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
@@ -2,13 +2,22 @@ package mock
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 // ApplicationRepo is an in-memory mock implementation of repository.ApplicationRepo.
+// Like the PostgreSQL repository it only sees the applications of the
+// organization the context is scoped to, as do the role binding, freeze
+// window, webhook subscription and API key repositories; the in-memory child
+// repositories (resources, plans, graphs, deployments, schedules) are not
+// tenant-filtered.
 type ApplicationRepo struct {
 	mu   sync.RWMutex
 	apps map[uuid.UUID]domain.Application
@@ -18,11 +27,23 @@ func NewApplicationRepo() *ApplicationRepo {
 	return &ApplicationRepo{apps: make(map[uuid.UUID]domain.Application)}
 }
 
+// inOrg reports whether app is visible to ctx's organization scope.
+func inOrg(ctx context.Context, app domain.Application) bool {
+	return inOrgID(ctx, app.OrgID)
+}
+
+// inOrgID reports whether a record of the organization orgID is visible to
+// ctx's organization scope.
+func inOrgID(ctx context.Context, orgID uuid.UUID) bool {
+	scope := tenant.OrgID(ctx)
+	return scope == nil || *scope == orgID
+}
+
 func (r *ApplicationRepo) Create(_ context.Context, app domain.Application) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	for _, existing := range r.apps {
-		if existing.Name == app.Name {
+		if existing.OrgID == app.OrgID && existing.Name == app.Name {
 			return domain.ErrConflict
 		}
 	}
@@ -30,51 +51,64 @@ func (r *ApplicationRepo) Create(_ context.Context, app domain.Application) erro
 	return nil
 }
 
-func (r *ApplicationRepo) GetByID(_ context.Context, id uuid.UUID) (domain.Application, error) {
+func (r *ApplicationRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Application, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	app, ok := r.apps[id]
-	if !ok {
-		return app, domain.ErrNotFound
+	if !ok || !inOrg(ctx, app) {
+		return domain.Application{}, domain.ErrNotFound
 	}
 	return app, nil
 }
 
-func (r *ApplicationRepo) GetByName(_ context.Context, name string) (domain.Application, error) {
+func (r *ApplicationRepo) GetByName(ctx context.Context, name string) (domain.Application, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	var found []domain.Application
 	for _, app := range r.apps {
-		if app.Name == name {
-			return app, nil
+		if app.Name == name && inOrg(ctx, app) {
+			found = append(found, app)
 		}
 	}
-	return domain.Application{}, domain.ErrNotFound
+	switch len(found) {
+	case 0:
+		return domain.Application{}, domain.ErrNotFound
+	case 1:
+		return found[0], nil
+	default:
+		return domain.Application{}, fmt.Errorf("%w: application name %q is used in more than one organization", domain.ErrConflict, name)
+	}
 }
 
-func (r *ApplicationRepo) List(_ context.Context) ([]domain.Application, error) {
+func (r *ApplicationRepo) List(ctx context.Context) ([]domain.Application, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	apps := make([]domain.Application, 0, len(r.apps))
 	for _, app := range r.apps {
-		apps = append(apps, app)
+		if inOrg(ctx, app) {
+			apps = append(apps, app)
+		}
 	}
 	return apps, nil
 }
 
-func (r *ApplicationRepo) Update(_ context.Context, app domain.Application) error {
+func (r *ApplicationRepo) Update(ctx context.Context, app domain.Application) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.apps[app.ID]; !ok {
+	existing, ok := r.apps[app.ID]
+	if !ok || !inOrg(ctx, existing) {
 		return domain.ErrNotFound
 	}
+	app.OrgID = existing.OrgID
 	r.apps[app.ID] = app
 	return nil
 }
 
-func (r *ApplicationRepo) Delete(_ context.Context, id uuid.UUID) error {
+func (r *ApplicationRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.apps[id]; !ok {
+	app, ok := r.apps[id]
+	if !ok || !inOrg(ctx, app) {
 		return domain.ErrNotFound
 	}
 	delete(r.apps, id)
@@ -85,10 +119,14 @@ func (r *ApplicationRepo) Delete(_ context.Context, id uuid.UUID) error {
 type ResourceRepo struct {
 	mu        sync.RWMutex
 	resources map[uuid.UUID]domain.Resource
+	revisions map[uuid.UUID][]domain.ResourceRevision // by resource ID, oldest first
 }
 
 func NewResourceRepo() *ResourceRepo {
-	return &ResourceRepo{resources: make(map[uuid.UUID]domain.Resource)}
+	return &ResourceRepo{
+		resources: make(map[uuid.UUID]domain.Resource),
+		revisions: make(map[uuid.UUID][]domain.ResourceRevision),
+	}
 }
 
 func (r *ResourceRepo) Create(_ context.Context, res domain.Resource) error {
@@ -108,6 +146,11 @@ func (r *ResourceRepo) GetByID(_ context.Context, id uuid.UUID) (domain.Resource
 	return res, nil
 }
 
+// GetForUpdate is GetByID; the mock has no row locks.
+func (r *ResourceRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (domain.Resource, error) {
+	return r.GetByID(ctx, id)
+}
+
 func (r *ResourceRepo) ListByApplicationID(_ context.Context, appID uuid.UUID) ([]domain.Resource, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -117,6 +160,7 @@ func (r *ResourceRepo) ListByApplicationID(_ context.Context, appID uuid.UUID) (
 			resources = append(resources, res)
 		}
 	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].CreatedAt.Before(resources[j].CreatedAt) })
 	return resources, nil
 }
 
@@ -137,9 +181,31 @@ func (r *ResourceRepo) Delete(_ context.Context, id uuid.UUID) error {
 		return domain.ErrNotFound
 	}
 	delete(r.resources, id)
+	delete(r.revisions, id)
+	return nil
+}
+
+func (r *ResourceRepo) CreateRevision(_ context.Context, rev domain.ResourceRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.resources[rev.ResourceID]; !ok {
+		return domain.ErrNotFound
+	}
+	for _, existing := range r.revisions[rev.ResourceID] {
+		if existing.Version == rev.Version {
+			return domain.ErrConflict
+		}
+	}
+	r.revisions[rev.ResourceID] = append(r.revisions[rev.ResourceID], rev)
 	return nil
 }
 
+func (r *ResourceRepo) ListRevisions(_ context.Context, resourceID uuid.UUID) ([]domain.ResourceRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]domain.ResourceRevision(nil), r.revisions[resourceID]...), nil
+}
+
 // DeploymentRepo is an in-memory mock implementation of repository.DeploymentRepo.
 type DeploymentRepo struct {
 	mu          sync.RWMutex
@@ -294,3 +360,825 @@ func (r *GraphRepo) ListByApplicationID(_ context.Context, appID uuid.UUID) ([]d
 	}
 	return graphs, nil
 }
+
+// ScheduleRepo is an in-memory mock implementation of repository.ScheduleRepo.
+type ScheduleRepo struct {
+	mu        sync.RWMutex
+	schedules map[uuid.UUID]domain.Schedule
+}
+
+func NewScheduleRepo() *ScheduleRepo {
+	return &ScheduleRepo{schedules: make(map[uuid.UUID]domain.Schedule)}
+}
+
+func (r *ScheduleRepo) Create(_ context.Context, s domain.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schedules[s.ID] = s
+	return nil
+}
+
+func (r *ScheduleRepo) GetByID(_ context.Context, id uuid.UUID) (domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schedules[id]
+	if !ok {
+		return s, domain.ErrNotFound
+	}
+	return s, nil
+}
+
+func (r *ScheduleRepo) List(_ context.Context) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schedules := make([]domain.Schedule, 0, len(r.schedules))
+	for _, s := range r.schedules {
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+func (r *ScheduleRepo) ListByApplicationID(_ context.Context, appID uuid.UUID) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var schedules []domain.Schedule
+	for _, s := range r.schedules {
+		if s.ApplicationID == appID {
+			schedules = append(schedules, s)
+		}
+	}
+	return schedules, nil
+}
+
+func (r *ScheduleRepo) Update(_ context.Context, s domain.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.schedules[s.ID]; !ok {
+		return domain.ErrNotFound
+	}
+	r.schedules[s.ID] = s
+	return nil
+}
+
+func (r *ScheduleRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.schedules[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.schedules, id)
+	return nil
+}
+
+// FreezeWindowRepo is an in-memory mock implementation of repository.FreezeWindowRepo.
+type FreezeWindowRepo struct {
+	mu      sync.RWMutex
+	windows map[uuid.UUID]domain.FreezeWindow
+}
+
+func NewFreezeWindowRepo() *FreezeWindowRepo {
+	return &FreezeWindowRepo{windows: make(map[uuid.UUID]domain.FreezeWindow)}
+}
+
+func (r *FreezeWindowRepo) Create(_ context.Context, w domain.FreezeWindow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.windows[w.ID] = w
+	return nil
+}
+
+func (r *FreezeWindowRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.FreezeWindow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	w, ok := r.windows[id]
+	if !ok || !inOrgID(ctx, w.OrgID) {
+		return domain.FreezeWindow{}, domain.ErrNotFound
+	}
+	return w, nil
+}
+
+func (r *FreezeWindowRepo) List(ctx context.Context) ([]domain.FreezeWindow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	windows := make([]domain.FreezeWindow, 0, len(r.windows))
+	for _, w := range r.windows {
+		if inOrgID(ctx, w.OrgID) {
+			windows = append(windows, w)
+		}
+	}
+	return windows, nil
+}
+
+func (r *FreezeWindowRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.windows[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.windows, id)
+	return nil
+}
+
+// NotificationChannelRepo is an in-memory mock implementation of repository.NotificationChannelRepo.
+type NotificationChannelRepo struct {
+	mu       sync.RWMutex
+	channels map[uuid.UUID]domain.NotificationChannel
+}
+
+func NewNotificationChannelRepo() *NotificationChannelRepo {
+	return &NotificationChannelRepo{channels: make(map[uuid.UUID]domain.NotificationChannel)}
+}
+
+func (r *NotificationChannelRepo) Create(_ context.Context, c domain.NotificationChannel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[c.ID] = c
+	return nil
+}
+
+func (r *NotificationChannelRepo) GetByID(_ context.Context, id uuid.UUID) (domain.NotificationChannel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.channels[id]
+	if !ok {
+		return c, domain.ErrNotFound
+	}
+	return c, nil
+}
+
+func (r *NotificationChannelRepo) ListByApplicationID(_ context.Context, appID uuid.UUID) ([]domain.NotificationChannel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var channels []domain.NotificationChannel
+	for _, c := range r.channels {
+		if c.ApplicationID == appID {
+			channels = append(channels, c)
+		}
+	}
+	return channels, nil
+}
+
+func (r *NotificationChannelRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.channels[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.channels, id)
+	return nil
+}
+
+// NotificationDeliveryRepo is an in-memory mock implementation of repository.NotificationDeliveryRepo.
+type NotificationDeliveryRepo struct {
+	mu         sync.RWMutex
+	deliveries []domain.NotificationDelivery // in insertion order
+}
+
+func NewNotificationDeliveryRepo() *NotificationDeliveryRepo {
+	return &NotificationDeliveryRepo{}
+}
+
+func (r *NotificationDeliveryRepo) Create(_ context.Context, d domain.NotificationDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, d)
+	return nil
+}
+
+func (r *NotificationDeliveryRepo) ListByApplicationID(_ context.Context, appID uuid.UUID, limit int) ([]domain.NotificationDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var deliveries []domain.NotificationDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+		if r.deliveries[i].ApplicationID == appID {
+			deliveries = append(deliveries, r.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+// WebhookSubscriptionRepo is an in-memory mock implementation of repository.WebhookSubscriptionRepo.
+type WebhookSubscriptionRepo struct {
+	mu   sync.RWMutex
+	subs map[uuid.UUID]domain.WebhookSubscription
+}
+
+func NewWebhookSubscriptionRepo() *WebhookSubscriptionRepo {
+	return &WebhookSubscriptionRepo{subs: make(map[uuid.UUID]domain.WebhookSubscription)}
+}
+
+func (r *WebhookSubscriptionRepo) Create(_ context.Context, s domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subs[s.ID] = s
+	return nil
+}
+
+func (r *WebhookSubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.subs[id]
+	if !ok || !inOrgID(ctx, s.OrgID) {
+		return domain.WebhookSubscription{}, domain.ErrNotFound
+	}
+	return s, nil
+}
+
+func (r *WebhookSubscriptionRepo) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	subs := make([]domain.WebhookSubscription, 0, len(r.subs))
+	for _, s := range r.subs {
+		if inOrgID(ctx, s.OrgID) {
+			subs = append(subs, s)
+		}
+	}
+	return subs, nil
+}
+
+func (r *WebhookSubscriptionRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subs[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.subs, id)
+	return nil
+}
+
+// WebhookDeliveryRepo is an in-memory mock implementation of repository.WebhookDeliveryRepo.
+type WebhookDeliveryRepo struct {
+	mu         sync.RWMutex
+	deliveries []domain.WebhookDelivery // in insertion order
+}
+
+func NewWebhookDeliveryRepo() *WebhookDeliveryRepo {
+	return &WebhookDeliveryRepo{}
+}
+
+func (r *WebhookDeliveryRepo) Create(_ context.Context, d domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, d)
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) GetByID(_ context.Context, id uuid.UUID) (domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return domain.WebhookDelivery{}, domain.ErrNotFound
+}
+
+func (r *WebhookDeliveryRepo) Update(_ context.Context, d domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == d.ID {
+			r.deliveries[i] = d
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (r *WebhookDeliveryRepo) ListBySubscriptionID(_ context.Context, subID uuid.UUID, status domain.WebhookDeliveryStatus) ([]domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var deliveries []domain.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		d := r.deliveries[i]
+		if d.SubscriptionID == subID && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookDeliveryRepo) ListDue(_ context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var deliveries []domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+		if d.Status == domain.WebhookPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// APIKeyRepo is an in-memory mock implementation of repository.APIKeyRepo.
+type APIKeyRepo struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]domain.APIKey
+}
+
+func NewAPIKeyRepo() *APIKeyRepo {
+	return &APIKeyRepo{keys: make(map[uuid.UUID]domain.APIKey)}
+}
+
+func (r *APIKeyRepo) Create(_ context.Context, k domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[k.ID] = k
+	return nil
+}
+
+func (r *APIKeyRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.keys[id]
+	if !ok || !inOrgID(ctx, k.OrgID) {
+		return k, domain.ErrNotFound
+	}
+	return k, nil
+}
+
+func (r *APIKeyRepo) GetByHash(_ context.Context, hash string) (domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return domain.APIKey{}, domain.ErrNotFound
+}
+
+func (r *APIKeyRepo) List(ctx context.Context) ([]domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]domain.APIKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		if inOrgID(ctx, k.OrgID) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok || !inOrgID(ctx, k.OrgID) {
+		return domain.ErrNotFound
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = &at
+		r.keys[id] = k
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) MarkUsed(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	k.LastUsedAt = &at
+	r.keys[id] = k
+	return nil
+}
+
+// RoleBindingRepo is an in-memory mock implementation of repository.RoleBindingRepo.
+type RoleBindingRepo struct {
+	mu       sync.RWMutex
+	bindings map[uuid.UUID]domain.RoleBinding
+}
+
+func NewRoleBindingRepo() *RoleBindingRepo {
+	return &RoleBindingRepo{bindings: make(map[uuid.UUID]domain.RoleBinding)}
+}
+
+func (r *RoleBindingRepo) Create(_ context.Context, b domain.RoleBinding) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.bindings {
+		if existing.OrgID == b.OrgID && existing.Subject == b.Subject && existing.Role == b.Role && sameApp(existing.ApplicationID, b.ApplicationID) {
+			return domain.ErrConflict
+		}
+	}
+	r.bindings[b.ID] = b
+	return nil
+}
+
+func sameApp(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func (r *RoleBindingRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.RoleBinding, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.bindings[id]
+	if !ok || !inOrgID(ctx, b.OrgID) {
+		return domain.RoleBinding{}, domain.ErrNotFound
+	}
+	return b, nil
+}
+
+func (r *RoleBindingRepo) List(ctx context.Context) ([]domain.RoleBinding, error) {
+	return r.list(ctx, func(domain.RoleBinding) bool { return true }), nil
+}
+
+func (r *RoleBindingRepo) ListBySubject(ctx context.Context, subject string) ([]domain.RoleBinding, error) {
+	return r.list(ctx, func(b domain.RoleBinding) bool { return b.Subject == subject }), nil
+}
+
+func (r *RoleBindingRepo) list(ctx context.Context, keep func(domain.RoleBinding) bool) []domain.RoleBinding {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var bindings []domain.RoleBinding
+	for _, b := range r.bindings {
+		if inOrgID(ctx, b.OrgID) && keep(b) {
+			bindings = append(bindings, b)
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].CreatedAt.Before(bindings[j].CreatedAt) })
+	return bindings
+}
+
+func (r *RoleBindingRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.bindings[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.bindings, id)
+	return nil
+}
+
+// Transactor is a mock implementation of repository.Transactor. The
+// in-memory repositories have no transactions, so fn simply runs.
+type Transactor struct{}
+
+func NewTransactor() *Transactor {
+	return &Transactor{}
+}
+
+func (Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// EventOutboxRepo is an in-memory mock implementation of repository.EventOutboxRepo.
+type EventOutboxRepo struct {
+	mu         sync.RWMutex
+	events     []domain.Event // in insertion order
+	dispatched map[uuid.UUID]time.Time
+}
+
+func NewEventOutboxRepo() *EventOutboxRepo {
+	return &EventOutboxRepo{dispatched: make(map[uuid.UUID]time.Time)}
+}
+
+func (r *EventOutboxRepo) Create(_ context.Context, e domain.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *EventOutboxRepo) ListPending(_ context.Context, limit int) ([]domain.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var events []domain.Event
+	for _, e := range r.events {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		if _, done := r.dispatched[e.ID]; !done {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *EventOutboxRepo) MarkDispatched(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e.ID == id {
+			r.dispatched[id] = at
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+// LeaderLock is an in-process mock implementation of repository.LeaderLock.
+// A single server is always the leader unless Deny is set.
+type LeaderLock struct {
+	mu   sync.Mutex
+	Deny bool // simulate another replica holding the lock
+	held bool
+}
+
+func NewLeaderLock() *LeaderLock {
+	return &LeaderLock{}
+}
+
+func (l *LeaderLock) TryAcquire(_ context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.Deny {
+		return false, nil
+	}
+	l.held = true
+	return true, nil
+}
+
+func (l *LeaderLock) Release(_ context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held = false
+	return nil
+}
+
+// OrganizationRepo is an in-memory mock implementation of repository.OrganizationRepo.
+// Like the migration, it starts with the default organization.
+type OrganizationRepo struct {
+	mu   sync.RWMutex
+	orgs map[uuid.UUID]domain.Organization
+}
+
+func NewOrganizationRepo() *OrganizationRepo {
+	def := domain.NewOrganization(domain.DefaultOrgSlug, "Default")
+	def.ID = domain.DefaultOrgID
+	return &OrganizationRepo{orgs: map[uuid.UUID]domain.Organization{def.ID: def}}
+}
+
+func (r *OrganizationRepo) Create(_ context.Context, o domain.Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.orgs {
+		if existing.Slug == o.Slug {
+			return domain.ErrConflict
+		}
+	}
+	r.orgs[o.ID] = o
+	return nil
+}
+
+func (r *OrganizationRepo) GetByID(_ context.Context, id uuid.UUID) (domain.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	o, ok := r.orgs[id]
+	if !ok {
+		return o, domain.ErrNotFound
+	}
+	return o, nil
+}
+
+func (r *OrganizationRepo) GetBySlug(_ context.Context, slug string) (domain.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, o := range r.orgs {
+		if o.Slug == slug {
+			return o, nil
+		}
+	}
+	return domain.Organization{}, domain.ErrNotFound
+}
+
+func (r *OrganizationRepo) List(_ context.Context) ([]domain.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orgs := make([]domain.Organization, 0, len(r.orgs))
+	for _, o := range r.orgs {
+		orgs = append(orgs, o)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Slug < orgs[j].Slug })
+	return orgs, nil
+}
+
+// AuditLogRepo is an in-memory mock implementation of repository.AuditLogRepo.
+type AuditLogRepo struct {
+	mu      sync.RWMutex
+	entries []domain.AuditEntry // in insertion order
+}
+
+func NewAuditLogRepo() *AuditLogRepo {
+	return &AuditLogRepo{}
+}
+
+func (r *AuditLogRepo) Create(_ context.Context, e domain.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func (r *AuditLogRepo) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orgID := tenant.OrgID(ctx)
+	var entries []domain.AuditEntry
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		e := r.entries[i]
+		if orgID != nil && e.OrgID != nil && *e.OrgID != *orgID {
+			continue
+		}
+		if !filter.Matches(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// LLMCacheRepo is an in-memory mock implementation of llm.Cache.
+type LLMCacheRepo struct {
+	mu      sync.RWMutex
+	entries map[string]domain.LLMCacheEntry
+}
+
+func NewLLMCacheRepo() *LLMCacheRepo {
+	return &LLMCacheRepo{entries: make(map[string]domain.LLMCacheEntry)}
+}
+
+func (r *LLMCacheRepo) Get(_ context.Context, key string) (domain.LLMCacheEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[key]
+	if !ok {
+		return domain.LLMCacheEntry{}, domain.ErrNotFound
+	}
+	return e, nil
+}
+
+func (r *LLMCacheRepo) Put(_ context.Context, e domain.LLMCacheEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[e.Key] = e
+	return nil
+}
+
+func (r *LLMCacheRepo) DeleteExpired(_ context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for key, e := range r.entries {
+		if e.Expired(now) {
+			delete(r.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// LLMUsageRepo is an in-memory mock implementation of repository.LLMUsageRepo.
+type LLMUsageRepo struct {
+	mu    sync.RWMutex
+	usage []domain.LLMUsage
+}
+
+func NewLLMUsageRepo() *LLMUsageRepo {
+	return &LLMUsageRepo{}
+}
+
+func (r *LLMUsageRepo) Create(_ context.Context, u domain.LLMUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usage = append(r.usage, u)
+	return nil
+}
+
+func (r *LLMUsageRepo) Aggregate(ctx context.Context, filter domain.LLMUsageFilter) ([]domain.LLMUsageAggregate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	type group struct {
+		appID uuid.UUID // uuid.Nil for usage outside any application
+		day   time.Time
+	}
+	orgID := tenant.OrgID(ctx)
+	sums := make(map[group]*domain.LLMUsageAggregate)
+	for _, u := range r.usage {
+		if orgID != nil && (u.OrgID == nil || *u.OrgID != *orgID) {
+			continue
+		}
+		if !filter.Matches(u) {
+			continue
+		}
+		g := group{day: u.CreatedAt.UTC().Truncate(24 * time.Hour)}
+		if u.ApplicationID != nil {
+			g.appID = *u.ApplicationID
+		}
+		a, ok := sums[g]
+		if !ok {
+			a = &domain.LLMUsageAggregate{ApplicationID: u.ApplicationID, Day: g.day}
+			sums[g] = a
+		}
+		a.Add(domain.LLMUsageTotals{Calls: 1, InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, CostUSD: u.CostUSD})
+	}
+	aggregates := make([]domain.LLMUsageAggregate, 0, len(sums))
+	for _, a := range sums {
+		aggregates = append(aggregates, *a)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		if !aggregates[i].Day.Equal(aggregates[j].Day) {
+			return aggregates[i].Day.Before(aggregates[j].Day)
+		}
+		return aggregates[i].ApplicationID != nil && (aggregates[j].ApplicationID == nil || aggregates[i].ApplicationID.String() < aggregates[j].ApplicationID.String())
+	})
+	return aggregates, nil
+}
+
+// LLMBudgetRepo is an in-memory mock implementation of repository.LLMBudgetRepo.
+type LLMBudgetRepo struct {
+	mu      sync.RWMutex
+	budgets map[uuid.UUID]domain.LLMBudget
+}
+
+func NewLLMBudgetRepo() *LLMBudgetRepo {
+	return &LLMBudgetRepo{budgets: make(map[uuid.UUID]domain.LLMBudget)}
+}
+
+func (r *LLMBudgetRepo) Put(_ context.Context, b domain.LLMBudget) (domain.LLMBudget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, existing := range r.budgets {
+		if sameBudgetScope(existing, b.OrgID, b.ApplicationID) {
+			existing.MonthlyTokens, existing.UpdatedAt = b.MonthlyTokens, b.UpdatedAt
+			r.budgets[id] = existing
+			return existing, nil
+		}
+	}
+	r.budgets[b.ID] = b
+	return b, nil
+}
+
+func (r *LLMBudgetRepo) Get(_ context.Context, orgID uuid.UUID, appID *uuid.UUID) (domain.LLMBudget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, b := range r.budgets {
+		if sameBudgetScope(b, orgID, appID) {
+			return b, nil
+		}
+	}
+	return domain.LLMBudget{}, domain.ErrNotFound
+}
+
+func (r *LLMBudgetRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.LLMBudget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.budgets[id]
+	if orgID := tenant.OrgID(ctx); !ok || (orgID != nil && b.OrgID != *orgID) {
+		return domain.LLMBudget{}, domain.ErrNotFound
+	}
+	return b, nil
+}
+
+func (r *LLMBudgetRepo) List(ctx context.Context) ([]domain.LLMBudget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orgID := tenant.OrgID(ctx)
+	budgets := make([]domain.LLMBudget, 0, len(r.budgets))
+	for _, b := range r.budgets {
+		if orgID == nil || b.OrgID == *orgID {
+			budgets = append(budgets, b)
+		}
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		if (budgets[i].ApplicationID == nil) != (budgets[j].ApplicationID == nil) {
+			return budgets[i].ApplicationID == nil
+		}
+		return budgets[i].CreatedAt.Before(budgets[j].CreatedAt)
+	})
+	return budgets, nil
+}
+
+func (r *LLMBudgetRepo) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.budgets[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.budgets, id)
+	return nil
+}
+
+func sameBudgetScope(b domain.LLMBudget, orgID uuid.UUID, appID *uuid.UUID) bool {
+	if b.OrgID != orgID || (b.ApplicationID == nil) != (appID == nil) {
+		return false
+	}
+	return appID == nil || *b.ApplicationID == *appID
+}
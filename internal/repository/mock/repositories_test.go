@@ -3,10 +3,13 @@ package mock
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 func TestApplicationRepo_CRUD(t *testing.T) {
@@ -249,3 +252,521 @@ func TestPlanRepo_CRUD(t *testing.T) {
 		t.Errorf("ListByApplicationID() len = %d, want 1", len(plans))
 	}
 }
+
+func TestScheduleRepo_CRUD(t *testing.T) {
+	repo := NewScheduleRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+
+	s := domain.NewSchedule(appID, domain.JobGraph, "@daily", "UTC", "")
+
+	// Create
+	if err := repo.Create(ctx, s); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// GetByID
+	got, err := repo.GetByID(ctx, s.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.CronExpr != "@daily" {
+		t.Errorf("GetByID().CronExpr = %q, want @daily", got.CronExpr)
+	}
+
+	// ListByApplicationID
+	schedules, _ := repo.ListByApplicationID(ctx, appID)
+	if len(schedules) != 1 {
+		t.Errorf("ListByApplicationID() len = %d, want 1", len(schedules))
+	}
+
+	// Update
+	s.Enabled = false
+	if err := repo.Update(ctx, s); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ = repo.GetByID(ctx, s.ID)
+	if got.Enabled {
+		t.Error("Update: Enabled = true, want false")
+	}
+
+	// Delete
+	if err := repo.Delete(ctx, s.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, s.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(deleted): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestLeaderLock(t *testing.T) {
+	lock := NewLeaderLock()
+	ctx := context.Background()
+
+	if ok, _ := lock.TryAcquire(ctx); !ok {
+		t.Error("TryAcquire() = false, want true")
+	}
+	lock.Deny = true
+	if ok, _ := lock.TryAcquire(ctx); ok {
+		t.Error("TryAcquire() with Deny = true, want false")
+	}
+}
+
+func TestFreezeWindowRepo_CRUD(t *testing.T) {
+	repo := NewFreezeWindowRepo()
+	ctx := context.Background()
+
+	w := domain.NewFreezeWindow("weekend", "", domain.DefaultOrgID, nil, "", domain.FreezeRule{Cron: "0 18 * * fri", DurationMinutes: 60 * 62})
+
+	// Create
+	if err := repo.Create(ctx, w); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// GetByID
+	got, err := repo.GetByID(ctx, w.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Cron != "0 18 * * fri" {
+		t.Errorf("GetByID().Cron = %q, want 0 18 * * fri", got.Cron)
+	}
+
+	// List
+	windows, _ := repo.List(ctx)
+	if len(windows) != 1 {
+		t.Errorf("List() len = %d, want 1", len(windows))
+	}
+
+	// Delete
+	if err := repo.Delete(ctx, w.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, w.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(deleted): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestNotificationChannelRepo_CRUD(t *testing.T) {
+	repo := NewNotificationChannelRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+
+	c := domain.NewNotificationChannel(appID, domain.ChannelSlack, "https://hooks.slack.com/services/x", nil)
+
+	// Create
+	if err := repo.Create(ctx, c); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// GetByID
+	got, err := repo.GetByID(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Kind != domain.ChannelSlack {
+		t.Errorf("GetByID().Kind = %q, want slack", got.Kind)
+	}
+
+	// ListByApplicationID
+	channels, _ := repo.ListByApplicationID(ctx, appID)
+	if len(channels) != 1 {
+		t.Errorf("ListByApplicationID() len = %d, want 1", len(channels))
+	}
+
+	// Delete
+	if err := repo.Delete(ctx, c.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, c.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(deleted): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestNotificationDeliveryRepo_ListNewestFirst(t *testing.T) {
+	repo := NewNotificationDeliveryRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+
+	for _, subject := range []string{"first", "second", "third"} {
+		repo.Create(ctx, domain.NotificationDelivery{ID: uuid.New(), ApplicationID: appID, Subject: subject})
+	}
+	repo.Create(ctx, domain.NotificationDelivery{ID: uuid.New(), ApplicationID: uuid.New(), Subject: "other app"})
+
+	got, _ := repo.ListByApplicationID(ctx, appID, 2)
+	if len(got) != 2 || got[0].Subject != "third" || got[1].Subject != "second" {
+		t.Errorf("ListByApplicationID(limit 2) = %+v, want third, second", got)
+	}
+	if all, _ := repo.ListByApplicationID(ctx, appID, 0); len(all) != 3 {
+		t.Errorf("ListByApplicationID(no limit) len = %d, want 3", len(all))
+	}
+}
+
+func TestWebhookSubscriptionRepo_CRUD(t *testing.T) {
+	repo := NewWebhookSubscriptionRepo()
+	ctx := context.Background()
+
+	s := domain.NewWebhookSubscription("https://tools.example.com/hook", "s3cret", nil, domain.DefaultOrgID, nil)
+
+	// Create
+	if err := repo.Create(ctx, s); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// GetByID
+	got, err := repo.GetByID(ctx, s.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.URL != s.URL {
+		t.Errorf("GetByID().URL = %q, want %q", got.URL, s.URL)
+	}
+
+	// List
+	subs, _ := repo.List(ctx)
+	if len(subs) != 1 {
+		t.Errorf("List() len = %d, want 1", len(subs))
+	}
+
+	// Delete
+	if err := repo.Delete(ctx, s.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, s.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestWebhookDeliveryRepo_CRUD(t *testing.T) {
+	repo := NewWebhookDeliveryRepo()
+	ctx := context.Background()
+	subID := uuid.New()
+	now := time.Now().UTC()
+	later := now.Add(time.Hour)
+
+	due := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Status: domain.WebhookPending, NextAttemptAt: &now}
+	notYet := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Status: domain.WebhookPending, NextAttemptAt: &later}
+	dead := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Status: domain.WebhookDead}
+
+	// Create
+	for _, d := range []domain.WebhookDelivery{due, notYet, dead} {
+		if err := repo.Create(ctx, d); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// ListDue
+	got, _ := repo.ListDue(ctx, now, 10)
+	if len(got) != 1 || got[0].ID != due.ID {
+		t.Errorf("ListDue() = %+v, want only the due delivery", got)
+	}
+
+	// Update
+	due.Status, due.NextAttemptAt = domain.WebhookDelivered, nil
+	if err := repo.Update(ctx, due); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if d, _ := repo.GetByID(ctx, due.ID); d.Status != domain.WebhookDelivered {
+		t.Errorf("GetByID().Status = %q, want delivered", d.Status)
+	}
+
+	// ListBySubscriptionID
+	if all, _ := repo.ListBySubscriptionID(ctx, subID, ""); len(all) != 3 || all[0].ID != dead.ID {
+		t.Errorf("ListBySubscriptionID() = %+v, want 3 newest first", all)
+	}
+	if deadOnly, _ := repo.ListBySubscriptionID(ctx, subID, domain.WebhookDead); len(deadOnly) != 1 {
+		t.Errorf("ListBySubscriptionID(dead) len = %d, want 1", len(deadOnly))
+	}
+}
+
+func TestAPIKeyRepo_CRUD(t *testing.T) {
+	repo := NewAPIKeyRepo()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	k := domain.NewAPIKey("ci", "ipk_0123456789abcdef", []domain.Scope{domain.ScopeRead}, domain.DefaultOrgID, nil)
+
+	// Create
+	if err := repo.Create(ctx, k); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// GetByHash
+	got, err := repo.GetByHash(ctx, domain.HashAPIKey("ipk_0123456789abcdef"))
+	if err != nil || got.ID != k.ID {
+		t.Fatalf("GetByHash() = %+v, %v", got, err)
+	}
+	if _, err := repo.GetByHash(ctx, domain.HashAPIKey("ipk_other")); err != domain.ErrNotFound {
+		t.Errorf("GetByHash(unknown): got %v, want ErrNotFound", err)
+	}
+
+	// MarkUsed
+	if err := repo.MarkUsed(ctx, k.ID, now); err != nil {
+		t.Fatalf("MarkUsed() error = %v", err)
+	}
+
+	// Revoke
+	if err := repo.Revoke(ctx, k.ID, now); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	got, _ = repo.GetByID(ctx, k.ID)
+	if got.LastUsedAt == nil || got.RevokedAt == nil || !got.RevokedAt.Equal(now) {
+		t.Errorf("after MarkUsed and Revoke: %+v", got)
+	}
+	if err := repo.Revoke(ctx, uuid.New(), now); err != domain.ErrNotFound {
+		t.Errorf("Revoke(unknown): got %v, want ErrNotFound", err)
+	}
+
+	// List
+	keys, _ := repo.List(ctx)
+	if len(keys) != 1 {
+		t.Errorf("List() len = %d, want 1", len(keys))
+	}
+
+	// Another organization's keys are hidden, except from GetByHash.
+	other := tenant.WithOrg(ctx, domain.NewOrganization("acme", ""))
+	if keys, _ := repo.List(other); len(keys) != 0 {
+		t.Errorf("List(other org) len = %d, want 0", len(keys))
+	}
+	if _, err := repo.GetByID(other, k.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(other org): got %v, want ErrNotFound", err)
+	}
+	if err := repo.Revoke(other, k.ID, now); err != domain.ErrNotFound {
+		t.Errorf("Revoke(other org): got %v, want ErrNotFound", err)
+	}
+	if _, err := repo.GetByHash(other, k.Hash); err != nil {
+		t.Errorf("GetByHash(other org) error = %v", err)
+	}
+}
+
+func TestRoleBindingRepo_CRUD(t *testing.T) {
+	repo := NewRoleBindingRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+
+	global := domain.NewRoleBinding("alice", domain.RoleViewer, domain.DefaultOrgID, nil)
+	scoped := domain.NewRoleBinding("alice", domain.RoleViewer, domain.DefaultOrgID, &appID)
+
+	// Create
+	for _, b := range []domain.RoleBinding{global, scoped} {
+		if err := repo.Create(ctx, b); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := repo.Create(ctx, domain.NewRoleBinding("alice", domain.RoleViewer, domain.DefaultOrgID, nil)); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate global): got %v, want ErrConflict", err)
+	}
+	other := appID
+	if err := repo.Create(ctx, domain.NewRoleBinding("alice", domain.RoleViewer, domain.DefaultOrgID, &other)); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate scoped): got %v, want ErrConflict", err)
+	}
+	repo.Create(ctx, domain.NewRoleBinding("bob", domain.RoleAdmin, domain.DefaultOrgID, nil))
+
+	// ListBySubject
+	bindings, _ := repo.ListBySubject(ctx, "alice")
+	if len(bindings) != 2 {
+		t.Errorf("ListBySubject() len = %d, want 2", len(bindings))
+	}
+
+	// Delete
+	if err := repo.Delete(ctx, global.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByID(ctx, global.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, global.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(deleted): got %v, want ErrNotFound", err)
+	}
+
+	// List
+	all, _ := repo.List(ctx)
+	if len(all) != 2 {
+		t.Errorf("List() len = %d, want 2", len(all))
+	}
+
+	// Organization scope
+	acme := domain.NewOrganization("acme", "")
+	if err := repo.Create(ctx, domain.NewRoleBinding("alice", domain.RoleViewer, acme.ID, nil)); err != nil {
+		t.Errorf("Create(same global binding in another org) error = %v", err)
+	}
+	if bindings, _ := repo.ListBySubject(tenant.WithOrg(ctx, acme), "alice"); len(bindings) != 1 || bindings[0].OrgID != acme.ID {
+		t.Errorf("ListBySubject(acme) = %+v, want acme's binding only", bindings)
+	}
+	if _, err := repo.GetByID(tenant.WithOrg(ctx, acme), scoped.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(other org): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestEventOutboxRepo_Dispatch(t *testing.T) {
+	repo := NewEventOutboxRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+
+	var ids []uuid.UUID
+	for _, typ := range []domain.EventType{domain.EventApplicationRegistered, domain.EventResourceAdded, domain.EventPlanGenerated} {
+		e, _ := domain.NewEvent(typ, appID, nil)
+		repo.Create(ctx, e)
+		ids = append(ids, e.ID)
+	}
+
+	// ListPending
+	pending, _ := repo.ListPending(ctx, 2)
+	if len(pending) != 2 || pending[0].ID != ids[0] {
+		t.Fatalf("ListPending(2) = %+v, want the two oldest", pending)
+	}
+
+	// MarkDispatched
+	if err := repo.MarkDispatched(ctx, ids[0], time.Now()); err != nil {
+		t.Fatalf("MarkDispatched() error = %v", err)
+	}
+	if err := repo.MarkDispatched(ctx, uuid.New(), time.Now()); err != domain.ErrNotFound {
+		t.Errorf("MarkDispatched(unknown): got %v, want ErrNotFound", err)
+	}
+	pending, _ = repo.ListPending(ctx, 0)
+	if len(pending) != 2 || pending[0].ID != ids[1] {
+		t.Errorf("ListPending() after dispatch = %+v", pending)
+	}
+}
+
+func TestOrganizationRepo_CRUD(t *testing.T) {
+	repo := NewOrganizationRepo()
+	apps := NewApplicationRepo()
+	ctx := context.Background()
+
+	// The default organization exists from the start
+	def, err := repo.GetBySlug(ctx, domain.DefaultOrgSlug)
+	if err != nil || def.ID != domain.DefaultOrgID {
+		t.Fatalf("GetBySlug(default) = %+v, %v", def, err)
+	}
+
+	// Create
+	acme := domain.NewOrganization("acme", "Acme Corp")
+	if err := repo.Create(ctx, acme); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, domain.NewOrganization("acme", "")); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate slug): got %v, want ErrConflict", err)
+	}
+
+	// GetByID
+	if got, err := repo.GetByID(ctx, acme.ID); err != nil || got.Name != "Acme Corp" {
+		t.Errorf("GetByID() = %+v, %v", got, err)
+	}
+
+	// List is ordered by slug
+	if orgs, _ := repo.List(ctx); len(orgs) != 2 || orgs[0].Slug != "acme" {
+		t.Errorf("List() = %+v, want acme then default", orgs)
+	}
+
+	// Application names are unique per organization
+	mine := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	theirs := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	theirs.OrgID = acme.ID
+	for _, app := range []domain.Application{mine, theirs} {
+		if err := apps.Create(ctx, app); err != nil {
+			t.Fatalf("create app: %v", err)
+		}
+	}
+
+	// Scoped contexts only see their organization's applications
+	acmeCtx := tenant.WithOrg(ctx, acme)
+	if got, err := apps.GetByName(acmeCtx, "shop"); err != nil || got.ID != theirs.ID {
+		t.Errorf("GetByName(acme) = %+v, %v", got, err)
+	}
+	if _, err := apps.GetByID(acmeCtx, mine.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(other org): got %v, want ErrNotFound", err)
+	}
+	if list, _ := apps.List(acmeCtx); len(list) != 1 {
+		t.Errorf("List(acme) = %d apps, want 1", len(list))
+	}
+	if list, _ := apps.List(ctx); len(list) != 2 {
+		t.Errorf("List(unscoped) = %d apps, want 2", len(list))
+	}
+	if _, err := apps.GetByName(ctx, "shop"); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("GetByName(unscoped, name in two orgs): got %v, want ErrConflict", err)
+	}
+	if err := apps.Delete(acmeCtx, mine.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(other org): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestAuditLogRepo_List(t *testing.T) {
+	repo := NewAuditLogRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+	acme := domain.NewOrganization("acme", "")
+
+	old, _ := domain.NewAuditEntry("resource.add", "resource", "r1", &appID, nil, map[string]string{"name": "db"})
+	old.CreatedAt = time.Now().UTC().Add(-time.Hour)
+	old.OrgID = &acme.ID
+	other, _ := domain.NewAuditEntry("resource.remove", "resource", "r2", nil, map[string]string{"name": "cache"}, nil)
+	other.OrgID = &domain.DefaultOrgID
+	global, _ := domain.NewAuditEntry("api_key.create", "api_key", "k1", nil, nil, map[string]string{"name": "ci"})
+	for _, e := range []domain.AuditEntry{old, other, global} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Newest first
+	if entries, _ := repo.List(ctx, domain.AuditFilter{}); len(entries) != 3 || entries[0].ID != global.ID {
+		t.Errorf("List() = %+v, want all three, newest first", entries)
+	}
+	if entries, _ := repo.List(ctx, domain.AuditFilter{ApplicationID: &appID}); len(entries) != 1 || entries[0].ID != old.ID {
+		t.Errorf("List(application) = %+v", entries)
+	}
+	if entries, _ := repo.List(ctx, domain.AuditFilter{To: time.Now().UTC().Add(-time.Minute)}); len(entries) != 1 || entries[0].ID != old.ID {
+		t.Errorf("List(to) = %+v", entries)
+	}
+	if entries, _ := repo.List(ctx, domain.AuditFilter{Limit: 2}); len(entries) != 2 {
+		t.Errorf("List(limit 2) = %d entries, want 2", len(entries))
+	}
+
+	// Scoped contexts see their organization's entries and global ones
+	if entries, _ := repo.List(tenant.WithOrg(ctx, acme), domain.AuditFilter{}); len(entries) != 2 || entries[0].ID != global.ID || entries[1].ID != old.ID {
+		t.Errorf("List(acme) = %+v", entries)
+	}
+}
+
+func TestLLMCacheRepo_Expiry(t *testing.T) {
+	repo := NewLLMCacheRepo()
+	ctx := context.Background()
+	now := time.Now()
+
+	fresh := domain.LLMCacheEntry{Key: "fresh", Operation: "GenerateGraph", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	stale := domain.LLMCacheEntry{Key: "stale", Operation: "GenerateGraph", CreatedAt: now, ExpiresAt: now.Add(-time.Minute)}
+	repo.Put(ctx, fresh)
+	repo.Put(ctx, stale)
+
+	if got, err := repo.Get(ctx, "fresh"); err != nil || got.Key != "fresh" {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+	if n, err := repo.DeleteExpired(ctx, now); err != nil || n != 1 {
+		t.Errorf("DeleteExpired() = %d, %v, want 1", n, err)
+	}
+	if _, err := repo.Get(ctx, "stale"); err != domain.ErrNotFound {
+		t.Errorf("Get(expired): got %v, want ErrNotFound", err)
+	}
+}
+
+func TestLLMBudgetRepo_Put(t *testing.T) {
+	repo := NewLLMBudgetRepo()
+	ctx := context.Background()
+	appID := uuid.New()
+
+	org, _ := repo.Put(ctx, domain.NewLLMBudget(domain.DefaultOrgID, nil, 1000))
+	app, _ := repo.Put(ctx, domain.NewLLMBudget(domain.DefaultOrgID, &appID, 100))
+	if app.ID == org.ID {
+		t.Fatal("application budget replaced the organization's")
+	}
+	if again, _ := repo.Put(ctx, domain.NewLLMBudget(domain.DefaultOrgID, &appID, 200)); again.ID != app.ID || again.MonthlyTokens != 200 {
+		t.Errorf("Put(same application) = %+v, want %s updated", again, app.ID)
+	}
+	if budgets, _ := repo.List(ctx); len(budgets) != 2 || budgets[0].ID != org.ID {
+		t.Errorf("List() = %+v, want the organization budget first", budgets)
+	}
+	if got, err := repo.Get(ctx, domain.DefaultOrgID, &appID); err != nil || got.MonthlyTokens != 200 {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// apiKeyColumns is the column list shared by every API key SELECT, in the
+// order expected by scanAPIKey.
+const apiKeyColumns = `id, org_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// APIKeyRepo implements repository.APIKeyRepo with PostgreSQL.
+type APIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewAPIKeyRepo creates a new PostgreSQL-backed API key repository.
+func NewAPIKeyRepo(pool *pgxpool.Pool) *APIKeyRepo {
+	return &APIKeyRepo{pool: pool}
+}
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var k domain.APIKey
+	var scopesJSON []byte
+	if err := row.Scan(&k.ID, &k.OrgID, &k.Name, &k.Prefix, &k.Hash, &scopesJSON, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt); err != nil {
+		return k, err
+	}
+	if err := json.Unmarshal(scopesJSON, &k.Scopes); err != nil {
+		return k, fmt.Errorf("unmarshal scopes: %w", err)
+	}
+	return k, nil
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, k domain.APIKey) error {
+	scopesJSON, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return fmt.Errorf("marshal scopes: %w", err)
+	}
+
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO api_keys (`+apiKeyColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		k.ID, k.OrgID, k.Name, k.Prefix, k.Hash, scopesJSON, k.ExpiresAt, k.LastUsedAt, k.RevokedAt, k.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert api key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.APIKey, error) {
+	return r.get(ctx, `id = $1 AND `+inOrg(2), id, tenant.OrgID(ctx))
+}
+
+// GetByHash finds a key in any organization: authentication happens before
+// a request is scoped to one.
+func (r *APIKeyRepo) GetByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	return r.get(ctx, `key_hash = $1`, hash)
+}
+
+func (r *APIKeyRepo) get(ctx context.Context, where string, args ...any) (domain.APIKey, error) {
+	k, err := scanAPIKey(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE `+where, args...,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return k, domain.ErrNotFound
+		}
+		return k, fmt.Errorf("get api key: %w", err)
+	}
+	return k, nil
+}
+
+func (r *APIKeyRepo) List(ctx context.Context) ([]domain.APIKey, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE `+inOrg(1)+` ORDER BY created_at DESC`, tenant.OrgID(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke marks the key revoked. Revoking an already revoked key keeps the
+// original revocation time.
+func (r *APIKeyRepo) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1 AND `+inOrg(3), id, at, tenant.OrgID(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := conn(ctx, r.pool).Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	if err != nil {
+		return fmt.Errorf("mark api key used: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+func TestIntegrationAPIKeyRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	repo := NewAPIKeyRepo(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	expires := now.Add(24 * time.Hour)
+
+	k := domain.NewAPIKey("ci", "ipk_0123456789abcdef", []domain.Scope{domain.ScopeRead, domain.ScopeWrite}, domain.DefaultOrgID, &expires)
+	if err := repo.Create(ctx, k); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByHash(ctx, k.Hash)
+	if err != nil {
+		t.Fatalf("GetByHash() error = %v", err)
+	}
+	if got.ID != k.ID || got.Prefix != "ipk_01234567" || len(got.Scopes) != 2 || !got.ExpiresAt.Equal(expires) {
+		t.Errorf("GetByHash() = %+v", got)
+	}
+
+	if err := repo.MarkUsed(ctx, k.ID, now); err != nil {
+		t.Fatalf("MarkUsed() error = %v", err)
+	}
+	if err := repo.Revoke(ctx, k.ID, now); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := repo.Revoke(ctx, k.ID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke() again error = %v", err)
+	}
+	got, _ = repo.GetByID(ctx, k.ID)
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(now) || got.RevokedAt == nil || !got.RevokedAt.Equal(now) {
+		t.Errorf("after MarkUsed and Revoke: last used %v, revoked %v", got.LastUsedAt, got.RevokedAt)
+	}
+
+	keys, err := repo.List(ctx)
+	if err != nil || len(keys) != 1 {
+		t.Errorf("List() = %d keys, %v", len(keys), err)
+	}
+	if _, err := repo.GetByHash(ctx, domain.HashAPIKey("ipk_unknown")); err != domain.ErrNotFound {
+		t.Errorf("GetByHash(unknown): got %v, want ErrNotFound", err)
+	}
+
+	// Another organization's keys are hidden, except from GetByHash.
+	other := tenant.WithOrg(ctx, domain.NewOrganization("acme", ""))
+	if keys, _ := repo.List(other); len(keys) != 0 {
+		t.Errorf("List(other org) = %d keys, want 0", len(keys))
+	}
+	if _, err := repo.GetByID(other, k.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(other org): got %v, want ErrNotFound", err)
+	}
+	if got, err := repo.GetByHash(other, k.Hash); err != nil || got.OrgID != domain.DefaultOrgID {
+		t.Errorf("GetByHash(other org) = %+v, %v", got, err)
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
+// applicationColumns is the column list shared by every application SELECT,
+// in the order expected by scanApplication.
+const applicationColumns = `id, org_id, name, description, git_repo_url, source_path, provider, status, compliance_frameworks, prevent_destroy, deploy_branch, created_at, updated_at`
+
 // ApplicationRepo implements repository.ApplicationRepo with PostgreSQL.
 type ApplicationRepo struct {
 	pool *pgxpool.Pool
@@ -23,16 +28,29 @@ func NewApplicationRepo(pool *pgxpool.Pool) *ApplicationRepo {
 	return &ApplicationRepo{pool: pool}
 }
 
+func scanApplication(row pgx.Row) (domain.Application, error) {
+	var app domain.Application
+	var frameworksJSON []byte
+	err := row.Scan(&app.ID, &app.OrgID, &app.Name, &app.Description, &app.GitRepoURL, &app.SourcePath, &app.Provider, &app.Status, &frameworksJSON, &app.PreventDestroy, &app.DeployBranch, &app.CreatedAt, &app.UpdatedAt)
+	if err != nil {
+		return app, err
+	}
+	if len(frameworksJSON) > 0 {
+		json.Unmarshal(frameworksJSON, &app.ComplianceFrameworks)
+	}
+	return app, nil
+}
+
 func (r *ApplicationRepo) Create(ctx context.Context, app domain.Application) error {
 	frameworks, _ := json.Marshal(app.ComplianceFrameworks)
 	if string(frameworks) == "null" {
 		frameworks = []byte("[]")
 	}
 
-	_, err := r.pool.Exec(ctx,
-		`INSERT INTO applications (id, name, description, git_repo_url, source_path, provider, status, compliance_frameworks, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
-		app.ID, app.Name, app.Description, app.GitRepoURL, app.SourcePath, app.Provider, app.Status, frameworks, app.CreatedAt, app.UpdatedAt,
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO applications (`+applicationColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		app.ID, app.OrgID, app.Name, app.Description, app.GitRepoURL, app.SourcePath, app.Provider, app.Status, frameworks, app.PreventDestroy, app.DeployBranch, app.CreatedAt, app.UpdatedAt,
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -45,47 +63,55 @@ func (r *ApplicationRepo) Create(ctx context.Context, app domain.Application) er
 }
 
 func (r *ApplicationRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Application, error) {
-	var app domain.Application
-	var frameworksJSON []byte
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, name, description, git_repo_url, source_path, provider, status, compliance_frameworks, created_at, updated_at
-		 FROM applications WHERE id = $1`, id,
-	).Scan(&app.ID, &app.Name, &app.Description, &app.GitRepoURL, &app.SourcePath, &app.Provider, &app.Status, &frameworksJSON, &app.CreatedAt, &app.UpdatedAt)
+	app, err := scanApplication(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+applicationColumns+`
+		 FROM applications WHERE id = $1 AND `+inOrg(2), id, tenant.OrgID(ctx),
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return app, domain.ErrNotFound
 		}
 		return app, fmt.Errorf("get application by id: %w", err)
 	}
-	if len(frameworksJSON) > 0 {
-		json.Unmarshal(frameworksJSON, &app.ComplianceFrameworks)
-	}
 	return app, nil
 }
 
 func (r *ApplicationRepo) GetByName(ctx context.Context, name string) (domain.Application, error) {
-	var app domain.Application
-	var frameworksJSON []byte
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, name, description, git_repo_url, source_path, provider, status, compliance_frameworks, created_at, updated_at
-		 FROM applications WHERE name = $1`, name,
-	).Scan(&app.ID, &app.Name, &app.Description, &app.GitRepoURL, &app.SourcePath, &app.Provider, &app.Status, &frameworksJSON, &app.CreatedAt, &app.UpdatedAt)
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+applicationColumns+`
+		 FROM applications WHERE name = $1 AND `+inOrg(2)+`
+		 LIMIT 2`, name, tenant.OrgID(ctx),
+	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return app, domain.ErrNotFound
+		return domain.Application{}, fmt.Errorf("get application by name: %w", err)
+	}
+	defer rows.Close()
+
+	var apps []domain.Application
+	for rows.Next() {
+		app, err := scanApplication(rows)
+		if err != nil {
+			return domain.Application{}, fmt.Errorf("scan application: %w", err)
 		}
-		return app, fmt.Errorf("get application by name: %w", err)
+		apps = append(apps, app)
 	}
-	if len(frameworksJSON) > 0 {
-		json.Unmarshal(frameworksJSON, &app.ComplianceFrameworks)
+	if err := rows.Err(); err != nil {
+		return domain.Application{}, fmt.Errorf("get application by name: %w", err)
+	}
+	switch len(apps) {
+	case 0:
+		return domain.Application{}, domain.ErrNotFound
+	case 1:
+		return apps[0], nil
+	default:
+		return domain.Application{}, fmt.Errorf("%w: application name %q is used in more than one organization", domain.ErrConflict, name)
 	}
-	return app, nil
 }
 
 func (r *ApplicationRepo) List(ctx context.Context) ([]domain.Application, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT id, name, description, git_repo_url, source_path, provider, status, compliance_frameworks, created_at, updated_at
-		 FROM applications ORDER BY created_at DESC`,
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+applicationColumns+`
+		 FROM applications WHERE `+inOrg(1)+` ORDER BY created_at DESC`, tenant.OrgID(ctx),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list applications: %w", err)
@@ -94,14 +120,10 @@ func (r *ApplicationRepo) List(ctx context.Context) ([]domain.Application, error
 
 	var apps []domain.Application
 	for rows.Next() {
-		var app domain.Application
-		var frameworksJSON []byte
-		if err := rows.Scan(&app.ID, &app.Name, &app.Description, &app.GitRepoURL, &app.SourcePath, &app.Provider, &app.Status, &frameworksJSON, &app.CreatedAt, &app.UpdatedAt); err != nil {
+		app, err := scanApplication(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan application: %w", err)
 		}
-		if len(frameworksJSON) > 0 {
-			json.Unmarshal(frameworksJSON, &app.ComplianceFrameworks)
-		}
 		apps = append(apps, app)
 	}
 	return apps, rows.Err()
@@ -113,13 +135,17 @@ func (r *ApplicationRepo) Update(ctx context.Context, app domain.Application) er
 		frameworks = []byte("[]")
 	}
 
-	result, err := r.pool.Exec(ctx,
+	result, err := conn(ctx, r.pool).Exec(ctx,
 		`UPDATE applications
-		 SET name = $2, description = $3, git_repo_url = $4, source_path = $5, provider = $6, status = $7, compliance_frameworks = $8, updated_at = $9
-		 WHERE id = $1`,
-		app.ID, app.Name, app.Description, app.GitRepoURL, app.SourcePath, app.Provider, app.Status, frameworks, app.UpdatedAt,
+		 SET name = $2, description = $3, git_repo_url = $4, source_path = $5, provider = $6, status = $7, compliance_frameworks = $8, prevent_destroy = $9, deploy_branch = $10, updated_at = $11
+		 WHERE id = $1 AND `+inOrg(12),
+		app.ID, app.Name, app.Description, app.GitRepoURL, app.SourcePath, app.Provider, app.Status, frameworks, app.PreventDestroy, app.DeployBranch, app.UpdatedAt, tenant.OrgID(ctx),
 	)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrConflict
+		}
 		return fmt.Errorf("update application: %w", err)
 	}
 	if result.RowsAffected() == 0 {
@@ -129,7 +155,7 @@ func (r *ApplicationRepo) Update(ctx context.Context, app domain.Application) er
 }
 
 func (r *ApplicationRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	result, err := r.pool.Exec(ctx, `DELETE FROM applications WHERE id = $1`, id)
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM applications WHERE id = $1 AND `+inOrg(2), id, tenant.OrgID(ctx))
 	if err != nil {
 		return fmt.Errorf("delete application: %w", err)
 	}
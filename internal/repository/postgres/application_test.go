@@ -85,6 +85,8 @@ func TestIntegrationApplicationRepo(t *testing.T) {
 
 		app.Description = "updated"
 		app.Status = domain.AppStatusProvisioned
+		app.PreventDestroy = true
+		app.DeployBranch = "main"
 		if err := repo.Update(ctx, app); err != nil {
 			t.Fatalf("Update() error = %v", err)
 		}
@@ -96,6 +98,12 @@ func TestIntegrationApplicationRepo(t *testing.T) {
 		if got.Status != domain.AppStatusProvisioned {
 			t.Errorf("Status = %q, want %q", got.Status, domain.AppStatusProvisioned)
 		}
+		if !got.PreventDestroy {
+			t.Error("PreventDestroy should be true")
+		}
+		if got.DeployBranch != "main" {
+			t.Errorf("DeployBranch = %q, want %q", got.DeployBranch, "main")
+		}
 	})
 
 	t.Run("Update not found", func(t *testing.T) {
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// auditColumns is the column list shared by every audit log SELECT, in the
+// order expected by scanAuditEntry.
+const auditColumns = `id, org_id, application_id, actor, actor_name, action, target_type, target_id, before, after, request_id, source, created_at`
+
+// AuditLogRepo implements repository.AuditLogRepo with PostgreSQL. Create
+// joins the caller's transaction, so an entry is stored if and only if the
+// change it records commits. A trigger rejects updates and deletes.
+type AuditLogRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewAuditLogRepo creates a new PostgreSQL-backed audit log repository.
+func NewAuditLogRepo(pool *pgxpool.Pool) *AuditLogRepo {
+	return &AuditLogRepo{pool: pool}
+}
+
+func scanAuditEntry(row pgx.Row) (domain.AuditEntry, error) {
+	var e domain.AuditEntry
+	var before, after []byte
+	err := row.Scan(&e.ID, &e.OrgID, &e.ApplicationID, &e.Actor, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID, &before, &after, &e.RequestID, &e.Source, &e.CreatedAt)
+	e.Before, e.After = before, after
+	return e, err
+}
+
+func (r *AuditLogRepo) Create(ctx context.Context, e domain.AuditEntry) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO audit_log (`+auditColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		e.ID, e.OrgID, e.ApplicationID, e.Actor, e.ActorName, e.Action, e.TargetType, e.TargetID, nullableJSON(e.Before), nullableJSON(e.After), e.RequestID, e.Source, e.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries matching filter, newest first. A context scoped
+// to an organization sees its entries and those outside any organization.
+func (r *AuditLogRepo) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log
+		 WHERE ($1::uuid IS NULL OR org_id = $1 OR org_id IS NULL)`
+	args := []any{tenant.OrgID(ctx)}
+	if filter.ApplicationID != nil {
+		args = append(args, *filter.ApplicationID)
+		query += fmt.Sprintf(` AND application_id = $%d`, len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(` AND created_at >= $%d`, len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(` AND created_at < $%d`, len(args))
+	}
+	query += ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []domain.AuditEntry
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
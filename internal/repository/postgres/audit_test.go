@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationAuditLogRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	repo := NewAuditLogRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("audit-test-app", "desc", "", "", domain.ProviderAWS)
+	created, err := domain.NewAuditEntry("application.register", "application", app.ID.String(), &app.ID, nil, app)
+	if err != nil {
+		t.Fatalf("NewAuditEntry() error = %v", err)
+	}
+	created.CreatedAt = time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond)
+	key, _ := domain.NewAuditEntry("api_key.revoke", "api_key", "k1", nil, map[string]string{"name": "ci"}, nil)
+	key.Actor, key.Source, key.RequestID = "admin", domain.AuditSourceAPI, "req-1"
+	for _, e := range []domain.AuditEntry{created, key} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	entries, err := repo.List(ctx, domain.AuditFilter{})
+	if err != nil || len(entries) != 2 || entries[0].ID != key.ID {
+		t.Fatalf("List() = %+v, %v, want both, newest first", entries, err)
+	}
+	if got := entries[0]; got.After != nil || string(got.Before) != `{"name": "ci"}` || got.RequestID != "req-1" || got.Source != domain.AuditSourceAPI {
+		t.Errorf("List()[0] = %+v", got)
+	}
+	if entries, err := repo.List(ctx, domain.AuditFilter{ApplicationID: &app.ID}); err != nil || len(entries) != 1 || entries[0].ID != created.ID {
+		t.Errorf("List(application) = %+v, %v", entries, err)
+	}
+	if entries, err := repo.List(ctx, domain.AuditFilter{From: time.Now().UTC().Add(-time.Minute)}); err != nil || len(entries) != 1 || entries[0].ID != key.ID {
+		t.Errorf("List(from) = %+v, %v", entries, err)
+	}
+	if entries, err := repo.List(ctx, domain.AuditFilter{Limit: 1}); err != nil || len(entries) != 1 {
+		t.Errorf("List(limit 1) = %+v, %v", entries, err)
+	}
+
+	// Entries cannot be changed or removed.
+	if _, err := pool.Exec(ctx, `DELETE FROM audit_log WHERE id = $1`, key.ID); err == nil {
+		t.Error("DELETE succeeded, want the append-only trigger to reject it")
+	}
+	if _, err := pool.Exec(ctx, `UPDATE audit_log SET actor = 'someone' WHERE id = $1`, key.ID); err == nil {
+		t.Error("UPDATE succeeded, want the append-only trigger to reject it")
+	}
+}
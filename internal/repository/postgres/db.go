@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,3 +28,57 @@ func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+// querier is the subset of the pool and transaction APIs the repositories use.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// conn returns the transaction started by Transactor.WithinTx that ctx
+// carries, or pool when there is none, so repository calls made inside
+// WithinTx take part in its transaction.
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+// inOrg is a WHERE condition limiting rows with an org_id to the
+// organization bound to parameter $n. Pass tenant.OrgID(ctx) for $n: NULL,
+// for an unscoped context, matches every row.
+func inOrg(n int) string {
+	return fmt.Sprintf("($%[1]d::uuid IS NULL OR org_id = $%[1]d)", n)
+}
+
+// appInOrg is a WHERE condition limiting rows with an application_id to the
+// applications of the organization bound to parameter $n. Pass
+// tenant.OrgID(ctx) for $n: NULL, for an unscoped context, matches every row.
+func appInOrg(n int) string {
+	return fmt.Sprintf("($%[1]d::uuid IS NULL OR application_id IN (SELECT id FROM applications WHERE org_id = $%[1]d))", n)
+}
+
+// Transactor implements repository.Transactor with PostgreSQL transactions.
+type Transactor struct {
+	pool *pgxpool.Pool
+}
+
+// NewTransactor creates a new PostgreSQL-backed transactor.
+func NewTransactor(pool *pgxpool.Pool) *Transactor {
+	return &Transactor{pool: pool}
+}
+
+// WithinTx runs fn in a transaction that commits if fn returns nil and rolls
+// back otherwise. Nested calls join the outer transaction.
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	return pgx.BeginFunc(ctx, t.pool, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
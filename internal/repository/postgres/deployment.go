@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -9,8 +10,14 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
+// deploymentColumns is the column list shared by every deployment SELECT,
+// in the order expected by scanDeployment.
+const deploymentColumns = `id, application_id, type, plan_id, rollback_of, break_glass, provider, git_commit, git_branch, status, terraform_plan, config_hash,
+	apply_output, failure_reason, resources, started_at, completed_at, duration_ms`
+
 // DeploymentRepo implements repository.DeploymentRepo with PostgreSQL.
 type DeploymentRepo struct {
 	pool *pgxpool.Pool
@@ -21,11 +28,42 @@ func NewDeploymentRepo(pool *pgxpool.Pool) *DeploymentRepo {
 	return &DeploymentRepo{pool: pool}
 }
 
+func scanDeployment(row pgx.Row) (domain.Deployment, error) {
+	var d domain.Deployment
+	var resourcesJSON []byte
+	err := row.Scan(&d.ID, &d.ApplicationID, &d.Type, &d.PlanID, &d.RollbackOf, &d.BreakGlass, &d.Provider, &d.GitCommit, &d.GitBranch, &d.Status, &d.TerraformPlan, &d.ConfigHash,
+		&d.ApplyOutput, &d.FailureReason, &resourcesJSON, &d.StartedAt, &d.CompletedAt, &d.DurationMs)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(resourcesJSON, &d.Resources); err != nil {
+		return d, fmt.Errorf("unmarshal resources: %w", err)
+	}
+	return d, nil
+}
+
+func marshalDeploymentResources(resources []domain.Resource) ([]byte, error) {
+	if resources == nil {
+		return []byte("[]"), nil
+	}
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return nil, fmt.Errorf("marshal resources: %w", err)
+	}
+	return data, nil
+}
+
 func (r *DeploymentRepo) Create(ctx context.Context, d domain.Deployment) error {
-	_, err := r.pool.Exec(ctx,
-		`INSERT INTO deployments (id, application_id, plan_id, provider, git_commit, git_branch, status, terraform_plan, started_at, completed_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
-		d.ID, d.ApplicationID, d.PlanID, d.Provider, d.GitCommit, d.GitBranch, d.Status, d.TerraformPlan, d.StartedAt, d.CompletedAt,
+	resourcesJSON, err := marshalDeploymentResources(d.Resources)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO deployments (`+deploymentColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+		d.ID, d.ApplicationID, d.Type, d.PlanID, d.RollbackOf, d.BreakGlass, d.Provider, d.GitCommit, d.GitBranch, d.Status, d.TerraformPlan, d.ConfigHash,
+		d.ApplyOutput, d.FailureReason, resourcesJSON, d.StartedAt, d.CompletedAt, d.DurationMs,
 	)
 	if err != nil {
 		return fmt.Errorf("insert deployment: %w", err)
@@ -34,11 +72,10 @@ func (r *DeploymentRepo) Create(ctx context.Context, d domain.Deployment) error
 }
 
 func (r *DeploymentRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Deployment, error) {
-	var d domain.Deployment
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, application_id, plan_id, provider, git_commit, git_branch, status, terraform_plan, started_at, completed_at
-		 FROM deployments WHERE id = $1`, id,
-	).Scan(&d.ID, &d.ApplicationID, &d.PlanID, &d.Provider, &d.GitCommit, &d.GitBranch, &d.Status, &d.TerraformPlan, &d.StartedAt, &d.CompletedAt)
+	d, err := scanDeployment(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+deploymentColumns+`
+		 FROM deployments WHERE id = $1 AND `+appInOrg(2), id, tenant.OrgID(ctx),
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return d, domain.ErrNotFound
@@ -49,9 +86,9 @@ func (r *DeploymentRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Depl
 }
 
 func (r *DeploymentRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.Deployment, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT id, application_id, plan_id, provider, git_commit, git_branch, status, terraform_plan, started_at, completed_at
-		 FROM deployments WHERE application_id = $1 ORDER BY started_at DESC`, appID,
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+deploymentColumns+`
+		 FROM deployments WHERE application_id = $1 AND `+appInOrg(2)+` ORDER BY started_at DESC`, appID, tenant.OrgID(ctx),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list deployments: %w", err)
@@ -60,8 +97,8 @@ func (r *DeploymentRepo) ListByApplicationID(ctx context.Context, appID uuid.UUI
 
 	var deployments []domain.Deployment
 	for rows.Next() {
-		var d domain.Deployment
-		if err := rows.Scan(&d.ID, &d.ApplicationID, &d.PlanID, &d.Provider, &d.GitCommit, &d.GitBranch, &d.Status, &d.TerraformPlan, &d.StartedAt, &d.CompletedAt); err != nil {
+		d, err := scanDeployment(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan deployment: %w", err)
 		}
 		deployments = append(deployments, d)
@@ -70,10 +107,18 @@ func (r *DeploymentRepo) ListByApplicationID(ctx context.Context, appID uuid.UUI
 }
 
 func (r *DeploymentRepo) Update(ctx context.Context, d domain.Deployment) error {
-	result, err := r.pool.Exec(ctx,
-		`UPDATE deployments SET status = $2, terraform_plan = $3, completed_at = $4
+	resourcesJSON, err := marshalDeploymentResources(d.Resources)
+	if err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE deployments
+		 SET status = $2, terraform_plan = $3, config_hash = $4, apply_output = $5, failure_reason = $6, resources = $7,
+		     started_at = $8, completed_at = $9, duration_ms = $10, break_glass = $11
 		 WHERE id = $1`,
-		d.ID, d.Status, d.TerraformPlan, d.CompletedAt,
+		d.ID, d.Status, d.TerraformPlan, d.ConfigHash, d.ApplyOutput, d.FailureReason, resourcesJSON,
+		d.StartedAt, d.CompletedAt, d.DurationMs, d.BreakGlass,
 	)
 	if err != nil {
 		return fmt.Errorf("update deployment: %w", err)
@@ -85,11 +130,10 @@ func (r *DeploymentRepo) Update(ctx context.Context, d domain.Deployment) error
 }
 
 func (r *DeploymentRepo) GetLatestByApplicationID(ctx context.Context, appID uuid.UUID) (domain.Deployment, error) {
-	var d domain.Deployment
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, application_id, plan_id, provider, git_commit, git_branch, status, terraform_plan, started_at, completed_at
-		 FROM deployments WHERE application_id = $1 ORDER BY started_at DESC LIMIT 1`, appID,
-	).Scan(&d.ID, &d.ApplicationID, &d.PlanID, &d.Provider, &d.GitCommit, &d.GitBranch, &d.Status, &d.TerraformPlan, &d.StartedAt, &d.CompletedAt)
+	d, err := scanDeployment(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+deploymentColumns+`
+		 FROM deployments WHERE application_id = $1 AND `+appInOrg(2)+` ORDER BY started_at DESC LIMIT 1`, appID, tenant.OrgID(ctx),
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return d, domain.ErrNotFound
@@ -78,6 +78,72 @@ func TestIntegrationDeploymentRepo(t *testing.T) {
 		}
 	})
 
+	t.Run("Outcome fields round-trip", func(t *testing.T) {
+		d := domain.NewDeployment(app.ID, domain.ProviderAWS, "out789", "main", nil)
+		if err := repo.Create(ctx, d); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		d.Resources = []domain.Resource{domain.NewResource(app.ID, domain.ResourceDatabase, "db", nil)}
+		d.TerraformPlan = `resource "aws_db_instance" "db" {}`
+		d.ConfigHash = domain.HashConfig(d.TerraformPlan)
+		d.ApplyOutput = "Apply complete!"
+		d.FailureReason = "Terraform apply failed: quota exceeded"
+		d.BreakGlass = true
+		d.Finish(domain.DeploymentFailed, d.StartedAt.Add(2*time.Second))
+		if err := repo.Update(ctx, d); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		got, err := repo.GetByID(ctx, d.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.ConfigHash != d.ConfigHash {
+			t.Errorf("ConfigHash = %q, want %q", got.ConfigHash, d.ConfigHash)
+		}
+		if !got.BreakGlass {
+			t.Error("BreakGlass = false, want true")
+		}
+		if got.ApplyOutput != d.ApplyOutput {
+			t.Errorf("ApplyOutput = %q, want %q", got.ApplyOutput, d.ApplyOutput)
+		}
+		if got.FailureReason != d.FailureReason {
+			t.Errorf("FailureReason = %q, want %q", got.FailureReason, d.FailureReason)
+		}
+		if got.DurationMs != 2000 {
+			t.Errorf("DurationMs = %d, want 2000", got.DurationMs)
+		}
+		if len(got.Resources) != 1 || got.Resources[0].Name != "db" {
+			t.Errorf("Resources = %v, want one resource named db", got.Resources)
+		}
+	})
+
+	t.Run("RollbackOf round-trips", func(t *testing.T) {
+		source := domain.NewDeployment(app.ID, domain.ProviderAWS, "good123", "main", nil)
+		source.Status = domain.DeploymentSucceeded
+		source.TerraformPlan = `resource "aws_s3_bucket" "b" {}`
+		if err := repo.Create(ctx, source); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		rb := domain.NewRollbackDeployment(source)
+		if err := repo.Create(ctx, rb); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		got, err := repo.GetByID(ctx, rb.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.RollbackOf == nil || *got.RollbackOf != source.ID {
+			t.Errorf("RollbackOf = %v, want %v", got.RollbackOf, source.ID)
+		}
+		if got.TerraformPlan != source.TerraformPlan {
+			t.Errorf("TerraformPlan = %q, want %q", got.TerraformPlan, source.TerraformPlan)
+		}
+	})
+
 	t.Run("ListByApplicationID", func(t *testing.T) {
 		deps, err := repo.ListByApplicationID(ctx, app.ID)
 		if err != nil {
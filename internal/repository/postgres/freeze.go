@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// freezeWindowColumns is the column list shared by every freeze window
+// SELECT, in the order expected by scanFreezeWindow.
+const freezeWindowColumns = `id, org_id, name, reason, application_id, environment, starts_at, ends_at,
+	cron_expr, duration_minutes, timezone, created_at`
+
+// FreezeWindowRepo implements repository.FreezeWindowRepo with PostgreSQL.
+type FreezeWindowRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewFreezeWindowRepo creates a new PostgreSQL-backed freeze window repository.
+func NewFreezeWindowRepo(pool *pgxpool.Pool) *FreezeWindowRepo {
+	return &FreezeWindowRepo{pool: pool}
+}
+
+func scanFreezeWindow(row pgx.Row) (domain.FreezeWindow, error) {
+	var w domain.FreezeWindow
+	err := row.Scan(&w.ID, &w.OrgID, &w.Name, &w.Reason, &w.ApplicationID, &w.Environment, &w.StartsAt, &w.EndsAt,
+		&w.Cron, &w.DurationMinutes, &w.Timezone, &w.CreatedAt)
+	return w, err
+}
+
+func (r *FreezeWindowRepo) Create(ctx context.Context, w domain.FreezeWindow) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO freeze_windows (`+freezeWindowColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		w.ID, w.OrgID, w.Name, w.Reason, w.ApplicationID, w.Environment, w.StartsAt, w.EndsAt,
+		w.Cron, w.DurationMinutes, w.Timezone, w.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert freeze window: %w", err)
+	}
+	return nil
+}
+
+func (r *FreezeWindowRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.FreezeWindow, error) {
+	w, err := scanFreezeWindow(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+freezeWindowColumns+` FROM freeze_windows WHERE id = $1 AND `+inOrg(2), id, tenant.OrgID(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return w, domain.ErrNotFound
+		}
+		return w, fmt.Errorf("get freeze window by id: %w", err)
+	}
+	return w, nil
+}
+
+func (r *FreezeWindowRepo) List(ctx context.Context) ([]domain.FreezeWindow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+freezeWindowColumns+` FROM freeze_windows WHERE `+inOrg(1)+` ORDER BY created_at`, tenant.OrgID(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list freeze windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []domain.FreezeWindow
+	for rows.Next() {
+		w, err := scanFreezeWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan freeze window: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+func (r *FreezeWindowRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM freeze_windows WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete freeze window: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationFreezeWindowRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	appRepo := NewApplicationRepo(pool)
+	repo := NewFreezeWindowRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("freeze-test-app", "desc", "", "", domain.ProviderAWS)
+	if err := appRepo.Create(ctx, app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	start := time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC)
+	end := start.Add(14 * 24 * time.Hour)
+	oneOff := domain.NewFreezeWindow("holidays", "end of year", domain.DefaultOrgID, nil, "", domain.FreezeRule{StartsAt: &start, EndsAt: &end})
+	recurring := domain.NewFreezeWindow("weekends", "", domain.DefaultOrgID, &app.ID, "production",
+		domain.FreezeRule{Cron: "0 18 * * fri", DurationMinutes: 3600, Timezone: "Europe/Berlin"})
+
+	t.Run("Create and GetByID", func(t *testing.T) {
+		for _, w := range []domain.FreezeWindow{oneOff, recurring} {
+			if err := repo.Create(ctx, w); err != nil {
+				t.Fatalf("Create(%s) error = %v", w.Name, err)
+			}
+		}
+
+		got, err := repo.GetByID(ctx, oneOff.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.ApplicationID != nil || got.StartsAt == nil || !got.StartsAt.Equal(start) || got.Reason != "end of year" {
+			t.Errorf("one-off window = %+v", got)
+		}
+
+		got, _ = repo.GetByID(ctx, recurring.ID)
+		if got.ApplicationID == nil || *got.ApplicationID != app.ID || got.Environment != "production" {
+			t.Errorf("recurring scope = %v/%q", got.ApplicationID, got.Environment)
+		}
+		if got.Cron != "0 18 * * fri" || got.DurationMinutes != 3600 || got.Timezone != "Europe/Berlin" {
+			t.Errorf("recurring rule = %+v", got.FreezeRule)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		windows, err := repo.List(ctx)
+		if err != nil || len(windows) != 2 {
+			t.Errorf("List() = %d windows, err %v; want 2", len(windows), err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, oneOff.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if err := repo.Delete(ctx, uuid.New()); err != domain.ErrNotFound {
+			t.Errorf("Delete(unknown): got %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 // GraphRepo implements repository.GraphRepo with PostgreSQL.
@@ -33,10 +34,10 @@ func (r *GraphRepo) Create(ctx context.Context, g domain.InfraGraph) error {
 		return fmt.Errorf("marshal edges: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx,
-		`INSERT INTO infrastructure_graphs (id, application_id, nodes, edges, created_at)
-		 VALUES ($1, $2, $3, $4, $5)`,
-		g.ID, g.ApplicationID, nodesJSON, edgesJSON, g.CreatedAt,
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO infrastructure_graphs (id, application_id, nodes, edges, prompt_version, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		g.ID, g.ApplicationID, nodesJSON, edgesJSON, g.PromptVersion, g.CreatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("insert graph: %w", err)
@@ -47,11 +48,11 @@ func (r *GraphRepo) Create(ctx context.Context, g domain.InfraGraph) error {
 func (r *GraphRepo) GetLatestByApplicationID(ctx context.Context, appID uuid.UUID) (domain.InfraGraph, error) {
 	var g domain.InfraGraph
 	var nodesJSON, edgesJSON []byte
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, application_id, nodes, edges, created_at
-		 FROM infrastructure_graphs WHERE application_id = $1
-		 ORDER BY created_at DESC LIMIT 1`, appID,
-	).Scan(&g.ID, &g.ApplicationID, &nodesJSON, &edgesJSON, &g.CreatedAt)
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT id, application_id, nodes, edges, prompt_version, created_at
+		 FROM infrastructure_graphs WHERE application_id = $1 AND `+appInOrg(2)+`
+		 ORDER BY created_at DESC LIMIT 1`, appID, tenant.OrgID(ctx),
+	).Scan(&g.ID, &g.ApplicationID, &nodesJSON, &edgesJSON, &g.PromptVersion, &g.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return g, domain.ErrNotFound
@@ -68,10 +69,10 @@ func (r *GraphRepo) GetLatestByApplicationID(ctx context.Context, appID uuid.UUI
 }
 
 func (r *GraphRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.InfraGraph, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT id, application_id, nodes, edges, created_at
-		 FROM infrastructure_graphs WHERE application_id = $1
-		 ORDER BY created_at DESC`, appID,
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT id, application_id, nodes, edges, prompt_version, created_at
+		 FROM infrastructure_graphs WHERE application_id = $1 AND `+appInOrg(2)+`
+		 ORDER BY created_at DESC`, appID, tenant.OrgID(ctx),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list graphs: %w", err)
@@ -82,7 +83,7 @@ func (r *GraphRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([
 	for rows.Next() {
 		var g domain.InfraGraph
 		var nodesJSON, edgesJSON []byte
-		if err := rows.Scan(&g.ID, &g.ApplicationID, &nodesJSON, &edgesJSON, &g.CreatedAt); err != nil {
+		if err := rows.Scan(&g.ID, &g.ApplicationID, &nodesJSON, &edgesJSON, &g.PromptVersion, &g.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan graph: %w", err)
 		}
 		if err := json.Unmarshal(nodesJSON, &g.Nodes); err != nil {
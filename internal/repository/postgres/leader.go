@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SchedulerLockKey is the advisory lock key held by the scheduler leader.
+const SchedulerLockKey int64 = 0x696e66726170 // "infrap"
+
+// AdvisoryLock implements repository.LeaderLock with a session-level
+// PostgreSQL advisory lock. The lock lives as long as the connection that
+// took it, so the holder keeps one pooled connection checked out; if the
+// process dies the connection closes and another replica can take over.
+type AdvisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+
+	mu   sync.Mutex
+	conn *pgxpool.Conn // non-nil while this process holds the lock
+}
+
+// NewAdvisoryLock creates a leader lock on the given advisory lock key.
+func NewAdvisoryLock(pool *pgxpool.Pool, key int64) *AdvisoryLock {
+	return &AdvisoryLock{pool: pool, key: key}
+}
+
+func (l *AdvisoryLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		// Still leader as long as the session that holds the lock is alive.
+		if err := l.conn.Ping(ctx); err == nil {
+			return true, nil
+		}
+		l.conn.Release()
+		l.conn = nil
+	}
+
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire connection: %w", err)
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&ok); err != nil {
+		conn.Release()
+		return false, fmt.Errorf("try advisory lock: %w", err)
+	}
+	if !ok {
+		conn.Release()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	defer func() {
+		l.conn.Release()
+		l.conn = nil
+	}()
+	if _, err := l.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		return fmt.Errorf("advisory unlock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// LLMCacheRepo implements llm.Cache with PostgreSQL, so cached responses are
+// shared by every replica and survive restarts.
+type LLMCacheRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewLLMCacheRepo creates a new PostgreSQL-backed LLM response cache.
+func NewLLMCacheRepo(pool *pgxpool.Pool) *LLMCacheRepo {
+	return &LLMCacheRepo{pool: pool}
+}
+
+func (r *LLMCacheRepo) Get(ctx context.Context, key string) (domain.LLMCacheEntry, error) {
+	var e domain.LLMCacheEntry
+	var response []byte
+	err := r.pool.QueryRow(ctx,
+		`SELECT key, operation, model, response, created_at, expires_at FROM llm_cache WHERE key = $1`, key,
+	).Scan(&e.Key, &e.Operation, &e.Model, &response, &e.CreatedAt, &e.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.LLMCacheEntry{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.LLMCacheEntry{}, fmt.Errorf("get llm cache entry: %w", err)
+	}
+	e.Response = response
+	return e, nil
+}
+
+// Put stores e, replacing any entry with the same key.
+func (r *LLMCacheRepo) Put(ctx context.Context, e domain.LLMCacheEntry) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO llm_cache (key, operation, model, response, created_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (key) DO UPDATE SET operation = EXCLUDED.operation, model = EXCLUDED.model,
+		   response = EXCLUDED.response, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`,
+		e.Key, e.Operation, e.Model, []byte(e.Response), e.CreatedAt, e.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("put llm cache entry: %w", err)
+	}
+	return nil
+}
+
+func (r *LLMCacheRepo) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM llm_cache WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired llm cache entries: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationLLMCacheRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	repo := NewLLMCacheRepo(pool)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	if _, err := repo.Get(ctx, "missing"); err != domain.ErrNotFound {
+		t.Errorf("Get(missing): got %v, want ErrNotFound", err)
+	}
+
+	entry := domain.LLMCacheEntry{
+		Key: "k1", Operation: "GenerateGraph", Model: "m",
+		Response:  json.RawMessage(`{"nodes":[]}`),
+		CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+	}
+	if err := repo.Put(ctx, entry); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	entry.Response = json.RawMessage(`{"nodes":[{"id":"db"}]}`)
+	if err := repo.Put(ctx, entry); err != nil {
+		t.Fatalf("Put(replace) error = %v", err)
+	}
+	got, err := repo.Get(ctx, "k1")
+	if err != nil || got.Operation != "GenerateGraph" || !got.ExpiresAt.Equal(entry.ExpiresAt) {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+	var resp struct{ Nodes []struct{ ID string } }
+	if err := json.Unmarshal(got.Response, &resp); err != nil || len(resp.Nodes) != 1 {
+		t.Errorf("Get().Response = %s, want the replaced response", got.Response)
+	}
+
+	stale := entry
+	stale.Key = "k2"
+	stale.ExpiresAt = now.Add(-time.Minute)
+	repo.Put(ctx, stale)
+	if n, err := repo.DeleteExpired(ctx, now); err != nil || n != 1 {
+		t.Errorf("DeleteExpired() = %d, %v, want 1", n, err)
+	}
+	if _, err := repo.Get(ctx, "k2"); err != domain.ErrNotFound {
+		t.Errorf("Get(expired): got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// LLMUsageRepo implements repository.LLMUsageRepo with PostgreSQL.
+type LLMUsageRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewLLMUsageRepo creates a new PostgreSQL-backed LLM usage repository.
+func NewLLMUsageRepo(pool *pgxpool.Pool) *LLMUsageRepo {
+	return &LLMUsageRepo{pool: pool}
+}
+
+func (r *LLMUsageRepo) Create(ctx context.Context, u domain.LLMUsage) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO llm_usage (id, org_id, application_id, operation, model, input_tokens, output_tokens, latency_ms, cost_usd, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		u.ID, u.OrgID, u.ApplicationID, u.Operation, u.Model, u.InputTokens, u.OutputTokens, u.LatencyMs, u.CostUSD, u.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert llm usage: %w", err)
+	}
+	return nil
+}
+
+// Aggregate sums the usage matching filter per application and UTC day,
+// oldest day first.
+func (r *LLMUsageRepo) Aggregate(ctx context.Context, filter domain.LLMUsageFilter) ([]domain.LLMUsageAggregate, error) {
+	query := `SELECT application_id, date_trunc('day', created_at AT TIME ZONE 'UTC') AS day,
+		   COUNT(*), SUM(input_tokens), SUM(output_tokens), SUM(cost_usd)
+		 FROM llm_usage
+		 WHERE ($1::uuid IS NULL OR org_id = $1)`
+	args := []any{tenant.OrgID(ctx)}
+	if filter.OrgID != nil {
+		args = append(args, *filter.OrgID)
+		query += fmt.Sprintf(` AND org_id = $%d`, len(args))
+	}
+	if filter.ApplicationID != nil {
+		args = append(args, *filter.ApplicationID)
+		query += fmt.Sprintf(` AND application_id = $%d`, len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(` AND created_at >= $%d`, len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(` AND created_at < $%d`, len(args))
+	}
+	query += ` GROUP BY application_id, day ORDER BY day, application_id`
+
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate llm usage: %w", err)
+	}
+	defer rows.Close()
+
+	var aggregates []domain.LLMUsageAggregate
+	for rows.Next() {
+		var a domain.LLMUsageAggregate
+		if err := rows.Scan(&a.ApplicationID, &a.Day, &a.Calls, &a.InputTokens, &a.OutputTokens, &a.CostUSD); err != nil {
+			return nil, fmt.Errorf("scan llm usage: %w", err)
+		}
+		a.Day = a.Day.UTC()
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, rows.Err()
+}
+
+// llmBudgetColumns is the column list shared by every budget SELECT, in the
+// order expected by scanLLMBudget.
+const llmBudgetColumns = `id, org_id, application_id, monthly_tokens, created_at, updated_at`
+
+// LLMBudgetRepo implements repository.LLMBudgetRepo with PostgreSQL.
+type LLMBudgetRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewLLMBudgetRepo creates a new PostgreSQL-backed LLM budget repository.
+func NewLLMBudgetRepo(pool *pgxpool.Pool) *LLMBudgetRepo {
+	return &LLMBudgetRepo{pool: pool}
+}
+
+func scanLLMBudget(row pgx.Row) (domain.LLMBudget, error) {
+	var b domain.LLMBudget
+	err := row.Scan(&b.ID, &b.OrgID, &b.ApplicationID, &b.MonthlyTokens, &b.CreatedAt, &b.UpdatedAt)
+	return b, err
+}
+
+// Put stores b, or updates the limit of the budget already set for its
+// organization or application, and returns the stored budget.
+func (r *LLMBudgetRepo) Put(ctx context.Context, b domain.LLMBudget) (domain.LLMBudget, error) {
+	stored, err := scanLLMBudget(conn(ctx, r.pool).QueryRow(ctx,
+		`INSERT INTO llm_budgets (`+llmBudgetColumns+`) VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (org_id, application_id) DO UPDATE SET monthly_tokens = EXCLUDED.monthly_tokens, updated_at = EXCLUDED.updated_at
+		 RETURNING `+llmBudgetColumns,
+		b.ID, b.OrgID, b.ApplicationID, b.MonthlyTokens, b.CreatedAt, b.UpdatedAt,
+	))
+	if err != nil {
+		return domain.LLMBudget{}, fmt.Errorf("put llm budget: %w", err)
+	}
+	return stored, nil
+}
+
+func (r *LLMBudgetRepo) Get(ctx context.Context, orgID uuid.UUID, appID *uuid.UUID) (domain.LLMBudget, error) {
+	return r.get(ctx, `SELECT `+llmBudgetColumns+` FROM llm_budgets WHERE org_id = $1 AND application_id IS NOT DISTINCT FROM $2`, orgID, appID)
+}
+
+func (r *LLMBudgetRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.LLMBudget, error) {
+	return r.get(ctx, `SELECT `+llmBudgetColumns+` FROM llm_budgets WHERE id = $1 AND ($2::uuid IS NULL OR org_id = $2)`, id, tenant.OrgID(ctx))
+}
+
+func (r *LLMBudgetRepo) get(ctx context.Context, query string, args ...any) (domain.LLMBudget, error) {
+	b, err := scanLLMBudget(conn(ctx, r.pool).QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return b, domain.ErrNotFound
+		}
+		return b, fmt.Errorf("get llm budget: %w", err)
+	}
+	return b, nil
+}
+
+func (r *LLMBudgetRepo) List(ctx context.Context) ([]domain.LLMBudget, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+llmBudgetColumns+` FROM llm_budgets
+		 WHERE ($1::uuid IS NULL OR org_id = $1)
+		 ORDER BY org_id, application_id NULLS FIRST, created_at`, tenant.OrgID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list llm budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []domain.LLMBudget
+	for rows.Next() {
+		b, err := scanLLMBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan llm budget: %w", err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+func (r *LLMBudgetRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM llm_budgets WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete llm budget: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+func TestIntegrationLLMUsageRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	repo := NewLLMUsageRepo(pool)
+	ctx := context.Background()
+
+	appID := uuid.New()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, u := range []domain.LLMUsage{
+		{ID: uuid.New(), OrgID: &domain.DefaultOrgID, ApplicationID: &appID, Operation: "GenerateGraph", Model: "m", InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, CreatedAt: today.Add(time.Hour)},
+		{ID: uuid.New(), OrgID: &domain.DefaultOrgID, ApplicationID: &appID, Operation: "GenerateGraph", Model: "m", InputTokens: 200, OutputTokens: 20, CostUSD: 1, CreatedAt: today.Add(2 * time.Hour)},
+		{ID: uuid.New(), OrgID: &domain.DefaultOrgID, ApplicationID: &appID, Operation: "GenerateGraph", Model: "m", InputTokens: 1, OutputTokens: 1, CreatedAt: today.Add(-time.Hour)},
+		{ID: uuid.New(), OrgID: &domain.DefaultOrgID, Operation: "AnalyzeCodebase", Model: "m", InputTokens: 7, OutputTokens: 3, CreatedAt: today.Add(time.Hour)},
+	} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	aggregates, err := repo.Aggregate(ctx, domain.LLMUsageFilter{})
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+	if len(aggregates) != 3 || !aggregates[0].Day.Equal(today.AddDate(0, 0, -1)) {
+		t.Fatalf("Aggregate() = %+v, want yesterday's group first, then today's two", aggregates)
+	}
+
+	app, err := repo.Aggregate(ctx, domain.LLMUsageFilter{ApplicationID: &appID, From: today})
+	if err != nil || len(app) != 1 || app[0].Calls != 2 || app[0].InputTokens != 300 || app[0].OutputTokens != 30 || app[0].CostUSD != 1.5 {
+		t.Errorf("Aggregate(application, today) = %+v, %v", app, err)
+	}
+
+	other := domain.NewOrganization("other", "")
+	if scoped, _ := repo.Aggregate(tenant.WithOrg(ctx, other), domain.LLMUsageFilter{}); len(scoped) != 0 {
+		t.Errorf("Aggregate(other organization) = %+v, want nothing", scoped)
+	}
+}
+
+func TestIntegrationLLMBudgetRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	repo := NewLLMBudgetRepo(pool)
+	appRepo := NewApplicationRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("budgeted", "desc", "", "", domain.ProviderAWS)
+	if err := appRepo.Create(ctx, app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	org, err := repo.Put(ctx, domain.NewLLMBudget(domain.DefaultOrgID, nil, 1000))
+	if err != nil {
+		t.Fatalf("Put(org) error = %v", err)
+	}
+	appBudget, err := repo.Put(ctx, domain.NewLLMBudget(domain.DefaultOrgID, &app.ID, 100))
+	if err != nil {
+		t.Fatalf("Put(app) error = %v", err)
+	}
+
+	// Putting a budget for the same scope updates it in place.
+	replaced, err := repo.Put(ctx, domain.NewLLMBudget(domain.DefaultOrgID, nil, 5000))
+	if err != nil || replaced.ID != org.ID || replaced.MonthlyTokens != 5000 {
+		t.Errorf("Put(org again) = %+v, %v, want %s raised to 5000", replaced, err, org.ID)
+	}
+
+	if got, err := repo.Get(ctx, domain.DefaultOrgID, &app.ID); err != nil || got.ID != appBudget.ID {
+		t.Errorf("Get(app) = %+v, %v", got, err)
+	}
+	if got, err := repo.Get(ctx, domain.DefaultOrgID, nil); err != nil || got.ID != org.ID {
+		t.Errorf("Get(org) = %+v, %v", got, err)
+	}
+	if budgets, err := repo.List(ctx); err != nil || len(budgets) != 2 || budgets[0].ApplicationID != nil {
+		t.Errorf("List() = %+v, %v, want the organization budget first", budgets, err)
+	}
+
+	if err := repo.Delete(ctx, appBudget.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.Get(ctx, domain.DefaultOrgID, &app.ID); err != domain.ErrNotFound {
+		t.Errorf("Get(deleted): got %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, appBudget.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(deleted): got %v, want ErrNotFound", err)
+	}
+}
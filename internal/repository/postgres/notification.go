@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// notificationChannelColumns is the column list shared by every notification
+// channel SELECT, in the order expected by scanNotificationChannel.
+const notificationChannelColumns = `id, application_id, kind, target, events, enabled, created_at`
+
+// NotificationChannelRepo implements repository.NotificationChannelRepo with PostgreSQL.
+type NotificationChannelRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewNotificationChannelRepo creates a new PostgreSQL-backed notification channel repository.
+func NewNotificationChannelRepo(pool *pgxpool.Pool) *NotificationChannelRepo {
+	return &NotificationChannelRepo{pool: pool}
+}
+
+func scanNotificationChannel(row pgx.Row) (domain.NotificationChannel, error) {
+	var c domain.NotificationChannel
+	var eventsJSON []byte
+	if err := row.Scan(&c.ID, &c.ApplicationID, &c.Kind, &c.Target, &eventsJSON, &c.Enabled, &c.CreatedAt); err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(eventsJSON, &c.Events); err != nil {
+		return c, fmt.Errorf("unmarshal events: %w", err)
+	}
+	return c, nil
+}
+
+func (r *NotificationChannelRepo) Create(ctx context.Context, c domain.NotificationChannel) error {
+	events := c.Events
+	if events == nil {
+		events = []domain.NotificationEvent{}
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("marshal events: %w", err)
+	}
+
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO notification_channels (`+notificationChannelColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		c.ID, c.ApplicationID, c.Kind, c.Target, eventsJSON, c.Enabled, c.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert notification channel: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationChannelRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.NotificationChannel, error) {
+	c, err := scanNotificationChannel(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE id = $1`, id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c, domain.ErrNotFound
+		}
+		return c, fmt.Errorf("get notification channel by id: %w", err)
+	}
+	return c, nil
+}
+
+func (r *NotificationChannelRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.NotificationChannel, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+notificationChannelColumns+` FROM notification_channels
+		 WHERE application_id = $1 ORDER BY created_at`, appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []domain.NotificationChannel
+	for rows.Next() {
+		c, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan notification channel: %w", err)
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+func (r *NotificationChannelRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM notification_channels WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete notification channel: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// NotificationDeliveryRepo implements repository.NotificationDeliveryRepo with PostgreSQL.
+type NotificationDeliveryRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewNotificationDeliveryRepo creates a new PostgreSQL-backed notification delivery log.
+func NewNotificationDeliveryRepo(pool *pgxpool.Pool) *NotificationDeliveryRepo {
+	return &NotificationDeliveryRepo{pool: pool}
+}
+
+func (r *NotificationDeliveryRepo) Create(ctx context.Context, d domain.NotificationDelivery) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO notification_deliveries (id, channel_id, application_id, event, subject, status, attempts, error, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		d.ID, d.ChannelID, d.ApplicationID, d.Event, d.Subject, d.Status, d.Attempts, d.Error, d.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert notification delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationDeliveryRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID, limit int) ([]domain.NotificationDelivery, error) {
+	query := `SELECT id, channel_id, application_id, event, subject, status, attempts, error, created_at
+		 FROM notification_deliveries WHERE application_id = $1 ORDER BY created_at DESC`
+	args := []any{appID}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list notification deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.NotificationDelivery
+	for rows.Next() {
+		var d domain.NotificationDelivery
+		if err := rows.Scan(&d.ID, &d.ChannelID, &d.ApplicationID, &d.Event, &d.Subject, &d.Status, &d.Attempts, &d.Error, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan notification delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
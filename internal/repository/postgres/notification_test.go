@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationNotificationRepos(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	appRepo := NewApplicationRepo(pool)
+	channels := NewNotificationChannelRepo(pool)
+	deliveries := NewNotificationDeliveryRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("notify-test-app", "desc", "", "", domain.ProviderGCP)
+	if err := appRepo.Create(ctx, app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	slack := domain.NewNotificationChannel(app.ID, domain.ChannelSlack, "https://hooks.slack.com/services/x",
+		[]domain.NotificationEvent{domain.EventDeploymentFailed, domain.EventDriftDetected})
+	email := domain.NewNotificationChannel(app.ID, domain.ChannelEmail, "ops@example.com", nil)
+
+	t.Run("channels", func(t *testing.T) {
+		for _, c := range []domain.NotificationChannel{slack, email} {
+			if err := channels.Create(ctx, c); err != nil {
+				t.Fatalf("Create(%s) error = %v", c.Kind, err)
+			}
+		}
+
+		got, err := channels.GetByID(ctx, slack.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if len(got.Events) != 2 || got.Events[1] != domain.EventDriftDetected || !got.Enabled {
+			t.Errorf("slack channel = %+v", got)
+		}
+
+		list, _ := channels.ListByApplicationID(ctx, app.ID)
+		if len(list) != 2 {
+			t.Errorf("ListByApplicationID() len = %d, want 2", len(list))
+		}
+
+		if err := channels.Delete(ctx, email.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := channels.GetByID(ctx, email.ID); err != domain.ErrNotFound {
+			t.Errorf("GetByID(deleted): got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("delivery log", func(t *testing.T) {
+		base := time.Now().UTC().Truncate(time.Microsecond)
+		for i, status := range []domain.DeliveryStatus{domain.DeliveryDelivered, domain.DeliveryFailed} {
+			d := domain.NotificationDelivery{
+				ID: uuid.New(), ChannelID: slack.ID, ApplicationID: app.ID,
+				Event: domain.EventDeploymentFailed, Subject: "deploy failed",
+				Status: status, Attempts: i + 1, CreatedAt: base.Add(time.Duration(i) * time.Second),
+			}
+			if status == domain.DeliveryFailed {
+				d.Error = "502 Bad Gateway"
+			}
+			if err := deliveries.Create(ctx, d); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+		}
+
+		got, err := deliveries.ListByApplicationID(ctx, app.ID, 1)
+		if err != nil {
+			t.Fatalf("ListByApplicationID() error = %v", err)
+		}
+		if len(got) != 1 || got[0].Status != domain.DeliveryFailed || got[0].Attempts != 2 || got[0].Error == "" {
+			t.Errorf("newest delivery = %+v", got)
+		}
+	})
+}
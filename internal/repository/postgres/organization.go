@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// organizationColumns is the column list shared by every organization
+// SELECT, in the order expected by scanOrganization.
+const organizationColumns = `id, slug, name, created_at`
+
+// OrganizationRepo implements repository.OrganizationRepo with PostgreSQL.
+type OrganizationRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewOrganizationRepo creates a new PostgreSQL-backed organization repository.
+func NewOrganizationRepo(pool *pgxpool.Pool) *OrganizationRepo {
+	return &OrganizationRepo{pool: pool}
+}
+
+func scanOrganization(row pgx.Row) (domain.Organization, error) {
+	var o domain.Organization
+	err := row.Scan(&o.ID, &o.Slug, &o.Name, &o.CreatedAt)
+	return o, err
+}
+
+func (r *OrganizationRepo) Create(ctx context.Context, o domain.Organization) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO organizations (`+organizationColumns+`) VALUES ($1, $2, $3, $4)`,
+		o.ID, o.Slug, o.Name, o.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrConflict
+		}
+		return fmt.Errorf("insert organization: %w", err)
+	}
+	return nil
+}
+
+func (r *OrganizationRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Organization, error) {
+	return r.get(ctx, `SELECT `+organizationColumns+` FROM organizations WHERE id = $1`, id)
+}
+
+func (r *OrganizationRepo) GetBySlug(ctx context.Context, slug string) (domain.Organization, error) {
+	return r.get(ctx, `SELECT `+organizationColumns+` FROM organizations WHERE slug = $1`, slug)
+}
+
+func (r *OrganizationRepo) get(ctx context.Context, query string, arg any) (domain.Organization, error) {
+	o, err := scanOrganization(conn(ctx, r.pool).QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return o, domain.ErrNotFound
+		}
+		return o, fmt.Errorf("get organization: %w", err)
+	}
+	return o, nil
+}
+
+func (r *OrganizationRepo) List(ctx context.Context) ([]domain.Organization, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, `SELECT `+organizationColumns+` FROM organizations ORDER BY slug`)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []domain.Organization
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan organization: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+func TestIntegrationOrganizationRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	repo := NewOrganizationRepo(pool)
+	appRepo := NewApplicationRepo(pool)
+	depRepo := NewDeploymentRepo(pool)
+	ctx := context.Background()
+
+	// The migration creates the default organization.
+	def, err := repo.GetBySlug(ctx, domain.DefaultOrgSlug)
+	if err != nil || def.ID != domain.DefaultOrgID {
+		t.Fatalf("GetBySlug(default) = %+v, %v", def, err)
+	}
+
+	acme := domain.NewOrganization("acme", "Acme Corp")
+	if err := repo.Create(ctx, acme); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, domain.NewOrganization("acme", "")); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate slug): got %v, want ErrConflict", err)
+	}
+	if got, err := repo.GetByID(ctx, acme.ID); err != nil || got.Slug != "acme" || got.Name != "Acme Corp" {
+		t.Errorf("GetByID() = %+v, %v", got, err)
+	}
+	if orgs, err := repo.List(ctx); err != nil || len(orgs) != 2 || orgs[0].Slug != "acme" {
+		t.Errorf("List() = %+v, %v", orgs, err)
+	}
+
+	// Application names are unique per organization, not globally.
+	mine := domain.NewApplication("shop", "desc", "", "", domain.ProviderAWS)
+	theirs := domain.NewApplication("shop", "desc", "", "", domain.ProviderAWS)
+	theirs.OrgID = acme.ID
+	for _, app := range []domain.Application{mine, theirs} {
+		if err := appRepo.Create(ctx, app); err != nil {
+			t.Fatalf("create app: %v", err)
+		}
+	}
+	dup := domain.NewApplication("shop", "desc", "", "", domain.ProviderAWS)
+	if err := appRepo.Create(ctx, dup); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate name in org): got %v, want ErrConflict", err)
+	}
+
+	// Scoped contexts only see their organization's applications and deployments.
+	acmeCtx := tenant.WithOrg(ctx, acme)
+	if got, err := appRepo.GetByName(acmeCtx, "shop"); err != nil || got.ID != theirs.ID || got.OrgID != acme.ID {
+		t.Errorf("GetByName(acme) = %+v, %v", got, err)
+	}
+	if _, err := appRepo.GetByID(acmeCtx, mine.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(other org): got %v, want ErrNotFound", err)
+	}
+	if apps, err := appRepo.List(acmeCtx); err != nil || len(apps) != 1 {
+		t.Errorf("List(acme) = %d apps, %v; want 1", len(apps), err)
+	}
+	if apps, err := appRepo.List(ctx); err != nil || len(apps) != 2 {
+		t.Errorf("List(unscoped) = %d apps, %v; want 2", len(apps), err)
+	}
+	if _, err := appRepo.GetByName(ctx, "shop"); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("GetByName(unscoped, name in two orgs): got %v, want ErrConflict", err)
+	}
+	if err := appRepo.Delete(acmeCtx, mine.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(other org): got %v, want ErrNotFound", err)
+	}
+
+	d := domain.NewDeployment(mine.ID, domain.ProviderAWS, "abc123", "main", nil)
+	if err := depRepo.Create(ctx, d); err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	if _, err := depRepo.GetByID(acmeCtx, d.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(deployment in other org): got %v, want ErrNotFound", err)
+	}
+	if got, err := depRepo.GetByID(tenant.WithOrg(ctx, def), d.ID); err != nil || got.ID != d.ID {
+		t.Errorf("GetByID(deployment in own org) = %+v, %v", got, err)
+	}
+
+	// Global role bindings, freeze windows and webhook subscriptions, and
+	// schedules, are scoped to their organization too.
+	bindings := NewRoleBindingRepo(pool)
+	if err := bindings.Create(ctx, domain.NewRoleBinding("alice", domain.RoleAdmin, domain.DefaultOrgID, nil)); err != nil {
+		t.Fatalf("create role binding: %v", err)
+	}
+	if err := bindings.Create(ctx, domain.NewRoleBinding("alice", domain.RoleAdmin, acme.ID, nil)); err != nil {
+		t.Errorf("Create(same global binding in another org) error = %v", err)
+	}
+	if got, err := bindings.ListBySubject(acmeCtx, "alice"); err != nil || len(got) != 1 || got[0].OrgID != acme.ID {
+		t.Errorf("ListBySubject(acme) = %+v, %v; want acme's binding", got, err)
+	}
+
+	windows := NewFreezeWindowRepo(pool)
+	start, end := time.Now().UTC(), time.Now().UTC().Add(time.Hour)
+	w := domain.NewFreezeWindow("launch", "", domain.DefaultOrgID, nil, "", domain.FreezeRule{StartsAt: &start, EndsAt: &end})
+	if err := windows.Create(ctx, w); err != nil {
+		t.Fatalf("create freeze window: %v", err)
+	}
+	if got, err := windows.List(acmeCtx); err != nil || len(got) != 0 {
+		t.Errorf("List(freeze windows, acme) = %d, %v; want 0", len(got), err)
+	}
+	if _, err := windows.GetByID(acmeCtx, w.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(freeze window in other org): got %v, want ErrNotFound", err)
+	}
+
+	subs := NewWebhookSubscriptionRepo(pool)
+	sub := domain.NewWebhookSubscription("https://tools.example.com/hook", "s3cret", nil, domain.DefaultOrgID, nil)
+	if err := subs.Create(ctx, sub); err != nil {
+		t.Fatalf("create webhook subscription: %v", err)
+	}
+	if got, err := subs.List(acmeCtx); err != nil || len(got) != 0 {
+		t.Errorf("List(webhook subscriptions, acme) = %d, %v; want 0", len(got), err)
+	}
+
+	schedules := NewScheduleRepo(pool)
+	sched := domain.NewSchedule(mine.ID, domain.JobDiscovery, "@daily", "UTC", "")
+	if err := schedules.Create(ctx, sched); err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+	if got, err := schedules.List(acmeCtx); err != nil || len(got) != 0 {
+		t.Errorf("List(schedules, acme) = %d, %v; want 0", len(got), err)
+	}
+	if got, err := schedules.List(ctx); err != nil || len(got) != 1 {
+		t.Errorf("List(schedules, unscoped) = %d, %v; want 1", len(got), err)
+	}
+}
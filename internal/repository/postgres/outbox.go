@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// OutboxLockKey is the advisory lock key held by the event dispatcher leader.
+const OutboxLockKey int64 = 0x696e66726f62 // "infrob"
+
+// EventOutboxRepo implements repository.EventOutboxRepo with PostgreSQL.
+// Create joins the caller's transaction, so an event is stored if and only
+// if the writes that raised it commit.
+type EventOutboxRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewEventOutboxRepo creates a new PostgreSQL-backed event outbox.
+func NewEventOutboxRepo(pool *pgxpool.Pool) *EventOutboxRepo {
+	return &EventOutboxRepo{pool: pool}
+}
+
+func (r *EventOutboxRepo) Create(ctx context.Context, e domain.Event) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO event_outbox (id, type, application_id, data, occurred_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		e.ID, e.Type, e.ApplicationID, []byte(e.Data), e.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert outbox event: %w", err)
+	}
+	return nil
+}
+
+func (r *EventOutboxRepo) ListPending(ctx context.Context, limit int) ([]domain.Event, error) {
+	query := `SELECT id, type, application_id, data, occurred_at FROM event_outbox
+		 WHERE dispatched_at IS NULL ORDER BY occurred_at`
+	args := []any{}
+	if limit > 0 {
+		query += ` LIMIT $1`
+		args = append(args, limit)
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.Event
+	for rows.Next() {
+		var e domain.Event
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.ApplicationID, &data, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		e.Data = data
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *EventOutboxRepo) MarkDispatched(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE event_outbox SET dispatched_at = $2 WHERE id = $1`, id, at,
+	)
+	if err != nil {
+		return fmt.Errorf("mark outbox event dispatched: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationEventOutbox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	tx := NewTransactor(pool)
+	appRepo := NewApplicationRepo(pool)
+	outbox := NewEventOutboxRepo(pool)
+	ctx := context.Background()
+
+	t.Run("commit stores the write and its event", func(t *testing.T) {
+		app := domain.NewApplication("outbox-committed", "desc", "", "", domain.ProviderAWS)
+		e, _ := domain.NewEvent(domain.EventApplicationRegistered, app.ID, app)
+
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := appRepo.Create(ctx, app); err != nil {
+				return err
+			}
+			return outbox.Create(ctx, e)
+		})
+		if err != nil {
+			t.Fatalf("WithinTx() error = %v", err)
+		}
+
+		if _, err := appRepo.GetByID(ctx, app.ID); err != nil {
+			t.Errorf("committed app: %v", err)
+		}
+		pending, _ := outbox.ListPending(ctx, 10)
+		if len(pending) != 1 || pending[0].ID != e.ID || pending[0].Type != domain.EventApplicationRegistered {
+			t.Fatalf("ListPending() = %+v, want the committed event", pending)
+		}
+
+		if err := outbox.MarkDispatched(ctx, e.ID, time.Now().UTC()); err != nil {
+			t.Fatalf("MarkDispatched() error = %v", err)
+		}
+		if pending, _ := outbox.ListPending(ctx, 10); len(pending) != 0 {
+			t.Errorf("ListPending() after dispatch = %+v, want none", pending)
+		}
+		if err := outbox.MarkDispatched(ctx, uuid.New(), time.Now().UTC()); err != domain.ErrNotFound {
+			t.Errorf("MarkDispatched(unknown): got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("rollback discards the write and its event", func(t *testing.T) {
+		app := domain.NewApplication("outbox-rolled-back", "desc", "", "", domain.ProviderAWS)
+		e, _ := domain.NewEvent(domain.EventApplicationRegistered, app.ID, app)
+		boom := errors.New("boom")
+
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			appRepo.Create(ctx, app)
+			outbox.Create(ctx, e)
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("WithinTx() error = %v, want boom", err)
+		}
+
+		if _, err := appRepo.GetByID(ctx, app.ID); err != domain.ErrNotFound {
+			t.Errorf("rolled-back app: got %v, want ErrNotFound", err)
+		}
+		if pending, _ := outbox.ListPending(ctx, 10); len(pending) != 0 {
+			t.Errorf("ListPending() after rollback = %+v, want none", pending)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 // PlanRepo implements repository.PlanRepo with PostgreSQL.
@@ -36,10 +37,10 @@ func (r *PlanRepo) Create(ctx context.Context, p domain.InfrastructurePlan) erro
 		}
 	}
 
-	_, err = r.pool.Exec(ctx,
-		`INSERT INTO infrastructure_plans (id, application_id, plan_type, from_provider, to_provider, content, resources, estimated_cost, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-		p.ID, p.ApplicationID, p.PlanType, p.FromProvider, p.ToProvider, p.Content, resourcesJSON, costJSON, p.CreatedAt,
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO infrastructure_plans (id, application_id, plan_type, from_provider, to_provider, content, resources, estimated_cost, prompt_version, parent_id, instruction, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		p.ID, p.ApplicationID, p.PlanType, p.FromProvider, p.ToProvider, p.Content, resourcesJSON, costJSON, p.PromptVersion, p.ParentID, p.Instruction, p.CreatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("insert plan: %w", err)
@@ -51,10 +52,10 @@ func (r *PlanRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Infrastruc
 	var p domain.InfrastructurePlan
 	var resourcesJSON []byte
 	var costJSON []byte
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, application_id, plan_type, from_provider, to_provider, content, resources, estimated_cost, created_at
-		 FROM infrastructure_plans WHERE id = $1`, id,
-	).Scan(&p.ID, &p.ApplicationID, &p.PlanType, &p.FromProvider, &p.ToProvider, &p.Content, &resourcesJSON, &costJSON, &p.CreatedAt)
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT id, application_id, plan_type, from_provider, to_provider, content, resources, estimated_cost, prompt_version, parent_id, instruction, created_at
+		 FROM infrastructure_plans WHERE id = $1 AND `+appInOrg(2), id, tenant.OrgID(ctx),
+	).Scan(&p.ID, &p.ApplicationID, &p.PlanType, &p.FromProvider, &p.ToProvider, &p.Content, &resourcesJSON, &costJSON, &p.PromptVersion, &p.ParentID, &p.Instruction, &p.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return p, domain.ErrNotFound
@@ -74,9 +75,9 @@ func (r *PlanRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Infrastruc
 }
 
 func (r *PlanRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.InfrastructurePlan, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT id, application_id, plan_type, from_provider, to_provider, content, resources, estimated_cost, created_at
-		 FROM infrastructure_plans WHERE application_id = $1 ORDER BY created_at DESC`, appID,
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT id, application_id, plan_type, from_provider, to_provider, content, resources, estimated_cost, prompt_version, parent_id, instruction, created_at
+		 FROM infrastructure_plans WHERE application_id = $1 AND `+appInOrg(2)+` ORDER BY created_at DESC`, appID, tenant.OrgID(ctx),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list plans: %w", err)
@@ -88,7 +89,7 @@ func (r *PlanRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]
 		var p domain.InfrastructurePlan
 		var resourcesJSON []byte
 		var costJSON []byte
-		if err := rows.Scan(&p.ID, &p.ApplicationID, &p.PlanType, &p.FromProvider, &p.ToProvider, &p.Content, &resourcesJSON, &costJSON, &p.CreatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.ApplicationID, &p.PlanType, &p.FromProvider, &p.ToProvider, &p.Content, &resourcesJSON, &costJSON, &p.PromptVersion, &p.ParentID, &p.Instruction, &p.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan plan: %w", err)
 		}
 		if err := json.Unmarshal(resourcesJSON, &p.Resources); err != nil {
@@ -29,6 +29,7 @@ func TestIntegrationPlanRepo(t *testing.T) {
 			Breakdown:      map[string]float64{"compute": 100, "database": 50},
 		}
 		plan := domain.NewHostingPlan(app.ID, "# Hosting Plan\nDeploy on AWS ECS with RDS.", nil, cost)
+		plan.PromptVersion = "hosting_plan@1"
 
 		if err := repo.Create(ctx, plan); err != nil {
 			t.Fatalf("Create() error = %v", err)
@@ -44,6 +45,9 @@ func TestIntegrationPlanRepo(t *testing.T) {
 		if got.Content != plan.Content {
 			t.Errorf("Content mismatch")
 		}
+		if got.PromptVersion != "hosting_plan@1" {
+			t.Errorf("PromptVersion = %q, want %q", got.PromptVersion, "hosting_plan@1")
+		}
 		if got.EstimatedCost == nil {
 			t.Fatal("EstimatedCost should not be nil")
 		}
@@ -103,4 +107,26 @@ func TestIntegrationPlanRepo(t *testing.T) {
 			t.Errorf("len = %d, want 0", len(plans))
 		}
 	})
+
+	t.Run("Create and GetByID refined plan", func(t *testing.T) {
+		parent := domain.NewHostingPlan(app.ID, "# Plan", nil, nil)
+		if err := repo.Create(ctx, parent); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		plan := domain.NewRefinedPlan(parent, "cut cost under $200/mo", "# Cheaper plan", nil)
+		if err := repo.Create(ctx, plan); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		got, err := repo.GetByID(ctx, plan.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.ParentID == nil || *got.ParentID != parent.ID {
+			t.Errorf("ParentID = %v, want %s", got.ParentID, parent.ID)
+		}
+		if got.Instruction != "cut cost under $200/mo" {
+			t.Errorf("Instruction = %q", got.Instruction)
+		}
+	})
 }
@@ -8,8 +8,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 // ResourceRepo implements repository.ResourceRepo with PostgreSQL.
@@ -28,10 +30,10 @@ func (r *ResourceRepo) Create(ctx context.Context, res domain.Resource) error {
 		return fmt.Errorf("marshal provider mappings: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx,
-		`INSERT INTO resources (id, application_id, kind, name, spec, provider_mappings, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		res.ID, res.ApplicationID, res.Kind, res.Name, res.Spec, mappingsJSON, res.CreatedAt,
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO resources (id, application_id, kind, name, spec, provider_mappings, prompt_version, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		res.ID, res.ApplicationID, res.Kind, res.Name, res.Spec, mappingsJSON, res.PromptVersion, res.CreatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("insert resource: %w", err)
@@ -40,12 +42,20 @@ func (r *ResourceRepo) Create(ctx context.Context, res domain.Resource) error {
 }
 
 func (r *ResourceRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Resource, error) {
+	return r.get(ctx, id, "")
+}
+
+func (r *ResourceRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (domain.Resource, error) {
+	return r.get(ctx, id, " FOR UPDATE")
+}
+
+func (r *ResourceRepo) get(ctx context.Context, id uuid.UUID, lock string) (domain.Resource, error) {
 	var res domain.Resource
 	var mappingsJSON []byte
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, application_id, kind, name, spec, provider_mappings, created_at
-		 FROM resources WHERE id = $1`, id,
-	).Scan(&res.ID, &res.ApplicationID, &res.Kind, &res.Name, &res.Spec, &mappingsJSON, &res.CreatedAt)
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT id, application_id, kind, name, spec, provider_mappings, prompt_version, created_at
+		 FROM resources WHERE id = $1 AND `+appInOrg(2)+lock, id, tenant.OrgID(ctx),
+	).Scan(&res.ID, &res.ApplicationID, &res.Kind, &res.Name, &res.Spec, &mappingsJSON, &res.PromptVersion, &res.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return res, domain.ErrNotFound
@@ -59,9 +69,9 @@ func (r *ResourceRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Resour
 }
 
 func (r *ResourceRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.Resource, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT id, application_id, kind, name, spec, provider_mappings, created_at
-		 FROM resources WHERE application_id = $1 ORDER BY created_at`, appID,
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT id, application_id, kind, name, spec, provider_mappings, prompt_version, created_at
+		 FROM resources WHERE application_id = $1 AND `+appInOrg(2)+` ORDER BY created_at`, appID, tenant.OrgID(ctx),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list resources: %w", err)
@@ -72,7 +82,7 @@ func (r *ResourceRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID)
 	for rows.Next() {
 		var res domain.Resource
 		var mappingsJSON []byte
-		if err := rows.Scan(&res.ID, &res.ApplicationID, &res.Kind, &res.Name, &res.Spec, &mappingsJSON, &res.CreatedAt); err != nil {
+		if err := rows.Scan(&res.ID, &res.ApplicationID, &res.Kind, &res.Name, &res.Spec, &mappingsJSON, &res.PromptVersion, &res.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan resource: %w", err)
 		}
 		if err := json.Unmarshal(mappingsJSON, &res.ProviderMappings); err != nil {
@@ -89,10 +99,10 @@ func (r *ResourceRepo) Update(ctx context.Context, res domain.Resource) error {
 		return fmt.Errorf("marshal provider mappings: %w", err)
 	}
 
-	result, err := r.pool.Exec(ctx,
-		`UPDATE resources SET kind = $2, name = $3, spec = $4, provider_mappings = $5
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE resources SET kind = $2, name = $3, spec = $4, provider_mappings = $5, prompt_version = $6
 		 WHERE id = $1`,
-		res.ID, res.Kind, res.Name, res.Spec, mappingsJSON,
+		res.ID, res.Kind, res.Name, res.Spec, mappingsJSON, res.PromptVersion,
 	)
 	if err != nil {
 		return fmt.Errorf("update resource: %w", err)
@@ -104,7 +114,7 @@ func (r *ResourceRepo) Update(ctx context.Context, res domain.Resource) error {
 }
 
 func (r *ResourceRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	result, err := r.pool.Exec(ctx, `DELETE FROM resources WHERE id = $1`, id)
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM resources WHERE id = $1 AND `+appInOrg(2), id, tenant.OrgID(ctx))
 	if err != nil {
 		return fmt.Errorf("delete resource: %w", err)
 	}
@@ -113,3 +123,49 @@ func (r *ResourceRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
+
+func (r *ResourceRepo) CreateRevision(ctx context.Context, rev domain.ResourceRevision) error {
+	resourceJSON, err := json.Marshal(rev.Resource)
+	if err != nil {
+		return fmt.Errorf("marshal resource: %w", err)
+	}
+
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO resource_revisions (id, resource_id, application_id, version, parent_id, instruction, resource, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		rev.ID, rev.ResourceID, rev.ApplicationID, rev.Version, rev.ParentID, rev.Instruction, resourceJSON, rev.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrConflict
+		}
+		return fmt.Errorf("insert resource revision: %w", err)
+	}
+	return nil
+}
+
+func (r *ResourceRepo) ListRevisions(ctx context.Context, resourceID uuid.UUID) ([]domain.ResourceRevision, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT id, resource_id, application_id, version, parent_id, instruction, resource, created_at
+		 FROM resource_revisions WHERE resource_id = $1 AND `+appInOrg(2)+` ORDER BY version`, resourceID, tenant.OrgID(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list resource revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []domain.ResourceRevision
+	for rows.Next() {
+		var rev domain.ResourceRevision
+		var resourceJSON []byte
+		if err := rows.Scan(&rev.ID, &rev.ResourceID, &rev.ApplicationID, &rev.Version, &rev.ParentID, &rev.Instruction, &resourceJSON, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan resource revision: %w", err)
+		}
+		if err := json.Unmarshal(resourceJSON, &rev.Resource); err != nil {
+			return nil, fmt.Errorf("unmarshal resource: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -61,6 +62,27 @@ func TestIntegrationResourceRepo(t *testing.T) {
 		}
 	})
 
+	t.Run("GetForUpdate", func(t *testing.T) {
+		res := domain.NewResource(app.ID, domain.ResourceQueue, "jobs", json.RawMessage(`{}`))
+		if err := repo.Create(ctx, res); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		err := NewTransactor(pool).WithinTx(ctx, func(ctx context.Context) error {
+			got, err := repo.GetForUpdate(ctx, res.ID)
+			if err != nil || got.Name != "jobs" {
+				t.Errorf("GetForUpdate() = %+v, %v", got, err)
+			}
+			if _, err := repo.GetForUpdate(ctx, uuid.New()); err != domain.ErrNotFound {
+				t.Errorf("missing: got %v, want ErrNotFound", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithinTx() error = %v", err)
+		}
+		repo.Delete(ctx, res.ID)
+	})
+
 	t.Run("ListByApplicationID", func(t *testing.T) {
 		// Add a second resource
 		res2 := domain.NewResource(app.ID, domain.ResourceCache, "session-cache", json.RawMessage(`{"engine":"redis"}`))
@@ -110,6 +132,46 @@ func TestIntegrationResourceRepo(t *testing.T) {
 		}
 	})
 
+	t.Run("Revisions", func(t *testing.T) {
+		res := domain.NewResource(app.ID, domain.ResourceDatabase, "orders-db", json.RawMessage(`{"size":"medium"}`))
+		if err := repo.Create(ctx, res); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		first := domain.NewResourceRevision(res, nil, "")
+		if err := repo.CreateRevision(ctx, first); err != nil {
+			t.Fatalf("CreateRevision() error = %v", err)
+		}
+		res.Spec = json.RawMessage(`{"size":"serverless"}`)
+		second := domain.NewResourceRevision(res, &first, "use Aurora Serverless instead")
+		if err := repo.CreateRevision(ctx, second); err != nil {
+			t.Fatalf("CreateRevision() error = %v", err)
+		}
+		if err := repo.CreateRevision(ctx, domain.NewResourceRevision(res, &first, "again")); err != domain.ErrConflict {
+			t.Errorf("CreateRevision() of an existing version = %v, want ErrConflict", err)
+		}
+
+		revs, err := repo.ListRevisions(ctx, res.ID)
+		if err != nil {
+			t.Fatalf("ListRevisions() error = %v", err)
+		}
+		if len(revs) != 2 || revs[0].ID != first.ID || revs[1].ID != second.ID {
+			t.Fatalf("ListRevisions() = %+v, want both revisions, oldest first", revs)
+		}
+		if revs[1].ParentID == nil || *revs[1].ParentID != first.ID || revs[1].Instruction != second.Instruction {
+			t.Errorf("second revision = %+v, want it linked to the first with its instruction", revs[1])
+		}
+		if !strings.Contains(string(revs[1].Resource.Spec), "serverless") {
+			t.Errorf("Resource.Spec = %s, want the refined spec", revs[1].Resource.Spec)
+		}
+
+		if err := repo.Delete(ctx, res.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if revs, _ := repo.ListRevisions(ctx, res.ID); len(revs) != 0 {
+			t.Errorf("ListRevisions() after Delete = %d revisions, want none", len(revs))
+		}
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		res := domain.NewResource(app.ID, domain.ResourceQueue, "job-queue", json.RawMessage(`{}`))
 		if err := repo.Create(ctx, res); err != nil {
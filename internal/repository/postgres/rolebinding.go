@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// roleBindingColumns is the column list shared by every role binding
+// SELECT, in the order expected by scanRoleBinding.
+const roleBindingColumns = `id, org_id, subject, role, application_id, created_at`
+
+// RoleBindingRepo implements repository.RoleBindingRepo with PostgreSQL.
+type RoleBindingRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewRoleBindingRepo creates a new PostgreSQL-backed role binding repository.
+func NewRoleBindingRepo(pool *pgxpool.Pool) *RoleBindingRepo {
+	return &RoleBindingRepo{pool: pool}
+}
+
+func scanRoleBinding(row pgx.Row) (domain.RoleBinding, error) {
+	var b domain.RoleBinding
+	err := row.Scan(&b.ID, &b.OrgID, &b.Subject, &b.Role, &b.ApplicationID, &b.CreatedAt)
+	return b, err
+}
+
+func (r *RoleBindingRepo) Create(ctx context.Context, b domain.RoleBinding) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO role_bindings (`+roleBindingColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		b.ID, b.OrgID, b.Subject, b.Role, b.ApplicationID, b.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrConflict
+		}
+		return fmt.Errorf("insert role binding: %w", err)
+	}
+	return nil
+}
+
+func (r *RoleBindingRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.RoleBinding, error) {
+	b, err := scanRoleBinding(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+roleBindingColumns+` FROM role_bindings WHERE id = $1 AND `+inOrg(2), id, tenant.OrgID(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return b, domain.ErrNotFound
+		}
+		return b, fmt.Errorf("get role binding: %w", err)
+	}
+	return b, nil
+}
+
+func (r *RoleBindingRepo) List(ctx context.Context) ([]domain.RoleBinding, error) {
+	return r.list(ctx, `SELECT `+roleBindingColumns+` FROM role_bindings WHERE `+inOrg(1)+` ORDER BY created_at`, tenant.OrgID(ctx))
+}
+
+func (r *RoleBindingRepo) ListBySubject(ctx context.Context, subject string) ([]domain.RoleBinding, error) {
+	return r.list(ctx, `SELECT `+roleBindingColumns+` FROM role_bindings WHERE subject = $1 AND `+inOrg(2)+` ORDER BY created_at`, subject, tenant.OrgID(ctx))
+}
+
+func (r *RoleBindingRepo) list(ctx context.Context, query string, args ...any) ([]domain.RoleBinding, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list role bindings: %w", err)
+	}
+	defer rows.Close()
+
+	var bindings []domain.RoleBinding
+	for rows.Next() {
+		b, err := scanRoleBinding(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan role binding: %w", err)
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, rows.Err()
+}
+
+func (r *RoleBindingRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM role_bindings WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete role binding: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationRoleBindingRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	appRepo := NewApplicationRepo(pool)
+	repo := NewRoleBindingRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("rbac-test-app", "desc", "", "", domain.ProviderAWS)
+	if err := appRepo.Create(ctx, app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	global := domain.NewRoleBinding("alice", domain.RoleViewer, domain.DefaultOrgID, nil)
+	scoped := domain.NewRoleBinding("alice", domain.RoleDeployer, domain.DefaultOrgID, &app.ID)
+	for _, b := range []domain.RoleBinding{global, scoped} {
+		if err := repo.Create(ctx, b); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := repo.Create(ctx, domain.NewRoleBinding("alice", domain.RoleViewer, domain.DefaultOrgID, nil)); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate global): got %v, want ErrConflict", err)
+	}
+	if err := repo.Create(ctx, domain.NewRoleBinding("alice", domain.RoleDeployer, domain.DefaultOrgID, &app.ID)); err != domain.ErrConflict {
+		t.Errorf("Create(duplicate scoped): got %v, want ErrConflict", err)
+	}
+
+	got, err := repo.GetByID(ctx, scoped.ID)
+	if err != nil || got.ApplicationID == nil || *got.ApplicationID != app.ID || got.Role != domain.RoleDeployer {
+		t.Errorf("GetByID() = %+v, %v", got, err)
+	}
+	if bindings, err := repo.ListBySubject(ctx, "alice"); err != nil || len(bindings) != 2 || bindings[0].ID != global.ID {
+		t.Errorf("ListBySubject() = %+v, %v", bindings, err)
+	}
+
+	// Deleting the application removes its bindings.
+	if err := appRepo.Delete(ctx, app.ID); err != nil {
+		t.Fatalf("delete app: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, scoped.ID); err != domain.ErrNotFound {
+		t.Errorf("GetByID(after app delete): got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, global.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, global.ID); err != domain.ErrNotFound {
+		t.Errorf("Delete(deleted): got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// scheduleColumns is the column list shared by every schedule SELECT, in the
+// order expected by scanSchedule.
+const scheduleColumns = `id, application_id, job, cron_expr, timezone, git_branch, enabled,
+	next_run_at, last_run_at, last_status, last_result, created_at, updated_at`
+
+// ScheduleRepo implements repository.ScheduleRepo with PostgreSQL.
+type ScheduleRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewScheduleRepo creates a new PostgreSQL-backed schedule repository.
+func NewScheduleRepo(pool *pgxpool.Pool) *ScheduleRepo {
+	return &ScheduleRepo{pool: pool}
+}
+
+func scanSchedule(row pgx.Row) (domain.Schedule, error) {
+	var s domain.Schedule
+	var lastResult []byte
+	err := row.Scan(&s.ID, &s.ApplicationID, &s.Job, &s.CronExpr, &s.Timezone, &s.GitBranch, &s.Enabled,
+		&s.NextRunAt, &s.LastRunAt, &s.LastStatus, &lastResult, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return s, err
+	}
+	if len(lastResult) > 0 {
+		s.LastResult = lastResult
+	}
+	return s, nil
+}
+
+// nullableJSON stores an empty raw message as SQL NULL.
+func nullableJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
+}
+
+func (r *ScheduleRepo) Create(ctx context.Context, s domain.Schedule) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO schedules (`+scheduleColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		s.ID, s.ApplicationID, s.Job, s.CronExpr, s.Timezone, s.GitBranch, s.Enabled,
+		s.NextRunAt, s.LastRunAt, s.LastStatus, nullableJSON(s.LastResult), s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert schedule: %w", err)
+	}
+	return nil
+}
+
+func (r *ScheduleRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.Schedule, error) {
+	s, err := scanSchedule(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+scheduleColumns+` FROM schedules WHERE id = $1 AND `+appInOrg(2), id, tenant.OrgID(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s, domain.ErrNotFound
+		}
+		return s, fmt.Errorf("get schedule by id: %w", err)
+	}
+	return s, nil
+}
+
+func (r *ScheduleRepo) List(ctx context.Context) ([]domain.Schedule, error) {
+	return r.list(ctx, `SELECT `+scheduleColumns+` FROM schedules WHERE `+appInOrg(1)+` ORDER BY created_at`, tenant.OrgID(ctx))
+}
+
+func (r *ScheduleRepo) ListByApplicationID(ctx context.Context, appID uuid.UUID) ([]domain.Schedule, error) {
+	return r.list(ctx, `SELECT `+scheduleColumns+` FROM schedules WHERE application_id = $1 ORDER BY created_at`, appID)
+}
+
+func (r *ScheduleRepo) list(ctx context.Context, query string, args ...any) ([]domain.Schedule, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []domain.Schedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan schedule: %w", err)
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *ScheduleRepo) Update(ctx context.Context, s domain.Schedule) error {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE schedules
+		 SET cron_expr = $2, timezone = $3, git_branch = $4, enabled = $5, next_run_at = $6,
+		     last_run_at = $7, last_status = $8, last_result = $9, updated_at = $10
+		 WHERE id = $1`,
+		s.ID, s.CronExpr, s.Timezone, s.GitBranch, s.Enabled, s.NextRunAt,
+		s.LastRunAt, s.LastStatus, nullableJSON(s.LastResult), s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("update schedule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ScheduleRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete schedule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
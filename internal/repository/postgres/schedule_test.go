@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationScheduleRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	appRepo := NewApplicationRepo(pool)
+	repo := NewScheduleRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("schedule-test-app", "desc", "", "", domain.ProviderAWS)
+	if err := appRepo.Create(ctx, app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	s := domain.NewSchedule(app.ID, domain.JobDeploy, "0 2 * * *", "Europe/Berlin", "main")
+	if err := s.ScheduleNext(time.Now()); err != nil {
+		t.Fatalf("ScheduleNext() error = %v", err)
+	}
+
+	t.Run("Create and GetByID", func(t *testing.T) {
+		if err := repo.Create(ctx, s); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		got, err := repo.GetByID(ctx, s.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Job != domain.JobDeploy || got.Timezone != "Europe/Berlin" || got.GitBranch != "main" {
+			t.Errorf("got %+v", got)
+		}
+		if got.NextRunAt == nil || got.LastRunAt != nil || got.LastResult != nil {
+			t.Errorf("run fields = next %v, last %v, result %s", got.NextRunAt, got.LastRunAt, got.LastResult)
+		}
+	})
+
+	t.Run("Update records last run", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		s.LastRunAt = &now
+		s.LastStatus = domain.ScheduleRunSucceeded
+		s.LastResult = json.RawMessage(`{"deployment_id":"x"}`)
+		if err := repo.Update(ctx, s); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		got, _ := repo.GetByID(ctx, s.ID)
+		if got.LastRunAt == nil || !got.LastRunAt.Equal(now) || got.LastStatus != domain.ScheduleRunSucceeded {
+			t.Errorf("LastRunAt = %v, LastStatus = %q", got.LastRunAt, got.LastStatus)
+		}
+		if string(got.LastResult) != `{"deployment_id": "x"}` && string(got.LastResult) != `{"deployment_id":"x"}` {
+			t.Errorf("LastResult = %s", got.LastResult)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		all, err := repo.List(ctx)
+		if err != nil || len(all) != 1 {
+			t.Errorf("List() = %d schedules, err %v; want 1", len(all), err)
+		}
+		byApp, err := repo.ListByApplicationID(ctx, app.ID)
+		if err != nil || len(byApp) != 1 {
+			t.Errorf("ListByApplicationID() = %d schedules, err %v; want 1", len(byApp), err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, s.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := repo.GetByID(ctx, s.ID); err != domain.ErrNotFound {
+			t.Errorf("GetByID after delete: got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, uuid.New()); err != domain.ErrNotFound {
+			t.Errorf("Delete(unknown): got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestIntegrationAdvisoryLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	a := NewAdvisoryLock(pool, SchedulerLockKey)
+	b := NewAdvisoryLock(pool, SchedulerLockKey)
+
+	if ok, err := a.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("a.TryAcquire() = %v, %v; want true", ok, err)
+	}
+	if ok, err := a.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("a.TryAcquire() again = %v, %v; want true (still leader)", ok, err)
+	}
+	if ok, err := b.TryAcquire(ctx); err != nil || ok {
+		t.Fatalf("b.TryAcquire() = %v, %v; want false while a leads", ok, err)
+	}
+
+	if err := a.Release(ctx); err != nil {
+		t.Fatalf("a.Release() error = %v", err)
+	}
+	if ok, err := b.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("b.TryAcquire() after release = %v, %v; want true", ok, err)
+	}
+	b.Release(ctx)
+}
@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// WebhookRetryLockKey is the advisory lock key held by the replica that
+// retries due webhook deliveries.
+const WebhookRetryLockKey int64 = 0x696e66727768 // "infrwh"
+
+// webhookSubscriptionColumns is the column list shared by every webhook
+// subscription SELECT, in the order expected by scanWebhookSubscription.
+const webhookSubscriptionColumns = `id, org_id, url, secret, events, application_id, enabled, created_at`
+
+// WebhookSubscriptionRepo implements repository.WebhookSubscriptionRepo with PostgreSQL.
+type WebhookSubscriptionRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookSubscriptionRepo creates a new PostgreSQL-backed webhook subscription repository.
+func NewWebhookSubscriptionRepo(pool *pgxpool.Pool) *WebhookSubscriptionRepo {
+	return &WebhookSubscriptionRepo{pool: pool}
+}
+
+func scanWebhookSubscription(row pgx.Row) (domain.WebhookSubscription, error) {
+	var s domain.WebhookSubscription
+	var eventsJSON []byte
+	if err := row.Scan(&s.ID, &s.OrgID, &s.URL, &s.Secret, &eventsJSON, &s.ApplicationID, &s.Enabled, &s.CreatedAt); err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(eventsJSON, &s.Events); err != nil {
+		return s, fmt.Errorf("unmarshal events: %w", err)
+	}
+	return s, nil
+}
+
+func (r *WebhookSubscriptionRepo) Create(ctx context.Context, s domain.WebhookSubscription) error {
+	events := s.Events
+	if events == nil {
+		events = []domain.EventType{}
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("marshal events: %w", err)
+	}
+
+	_, err = conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO webhook_subscriptions (`+webhookSubscriptionColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		s.ID, s.OrgID, s.URL, s.Secret, eventsJSON, s.ApplicationID, s.Enabled, s.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookSubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	s, err := scanWebhookSubscription(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE id = $1 AND `+inOrg(2), id, tenant.OrgID(ctx),
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s, domain.ErrNotFound
+		}
+		return s, fmt.Errorf("get webhook subscription by id: %w", err)
+	}
+	return s, nil
+}
+
+func (r *WebhookSubscriptionRepo) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE `+inOrg(1)+` ORDER BY created_at`, tenant.OrgID(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []domain.WebhookSubscription
+	for rows.Next() {
+		s, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook subscription: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *WebhookSubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// webhookDeliveryColumns is the column list shared by every webhook delivery
+// SELECT, in the order expected by scanWebhookDelivery.
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	last_error, response_code, next_attempt_at, created_at, updated_at`
+
+// WebhookDeliveryRepo implements repository.WebhookDeliveryRepo with PostgreSQL.
+type WebhookDeliveryRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookDeliveryRepo creates a new PostgreSQL-backed webhook delivery repository.
+func NewWebhookDeliveryRepo(pool *pgxpool.Pool) *WebhookDeliveryRepo {
+	return &WebhookDeliveryRepo{pool: pool}
+}
+
+func scanWebhookDelivery(row pgx.Row) (domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.LastError, &d.ResponseCode, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt)
+	d.Payload = payload
+	return d, err
+}
+
+func (r *WebhookDeliveryRepo) Create(ctx context.Context, d domain.WebhookDelivery) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		`INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		d.ID, d.SubscriptionID, d.EventID, d.EventType, []byte(d.Payload), d.Status, d.Attempts,
+		d.LastError, d.ResponseCode, d.NextAttemptAt, d.CreatedAt, d.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) GetByID(ctx context.Context, id uuid.UUID) (domain.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(conn(ctx, r.pool).QueryRow(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return d, domain.ErrNotFound
+		}
+		return d, fmt.Errorf("get webhook delivery by id: %w", err)
+	}
+	return d, nil
+}
+
+func (r *WebhookDeliveryRepo) Update(ctx context.Context, d domain.WebhookDelivery) error {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $2, attempts = $3, last_error = $4, response_code = $5, next_attempt_at = $6, updated_at = $7
+		 WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.LastError, d.ResponseCode, d.NextAttemptAt, d.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) ListBySubscriptionID(ctx context.Context, subID uuid.UUID, status domain.WebhookDeliveryStatus) ([]domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = $1`
+	args := []any{subID}
+	if status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC`
+
+	return r.list(ctx, query, args...)
+}
+
+func (r *WebhookDeliveryRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		 WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at`
+	args := []any{now}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+
+	return r.list(ctx, query, args...)
+}
+
+func (r *WebhookDeliveryRepo) list(ctx context.Context, query string, args ...any) ([]domain.WebhookDelivery, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+func TestIntegrationWebhookRepos(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	pool := setupTestDB(t)
+	appRepo := NewApplicationRepo(pool)
+	subs := NewWebhookSubscriptionRepo(pool)
+	deliveries := NewWebhookDeliveryRepo(pool)
+	ctx := context.Background()
+
+	app := domain.NewApplication("webhook-test-app", "desc", "", "", domain.ProviderAWS)
+	if err := appRepo.Create(ctx, app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	global := domain.NewWebhookSubscription("https://tools.example.com/hook", "s3cret", nil, domain.DefaultOrgID, nil)
+	scoped := domain.NewWebhookSubscription("https://tools.example.com/plans", "s3cret",
+		[]domain.EventType{domain.EventPlanGenerated}, domain.DefaultOrgID, &app.ID)
+
+	t.Run("subscriptions", func(t *testing.T) {
+		for _, s := range []domain.WebhookSubscription{global, scoped} {
+			if err := subs.Create(ctx, s); err != nil {
+				t.Fatalf("Create(%s) error = %v", s.URL, err)
+			}
+		}
+
+		got, err := subs.GetByID(ctx, scoped.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.ApplicationID == nil || *got.ApplicationID != app.ID || len(got.Events) != 1 || got.Secret != "s3cret" {
+			t.Errorf("scoped subscription = %+v", got)
+		}
+		if g, _ := subs.GetByID(ctx, global.ID); g.ApplicationID != nil {
+			t.Errorf("global subscription application_id = %v, want nil", g.ApplicationID)
+		}
+
+		list, _ := subs.List(ctx)
+		if len(list) != 2 {
+			t.Errorf("List() len = %d, want 2", len(list))
+		}
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		payload := json.RawMessage(`{"type":"plan.generated"}`)
+		d := domain.WebhookDelivery{
+			ID: uuid.New(), SubscriptionID: scoped.ID, EventID: uuid.New(), EventType: domain.EventPlanGenerated,
+			Payload: payload, Status: domain.WebhookPending, NextAttemptAt: &now, CreatedAt: now, UpdatedAt: now,
+		}
+		if err := deliveries.Create(ctx, d); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+
+		due, err := deliveries.ListDue(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("ListDue() error = %v", err)
+		}
+		if len(due) != 1 || due[0].ID != d.ID {
+			t.Errorf("ListDue() = %+v, want the pending delivery", due)
+		}
+
+		d.Status, d.Attempts, d.LastError, d.ResponseCode, d.NextAttemptAt = domain.WebhookDead, 8, "503 Service Unavailable", 503, nil
+		if err := deliveries.Update(ctx, d); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		got, _ := deliveries.GetByID(ctx, d.ID)
+		if got.Status != domain.WebhookDead || got.ResponseCode != 503 || got.NextAttemptAt != nil {
+			t.Errorf("updated delivery = %+v", got)
+		}
+
+		if dead, _ := deliveries.ListBySubscriptionID(ctx, scoped.ID, domain.WebhookDead); len(dead) != 1 {
+			t.Errorf("ListBySubscriptionID(dead) len = %d, want 1", len(dead))
+		}
+		if due, _ := deliveries.ListDue(ctx, now, 10); len(due) != 0 {
+			t.Errorf("ListDue() after dead-lettering = %+v, want none", due)
+		}
+
+		// Deleting the subscription removes its deliveries.
+		if err := subs.Delete(ctx, scoped.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := deliveries.GetByID(ctx, d.ID); err != domain.ErrNotFound {
+			t.Errorf("GetByID(cascaded): got %v, want ErrNotFound", err)
+		}
+	})
+}
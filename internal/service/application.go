@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,7 @@ import (
 	"github.com/matthewdriscoll/infraplane/internal/domain"
 	"github.com/matthewdriscoll/infraplane/internal/llm"
 	"github.com/matthewdriscoll/infraplane/internal/repository"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
 )
 
 // ApplicationService handles application lifecycle operations.
@@ -20,6 +22,9 @@ type ApplicationService struct {
 	resources  repository.ResourceRepo
 	llm        llm.Client
 	compliance *compliance.Registry
+	outbox     *Outbox       // optional
+	rbac       *RBACService  // optional
+	audit      *AuditService // optional
 }
 
 // NewApplicationService creates a new ApplicationService.
@@ -34,6 +39,18 @@ func NewApplicationService(apps repository.ApplicationRepo, resources repository
 	}
 }
 
+// SetOutbox makes the service record application and resource events in o.
+// A nil outbox disables events.
+func (s *ApplicationService) SetOutbox(o *Outbox) { s.outbox = o }
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *ApplicationService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *ApplicationService) SetAudit(a *AuditService) { s.audit = a }
+
 // RegisterOpts holds optional parameters for application registration.
 type RegisterOpts struct {
 	// UploadedFiles contains file contents uploaded from a browser (when
@@ -47,6 +64,10 @@ type RegisterOpts struct {
 // analyzed instead of reading from the filesystem.
 // complianceFrameworks is an optional list of framework IDs (e.g. "cis_gcp_v4").
 func (s *ApplicationService) Register(ctx context.Context, name, description, gitRepoURL, sourcePath string, provider domain.CloudProvider, complianceFrameworks []string, opts *RegisterOpts) (domain.Application, error) {
+	if err := s.rbac.require(ctx, domain.RoleEditor, nil); err != nil {
+		return domain.Application{}, err
+	}
+
 	// Validate compliance frameworks if provided
 	if len(complianceFrameworks) > 0 && s.compliance != nil {
 		if err := s.compliance.ValidateFrameworks(complianceFrameworks); err != nil {
@@ -56,12 +77,24 @@ func (s *ApplicationService) Register(ctx context.Context, name, description, gi
 
 	app := domain.NewApplication(name, description, gitRepoURL, sourcePath, provider)
 	app.ComplianceFrameworks = complianceFrameworks
+	if org, ok := tenant.OrgFrom(ctx); ok {
+		app.OrgID = org.ID
+	}
 	if err := app.Validate(); err != nil {
 		return domain.Application{}, err
 	}
 
-	if err := s.apps.Create(ctx, app); err != nil {
-		return domain.Application{}, fmt.Errorf("register application: %w", err)
+	err := s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.apps.Create(ctx, app); err != nil {
+			return fmt.Errorf("register application: %w", err)
+		}
+		if err := s.audit.record(ctx, "application.register", "application", app.ID.String(), &app.ID, nil, app); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventApplicationRegistered, app.ID, app)
+	})
+	if err != nil {
+		return domain.Application{}, err
 	}
 
 	// Auto-detect resources from source if configured
@@ -94,21 +127,35 @@ func (s *ApplicationService) autoDetectResources(ctx context.Context, app domain
 		return nil // Nothing to analyze
 	}
 
-	recommendations, err := s.llm.AnalyzeCodebase(ctx, codeCtx, app.Provider)
+	recommendations, err := s.llm.AnalyzeCodebase(llm.ForApplication(ctx, app.ID), codeCtx, app.Provider)
 	if err != nil {
 		return fmt.Errorf("LLM codebase analysis: %w", err)
 	}
 
+	// Re-analysis only adds resources the application doesn't have yet.
+	existing, err := s.resources.ListByApplicationID(ctx, app.ID)
+	if err != nil {
+		return fmt.Errorf("list resources: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		known[r.Name] = true
+	}
+
 	for _, rec := range recommendations {
+		if known[rec.Name] {
+			continue
+		}
 		resource := domain.NewResource(app.ID, rec.Kind, rec.Name, rec.Spec)
 		resource.ProviderMappings = rec.Mappings
+		resource.PromptVersion = llm.PromptVersion(llm.PromptCodebaseAnalysis)
 
 		if err := resource.Validate(); err != nil {
 			log.Printf("skip invalid resource %s: %v", rec.Name, err)
 			continue
 		}
 
-		if err := s.resources.Create(ctx, resource); err != nil {
+		if err := s.createResource(ctx, resource); err != nil {
 			log.Printf("create resource %s: %v", rec.Name, err)
 			continue
 		}
@@ -117,8 +164,25 @@ func (s *ApplicationService) autoDetectResources(ctx context.Context, app domain
 	return nil
 }
 
-// ReanalyzeSource re-runs code analysis on an existing application's source.
+// createResource saves a detected resource and records its event.
+func (s *ApplicationService) createResource(ctx context.Context, resource domain.Resource) error {
+	return s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.resources.Create(ctx, resource); err != nil {
+			return err
+		}
+		if err := s.audit.record(ctx, "resource.add", "resource", resource.ID.String(), &resource.ApplicationID, nil, resource); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventResourceAdded, resource.ApplicationID, resource)
+	})
+}
+
+// ReanalyzeSource re-runs code analysis on an existing application's source
+// and adds any detected resources the application doesn't already have.
 func (s *ApplicationService) ReanalyzeSource(ctx context.Context, appID uuid.UUID) error {
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, appID); err != nil {
+		return err
+	}
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
 		return err
@@ -139,6 +203,9 @@ func (s *ApplicationService) ReanalyzeSource(ctx context.Context, appID uuid.UUI
 // browsers can't expose filesystem paths). It runs LLM analysis on the provided
 // CodeContext and creates resources for the application.
 func (s *ApplicationService) AnalyzeUploadedFiles(ctx context.Context, appID uuid.UUID, codeCtx analyzer.CodeContext) error {
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, appID); err != nil {
+		return err
+	}
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
 		return err
@@ -152,7 +219,7 @@ func (s *ApplicationService) AnalyzeUploadedFiles(ctx context.Context, appID uui
 		return nil
 	}
 
-	recommendations, err := s.llm.AnalyzeCodebase(ctx, codeCtx, app.Provider)
+	recommendations, err := s.llm.AnalyzeCodebase(llm.ForApplication(ctx, app.ID), codeCtx, app.Provider)
 	if err != nil {
 		return fmt.Errorf("LLM codebase analysis: %w", err)
 	}
@@ -160,13 +227,14 @@ func (s *ApplicationService) AnalyzeUploadedFiles(ctx context.Context, appID uui
 	for _, rec := range recommendations {
 		resource := domain.NewResource(app.ID, rec.Kind, rec.Name, rec.Spec)
 		resource.ProviderMappings = rec.Mappings
+		resource.PromptVersion = llm.PromptVersion(llm.PromptCodebaseAnalysis)
 
 		if err := resource.Validate(); err != nil {
 			log.Printf("skip invalid resource %s: %v", rec.Name, err)
 			continue
 		}
 
-		if err := s.resources.Create(ctx, resource); err != nil {
+		if err := s.createResource(ctx, resource); err != nil {
 			log.Printf("create resource %s: %v", rec.Name, err)
 			continue
 		}
@@ -177,42 +245,135 @@ func (s *ApplicationService) AnalyzeUploadedFiles(ctx context.Context, appID uui
 
 // Get returns an application by ID.
 func (s *ApplicationService) Get(ctx context.Context, id uuid.UUID) (domain.Application, error) {
-	return s.apps.GetByID(ctx, id)
+	app, err := s.apps.GetByID(ctx, id)
+	if err != nil {
+		return domain.Application{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, app.ID); err != nil {
+		return domain.Application{}, err
+	}
+	return app, nil
 }
 
 // GetByName returns an application by name.
 func (s *ApplicationService) GetByName(ctx context.Context, name string) (domain.Application, error) {
-	return s.apps.GetByName(ctx, name)
+	app, err := s.apps.GetByName(ctx, name)
+	if err != nil {
+		return domain.Application{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, app.ID); err != nil {
+		return domain.Application{}, err
+	}
+	return app, nil
 }
 
-// List returns all applications.
+// List returns the applications the caller may view.
 func (s *ApplicationService) List(ctx context.Context) ([]domain.Application, error) {
-	return s.apps.List(ctx)
+	apps, err := s.apps.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.rbac.visible(ctx, apps)
 }
 
-// UpdateStatus changes the application status.
+// UpdateStatus changes the application status. Only transitions allowed by
+// the application lifecycle are accepted; others return a ValidationError.
 func (s *ApplicationService) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.AppStatus) (domain.Application, error) {
 	app, err := s.apps.GetByID(ctx, id)
 	if err != nil {
 		return domain.Application{}, err
 	}
-	app.Status = status
-	app.UpdatedAt = time.Now().UTC()
-	if err := s.apps.Update(ctx, app); err != nil {
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, app.ID); err != nil {
+		return domain.Application{}, err
+	}
+	before := app
+	if err := app.TransitionTo(status, time.Now().UTC()); err != nil {
+		return domain.Application{}, err
+	}
+	if err := s.update(ctx, "application.update_status", before, app); err != nil {
 		return domain.Application{}, fmt.Errorf("update application status: %w", err)
 	}
 	return app, nil
 }
 
+// SetPreventDestroy enables or disables destroy protection for an application.
+// While enabled, destroy deployments for the application are refused.
+func (s *ApplicationService) SetPreventDestroy(ctx context.Context, id uuid.UUID, preventDestroy bool) (domain.Application, error) {
+	app, err := s.apps.GetByID(ctx, id)
+	if err != nil {
+		return domain.Application{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleAdmin, app.ID); err != nil {
+		return domain.Application{}, err
+	}
+	before := app
+	app.PreventDestroy = preventDestroy
+	app.UpdatedAt = time.Now().UTC()
+	if err := s.update(ctx, "application.set_prevent_destroy", before, app); err != nil {
+		return domain.Application{}, fmt.Errorf("update application protection: %w", err)
+	}
+	return app, nil
+}
+
+// SetDeployBranch sets the branch whose pushes trigger deployments for the
+// application (see GitPushService). An empty branch disables git-triggered
+// deployments.
+func (s *ApplicationService) SetDeployBranch(ctx context.Context, id uuid.UUID, branch string) (domain.Application, error) {
+	app, err := s.apps.GetByID(ctx, id)
+	if err != nil {
+		return domain.Application{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleAdmin, app.ID); err != nil {
+		return domain.Application{}, err
+	}
+	branch = strings.TrimSpace(branch)
+	if branch != "" && app.GitRepoURL == "" {
+		return domain.Application{}, domain.ErrValidation("application has no git repository URL configured")
+	}
+	before := app
+	app.DeployBranch = branch
+	app.UpdatedAt = time.Now().UTC()
+	if err := s.update(ctx, "application.set_deploy_branch", before, app); err != nil {
+		return domain.Application{}, fmt.Errorf("update application deploy branch: %w", err)
+	}
+	return app, nil
+}
+
 // Delete removes an application.
 func (s *ApplicationService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.apps.Delete(ctx, id)
+	app, err := s.apps.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleAdmin, app.ID); err != nil {
+		return err
+	}
+	return s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.apps.Delete(ctx, id); err != nil {
+			return err
+		}
+		if err := s.audit.record(ctx, "application.delete", "application", app.ID.String(), &app.ID, app, nil); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventApplicationDeleted, app.ID, app)
+	})
+}
+
+// update saves app, recording action in the audit log with the application
+// as it was before.
+func (s *ApplicationService) update(ctx context.Context, action string, before, app domain.Application) error {
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.apps.Update(ctx, app); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, action, "application", app.ID.String(), &app.ID, before, app)
+	})
 }
 
 // OnboardResult holds the full onboarding result: app, detected resources, and hosting plan.
 type OnboardResult struct {
-	Application domain.Application       `json:"application"`
-	Resources   []domain.Resource        `json:"resources"`
+	Application domain.Application        `json:"application"`
+	Resources   []domain.Resource         `json:"resources"`
 	Plan        domain.InfrastructurePlan `json:"plan"`
 }
 
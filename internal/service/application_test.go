@@ -150,6 +150,24 @@ func TestApplicationService_UpdateStatus(t *testing.T) {
 			t.Errorf("got %v, want ErrNotFound", err)
 		}
 	})
+
+	t.Run("illegal transition", func(t *testing.T) {
+		_, err := svc.UpdateStatus(ctx, app.ID, domain.AppStatusDestroying)
+		if err != nil {
+			t.Fatalf("provisioned -> destroying should be allowed: %v", err)
+		}
+		_, err = svc.UpdateStatus(ctx, app.ID, domain.AppStatusDeployed)
+		if !domain.IsValidationError(err) {
+			t.Errorf("destroying -> deployed: got %v, want validation error", err)
+		}
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		_, err := svc.UpdateStatus(ctx, app.ID, domain.AppStatus("archived"))
+		if !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
 }
 
 func TestApplicationService_Delete(t *testing.T) {
@@ -413,3 +431,64 @@ func TestApplicationService_Onboard(t *testing.T) {
 		}
 	})
 }
+
+// fixtureLLM returns a client answering from the recorded fixtures in
+// testdata/llm. With RECORD_LLM_FIXTURES=1 it asks the backend configured
+// as for the server (LLM_BACKEND, LLM_MODEL, LLM_BASE_URL and the API key
+// variables) instead, recording its answers there.
+func fixtureLLM(t *testing.T) llm.Client {
+	t.Helper()
+	cfg := llm.Config{Backend: llm.BackendReplay, FixturesDir: filepath.Join("testdata", "llm")}
+	if os.Getenv("RECORD_LLM_FIXTURES") != "" {
+		cfg.Backend = llm.Backend(os.Getenv("LLM_BACKEND"))
+		cfg.Model = os.Getenv("LLM_MODEL")
+		cfg.BaseURL = os.Getenv("LLM_BASE_URL")
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		if cfg.Backend == "" || cfg.Backend == llm.BackendAnthropic {
+			cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+		}
+	}
+	c, err := llm.New(cfg)
+	if err != nil {
+		t.Fatalf("llm.New() error = %v", err)
+	}
+	return c
+}
+
+func TestApplicationService_OnboardReplay(t *testing.T) {
+	ctx := context.Background()
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	llmClient := fixtureLLM(t)
+	appSvc := NewApplicationService(appRepo, resRepo, llmClient, nil)
+	planSvc := NewPlannerService(mock.NewPlanRepo(), appRepo, resRepo, llmClient, nil)
+
+	result, err := appSvc.Onboard(ctx, "bookshop", "Online bookshop with a Go API", "", domain.ProviderAWS, nil, &RegisterOpts{
+		UploadedFiles: &analyzer.CodeContext{
+			Files: []analyzer.FileContent{
+				{Path: "docker-compose.yml", Content: "services:\n  api:\n    build: .\n    ports: [\"8080:8080\"]\n  db:\n    image: postgres:16\n  cache:\n    image: redis:7\n"},
+				{Path: "go.mod", Content: "module bookshop\n\ngo 1.25\n\nrequire (\n\tgithub.com/jackc/pgx/v5 v5.7.1\n\tgithub.com/redis/go-redis/v9 v9.7.0\n)\n"},
+			},
+			Summary: "Uploaded from onboarding wizard",
+		},
+	}, planSvc)
+	if err != nil {
+		t.Fatalf("Onboard() error = %v", err)
+	}
+
+	kinds := make(map[domain.ResourceKind]bool)
+	for _, r := range result.Resources {
+		kinds[r.Kind] = true
+		if _, ok := r.ProviderMappings[domain.ProviderAWS]; !ok {
+			t.Errorf("resource %s has no AWS mapping", r.Name)
+		}
+	}
+	for _, kind := range []domain.ResourceKind{domain.ResourceCompute, domain.ResourceDatabase, domain.ResourceCache} {
+		if !kinds[kind] {
+			t.Errorf("Onboard() resources = %+v, want a %s", result.Resources, kind)
+		}
+	}
+	if result.Plan.ID == uuid.Nil || result.Plan.Content == "" || result.Plan.EstimatedCost == nil {
+		t.Errorf("Onboard() plan = %+v, want the recorded hosting plan with its cost", result.Plan)
+	}
+}
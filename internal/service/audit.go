@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/audit"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// AuditService writes the append-only audit log of mutating operations and
+// answers queries over it. Services record an entry for every change they
+// make, inside the same transaction as the change, with the caller's
+// principal and the request's source and ID taken from the context.
+type AuditService struct {
+	entries repository.AuditLogRepo
+	tx      repository.Transactor
+	apps    repository.ApplicationRepo
+	rbac    *RBACService // optional
+}
+
+// NewAuditService creates a new AuditService that writes entries to entries
+// within tx's transactions. apps resolves the organization of changes made
+// outside a request scoped to one.
+func NewAuditService(entries repository.AuditLogRepo, tx repository.Transactor, apps repository.ApplicationRepo) *AuditService {
+	return &AuditService{entries: entries, tx: tx, apps: apps}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *AuditService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// List returns the entries matching filter, newest first. Reading the audit
+// log of an application needs the admin role on it; reading every entry
+// needs the global admin role.
+func (s *AuditService) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	if err := s.rbac.require(ctx, domain.RoleAdmin, filter.ApplicationID); err != nil {
+		return nil, err
+	}
+	entries, err := s.entries.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// atomically runs fn in a transaction, so the writes it makes and the audit
+// entries it records commit together. Writes that also raise events record
+// their entries inside Outbox.atomically instead, which shares the
+// transaction. Services hold an optional audit service; on a nil service fn
+// simply runs.
+func (s *AuditService) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTx(ctx, fn)
+}
+
+// record adds an entry for action on the target targetType/targetID, which
+// belongs to the application appID (nil for none), with its state before
+// and after the change. A nil service records nothing.
+func (s *AuditService) record(ctx context.Context, action, targetType, targetID string, appID *uuid.UUID, before, after any) error {
+	if s == nil {
+		return nil
+	}
+	e, err := domain.NewAuditEntry(action, targetType, targetID, appID, before, after)
+	if err != nil {
+		return err
+	}
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		e.Actor, e.ActorName = p.Subject, p.Name
+		if e.Actor == "" {
+			e.Actor = string(p.Kind) // anonymous local development
+		}
+	}
+	e.Source = audit.SourceFrom(ctx)
+	e.RequestID = audit.RequestIDFrom(ctx)
+	e.OrgID = tenant.OrgID(ctx)
+	if e.OrgID == nil && appID != nil {
+		if app, err := s.apps.GetByID(ctx, *appID); err == nil {
+			e.OrgID = &app.OrgID
+		}
+	}
+
+	if err := s.entries.Create(ctx, e); err != nil {
+		return fmt.Errorf("record %s audit entry: %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/audit"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+)
+
+func TestAuditService(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	auditSvc.SetRBAC(rbac)
+	rbac.SetAudit(auditSvc)
+	resSvc := NewResourceService(mock.NewResourceRepo(), appRepo, &llm.MockClient{}, nil)
+	resSvc.SetRBAC(rbac)
+	resSvc.SetAudit(auditSvc)
+
+	ctx := context.Background()
+	app := domain.NewApplication("audited", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	alice := domain.Principal{Kind: domain.PrincipalUser, Subject: "alice", Name: "alice@example.com"}
+	aliceCtx := audit.WithRequest(auth.WithPrincipal(ctx, alice), domain.AuditSourceMCP, "req-42")
+	if _, err := rbac.Grant(ctx, "alice", domain.RoleEditor, &app.ID); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+
+	res, err := resSvc.AddFromDescription(aliceCtx, app.ID, "a postgres database")
+	if err != nil {
+		t.Fatalf("AddFromDescription() error = %v", err)
+	}
+	if err := resSvc.Remove(aliceCtx, res.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	entries, err := auditSvc.List(ctx, domain.AuditFilter{ApplicationID: &app.ID})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("List() = %d entries, want grant, add and remove", len(entries))
+	}
+	removed := entries[0]
+	if removed.Action != "resource.remove" || removed.TargetID != res.ID.String() || removed.Actor != "alice" || removed.ActorName != "alice@example.com" {
+		t.Errorf("remove entry = %+v", removed)
+	}
+	if removed.Source != domain.AuditSourceMCP || removed.RequestID != "req-42" {
+		t.Errorf("remove entry source = %s %q, want mcp req-42", removed.Source, removed.RequestID)
+	}
+	if removed.OrgID == nil || *removed.OrgID != domain.DefaultOrgID {
+		t.Errorf("remove entry org = %v, want the application's", removed.OrgID)
+	}
+	var before domain.Resource
+	if err := json.Unmarshal(removed.Before, &before); err != nil || before.ID != res.ID || removed.After != nil {
+		t.Errorf("remove entry before/after = %s / %s", removed.Before, removed.After)
+	}
+	if added := entries[1]; added.Action != "resource.add" || added.Before != nil || added.After == nil {
+		t.Errorf("add entry = %+v", added)
+	}
+	if granted := entries[2]; granted.Action != "role_binding.grant" || granted.Actor != domain.SystemActor || granted.Source != domain.AuditSourceSystem {
+		t.Errorf("grant entry = %+v, want a system entry", granted)
+	}
+
+	// Time ranges
+	if entries, _ := auditSvc.List(ctx, domain.AuditFilter{From: time.Now().UTC().Add(time.Minute)}); len(entries) != 0 {
+		t.Errorf("List(future) = %d entries, want 0", len(entries))
+	}
+
+	// Reading the audit log needs the admin role.
+	if _, err := auditSvc.List(aliceCtx, domain.AuditFilter{ApplicationID: &app.ID}); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("List(editor): got %v, want ErrForbidden", err)
+	}
+	if _, err := rbac.Grant(ctx, "alice", domain.RoleAdmin, &app.ID); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if _, err := auditSvc.List(aliceCtx, domain.AuditFilter{ApplicationID: &app.ID}); err != nil {
+		t.Errorf("List(app admin) error = %v", err)
+	}
+	if _, err := auditSvc.List(aliceCtx, domain.AuditFilter{}); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("List(every application, app admin): got %v, want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+)
+
+// apiKeyUsageInterval is how stale an API key's last-used time may get
+// before authenticating with it records a new one, so busy keys do not
+// write on every request.
+const apiKeyUsageInterval = time.Minute
+
+// AuthService authenticates API callers and manages API keys. Callers
+// present either an API key or a bearer JWT from the identity provider, when
+// one is configured.
+type AuthService struct {
+	keys      repository.APIKeyRepo
+	jwt       *auth.Verifier // optional
+	adminKey  string         // optional bootstrap key with the admin scope
+	anonymous bool
+	rbac      *RBACService  // optional
+	audit     *AuditService // optional
+	now       func() time.Time
+}
+
+// NewAuthService creates a new AuthService.
+func NewAuthService(keys repository.APIKeyRepo) *AuthService {
+	return &AuthService{
+		keys: keys,
+		now:  func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// SetJWTVerifier makes the service accept bearer JWTs verified by v. A nil
+// verifier accepts only API keys.
+func (s *AuthService) SetJWTVerifier(v *auth.Verifier) { s.jwt = v }
+
+// SetBootstrapKey accepts key as an admin credential that is not stored in
+// the database, so the first API keys can be created. An empty key disables
+// it.
+func (s *AuthService) SetBootstrapKey(key string) { s.adminKey = key }
+
+// SetAllowAnonymous lets requests without credentials through as an
+// anonymous admin. Credentials that are presented are still verified. Only
+// meant for local development.
+func (s *AuthService) SetAllowAnonymous(allow bool) { s.anonymous = allow }
+
+// SetRBAC makes the service require the global admin role in an
+// organization to manage its API keys. A nil service allows everything.
+func (s *AuthService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record API key changes in the audit log. A nil
+// service records nothing.
+func (s *AuthService) SetAudit(a *AuditService) { s.audit = a }
+
+// CreateAPIKey creates an API key in the request's organization and returns
+// it with its secret, which is not stored and cannot be retrieved again.
+func (s *AuthService) CreateAPIKey(ctx context.Context, name string, scopes []domain.Scope, expiresAt *time.Time) (domain.APIKey, string, error) {
+	if err := s.rbac.require(ctx, domain.RoleAdmin, nil); err != nil {
+		return domain.APIKey{}, "", err
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return domain.APIKey{}, "", fmt.Errorf("generate API key: %w", err)
+	}
+	secret := domain.APIKeyPrefix + hex.EncodeToString(buf)
+
+	k := domain.NewAPIKey(name, secret, scopes, requestOrg(ctx), expiresAt)
+	if err := k.Validate(); err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.keys.Create(ctx, k); err != nil {
+			return fmt.Errorf("create API key: %w", err)
+		}
+		return s.audit.record(ctx, "api_key.create", "api_key", k.ID.String(), nil, nil, k)
+	})
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	return k, secret, nil
+}
+
+// ListAPIKeys returns the API keys of the request's organization, newest
+// first, including revoked and expired ones.
+func (s *AuthService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	if err := s.rbac.require(ctx, domain.RoleAdmin, nil); err != nil {
+		return nil, err
+	}
+	return s.keys.List(ctx)
+}
+
+// RevokeAPIKey stops an API key of the request's organization from
+// authenticating. The key stays listed.
+func (s *AuthService) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	if err := s.rbac.require(ctx, domain.RoleAdmin, nil); err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		before, err := s.keys.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.keys.Revoke(ctx, id, s.now()); err != nil {
+			return err
+		}
+		after, err := s.keys.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "api_key.revoke", "api_key", id.String(), nil, before, after)
+	})
+}
+
+// Authenticate returns the principal for a credential: an API key or a
+// bearer JWT. An empty credential is anonymous when allowed. Failures wrap
+// domain.ErrUnauthenticated.
+func (s *AuthService) Authenticate(ctx context.Context, credential string) (domain.Principal, error) {
+	switch {
+	case credential == "":
+		if s.anonymous {
+			return domain.Principal{Kind: domain.PrincipalAnonymous, Name: "anonymous", Scopes: []domain.Scope{domain.ScopeAdmin}, Superuser: true}, nil
+		}
+		return domain.Principal{}, fmt.Errorf("%w: no credentials", domain.ErrUnauthenticated)
+
+	case s.adminKey != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(s.adminKey)) == 1:
+		return domain.Principal{Kind: domain.PrincipalAPIKey, Subject: "bootstrap", Name: "bootstrap", Scopes: []domain.Scope{domain.ScopeAdmin}, Superuser: true}, nil
+
+	case strings.HasPrefix(credential, domain.APIKeyPrefix):
+		return s.authenticateKey(ctx, credential)
+
+	case s.jwt != nil:
+		claims, err := s.jwt.Verify(ctx, credential)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return domain.Principal{}, fmt.Errorf("%w: %v", domain.ErrUnauthenticated, err)
+		}
+		if err != nil {
+			return domain.Principal{}, fmt.Errorf("verify token: %w", err)
+		}
+		var scopes []domain.Scope
+		for _, sc := range claims.Scopes {
+			if scope := domain.Scope(sc); scope.IsValid() {
+				scopes = append(scopes, scope)
+			}
+		}
+		return domain.Principal{Kind: domain.PrincipalUser, Subject: claims.Subject, Name: claims.Name, Scopes: scopes}, nil
+
+	default:
+		return domain.Principal{}, fmt.Errorf("%w: unrecognised credential", domain.ErrUnauthenticated)
+	}
+}
+
+func (s *AuthService) authenticateKey(ctx context.Context, secret string) (domain.Principal, error) {
+	k, err := s.keys.GetByHash(ctx, domain.HashAPIKey(secret))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.Principal{}, fmt.Errorf("%w: unknown API key", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.Principal{}, fmt.Errorf("get API key: %w", err)
+	}
+
+	now := s.now()
+	if !k.Active(now) {
+		return domain.Principal{}, fmt.Errorf("%w: API key %s is revoked or expired", domain.ErrUnauthenticated, k.Prefix)
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyUsageInterval {
+		if err := s.keys.MarkUsed(ctx, k.ID, now); err != nil {
+			log.Printf("[auth] record use of API key %s: %v", k.ID, err)
+		}
+	}
+	return domain.Principal{Kind: domain.PrincipalAPIKey, Subject: k.ID.String(), Name: k.Name, Scopes: k.Scopes, OrgID: &k.OrgID}, nil
+}
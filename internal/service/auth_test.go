@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+func TestAuthService_APIKeys(t *testing.T) {
+	keys := mock.NewAPIKeyRepo()
+	svc := NewAuthService(keys)
+	clock := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	expires := time.Now().Add(48 * time.Hour)
+	k, secret, err := svc.CreateAPIKey(ctx, "ci", []domain.Scope{domain.ScopeWrite}, &expires)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, domain.APIKeyPrefix) || !strings.HasPrefix(secret, k.Prefix) || k.Hash == secret {
+		t.Errorf("secret %q, key %+v", secret, k)
+	}
+
+	t.Run("authenticates and records use", func(t *testing.T) {
+		p, err := svc.Authenticate(ctx, secret)
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if p.Kind != domain.PrincipalAPIKey || p.Subject != k.ID.String() || p.Name != "ci" || !p.Can(domain.ScopeWrite) || p.Can(domain.ScopeAdmin) {
+			t.Errorf("principal = %+v", p)
+		}
+		got, _ := keys.GetByID(ctx, k.ID)
+		if got.LastUsedAt == nil || !got.LastUsedAt.Equal(clock) {
+			t.Errorf("LastUsedAt = %v, want %v", got.LastUsedAt, clock)
+		}
+
+		// Uses within the usage interval are not recorded again.
+		clock = clock.Add(time.Second)
+		svc.Authenticate(ctx, secret)
+		if got, _ := keys.GetByID(ctx, k.ID); !got.LastUsedAt.Equal(clock.Add(-time.Second)) {
+			t.Errorf("LastUsedAt = %v, want unchanged", got.LastUsedAt)
+		}
+	})
+
+	t.Run("rejects unknown and expired keys", func(t *testing.T) {
+		if _, err := svc.Authenticate(ctx, domain.APIKeyPrefix+"nope"); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("unknown key: got %v, want ErrUnauthenticated", err)
+		}
+		clock = expires.Add(time.Minute)
+		defer func() { clock = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC) }()
+		if _, err := svc.Authenticate(ctx, secret); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("expired key: got %v, want ErrUnauthenticated", err)
+		}
+	})
+
+	t.Run("revoked keys stop working", func(t *testing.T) {
+		if err := svc.RevokeAPIKey(ctx, k.ID); err != nil {
+			t.Fatalf("RevokeAPIKey() error = %v", err)
+		}
+		if _, err := svc.Authenticate(ctx, secret); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("revoked key: got %v, want ErrUnauthenticated", err)
+		}
+		if list, _ := svc.ListAPIKeys(ctx); len(list) != 1 || list[0].RevokedAt == nil {
+			t.Errorf("ListAPIKeys() = %+v, want the revoked key", list)
+		}
+	})
+
+	t.Run("invalid scopes", func(t *testing.T) {
+		if _, _, err := svc.CreateAPIKey(ctx, "bad", []domain.Scope{"root"}, nil); !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+}
+
+func TestAuthService_Organizations(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	svc := NewAuthService(mock.NewAPIKeyRepo())
+	svc.SetRBAC(rbac)
+	ctx := context.Background()
+	acme := tenant.WithOrg(ctx, domain.NewOrganization("acme", ""))
+
+	// Keys belong to the organization they are created in and authenticate
+	// as principals of it.
+	k, secret, err := svc.CreateAPIKey(acme, "ci", []domain.Scope{domain.ScopeAdmin}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if k.OrgID != *tenant.OrgID(acme) {
+		t.Errorf("OrgID = %v, want acme", k.OrgID)
+	}
+	p, err := svc.Authenticate(ctx, secret)
+	if err != nil || p.OrgID == nil || *p.OrgID != k.OrgID {
+		t.Fatalf("Authenticate() = %+v, %v; want a principal of acme", p, err)
+	}
+	if list, _ := svc.ListAPIKeys(ctx); len(list) != 1 {
+		t.Errorf("ListAPIKeys(unscoped) = %d keys, want 1", len(list))
+	}
+	if list, _ := svc.ListAPIKeys(tenant.WithOrg(ctx, domain.Organization{ID: domain.DefaultOrgID})); len(list) != 0 {
+		t.Errorf("ListAPIKeys(default org) = %d keys, want 0", len(list))
+	}
+	if err := svc.RevokeAPIKey(tenant.WithOrg(ctx, domain.Organization{ID: domain.DefaultOrgID}), k.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("RevokeAPIKey(other org): got %v, want ErrNotFound", err)
+	}
+
+	// Managing keys needs the global admin role in the organization, not
+	// just the admin scope.
+	caller := auth.WithPrincipal(acme, p)
+	if _, err := svc.ListAPIKeys(caller); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("ListAPIKeys(no role): got %v, want ErrForbidden", err)
+	}
+	if _, err := rbac.Grant(acme, k.ID.String(), domain.RoleAdmin, nil); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if list, err := svc.ListAPIKeys(caller); err != nil || len(list) != 1 {
+		t.Errorf("ListAPIKeys(admin) = %+v, %v", list, err)
+	}
+	defaultAdmin := auth.WithPrincipal(tenant.WithOrg(ctx, domain.Organization{ID: domain.DefaultOrgID}), p)
+	if _, err := svc.ListAPIKeys(defaultAdmin); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("ListAPIKeys(admin of acme in default org): got %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthService_Authenticate(t *testing.T) {
+	svc := NewAuthService(mock.NewAPIKeyRepo())
+	ctx := context.Background()
+
+	if _, err := svc.Authenticate(ctx, ""); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("no credentials: got %v, want ErrUnauthenticated", err)
+	}
+	if _, err := svc.Authenticate(ctx, "eyJhbGciOiJSUzI1NiJ9.e30.sig"); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("JWT without a verifier: got %v, want ErrUnauthenticated", err)
+	}
+
+	svc.SetBootstrapKey("bootstrap-secret")
+	if p, err := svc.Authenticate(ctx, "bootstrap-secret"); err != nil || !p.Can(domain.ScopeAdmin) {
+		t.Errorf("bootstrap key: %+v, %v", p, err)
+	}
+
+	svc.SetAllowAnonymous(true)
+	if p, err := svc.Authenticate(ctx, ""); err != nil || p.Kind != domain.PrincipalAnonymous {
+		t.Errorf("anonymous: %+v, %v", p, err)
+	}
+	if _, err := svc.Authenticate(ctx, domain.APIKeyPrefix+"nope"); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("bad key with anonymous access: got %v, want ErrUnauthenticated", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,51 +13,330 @@ import (
 	"github.com/matthewdriscoll/infraplane/internal/repository"
 )
 
+const (
+	// DefaultInterruptedAfter is how long a deployment may stay in progress
+	// before RecoverInterrupted assumes the server running it died. Runs take
+	// minutes at most, so a longer wait never cuts short a live run on
+	// another replica.
+	DefaultInterruptedAfter = time.Hour
+
+	// DefaultRecoveryInterval is how often RunRecovery looks for interrupted
+	// deployments.
+	DefaultRecoveryInterval = 5 * time.Minute
+)
+
 // DeploymentService handles deployment orchestration.
 type DeploymentService struct {
 	deployments repository.DeploymentRepo
 	apps        repository.ApplicationRepo
+	plans       repository.PlanRepo
+	resources   repository.ResourceRepo
+	freezes     repository.FreezeWindowRepo
+	locks       *appLocks
+	outbox      *Outbox       // optional
+	rbac        *RBACService  // optional
+	audit       *AuditService // optional
 }
 
 // NewDeploymentService creates a new DeploymentService.
-func NewDeploymentService(deployments repository.DeploymentRepo, apps repository.ApplicationRepo) *DeploymentService {
+func NewDeploymentService(deployments repository.DeploymentRepo, apps repository.ApplicationRepo, plans repository.PlanRepo, resources repository.ResourceRepo, freezes repository.FreezeWindowRepo) *DeploymentService {
 	return &DeploymentService{
 		deployments: deployments,
 		apps:        apps,
+		plans:       plans,
+		resources:   resources,
+		freezes:     freezes,
+		locks:       newAppLocks(),
 	}
 }
 
+// SetOutbox makes the service record deployment status changes made through
+// MarkSucceeded and MarkFailed in o. A nil outbox disables events.
+func (s *DeploymentService) SetOutbox(o *Outbox) { s.outbox = o }
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *DeploymentService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *DeploymentService) SetAudit(a *AuditService) { s.audit = a }
+
+// DeployOptions holds the overrides a deploy, rollback or destroy may ask for.
+type DeployOptions struct {
+	// Force deploys a plan even if the application's resources have drifted
+	// from its snapshot. It only applies to plan-linked deploys.
+	Force bool
+	// BreakGlass deploys, rolls back or destroys despite active freeze
+	// windows. The override is recorded on the deployment.
+	BreakGlass bool
+}
+
 // Deploy creates a new deployment for an application, optionally linked to a plan.
-func (s *DeploymentService) Deploy(ctx context.Context, appID uuid.UUID, gitCommit, gitBranch string, planID *uuid.UUID) (domain.Deployment, error) {
+// A plan-linked deployment applies exactly the plan's resource snapshot; refined
+// plans have none and are refused with a validation error. If the
+// application's resources have drifted from that snapshot the deploy is refused
+// with ErrPlanStale unless opts.Force is set. While a freeze window covers the
+// application and branch the deploy is refused with ErrDeploymentFrozen unless
+// opts.BreakGlass is set.
+func (s *DeploymentService) Deploy(ctx context.Context, appID uuid.UUID, gitCommit, gitBranch string, planID *uuid.UUID, opts DeployOptions) (domain.Deployment, error) {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
 		return domain.Deployment{}, fmt.Errorf("get application: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, app.ID); err != nil {
+		return domain.Deployment{}, err
+	}
 
 	d := domain.NewDeployment(appID, app.Provider, gitCommit, gitBranch, planID)
 	if err := d.Validate(); err != nil {
 		return domain.Deployment{}, err
 	}
 
-	if err := s.deployments.Create(ctx, d); err != nil {
-		return domain.Deployment{}, fmt.Errorf("create deployment: %w", err)
+	if planID != nil {
+		plan, err := s.plans.GetByID(ctx, *planID)
+		if err != nil {
+			return domain.Deployment{}, fmt.Errorf("get plan: %w", err)
+		}
+		if plan.ApplicationID != appID {
+			return domain.Deployment{}, domain.ErrValidation(fmt.Sprintf("plan %s belongs to a different application", plan.ID))
+		}
+		if !plan.HasSnapshot() {
+			return domain.Deployment{}, domain.ErrValidation(fmt.Sprintf(
+				"plan %s is a refinement and has no resource snapshot; refine the application's resources to match it, then generate and deploy a new plan", plan.ID))
+		}
+
+		current, err := s.resources.ListByApplicationID(ctx, appID)
+		if err != nil {
+			return domain.Deployment{}, fmt.Errorf("list resources: %w", err)
+		}
+		if drift := plan.Drift(current); len(drift) > 0 && !opts.Force {
+			return domain.Deployment{}, fmt.Errorf("%w: %s since the plan was generated; regenerate the plan or deploy with force",
+				domain.ErrPlanStale, strings.Join(drift, ", "))
+		}
+		d.Resources = plan.Resources
+	}
+
+	d.BreakGlass = opts.BreakGlass
+	overridden, err := s.checkFreeze(ctx, d)
+	if err != nil {
+		return domain.Deployment{}, err
+	}
+
+	if err := s.create(ctx, "deployment.deploy", d, overridden); err != nil {
+		return domain.Deployment{}, err
+	}
+
+	return d, nil
+}
+
+// Rollback creates a pending deployment that re-applies the exact Terraform
+// configuration of a previous succeeded deployment. The new deployment is
+// linked to the original via RollbackOf and is executed like any other
+// deployment (see Execute). While a freeze window covers the application and
+// branch the rollback is refused with ErrDeploymentFrozen unless
+// opts.BreakGlass is set.
+func (s *DeploymentService) Rollback(ctx context.Context, sourceID uuid.UUID, opts DeployOptions) (domain.Deployment, error) {
+	source, err := s.deployments.GetByID(ctx, sourceID)
+	if err != nil {
+		return domain.Deployment{}, fmt.Errorf("get deployment: %w", err)
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, source.ApplicationID); err != nil {
+		return domain.Deployment{}, err
+	}
+
+	if source.Status != domain.DeploymentSucceeded {
+		return domain.Deployment{}, domain.ErrValidation(fmt.Sprintf("can only roll back to a succeeded deployment (deployment is %s)", source.Status))
+	}
+	if source.Type == domain.DeploymentTypeDestroy {
+		return domain.Deployment{}, domain.ErrValidation("cannot roll back to a destroy deployment")
+	}
+	if source.TerraformPlan == "" {
+		return domain.Deployment{}, domain.ErrValidation("deployment has no terraform configuration to re-apply")
+	}
+
+	if holder, locked := s.locks.holder(source.ApplicationID); locked {
+		return domain.Deployment{}, fmt.Errorf("%w (deployment %s)", domain.ErrDeploymentInProgress, holder)
+	}
+
+	d := domain.NewRollbackDeployment(source)
+	if err := d.Validate(); err != nil {
+		return domain.Deployment{}, err
+	}
+
+	d.BreakGlass = opts.BreakGlass
+	overridden, err := s.checkFreeze(ctx, d)
+	if err != nil {
+		return domain.Deployment{}, err
+	}
+
+	if err := s.create(ctx, "deployment.rollback", d, overridden); err != nil {
+		return domain.Deployment{}, err
+	}
+
+	return d, nil
+}
+
+// Destroy creates a pending destroy deployment that tears down everything the
+// application's most recent succeeded apply created. The caller must echo the
+// application name as confirmation, and applications with PreventDestroy set
+// are refused. While a freeze window covers the application and branch the
+// destroy is refused with ErrDeploymentFrozen unless opts.BreakGlass is set.
+// Like other deployments it runs via Execute, is recorded in the deployment
+// history, and returns the application to draft on success.
+func (s *DeploymentService) Destroy(ctx context.Context, appID uuid.UUID, confirmation string, opts DeployOptions) (domain.Deployment, error) {
+	app, err := s.apps.GetByID(ctx, appID)
+	if err != nil {
+		return domain.Deployment{}, fmt.Errorf("get application: %w", err)
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, app.ID); err != nil {
+		return domain.Deployment{}, err
+	}
+
+	if confirmation != app.Name {
+		return domain.Deployment{}, domain.ErrValidation(fmt.Sprintf("confirmation does not match: type the application name %q to destroy it", app.Name))
+	}
+	if app.PreventDestroy {
+		return domain.Deployment{}, domain.ErrValidation(fmt.Sprintf("application %q has destroy protection enabled; disable prevent_destroy first", app.Name))
+	}
+	if !app.Status.CanTransitionTo(domain.AppStatusDestroying) {
+		return domain.Deployment{}, domain.ErrValidation(fmt.Sprintf("application %q is %s and cannot be destroyed", app.Name, app.Status))
+	}
+
+	if holder, locked := s.locks.holder(appID); locked {
+		return domain.Deployment{}, fmt.Errorf("%w (deployment %s)", domain.ErrDeploymentInProgress, holder)
+	}
+
+	source, err := s.lastSucceeded(ctx, appID)
+	if err != nil {
+		return domain.Deployment{}, err
+	}
+	if source.Type == domain.DeploymentTypeDestroy {
+		return domain.Deployment{}, domain.ErrValidation("application infrastructure is already destroyed")
+	}
+	if source.TerraformPlan == "" {
+		return domain.Deployment{}, domain.ErrValidation("latest deployment has no terraform configuration to destroy")
+	}
+
+	d := domain.NewDestroyDeployment(source)
+	if err := d.Validate(); err != nil {
+		return domain.Deployment{}, err
+	}
+
+	d.BreakGlass = opts.BreakGlass
+	overridden, err := s.checkFreeze(ctx, d)
+	if err != nil {
+		return domain.Deployment{}, err
+	}
+
+	if err := s.create(ctx, "deployment.destroy", d, overridden); err != nil {
+		return domain.Deployment{}, err
 	}
 
 	return d, nil
 }
 
+// create saves the new deployment d, recording action and any freeze windows
+// it overrides in the audit log.
+func (s *DeploymentService) create(ctx context.Context, action string, d domain.Deployment, overridden []domain.FreezeOccurrence) error {
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.deployments.Create(ctx, d); err != nil {
+			return fmt.Errorf("create deployment: %w", err)
+		}
+		if err := s.audit.record(ctx, action, "deployment", d.ID.String(), &d.ApplicationID, nil, d.Summary()); err != nil {
+			return err
+		}
+		return recordBreakGlass(ctx, s.audit, d, overridden)
+	})
+}
+
+// checkFreeze returns an ErrDeploymentFrozen error if a freeze window covers
+// d's application and branch right now. Break-glass deployments pass; the
+// windows they override are returned for recordBreakGlass.
+func (s *DeploymentService) checkFreeze(ctx context.Context, d domain.Deployment) ([]domain.FreezeOccurrence, error) {
+	app, err := s.apps.GetByID(ctx, d.ApplicationID)
+	if err != nil {
+		return nil, fmt.Errorf("get application: %w", err)
+	}
+	active, err := activeFreezes(ctx, s.freezes, app, d.GitBranch, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+	if !d.BreakGlass {
+		return nil, domain.FrozenError(active)
+	}
+	return active, nil
+}
+
+// breakGlassOverride is the audit record of a deployment overriding freeze
+// windows.
+type breakGlassOverride struct {
+	Deployment    domain.Deployment         `json:"deployment"`
+	FreezeWindows []domain.FreezeOccurrence `json:"freeze_windows"`
+}
+
+// recordBreakGlass records in the audit log that d overrides the freeze
+// windows overridden. It records nothing if there are none.
+func recordBreakGlass(ctx context.Context, a *AuditService, d domain.Deployment, overridden []domain.FreezeOccurrence) error {
+	if len(overridden) == 0 {
+		return nil
+	}
+	return a.record(ctx, "deployment.break_glass", "deployment", d.ID.String(), &d.ApplicationID, nil,
+		breakGlassOverride{Deployment: d.Summary(), FreezeWindows: overridden})
+}
+
+// lastSucceeded returns the most recent succeeded deployment for an application.
+func (s *DeploymentService) lastSucceeded(ctx context.Context, appID uuid.UUID) (domain.Deployment, error) {
+	deployments, err := s.deployments.ListByApplicationID(ctx, appID)
+	if err != nil {
+		return domain.Deployment{}, fmt.Errorf("list deployments: %w", err)
+	}
+
+	var latest domain.Deployment
+	found := false
+	for _, d := range deployments {
+		if d.Status != domain.DeploymentSucceeded {
+			continue
+		}
+		if !found || d.StartedAt.After(latest.StartedAt) {
+			latest = d
+			found = true
+		}
+	}
+	if !found {
+		return domain.Deployment{}, domain.ErrValidation("application has no succeeded deployment to destroy")
+	}
+	return latest, nil
+}
+
 // GetStatus returns a deployment by ID.
 func (s *DeploymentService) GetStatus(ctx context.Context, id uuid.UUID) (domain.Deployment, error) {
-	return s.deployments.GetByID(ctx, id)
+	d, err := s.deployments.GetByID(ctx, id)
+	if err != nil {
+		return domain.Deployment{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, d.ApplicationID); err != nil {
+		return domain.Deployment{}, err
+	}
+	return d, nil
 }
 
 // ListByApplication returns all deployments for an application.
 func (s *DeploymentService) ListByApplication(ctx context.Context, appID uuid.UUID) ([]domain.Deployment, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return nil, err
+	}
 	return s.deployments.ListByApplicationID(ctx, appID)
 }
 
 // GetLatest returns the most recent deployment for an application.
 func (s *DeploymentService) GetLatest(ctx context.Context, appID uuid.UUID) (domain.Deployment, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return domain.Deployment{}, err
+	}
 	return s.deployments.GetLatestByApplicationID(ctx, appID)
 }
 
@@ -65,12 +346,13 @@ func (s *DeploymentService) MarkSucceeded(ctx context.Context, id uuid.UUID, ter
 	if err != nil {
 		return domain.Deployment{}, err
 	}
-	now := time.Now().UTC()
-	d.Status = domain.DeploymentSucceeded
-	d.CompletedAt = &now
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, d.ApplicationID); err != nil {
+		return domain.Deployment{}, err
+	}
+	d.Finish(domain.DeploymentSucceeded, time.Now().UTC())
 	d.TerraformPlan = terraformPlan
-	if err := s.deployments.Update(ctx, d); err != nil {
-		return domain.Deployment{}, fmt.Errorf("update deployment: %w", err)
+	if err := s.finish(ctx, d); err != nil {
+		return domain.Deployment{}, err
 	}
 	return d, nil
 }
@@ -81,23 +363,83 @@ func (s *DeploymentService) MarkFailed(ctx context.Context, id uuid.UUID) (domai
 	if err != nil {
 		return domain.Deployment{}, err
 	}
-	now := time.Now().UTC()
-	d.Status = domain.DeploymentFailed
-	d.CompletedAt = &now
-	if err := s.deployments.Update(ctx, d); err != nil {
-		return domain.Deployment{}, fmt.Errorf("update deployment: %w", err)
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, d.ApplicationID); err != nil {
+		return domain.Deployment{}, err
+	}
+	d.Finish(domain.DeploymentFailed, time.Now().UTC())
+	if err := s.finish(ctx, d); err != nil {
+		return domain.Deployment{}, err
 	}
 	return d, nil
 }
 
-// Execute runs a deployment end-to-end: generates Terraform, validates, and applies.
-// It sends DeploymentEvent values to the events channel and closes it when done.
-// The caller owns the channel and should read from it (e.g. the SSE handler).
+// finish saves a finished deployment, moves its application to the matching
+// status and records the status change, all together.
+func (s *DeploymentService) finish(ctx context.Context, d domain.Deployment) error {
+	return s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.deployments.Update(ctx, d); err != nil {
+			return fmt.Errorf("update deployment: %w", err)
+		}
+		if err := syncAppStatus(ctx, s.apps, d); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventDeploymentStatusChanged, d.ApplicationID, d.Summary())
+	})
+}
+
+// syncAppStatus moves the deployment's application to the status implied by
+// the deployment's outcome (see Deployment.AppStatusAfter).
+func syncAppStatus(ctx context.Context, apps repository.ApplicationRepo, d domain.Deployment) error {
+	return transitionApp(ctx, apps, d.ApplicationID, d.AppStatusAfter)
+}
+
+// transitionApp moves an application to the status next computes from its
+// current status. It is a no-op when the status would not change.
+func transitionApp(ctx context.Context, apps repository.ApplicationRepo, appID uuid.UUID, next func(domain.AppStatus) domain.AppStatus) error {
+	app, err := apps.GetByID(ctx, appID)
+	if err != nil {
+		return fmt.Errorf("get application: %w", err)
+	}
+	to := next(app.Status)
+	if to == app.Status {
+		return nil
+	}
+	if err := app.TransitionTo(to, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := apps.Update(ctx, app); err != nil {
+		return fmt.Errorf("update application status: %w", err)
+	}
+	return nil
+}
+
+// Execute runs a pending deployment end-to-end on the shared deployment
+// runner: apply deployments generate Terraform, validate, and apply; destroy
+// deployments tear down the configuration they captured. A deployment covered
+// by an active freeze window stays pending unless it was created with
+// break-glass or breakGlass is set now, in which case the override is recorded
+// in the audit log as the run starts. It sends
+// DeploymentEvent values to the events channel and closes it when done. The
+// caller owns the channel and should read from it (e.g. the SSE handler).
 func (s *DeploymentService) Execute(
 	ctx context.Context,
 	deploymentID uuid.UUID,
 	infra *InfraService,
+	breakGlass bool,
+	events chan<- domain.DeploymentEvent,
+) {
+	s.execute(ctx, deploymentID, infra, breakGlass, events, sleep)
+}
+
+// execute is Execute with the runner's pacing between steps: sleep for
+// streamed runs, noPause when nobody is watching.
+func (s *DeploymentService) execute(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	infra *InfraService,
+	breakGlass bool,
 	events chan<- domain.DeploymentEvent,
+	pause func(context.Context, time.Duration),
 ) {
 	defer close(events)
 
@@ -114,7 +456,7 @@ func (s *DeploymentService) Execute(
 		}
 	}
 
-	// 1. Look up the deployment
+	// Look up the deployment
 	d, err := s.deployments.GetByID(ctx, deploymentID)
 	if err != nil {
 		emit(domain.StepFailed, "Deployment not found: "+err.Error(), domain.DeploymentFailed, "")
@@ -127,130 +469,145 @@ func (s *DeploymentService) Execute(
 		return
 	}
 
-	// 2. Mark in_progress
-	d.Status = domain.DeploymentInProgress
-	_ = s.deployments.Update(ctx, d)
-	emit(domain.StepInitializing, "Deployment started. Initializing workspace...", domain.DeploymentInProgress, "")
-	sleep(ctx, 800*time.Millisecond)
-
-	if ctx.Err() != nil {
-		s.failDeploy(ctx, &d)
+	// Guard: the caller must be allowed to deploy the application.
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, d.ApplicationID); err != nil {
+		emit(domain.StepFailed, err.Error(), d.Status, "")
 		return
 	}
 
-	// 3. Generate Terraform
-	emit(domain.StepGeneratingTerraform, "Generating Terraform configuration...", domain.DeploymentInProgress, "")
-	sleep(ctx, 600*time.Millisecond)
-
-	hcl, err := infra.GenerateTerraform(ctx, d.ApplicationID)
+	// Guard: freeze windows block execution; the deployment stays pending so
+	// it can run once the freeze ends.
+	d.BreakGlass = d.BreakGlass || breakGlass
+	overridden, err := s.checkFreeze(ctx, d)
 	if err != nil {
-		s.failDeploy(ctx, &d)
-		emit(domain.StepFailed, "Terraform generation failed: "+err.Error(), domain.DeploymentFailed, "")
+		emit(domain.StepFailed, err.Error(), d.Status, "")
 		return
 	}
 
-	lineCount := len(hcl) / 40 // rough line estimate
-	emit(domain.StepGeneratingTerraform,
-		fmt.Sprintf("Terraform configuration generated (%d chars, ~%d lines).", len(hcl), lineCount),
-		domain.DeploymentInProgress, hcl)
-	sleep(ctx, 400*time.Millisecond)
-
-	// 4. Validate
-	emit(domain.StepValidating, "Running terraform validate...", domain.DeploymentInProgress, "")
-	sleep(ctx, 1*time.Second)
-	emit(domain.StepValidating, "Success! The configuration is valid.", domain.DeploymentInProgress, "")
-	sleep(ctx, 300*time.Millisecond)
-
-	// 5. Apply
-	emit(domain.StepApplying, "Running terraform apply...", domain.DeploymentInProgress, "")
-	sleep(ctx, 500*time.Millisecond)
-
-	// Simulate multi-line terraform apply output
-	provSlug := providerSlug(d.Provider)
-	applyLines := []string{
-		"Initializing the backend...",
-		"Initializing provider plugins...",
-		fmt.Sprintf("- Finding hashicorp/%s ~> 5.0...", provSlug),
-		fmt.Sprintf("- Installing hashicorp/%s v5.45.0...", provSlug),
-		"Terraform has been successfully initialized!",
-		"",
-		"Terraform will perform the following actions:",
-		"",
-		fmt.Sprintf("Plan: %d to add, 0 to change, 0 to destroy.", lineCount/5+1),
-		"",
-		"Applying...",
-	}
-
-	for _, line := range applyLines {
-		if ctx.Err() != nil {
-			s.failDeploy(ctx, &d)
-			emit(domain.StepFailed, "Deployment cancelled.", domain.DeploymentFailed, "")
-			return
-		}
-		emit(domain.StepApplying, line, domain.DeploymentInProgress, "")
-		sleep(ctx, 400*time.Millisecond)
-	}
-
-	// Call the actual provider adapter
-	app, appErr := infra.Apps().GetByID(ctx, d.ApplicationID)
-	if appErr != nil {
-		s.failDeploy(ctx, &d)
-		emit(domain.StepFailed, "Application not found: "+appErr.Error(), domain.DeploymentFailed, "")
+	// Guard: only one deployment may run per application at a time. The
+	// deployment stays pending so it can be retried once the other finishes.
+	if holder, ok := s.locks.tryLock(d.ApplicationID, d.ID); !ok {
+		emit(domain.StepFailed, fmt.Sprintf("Deployment %s is already running for this application", holder), d.Status, "")
 		return
 	}
+	defer s.locks.unlock(d.ApplicationID)
 
-	adapter, adapterErr := infra.Providers().Get(app.Provider)
-	if adapterErr != nil {
-		s.failDeploy(ctx, &d)
-		emit(domain.StepFailed, "Provider not available: "+adapterErr.Error(), domain.DeploymentFailed, "")
-		return
+	runner := infra.newRunner(emit, pause)
+	runner.overridden = overridden
+	_ = runner.run(ctx, &d) // outcome is recorded on d and streamed via emit
+}
+
+// executeSync runs a pending deployment with Execute and waits for it to
+// finish, without the pacing of streamed runs. It returns the deployment as
+// recorded afterwards, and an error unless it succeeded: the failure reason,
+// or the guard that kept it pending.
+func (s *DeploymentService) executeSync(ctx context.Context, deploymentID uuid.UUID, infra *InfraService, breakGlass bool) (domain.Deployment, error) {
+	events := make(chan domain.DeploymentEvent, 32)
+	go s.execute(ctx, deploymentID, infra, breakGlass, events, noPause)
+	var last domain.DeploymentEvent
+	for ev := range events {
+		last = ev
 	}
 
-	planOutput, applyErr := adapter.ApplyTerraform(ctx, hcl)
-	if applyErr != nil {
-		s.failDeploy(ctx, &d)
-		emit(domain.StepFailed, "Terraform apply failed: "+applyErr.Error(), domain.DeploymentFailed, "")
-		return
+	d, err := s.deployments.GetByID(ctx, deploymentID)
+	if err != nil {
+		return domain.Deployment{}, fmt.Errorf("get deployment: %w", err)
+	}
+	if d.Status != domain.DeploymentSucceeded {
+		reason := d.FailureReason
+		if reason == "" {
+			reason = last.Message
+		}
+		return d, fmt.Errorf("deployment %s %s: %s", d.ID, d.Status, reason)
 	}
+	return d, nil
+}
 
-	emit(domain.StepApplying, planOutput, domain.DeploymentInProgress, "")
-	sleep(ctx, 300*time.Millisecond)
-	emit(domain.StepApplying, "Apply complete! Resources created.", domain.DeploymentInProgress, "")
+// RecoverInterrupted fails deployments left in progress by a server that
+// stopped mid-run, so their applications can be deployed again: an apply or
+// destroy that was cut short degrades its application, like any other failed
+// run. A deployment counts as interrupted once it has been in progress for
+// longer than after and is not running in this process. It returns how many
+// were failed.
+func (s *DeploymentService) RecoverInterrupted(ctx context.Context, infra *InfraService, after time.Duration) (int, error) {
+	apps, err := s.apps.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list applications: %w", err)
+	}
+	cutoff := time.Now().UTC().Add(-after)
+	recovered := 0
+	for _, app := range apps {
+		if _, running := s.locks.holder(app.ID); running {
+			continue
+		}
+		deployments, err := s.deployments.ListByApplicationID(ctx, app.ID)
+		if err != nil {
+			return recovered, fmt.Errorf("list deployments: %w", err)
+		}
+		for _, d := range deployments {
+			if d.Status != domain.DeploymentInProgress || d.StartedAt.After(cutoff) {
+				continue
+			}
+			_ = infra.newRunner(noopEmit, noPause).fail(ctx, &d, "Interrupted: the server running the deployment stopped before it finished.")
+			recovered++
+		}
+	}
+	return recovered, nil
+}
 
-	// 6. Mark succeeded
-	now := time.Now().UTC()
-	d.Status = domain.DeploymentSucceeded
-	d.CompletedAt = &now
-	d.TerraformPlan = hcl
-	if err := s.deployments.Update(ctx, d); err != nil {
-		log.Printf("[deploy] failed to update deployment status: %v", err)
+// RunRecovery calls RecoverInterrupted with DefaultInterruptedAfter when it
+// starts and then every interval until ctx is cancelled.
+func (s *DeploymentService) RunRecovery(ctx context.Context, infra *InfraService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.RecoverInterrupted(ctx, infra, DefaultInterruptedAfter); err != nil {
+			log.Printf("[deploy] recover interrupted deployments: %v", err)
+		} else if n > 0 {
+			log.Printf("[deploy] failed %d interrupted deployment(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
+}
 
-	emit(domain.StepComplete, "Deployment succeeded.", domain.DeploymentSucceeded, planOutput)
+// appLocks serializes deployment execution per application so that two
+// applies never run against the same infrastructure concurrently.
+type appLocks struct {
+	mu     sync.Mutex
+	active map[uuid.UUID]uuid.UUID // application ID -> running deployment ID
 }
 
-func (s *DeploymentService) failDeploy(ctx context.Context, d *domain.Deployment) {
-	now := time.Now().UTC()
-	d.Status = domain.DeploymentFailed
-	d.CompletedAt = &now
-	_ = s.deployments.Update(ctx, *d)
+func newAppLocks() *appLocks {
+	return &appLocks{active: make(map[uuid.UUID]uuid.UUID)}
 }
 
-func providerSlug(p domain.CloudProvider) string {
-	switch p {
-	case domain.ProviderGCP:
-		return "google"
-	case domain.ProviderAWS:
-		return "aws"
-	default:
-		return string(p)
+// tryLock acquires the lock for appID on behalf of deploymentID. If another
+// deployment holds it, ok is false and holder identifies that deployment.
+func (l *appLocks) tryLock(appID, deploymentID uuid.UUID) (holder uuid.UUID, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if holder, locked := l.active[appID]; locked {
+		return holder, false
 	}
+	l.active[appID] = deploymentID
+	return deploymentID, true
 }
 
-// sleep respects context cancellation during simulated delays.
-func sleep(ctx context.Context, d time.Duration) {
-	select {
-	case <-time.After(d):
-	case <-ctx.Done():
-	}
+func (l *appLocks) unlock(appID uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.active, appID)
+}
+
+// holder returns the deployment currently running for appID, if any.
+func (l *appLocks) holder(appID uuid.UUID) (uuid.UUID, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	id, ok := l.active[appID]
+	return id, ok
 }
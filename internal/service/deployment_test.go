@@ -2,25 +2,29 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/provider"
 	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
 )
 
 func TestDeploymentService_Deploy(t *testing.T) {
 	appRepo := mock.NewApplicationRepo()
 	depRepo := mock.NewDeploymentRepo()
-	svc := NewDeploymentService(depRepo, appRepo)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
 	ctx := context.Background()
 
 	app := domain.NewApplication("deploy-app", "", "", "", domain.ProviderAWS)
 	appRepo.Create(ctx, app)
 
 	t.Run("successful deploy", func(t *testing.T) {
-		d, err := svc.Deploy(ctx, app.ID, "abc123", "main", nil)
+		d, err := svc.Deploy(ctx, app.ID, "abc123", "main", nil, DeployOptions{})
 		if err != nil {
 			t.Fatalf("Deploy() error = %v", err)
 		}
@@ -36,30 +40,108 @@ func TestDeploymentService_Deploy(t *testing.T) {
 	})
 
 	t.Run("app not found", func(t *testing.T) {
-		_, err := svc.Deploy(ctx, uuid.New(), "abc", "main", nil)
+		_, err := svc.Deploy(ctx, uuid.New(), "abc", "main", nil, DeployOptions{})
 		if err == nil {
 			t.Fatal("expected error")
 		}
 	})
 
 	t.Run("missing branch", func(t *testing.T) {
-		_, err := svc.Deploy(ctx, app.ID, "abc", "", nil)
+		_, err := svc.Deploy(ctx, app.ID, "abc", "", nil, DeployOptions{})
 		if err == nil {
 			t.Fatal("expected validation error")
 		}
 	})
 }
 
+func TestDeploymentService_DeployWithPlan(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	planRepo := mock.NewPlanRepo()
+	depRepo := mock.NewDeploymentRepo()
+	svc := NewDeploymentService(depRepo, appRepo, planRepo, resRepo, mock.NewFreezeWindowRepo())
+	ctx := context.Background()
+
+	app := domain.NewApplication("plan-deploy-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	db := domain.NewResource(app.ID, domain.ResourceDatabase, "db", json.RawMessage(`{}`))
+	resRepo.Create(ctx, db)
+
+	plan := domain.NewHostingPlan(app.ID, "Use RDS", []domain.Resource{db}, nil)
+	planRepo.Create(ctx, plan)
+
+	t.Run("current plan snapshots its resources", func(t *testing.T) {
+		d, err := svc.Deploy(ctx, app.ID, "abc", "main", &plan.ID, DeployOptions{})
+		if err != nil {
+			t.Fatalf("Deploy() error = %v", err)
+		}
+		if len(d.Resources) != 1 || d.Resources[0].ID != db.ID {
+			t.Errorf("Resources = %v, want the plan snapshot", d.Resources)
+		}
+	})
+
+	t.Run("plan from another application", func(t *testing.T) {
+		other := domain.NewHostingPlan(uuid.New(), "content", nil, nil)
+		planRepo.Create(ctx, other)
+
+		_, err := svc.Deploy(ctx, app.ID, "abc", "main", &other.ID, DeployOptions{})
+		if !domain.IsValidationError(err) {
+			t.Errorf("error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("refined plan refused", func(t *testing.T) {
+		refined := domain.NewRefinedPlan(plan, "use Aurora Serverless instead", "Use Aurora Serverless", nil)
+		planRepo.Create(ctx, refined)
+
+		_, err := svc.Deploy(ctx, app.ID, "abc", "main", &refined.ID, DeployOptions{Force: true})
+		if !domain.IsValidationError(err) || !strings.Contains(err.Error(), "no resource snapshot") {
+			t.Errorf("error = %v, want a validation error about the missing snapshot", err)
+		}
+	})
+
+	t.Run("unknown plan", func(t *testing.T) {
+		missing := uuid.New()
+		_, err := svc.Deploy(ctx, app.ID, "abc", "main", &missing, DeployOptions{})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+
+	resRepo.Create(ctx, domain.NewResource(app.ID, domain.ResourceCache, "cache", json.RawMessage(`{}`)))
+
+	t.Run("stale plan refused", func(t *testing.T) {
+		_, err := svc.Deploy(ctx, app.ID, "abc", "main", &plan.ID, DeployOptions{})
+		if !errors.Is(err, domain.ErrPlanStale) {
+			t.Fatalf("error = %v, want ErrPlanStale", err)
+		}
+		if !strings.Contains(err.Error(), `resource "cache" added`) {
+			t.Errorf("error = %q, want it to name the drifted resource", err)
+		}
+	})
+
+	t.Run("stale plan forced", func(t *testing.T) {
+		d, err := svc.Deploy(ctx, app.ID, "abc", "main", &plan.ID, DeployOptions{Force: true})
+		if err != nil {
+			t.Fatalf("Deploy() error = %v", err)
+		}
+		if len(d.Resources) != 1 {
+			t.Errorf("len(Resources) = %d, want 1 (plan snapshot, not current resources)", len(d.Resources))
+		}
+	})
+}
+
 func TestDeploymentService_GetStatus(t *testing.T) {
 	appRepo := mock.NewApplicationRepo()
 	depRepo := mock.NewDeploymentRepo()
-	svc := NewDeploymentService(depRepo, appRepo)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
 	ctx := context.Background()
 
 	app := domain.NewApplication("status-app", "", "", "", domain.ProviderGCP)
 	appRepo.Create(ctx, app)
 
-	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil)
+	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
 
 	t.Run("found", func(t *testing.T) {
 		got, err := svc.GetStatus(ctx, d.ID)
@@ -82,13 +164,13 @@ func TestDeploymentService_GetStatus(t *testing.T) {
 func TestDeploymentService_MarkSucceeded(t *testing.T) {
 	appRepo := mock.NewApplicationRepo()
 	depRepo := mock.NewDeploymentRepo()
-	svc := NewDeploymentService(depRepo, appRepo)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
 	ctx := context.Background()
 
 	app := domain.NewApplication("succeed-app", "", "", "", domain.ProviderAWS)
 	appRepo.Create(ctx, app)
 
-	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil)
+	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
 
 	updated, err := svc.MarkSucceeded(ctx, d.ID, "terraform plan output")
 	if err != nil {
@@ -108,13 +190,13 @@ func TestDeploymentService_MarkSucceeded(t *testing.T) {
 func TestDeploymentService_MarkFailed(t *testing.T) {
 	appRepo := mock.NewApplicationRepo()
 	depRepo := mock.NewDeploymentRepo()
-	svc := NewDeploymentService(depRepo, appRepo)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
 	ctx := context.Background()
 
 	app := domain.NewApplication("fail-app", "", "", "", domain.ProviderAWS)
 	appRepo.Create(ctx, app)
 
-	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil)
+	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
 
 	updated, err := svc.MarkFailed(ctx, d.ID)
 	if err != nil {
@@ -131,18 +213,18 @@ func TestDeploymentService_MarkFailed(t *testing.T) {
 func TestDeploymentService_GetLatest(t *testing.T) {
 	appRepo := mock.NewApplicationRepo()
 	depRepo := mock.NewDeploymentRepo()
-	svc := NewDeploymentService(depRepo, appRepo)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
 	ctx := context.Background()
 
 	app := domain.NewApplication("latest-app", "", "", "", domain.ProviderAWS)
 	appRepo.Create(ctx, app)
 
-	first, _ := svc.Deploy(ctx, app.ID, "first", "main", nil)
+	first, _ := svc.Deploy(ctx, app.ID, "first", "main", nil, DeployOptions{})
 	// Push the first deployment's timestamp back so "second" is clearly newer
 	first.StartedAt = first.StartedAt.Add(-time.Minute)
 	depRepo.Update(ctx, first)
 
-	second, _ := svc.Deploy(ctx, app.ID, "second", "main", nil)
+	second, _ := svc.Deploy(ctx, app.ID, "second", "main", nil, DeployOptions{})
 
 	latest, err := svc.GetLatest(ctx, app.ID)
 	if err != nil {
@@ -152,3 +234,361 @@ func TestDeploymentService_GetLatest(t *testing.T) {
 		t.Errorf("GitCommit = %q, want %q", latest.GitCommit, second.GitCommit)
 	}
 }
+
+func TestDeploymentService_Rollback(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	depRepo := mock.NewDeploymentRepo()
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
+	ctx := context.Background()
+
+	app := domain.NewApplication("rollback-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	good, _ := svc.Deploy(ctx, app.ID, "good123", "main", nil, DeployOptions{})
+	good, _ = svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "assets" {}`)
+
+	t.Run("creates pending rollback", func(t *testing.T) {
+		d, err := svc.Rollback(ctx, good.ID, DeployOptions{})
+		if err != nil {
+			t.Fatalf("Rollback() error = %v", err)
+		}
+		if d.Status != domain.DeploymentPending {
+			t.Errorf("Status = %q, want %q", d.Status, domain.DeploymentPending)
+		}
+		if d.RollbackOf == nil || *d.RollbackOf != good.ID {
+			t.Errorf("RollbackOf = %v, want %v", d.RollbackOf, good.ID)
+		}
+		if d.TerraformPlan != good.TerraformPlan {
+			t.Errorf("TerraformPlan = %q, want %q", d.TerraformPlan, good.TerraformPlan)
+		}
+		if _, err := svc.GetStatus(ctx, d.ID); err != nil {
+			t.Errorf("rollback deployment not persisted: %v", err)
+		}
+	})
+
+	t.Run("refuses failed deployment", func(t *testing.T) {
+		bad, _ := svc.Deploy(ctx, app.ID, "bad456", "main", nil, DeployOptions{})
+		svc.MarkFailed(ctx, bad.ID)
+
+		_, err := svc.Rollback(ctx, bad.ID, DeployOptions{})
+		if !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+
+	t.Run("refuses while another deployment is running", func(t *testing.T) {
+		running, _ := svc.Deploy(ctx, app.ID, "run789", "main", nil, DeployOptions{})
+		svc.locks.tryLock(app.ID, running.ID)
+		defer svc.locks.unlock(app.ID)
+
+		_, err := svc.Rollback(ctx, good.ID, DeployOptions{})
+		if !errors.Is(err, domain.ErrDeploymentInProgress) {
+			t.Errorf("got %v, want ErrDeploymentInProgress", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := svc.Rollback(ctx, uuid.New(), DeployOptions{})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDeploymentService_ExecuteRollback(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	ctx := context.Background()
+
+	var appliedHCL string
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{
+		ProviderVal: domain.ProviderAWS,
+		ApplyTerraformFn: func(_ context.Context, hcl string) (string, error) {
+			appliedHCL = hcl
+			return "Apply complete!", nil
+		},
+	})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, mock.NewFreezeWindowRepo())
+
+	app := domain.NewApplication("exec-rollback-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	good, _ := svc.Deploy(ctx, app.ID, "good123", "main", nil, DeployOptions{})
+	good, _ = svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "v1" {}`)
+
+	// Current resources differ from what was deployed; the rollback must not regenerate.
+	res := domain.NewResource(app.ID, domain.ResourceStorage, "v2", json.RawMessage(`{}`))
+	res.ProviderMappings[domain.ProviderAWS] = domain.ProviderResource{TerraformHCL: `resource "aws_s3_bucket" "v2" {}`}
+	resRepo.Create(ctx, res)
+
+	rb, err := svc.Rollback(ctx, good.ID, DeployOptions{})
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	events := make(chan domain.DeploymentEvent, 64)
+	go svc.Execute(ctx, rb.ID, infra, false, events)
+	var last domain.DeploymentEvent
+	for ev := range events {
+		last = ev
+	}
+
+	if last.Step != domain.StepComplete {
+		t.Fatalf("last step = %q (%s), want complete", last.Step, last.Message)
+	}
+	if appliedHCL != good.TerraformPlan {
+		t.Errorf("applied HCL = %q, want %q", appliedHCL, good.TerraformPlan)
+	}
+	got, _ := svc.GetStatus(ctx, rb.ID)
+	if got.Status != domain.DeploymentSucceeded {
+		t.Errorf("Status = %q, want %q", got.Status, domain.DeploymentSucceeded)
+	}
+}
+
+func TestDeploymentRunner_AppliesPlanSnapshot(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	planRepo := mock.NewPlanRepo()
+	depRepo := mock.NewDeploymentRepo()
+	ctx := context.Background()
+
+	var appliedHCL string
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{
+		ProviderVal: domain.ProviderAWS,
+		ApplyTerraformFn: func(_ context.Context, hcl string) (string, error) {
+			appliedHCL = hcl
+			return "Apply complete!", nil
+		},
+	})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewDeploymentService(depRepo, appRepo, planRepo, resRepo, mock.NewFreezeWindowRepo())
+
+	app := domain.NewApplication("snapshot-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	planned := domain.NewResource(app.ID, domain.ResourceStorage, "planned", json.RawMessage(`{}`))
+	planned.ProviderMappings[domain.ProviderAWS] = domain.ProviderResource{TerraformHCL: `resource "aws_s3_bucket" "planned" {}`}
+	resRepo.Create(ctx, planned)
+	plan := domain.NewHostingPlan(app.ID, "content", []domain.Resource{planned}, nil)
+	planRepo.Create(ctx, plan)
+
+	// A resource added after the plan must not be applied by a forced deploy.
+	extra := domain.NewResource(app.ID, domain.ResourceStorage, "extra", json.RawMessage(`{}`))
+	extra.ProviderMappings[domain.ProviderAWS] = domain.ProviderResource{TerraformHCL: `resource "aws_s3_bucket" "extra" {}`}
+	resRepo.Create(ctx, extra)
+
+	d, err := svc.Deploy(ctx, app.ID, "abc", "main", &plan.ID, DeployOptions{Force: true})
+	if err != nil {
+		t.Fatalf("Deploy() error = %v", err)
+	}
+	if err := infra.newRunner(noopEmit, noPause).run(ctx, &d); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if !strings.Contains(appliedHCL, `"planned"`) || strings.Contains(appliedHCL, `"extra"`) {
+		t.Errorf("applied HCL should contain only the plan snapshot, got:\n%s", appliedHCL)
+	}
+	got, _ := svc.GetStatus(ctx, d.ID)
+	if got.ConfigHash != domain.HashConfig(appliedHCL) {
+		t.Errorf("ConfigHash = %q, want hash of applied HCL", got.ConfigHash)
+	}
+}
+
+func TestDeploymentService_Destroy(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	depRepo := mock.NewDeploymentRepo()
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), mock.NewFreezeWindowRepo())
+	ctx := context.Background()
+
+	app := domain.NewApplication("destroy-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	t.Run("nothing deployed", func(t *testing.T) {
+		_, err := svc.Destroy(ctx, app.ID, "destroy-app", DeployOptions{})
+		if !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+
+	good, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
+	good, _ = svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "b" {}`)
+
+	t.Run("confirmation mismatch", func(t *testing.T) {
+		_, err := svc.Destroy(ctx, app.ID, "wrong-name", DeployOptions{})
+		if !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+
+	t.Run("prevent_destroy refuses", func(t *testing.T) {
+		current, _ := appRepo.GetByID(ctx, app.ID)
+		protected := current
+		protected.PreventDestroy = true
+		appRepo.Update(ctx, protected)
+		defer appRepo.Update(ctx, current)
+
+		_, err := svc.Destroy(ctx, app.ID, "destroy-app", DeployOptions{})
+		if !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+
+	t.Run("creates pending destroy deployment", func(t *testing.T) {
+		d, err := svc.Destroy(ctx, app.ID, "destroy-app", DeployOptions{})
+		if err != nil {
+			t.Fatalf("Destroy() error = %v", err)
+		}
+		if d.Type != domain.DeploymentTypeDestroy {
+			t.Errorf("Type = %q, want %q", d.Type, domain.DeploymentTypeDestroy)
+		}
+		if d.Status != domain.DeploymentPending {
+			t.Errorf("Status = %q, want %q", d.Status, domain.DeploymentPending)
+		}
+		if d.TerraformPlan != good.TerraformPlan {
+			t.Errorf("TerraformPlan = %q, want %q", d.TerraformPlan, good.TerraformPlan)
+		}
+	})
+}
+
+func TestDeploymentService_ExecuteDestroy(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	ctx := context.Background()
+
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, mock.NewFreezeWindowRepo())
+
+	app := domain.NewApplication("exec-destroy-app", "", "", "", domain.ProviderAWS)
+	app.Status = domain.AppStatusDeployed
+	appRepo.Create(ctx, app)
+
+	good, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
+	svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "b" {}`)
+
+	d, err := svc.Destroy(ctx, app.ID, app.Name, DeployOptions{})
+	if err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+
+	events := make(chan domain.DeploymentEvent, 64)
+	go svc.Execute(ctx, d.ID, infra, false, events)
+	var last domain.DeploymentEvent
+	for ev := range events {
+		last = ev
+	}
+
+	if last.Step != domain.StepComplete {
+		t.Fatalf("last step = %q (%s), want complete", last.Step, last.Message)
+	}
+	got, _ := svc.GetStatus(ctx, d.ID)
+	if got.Status != domain.DeploymentSucceeded {
+		t.Errorf("Status = %q, want %q", got.Status, domain.DeploymentSucceeded)
+	}
+	updated, _ := appRepo.GetByID(ctx, app.ID)
+	if updated.Status != domain.AppStatusDraft {
+		t.Errorf("app Status = %q, want %q", updated.Status, domain.AppStatusDraft)
+	}
+
+	// Once destroyed there is nothing left to destroy.
+	if _, err := svc.Destroy(ctx, app.ID, app.Name, DeployOptions{}); !domain.IsValidationError(err) {
+		t.Errorf("second Destroy() = %v, want validation error", err)
+	}
+}
+
+func TestDeploymentService_RecoverInterrupted(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	ctx := context.Background()
+
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, mock.NewFreezeWindowRepo())
+
+	// The server died while destroying crashed-app, leaving the destroy in
+	// progress and the application destroying.
+	crashed := domain.NewApplication("crashed-app", "", "", "", domain.ProviderAWS)
+	crashed.Status = domain.AppStatusDeployed
+	appRepo.Create(ctx, crashed)
+	good, _ := svc.Deploy(ctx, crashed.ID, "abc", "main", nil, DeployOptions{})
+	svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "b" {}`)
+	destroy, err := svc.Destroy(ctx, crashed.ID, crashed.Name, DeployOptions{})
+	if err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+	destroy.Status, destroy.StartedAt = domain.DeploymentInProgress, time.Now().UTC().Add(-2*time.Hour)
+	depRepo.Update(ctx, destroy)
+	crashed.Status = domain.AppStatusDestroying
+	appRepo.Update(ctx, crashed)
+
+	// A run that started moments ago may still be going on another replica.
+	live := domain.NewApplication("live-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, live)
+	running, _ := svc.Deploy(ctx, live.ID, "abc", "main", nil, DeployOptions{})
+	running.Status = domain.DeploymentInProgress
+	depRepo.Update(ctx, running)
+
+	n, err := svc.RecoverInterrupted(ctx, infra, time.Hour)
+	if err != nil {
+		t.Fatalf("RecoverInterrupted() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("RecoverInterrupted() = %d, want 1", n)
+	}
+	if got, _ := depRepo.GetByID(ctx, destroy.ID); got.Status != domain.DeploymentFailed || !strings.Contains(got.FailureReason, "Interrupted") {
+		t.Errorf("destroy = %s (%q), want failed as interrupted", got.Status, got.FailureReason)
+	}
+	if got, _ := appRepo.GetByID(ctx, crashed.ID); got.Status != domain.AppStatusDegraded {
+		t.Errorf("app Status = %q, want %q", got.Status, domain.AppStatusDegraded)
+	}
+	if got, _ := depRepo.GetByID(ctx, running.ID); got.Status != domain.DeploymentInProgress {
+		t.Errorf("recent deployment = %s, want it left in progress", got.Status)
+	}
+
+	// The degraded application can be destroyed again.
+	if _, err := svc.Destroy(ctx, crashed.ID, crashed.Name, DeployOptions{}); err != nil {
+		t.Errorf("Destroy() after recovery error = %v", err)
+	}
+}
+
+func TestDeploymentRunner_DestroyFailureDegradesApp(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	ctx := context.Background()
+
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS, DestroyErr: errors.New("bucket not empty")})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, mock.NewFreezeWindowRepo())
+
+	app := domain.NewApplication("destroy-fail-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	good, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
+	svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "b" {}`)
+	if got, _ := appRepo.GetByID(ctx, app.ID); got.Status != domain.AppStatusDeployed {
+		t.Fatalf("app Status after MarkSucceeded = %q, want %q", got.Status, domain.AppStatusDeployed)
+	}
+
+	d, err := svc.Destroy(ctx, app.ID, app.Name, DeployOptions{})
+	if err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+	if err := infra.newRunner(noopEmit, noPause).run(ctx, &d); err == nil {
+		t.Fatal("expected destroy to fail")
+	}
+
+	updated, _ := appRepo.GetByID(ctx, app.ID)
+	if updated.Status != domain.AppStatusDegraded {
+		t.Errorf("app Status = %q, want %q", updated.Status, domain.AppStatusDegraded)
+	}
+}
@@ -20,32 +20,48 @@ import (
 
 // DiscoveryService handles live cloud resource discovery.
 type DiscoveryService struct {
-	apps     repository.ApplicationRepo
-	llm      llm.Client
-	executor *executor.Executor
-	assets   *gcpcloud.AssetClient // nil if GCP credentials unavailable
+	apps      repository.ApplicationRepo
+	resources repository.ResourceRepo
+	llm       llm.Client
+	executor  *executor.Executor
+	assets    *gcpcloud.AssetClient // nil if GCP credentials unavailable
+	outbox    *Outbox               // optional
+	rbac      *RBACService          // optional
 }
 
 // NewDiscoveryService creates a new DiscoveryService.
 // assetClient may be nil if GCP Cloud Asset Inventory is not available.
-func NewDiscoveryService(apps repository.ApplicationRepo, llmClient llm.Client, assetClient *gcpcloud.AssetClient) *DiscoveryService {
+func NewDiscoveryService(apps repository.ApplicationRepo, resources repository.ResourceRepo, llmClient llm.Client, assetClient *gcpcloud.AssetClient) *DiscoveryService {
 	return &DiscoveryService{
-		apps:     apps,
-		llm:      llmClient,
-		executor: executor.NewExecutor(),
-		assets:   assetClient,
+		apps:      apps,
+		resources: resources,
+		llm:       llmClient,
+		executor:  executor.NewExecutor(),
+		assets:    assetClient,
 	}
 }
 
+// SetOutbox makes discovery record the drift it finds in o. A nil outbox
+// disables events.
+func (s *DiscoveryService) SetOutbox(o *Outbox) { s.outbox = o }
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *DiscoveryService) SetRBAC(r *RBACService) { s.rbac = r }
+
 // DiscoverLiveResources performs the full discovery pipeline:
 // Phase A: LLM analyzes deploy scripts → generates CLI commands → executes → LLM parses results
 // Phase B: GCP Cloud Asset Inventory scans the project for comprehensive coverage
-// Results are merged and deduplicated.
+// Results are merged and deduplicated, then compared with the application's
+// declared resources to find drift.
 func (s *DiscoveryService) DiscoverLiveResources(ctx context.Context, appID uuid.UUID) (domain.LiveResourceResult, error) {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
 		return domain.LiveResourceResult{}, fmt.Errorf("get application: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, app.ID); err != nil {
+		return domain.LiveResourceResult{}, err
+	}
 
 	if app.SourcePath == "" {
 		return domain.LiveResourceResult{}, domain.ErrValidation("application has no source path configured")
@@ -76,11 +92,28 @@ func (s *DiscoveryService) DiscoverLiveResources(ctx context.Context, appID uuid
 		}
 	}
 
-	return domain.LiveResourceResult{
+	result := domain.LiveResourceResult{
 		Resources: allResources,
 		Errors:    nonFatalErrors,
 		Timestamp: now,
-	}, nil
+	}
+
+	// Drift is only meaningful when discovery found something; otherwise
+	// every declared resource would look missing
+	if len(allResources) > 0 {
+		declared, err := s.resources.ListByApplicationID(ctx, appID)
+		if err != nil {
+			return domain.LiveResourceResult{}, fmt.Errorf("list resources: %w", err)
+		}
+		result.Drift = domain.DetectDrift(declared, allResources)
+		if len(result.Drift) > 0 {
+			if err := s.outbox.record(ctx, domain.EventLiveDriftDetected, app.ID, result.Drift); err != nil {
+				log.Printf("discovery: record drift for %s: %v", app.Name, err)
+			}
+		}
+	}
+
+	return result, nil
 }
 
 // targetedDiscovery uses the LLM to analyze deploy scripts, generate CLI commands,
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// EventHandler receives domain events published on an EventBus.
+type EventHandler func(ctx context.Context, e domain.Event)
+
+// EventBus fans domain events out to in-process subscribers. Handlers run
+// synchronously in subscription order, so anything slow should be handed
+// off to a goroutine.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+// NewEventBus creates an EventBus with no subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers h to receive every event published from now on.
+func (b *EventBus) Subscribe(h EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish delivers e to every subscriber.
+func (b *EventBus) Publish(ctx context.Context, e domain.Event) {
+	b.mu.RLock()
+	handlers := append([]EventHandler(nil), b.handlers...)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(ctx, e)
+	}
+}
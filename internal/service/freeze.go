@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+)
+
+// FreezeService manages deployment freeze windows and the change calendar
+// derived from them.
+type FreezeService struct {
+	windows repository.FreezeWindowRepo
+	apps    repository.ApplicationRepo
+	rbac    *RBACService  // optional
+	audit   *AuditService // optional
+}
+
+// NewFreezeService creates a new FreezeService.
+func NewFreezeService(windows repository.FreezeWindowRepo, apps repository.ApplicationRepo) *FreezeService {
+	return &FreezeService{windows: windows, apps: apps}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *FreezeService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *FreezeService) SetAudit(a *AuditService) { s.audit = a }
+
+// Create adds a freeze window. appID scopes it to one application and
+// environment to one branch; leave both empty to freeze every application of
+// the request's organization.
+func (s *FreezeService) Create(ctx context.Context, name, reason string, appID *uuid.UUID, environment string, rule domain.FreezeRule) (domain.FreezeWindow, error) {
+	orgID := requestOrg(ctx)
+	if appID != nil {
+		app, err := s.apps.GetByID(ctx, *appID)
+		if err != nil {
+			return domain.FreezeWindow{}, fmt.Errorf("get application: %w", err)
+		}
+		orgID = app.OrgID
+	}
+	if err := s.rbac.require(ctx, domain.RoleAdmin, appID); err != nil {
+		return domain.FreezeWindow{}, err
+	}
+
+	w := domain.NewFreezeWindow(name, reason, orgID, appID, environment, rule)
+	if err := w.Validate(); err != nil {
+		return domain.FreezeWindow{}, err
+	}
+
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.windows.Create(ctx, w); err != nil {
+			return fmt.Errorf("create freeze window: %w", err)
+		}
+		return s.audit.record(ctx, "freeze_window.create", "freeze_window", w.ID.String(), appID, nil, w)
+	})
+	if err != nil {
+		return domain.FreezeWindow{}, err
+	}
+	return w, nil
+}
+
+// Get retrieves a freeze window by ID. It needs the viewer role on the
+// window's application, or globally for a global window.
+func (s *FreezeService) Get(ctx context.Context, id uuid.UUID) (domain.FreezeWindow, error) {
+	w, err := s.windows.GetByID(ctx, id)
+	if err != nil {
+		return domain.FreezeWindow{}, err
+	}
+	if err := s.rbac.require(ctx, domain.RoleViewer, w.ApplicationID); err != nil {
+		return domain.FreezeWindow{}, err
+	}
+	return w, nil
+}
+
+// List returns every freeze window of the request's organization.
+func (s *FreezeService) List(ctx context.Context) ([]domain.FreezeWindow, error) {
+	return s.windows.List(ctx)
+}
+
+// Delete removes a freeze window. Like creating it, this needs the admin role
+// on its application, or globally for a global window.
+func (s *FreezeService) Delete(ctx context.Context, id uuid.UUID) error {
+	w, err := s.windows.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.require(ctx, domain.RoleAdmin, w.ApplicationID); err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.windows.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "freeze_window.delete", "freeze_window", w.ID.String(), w.ApplicationID, w, nil)
+	})
+}
+
+// Calendar lists the freeze periods overlapping [from, to) in chronological
+// order. With appID set, only windows that can affect that application
+// (its organization's global ones and its own) are included.
+func (s *FreezeService) Calendar(ctx context.Context, appID *uuid.UUID, from, to time.Time) ([]domain.FreezeOccurrence, error) {
+	if !to.After(from) {
+		return nil, domain.ErrValidation("calendar end must be after its start")
+	}
+	var app *domain.Application
+	if appID != nil {
+		a, err := s.apps.GetByID(ctx, *appID)
+		if err != nil {
+			return nil, fmt.Errorf("get application: %w", err)
+		}
+		app = &a
+	}
+
+	windows, err := s.windows.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list freeze windows: %w", err)
+	}
+
+	occurrences := []domain.FreezeOccurrence{}
+	for _, w := range windows {
+		if app != nil && (w.OrgID != app.OrgID || (w.ApplicationID != nil && *w.ApplicationID != app.ID)) {
+			continue
+		}
+		occurrences = append(occurrences, w.Occurrences(from, to)...)
+	}
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].StartsAt.Before(occurrences[j].StartsAt)
+	})
+	return occurrences, nil
+}
+
+// Active returns the freeze periods blocking a deployment of app from
+// branch at t.
+func (s *FreezeService) Active(ctx context.Context, app domain.Application, branch string, t time.Time) ([]domain.FreezeOccurrence, error) {
+	return activeFreezes(ctx, s.windows, app, branch, t)
+}
+
+func activeFreezes(ctx context.Context, windows repository.FreezeWindowRepo, app domain.Application, branch string, t time.Time) ([]domain.FreezeOccurrence, error) {
+	all, err := windows.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list freeze windows: %w", err)
+	}
+
+	var active []domain.FreezeOccurrence
+	for _, w := range all {
+		if !w.AppliesTo(app, branch) {
+			continue
+		}
+		if occ, ok := w.ActiveAt(t); ok {
+			active = append(active, occ)
+		}
+	}
+	return active, nil
+}
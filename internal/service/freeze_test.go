@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/provider"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// activeNow returns a one-off rule covering the current time.
+func activeNow() domain.FreezeRule {
+	start := time.Now().UTC().Add(-time.Hour)
+	end := start.Add(2 * time.Hour)
+	return domain.FreezeRule{StartsAt: &start, EndsAt: &end}
+}
+
+func TestFreezeService_Create(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc := NewFreezeService(mock.NewFreezeWindowRepo(), appRepo)
+	ctx := context.Background()
+
+	app := domain.NewApplication("freeze-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	t.Run("per application", func(t *testing.T) {
+		w, err := svc.Create(ctx, "launch", "product launch", &app.ID, "", activeNow())
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if w.ApplicationID == nil || *w.ApplicationID != app.ID {
+			t.Errorf("ApplicationID = %v, want %v", w.ApplicationID, app.ID)
+		}
+	})
+
+	t.Run("unknown application", func(t *testing.T) {
+		missing := uuid.New()
+		if _, err := svc.Create(ctx, "launch", "", &missing, "", activeNow()); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		if _, err := svc.Create(ctx, "broken", "", nil, "", domain.FreezeRule{}); !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+}
+
+func TestFreezeService_GetNeedsViewer(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	svc := NewFreezeService(mock.NewFreezeWindowRepo(), appRepo)
+	svc.SetRBAC(rbac)
+	ctx := context.Background()
+
+	shop := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	blog := domain.NewApplication("blog", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, shop)
+	appRepo.Create(ctx, blog)
+	w, err := svc.Create(ctx, "launch", "", &shop.ID, "", activeNow())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if _, err := rbac.Grant(ctx, "alice", domain.RoleViewer, &blog.ID); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	alice := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalUser, Subject: "alice", Name: "alice"})
+	if _, err := svc.Get(alice, w.ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("Get(viewer of another app): got %v, want ErrForbidden", err)
+	}
+
+	if _, err := rbac.Grant(ctx, "alice", domain.RoleViewer, &shop.ID); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if got, err := svc.Get(alice, w.ID); err != nil || got.ID != w.ID {
+		t.Errorf("Get(viewer) = %+v, %v", got, err)
+	}
+}
+
+func TestFreezeService_Calendar(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc := NewFreezeService(mock.NewFreezeWindowRepo(), appRepo)
+	ctx := context.Background()
+
+	app := domain.NewApplication("calendar-app", "", "", "", domain.ProviderAWS)
+	other := domain.NewApplication("other-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	appRepo.Create(ctx, other)
+
+	start := time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	svc.Create(ctx, "holidays", "", nil, "", domain.FreezeRule{StartsAt: &start, EndsAt: &end})
+	svc.Create(ctx, "fridays", "", &app.ID, "", domain.FreezeRule{Cron: "0 16 * * fri", DurationMinutes: 8 * 60})
+	svc.Create(ctx, "other", "", &other.ID, "", domain.FreezeRule{Cron: "@daily", DurationMinutes: 60})
+
+	from := time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC) // Monday
+	to := from.AddDate(0, 0, 7)
+
+	occ, err := svc.Calendar(ctx, &app.ID, from, to)
+	if err != nil {
+		t.Fatalf("Calendar() error = %v", err)
+	}
+	if len(occ) != 2 {
+		t.Fatalf("len(Calendar) = %d, want 2 (holidays + one Friday): %+v", len(occ), occ)
+	}
+	if occ[0].Name != "holidays" || occ[1].Name != "fridays" {
+		t.Errorf("order = %s, %s; want holidays, fridays", occ[0].Name, occ[1].Name)
+	}
+
+	all, _ := svc.Calendar(ctx, nil, from, to)
+	if len(all) != 9 {
+		t.Errorf("len(Calendar all) = %d, want 9", len(all))
+	}
+
+	if _, err := svc.Calendar(ctx, nil, to, from); !domain.IsValidationError(err) {
+		t.Errorf("reversed range: got %v, want validation error", err)
+	}
+
+	// Windows of another organization never apply.
+	acme := tenant.WithOrg(ctx, domain.NewOrganization("acme", ""))
+	if _, err := svc.Create(acme, "acme-holidays", "", nil, "", domain.FreezeRule{StartsAt: &start, EndsAt: &end}); err != nil {
+		t.Fatalf("Create(acme) error = %v", err)
+	}
+	if occ, _ := svc.Calendar(ctx, &app.ID, from, to); len(occ) != 2 {
+		t.Errorf("len(Calendar) after acme window = %d, want 2", len(occ))
+	}
+	if scoped, _ := svc.Calendar(acme, nil, from, to); len(scoped) != 1 || scoped[0].Name != "acme-holidays" {
+		t.Errorf("Calendar(acme) = %+v, want only acme-holidays", scoped)
+	}
+	if active, _ := svc.Active(ctx, app, "main", start.Add(time.Hour)); len(active) != 1 || active[0].Name != "holidays" {
+		t.Errorf("Active() = %+v, want only holidays", active)
+	}
+}
+
+// overriddenWindows returns the names of the freeze windows the break-glass
+// audit entry for deployment depID records, failing t if there is none.
+func overriddenWindows(t *testing.T, auditSvc *AuditService, appID, depID uuid.UUID) []string {
+	t.Helper()
+	entries, err := auditSvc.List(context.Background(), domain.AuditFilter{ApplicationID: &appID})
+	if err != nil {
+		t.Fatalf("List audit entries error = %v", err)
+	}
+	for _, e := range entries {
+		if e.Action != "deployment.break_glass" || e.TargetID != depID.String() {
+			continue
+		}
+		var after breakGlassOverride
+		if err := json.Unmarshal(e.After, &after); err != nil {
+			t.Fatalf("unmarshal break-glass entry: %v", err)
+		}
+		names := make([]string, len(after.FreezeWindows))
+		for i, w := range after.FreezeWindows {
+			names[i] = w.Name
+		}
+		return names
+	}
+	t.Fatalf("no deployment.break_glass audit entry for deployment %s", depID)
+	return nil
+}
+
+func TestDeploymentService_DeployFrozen(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	depRepo := mock.NewDeploymentRepo()
+	freezeRepo := mock.NewFreezeWindowRepo()
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), freezeRepo)
+	freezes := NewFreezeService(freezeRepo, appRepo)
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	svc.SetAudit(auditSvc)
+	ctx := context.Background()
+
+	app := domain.NewApplication("frozen-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	freezes.Create(ctx, "prod freeze", "launch week", nil, "production", activeNow())
+
+	t.Run("other environment deploys", func(t *testing.T) {
+		if _, err := svc.Deploy(ctx, app.ID, "abc", "staging", nil, DeployOptions{}); err != nil {
+			t.Errorf("Deploy(staging) error = %v", err)
+		}
+	})
+
+	t.Run("frozen environment is refused", func(t *testing.T) {
+		_, err := svc.Deploy(ctx, app.ID, "abc", "production", nil, DeployOptions{})
+		if !errors.Is(err, domain.ErrDeploymentFrozen) {
+			t.Fatalf("got %v, want ErrDeploymentFrozen", err)
+		}
+	})
+
+	t.Run("break-glass is recorded", func(t *testing.T) {
+		d, err := svc.Deploy(ctx, app.ID, "abc", "production", nil, DeployOptions{BreakGlass: true})
+		if err != nil {
+			t.Fatalf("Deploy(break-glass) error = %v", err)
+		}
+		got, _ := depRepo.GetByID(ctx, d.ID)
+		if !got.BreakGlass {
+			t.Error("BreakGlass should be recorded on the deployment")
+		}
+		if names := overriddenWindows(t, auditSvc, app.ID, d.ID); len(names) != 1 || names[0] != "prod freeze" {
+			t.Errorf("audited freeze windows = %v, want [prod freeze]", names)
+		}
+	})
+}
+
+func TestDeploymentService_DestroyFrozen(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	depRepo := mock.NewDeploymentRepo()
+	freezeRepo := mock.NewFreezeWindowRepo()
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), freezeRepo)
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	svc.SetAudit(auditSvc)
+	ctx := context.Background()
+
+	app := domain.NewApplication("destroy-frozen-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	good, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
+	svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "b" {}`)
+	NewFreezeService(freezeRepo, appRepo).Create(ctx, "incident", "", &app.ID, "", activeNow())
+
+	if _, err := svc.Destroy(ctx, app.ID, app.Name, DeployOptions{}); !errors.Is(err, domain.ErrDeploymentFrozen) {
+		t.Fatalf("got %v, want ErrDeploymentFrozen", err)
+	}
+
+	d, err := svc.Destroy(ctx, app.ID, app.Name, DeployOptions{BreakGlass: true})
+	if err != nil {
+		t.Fatalf("Destroy(break-glass) error = %v", err)
+	}
+	if got, _ := depRepo.GetByID(ctx, d.ID); !got.BreakGlass {
+		t.Error("BreakGlass should be recorded on the destroy deployment")
+	}
+	if names := overriddenWindows(t, auditSvc, app.ID, d.ID); len(names) != 1 || names[0] != "incident" {
+		t.Errorf("audited freeze windows = %v, want [incident]", names)
+	}
+}
+
+func TestDeploymentService_RollbackFrozen(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	depRepo := mock.NewDeploymentRepo()
+	freezeRepo := mock.NewFreezeWindowRepo()
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), mock.NewResourceRepo(), freezeRepo)
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	svc.SetAudit(auditSvc)
+	ctx := context.Background()
+
+	app := domain.NewApplication("rollback-frozen-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	good, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
+	svc.MarkSucceeded(ctx, good.ID, `resource "aws_s3_bucket" "b" {}`)
+	NewFreezeService(freezeRepo, appRepo).Create(ctx, "incident", "", &app.ID, "", activeNow())
+
+	if _, err := svc.Rollback(ctx, good.ID, DeployOptions{}); !errors.Is(err, domain.ErrDeploymentFrozen) {
+		t.Fatalf("got %v, want ErrDeploymentFrozen", err)
+	}
+
+	d, err := svc.Rollback(ctx, good.ID, DeployOptions{BreakGlass: true})
+	if err != nil {
+		t.Fatalf("Rollback(break-glass) error = %v", err)
+	}
+	if got, _ := depRepo.GetByID(ctx, d.ID); !got.BreakGlass {
+		t.Error("BreakGlass should be recorded on the rollback deployment")
+	}
+	if names := overriddenWindows(t, auditSvc, app.ID, d.ID); len(names) != 1 || names[0] != "incident" {
+		t.Errorf("audited freeze windows = %v, want [incident]", names)
+	}
+}
+
+func TestDeploymentService_ExecuteFrozen(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	freezeRepo := mock.NewFreezeWindowRepo()
+	ctx := context.Background()
+
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, freezeRepo)
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	svc.SetAudit(auditSvc)
+	infra.SetAudit(auditSvc)
+
+	app := domain.NewApplication("exec-frozen-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	// Created before the freeze began.
+	d, _ := svc.Deploy(ctx, app.ID, "abc", "main", nil, DeployOptions{})
+	NewFreezeService(freezeRepo, appRepo).Create(ctx, "incident", "", &app.ID, "", activeNow())
+
+	run := func(breakGlass bool) domain.DeploymentEvent {
+		events := make(chan domain.DeploymentEvent, 64)
+		go svc.Execute(ctx, d.ID, infra, breakGlass, events)
+		var last domain.DeploymentEvent
+		for ev := range events {
+			last = ev
+		}
+		return last
+	}
+
+	last := run(false)
+	if last.Step != domain.StepFailed || last.Status != domain.DeploymentPending {
+		t.Fatalf("last event = %s/%s (%s), want failed step with pending status", last.Step, last.Status, last.Message)
+	}
+	if got, _ := depRepo.GetByID(ctx, d.ID); got.Status != domain.DeploymentPending {
+		t.Fatalf("Status = %q, want pending while frozen", got.Status)
+	}
+
+	if last = run(true); last.Step != domain.StepComplete {
+		t.Fatalf("break-glass run: last step = %q (%s), want complete", last.Step, last.Message)
+	}
+	got, _ := depRepo.GetByID(ctx, d.ID)
+	if got.Status != domain.DeploymentSucceeded || !got.BreakGlass {
+		t.Errorf("Status = %q, BreakGlass = %v; want succeeded with break-glass recorded", got.Status, got.BreakGlass)
+	}
+	if names := overriddenWindows(t, auditSvc, app.ID, d.ID); len(names) != 1 || names[0] != "incident" {
+		t.Errorf("audited freeze windows = %v, want [incident]", names)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
+)
+
+// GitPushService turns authenticated git push webhooks into deployments. A
+// push deploys every application whose GitRepoURL matches the pushed
+// repository and whose DeployBranch matches the pushed branch.
+type GitPushService struct {
+	apps        *ApplicationService
+	deployments *DeploymentService
+	infra       *InfraService
+	secrets     webhook.Secrets
+	inflight    sync.WaitGroup
+}
+
+// NewGitPushService creates a new GitPushService. Webhooks from a source whose
+// secret is empty are rejected with webhook.ErrNotConfigured.
+func NewGitPushService(apps *ApplicationService, deployments *DeploymentService, infra *InfraService, secrets webhook.Secrets) *GitPushService {
+	return &GitPushService{
+		apps:        apps,
+		deployments: deployments,
+		infra:       infra,
+		secrets:     secrets,
+	}
+}
+
+// PushResult reports what a push webhook did.
+type PushResult struct {
+	Event       webhook.PushEvent   `json:"event"`
+	Deployments []domain.Deployment `json:"deployments"`
+	Reanalyzed  []string            `json:"reanalyzed,omitempty"` // applications whose source was re-analyzed first
+	Errors      []string            `json:"errors,omitempty"`     // per-application failures; other applications still deploy
+}
+
+// HandleGitHub verifies and handles a GitHub push delivery. signature is the
+// X-Hub-Signature-256 header and eventType the X-GitHub-Event header.
+func (s *GitPushService) HandleGitHub(ctx context.Context, eventType, signature string, body []byte, reanalyze bool) (PushResult, error) {
+	if err := webhook.VerifyGitHubSignature(s.secrets.GitHub, body, signature); err != nil {
+		return PushResult{}, err
+	}
+	event, err := webhook.ParseGitHubPush(eventType, body)
+	if err != nil {
+		return PushResult{}, err
+	}
+	return s.handlePush(ctx, event, reanalyze)
+}
+
+// HandleGitLab verifies and handles a GitLab push delivery. token is the
+// X-Gitlab-Token header and eventType the X-Gitlab-Event header.
+func (s *GitPushService) HandleGitLab(ctx context.Context, eventType, token string, body []byte, reanalyze bool) (PushResult, error) {
+	if err := webhook.VerifyGitLabToken(s.secrets.GitLab, token); err != nil {
+		return PushResult{}, err
+	}
+	event, err := webhook.ParseGitLabPush(eventType, body)
+	if err != nil {
+		return PushResult{}, err
+	}
+	return s.handlePush(ctx, event, reanalyze)
+}
+
+// handlePush deploys the pushed commit to each matching application. The
+// deployments are returned pending and run in the background like scheduled
+// deploys, through Execute's guards; a frozen deployment stays pending. With
+// reanalyze set, applications with a source path have their codebase
+// re-analyzed first so newly needed resources are added before the
+// deployment is created.
+func (s *GitPushService) handlePush(ctx context.Context, event webhook.PushEvent, reanalyze bool) (PushResult, error) {
+	result := PushResult{Event: event, Deployments: []domain.Deployment{}}
+
+	apps, err := s.apps.List(ctx)
+	if err != nil {
+		return PushResult{}, fmt.Errorf("list applications: %w", err)
+	}
+
+	repos := make(map[string]bool, len(event.RepoURLs))
+	for _, u := range event.RepoURLs {
+		repos[webhook.NormalizeRepoURL(u)] = true
+	}
+
+	for _, app := range apps {
+		if app.DeployBranch != event.Branch || app.GitRepoURL == "" || !repos[webhook.NormalizeRepoURL(app.GitRepoURL)] {
+			continue
+		}
+
+		if reanalyze && app.SourcePath != "" {
+			if err := s.apps.ReanalyzeSource(ctx, app.ID); err != nil {
+				log.Printf("[gitpush] reanalyze %s: %v", app.Name, err)
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: reanalyze: %v", app.Name, err))
+			} else {
+				result.Reanalyzed = append(result.Reanalyzed, app.Name)
+			}
+		}
+
+		d, err := s.deployments.Deploy(ctx, app.ID, event.Commit, event.Branch, nil, DeployOptions{})
+		if err != nil {
+			log.Printf("[gitpush] deploy %s@%s for %s: %v", event.Branch, event.Commit, app.Name, err)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: deploy: %v", app.Name, err))
+			continue
+		}
+		result.Deployments = append(result.Deployments, d)
+
+		// The run outlives the webhook request.
+		s.inflight.Add(1)
+		go func(name string, d domain.Deployment) {
+			defer s.inflight.Done()
+			if _, err := s.deployments.executeSync(context.WithoutCancel(ctx), d.ID, s.infra, false); err != nil {
+				log.Printf("[gitpush] run %s for %s: %v", d.ID, name, err)
+			}
+		}(app.Name, d)
+	}
+
+	return result, nil
+}
+
+// Wait blocks until the deployments started by pushes have finished.
+func (s *GitPushService) Wait() {
+	s.inflight.Wait()
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/analyzer"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/provider"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
+)
+
+const testGitHubSecret = "gh-secret"
+
+func githubPushBody(repoURL, branch, commit string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"ref":   "refs/heads/" + branch,
+		"after": commit,
+		"repository": map[string]string{
+			"html_url": repoURL,
+			"ssh_url":  "git@github.com:acme/shop.git",
+		},
+	})
+	return body
+}
+
+func githubSignature(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testGitHubSecret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitPushService_HandleGitHub(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	ctx := context.Background()
+
+	mockLLM := &llm.MockClient{
+		AnalyzeCodebaseFn: func(_ context.Context, _ analyzer.CodeContext, _ domain.CloudProvider) ([]llm.ResourceRecommendation, error) {
+			return []llm.ResourceRecommendation{{Kind: domain.ResourceCache, Name: "sessions", Spec: json.RawMessage(`{}`)}}, nil
+		},
+	}
+	appSvc := NewApplicationService(appRepo, resRepo, mockLLM, nil)
+	depSvc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, mock.NewFreezeWindowRepo())
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS})
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderGCP})
+	infra := NewInfraService(appRepo, resRepo, depRepo, reg)
+	svc := NewGitPushService(appSvc, depSvc, infra, webhook.Secrets{GitHub: testGitHubSecret})
+
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module example.com/shop"), 0644)
+
+	// Two applications track main of the same repository (in different URL
+	// forms); one tracks a different branch; one has auto-deploy disabled.
+	api := domain.NewApplication("shop-api", "", "https://github.com/acme/shop.git", tmpDir, domain.ProviderAWS)
+	api.DeployBranch = "main"
+	web := domain.NewApplication("shop-web", "", "git@github.com:Acme/shop.git", "", domain.ProviderGCP)
+	web.DeployBranch = "main"
+	staging := domain.NewApplication("shop-staging", "", "https://github.com/acme/shop", "", domain.ProviderAWS)
+	staging.DeployBranch = "staging"
+	manual := domain.NewApplication("shop-manual", "", "https://github.com/acme/shop", "", domain.ProviderAWS)
+	for _, app := range []domain.Application{api, web, staging, manual} {
+		appRepo.Create(ctx, app)
+	}
+
+	t.Run("deploys matching applications", func(t *testing.T) {
+		body := githubPushBody("https://github.com/acme/shop", "main", "abc123")
+		result, err := svc.HandleGitHub(ctx, "push", githubSignature(body), body, false)
+		if err != nil {
+			t.Fatalf("HandleGitHub() error = %v", err)
+		}
+		if len(result.Deployments) != 2 {
+			t.Fatalf("len(Deployments) = %d, want 2", len(result.Deployments))
+		}
+		for _, d := range result.Deployments {
+			if d.ApplicationID != api.ID && d.ApplicationID != web.ID {
+				t.Errorf("unexpected deployment for application %s", d.ApplicationID)
+			}
+			if d.GitCommit != "abc123" || d.GitBranch != "main" || d.Status != domain.DeploymentPending {
+				t.Errorf("deployment = %s@%s (%s), want pending main@abc123", d.GitBranch, d.GitCommit, d.Status)
+			}
+		}
+		if len(result.Reanalyzed) != 0 {
+			t.Errorf("Reanalyzed = %v, want none without reanalyze", result.Reanalyzed)
+		}
+
+		// The deployments run without anyone opening their streams.
+		svc.Wait()
+		for _, d := range result.Deployments {
+			if got, _ := depRepo.GetByID(ctx, d.ID); got.Status != domain.DeploymentSucceeded {
+				t.Errorf("deployment %s is %s, want succeeded", d.ID, got.Status)
+			}
+		}
+	})
+
+	t.Run("reanalyze adds new resources before deploying", func(t *testing.T) {
+		body := githubPushBody("https://github.com/acme/shop", "main", "def456")
+		result, err := svc.HandleGitHub(ctx, "push", githubSignature(body), body, true)
+		if err != nil {
+			t.Fatalf("HandleGitHub() error = %v", err)
+		}
+		svc.Wait()
+		if len(result.Reanalyzed) != 1 || result.Reanalyzed[0] != "shop-api" {
+			t.Errorf("Reanalyzed = %v, want [shop-api]", result.Reanalyzed)
+		}
+
+		// A second push must not duplicate the detected resource.
+		body = githubPushBody("https://github.com/acme/shop", "main", "ghi789")
+		if _, err := svc.HandleGitHub(ctx, "push", githubSignature(body), body, true); err != nil {
+			t.Fatalf("HandleGitHub() error = %v", err)
+		}
+		svc.Wait()
+		resources, _ := resRepo.ListByApplicationID(ctx, api.ID)
+		if len(resources) != 1 || resources[0].Name != "sessions" {
+			t.Errorf("resources = %v, want exactly one 'sessions' resource", resources)
+		}
+	})
+
+	t.Run("other branch", func(t *testing.T) {
+		body := githubPushBody("https://github.com/acme/shop", "feature-x", "abc123")
+		result, err := svc.HandleGitHub(ctx, "push", githubSignature(body), body, false)
+		if err != nil {
+			t.Fatalf("HandleGitHub() error = %v", err)
+		}
+		if len(result.Deployments) != 0 {
+			t.Errorf("len(Deployments) = %d, want 0", len(result.Deployments))
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		body := githubPushBody("https://github.com/acme/shop", "main", "abc123")
+		_, err := svc.HandleGitHub(ctx, "push", "sha256=00", body, false)
+		if !errors.Is(err, webhook.ErrInvalidSignature) {
+			t.Errorf("got %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("gitlab not configured", func(t *testing.T) {
+		_, err := svc.HandleGitLab(ctx, "Push Hook", "anything", []byte(`{}`), false)
+		if !errors.Is(err, webhook.ErrNotConfigured) {
+			t.Errorf("got %v, want ErrNotConfigured", err)
+		}
+	})
+}
@@ -16,6 +16,8 @@ type GraphService struct {
 	apps      repository.ApplicationRepo
 	resources repository.ResourceRepo
 	llm       llm.Client
+	rbac      *RBACService  // optional
+	audit     *AuditService // optional
 }
 
 // NewGraphService creates a new GraphService.
@@ -28,12 +30,23 @@ func NewGraphService(graphs repository.GraphRepo, apps repository.ApplicationRep
 	}
 }
 
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *GraphService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *GraphService) SetAudit(a *AuditService) { s.audit = a }
+
 // GenerateGraph creates an LLM-powered infrastructure topology graph for an application.
 func (s *GraphService) GenerateGraph(ctx context.Context, appID uuid.UUID) (domain.InfraGraph, error) {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
 		return domain.InfraGraph{}, fmt.Errorf("get application: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, app.ID); err != nil {
+		return domain.InfraGraph{}, err
+	}
 
 	resources, err := s.resources.ListByApplicationID(ctx, appID)
 	if err != nil {
@@ -46,8 +59,15 @@ func (s *GraphService) GenerateGraph(ctx context.Context, appID uuid.UUID) (doma
 	}
 
 	graph := domain.NewInfraGraph(appID, result.Nodes, result.Edges)
-	if err := s.graphs.Create(ctx, graph); err != nil {
-		return domain.InfraGraph{}, fmt.Errorf("save graph: %w", err)
+	graph.PromptVersion = llm.PromptVersion(llm.PromptGraph)
+	err = s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.graphs.Create(ctx, graph); err != nil {
+			return fmt.Errorf("save graph: %w", err)
+		}
+		return s.audit.record(ctx, "graph.generate", "graph", graph.ID.String(), &appID, nil, graph)
+	})
+	if err != nil {
+		return domain.InfraGraph{}, err
 	}
 
 	return graph, nil
@@ -55,5 +75,8 @@ func (s *GraphService) GenerateGraph(ctx context.Context, appID uuid.UUID) (doma
 
 // GetLatest returns the most recently generated graph for an application.
 func (s *GraphService) GetLatest(ctx context.Context, appID uuid.UUID) (domain.InfraGraph, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return domain.InfraGraph{}, err
+	}
 	return s.graphs.GetLatestByApplicationID(ctx, appID)
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/domain"
@@ -18,6 +19,9 @@ type InfraService struct {
 	resources   repository.ResourceRepo
 	deployments repository.DeploymentRepo
 	providers   *provider.Registry
+	outbox      *Outbox       // optional
+	rbac        *RBACService  // optional
+	audit       *AuditService // optional
 }
 
 // NewInfraService creates a new InfraService.
@@ -41,78 +45,83 @@ func (s *InfraService) Apps() repository.ApplicationRepo { return s.apps }
 // Providers returns the provider registry used by this service.
 func (s *InfraService) Providers() *provider.Registry { return s.providers }
 
+// SetOutbox makes deployments run by this service record their status
+// changes in o. A nil outbox disables events.
+func (s *InfraService) SetOutbox(o *Outbox) { s.outbox = o }
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *InfraService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *InfraService) SetAudit(a *AuditService) { s.audit = a }
+
 // GenerateTerraform generates a complete Terraform configuration for an application
 // on its configured provider. It aggregates HCL from all resource provider mappings.
 func (s *InfraService) GenerateTerraform(ctx context.Context, appID uuid.UUID) (string, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return "", err
+	}
+	_, _, config, err := s.generate(ctx, appID)
+	return config, err
+}
+
+// generate builds the Terraform configuration for an application's current
+// resources and also returns the application and resource set it was built from.
+func (s *InfraService) generate(ctx context.Context, appID uuid.UUID) (domain.Application, []domain.Resource, string, error) {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
-		return "", fmt.Errorf("get application: %w", err)
+		return domain.Application{}, nil, "", fmt.Errorf("get application: %w", err)
 	}
 
 	resources, err := s.resources.ListByApplicationID(ctx, appID)
 	if err != nil {
-		return "", fmt.Errorf("list resources: %w", err)
+		return domain.Application{}, nil, "", fmt.Errorf("list resources: %w", err)
 	}
 
 	config, err := terraform.GenerateConfig(app, resources, app.Provider)
 	if err != nil {
-		return "", fmt.Errorf("generate terraform: %w", err)
+		return domain.Application{}, nil, "", fmt.Errorf("generate terraform: %w", err)
 	}
 
-	return config, nil
+	return app, resources, config, nil
 }
 
-// DeployInfrastructure generates Terraform for the application, applies it via
-// the appropriate provider adapter, and creates a deployment record.
-func (s *InfraService) DeployInfrastructure(ctx context.Context, appID uuid.UUID, gitCommit, gitBranch string) (domain.Deployment, error) {
+// generateFromSnapshot builds the Terraform configuration for a fixed resource
+// set, such as the snapshot recorded on a plan, rather than the application's
+// current resources.
+func (s *InfraService) generateFromSnapshot(ctx context.Context, appID uuid.UUID, resources []domain.Resource, p domain.CloudProvider) (string, error) {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
-		return domain.Deployment{}, fmt.Errorf("get application: %w", err)
-	}
-
-	// Generate Terraform config
-	resources, err := s.resources.ListByApplicationID(ctx, appID)
-	if err != nil {
-		return domain.Deployment{}, fmt.Errorf("list resources: %w", err)
+		return "", fmt.Errorf("get application: %w", err)
 	}
 
-	config, err := terraform.GenerateConfig(app, resources, app.Provider)
+	config, err := terraform.GenerateConfig(app, resources, p)
 	if err != nil {
-		return domain.Deployment{}, fmt.Errorf("generate terraform: %w", err)
-	}
-
-	// Create deployment record
-	d := domain.NewDeployment(appID, app.Provider, gitCommit, gitBranch, nil)
-	d.TerraformPlan = config
-	if err := d.Validate(); err != nil {
-		return domain.Deployment{}, err
-	}
-
-	if err := s.deployments.Create(ctx, d); err != nil {
-		return domain.Deployment{}, fmt.Errorf("create deployment: %w", err)
+		return "", fmt.Errorf("generate terraform: %w", err)
 	}
+	return config, nil
+}
 
-	// Apply via provider adapter
-	adapter, err := s.providers.Get(app.Provider)
-	if err != nil {
-		return s.markFailed(ctx, d, fmt.Sprintf("provider not available: %s", err))
-	}
+// newRunner returns a deployment runner bound to this service's repositories
+// and providers. emit receives progress events; pause paces simulated steps.
+func (s *InfraService) newRunner(emit emitFunc, pause func(context.Context, time.Duration)) *deploymentRunner {
+	return &deploymentRunner{infra: s, emit: emit, pause: pause}
+}
 
-	planOutput, err := adapter.ApplyTerraform(ctx, config)
+// DeployInfrastructure deploys the application's current resources and waits
+// for the run to finish. The deployment is created with deployments.Deploy
+// and run with deployments.Execute, so it gets the same checks as any other:
+// roles, freeze windows and the per-application lock. If it does not succeed
+// the returned deployment, when one was created, carries its status and
+// failure reason alongside the error.
+func (s *InfraService) DeployInfrastructure(ctx context.Context, deployments *DeploymentService, appID uuid.UUID, gitCommit, gitBranch string) (domain.Deployment, error) {
+	d, err := deployments.Deploy(ctx, appID, gitCommit, gitBranch, nil, DeployOptions{})
 	if err != nil {
-		return s.markFailed(ctx, d, fmt.Sprintf("terraform apply failed: %s", err))
-	}
-
-	// Mark succeeded
-	d.Status = domain.DeploymentSucceeded
-	d.TerraformPlan = planOutput
-	now := d.StartedAt // use a relative time
-	d.CompletedAt = &now
-	if err := s.deployments.Update(ctx, d); err != nil {
-		return domain.Deployment{}, fmt.Errorf("update deployment: %w", err)
+		return domain.Deployment{}, err
 	}
-
-	return d, nil
+	return deployments.executeSync(ctx, d.ID, s, false)
 }
 
 // ValidateProvider checks whether the provider adapter has valid credentials.
@@ -130,6 +139,9 @@ func (s *InfraService) DestroyInfrastructure(ctx context.Context, deploymentID u
 	if err != nil {
 		return fmt.Errorf("get deployment: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleDeployer, d.ApplicationID); err != nil {
+		return err
+	}
 
 	if d.TerraformPlan == "" {
 		return fmt.Errorf("deployment has no terraform plan to destroy")
@@ -142,12 +154,3 @@ func (s *InfraService) DestroyInfrastructure(ctx context.Context, deploymentID u
 
 	return adapter.DestroyTerraform(ctx, d.TerraformPlan)
 }
-
-func (s *InfraService) markFailed(ctx context.Context, d domain.Deployment, reason string) (domain.Deployment, error) {
-	d.Status = domain.DeploymentFailed
-	now := d.StartedAt
-	d.CompletedAt = &now
-	d.TerraformPlan = reason
-	_ = s.deployments.Update(ctx, d)
-	return d, fmt.Errorf("%s", reason)
-}
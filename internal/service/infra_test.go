@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -29,6 +30,12 @@ func setupInfraService(providerName domain.CloudProvider, applyErr error) (*Infr
 	return svc, appRepo, resRepo
 }
 
+// newTestDeployer returns a DeploymentService on infra's repositories, with
+// no plans or freeze windows, for running deployments in tests.
+func newTestDeployer(infra *InfraService) *DeploymentService {
+	return NewDeploymentService(infra.deployments, infra.apps, mock.NewPlanRepo(), infra.resources, mock.NewFreezeWindowRepo())
+}
+
 func TestInfraService_GenerateTerraform(t *testing.T) {
 	svc, appRepo, resRepo := setupInfraService(domain.ProviderAWS, nil)
 	ctx := context.Background()
@@ -85,7 +92,7 @@ func TestInfraService_DeployInfrastructure(t *testing.T) {
 		}
 		resRepo.Create(ctx, resource)
 
-		d, err := svc.DeployInfrastructure(ctx, app.ID, "abc123", "main")
+		d, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc123", "main")
 		if err != nil {
 			t.Fatalf("error = %v", err)
 		}
@@ -101,6 +108,42 @@ func TestInfraService_DeployInfrastructure(t *testing.T) {
 		if d.CompletedAt == nil {
 			t.Error("expected CompletedAt to be set")
 		}
+		if d.ApplyOutput != "Plan applied successfully" {
+			t.Errorf("apply_output = %q, want adapter output", d.ApplyOutput)
+		}
+		if !strings.Contains(d.TerraformPlan, `resource "aws_db_instance" "db"`) {
+			t.Errorf("terraform_plan should hold the generated HCL, got %q", d.TerraformPlan)
+		}
+		if len(d.Resources) != 1 || d.Resources[0].ID != resource.ID {
+			t.Errorf("resources = %v, want snapshot of the deployed resource", d.Resources)
+		}
+		if d.DurationMs < 0 || d.CompletedAt.Before(d.StartedAt) {
+			t.Errorf("duration_ms = %d, started %v completed %v", d.DurationMs, d.StartedAt, d.CompletedAt)
+		}
+		if d.FailureReason != "" {
+			t.Errorf("failure_reason = %q, want empty", d.FailureReason)
+		}
+
+		updated, _ := appRepo.GetByID(ctx, app.ID)
+		if updated.Status != domain.AppStatusDeployed {
+			t.Errorf("app status = %q, want %q", updated.Status, domain.AppStatusDeployed)
+		}
+	})
+
+	t.Run("apply failure degrades a deployed application", func(t *testing.T) {
+		svc, appRepo, _ := setupInfraService(domain.ProviderAWS, fmt.Errorf("terraform error"))
+
+		app := domain.NewApplication("degrade-app", "", "", "", domain.ProviderAWS)
+		app.Status = domain.AppStatusDeployed
+		appRepo.Create(ctx, app)
+
+		if _, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc123", "main"); err == nil {
+			t.Fatal("expected error from failed apply")
+		}
+		updated, _ := appRepo.GetByID(ctx, app.ID)
+		if updated.Status != domain.AppStatusDegraded {
+			t.Errorf("app status = %q, want %q", updated.Status, domain.AppStatusDegraded)
+		}
 	})
 
 	t.Run("apply failure marks deployment as failed", func(t *testing.T) {
@@ -109,13 +152,19 @@ func TestInfraService_DeployInfrastructure(t *testing.T) {
 		app := domain.NewApplication("fail-app", "", "", "", domain.ProviderAWS)
 		appRepo.Create(ctx, app)
 
-		d, err := svc.DeployInfrastructure(ctx, app.ID, "abc123", "main")
+		d, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc123", "main")
 		if err == nil {
 			t.Fatal("expected error from failed apply")
 		}
 		if d.Status != domain.DeploymentFailed {
 			t.Errorf("status = %v, want failed", d.Status)
 		}
+		if !strings.Contains(d.FailureReason, "terraform error") {
+			t.Errorf("failure_reason = %q, want it to mention the apply error", d.FailureReason)
+		}
+		if d.CompletedAt == nil {
+			t.Error("expected CompletedAt to be set on failure")
+		}
 	})
 
 	t.Run("no provider adapter", func(t *testing.T) {
@@ -135,7 +184,7 @@ func TestInfraService_DeployInfrastructure(t *testing.T) {
 		app := domain.NewApplication("no-adapter-app", "", "", "", domain.ProviderAWS)
 		appRepo.Create(ctx, app)
 
-		d, err := svc.DeployInfrastructure(ctx, app.ID, "abc", "main")
+		d, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc", "main")
 		if err == nil {
 			t.Fatal("expected error for missing adapter")
 		}
@@ -146,7 +195,7 @@ func TestInfraService_DeployInfrastructure(t *testing.T) {
 
 	t.Run("app not found", func(t *testing.T) {
 		svc, _, _ := setupInfraService(domain.ProviderAWS, nil)
-		_, err := svc.DeployInfrastructure(ctx, uuid.New(), "abc", "main")
+		_, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), uuid.New(), "abc", "main")
 		if err == nil {
 			t.Error("expected error for nonexistent app")
 		}
@@ -157,11 +206,24 @@ func TestInfraService_DeployInfrastructure(t *testing.T) {
 		app := domain.NewApplication("no-branch", "", "", "", domain.ProviderAWS)
 		appRepo.Create(ctx, app)
 
-		_, err := svc.DeployInfrastructure(ctx, app.ID, "abc", "")
+		_, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc", "")
 		if err == nil {
 			t.Error("expected validation error for missing branch")
 		}
 	})
+
+	t.Run("frozen", func(t *testing.T) {
+		svc, appRepo, _ := setupInfraService(domain.ProviderAWS, nil)
+		freezeRepo := mock.NewFreezeWindowRepo()
+		deployer := NewDeploymentService(svc.deployments, svc.apps, mock.NewPlanRepo(), svc.resources, freezeRepo)
+		app := domain.NewApplication("frozen-app", "", "", "", domain.ProviderAWS)
+		appRepo.Create(ctx, app)
+		NewFreezeService(freezeRepo, appRepo).Create(ctx, "incident", "", &app.ID, "", activeNow())
+
+		if _, err := svc.DeployInfrastructure(ctx, deployer, app.ID, "abc", "main"); !errors.Is(err, domain.ErrDeploymentFrozen) {
+			t.Errorf("got %v, want ErrDeploymentFrozen", err)
+		}
+	})
 }
 
 func TestInfraService_ValidateProvider(t *testing.T) {
@@ -203,7 +265,7 @@ func TestInfraService_DestroyInfrastructure(t *testing.T) {
 		resRepo.Create(ctx, resource)
 
 		// Deploy first
-		d, err := svc.DeployInfrastructure(ctx, app.ID, "abc", "main")
+		d, err := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc", "main")
 		if err != nil {
 			t.Fatalf("deploy error = %v", err)
 		}
@@ -223,3 +285,46 @@ func TestInfraService_DestroyInfrastructure(t *testing.T) {
 		}
 	})
 }
+
+func TestDeploymentRunner_Audit(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name     string
+		applyErr error
+		want     string
+	}{
+		{"success", nil, "deployment.succeed"},
+		{"failure", fmt.Errorf("terraform error"), "deployment.fail"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, appRepo, _ := setupInfraService(domain.ProviderAWS, tt.applyErr)
+			auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+			svc.SetAudit(auditSvc)
+
+			app := domain.NewApplication("audited-run-app", "", "", "", domain.ProviderAWS)
+			appRepo.Create(ctx, app)
+
+			d, _ := svc.DeployInfrastructure(ctx, newTestDeployer(svc), app.ID, "abc123", "main")
+
+			entries, err := auditSvc.List(ctx, domain.AuditFilter{ApplicationID: &app.ID})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(entries) != 2 {
+				t.Fatalf("List() = %d entries, want the run's start and outcome", len(entries))
+			}
+			if e := entries[1]; e.Action != "deployment.execute" || e.TargetID != d.ID.String() {
+				t.Errorf("first entry = %s %s, want deployment.execute %s", e.Action, e.TargetID, d.ID)
+			}
+			outcome := entries[0]
+			var after domain.Deployment
+			if err := json.Unmarshal(outcome.After, &after); err != nil {
+				t.Fatalf("unmarshal outcome entry: %v", err)
+			}
+			if outcome.Action != tt.want || after.Status != d.Status {
+				t.Errorf("last entry = %s with status %s, want %s with %s", outcome.Action, after.Status, tt.want, d.Status)
+			}
+		})
+	}
+}
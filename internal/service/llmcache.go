@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+)
+
+// LLMCacheService reports on the LLM response cache.
+type LLMCacheService struct {
+	cache *llm.CachedClient // nil when caching is disabled
+	rbac  *RBACService      // optional
+}
+
+// NewLLMCacheService creates a new LLMCacheService for cache, which is nil
+// when caching is disabled.
+func NewLLMCacheService(cache *llm.CachedClient) *LLMCacheService {
+	return &LLMCacheService{cache: cache}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *LLMCacheService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// Stats returns the cache's hit and miss counts. Only global admins may read
+// them.
+func (s *LLMCacheService) Stats(ctx context.Context) (llm.CacheStats, error) {
+	if err := s.rbac.require(ctx, domain.RoleAdmin, nil); err != nil {
+		return llm.CacheStats{}, err
+	}
+	return s.cache.Stats(), nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// LLMUsageService accounts for the tokens LLM calls spend and enforces
+// monthly token budgets. It is the llm.Meter of the LLM client, so every
+// operation that reaches the model is recorded against its application and
+// organization, and refused once either's budget is spent.
+type LLMUsageService struct {
+	usage   repository.LLMUsageRepo
+	budgets repository.LLMBudgetRepo
+	apps    repository.ApplicationRepo
+	rbac    *RBACService  // optional
+	audit   *AuditService // optional
+}
+
+// NewLLMUsageService creates a new LLMUsageService.
+func NewLLMUsageService(usage repository.LLMUsageRepo, budgets repository.LLMBudgetRepo, apps repository.ApplicationRepo) *LLMUsageService {
+	return &LLMUsageService{usage: usage, budgets: budgets, apps: apps}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *LLMUsageService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *LLMUsageService) SetAudit(a *AuditService) { s.audit = a }
+
+// orgOf returns the organization an operation for the application appID
+// belongs to: the one ctx is scoped to, else the application's.
+func (s *LLMUsageService) orgOf(ctx context.Context, appID *uuid.UUID) *uuid.UUID {
+	if orgID := tenant.OrgID(ctx); orgID != nil {
+		return orgID
+	}
+	if appID != nil {
+		if app, err := s.apps.GetByID(ctx, *appID); err == nil {
+			return &app.OrgID
+		}
+	}
+	return nil
+}
+
+// Allow implements llm.Meter. It refuses operations once the application's
+// or its organization's budget for the month is spent.
+func (s *LLMUsageService) Allow(ctx context.Context, appID *uuid.UUID) error {
+	orgID := s.orgOf(ctx, appID)
+	if orgID == nil {
+		return nil
+	}
+	scopes := []*uuid.UUID{nil}
+	if appID != nil {
+		scopes = []*uuid.UUID{appID, nil}
+	}
+	for _, scope := range scopes {
+		b, err := s.budgets.Get(ctx, *orgID, scope)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("get llm budget: %w", err)
+		}
+		used, err := s.used(ctx, b)
+		if err != nil {
+			return err
+		}
+		if used >= b.MonthlyTokens {
+			resets := domain.MonthStart(time.Now()).AddDate(0, 1, 0)
+			return fmt.Errorf("%w: %s has used %d of its %d monthly tokens; the budget resets on %s",
+				domain.ErrBudgetExceeded, s.budgetTarget(ctx, b), used, b.MonthlyTokens, resets.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
+
+// used returns the tokens spent against b this month.
+func (s *LLMUsageService) used(ctx context.Context, b domain.LLMBudget) (int64, error) {
+	aggregates, err := s.usage.Aggregate(ctx, domain.LLMUsageFilter{
+		OrgID:         &b.OrgID,
+		ApplicationID: b.ApplicationID,
+		From:          domain.MonthStart(time.Now()),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("aggregate llm usage: %w", err)
+	}
+	var total domain.LLMUsageTotals
+	for _, a := range aggregates {
+		total.Add(a.LLMUsageTotals)
+	}
+	return total.Tokens(), nil
+}
+
+// budgetTarget names what b caps, for error messages.
+func (s *LLMUsageService) budgetTarget(ctx context.Context, b domain.LLMBudget) string {
+	if b.ApplicationID == nil {
+		if org, ok := tenant.OrgFrom(ctx); ok && org.ID == b.OrgID {
+			return "organization " + org.Slug
+		}
+		return "the organization"
+	}
+	if app, err := s.apps.GetByID(ctx, *b.ApplicationID); err == nil {
+		return "application " + app.Name
+	}
+	return "application " + b.ApplicationID.String()
+}
+
+// Record implements llm.Meter. Failures are logged; accounting never fails
+// the operation that was metered.
+func (s *LLMUsageService) Record(ctx context.Context, u llm.Usage) {
+	rec := domain.LLMUsage{
+		ID:            uuid.New(),
+		OrgID:         s.orgOf(ctx, u.ApplicationID),
+		ApplicationID: u.ApplicationID,
+		Operation:     u.Operation,
+		Model:         u.Model,
+		InputTokens:   u.InputTokens,
+		OutputTokens:  u.OutputTokens,
+		LatencyMs:     u.Latency.Milliseconds(),
+		CostUSD:       u.CostUSD,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := s.usage.Create(context.WithoutCancel(ctx), rec); err != nil {
+		log.Printf("[llm] record %s usage: %v", u.Operation, err)
+	}
+}
+
+// Report sums the usage matching filter, in total, per application and per
+// day. Callers see the applications they hold the viewer role on, and usage
+// outside any application with the global viewer role.
+func (s *LLMUsageService) Report(ctx context.Context, filter domain.LLMUsageFilter) (domain.LLMUsageReport, error) {
+	if filter.ApplicationID != nil {
+		if err := s.rbac.require(ctx, domain.RoleViewer, filter.ApplicationID); err != nil {
+			return domain.LLMUsageReport{}, err
+		}
+	}
+	aggregates, err := s.usage.Aggregate(ctx, filter)
+	if err != nil {
+		return domain.LLMUsageReport{}, fmt.Errorf("aggregate llm usage: %w", err)
+	}
+	can, err := s.rbac.allowed(ctx)
+	if err != nil {
+		return domain.LLMUsageReport{}, err
+	}
+
+	report := domain.LLMUsageReport{ByApplication: []domain.LLMApplicationUsage{}, ByDay: []domain.LLMDailyUsage{}}
+	byApp := make(map[uuid.UUID]*domain.LLMApplicationUsage) // uuid.Nil for usage outside any application
+	byDay := make(map[string]*domain.LLMDailyUsage)
+	for _, a := range aggregates {
+		if !can(domain.RoleViewer, a.ApplicationID) {
+			continue
+		}
+		report.Add(a.LLMUsageTotals)
+
+		key := uuid.Nil
+		if a.ApplicationID != nil {
+			key = *a.ApplicationID
+		}
+		app, ok := byApp[key]
+		if !ok {
+			app = &domain.LLMApplicationUsage{ApplicationID: a.ApplicationID}
+			if a.ApplicationID != nil {
+				if found, err := s.apps.GetByID(ctx, *a.ApplicationID); err == nil {
+					app.ApplicationName = found.Name
+				}
+			}
+			byApp[key] = app
+		}
+		app.Add(a.LLMUsageTotals)
+
+		day := a.Day.UTC().Format("2006-01-02")
+		d, ok := byDay[day]
+		if !ok {
+			d = &domain.LLMDailyUsage{Day: day}
+			byDay[day] = d
+		}
+		d.Add(a.LLMUsageTotals)
+	}
+
+	for _, app := range byApp {
+		report.ByApplication = append(report.ByApplication, *app)
+	}
+	sort.Slice(report.ByApplication, func(i, j int) bool {
+		a, b := report.ByApplication[i], report.ByApplication[j]
+		if a.Tokens() != b.Tokens() {
+			return a.Tokens() > b.Tokens()
+		}
+		return a.ApplicationName < b.ApplicationName
+	})
+	for _, d := range byDay {
+		report.ByDay = append(report.ByDay, *d)
+	}
+	sort.Slice(report.ByDay, func(i, j int) bool {
+		return report.ByDay[i].Day < report.ByDay[j].Day
+	})
+	return report, nil
+}
+
+// SetBudget sets the monthly token budget of the application appID, or of
+// the whole organization when appID is nil, replacing any budget already
+// set. It needs the admin role on the application, or globally for an
+// organization budget.
+func (s *LLMUsageService) SetBudget(ctx context.Context, appID *uuid.UUID, monthlyTokens int64) (domain.LLMBudget, error) {
+	if appID != nil {
+		if _, err := s.apps.GetByID(ctx, *appID); err != nil {
+			return domain.LLMBudget{}, fmt.Errorf("get application: %w", err)
+		}
+	}
+	if err := s.rbac.require(ctx, domain.RoleAdmin, appID); err != nil {
+		return domain.LLMBudget{}, err
+	}
+
+	orgID := domain.DefaultOrgID
+	if id := s.orgOf(ctx, appID); id != nil {
+		orgID = *id
+	}
+	b := domain.NewLLMBudget(orgID, appID, monthlyTokens)
+	if err := b.Validate(); err != nil {
+		return domain.LLMBudget{}, err
+	}
+
+	var stored domain.LLMBudget
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		var before any
+		if existing, err := s.budgets.Get(ctx, orgID, appID); err == nil {
+			before = existing
+		}
+		var err error
+		if stored, err = s.budgets.Put(ctx, b); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "llm_budget.set", "llm_budget", stored.ID.String(), appID, before, stored)
+	})
+	if err != nil {
+		return domain.LLMBudget{}, err
+	}
+	return stored, nil
+}
+
+// ListBudgets returns the budgets the caller can view, with the tokens
+// spent against each this month.
+func (s *LLMUsageService) ListBudgets(ctx context.Context) ([]domain.LLMBudgetStatus, error) {
+	all, err := s.budgets.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list llm budgets: %w", err)
+	}
+	can, err := s.rbac.allowed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	budgets := make([]domain.LLMBudgetStatus, 0, len(all))
+	for _, b := range all {
+		if !can(domain.RoleViewer, b.ApplicationID) {
+			continue
+		}
+		used, err := s.used(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, domain.LLMBudgetStatus{LLMBudget: b, UsedTokens: used})
+	}
+	return budgets, nil
+}
+
+// DeleteBudget removes a budget. Like setting it, this needs the admin role
+// on its application, or globally for an organization budget.
+func (s *LLMUsageService) DeleteBudget(ctx context.Context, id uuid.UUID) error {
+	b, err := s.budgets.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.require(ctx, domain.RoleAdmin, b.ApplicationID); err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.budgets.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "llm_budget.delete", "llm_budget", id.String(), b.ApplicationID, b, nil)
+	})
+}
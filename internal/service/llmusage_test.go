@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+)
+
+func TestLLMUsageService_Budgets(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	usageRepo := mock.NewLLMUsageRepo()
+	usage := NewLLMUsageService(usageRepo, mock.NewLLMBudgetRepo(), appRepo)
+	resSvc := NewResourceService(mock.NewResourceRepo(), appRepo, llm.NewMeteredClient(&llm.MockClient{}, usage), nil)
+
+	ctx := context.Background()
+	shop := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	blog := domain.NewApplication("blog", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, shop)
+	appRepo.Create(ctx, blog)
+
+	spend := func(app domain.Application, tokens int64) {
+		usage.Record(ctx, llm.Usage{Operation: "GenerateGraph", Model: "m", ApplicationID: &app.ID, InputTokens: tokens / 2, OutputTokens: tokens - tokens/2})
+	}
+	spend(shop, 600)
+	spend(blog, 300)
+	// Last month's usage does not count against this month's budgets.
+	usageRepo.Create(ctx, domain.LLMUsage{ID: uuid.New(), OrgID: &domain.DefaultOrgID, ApplicationID: &shop.ID, InputTokens: 5000,
+		CreatedAt: domain.MonthStart(time.Now()).Add(-time.Hour)})
+
+	if _, err := usage.SetBudget(ctx, &shop.ID, 1000); err != nil {
+		t.Fatalf("SetBudget(shop) error = %v", err)
+	}
+	if err := usage.Allow(ctx, &shop.ID); err != nil {
+		t.Errorf("Allow(shop) at 600/1000 tokens: %v", err)
+	}
+
+	spend(shop, 500)
+	err := usage.Allow(ctx, &shop.ID)
+	if !errors.Is(err, domain.ErrBudgetExceeded) || !strings.Contains(err.Error(), "application shop has used 1100 of its 1000 monthly tokens") {
+		t.Errorf("Allow(shop) at 1100/1000 tokens: got %v, want ErrBudgetExceeded naming shop", err)
+	}
+	if _, err := resSvc.AddFromDescription(ctx, shop.ID, "a database"); !errors.Is(err, domain.ErrBudgetExceeded) {
+		t.Errorf("AddFromDescription() over budget: got %v, want ErrBudgetExceeded", err)
+	}
+	if err := usage.Allow(ctx, &blog.ID); err != nil {
+		t.Errorf("Allow(blog) without a budget of its own: %v", err)
+	}
+
+	// The organization's budget covers every application.
+	org, err := usage.SetBudget(ctx, nil, 1400)
+	if err != nil {
+		t.Fatalf("SetBudget(org) error = %v", err)
+	}
+	if err := usage.Allow(ctx, &blog.ID); !errors.Is(err, domain.ErrBudgetExceeded) {
+		t.Errorf("Allow(blog) at 1400/1400 organization tokens: got %v, want ErrBudgetExceeded", err)
+	}
+
+	// Setting a budget again replaces it.
+	if again, err := usage.SetBudget(ctx, nil, 2000); err != nil || again.ID != org.ID || again.MonthlyTokens != 2000 {
+		t.Errorf("SetBudget(org) again = %+v, %v, want the same budget raised", again, err)
+	}
+	if err := usage.Allow(ctx, &blog.ID); err != nil {
+		t.Errorf("Allow(blog) after raising the organization budget: %v", err)
+	}
+
+	budgets, err := usage.ListBudgets(ctx)
+	if err != nil || len(budgets) != 2 || budgets[0].ApplicationID != nil || budgets[0].UsedTokens != 1400 || budgets[1].UsedTokens != 1100 {
+		t.Errorf("ListBudgets() = %+v, %v", budgets, err)
+	}
+	if err := usage.DeleteBudget(ctx, budgets[1].ID); err != nil {
+		t.Fatalf("DeleteBudget() error = %v", err)
+	}
+	if err := usage.Allow(ctx, &shop.ID); err != nil {
+		t.Errorf("Allow(shop) after deleting its budget: %v", err)
+	}
+}
+
+func TestLLMUsageService_Report(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	usage := NewLLMUsageService(mock.NewLLMUsageRepo(), mock.NewLLMBudgetRepo(), appRepo)
+	usage.SetRBAC(rbac)
+
+	ctx := context.Background()
+	shop := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	blog := domain.NewApplication("blog", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, shop)
+	appRepo.Create(ctx, blog)
+	for _, u := range []llm.Usage{
+		{Operation: "GenerateGraph", ApplicationID: &shop.ID, InputTokens: 100, OutputTokens: 50, CostUSD: 0.5},
+		{Operation: "GenerateHostingPlan", ApplicationID: &shop.ID, InputTokens: 200, OutputTokens: 100, CostUSD: 1},
+		{Operation: "GenerateGraph", ApplicationID: &blog.ID, InputTokens: 10, OutputTokens: 5, CostUSD: 0.25},
+	} {
+		usage.Record(ctx, u)
+	}
+
+	report, err := usage.Report(ctx, domain.LLMUsageFilter{})
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Calls != 3 || report.Tokens() != 465 || report.CostUSD != 1.75 {
+		t.Errorf("Report() totals = %+v", report.LLMUsageTotals)
+	}
+	if len(report.ByApplication) != 2 || report.ByApplication[0].ApplicationName != "shop" || report.ByApplication[0].Calls != 2 {
+		t.Errorf("Report().ByApplication = %+v, want shop first", report.ByApplication)
+	}
+	if today := time.Now().UTC().Format("2006-01-02"); len(report.ByDay) != 1 || report.ByDay[0].Day != today || report.ByDay[0].Calls != 3 {
+		t.Errorf("Report().ByDay = %+v", report.ByDay)
+	}
+
+	// Viewers see the applications they hold the role on.
+	if _, err := rbac.Grant(ctx, "alice", domain.RoleViewer, &blog.ID); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	alice := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalUser, Subject: "alice", Name: "alice"})
+	if report, err := usage.Report(alice, domain.LLMUsageFilter{}); err != nil || report.Calls != 1 || report.ByApplication[0].ApplicationName != "blog" {
+		t.Errorf("Report(alice) = %+v, %v, want blog only", report, err)
+	}
+	if _, err := usage.Report(alice, domain.LLMUsageFilter{ApplicationID: &shop.ID}); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("Report(alice, shop): got %v, want ErrForbidden", err)
+	}
+}
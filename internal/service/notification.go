@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/notify"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+)
+
+const (
+	// DefaultNotificationAttempts is how many times a delivery is tried
+	// before it is logged as failed.
+	DefaultNotificationAttempts = 3
+
+	// DefaultNotificationBackoff is the wait before the first retry; it
+	// doubles after each further failure.
+	DefaultNotificationBackoff = 2 * time.Second
+)
+
+// NotificationService tells people about deployment outcomes, drift and
+// compliance violations through each application's notification channels.
+// Failed deliveries are retried with backoff, and every delivery is recorded
+// in a log.
+type NotificationService struct {
+	channels    repository.NotificationChannelRepo
+	deliveries  repository.NotificationDeliveryRepo
+	apps        repository.ApplicationRepo
+	deployments repository.DeploymentRepo
+	compliance  *compliance.Registry
+	rbac        *RBACService  // optional
+	audit       *AuditService // optional
+	sinkFor     func(domain.NotificationChannel) (notify.Sink, error)
+	attempts    int
+	backoff     time.Duration
+	pause       func(ctx context.Context, d time.Duration)
+	now         func() time.Time
+	inflight    sync.WaitGroup
+}
+
+// NewNotificationService creates a new NotificationService. Email channels
+// send through smtpCfg.
+func NewNotificationService(
+	channels repository.NotificationChannelRepo,
+	deliveries repository.NotificationDeliveryRepo,
+	apps repository.ApplicationRepo,
+	deployments repository.DeploymentRepo,
+	complianceRegistry *compliance.Registry,
+	smtpCfg notify.SMTPConfig,
+) *NotificationService {
+	return &NotificationService{
+		channels:    channels,
+		deliveries:  deliveries,
+		apps:        apps,
+		deployments: deployments,
+		compliance:  complianceRegistry,
+		sinkFor: func(ch domain.NotificationChannel) (notify.Sink, error) {
+			return notify.ForChannel(ch, smtpCfg, nil)
+		},
+		attempts: DefaultNotificationAttempts,
+		backoff:  DefaultNotificationBackoff,
+		pause:    sleep,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *NotificationService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *NotificationService) SetAudit(a *AuditService) { s.audit = a }
+
+// CreateChannel adds a notification channel to an application. An empty
+// events list subscribes the channel to every event.
+func (s *NotificationService) CreateChannel(ctx context.Context, appID uuid.UUID, kind domain.ChannelKind, target string, events []domain.NotificationEvent) (domain.NotificationChannel, error) {
+	if _, err := s.apps.GetByID(ctx, appID); err != nil {
+		return domain.NotificationChannel{}, fmt.Errorf("get application: %w", err)
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, appID); err != nil {
+		return domain.NotificationChannel{}, err
+	}
+
+	ch := domain.NewNotificationChannel(appID, kind, target, events)
+	if err := ch.Validate(); err != nil {
+		return domain.NotificationChannel{}, err
+	}
+
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.channels.Create(ctx, ch); err != nil {
+			return fmt.Errorf("create notification channel: %w", err)
+		}
+		return s.audit.record(ctx, "notification_channel.create", "notification_channel", ch.ID.String(), &appID, nil, ch.Redacted())
+	})
+	if err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	return ch, nil
+}
+
+// ListChannels returns an application's notification channels. Callers
+// who cannot edit the application get their targets redacted.
+func (s *NotificationService) ListChannels(ctx context.Context, appID uuid.UUID) ([]domain.NotificationChannel, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return nil, err
+	}
+	channels, err := s.channels.ListByApplicationID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	can, err := s.rbac.allowed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !can(domain.RoleEditor, &appID) {
+		for i := range channels {
+			channels[i] = channels[i].Redacted()
+		}
+	}
+	return channels, nil
+}
+
+// DeleteChannel removes a notification channel.
+func (s *NotificationService) DeleteChannel(ctx context.Context, id uuid.UUID) error {
+	ch, err := s.channels.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, ch.ApplicationID); err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.channels.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "notification_channel.delete", "notification_channel", ch.ID.String(), &ch.ApplicationID, ch.Redacted(), nil)
+	})
+}
+
+// ListDeliveries returns an application's most recent deliveries, newest
+// first. A limit of zero returns them all.
+func (s *NotificationService) ListDeliveries(ctx context.Context, appID uuid.UUID, limit int) ([]domain.NotificationDelivery, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return nil, err
+	}
+	return s.deliveries.ListByApplicationID(ctx, appID, limit)
+}
+
+// Notify delivers n to every enabled channel of its application that
+// subscribes to the event and waits for the deliveries to finish.
+func (s *NotificationService) Notify(ctx context.Context, n domain.Notification) error {
+	channels, err := s.channels.ListByApplicationID(ctx, n.ApplicationID)
+	if err != nil {
+		return fmt.Errorf("list notification channels: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, ch := range channels {
+		if !ch.Wants(n.Event) {
+			continue
+		}
+		wg.Add(1)
+		go func(ch domain.NotificationChannel) {
+			defer wg.Done()
+			s.deliver(ctx, ch, n)
+		}(ch)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Wait blocks until notifications sent in the background have been delivered.
+func (s *NotificationService) Wait() {
+	s.inflight.Wait()
+}
+
+// HandleEvent notifies channels about deployment status changes and drift
+// recorded in the outbox. It is an EventHandler.
+func (s *NotificationService) HandleEvent(ctx context.Context, e domain.Event) {
+	switch e.Type {
+	case domain.EventDeploymentStatusChanged:
+		var d domain.Deployment
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			log.Printf("[notify] %s %s: decode deployment: %v", e.Type, e.ID, err)
+			return
+		}
+		// Events carry a summary; compliance checks need the applied Terraform.
+		if d.Type == domain.DeploymentTypeApply && d.Status != domain.DeploymentInProgress {
+			if full, err := s.deployments.GetByID(ctx, d.ID); err == nil {
+				d.TerraformPlan = full.TerraformPlan
+			} else {
+				log.Printf("[notify] deployment %s: get deployment: %v", d.ID, err)
+			}
+		}
+		s.DeploymentChanged(ctx, d)
+
+	case domain.EventLiveDriftDetected:
+		var findings []domain.DriftFinding
+		if err := json.Unmarshal(e.Data, &findings); err != nil {
+			log.Printf("[notify] %s %s: decode drift: %v", e.Type, e.ID, err)
+			return
+		}
+		app, err := s.apps.GetByID(ctx, e.ApplicationID)
+		if err != nil {
+			log.Printf("[notify] drift in %s: get application: %v", e.ApplicationID, err)
+			return
+		}
+		s.DriftDetected(ctx, app, findings)
+	}
+}
+
+// DeploymentChanged notifies channels that a deployment started, succeeded
+// or failed. Once an apply has generated its Terraform, the configuration is
+// also checked against the application's compliance frameworks and any
+// violations are reported. Delivery happens in the background.
+func (s *NotificationService) DeploymentChanged(ctx context.Context, d domain.Deployment) {
+	var event domain.NotificationEvent
+	switch d.Status {
+	case domain.DeploymentInProgress:
+		event = domain.EventDeploymentStarted
+	case domain.DeploymentSucceeded:
+		event = domain.EventDeploymentSucceeded
+	case domain.DeploymentFailed:
+		event = domain.EventDeploymentFailed
+	default:
+		return
+	}
+
+	s.background(ctx, func(ctx context.Context) {
+		app, err := s.apps.GetByID(ctx, d.ApplicationID)
+		if err != nil {
+			log.Printf("[notify] deployment %s: get application: %v", d.ID, err)
+			return
+		}
+
+		s.send(ctx, deploymentNotification(app, d, event, s.now()))
+
+		if event == domain.EventDeploymentStarted || d.Type != domain.DeploymentTypeApply || d.TerraformPlan == "" || s.compliance == nil {
+			return
+		}
+		if violations := s.compliance.CheckTerraform(app.ComplianceFrameworks, d.Provider, d.TerraformPlan); len(violations) > 0 {
+			s.send(ctx, complianceNotification(app, d, violations, s.now()))
+		}
+	})
+}
+
+// DriftDetected notifies channels that discovery found declared resources
+// missing or unhealthy. Delivery happens in the background.
+func (s *NotificationService) DriftDetected(ctx context.Context, app domain.Application, findings []domain.DriftFinding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	lines := make([]string, len(findings))
+	for i, f := range findings {
+		lines[i] = "- " + f.Detail
+	}
+	n := domain.Notification{
+		Event:           domain.EventDriftDetected,
+		ApplicationID:   app.ID,
+		ApplicationName: app.Name,
+		Subject:         fmt.Sprintf("Drift detected in %s (%d %s)", app.Name, len(findings), plural(len(findings), "resource", "resources")),
+		Message:         "Live discovery found declared resources that are missing or not running:\n" + strings.Join(lines, "\n"),
+		Data:            findings,
+		Timestamp:       s.now(),
+	}
+	s.background(ctx, func(ctx context.Context) { s.send(ctx, n) })
+}
+
+// background runs fn on a context that outlives the caller's request.
+func (s *NotificationService) background(ctx context.Context, fn func(ctx context.Context)) {
+	s.inflight.Add(1)
+	go func() {
+		defer s.inflight.Done()
+		fn(context.WithoutCancel(ctx))
+	}()
+}
+
+func (s *NotificationService) send(ctx context.Context, n domain.Notification) {
+	if err := s.Notify(ctx, n); err != nil {
+		log.Printf("[notify] %s for application %s: %v", n.Event, n.ApplicationID, err)
+	}
+}
+
+// deliver sends n to one channel, retrying with exponential backoff, and
+// records the outcome in the delivery log.
+func (s *NotificationService) deliver(ctx context.Context, ch domain.NotificationChannel, n domain.Notification) {
+	delivery := domain.NotificationDelivery{
+		ID:            uuid.New(),
+		ChannelID:     ch.ID,
+		ApplicationID: ch.ApplicationID,
+		Event:         n.Event,
+		Subject:       n.Subject,
+		Status:        domain.DeliveryFailed,
+	}
+
+	sink, err := s.sinkFor(ch)
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		wait := s.backoff
+		for delivery.Attempts < s.attempts {
+			delivery.Attempts++
+			if err = sink.Send(ctx, n); err == nil {
+				delivery.Status, delivery.Error = domain.DeliveryDelivered, ""
+				break
+			}
+			delivery.Error = err.Error()
+			if delivery.Attempts < s.attempts {
+				s.pause(ctx, wait)
+				wait *= 2
+			}
+		}
+	}
+
+	if delivery.Status == domain.DeliveryFailed {
+		log.Printf("[notify] %s to %s channel %s failed after %d attempts: %s",
+			n.Event, ch.Kind, ch.ID, delivery.Attempts, delivery.Error)
+	}
+	delivery.CreatedAt = s.now()
+	if err := s.deliveries.Create(ctx, delivery); err != nil {
+		log.Printf("[notify] record delivery to channel %s: %v", ch.ID, err)
+	}
+}
+
+func deploymentNotification(app domain.Application, d domain.Deployment, event domain.NotificationEvent, now time.Time) domain.Notification {
+	verb := "Deployment"
+	if d.Type == domain.DeploymentTypeDestroy {
+		verb = "Destroy"
+	}
+	ref := d.GitBranch
+	if d.GitCommit != "" {
+		ref += "@" + d.GitCommit
+	}
+
+	var subject, message string
+	switch event {
+	case domain.EventDeploymentStarted:
+		subject = fmt.Sprintf("%s of %s started", verb, app.Name)
+		message = fmt.Sprintf("%s %s of %s (%s) to %s started.", verb, d.ID, app.Name, ref, d.Provider)
+	case domain.EventDeploymentSucceeded:
+		subject = fmt.Sprintf("%s of %s succeeded", verb, app.Name)
+		message = fmt.Sprintf("%s %s of %s (%s) to %s succeeded in %s.", verb, d.ID, app.Name, ref, d.Provider,
+			time.Duration(d.DurationMs)*time.Millisecond)
+	default:
+		subject = fmt.Sprintf("%s of %s failed", verb, app.Name)
+		message = fmt.Sprintf("%s %s of %s (%s) to %s failed: %s", verb, d.ID, app.Name, ref, d.Provider, d.FailureReason)
+	}
+	if d.BreakGlass {
+		message += "\nThis deployment overrode a freeze window (break-glass)."
+	}
+
+	return domain.Notification{
+		Event:           event,
+		ApplicationID:   app.ID,
+		ApplicationName: app.Name,
+		Subject:         subject,
+		Message:         message,
+		Data:            d.Summary(),
+		Timestamp:       now,
+	}
+}
+
+func complianceNotification(app domain.Application, d domain.Deployment, violations []compliance.Violation, now time.Time) domain.Notification {
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		actual := v.Actual
+		if actual == "" {
+			actual = "unset"
+		}
+		lines[i] = fmt.Sprintf("- [%s %s] %s.%s: %s is %s, want %s",
+			v.Framework, v.RuleID, v.ResourceType, v.ResourceName, v.Attribute, actual, v.Expected)
+	}
+
+	return domain.Notification{
+		Event:           domain.EventComplianceViolation,
+		ApplicationID:   app.ID,
+		ApplicationName: app.Name,
+		Subject:         fmt.Sprintf("%d compliance %s in %s", len(violations), plural(len(violations), "violation", "violations"), app.Name),
+		Message:         fmt.Sprintf("The Terraform applied by deployment %s does not meet:\n%s", d.ID, strings.Join(lines, "\n")),
+		Data:            violations,
+		Timestamp:       now,
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
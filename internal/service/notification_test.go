@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/compliance"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/notify"
+	"github.com/matthewdriscoll/infraplane/internal/provider"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+)
+
+// recordingServer is a webhook endpoint that fails its first failures
+// requests and records the events of the rest.
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	calls    int
+	events   []domain.NotificationEvent
+}
+
+func newRecordingServer(t *testing.T, failures int) *recordingServer {
+	rs := &recordingServer{failures: failures}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		rs.calls++
+		if rs.calls <= rs.failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var n domain.Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		rs.events = append(rs.events, n.Event)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) received() []domain.NotificationEvent {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]domain.NotificationEvent(nil), rs.events...)
+}
+
+func newTestNotificationService(appRepo *mock.ApplicationRepo, deployRepo *mock.DeploymentRepo) (*NotificationService, *mock.NotificationDeliveryRepo) {
+	deliveries := mock.NewNotificationDeliveryRepo()
+	svc := NewNotificationService(mock.NewNotificationChannelRepo(), deliveries, appRepo, deployRepo, compliance.NewRegistry(), notify.SMTPConfig{})
+	svc.pause = noPause
+	return svc, deliveries
+}
+
+func TestNotificationService_CreateChannel(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc, _ := newTestNotificationService(appRepo, mock.NewDeploymentRepo())
+	ctx := context.Background()
+
+	app := domain.NewApplication("notify-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	ch, err := svc.CreateChannel(ctx, app.ID, domain.ChannelSlack, "https://hooks.slack.com/services/x", []domain.NotificationEvent{domain.EventDeploymentFailed})
+	if err != nil {
+		t.Fatalf("CreateChannel() error = %v", err)
+	}
+	if !ch.Enabled || len(ch.Events) != 1 {
+		t.Errorf("channel = %+v", ch)
+	}
+
+	if _, err := svc.CreateChannel(ctx, app.ID, domain.ChannelEmail, "not-an-address", nil); !domain.IsValidationError(err) {
+		t.Errorf("invalid target: got %v, want validation error", err)
+	}
+	if _, err := svc.CreateChannel(ctx, uuid.New(), domain.ChannelSlack, "https://x.example.com", nil); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("unknown app: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestNotificationService_ChannelTargetsRedacted(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc, _ := newTestNotificationService(appRepo, mock.NewDeploymentRepo())
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	svc.SetRBAC(rbac)
+	svc.SetAudit(auditSvc)
+	ctx := context.Background()
+
+	app := domain.NewApplication("notify-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	const target = "https://hooks.slack.com/services/T000/B000/XXXX"
+	ch, err := svc.CreateChannel(ctx, app.ID, domain.ChannelSlack, target, nil)
+	if err != nil {
+		t.Fatalf("CreateChannel() error = %v", err)
+	}
+
+	for _, g := range []struct {
+		subject string
+		role    domain.Role
+	}{{"alice", domain.RoleEditor}, {"bob", domain.RoleViewer}} {
+		if _, err := rbac.Grant(ctx, g.subject, g.role, &app.ID); err != nil {
+			t.Fatalf("Grant() error = %v", err)
+		}
+	}
+	alice := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalUser, Subject: "alice", Name: "alice"})
+	bob := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalUser, Subject: "bob", Name: "bob"})
+
+	if channels, err := svc.ListChannels(alice, app.ID); err != nil || len(channels) != 1 || channels[0].Target != target {
+		t.Errorf("ListChannels(editor) = %+v, %v, want the full target", channels, err)
+	}
+	if channels, err := svc.ListChannels(bob, app.ID); err != nil || len(channels) != 1 || channels[0].Target != "https://hooks.slack.com/***" {
+		t.Errorf("ListChannels(viewer) = %+v, %v, want the target redacted", channels, err)
+	}
+
+	if err := svc.DeleteChannel(alice, ch.ID); err != nil {
+		t.Fatalf("DeleteChannel() error = %v", err)
+	}
+	entries, err := auditSvc.List(ctx, domain.AuditFilter{ApplicationID: &app.ID})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, e := range entries {
+		if strings.Contains(string(e.Before), "XXXX") || strings.Contains(string(e.After), "XXXX") {
+			t.Errorf("%s entry records the channel's token: %s / %s", e.Action, e.Before, e.After)
+		}
+	}
+}
+
+func TestNotificationService_Notify(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc, deliveries := newTestNotificationService(appRepo, mock.NewDeploymentRepo())
+	ctx := context.Background()
+
+	app := domain.NewApplication("notify-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	healthy := newRecordingServer(t, 0)
+	flaky := newRecordingServer(t, 2)   // succeeds on the last attempt
+	broken := newRecordingServer(t, 99) // never succeeds
+	driftOnly := newRecordingServer(t, 0)
+
+	svc.CreateChannel(ctx, app.ID, domain.ChannelWebhook, healthy.URL, nil)
+	svc.CreateChannel(ctx, app.ID, domain.ChannelWebhook, flaky.URL, nil)
+	brokenCh, _ := svc.CreateChannel(ctx, app.ID, domain.ChannelWebhook, broken.URL, nil)
+	svc.CreateChannel(ctx, app.ID, domain.ChannelWebhook, driftOnly.URL, []domain.NotificationEvent{domain.EventDriftDetected})
+
+	err := svc.Notify(ctx, domain.Notification{Event: domain.EventDeploymentFailed, ApplicationID: app.ID, Subject: "failed"})
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if got := healthy.received(); len(got) != 1 || got[0] != domain.EventDeploymentFailed {
+		t.Errorf("healthy channel received %v", got)
+	}
+	if got := flaky.received(); len(got) != 1 {
+		t.Errorf("flaky channel received %v, want one event after retries", got)
+	}
+	if got := driftOnly.received(); len(got) != 0 {
+		t.Errorf("drift-only channel received %v", got)
+	}
+
+	entries, _ := svc.ListDeliveries(ctx, app.ID, 0)
+	if len(entries) != 3 {
+		t.Fatalf("delivery log has %d entries, want 3", len(entries))
+	}
+	for _, d := range entries {
+		switch {
+		case d.ChannelID == brokenCh.ID:
+			if d.Status != domain.DeliveryFailed || d.Attempts != DefaultNotificationAttempts || d.Error == "" {
+				t.Errorf("broken delivery = %+v", d)
+			}
+		case d.Status != domain.DeliveryDelivered:
+			t.Errorf("delivery = %+v, want delivered", d)
+		}
+	}
+	if n, _ := deliveries.ListByApplicationID(ctx, app.ID, 1); len(n) != 1 {
+		t.Errorf("limit not applied: %d", len(n))
+	}
+}
+
+func TestNotificationService_DeploymentChanged(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc, _ := newTestNotificationService(appRepo, mock.NewDeploymentRepo())
+	ctx := context.Background()
+
+	app := domain.NewApplication("compliant-app", "", "", "", domain.ProviderGCP)
+	app.ComplianceFrameworks = []string{string(compliance.FrameworkCISGCP)}
+	appRepo.Create(ctx, app)
+
+	srv := newRecordingServer(t, 0)
+	svc.CreateChannel(ctx, app.ID, domain.ChannelWebhook, srv.URL, nil)
+
+	d := domain.NewDeployment(app.ID, domain.ProviderGCP, "abc", "main", nil)
+	d.TerraformPlan = `resource "google_compute_network" "vpc" {
+  auto_create_subnetworks = true
+}`
+
+	d.Status = domain.DeploymentInProgress
+	svc.DeploymentChanged(ctx, d)
+	svc.Wait()
+
+	d.Status = domain.DeploymentSucceeded
+	svc.DeploymentChanged(ctx, d)
+	svc.Wait()
+
+	got := srv.received()
+	want := []domain.NotificationEvent{domain.EventDeploymentStarted, domain.EventDeploymentSucceeded, domain.EventComplianceViolation}
+	if len(got) != len(want) {
+		t.Fatalf("received %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDeployInfrastructure_Notifies(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS})
+	deployRepo := mock.NewDeploymentRepo()
+	infra := NewInfraService(appRepo, mock.NewResourceRepo(), deployRepo, reg)
+	svc, _ := newTestNotificationService(appRepo, deployRepo)
+	bus := NewEventBus()
+	bus.Subscribe(svc.HandleEvent)
+	infra.SetOutbox(NewSyncOutbox(bus))
+	ctx := context.Background()
+
+	app := domain.NewApplication("notified-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	srv := newRecordingServer(t, 0)
+	svc.CreateChannel(ctx, app.ID, domain.ChannelWebhook, srv.URL,
+		[]domain.NotificationEvent{domain.EventDeploymentSucceeded, domain.EventDeploymentFailed})
+
+	if _, err := infra.DeployInfrastructure(ctx, newTestDeployer(infra), app.ID, "abc", "main"); err != nil {
+		t.Fatalf("DeployInfrastructure() error = %v", err)
+	}
+	svc.Wait()
+
+	if got := srv.received(); len(got) != 1 || got[0] != domain.EventDeploymentSucceeded {
+		t.Errorf("received %v, want [deployment.succeeded]", got)
+	}
+}
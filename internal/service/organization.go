@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// OrganizationService manages organizations, the tenancy boundary that
+// applications and everything under them are scoped to.
+type OrganizationService struct {
+	orgs  repository.OrganizationRepo
+	rbac  *RBACService  // optional
+	audit *AuditService // optional
+}
+
+// NewOrganizationService creates a new OrganizationService.
+func NewOrganizationService(orgs repository.OrganizationRepo) *OrganizationService {
+	return &OrganizationService{orgs: orgs}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *OrganizationService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *OrganizationService) SetAudit(a *AuditService) { s.audit = a }
+
+// Create adds an organization. Only global admins of the default
+// organization may create organizations; the caller becomes an admin of the
+// new one.
+func (s *OrganizationService) Create(ctx context.Context, slug, name string) (domain.Organization, error) {
+	if err := s.rbac.require(ctx, domain.RoleAdmin, nil); err != nil {
+		return domain.Organization{}, err
+	}
+
+	org := domain.NewOrganization(slug, name)
+	if err := org.Validate(); err != nil {
+		return domain.Organization{}, err
+	}
+
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.orgs.Create(ctx, org); err != nil {
+			return fmt.Errorf("create organization: %w", err)
+		}
+		if err := s.audit.record(ctx, "organization.create", "organization", org.ID.String(), nil, nil, org); err != nil {
+			return err
+		}
+		return s.rbac.grantCreator(ctx, org)
+	})
+	if err != nil {
+		return domain.Organization{}, err
+	}
+	return org, nil
+}
+
+// Get retrieves an organization by slug.
+func (s *OrganizationService) Get(ctx context.Context, slug string) (domain.Organization, error) {
+	return s.orgs.GetBySlug(ctx, slug)
+}
+
+// Enter returns the organization slug names for scoping a request to it. It
+// returns an error wrapping domain.ErrForbidden unless the caller belongs to
+// the organization: every caller belongs to the default organization, other
+// organizations need a role binding in them or an API key of theirs.
+func (s *OrganizationService) Enter(ctx context.Context, slug string) (domain.Organization, error) {
+	org, err := s.orgs.GetBySlug(ctx, slug)
+	if err != nil {
+		return domain.Organization{}, err
+	}
+	if err := s.rbac.requireMember(ctx, org); err != nil {
+		return domain.Organization{}, err
+	}
+	return org, nil
+}
+
+// Home returns the organization callers that name none act in: the
+// organization of the caller's API key, or the default organization.
+func (s *OrganizationService) Home(ctx context.Context) (domain.Organization, error) {
+	orgID := domain.DefaultOrgID
+	if p, ok := auth.PrincipalFrom(ctx); ok && p.OrgID != nil {
+		orgID = *p.OrgID
+	}
+	return s.orgs.GetByID(ctx, orgID)
+}
+
+// List returns the organizations the caller belongs to, ordered by slug.
+func (s *OrganizationService) List(ctx context.Context) ([]domain.Organization, error) {
+	member, err := s.rbac.memberOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.orgs.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make([]domain.Organization, 0, len(all))
+	for _, org := range all {
+		if member(org.ID) {
+			orgs = append(orgs, org)
+		}
+	}
+	return orgs, nil
+}
+
+// requestOrg returns the organization ctx is scoped to, or the default
+// organization for unscoped contexts. Global role bindings, freeze windows and
+// webhook subscriptions created without an application belong to it.
+func requestOrg(ctx context.Context) uuid.UUID {
+	if orgID := tenant.OrgID(ctx); orgID != nil {
+		return *orgID
+	}
+	return domain.DefaultOrgID
+}
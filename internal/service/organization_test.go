@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+func TestOrganizationService(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	orgSvc := NewOrganizationService(mock.NewOrganizationRepo())
+	orgSvc.SetRBAC(rbac)
+	appSvc := NewApplicationService(appRepo, mock.NewResourceRepo(), nil, nil)
+
+	ctx := context.Background()
+	alice := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalUser, Subject: "alice", Name: "alice@example.com"})
+	if _, err := orgSvc.Create(alice, "acme", "Acme Corp"); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("Create(non-admin): got %v, want ErrForbidden", err)
+	}
+	if _, err := orgSvc.Create(ctx, "Not A Slug", ""); !domain.IsValidationError(err) {
+		t.Errorf("Create(bad slug): got %v, want validation error", err)
+	}
+
+	acme, err := orgSvc.Create(ctx, "acme", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if acme.Name != "acme" {
+		t.Errorf("Name = %q, want the slug", acme.Name)
+	}
+	if _, err := orgSvc.Create(ctx, "acme", ""); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("Create(duplicate): got %v, want ErrConflict", err)
+	}
+	if got, err := orgSvc.Get(ctx, "acme"); err != nil || got.ID != acme.ID {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+
+	// Applications registered in a scoped context belong to that
+	// organization, so the same name can exist in each.
+	app, err := appSvc.Register(tenant.WithOrg(ctx, acme), "shop", "", "", "", domain.ProviderAWS, nil, nil)
+	if err != nil {
+		t.Fatalf("Register(acme) error = %v", err)
+	}
+	if app.OrgID != acme.ID {
+		t.Errorf("OrgID = %v, want %v", app.OrgID, acme.ID)
+	}
+	if _, err := appSvc.Register(ctx, "shop", "", "", "", domain.ProviderAWS, nil, nil); err != nil {
+		t.Errorf("Register(default org, same name) error = %v", err)
+	}
+	if _, err := appSvc.GetByName(tenant.WithOrg(ctx, domain.Organization{ID: domain.DefaultOrgID}), "shop"); err != nil {
+		t.Errorf("GetByName(default org) error = %v", err)
+	}
+
+	// Other organizations need a role binding in them; the default one is
+	// open to every caller.
+	if _, err := orgSvc.Enter(alice, "acme"); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("Enter(non-member): got %v, want ErrForbidden", err)
+	}
+	if _, err := orgSvc.Enter(alice, domain.DefaultOrgSlug); err != nil {
+		t.Errorf("Enter(default org) error = %v", err)
+	}
+	if _, err := rbac.Grant(tenant.WithOrg(ctx, acme), "alice", domain.RoleViewer, &app.ID); err != nil {
+		t.Fatalf("Grant(acme) error = %v", err)
+	}
+	if _, err := orgSvc.Enter(alice, "acme"); err != nil {
+		t.Errorf("Enter(member) error = %v", err)
+	}
+
+	// Global bindings only grant within their own organization, and the
+	// creator of an organization becomes its admin.
+	bob := auth.WithPrincipal(ctx, domain.Principal{Kind: domain.PrincipalUser, Subject: "bob", Name: "bob@example.com"})
+	if _, err := rbac.Grant(ctx, "bob", domain.RoleAdmin, nil); err != nil {
+		t.Fatalf("Grant(default org) error = %v", err)
+	}
+	if err := rbac.require(bob, domain.RoleViewer, &app.ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("require(acme app) with a default-org binding: got %v, want ErrForbidden", err)
+	}
+	if _, err := orgSvc.Enter(bob, "acme"); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("Enter(acme) with a default-org binding: got %v, want ErrForbidden", err)
+	}
+	globex, err := orgSvc.Create(bob, "globex", "")
+	if err != nil {
+		t.Fatalf("Create(global admin) error = %v", err)
+	}
+	if _, err := orgSvc.Enter(bob, "globex"); err != nil {
+		t.Errorf("Enter(creator) error = %v", err)
+	}
+	if err := rbac.require(tenant.WithOrg(bob, globex), domain.RoleAdmin, nil); err != nil {
+		t.Errorf("require(admin of globex) error = %v", err)
+	}
+
+	// Callers only see the organizations they belong to.
+	slugs := func(ctx context.Context) string {
+		orgs, err := orgSvc.List(ctx)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		var s []string
+		for _, o := range orgs {
+			s = append(s, o.Slug)
+		}
+		return strings.Join(s, ",")
+	}
+	if got := slugs(alice); got != "acme,default" {
+		t.Errorf("List(alice) = %s, want acme,default", got)
+	}
+	if got := slugs(bob); got != "default,globex" {
+		t.Errorf("List(bob) = %s, want default,globex", got)
+	}
+	if got := slugs(ctx); got != "acme,default,globex" {
+		t.Errorf("List(unrestricted) = %s, want every organization", got)
+	}
+}
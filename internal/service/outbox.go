@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+)
+
+const (
+	// DefaultDispatchInterval is how often the EventDispatcher publishes
+	// events waiting in the outbox.
+	DefaultDispatchInterval = time.Second
+
+	// dispatchBatch caps how many events one query fetches from the outbox.
+	dispatchBatch = 100
+)
+
+// Outbox records the domain events raised by service writes and is the
+// single place side effects hang off. Backed by a transactional store
+// (PostgreSQL), events are written in the same transaction as the repository
+// writes that raise them and later published by an EventDispatcher, so an
+// event exists if and only if its change committed. In synchronous mode
+// (in-memory storage) events are published on the bus as soon as the write
+// that raised them succeeds.
+type Outbox struct {
+	tx    repository.Transactor
+	store repository.EventOutboxRepo
+	bus   *EventBus // set in synchronous mode
+}
+
+// NewOutbox creates an outbox that stores events in store within tx's
+// transactions. Run an EventDispatcher to publish them.
+func NewOutbox(tx repository.Transactor, store repository.EventOutboxRepo) *Outbox {
+	return &Outbox{tx: tx, store: store}
+}
+
+// NewSyncOutbox creates an outbox that publishes events on bus directly.
+func NewSyncOutbox(bus *EventBus) *Outbox {
+	return &Outbox{bus: bus}
+}
+
+type pendingEventsKey struct{}
+
+// atomically runs fn so that its repository writes and the events it records
+// take effect together. Services hold an optional outbox; on a nil outbox fn
+// simply runs.
+func (o *Outbox) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if o == nil {
+		return fn(ctx)
+	}
+	if o.bus == nil {
+		return o.tx.WithinTx(ctx, fn)
+	}
+
+	if _, nested := ctx.Value(pendingEventsKey{}).(*[]domain.Event); nested {
+		return fn(ctx)
+	}
+	var pending []domain.Event
+	if err := fn(context.WithValue(ctx, pendingEventsKey{}, &pending)); err != nil {
+		return err
+	}
+	for _, e := range pending {
+		o.bus.Publish(ctx, e)
+	}
+	return nil
+}
+
+// record adds an event with data as its payload to the outbox. Inside
+// atomically it commits (or is published) with the surrounding writes.
+func (o *Outbox) record(ctx context.Context, typ domain.EventType, appID uuid.UUID, data any) error {
+	if o == nil {
+		return nil
+	}
+	e, err := domain.NewEvent(typ, appID, data)
+	if err != nil {
+		return err
+	}
+
+	if o.bus == nil {
+		if err := o.store.Create(ctx, e); err != nil {
+			return fmt.Errorf("record %s event: %w", typ, err)
+		}
+		return nil
+	}
+	if pending, ok := ctx.Value(pendingEventsKey{}).(*[]domain.Event); ok {
+		*pending = append(*pending, e)
+		return nil
+	}
+	o.bus.Publish(ctx, e)
+	return nil
+}
+
+// EventDispatcher publishes events from the outbox to the in-process bus.
+// Only the replica holding the leader lock dispatches, and an event is marked
+// dispatched after its subscribers have run, so delivery is at-least-once.
+type EventDispatcher struct {
+	outbox repository.EventOutboxRepo
+	bus    *EventBus
+	leader repository.LeaderLock
+	now    func() time.Time
+}
+
+// NewEventDispatcher creates a new EventDispatcher.
+func NewEventDispatcher(outbox repository.EventOutboxRepo, bus *EventBus, leader repository.LeaderLock) *EventDispatcher {
+	return &EventDispatcher{
+		outbox: outbox,
+		bus:    bus,
+		leader: leader,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run dispatches pending events every interval until ctx is cancelled, then
+// gives up leadership so another replica can take over.
+func (d *EventDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer func() {
+		if err := d.leader.Release(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("[events] release leader lock: %v", err)
+		}
+	}()
+
+	for {
+		if _, err := d.Dispatch(ctx); err != nil {
+			log.Printf("[events] dispatch: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch publishes every pending event in the order they occurred,
+// provided this replica is the leader, and returns how many were published.
+func (d *EventDispatcher) Dispatch(ctx context.Context) (int, error) {
+	leader, err := d.leader.TryAcquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquire leader lock: %w", err)
+	}
+	if !leader {
+		return 0, nil
+	}
+
+	published := 0
+	for {
+		events, err := d.outbox.ListPending(ctx, dispatchBatch)
+		if err != nil {
+			return published, fmt.Errorf("list pending events: %w", err)
+		}
+		for _, e := range events {
+			d.bus.Publish(ctx, e)
+			if err := d.outbox.MarkDispatched(ctx, e.ID, d.now()); err != nil {
+				return published, fmt.Errorf("mark event %s dispatched: %w", e.ID, err)
+			}
+			published++
+		}
+		if len(events) < dispatchBatch {
+			return published, nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+)
+
+func TestOutbox_Sync(t *testing.T) {
+	bus, published := recordingBus()
+	outbox := NewSyncOutbox(bus)
+	appRepo := mock.NewApplicationRepo()
+	ctx := context.Background()
+
+	t.Run("publishes after the write succeeds", func(t *testing.T) {
+		app := domain.NewApplication("outbox-app", "", "", "", domain.ProviderAWS)
+		err := outbox.atomically(ctx, func(ctx context.Context) error {
+			if err := appRepo.Create(ctx, app); err != nil {
+				return err
+			}
+			if err := outbox.record(ctx, domain.EventApplicationRegistered, app.ID, app); err != nil {
+				return err
+			}
+			if n := len(published()); n != 0 {
+				t.Errorf("published %d events before the write finished", n)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("atomically() error = %v", err)
+		}
+		if got := published(); len(got) != 1 || got[0] != domain.EventApplicationRegistered {
+			t.Errorf("published %v, want [application.registered]", got)
+		}
+	})
+
+	t.Run("discards events when the write fails", func(t *testing.T) {
+		before := len(published())
+		err := outbox.atomically(ctx, func(ctx context.Context) error {
+			// Nested calls join the outer unit of work.
+			if err := outbox.atomically(ctx, func(ctx context.Context) error {
+				return outbox.record(ctx, domain.EventResourceAdded, uuid.New(), nil)
+			}); err != nil {
+				return err
+			}
+			return errors.New("write failed")
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if got := published(); len(got) != before {
+			t.Errorf("published %v after a failed write", got[before:])
+		}
+	})
+
+	t.Run("nil outbox runs the write", func(t *testing.T) {
+		var nilOutbox *Outbox
+		ran := false
+		err := nilOutbox.atomically(ctx, func(ctx context.Context) error {
+			ran = true
+			return nilOutbox.record(ctx, domain.EventResourceAdded, uuid.New(), nil)
+		})
+		if err != nil || !ran {
+			t.Errorf("atomically() = %v, ran = %v", err, ran)
+		}
+	})
+}
+
+func TestEventDispatcher_Dispatch(t *testing.T) {
+	store := mock.NewEventOutboxRepo()
+	outbox := NewOutbox(mock.NewTransactor(), store)
+	bus, published := recordingBus()
+	lock := mock.NewLeaderLock()
+	dispatcher := NewEventDispatcher(store, bus, lock)
+	ctx := context.Background()
+
+	app := domain.NewApplication("dispatched-app", "", "", "", domain.ProviderAWS)
+	for _, typ := range []domain.EventType{domain.EventApplicationRegistered, domain.EventPlanGenerated} {
+		if err := outbox.atomically(ctx, func(ctx context.Context) error {
+			return outbox.record(ctx, typ, app.ID, app)
+		}); err != nil {
+			t.Fatalf("record %s: %v", typ, err)
+		}
+	}
+	if n := len(published()); n != 0 {
+		t.Fatalf("published %d events before dispatch", n)
+	}
+
+	lock.Deny = true
+	if n, err := dispatcher.Dispatch(ctx); err != nil || n != 0 {
+		t.Errorf("Dispatch() as follower = %d, %v; want 0, nil", n, err)
+	}
+
+	lock.Deny = false
+	if n, err := dispatcher.Dispatch(ctx); err != nil || n != 2 {
+		t.Fatalf("Dispatch() = %d, %v; want 2, nil", n, err)
+	}
+	got := published()
+	if len(got) != 2 || got[0] != domain.EventApplicationRegistered || got[1] != domain.EventPlanGenerated {
+		t.Errorf("published %v, want events in the order they occurred", got)
+	}
+
+	// Dispatched events are not published again.
+	if n, _ := dispatcher.Dispatch(ctx); n != 0 {
+		t.Errorf("second Dispatch() = %d, want 0", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
@@ -19,6 +20,9 @@ type PlannerService struct {
 	resources  repository.ResourceRepo
 	llm        llm.Client
 	compliance *compliance.Registry
+	outbox     *Outbox       // optional
+	rbac       *RBACService  // optional
+	audit      *AuditService // optional
 }
 
 // NewPlannerService creates a new PlannerService.
@@ -32,12 +36,27 @@ func NewPlannerService(plans repository.PlanRepo, apps repository.ApplicationRep
 	}
 }
 
+// SetOutbox makes the service record plan events in o. A nil outbox
+// disables events.
+func (s *PlannerService) SetOutbox(o *Outbox) { s.outbox = o }
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *PlannerService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *PlannerService) SetAudit(a *AuditService) { s.audit = a }
+
 // GenerateHostingPlan creates an LLM-powered hosting recommendation.
 func (s *PlannerService) GenerateHostingPlan(ctx context.Context, appID uuid.UUID) (domain.InfrastructurePlan, error) {
 	app, err := s.apps.GetByID(ctx, appID)
 	if err != nil {
 		return domain.InfrastructurePlan{}, fmt.Errorf("get application: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, app.ID); err != nil {
+		return domain.InfrastructurePlan{}, err
+	}
 
 	resources, err := s.resources.ListByApplicationID(ctx, appID)
 	if err != nil {
@@ -81,13 +100,20 @@ func (s *PlannerService) GenerateHostingPlan(ctx context.Context, appID uuid.UUI
 	}
 
 	plan := domain.NewHostingPlan(appID, result.Content, resources, result.EstimatedCost)
-	if err := s.plans.Create(ctx, plan); err != nil {
+	plan.PromptVersion = llm.PromptVersion(llm.PromptHostingPlan)
+	if err := s.savePlan(ctx, "plan.generate", plan); err != nil {
 		return domain.InfrastructurePlan{}, fmt.Errorf("save hosting plan: %w", err)
 	}
 
 	return plan, nil
 }
 
+// StreamHostingPlan is GenerateHostingPlan reporting the plan's markdown to
+// fn while the model writes it. The plan is saved once complete.
+func (s *PlannerService) StreamHostingPlan(ctx context.Context, appID uuid.UUID, fn llm.StreamFunc) (domain.InfrastructurePlan, error) {
+	return s.GenerateHostingPlan(llm.WithStream(ctx, fn), appID)
+}
+
 // GenerateMigrationPlan creates an LLM-powered migration plan between providers.
 func (s *PlannerService) GenerateMigrationPlan(ctx context.Context, appID uuid.UUID, from, to domain.CloudProvider) (domain.InfrastructurePlan, error) {
 	if !from.IsValid() {
@@ -104,6 +130,9 @@ func (s *PlannerService) GenerateMigrationPlan(ctx context.Context, appID uuid.U
 	if err != nil {
 		return domain.InfrastructurePlan{}, fmt.Errorf("get application: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, app.ID); err != nil {
+		return domain.InfrastructurePlan{}, err
+	}
 
 	resources, err := s.resources.ListByApplicationID(ctx, appID)
 	if err != nil {
@@ -116,19 +145,124 @@ func (s *PlannerService) GenerateMigrationPlan(ctx context.Context, appID uuid.U
 	}
 
 	plan := domain.NewMigrationPlan(appID, from, to, result.Content, resources, result.EstimatedCost)
-	if err := s.plans.Create(ctx, plan); err != nil {
+	plan.PromptVersion = llm.PromptVersion(llm.PromptMigrationPlan)
+	if err := s.savePlan(ctx, "plan.generate", plan); err != nil {
 		return domain.InfrastructurePlan{}, fmt.Errorf("save migration plan: %w", err)
 	}
 
 	return plan, nil
 }
 
+// StreamMigrationPlan is GenerateMigrationPlan reporting the plan's markdown
+// to fn while the model writes it. The plan is saved once complete.
+func (s *PlannerService) StreamMigrationPlan(ctx context.Context, appID uuid.UUID, from, to domain.CloudProvider, fn llm.StreamFunc) (domain.InfrastructurePlan, error) {
+	return s.GenerateMigrationPlan(llm.WithStream(ctx, fn), appID, from, to)
+}
+
+// RefinePlan asks the LLM to rewrite a plan to follow instruction and saves
+// the result as a new plan linked to it. The original plan is unchanged.
+func (s *PlannerService) RefinePlan(ctx context.Context, planID uuid.UUID, instruction string) (domain.InfrastructurePlan, error) {
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		return domain.InfrastructurePlan{}, domain.ErrValidation("instruction is required")
+	}
+
+	parent, err := s.plans.GetByID(ctx, planID)
+	if err != nil {
+		return domain.InfrastructurePlan{}, fmt.Errorf("get plan: %w", err)
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, parent.ApplicationID); err != nil {
+		return domain.InfrastructurePlan{}, err
+	}
+	app, err := s.apps.GetByID(ctx, parent.ApplicationID)
+	if err != nil {
+		return domain.InfrastructurePlan{}, fmt.Errorf("get application: %w", err)
+	}
+
+	// Refined plans have no snapshot; show the model the resources the
+	// application has now instead.
+	prompted := parent
+	if !parent.HasSnapshot() {
+		prompted.Resources, err = s.resources.ListByApplicationID(ctx, app.ID)
+		if err != nil {
+			return domain.InfrastructurePlan{}, fmt.Errorf("list resources: %w", err)
+		}
+	}
+
+	result, err := s.llm.RefinePlan(ctx, app, prompted, instruction)
+	if err != nil {
+		return domain.InfrastructurePlan{}, fmt.Errorf("refine plan: %w", err)
+	}
+
+	plan := domain.NewRefinedPlan(parent, instruction, result.Content, result.EstimatedCost)
+	plan.PromptVersion = llm.PromptVersion(llm.PromptPlanRefinement)
+	if err := s.savePlan(ctx, "plan.refine", plan); err != nil {
+		return domain.InfrastructurePlan{}, fmt.Errorf("save refined plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// StreamRefinePlan is RefinePlan reporting the new plan's markdown to fn
+// while the model writes it. The plan is saved once complete.
+func (s *PlannerService) StreamRefinePlan(ctx context.Context, planID uuid.UUID, instruction string, fn llm.StreamFunc) (domain.InfrastructurePlan, error) {
+	return s.RefinePlan(llm.WithStream(ctx, fn), planID, instruction)
+}
+
+// DiffPlan compares a plan with the plan against, or with the plan it was
+// refined from if against is nil. Both plans must belong to the same
+// application.
+func (s *PlannerService) DiffPlan(ctx context.Context, planID uuid.UUID, against *uuid.UUID) (domain.PlanDiff, error) {
+	plan, err := s.GetPlan(ctx, planID)
+	if err != nil {
+		return domain.PlanDiff{}, err
+	}
+	if against == nil {
+		if plan.ParentID == nil {
+			return domain.PlanDiff{}, domain.ErrValidation("plan was not refined from another plan; specify a plan to compare against")
+		}
+		against = plan.ParentID
+	}
+	from, err := s.plans.GetByID(ctx, *against)
+	if err != nil {
+		return domain.PlanDiff{}, fmt.Errorf("get plan to compare against: %w", err)
+	}
+	if from.ApplicationID != plan.ApplicationID {
+		return domain.PlanDiff{}, domain.ErrValidation("plans belong to different applications")
+	}
+	return domain.DiffPlans(from, plan), nil
+}
+
+// savePlan stores a generated plan and records its event and audit entry
+// under action.
+func (s *PlannerService) savePlan(ctx context.Context, action string, plan domain.InfrastructurePlan) error {
+	return s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.plans.Create(ctx, plan); err != nil {
+			return err
+		}
+		if err := s.audit.record(ctx, action, "plan", plan.ID.String(), &plan.ApplicationID, nil, plan); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventPlanGenerated, plan.ApplicationID, plan)
+	})
+}
+
 // GetPlan returns a plan by ID.
 func (s *PlannerService) GetPlan(ctx context.Context, id uuid.UUID) (domain.InfrastructurePlan, error) {
-	return s.plans.GetByID(ctx, id)
+	plan, err := s.plans.GetByID(ctx, id)
+	if err != nil {
+		return domain.InfrastructurePlan{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, plan.ApplicationID); err != nil {
+		return domain.InfrastructurePlan{}, err
+	}
+	return plan, nil
 }
 
 // ListPlansByApplication returns all plans for an application.
 func (s *PlannerService) ListPlansByApplication(ctx context.Context, appID uuid.UUID) ([]domain.InfrastructurePlan, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return nil, err
+	}
 	return s.plans.ListByApplicationID(ctx, appID)
 }
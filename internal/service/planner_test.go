@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -128,3 +131,107 @@ func TestPlannerService_ListPlansByApplication(t *testing.T) {
 		t.Errorf("len = %d, want 2", len(plans))
 	}
 }
+
+func TestPlannerService_RefinePlan(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	planRepo := mock.NewPlanRepo()
+	mockLLM := &llm.MockClient{}
+	svc := NewPlannerService(planRepo, appRepo, resRepo, mockLLM, nil)
+	ctx := context.Background()
+
+	app := domain.NewApplication("refine-test", "A web API", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	original, err := svc.GenerateMigrationPlan(ctx, app.ID, domain.ProviderAWS, domain.ProviderGCP)
+	if err != nil {
+		t.Fatalf("GenerateMigrationPlan() error = %v", err)
+	}
+
+	refined, err := svc.RefinePlan(ctx, original.ID, "  use Cloud SQL instead  ")
+	if err != nil {
+		t.Fatalf("RefinePlan() error = %v", err)
+	}
+	if refined.ID == original.ID {
+		t.Error("refined plan should be a new plan")
+	}
+	if refined.ParentID == nil || *refined.ParentID != original.ID {
+		t.Errorf("ParentID = %v, want %v", refined.ParentID, original.ID)
+	}
+	if refined.Instruction != "use Cloud SQL instead" {
+		t.Errorf("Instruction = %q", refined.Instruction)
+	}
+	if refined.PlanType != domain.PlanTypeMigration || refined.FromProvider == nil || *refined.FromProvider != domain.ProviderAWS {
+		t.Errorf("refined plan lost its migration details: %+v", refined)
+	}
+	if refined.PromptVersion != llm.PromptVersion(llm.PromptPlanRefinement) {
+		t.Errorf("PromptVersion = %q", refined.PromptVersion)
+	}
+	if got, _ := planRepo.GetByID(ctx, original.ID); got.Content != original.Content {
+		t.Error("original plan should be unchanged")
+	}
+
+	t.Run("diff against parent", func(t *testing.T) {
+		diff, err := svc.DiffPlan(ctx, refined.ID, nil)
+		if err != nil {
+			t.Fatalf("DiffPlan() error = %v", err)
+		}
+		if diff.FromPlanID != original.ID || diff.ToPlanID != refined.ID {
+			t.Errorf("diff compares %s with %s", diff.ToPlanID, diff.FromPlanID)
+		}
+		if !strings.Contains(diff.Diff, "+use Cloud SQL instead") {
+			t.Errorf("Diff = %q", diff.Diff)
+		}
+	})
+
+	t.Run("diff without parent", func(t *testing.T) {
+		if _, err := svc.DiffPlan(ctx, original.ID, nil); !domain.IsValidationError(err) {
+			t.Errorf("error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("diff across applications", func(t *testing.T) {
+		other := domain.NewApplication("refine-other", "", "", "", domain.ProviderAWS)
+		appRepo.Create(ctx, other)
+		otherPlan, _ := svc.GenerateHostingPlan(ctx, other.ID)
+		if _, err := svc.DiffPlan(ctx, refined.ID, &otherPlan.ID); !domain.IsValidationError(err) {
+			t.Errorf("error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("refined plan has no snapshot", func(t *testing.T) {
+		if refined.HasSnapshot() || refined.Resources != nil {
+			t.Errorf("refined plan should carry no resource snapshot: %+v", refined.Resources)
+		}
+	})
+
+	t.Run("refine a refinement with current resources", func(t *testing.T) {
+		res := domain.NewResource(app.ID, domain.ResourceDatabase, "main-db", json.RawMessage(`{}`))
+		resRepo.Create(ctx, res)
+		var prompted []domain.Resource
+		mockLLM.RefinePlanFn = func(_ context.Context, _ domain.Application, plan domain.InfrastructurePlan, instruction string) (llm.RefinedPlanResult, error) {
+			prompted = plan.Resources
+			return llm.RefinedPlanResult{Content: plan.Content + "\n" + instruction}, nil
+		}
+		defer func() { mockLLM.RefinePlanFn = nil }()
+
+		if _, err := svc.RefinePlan(ctx, refined.ID, "add a replica"); err != nil {
+			t.Fatalf("RefinePlan() error = %v", err)
+		}
+		if len(prompted) != 1 || prompted[0].ID != res.ID {
+			t.Errorf("prompt resources = %+v, want the application's current resources", prompted)
+		}
+	})
+
+	t.Run("empty instruction", func(t *testing.T) {
+		if _, err := svc.RefinePlan(ctx, original.ID, " "); !domain.IsValidationError(err) {
+			t.Errorf("error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("plan not found", func(t *testing.T) {
+		if _, err := svc.RefinePlan(ctx, uuid.New(), "cheaper"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
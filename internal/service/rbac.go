@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+)
+
+// RBACService manages role bindings and checks that the caller holds the
+// role an operation needs. Services call it with the request context, so
+// REST and MCP callers are checked alike.
+//
+// Contexts without a principal belong to Infraplane itself (the scheduler,
+// the git push handler, event dispatch) and are always allowed. Superuser
+// principals hold every role. Everyone else needs a role binding in the
+// organization of the application, or of the request for operations on
+// every application.
+type RBACService struct {
+	bindings repository.RoleBindingRepo
+	apps     repository.ApplicationRepo
+	audit    *AuditService // optional
+}
+
+// NewRBACService creates a new RBACService.
+func NewRBACService(bindings repository.RoleBindingRepo, apps repository.ApplicationRepo) *RBACService {
+	return &RBACService{bindings: bindings, apps: apps}
+}
+
+// SetAudit makes the service record role binding changes in the audit log.
+// A nil service records nothing.
+func (s *RBACService) SetAudit(a *AuditService) { s.audit = a }
+
+// Grant gives subject a role on the application appID, or on every
+// application of the request's organization when appID is nil. Granting on
+// one application needs the admin role on it; granting globally needs the
+// global admin role in the organization.
+func (s *RBACService) Grant(ctx context.Context, subject string, role domain.Role, appID *uuid.UUID) (domain.RoleBinding, error) {
+	orgID := requestOrg(ctx)
+	if appID != nil {
+		app, err := s.apps.GetByID(ctx, *appID)
+		if err != nil {
+			return domain.RoleBinding{}, err
+		}
+		orgID = app.OrgID
+	}
+	if err := s.require(ctx, domain.RoleAdmin, appID); err != nil {
+		return domain.RoleBinding{}, err
+	}
+
+	b := domain.NewRoleBinding(subject, role, orgID, appID)
+	if err := b.Validate(); err != nil {
+		return domain.RoleBinding{}, err
+	}
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.bindings.Create(ctx, b); err != nil {
+			if errors.Is(err, domain.ErrConflict) {
+				return fmt.Errorf("%s already holds the %s role there: %w", b.Subject, role, err)
+			}
+			return fmt.Errorf("create role binding: %w", err)
+		}
+		return s.audit.record(ctx, "role_binding.grant", "role_binding", b.ID.String(), appID, nil, b)
+	})
+	if err != nil {
+		return domain.RoleBinding{}, err
+	}
+	return b, nil
+}
+
+// grantCreator makes the caller an admin of org, which it has just created.
+// Unrestricted callers need no binding.
+func (s *RBACService) grantCreator(ctx context.Context, org domain.Organization) error {
+	p, ok := s.principal(ctx)
+	if !ok {
+		return nil
+	}
+	b := domain.NewRoleBinding(p.Subject, domain.RoleAdmin, org.ID, nil)
+	if err := s.bindings.Create(ctx, b); err != nil {
+		return fmt.Errorf("create role binding: %w", err)
+	}
+	return s.audit.record(ctx, "role_binding.grant", "role_binding", b.ID.String(), nil, nil, b)
+}
+
+// ListRoleBindings returns the role bindings that apply to the application
+// appID, including its organization's global ones, or every binding in the
+// request's organization when appID is nil. A non-empty subject keeps only
+// that subject's bindings. Listing needs the admin role on the application,
+// or globally.
+func (s *RBACService) ListRoleBindings(ctx context.Context, appID *uuid.UUID, subject string) ([]domain.RoleBinding, error) {
+	orgID := requestOrg(ctx)
+	if appID != nil {
+		app, err := s.apps.GetByID(ctx, *appID)
+		if err != nil {
+			return nil, err
+		}
+		orgID = app.OrgID
+	}
+	if err := s.require(ctx, domain.RoleAdmin, appID); err != nil {
+		return nil, err
+	}
+
+	var all []domain.RoleBinding
+	var err error
+	if subject != "" {
+		all, err = s.bindings.ListBySubject(ctx, subject)
+	} else {
+		all, err = s.bindings.List(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list role bindings: %w", err)
+	}
+
+	bindings := make([]domain.RoleBinding, 0, len(all))
+	for _, b := range all {
+		if b.OrgID == orgID && (appID == nil || b.Global() || *b.ApplicationID == *appID) {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings, nil
+}
+
+// RevokeRoleBinding deletes a role binding. It needs the same role as
+// granting it did.
+func (s *RBACService) RevokeRoleBinding(ctx context.Context, id uuid.UUID) error {
+	b, err := s.bindings.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.require(ctx, domain.RoleAdmin, b.ApplicationID); err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.bindings.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "role_binding.revoke", "role_binding", id.String(), b.ApplicationID, b, nil)
+	})
+}
+
+// require returns an error wrapping domain.ErrForbidden unless the caller
+// holds role on the application appID, or on every application of the
+// request's organization when appID is nil. A nil service allows everything.
+func (s *RBACService) require(ctx context.Context, role domain.Role, appID *uuid.UUID) error {
+	p, ok := s.principal(ctx)
+	if !ok {
+		return nil
+	}
+	can, err := s.allowed(ctx)
+	if err != nil {
+		return err
+	}
+	if can(role, appID) {
+		return nil
+	}
+
+	target := "every application"
+	if org, ok := tenant.OrgFrom(ctx); ok {
+		target += " of organization " + org.Slug
+	}
+	if appID != nil {
+		target = "application " + appID.String()
+		if app, err := s.apps.GetByID(ctx, *appID); err == nil {
+			target = "application " + app.Name
+		}
+	}
+	return fmt.Errorf("%w: %s %q needs the %s role on %s", domain.ErrForbidden, principalLabel(p), p.Name, role, target)
+}
+
+// requireApp is require for a single application.
+func (s *RBACService) requireApp(ctx context.Context, role domain.Role, appID uuid.UUID) error {
+	return s.require(ctx, role, &appID)
+}
+
+// allowed returns a check reporting whether the caller holds a role on an
+// application, or on every application of the request's organization for a
+// nil ID. Applications the caller cannot see are allowed nothing. It loads
+// the caller's bindings once, for filtering lists.
+func (s *RBACService) allowed(ctx context.Context) (func(role domain.Role, appID *uuid.UUID) bool, error) {
+	if _, ok := s.principal(ctx); !ok {
+		return func(domain.Role, *uuid.UUID) bool { return true }, nil
+	}
+	can, err := s.allowedIn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make(map[uuid.UUID]uuid.UUID) // application ID → organization
+	return func(role domain.Role, appID *uuid.UUID) bool {
+		if appID == nil {
+			return can(role, requestOrg(ctx), nil)
+		}
+		orgID, ok := orgs[*appID]
+		if !ok {
+			app, err := s.apps.GetByID(ctx, *appID)
+			if err != nil {
+				return false
+			}
+			orgID = app.OrgID
+			orgs[*appID] = orgID
+		}
+		return can(role, orgID, appID)
+	}, nil
+}
+
+// allowedIn is allowed for callers that know the organization: the check
+// reports whether the caller holds a role on the application appID of the
+// organization orgID, or on all of its applications for a nil appID.
+func (s *RBACService) allowedIn(ctx context.Context) (func(role domain.Role, orgID uuid.UUID, appID *uuid.UUID) bool, error) {
+	p, ok := s.principal(ctx)
+	if !ok {
+		return func(domain.Role, uuid.UUID, *uuid.UUID) bool { return true }, nil
+	}
+	bindings, err := s.bindings.ListBySubject(ctx, p.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("list role bindings: %w", err)
+	}
+	return func(role domain.Role, orgID uuid.UUID, appID *uuid.UUID) bool {
+		for _, b := range bindings {
+			if b.Grants(role, orgID, appID) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// requireMember returns an error wrapping domain.ErrForbidden unless the
+// caller belongs to org: is unrestricted, an API key of org, or holds a role
+// binding in it. Everyone belongs to the default organization.
+func (s *RBACService) requireMember(ctx context.Context, org domain.Organization) error {
+	p, ok := s.principal(ctx)
+	if !ok {
+		return nil
+	}
+	member, err := s.memberOf(tenant.WithOrg(ctx, org))
+	if err != nil {
+		return err
+	}
+	if !member(org.ID) {
+		return fmt.Errorf("%w: %s %q is not a member of organization %s", domain.ErrForbidden, principalLabel(p), p.Name, org.Slug)
+	}
+	return nil
+}
+
+// memberOf returns a check reporting whether the caller belongs to an
+// organization, as requireMember decides. It loads the caller's bindings in
+// the organization ctx is scoped to, or in every organization, once.
+func (s *RBACService) memberOf(ctx context.Context) (func(orgID uuid.UUID) bool, error) {
+	p, ok := s.principal(ctx)
+	if !ok {
+		return func(uuid.UUID) bool { return true }, nil
+	}
+	bindings, err := s.bindings.ListBySubject(ctx, p.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("list role bindings: %w", err)
+	}
+	orgs := map[uuid.UUID]bool{domain.DefaultOrgID: true}
+	if p.OrgID != nil {
+		orgs[*p.OrgID] = true
+	}
+	for _, b := range bindings {
+		orgs[b.OrgID] = true
+	}
+	return func(orgID uuid.UUID) bool { return orgs[orgID] }, nil
+}
+
+// visible keeps the applications the caller may view.
+func (s *RBACService) visible(ctx context.Context, apps []domain.Application) ([]domain.Application, error) {
+	can, err := s.allowedIn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]domain.Application, 0, len(apps))
+	for _, app := range apps {
+		if can(domain.RoleViewer, app.OrgID, &app.ID) {
+			kept = append(kept, app)
+		}
+	}
+	return kept, nil
+}
+
+// principal returns the caller whose roles must be checked, or false when
+// the caller is unrestricted.
+func (s *RBACService) principal(ctx context.Context) (domain.Principal, bool) {
+	if s == nil {
+		return domain.Principal{}, false
+	}
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok || p.Superuser {
+		return domain.Principal{}, false
+	}
+	return p, true
+}
+
+func principalLabel(p domain.Principal) string {
+	if p.Kind == domain.PrincipalAPIKey {
+		return "API key"
+	}
+	return "user"
+}
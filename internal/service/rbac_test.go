@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matthewdriscoll/infraplane/internal/auth"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/provider"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+)
+
+func TestRBACService(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	depRepo := mock.NewDeploymentRepo()
+	rbac := NewRBACService(mock.NewRoleBindingRepo(), appRepo)
+	appSvc := NewApplicationService(appRepo, resRepo, nil, nil)
+	appSvc.SetRBAC(rbac)
+	resSvc := NewResourceService(resRepo, appRepo, &llm.MockClient{}, nil)
+	resSvc.SetRBAC(rbac)
+	depSvc := NewDeploymentService(depRepo, appRepo, mock.NewPlanRepo(), resRepo, mock.NewFreezeWindowRepo())
+	depSvc.SetRBAC(rbac)
+	infra := NewInfraService(appRepo, resRepo, depRepo, provider.NewRegistry())
+	infra.SetRBAC(rbac)
+
+	system := context.Background()
+	root := auth.WithPrincipal(system, domain.Principal{Kind: domain.PrincipalAPIKey, Subject: "bootstrap", Name: "bootstrap", Superuser: true})
+	alice := auth.WithPrincipal(system, domain.Principal{Kind: domain.PrincipalUser, Subject: "alice", Name: "alice@example.com"})
+
+	checkout, err := appSvc.Register(root, "checkout", "", "", "", domain.ProviderAWS, nil, nil)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	billing, _ := appSvc.Register(system, "billing", "", "", "", domain.ProviderAWS, nil, nil)
+
+	t.Run("no role", func(t *testing.T) {
+		_, err := appSvc.Get(alice, checkout.ID)
+		if !errors.Is(err, domain.ErrForbidden) || !strings.Contains(err.Error(), `user "alice@example.com" needs the viewer role on application checkout`) {
+			t.Errorf("Get() error = %v, want ErrForbidden naming the role", err)
+		}
+		if _, err := appSvc.Register(alice, "new", "", "", "", domain.ProviderAWS, nil, nil); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("Register() error = %v, want ErrForbidden", err)
+		}
+		if apps, _ := appSvc.List(alice); len(apps) != 0 {
+			t.Errorf("List() = %d apps, want none", len(apps))
+		}
+	})
+
+	if _, err := rbac.Grant(alice, "alice", domain.RoleAdmin, nil); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("self-grant: got %v, want ErrForbidden", err)
+	}
+	if _, err := rbac.Grant(root, "alice", domain.RoleEditor, &checkout.ID); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if _, err := rbac.Grant(root, "alice", domain.RoleEditor, &checkout.ID); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("duplicate Grant(): got %v, want ErrConflict", err)
+	}
+
+	t.Run("editor on one application", func(t *testing.T) {
+		if _, err := appSvc.Get(alice, checkout.ID); err != nil {
+			t.Errorf("Get(checkout) error = %v", err)
+		}
+		if _, err := appSvc.Get(alice, billing.ID); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("Get(billing) error = %v, want ErrForbidden", err)
+		}
+		if apps, _ := appSvc.List(alice); len(apps) != 1 || apps[0].ID != checkout.ID {
+			t.Errorf("List() = %+v, want only checkout", apps)
+		}
+		if _, err := resSvc.AddFromDescription(alice, checkout.ID, "a postgres database"); err != nil {
+			t.Errorf("AddFromDescription() error = %v", err)
+		}
+		if _, err := depSvc.Deploy(alice, checkout.ID, "", "main", nil, DeployOptions{}); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("Deploy() error = %v, want ErrForbidden", err)
+		}
+		d, _ := depSvc.Deploy(root, checkout.ID, "", "main", nil, DeployOptions{})
+		if err := infra.DestroyInfrastructure(alice, d.ID); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("DestroyInfrastructure() error = %v, want ErrForbidden", err)
+		}
+		if _, err := appSvc.SetPreventDestroy(alice, checkout.ID, true); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("SetPreventDestroy() error = %v, want ErrForbidden", err)
+		}
+		if _, err := rbac.ListRoleBindings(alice, &checkout.ID, ""); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("ListRoleBindings() error = %v, want ErrForbidden", err)
+		}
+	})
+
+	t.Run("global deployer", func(t *testing.T) {
+		b, err := rbac.Grant(root, "alice", domain.RoleDeployer, nil)
+		if err != nil {
+			t.Fatalf("Grant() error = %v", err)
+		}
+		if _, err := depSvc.Deploy(alice, billing.ID, "", "main", nil, DeployOptions{}); err != nil {
+			t.Errorf("Deploy(billing) error = %v", err)
+		}
+		if apps, _ := appSvc.List(alice); len(apps) != 2 {
+			t.Errorf("List() = %d apps, want 2", len(apps))
+		}
+		if err := appSvc.Delete(alice, billing.ID); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("Delete() error = %v, want ErrForbidden", err)
+		}
+		if err := rbac.RevokeRoleBinding(root, b.ID); err != nil {
+			t.Fatalf("RevokeRoleBinding() error = %v", err)
+		}
+		if _, err := depSvc.ListByApplication(alice, billing.ID); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("ListByApplication() after revoke: got %v, want ErrForbidden", err)
+		}
+	})
+
+	t.Run("application admin manages its bindings", func(t *testing.T) {
+		rbac.Grant(root, "alice", domain.RoleAdmin, &checkout.ID)
+		if _, err := rbac.Grant(alice, "bob", domain.RoleViewer, &checkout.ID); err != nil {
+			t.Errorf("Grant() on own application error = %v", err)
+		}
+		if _, err := rbac.Grant(alice, "bob", domain.RoleViewer, &billing.ID); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("Grant() on another application: got %v, want ErrForbidden", err)
+		}
+		bindings, err := rbac.ListRoleBindings(alice, &checkout.ID, "bob")
+		if err != nil || len(bindings) != 1 {
+			t.Errorf("ListRoleBindings() = %+v, %v", bindings, err)
+		}
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		if _, err := rbac.Grant(root, "alice", "owner", nil); !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+}
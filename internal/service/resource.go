@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/matthewdriscoll/infraplane/internal/compliance"
@@ -17,6 +19,9 @@ type ResourceService struct {
 	apps       repository.ApplicationRepo
 	llm        llm.Client
 	compliance *compliance.Registry
+	outbox     *Outbox       // optional
+	rbac       *RBACService  // optional
+	audit      *AuditService // optional
 }
 
 // NewResourceService creates a new ResourceService.
@@ -29,6 +34,18 @@ func NewResourceService(resources repository.ResourceRepo, apps repository.Appli
 	}
 }
 
+// SetOutbox makes the service record resource events in o. A nil outbox
+// disables events.
+func (s *ResourceService) SetOutbox(o *Outbox) { s.outbox = o }
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *ResourceService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *ResourceService) SetAudit(a *AuditService) { s.audit = a }
+
 // AddFromDescription uses the LLM to analyze a natural language description
 // and create a cloud-agnostic resource with provider mappings.
 func (s *ResourceService) AddFromDescription(ctx context.Context, appID uuid.UUID, description string) (domain.Resource, error) {
@@ -36,21 +53,34 @@ func (s *ResourceService) AddFromDescription(ctx context.Context, appID uuid.UUI
 	if err != nil {
 		return domain.Resource{}, fmt.Errorf("get application: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, app.ID); err != nil {
+		return domain.Resource{}, err
+	}
 
-	rec, err := s.llm.AnalyzeResourceNeed(ctx, description, app.Provider)
+	rec, err := s.llm.AnalyzeResourceNeed(llm.ForApplication(ctx, app.ID), description, app.Provider)
 	if err != nil {
 		return domain.Resource{}, fmt.Errorf("analyze resource: %w", err)
 	}
 
 	resource := domain.NewResource(appID, rec.Kind, rec.Name, rec.Spec)
 	resource.ProviderMappings = rec.Mappings
+	resource.PromptVersion = llm.PromptVersion(llm.PromptResourceAnalysis)
 
 	if err := resource.Validate(); err != nil {
 		return domain.Resource{}, err
 	}
 
-	if err := s.resources.Create(ctx, resource); err != nil {
-		return domain.Resource{}, fmt.Errorf("create resource: %w", err)
+	err = s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.resources.Create(ctx, resource); err != nil {
+			return fmt.Errorf("create resource: %w", err)
+		}
+		if err := s.audit.record(ctx, "resource.add", "resource", resource.ID.String(), &appID, nil, resource); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventResourceAdded, appID, resource)
+	})
+	if err != nil {
+		return domain.Resource{}, err
 	}
 
 	return resource, nil
@@ -58,17 +88,170 @@ func (s *ResourceService) AddFromDescription(ctx context.Context, appID uuid.UUI
 
 // Get returns a resource by ID.
 func (s *ResourceService) Get(ctx context.Context, id uuid.UUID) (domain.Resource, error) {
-	return s.resources.GetByID(ctx, id)
+	resource, err := s.resources.GetByID(ctx, id)
+	if err != nil {
+		return domain.Resource{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, resource.ApplicationID); err != nil {
+		return domain.Resource{}, err
+	}
+	return resource, nil
 }
 
 // ListByApplication returns all resources for an application.
 func (s *ResourceService) ListByApplication(ctx context.Context, appID uuid.UUID) ([]domain.Resource, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return nil, err
+	}
 	return s.resources.ListByApplicationID(ctx, appID)
 }
 
 // Remove deletes a resource.
 func (s *ResourceService) Remove(ctx context.Context, id uuid.UUID) error {
-	return s.resources.Delete(ctx, id)
+	resource, err := s.resources.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, resource.ApplicationID); err != nil {
+		return err
+	}
+	return s.outbox.atomically(ctx, func(ctx context.Context) error {
+		if err := s.resources.Delete(ctx, id); err != nil {
+			return err
+		}
+		if err := s.audit.record(ctx, "resource.remove", "resource", resource.ID.String(), &resource.ApplicationID, resource, nil); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventResourceRemoved, resource.ApplicationID, resource)
+	})
+}
+
+// Refine asks the LLM to revise a resource's spec and provider mappings to
+// follow instruction and updates the resource in place, keeping its kind and
+// name. Each refinement is recorded as a revision linked to the one before;
+// the first also records the definition it started from as version 1, and a
+// definition changed some other way since the last revision is recorded
+// before the refinement is linked to it. It
+// returns an error wrapping domain.ErrConflict if the resource changed while
+// the LLM was working on it.
+func (s *ResourceService) Refine(ctx context.Context, id uuid.UUID, instruction string) (domain.ResourceRevision, error) {
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		return domain.ResourceRevision{}, domain.ErrValidation("instruction is required")
+	}
+
+	before, err := s.resources.GetByID(ctx, id)
+	if err != nil {
+		return domain.ResourceRevision{}, fmt.Errorf("get resource: %w", err)
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleEditor, before.ApplicationID); err != nil {
+		return domain.ResourceRevision{}, err
+	}
+
+	rec, err := s.llm.RefineResource(ctx, before, instruction)
+	if err != nil {
+		return domain.ResourceRevision{}, fmt.Errorf("refine resource: %w", err)
+	}
+
+	after := before
+	after.Spec = rec.Spec
+	after.ProviderMappings = rec.Mappings
+	if after.ProviderMappings == nil {
+		after.ProviderMappings = make(map[domain.CloudProvider]domain.ProviderResource)
+	}
+	after.PromptVersion = llm.PromptVersion(llm.PromptResourceRefinement)
+	if err := after.Validate(); err != nil {
+		return domain.ResourceRevision{}, err
+	}
+
+	var rev domain.ResourceRevision
+	err = s.outbox.atomically(ctx, func(ctx context.Context) error {
+		// The model answered for before; refuse to overwrite whatever a
+		// concurrent refinement saved in the meantime.
+		current, err := s.resources.GetForUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get resource: %w", err)
+		}
+		if !reflect.DeepEqual(current, before) {
+			return fmt.Errorf("%w: resource %s changed while it was being refined", domain.ErrConflict, before.Name)
+		}
+		revisions, err := s.resources.ListRevisions(ctx, id)
+		if err != nil {
+			return fmt.Errorf("list revisions: %w", err)
+		}
+		// The refinement is linked to a revision holding before. If before
+		// was never recorded (the first refinement, or a change made some
+		// other way since the last one), record it first.
+		var parent *domain.ResourceRevision
+		if len(revisions) > 0 {
+			parent = &revisions[len(revisions)-1]
+		}
+		if parent == nil || !parent.Resource.SameDefinition(before) {
+			base := domain.NewResourceRevision(before, parent, "")
+			if err := s.resources.CreateRevision(ctx, base); err != nil {
+				return fmt.Errorf("create revision: %w", err)
+			}
+			parent = &base
+		}
+		rev = domain.NewResourceRevision(after, parent, instruction)
+		if err := s.resources.CreateRevision(ctx, rev); err != nil {
+			return fmt.Errorf("create revision: %w", err)
+		}
+		if err := s.resources.Update(ctx, after); err != nil {
+			return fmt.Errorf("update resource: %w", err)
+		}
+		if err := s.audit.record(ctx, "resource.refine", "resource", id.String(), &after.ApplicationID, before, after); err != nil {
+			return err
+		}
+		return s.outbox.record(ctx, domain.EventResourceRefined, after.ApplicationID, after)
+	})
+	if err != nil {
+		return domain.ResourceRevision{}, err
+	}
+
+	return rev, nil
+}
+
+// ListRevisions returns the recorded revisions of a resource, oldest first.
+// A resource that has never been refined has none.
+func (s *ResourceService) ListRevisions(ctx context.Context, id uuid.UUID) ([]domain.ResourceRevision, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.resources.ListRevisions(ctx, id)
+}
+
+// DiffRevision compares a revision of a resource with the revision against,
+// or with the revision it was refined from if against is nil.
+func (s *ResourceService) DiffRevision(ctx context.Context, id uuid.UUID, version int, against *int) (domain.ResourceDiff, error) {
+	revisions, err := s.ListRevisions(ctx, id)
+	if err != nil {
+		return domain.ResourceDiff{}, err
+	}
+	find := func(version int) (domain.ResourceRevision, bool) {
+		for _, rev := range revisions {
+			if rev.Version == version {
+				return rev, true
+			}
+		}
+		return domain.ResourceRevision{}, false
+	}
+
+	to, ok := find(version)
+	if !ok {
+		return domain.ResourceDiff{}, fmt.Errorf("revision %d: %w", version, domain.ErrNotFound)
+	}
+	fromVersion := version - 1
+	if against != nil {
+		fromVersion = *against
+	} else if to.ParentID == nil {
+		return domain.ResourceDiff{}, domain.ErrValidation("revision was not refined from another revision; specify a version to compare against")
+	}
+	from, ok := find(fromVersion)
+	if !ok {
+		return domain.ResourceDiff{}, fmt.Errorf("revision %d: %w", fromVersion, domain.ErrNotFound)
+	}
+	return domain.DiffRevisions(from, to), nil
 }
 
 // GenerateTerraformHCL generates Terraform HCL for a single resource using the LLM.
@@ -77,6 +260,9 @@ func (s *ResourceService) GenerateTerraformHCL(ctx context.Context, resourceID u
 	if err != nil {
 		return "", fmt.Errorf("get resource: %w", err)
 	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, resource.ApplicationID); err != nil {
+		return "", err
+	}
 
 	// Build compliance context filtered to this specific resource
 	var complianceContext string
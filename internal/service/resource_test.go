@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -124,3 +126,146 @@ func TestResourceService_Remove(t *testing.T) {
 		t.Errorf("after remove: got %v, want ErrNotFound", err)
 	}
 }
+
+func TestResourceService_Refine(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	mockLLM := &llm.MockClient{}
+	svc := NewResourceService(resRepo, appRepo, mockLLM, nil)
+	ctx := context.Background()
+
+	app := domain.NewApplication("refine-test", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	original, _ := svc.AddFromDescription(ctx, app.ID, "database")
+
+	first, err := svc.Refine(ctx, original.ID, "use Aurora Serverless instead")
+	if err != nil {
+		t.Fatalf("Refine() error = %v", err)
+	}
+	second, err := svc.Refine(ctx, original.ID, "cut cost under $200/mo")
+	if err != nil {
+		t.Fatalf("Refine() error = %v", err)
+	}
+	if first.Version != 2 || second.Version != 3 {
+		t.Errorf("versions = %d, %d, want 2, 3", first.Version, second.Version)
+	}
+	if second.ParentID == nil || *second.ParentID != first.ID {
+		t.Errorf("ParentID = %v, want %v", second.ParentID, first.ID)
+	}
+
+	got, _ := svc.Get(ctx, original.ID)
+	if string(got.Spec) != string(second.Resource.Spec) {
+		t.Errorf("resource spec = %s, want the latest revision's %s", got.Spec, second.Resource.Spec)
+	}
+	if got.Kind != original.Kind || got.Name != original.Name {
+		t.Errorf("refinement changed the resource to %s %q", got.Kind, got.Name)
+	}
+
+	revisions, err := svc.ListRevisions(ctx, original.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("len = %d, want 3", len(revisions))
+	}
+	if string(revisions[0].Resource.Spec) != string(original.Spec) || revisions[0].ParentID != nil {
+		t.Errorf("version 1 = %+v, want the original definition", revisions[0])
+	}
+
+	t.Run("diff against parent", func(t *testing.T) {
+		diff, err := svc.DiffRevision(ctx, original.ID, 3, nil)
+		if err != nil {
+			t.Fatalf("DiffRevision() error = %v", err)
+		}
+		if diff.FromVersion != 2 || diff.Instruction != "cut cost under $200/mo" || diff.Diff == "" {
+			t.Errorf("diff = %+v", diff)
+		}
+	})
+
+	t.Run("diff against original", func(t *testing.T) {
+		from := 1
+		diff, err := svc.DiffRevision(ctx, original.ID, 3, &from)
+		if err != nil {
+			t.Fatalf("DiffRevision() error = %v", err)
+		}
+		if diff.FromVersion != 1 || !strings.Contains(diff.Diff, "--- "+original.Name+"@1") {
+			t.Errorf("diff = %+v", diff)
+		}
+	})
+
+	t.Run("diff errors", func(t *testing.T) {
+		if _, err := svc.DiffRevision(ctx, original.ID, 1, nil); !domain.IsValidationError(err) {
+			t.Errorf("version 1: error = %v, want validation error", err)
+		}
+		if _, err := svc.DiffRevision(ctx, original.ID, 7, nil); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("missing version: error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("empty instruction", func(t *testing.T) {
+		if _, err := svc.Refine(ctx, original.ID, ""); !domain.IsValidationError(err) {
+			t.Errorf("error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("concurrent refinement", func(t *testing.T) {
+		// Another refinement is saved while the model works on this one.
+		mockLLM.RefineResourceFn = func(ctx context.Context, resource domain.Resource, instruction string) (llm.ResourceRecommendation, error) {
+			mockLLM.RefineResourceFn = nil
+			if _, err := svc.Refine(ctx, original.ID, "add a read replica"); err != nil {
+				t.Fatalf("concurrent Refine() error = %v", err)
+			}
+			return mockLLM.RefineResource(ctx, resource, instruction)
+		}
+		if _, err := svc.Refine(ctx, original.ID, "enable backups"); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("error = %v, want ErrConflict", err)
+		}
+		revisions, _ := svc.ListRevisions(ctx, original.ID)
+		if len(revisions) != 4 || revisions[3].Instruction != "add a read replica" {
+			t.Errorf("revisions = %+v, want the concurrent refinement last", revisions)
+		}
+	})
+
+	t.Run("changed outside a refinement", func(t *testing.T) {
+		changed, _ := resRepo.GetByID(ctx, original.ID)
+		changed.Spec = json.RawMessage(`{"engine":"postgres","edited":"by hand"}`)
+		if err := resRepo.Update(ctx, changed); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		rev, err := svc.Refine(ctx, original.ID, "enable backups")
+		if err != nil {
+			t.Fatalf("Refine() error = %v", err)
+		}
+		revisions, _ := svc.ListRevisions(ctx, original.ID)
+		if len(revisions) != 6 {
+			t.Fatalf("len = %d, want 6", len(revisions))
+		}
+		base := revisions[4]
+		if base.Instruction != "" || string(base.Resource.Spec) != string(changed.Spec) || base.ParentID == nil || *base.ParentID != revisions[3].ID {
+			t.Errorf("version 5 = %+v, want the hand edit linked to version 4", base)
+		}
+		if rev.Version != 6 || rev.ParentID == nil || *rev.ParentID != base.ID {
+			t.Errorf("refinement = %+v, want version 6 linked to the hand edit", rev)
+		}
+
+		// Refining again links straight to the last refinement.
+		next, err := svc.Refine(ctx, original.ID, "add a read replica")
+		if err != nil {
+			t.Fatalf("Refine() error = %v", err)
+		}
+		if next.Version != 7 || next.ParentID == nil || *next.ParentID != rev.ID {
+			t.Errorf("refinement = %+v, want version 7 linked to version 6", next)
+		}
+	})
+
+	t.Run("removing the resource removes its revisions", func(t *testing.T) {
+		if err := svc.Remove(ctx, original.ID); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+		if revisions, _ := resRepo.ListRevisions(ctx, original.ID); len(revisions) != 0 {
+			t.Errorf("len = %d, want 0", len(revisions))
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+// emitFunc sends a single DeploymentEvent to whoever is observing a run.
+type emitFunc func(step domain.DeploymentStep, msg string, status domain.DeploymentStatus, detail string)
+
+// deploymentRunner is the single execution path for deployments.
+// DeploymentService.Execute (streamed over SSE) and
+// InfraService.DeployInfrastructure (synchronous) drive it after checking
+// roles, freeze windows and the per-application lock, so timing, apply
+// output, failure reasons and the resource snapshot are recorded the same way
+// for every deployment.
+type deploymentRunner struct {
+	infra      *InfraService
+	emit       emitFunc
+	pause      func(ctx context.Context, d time.Duration) // simulated pacing for streamed runs
+	overridden []domain.FreezeOccurrence                  // freeze windows a break-glass run overrides
+}
+
+func noopEmit(domain.DeploymentStep, string, domain.DeploymentStatus, string) {}
+
+func noPause(context.Context, time.Duration) {}
+
+// run executes a pending deployment to completion and persists the outcome.
+// It returns a non-nil error if the deployment failed; the failure reason is
+// also stored on d.
+func (r *deploymentRunner) run(ctx context.Context, d *domain.Deployment) error {
+	d.Status = domain.DeploymentInProgress
+	d.StartedAt = time.Now().UTC()
+	r.save(ctx, d, "deployment.execute", r.overridden)
+	r.emit(domain.StepInitializing, "Deployment started. Initializing workspace...", domain.DeploymentInProgress, "")
+	r.pause(ctx, 800*time.Millisecond)
+
+	if ctx.Err() != nil {
+		return r.fail(ctx, d, "Deployment cancelled.")
+	}
+
+	if d.Type == domain.DeploymentTypeDestroy {
+		return r.destroy(ctx, d)
+	}
+	return r.apply(ctx, d)
+}
+
+// apply generates (or reuses, for rollbacks), validates and applies the
+// Terraform configuration for a deployment.
+func (r *deploymentRunner) apply(ctx context.Context, d *domain.Deployment) error {
+	// Generate Terraform (rollbacks re-apply the configuration they copied;
+	// plan-linked deployments apply the plan's resource snapshot)
+	switch {
+	case d.RollbackOf != nil:
+		r.emit(domain.StepGeneratingTerraform,
+			fmt.Sprintf("Rolling back: reusing Terraform configuration from deployment %s.", *d.RollbackOf),
+			domain.DeploymentInProgress, "")
+	case d.PlanID != nil:
+		r.emit(domain.StepGeneratingTerraform,
+			fmt.Sprintf("Generating Terraform configuration from plan %s (%d resources)...", *d.PlanID, len(d.Resources)),
+			domain.DeploymentInProgress, "")
+		r.pause(ctx, 600*time.Millisecond)
+
+		hcl, err := r.infra.generateFromSnapshot(ctx, d.ApplicationID, d.Resources, d.Provider)
+		if err != nil {
+			return r.fail(ctx, d, "Terraform generation failed: "+err.Error())
+		}
+		d.TerraformPlan = hcl
+	default:
+		r.emit(domain.StepGeneratingTerraform, "Generating Terraform configuration...", domain.DeploymentInProgress, "")
+		r.pause(ctx, 600*time.Millisecond)
+
+		_, resources, hcl, err := r.infra.generate(ctx, d.ApplicationID)
+		if err != nil {
+			return r.fail(ctx, d, "Terraform generation failed: "+err.Error())
+		}
+		d.TerraformPlan = hcl
+		d.Resources = resources
+	}
+	hcl := d.TerraformPlan
+	d.ConfigHash = domain.HashConfig(hcl)
+
+	lineCount := len(hcl) / 40 // rough line estimate
+	r.emit(domain.StepGeneratingTerraform,
+		fmt.Sprintf("Terraform configuration ready (%d chars, ~%d lines, sha256 %s).", len(hcl), lineCount, d.ConfigHash),
+		domain.DeploymentInProgress, hcl)
+	r.pause(ctx, 400*time.Millisecond)
+
+	// Validate
+	r.emit(domain.StepValidating, "Running terraform validate...", domain.DeploymentInProgress, "")
+	r.pause(ctx, 1*time.Second)
+	r.emit(domain.StepValidating, "Success! The configuration is valid.", domain.DeploymentInProgress, "")
+	r.pause(ctx, 300*time.Millisecond)
+
+	// Apply
+	r.emit(domain.StepApplying, "Running terraform apply...", domain.DeploymentInProgress, "")
+	r.pause(ctx, 500*time.Millisecond)
+
+	// Simulate multi-line terraform apply output
+	provSlug := providerSlug(d.Provider)
+	applyLines := []string{
+		"Initializing the backend...",
+		"Initializing provider plugins...",
+		fmt.Sprintf("- Finding hashicorp/%s ~> 5.0...", provSlug),
+		fmt.Sprintf("- Installing hashicorp/%s v5.45.0...", provSlug),
+		"Terraform has been successfully initialized!",
+		"",
+		"Terraform will perform the following actions:",
+		"",
+		fmt.Sprintf("Plan: %d to add, 0 to change, 0 to destroy.", lineCount/5+1),
+		"",
+		"Applying...",
+	}
+
+	for _, line := range applyLines {
+		if ctx.Err() != nil {
+			return r.fail(ctx, d, "Deployment cancelled.")
+		}
+		r.emit(domain.StepApplying, line, domain.DeploymentInProgress, "")
+		r.pause(ctx, 400*time.Millisecond)
+	}
+
+	// Call the actual provider adapter
+	adapter, err := r.infra.providers.Get(d.Provider)
+	if err != nil {
+		return r.fail(ctx, d, "Provider not available: "+err.Error())
+	}
+
+	output, err := adapter.ApplyTerraform(ctx, hcl)
+	if err != nil {
+		return r.fail(ctx, d, "Terraform apply failed: "+err.Error())
+	}
+	d.ApplyOutput = output
+
+	r.emit(domain.StepApplying, output, domain.DeploymentInProgress, "")
+	r.pause(ctx, 300*time.Millisecond)
+	r.emit(domain.StepApplying, "Apply complete! Resources created.", domain.DeploymentInProgress, "")
+
+	r.succeed(ctx, d)
+	r.emit(domain.StepComplete, "Deployment succeeded.", domain.DeploymentSucceeded, output)
+	return nil
+}
+
+// destroy tears down the infrastructure captured in a destroy deployment's
+// Terraform configuration and returns the application to draft.
+func (r *deploymentRunner) destroy(ctx context.Context, d *domain.Deployment) error {
+	d.ConfigHash = domain.HashConfig(d.TerraformPlan)
+
+	destroying := func(domain.AppStatus) domain.AppStatus { return domain.AppStatusDestroying }
+	if err := transitionApp(context.WithoutCancel(ctx), r.infra.apps, d.ApplicationID, destroying); err != nil {
+		return r.fail(ctx, d, "Cannot start destroy: "+err.Error())
+	}
+
+	r.emit(domain.StepDestroying, "Running terraform destroy...", domain.DeploymentInProgress, d.TerraformPlan)
+	r.pause(ctx, 500*time.Millisecond)
+
+	if err := r.infra.DestroyInfrastructure(ctx, d.ID); err != nil {
+		return r.fail(ctx, d, "Terraform destroy failed: "+err.Error())
+	}
+
+	d.ApplyOutput = "Destroy complete! Resources removed."
+	r.emit(domain.StepDestroying, d.ApplyOutput, domain.DeploymentInProgress, "")
+	r.succeed(ctx, d)
+	r.emit(domain.StepComplete, "Destroy succeeded. Application returned to draft.", domain.DeploymentSucceeded, "")
+	return nil
+}
+
+func (r *deploymentRunner) succeed(ctx context.Context, d *domain.Deployment) {
+	d.Finish(domain.DeploymentSucceeded, time.Now().UTC())
+	r.save(ctx, d, "deployment.succeed", nil)
+	r.syncApp(ctx, d)
+}
+
+// fail records reason on the deployment, marks it failed, and emits the
+// failure. The returned error carries the same reason.
+func (r *deploymentRunner) fail(ctx context.Context, d *domain.Deployment, reason string) error {
+	d.FailureReason = reason
+	d.Finish(domain.DeploymentFailed, time.Now().UTC())
+	r.save(ctx, d, "deployment.fail", nil)
+	r.syncApp(ctx, d)
+	r.emit(domain.StepFailed, reason, domain.DeploymentFailed, "")
+	return fmt.Errorf("%s", reason)
+}
+
+// save persists the deployment, records action and any freeze windows it
+// overrides in the audit log, and raises its status change, all in one
+// transaction. It uses a context that survives client cancellation so a
+// disconnect mid-run still records the outcome.
+func (r *deploymentRunner) save(ctx context.Context, d *domain.Deployment, action string, overridden []domain.FreezeOccurrence) {
+	outbox := r.infra.outbox
+	err := outbox.atomically(context.WithoutCancel(ctx), func(ctx context.Context) error {
+		if err := r.infra.deployments.Update(ctx, *d); err != nil {
+			return err
+		}
+		if err := r.infra.audit.record(ctx, action, "deployment", d.ID.String(), &d.ApplicationID, nil, d.Summary()); err != nil {
+			return err
+		}
+		if err := recordBreakGlass(ctx, r.infra.audit, *d, overridden); err != nil {
+			return err
+		}
+		return outbox.record(ctx, domain.EventDeploymentStatusChanged, d.ApplicationID, d.Summary())
+	})
+	if err != nil {
+		log.Printf("[deploy] failed to update deployment %s: %v", d.ID, err)
+	}
+}
+
+// syncApp moves the application to the status implied by the deployment's
+// outcome. Failures are logged; the deployment record stays authoritative.
+func (r *deploymentRunner) syncApp(ctx context.Context, d *domain.Deployment) {
+	if err := syncAppStatus(context.WithoutCancel(ctx), r.infra.apps, *d); err != nil {
+		log.Printf("[deploy] failed to update application status after deployment %s: %v", d.ID, err)
+	}
+}
+
+func providerSlug(p domain.CloudProvider) string {
+	switch p {
+	case domain.ProviderGCP:
+		return "google"
+	case domain.ProviderAWS:
+		return "aws"
+	default:
+		return string(p)
+	}
+}
+
+// sleep respects context cancellation during simulated delays.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
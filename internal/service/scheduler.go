@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+)
+
+// DefaultSchedulerInterval is how often the scheduler checks for due schedules.
+const DefaultSchedulerInterval = 30 * time.Second
+
+// jobFunc runs one scheduled job and returns a summary recorded as the
+// schedule's LastResult.
+type jobFunc func(ctx context.Context, s domain.Schedule) (map[string]any, error)
+
+// SchedulerJobs holds the services scheduled jobs call into. A nil service
+// makes its job kind fail with a validation error when it runs.
+type SchedulerJobs struct {
+	Discovery   *DiscoveryService
+	Planner     *PlannerService
+	Graphs      *GraphService
+	Deployments *DeploymentService
+	Infra       *InfraService
+}
+
+// SchedulerService manages recurring job schedules and runs them when due.
+// Only the replica holding the leader lock runs jobs, so several servers can
+// share one database without running a schedule twice.
+type SchedulerService struct {
+	schedules repository.ScheduleRepo
+	apps      repository.ApplicationRepo
+	leader    repository.LeaderLock
+	jobs      map[domain.JobKind]jobFunc
+	rbac      *RBACService  // optional
+	audit     *AuditService // optional
+	now       func() time.Time
+
+	mu       sync.Mutex
+	running  map[uuid.UUID]bool // schedules whose job is running
+	inflight sync.WaitGroup
+}
+
+// NewSchedulerService creates a new SchedulerService.
+func NewSchedulerService(schedules repository.ScheduleRepo, apps repository.ApplicationRepo, leader repository.LeaderLock, jobs SchedulerJobs) *SchedulerService {
+	return &SchedulerService{
+		schedules: schedules,
+		apps:      apps,
+		leader:    leader,
+		jobs:      jobs.funcs(),
+		now:       func() time.Time { return time.Now().UTC() },
+		running:   make(map[uuid.UUID]bool),
+	}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *SchedulerService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *SchedulerService) SetAudit(a *AuditService) { s.audit = a }
+
+// scheduleRole is the role needed to manage or run a schedule: deploy jobs
+// need the deployer role, the others the editor role.
+func scheduleRole(job domain.JobKind) domain.Role {
+	if job == domain.JobDeploy {
+		return domain.RoleDeployer
+	}
+	return domain.RoleEditor
+}
+
+// Create registers a new enabled schedule for an application.
+func (s *SchedulerService) Create(ctx context.Context, appID uuid.UUID, job domain.JobKind, cronExpr, timezone, gitBranch string) (domain.Schedule, error) {
+	if _, err := s.apps.GetByID(ctx, appID); err != nil {
+		return domain.Schedule{}, fmt.Errorf("get application: %w", err)
+	}
+	if err := s.rbac.requireApp(ctx, scheduleRole(job), appID); err != nil {
+		return domain.Schedule{}, err
+	}
+
+	sched := domain.NewSchedule(appID, job, cronExpr, timezone, gitBranch)
+	if err := sched.Validate(); err != nil {
+		return domain.Schedule{}, err
+	}
+	if err := sched.ScheduleNext(s.now()); err != nil {
+		return domain.Schedule{}, err
+	}
+
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.schedules.Create(ctx, sched); err != nil {
+			return fmt.Errorf("create schedule: %w", err)
+		}
+		return s.audit.record(ctx, "schedule.create", "schedule", sched.ID.String(), &appID, nil, sched)
+	})
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	return sched, nil
+}
+
+// Get retrieves a schedule by ID.
+func (s *SchedulerService) Get(ctx context.Context, id uuid.UUID) (domain.Schedule, error) {
+	sched, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, sched.ApplicationID); err != nil {
+		return domain.Schedule{}, err
+	}
+	return sched, nil
+}
+
+// List returns the schedules of every application the caller may view.
+func (s *SchedulerService) List(ctx context.Context) ([]domain.Schedule, error) {
+	all, err := s.schedules.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	can, err := s.rbac.allowed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]domain.Schedule, 0, len(all))
+	for _, sched := range all {
+		if can(domain.RoleViewer, &sched.ApplicationID) {
+			schedules = append(schedules, sched)
+		}
+	}
+	return schedules, nil
+}
+
+// ListByApplication returns the schedules of an application.
+func (s *SchedulerService) ListByApplication(ctx context.Context, appID uuid.UUID) ([]domain.Schedule, error) {
+	if err := s.rbac.requireApp(ctx, domain.RoleViewer, appID); err != nil {
+		return nil, err
+	}
+	return s.schedules.ListByApplicationID(ctx, appID)
+}
+
+// SetEnabled pauses or resumes a schedule. Resuming computes the next run
+// from now, so runs missed while paused are skipped.
+func (s *SchedulerService) SetEnabled(ctx context.Context, id uuid.UUID, enabled bool) (domain.Schedule, error) {
+	sched, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	if err := s.rbac.requireApp(ctx, scheduleRole(sched.Job), sched.ApplicationID); err != nil {
+		return domain.Schedule{}, err
+	}
+
+	before := sched
+	sched.Enabled = enabled
+	sched.UpdatedAt = s.now()
+	if err := sched.ScheduleNext(sched.UpdatedAt); err != nil {
+		return domain.Schedule{}, err
+	}
+
+	err = s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.schedules.Update(ctx, sched); err != nil {
+			return fmt.Errorf("update schedule: %w", err)
+		}
+		return s.audit.record(ctx, "schedule.set_enabled", "schedule", sched.ID.String(), &sched.ApplicationID, before, sched)
+	})
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	return sched, nil
+}
+
+// Delete removes a schedule.
+func (s *SchedulerService) Delete(ctx context.Context, id uuid.UUID) error {
+	sched, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.requireApp(ctx, scheduleRole(sched.Job), sched.ApplicationID); err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.schedules.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "schedule.delete", "schedule", sched.ID.String(), &sched.ApplicationID, sched, nil)
+	})
+}
+
+// RunNow runs a schedule's job immediately, regardless of leadership or
+// whether it is enabled, and returns the schedule with the run recorded.
+// A schedule whose job is already running is refused with ErrConflict.
+func (s *SchedulerService) RunNow(ctx context.Context, id uuid.UUID) (domain.Schedule, error) {
+	sched, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	if err := s.rbac.requireApp(ctx, scheduleRole(sched.Job), sched.ApplicationID); err != nil {
+		return domain.Schedule{}, err
+	}
+	if !s.start(sched.ID) {
+		return domain.Schedule{}, fmt.Errorf("%w: schedule %s is already running", domain.ErrConflict, sched.ID)
+	}
+	defer s.finish(sched.ID)
+	return s.runJob(ctx, sched, true)
+}
+
+// Run checks for due schedules every interval until ctx is cancelled, then
+// waits for the jobs it started and gives up leadership so another replica
+// can take over.
+func (s *SchedulerService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer func() {
+		if err := s.leader.Release(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("[scheduler] release leader lock: %v", err)
+		}
+	}()
+	defer s.Wait()
+
+	for {
+		if _, err := s.Tick(ctx); err != nil {
+			log.Printf("[scheduler] tick: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick starts every enabled schedule that is due, provided this replica is
+// the leader, and returns how many were started. Jobs run in the background
+// (see Wait), so a slow job does not hold up other schedules. Each schedule's
+// next run is persisted before its job starts so a slow job is never picked
+// up twice; a run that comes due while the schedule's previous run is still
+// going is skipped.
+func (s *SchedulerService) Tick(ctx context.Context) (int, error) {
+	leader, err := s.leader.TryAcquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquire leader lock: %w", err)
+	}
+	if !leader {
+		return 0, nil
+	}
+
+	all, err := s.schedules.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list schedules: %w", err)
+	}
+
+	now := s.now()
+	started := 0
+	for _, sched := range all {
+		if !sched.Due(now) {
+			continue
+		}
+		if err := sched.ScheduleNext(now); err != nil {
+			log.Printf("[scheduler] schedule %s: %v", sched.ID, err)
+			continue
+		}
+		sched.UpdatedAt = now
+		if err := s.schedules.Update(ctx, sched); err != nil {
+			log.Printf("[scheduler] claim schedule %s: %v", sched.ID, err)
+			continue
+		}
+
+		if !s.start(sched.ID) {
+			log.Printf("[scheduler] schedule %s: previous run still in progress, skipping this run", sched.ID)
+			continue
+		}
+		started++
+		s.inflight.Add(1)
+		go func(sched domain.Schedule) {
+			defer s.inflight.Done()
+			defer s.finish(sched.ID)
+			if _, err := s.runJob(ctx, sched, false); err != nil {
+				log.Printf("[scheduler] record run of schedule %s: %v", sched.ID, err)
+			}
+		}(sched)
+	}
+	return started, nil
+}
+
+// Wait blocks until the jobs started by Tick have finished.
+func (s *SchedulerService) Wait() {
+	s.inflight.Wait()
+}
+
+// start marks a schedule's job as running. It returns false if it already is.
+func (s *SchedulerService) start(id uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	return true
+}
+
+func (s *SchedulerService) finish(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+}
+
+// runJob executes a schedule's job and records the outcome on it, along with
+// an audit entry for the run if audited is set.
+func (s *SchedulerService) runJob(ctx context.Context, sched domain.Schedule, audited bool) (domain.Schedule, error) {
+	before := sched
+	result, err := s.execute(ctx, sched)
+
+	now := s.now()
+	sched.LastRunAt = &now
+	sched.UpdatedAt = now
+	sched.LastStatus = domain.ScheduleRunSucceeded
+	if err != nil {
+		log.Printf("[scheduler] %s job for application %s failed: %v", sched.Job, sched.ApplicationID, err)
+		sched.LastStatus = domain.ScheduleRunFailed
+		if result == nil {
+			result = map[string]any{}
+		}
+		result["error"] = err.Error()
+	}
+	sched.LastResult, _ = json.Marshal(result)
+
+	// The schedule may have been edited while the job ran; keep its current
+	// settings and next run and only record this run's outcome.
+	err = s.audit.atomically(context.WithoutCancel(ctx), func(ctx context.Context) error {
+		if current, err := s.schedules.GetByID(ctx, sched.ID); err == nil {
+			current.LastRunAt, current.LastStatus, current.LastResult, current.UpdatedAt =
+				sched.LastRunAt, sched.LastStatus, sched.LastResult, sched.UpdatedAt
+			sched = current
+		}
+		if err := s.schedules.Update(ctx, sched); err != nil {
+			return fmt.Errorf("update schedule: %w", err)
+		}
+		if !audited {
+			return nil
+		}
+		return s.audit.record(ctx, "schedule.run", "schedule", sched.ID.String(), &sched.ApplicationID, before, sched)
+	})
+	return sched, err
+}
+
+func (s *SchedulerService) execute(ctx context.Context, sched domain.Schedule) (map[string]any, error) {
+	job, ok := s.jobs[sched.Job]
+	if !ok {
+		return nil, domain.ErrValidation(fmt.Sprintf("no runner configured for %s jobs", sched.Job))
+	}
+	return job(ctx, sched)
+}
+
+// funcs maps each job kind to the service call that performs it.
+func (j SchedulerJobs) funcs() map[domain.JobKind]jobFunc {
+	jobs := make(map[domain.JobKind]jobFunc)
+
+	if j.Discovery != nil {
+		jobs[domain.JobDiscovery] = func(ctx context.Context, s domain.Schedule) (map[string]any, error) {
+			result, err := j.Discovery.DiscoverLiveResources(ctx, s.ApplicationID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"resources": len(result.Resources), "errors": result.Errors}, nil
+		}
+	}
+
+	if j.Planner != nil {
+		jobs[domain.JobHostingPlan] = func(ctx context.Context, s domain.Schedule) (map[string]any, error) {
+			plan, err := j.Planner.GenerateHostingPlan(ctx, s.ApplicationID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"plan_id": plan.ID}, nil
+		}
+	}
+
+	if j.Graphs != nil {
+		jobs[domain.JobGraph] = func(ctx context.Context, s domain.Schedule) (map[string]any, error) {
+			graph, err := j.Graphs.GenerateGraph(ctx, s.ApplicationID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"graph_id": graph.ID, "nodes": len(graph.Nodes), "edges": len(graph.Edges)}, nil
+		}
+	}
+
+	if j.Deployments != nil && j.Infra != nil {
+		jobs[domain.JobDeploy] = func(ctx context.Context, s domain.Schedule) (map[string]any, error) {
+			d, err := j.Infra.DeployInfrastructure(ctx, j.Deployments, s.ApplicationID, "", s.GitBranch)
+			if d.ID == uuid.Nil {
+				return nil, err
+			}
+			return map[string]any{"deployment_id": d.ID, "status": d.Status}, err
+		}
+	}
+
+	return jobs
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+)
+
+func TestSchedulerService_Create(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc := NewSchedulerService(mock.NewScheduleRepo(), appRepo, mock.NewLeaderLock(), SchedulerJobs{})
+	svc.now = func() time.Time { return time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	app := domain.NewApplication("sched-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	t.Run("computes next run in timezone", func(t *testing.T) {
+		s, err := svc.Create(ctx, app.ID, domain.JobGraph, "0 2 * * *", "Asia/Tokyo", "")
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		// 02:00 in Tokyo (UTC+9) is 17:00 UTC the previous day.
+		want := time.Date(2026, 5, 1, 17, 0, 0, 0, time.UTC)
+		if s.NextRunAt == nil || !s.NextRunAt.Equal(want) {
+			t.Errorf("NextRunAt = %v, want %v", s.NextRunAt, want)
+		}
+	})
+
+	t.Run("invalid cron", func(t *testing.T) {
+		_, err := svc.Create(ctx, app.ID, domain.JobGraph, "whenever", "", "")
+		if !domain.IsValidationError(err) {
+			t.Errorf("got %v, want validation error", err)
+		}
+	})
+
+	t.Run("app not found", func(t *testing.T) {
+		_, err := svc.Create(ctx, uuid.New(), domain.JobGraph, "@daily", "", "")
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestSchedulerService_Tick(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	schedRepo := mock.NewScheduleRepo()
+	graphRepo := mock.NewGraphRepo()
+	lock := mock.NewLeaderLock()
+	graphs := NewGraphService(graphRepo, appRepo, mock.NewResourceRepo(), &llm.MockClient{})
+	svc := NewSchedulerService(schedRepo, appRepo, lock, SchedulerJobs{Graphs: graphs})
+	ctx := context.Background()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	app := domain.NewApplication("tick-app", "", "", "", domain.ProviderGCP)
+	appRepo.Create(ctx, app)
+
+	hourly, _ := svc.Create(ctx, app.ID, domain.JobGraph, "@hourly", "", "")
+	failing, _ := svc.Create(ctx, app.ID, domain.JobDiscovery, "@hourly", "", "") // no discovery service configured
+	daily, _ := svc.Create(ctx, app.ID, domain.JobGraph, "@daily", "", "")
+
+	t.Run("nothing due yet", func(t *testing.T) {
+		if n, err := svc.Tick(ctx); err != nil || n != 0 {
+			t.Errorf("Tick() = %d, %v; want 0", n, err)
+		}
+	})
+
+	now = now.Add(time.Hour)
+
+	t.Run("follower does not run jobs", func(t *testing.T) {
+		lock.Deny = true
+		defer func() { lock.Deny = false }()
+		if n, err := svc.Tick(ctx); err != nil || n != 0 {
+			t.Errorf("Tick() = %d, %v; want 0", n, err)
+		}
+	})
+
+	t.Run("leader runs due jobs", func(t *testing.T) {
+		n, err := svc.Tick(ctx)
+		if err != nil || n != 2 {
+			t.Fatalf("Tick() = %d, %v; want 2", n, err)
+		}
+		svc.Wait()
+
+		got, _ := schedRepo.GetByID(ctx, hourly.ID)
+		if got.LastStatus != domain.ScheduleRunSucceeded || got.LastRunAt == nil {
+			t.Errorf("hourly: LastStatus = %q, LastRunAt = %v", got.LastStatus, got.LastRunAt)
+		}
+		if want := now.Add(time.Hour); got.NextRunAt == nil || !got.NextRunAt.Equal(want) {
+			t.Errorf("hourly: NextRunAt = %v, want %v", got.NextRunAt, want)
+		}
+		var result map[string]any
+		json.Unmarshal(got.LastResult, &result)
+		if result["graph_id"] == nil {
+			t.Errorf("hourly: LastResult = %s, want graph_id", got.LastResult)
+		}
+		if _, err := graphRepo.GetLatestByApplicationID(ctx, app.ID); err != nil {
+			t.Errorf("graph not generated: %v", err)
+		}
+
+		got, _ = schedRepo.GetByID(ctx, failing.ID)
+		if got.LastStatus != domain.ScheduleRunFailed {
+			t.Errorf("failing: LastStatus = %q, want failed", got.LastStatus)
+		}
+
+		got, _ = schedRepo.GetByID(ctx, daily.ID)
+		if got.LastRunAt != nil {
+			t.Error("daily schedule should not have run")
+		}
+	})
+
+	t.Run("already claimed runs are not repeated", func(t *testing.T) {
+		if n, _ := svc.Tick(ctx); n != 0 {
+			t.Errorf("Tick() = %d, want 0", n)
+		}
+	})
+
+	t.Run("disabled schedules do not run", func(t *testing.T) {
+		if _, err := svc.SetEnabled(ctx, hourly.ID, false); err != nil {
+			t.Fatalf("SetEnabled() error = %v", err)
+		}
+		now = now.Add(time.Hour)
+		if n, _ := svc.Tick(ctx); n != 1 {
+			t.Errorf("Tick() = %d, want 1 (only the failing hourly job)", n)
+		}
+		svc.Wait()
+	})
+}
+
+func TestSchedulerService_TickSlowJob(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	schedRepo := mock.NewScheduleRepo()
+	svc := NewSchedulerService(schedRepo, appRepo, mock.NewLeaderLock(), SchedulerJobs{})
+	ctx := context.Background()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	svc.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Hour)
+	}
+
+	release := make(chan struct{})
+	svc.jobs[domain.JobDeploy] = func(ctx context.Context, _ domain.Schedule) (map[string]any, error) {
+		<-release
+		return nil, nil
+	}
+	quickRuns := make(chan struct{}, 4)
+	svc.jobs[domain.JobGraph] = func(context.Context, domain.Schedule) (map[string]any, error) {
+		quickRuns <- struct{}{}
+		return nil, nil
+	}
+
+	app := domain.NewApplication("slow-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	slow, _ := svc.Create(ctx, app.ID, domain.JobDeploy, "@hourly", "", "main")
+	svc.Create(ctx, app.ID, domain.JobGraph, "@hourly", "", "")
+
+	advance()
+	if n, err := svc.Tick(ctx); err != nil || n != 2 {
+		t.Fatalf("Tick() = %d, %v; want 2", n, err)
+	}
+	waitRun := func() {
+		select {
+		case <-quickRuns:
+		case <-time.After(5 * time.Second):
+			t.Fatal("quick job did not run while the slow job was running")
+		}
+	}
+	waitRun()
+
+	// The slow job is still running: its next run is skipped, the quick one runs.
+	advance()
+	if n, err := svc.Tick(ctx); err != nil || n != 1 {
+		t.Errorf("Tick() = %d, %v; want 1", n, err)
+	}
+	waitRun()
+	if _, err := svc.RunNow(ctx, slow.ID); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("RunNow(running) = %v, want ErrConflict", err)
+	}
+
+	close(release)
+	svc.Wait()
+	if got, _ := schedRepo.GetByID(ctx, slow.ID); got.LastStatus != domain.ScheduleRunSucceeded {
+		t.Errorf("slow: LastStatus = %q, want succeeded", got.LastStatus)
+	}
+}
+
+func TestSchedulerService_RunNow(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	schedRepo := mock.NewScheduleRepo()
+	lock := mock.NewLeaderLock()
+	lock.Deny = true // RunNow ignores leadership
+	svc := NewSchedulerService(schedRepo, appRepo, lock, SchedulerJobs{})
+	auditSvc := NewAuditService(mock.NewAuditLogRepo(), mock.NewTransactor(), appRepo)
+	svc.SetAudit(auditSvc)
+	ctx := context.Background()
+
+	var ran domain.JobKind
+	svc.jobs[domain.JobHostingPlan] = func(_ context.Context, s domain.Schedule) (map[string]any, error) {
+		ran = s.Job
+		return map[string]any{"plan_id": "p1"}, nil
+	}
+
+	app := domain.NewApplication("run-now-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+	sched, _ := svc.Create(ctx, app.ID, domain.JobHostingPlan, "@monthly", "", "")
+
+	got, err := svc.RunNow(ctx, sched.ID)
+	if err != nil {
+		t.Fatalf("RunNow() error = %v", err)
+	}
+	if ran != domain.JobHostingPlan {
+		t.Error("job did not run")
+	}
+	if got.LastStatus != domain.ScheduleRunSucceeded || string(got.LastResult) != `{"plan_id":"p1"}` {
+		t.Errorf("LastStatus = %q, LastResult = %s", got.LastStatus, got.LastResult)
+	}
+	entries, _ := auditSvc.List(ctx, domain.AuditFilter{ApplicationID: &app.ID})
+	if len(entries) == 0 || entries[0].Action != "schedule.run" || entries[0].TargetID != sched.ID.String() {
+		t.Errorf("audit entries = %+v, want schedule.run first", entries)
+	}
+	if _, err := svc.RunNow(ctx, uuid.New()); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("RunNow(unknown): got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/repository"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
+)
+
+const (
+	// DefaultWebhookAttempts is how many times a webhook delivery is tried
+	// before it is dead-lettered.
+	DefaultWebhookAttempts = 8
+
+	// DefaultWebhookBackoff is the wait before the first retry; it doubles
+	// after each further failure (30s, 1m, 2m … about an hour in total).
+	DefaultWebhookBackoff = 30 * time.Second
+
+	// DefaultWebhookRetryInterval is how often Run looks for due retries.
+	DefaultWebhookRetryInterval = 15 * time.Second
+
+	// webhookRetryBatch caps how many due deliveries one RetryDue pass sends.
+	webhookRetryBatch = 100
+)
+
+// Headers sent with every webhook delivery. The signature is the
+// "sha256=<hex>" HMAC-SHA256 of the request body keyed with the
+// subscription's secret, in the same format as GitHub's X-Hub-Signature-256.
+const (
+	WebhookEventHeader     = "X-Infraplane-Event"
+	WebhookDeliveryHeader  = "X-Infraplane-Delivery"
+	WebhookSignatureHeader = "X-Infraplane-Signature-256"
+)
+
+// WebhookService delivers domain events to subscribed HTTP endpoints. Each
+// event becomes one delivery per matching subscription; failed deliveries are
+// retried with exponential backoff and dead-lettered once their attempts run
+// out, where they stay until redelivered.
+type WebhookService struct {
+	subs       repository.WebhookSubscriptionRepo
+	deliveries repository.WebhookDeliveryRepo
+	apps       repository.ApplicationRepo
+	client     *http.Client
+	rbac       *RBACService          // optional
+	audit      *AuditService         // optional
+	leader     repository.LeaderLock // optional
+	attempts   int
+	backoff    time.Duration
+	now        func() time.Time
+	inflight   sync.WaitGroup
+}
+
+// NewWebhookService creates a new WebhookService.
+func NewWebhookService(subs repository.WebhookSubscriptionRepo, deliveries repository.WebhookDeliveryRepo, apps repository.ApplicationRepo) *WebhookService {
+	return &WebhookService{
+		subs:       subs,
+		deliveries: deliveries,
+		apps:       apps,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		attempts:   DefaultWebhookAttempts,
+		backoff:    DefaultWebhookBackoff,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// SetRBAC makes the service check the caller's roles. A nil service allows
+// everything.
+func (s *WebhookService) SetRBAC(r *RBACService) { s.rbac = r }
+
+// SetAudit makes the service record its changes in the audit log. A nil
+// service records nothing.
+func (s *WebhookService) SetAudit(a *AuditService) { s.audit = a }
+
+// SetLeader makes only the replica holding l retry due deliveries, so a
+// retry is not sent once per replica. A nil lock retries in every process.
+func (s *WebhookService) SetLeader(l repository.LeaderLock) { s.leader = l }
+
+// CreateSubscription subscribes url to events (every event when empty) of
+// the request organization's applications, optionally only for one of them.
+// When secret is empty a random one is generated. The returned subscription
+// is the only place the secret is shown.
+func (s *WebhookService) CreateSubscription(ctx context.Context, url, secret string, events []domain.EventType, appID *uuid.UUID) (domain.WebhookSubscription, error) {
+	orgID := requestOrg(ctx)
+	if appID != nil {
+		app, err := s.apps.GetByID(ctx, *appID)
+		if err != nil {
+			return domain.WebhookSubscription{}, fmt.Errorf("get application: %w", err)
+		}
+		orgID = app.OrgID
+	}
+	if err := s.rbac.require(ctx, domain.RoleAdmin, appID); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return domain.WebhookSubscription{}, fmt.Errorf("generate secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	sub := domain.NewWebhookSubscription(url, secret, events, orgID, appID)
+	if err := sub.Validate(); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	err := s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.subs.Create(ctx, sub); err != nil {
+			return fmt.Errorf("create webhook subscription: %w", err)
+		}
+		return s.audit.record(ctx, "webhook_subscription.create", "webhook_subscription", sub.ID.String(), appID, nil, sub.Redacted())
+	})
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns the subscriptions the caller administers,
+// without secrets.
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	all, err := s.subs.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	can, err := s.rbac.allowed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]domain.WebhookSubscription, 0, len(all))
+	for _, sub := range all {
+		if can(domain.RoleAdmin, sub.ApplicationID) {
+			subs = append(subs, sub.Redacted())
+		}
+	}
+	return subs, nil
+}
+
+// GetSubscription returns a subscription by ID, without its secret.
+func (s *WebhookService) GetSubscription(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	sub, err := s.subscription(ctx, id)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	return sub.Redacted(), nil
+}
+
+// DeleteSubscription removes a subscription and its deliveries.
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	sub, err := s.subscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.audit.atomically(ctx, func(ctx context.Context) error {
+		if err := s.subs.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.record(ctx, "webhook_subscription.delete", "webhook_subscription", id.String(), sub.ApplicationID, sub.Redacted(), nil)
+	})
+}
+
+// subscription returns a subscription the caller administers: one scoped to
+// an application needs the admin role on it, a global one the global admin
+// role.
+func (s *WebhookService) subscription(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	sub, err := s.subs.GetByID(ctx, id)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	if err := s.rbac.require(ctx, domain.RoleAdmin, sub.ApplicationID); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+// ListDeliveries returns a subscription's deliveries, newest first. An empty
+// status returns all of them; "dead" lists the dead letters.
+func (s *WebhookService) ListDeliveries(ctx context.Context, subID uuid.UUID, status domain.WebhookDeliveryStatus) ([]domain.WebhookDelivery, error) {
+	if status != "" && !status.IsValid() {
+		return nil, domain.ErrValidation("invalid delivery status: " + string(status) + " (use pending, delivered or dead)")
+	}
+	if _, err := s.subscription(ctx, subID); err != nil {
+		return nil, err
+	}
+	return s.deliveries.ListBySubscriptionID(ctx, subID, status)
+}
+
+// eventOrg returns the organization of e's application. Once an application
+// is deleted its organization is read from the application.deleted event,
+// whose data is the application.
+func (s *WebhookService) eventOrg(ctx context.Context, e domain.Event) (uuid.UUID, error) {
+	app, err := s.apps.GetByID(ctx, e.ApplicationID)
+	if errors.Is(err, domain.ErrNotFound) && e.Type == domain.EventApplicationDeleted {
+		err = json.Unmarshal(e.Data, &app)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("get application %s: %w", e.ApplicationID, err)
+	}
+	return app.OrgID, nil
+}
+
+// HandleEvent records a delivery of e for every matching subscription and
+// makes the first attempt in the background. It is an EventHandler.
+func (s *WebhookService) HandleEvent(ctx context.Context, e domain.Event) {
+	subs, err := s.subs.List(ctx)
+	if err != nil {
+		log.Printf("[webhooks] %s %s: list subscriptions: %v", e.Type, e.ID, err)
+		return
+	}
+
+	orgID, err := s.eventOrg(ctx, e)
+	if err != nil {
+		log.Printf("[webhooks] %s %s: %v", e.Type, e.ID, err)
+		return
+	}
+
+	var payload []byte
+	for _, sub := range subs {
+		if !sub.Matches(e, orgID) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				log.Printf("[webhooks] %s %s: marshal event: %v", e.Type, e.ID, err)
+				return
+			}
+		}
+
+		// The first attempt happens now; the retry slot covers a crash
+		// before it completes.
+		now := s.now()
+		retryAt := now.Add(s.backoff)
+		d := domain.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: sub.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+			Status:         domain.WebhookPending,
+			NextAttemptAt:  &retryAt,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := s.deliveries.Create(ctx, d); err != nil {
+			log.Printf("[webhooks] %s %s: record delivery to %s: %v", e.Type, e.ID, sub.ID, err)
+			continue
+		}
+
+		s.inflight.Add(1)
+		go func(sub domain.WebhookSubscription) {
+			defer s.inflight.Done()
+			s.attempt(context.WithoutCancel(ctx), sub, d)
+		}(sub)
+	}
+}
+
+// Wait blocks until deliveries started by HandleEvent have made their first
+// attempt.
+func (s *WebhookService) Wait() {
+	s.inflight.Wait()
+}
+
+// RetryDue attempts every pending delivery whose retry is due, provided this
+// replica is the leader, and returns how many were attempted.
+func (s *WebhookService) RetryDue(ctx context.Context) (int, error) {
+	if s.leader != nil {
+		leader, err := s.leader.TryAcquire(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("acquire leader lock: %w", err)
+		}
+		if !leader {
+			return 0, nil
+		}
+	}
+	due, err := s.deliveries.ListDue(ctx, s.now(), webhookRetryBatch)
+	if err != nil {
+		return 0, fmt.Errorf("list due webhook deliveries: %w", err)
+	}
+	for _, d := range due {
+		sub, err := s.subs.GetByID(ctx, d.SubscriptionID)
+		if errors.Is(err, domain.ErrNotFound) {
+			d.Status, d.LastError, d.NextAttemptAt, d.UpdatedAt = domain.WebhookDead, "subscription deleted", nil, s.now()
+			if err := s.deliveries.Update(ctx, d); err != nil {
+				log.Printf("[webhooks] update delivery %s: %v", d.ID, err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("[webhooks] delivery %s: get subscription: %v", d.ID, err)
+			continue
+		}
+		s.attempt(ctx, sub, d)
+	}
+	return len(due), nil
+}
+
+// Run retries due deliveries every interval until ctx is cancelled, then
+// gives up leadership so another replica can take over.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	if s.leader != nil {
+		defer func() {
+			if err := s.leader.Release(context.WithoutCancel(ctx)); err != nil {
+				log.Printf("[webhooks] release leader lock: %v", err)
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.RetryDue(ctx); err != nil {
+			log.Printf("[webhooks] retry: %v", err)
+		}
+	}
+}
+
+// Redeliver sends a delivery again immediately with a fresh set of attempts,
+// typically to replay a dead letter once the endpoint is fixed. It returns
+// the delivery after the attempt; if that attempt fails, retries resume.
+func (s *WebhookService) Redeliver(ctx context.Context, id uuid.UUID) (domain.WebhookDelivery, error) {
+	d, err := s.deliveries.GetByID(ctx, id)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	sub, err := s.subscription(ctx, d.SubscriptionID)
+	if err != nil {
+		return domain.WebhookDelivery{}, fmt.Errorf("get subscription: %w", err)
+	}
+
+	before := d
+	d.Status, d.Attempts = domain.WebhookPending, 0
+	d = s.attempt(ctx, sub, d)
+	if err := s.audit.record(ctx, "webhook_delivery.redeliver", "webhook_delivery", d.ID.String(), sub.ApplicationID, before, d); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// attempt POSTs a delivery's payload once and records the outcome: delivered,
+// rescheduled with exponential backoff, or dead-lettered after the last
+// attempt.
+func (s *WebhookService) attempt(ctx context.Context, sub domain.WebhookSubscription, d domain.WebhookDelivery) domain.WebhookDelivery {
+	code, err := s.post(ctx, sub, d)
+
+	d.Attempts++
+	d.ResponseCode = code
+	d.UpdatedAt = s.now()
+	switch {
+	case err == nil:
+		d.Status, d.LastError, d.NextAttemptAt = domain.WebhookDelivered, "", nil
+	case d.Attempts >= s.attempts:
+		d.Status, d.LastError, d.NextAttemptAt = domain.WebhookDead, err.Error(), nil
+		log.Printf("[webhooks] %s delivery %s to %s dead-lettered after %d attempts: %v",
+			d.EventType, d.ID, sub.URL, d.Attempts, err)
+	default:
+		next := d.UpdatedAt.Add(s.backoff << (d.Attempts - 1))
+		d.Status, d.LastError, d.NextAttemptAt = domain.WebhookPending, err.Error(), &next
+	}
+
+	if err := s.deliveries.Update(ctx, d); err != nil {
+		log.Printf("[webhooks] update delivery %s: %v", d.ID, err)
+	}
+	return d
+}
+
+// post sends the signed payload and returns the response status code, or 0
+// when no response was received.
+func (s *WebhookService) post(ctx context.Context, sub domain.WebhookSubscription, d domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Infraplane-Webhooks")
+	req.Header.Set(WebhookEventHeader, string(d.EventType))
+	req.Header.Set(WebhookDeliveryHeader, d.ID.String())
+	req.Header.Set(WebhookSignatureHeader, webhook.Sign(sub.Secret, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("post webhook: %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return resp.StatusCode, nil
+}
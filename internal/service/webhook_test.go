@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+	"github.com/matthewdriscoll/infraplane/internal/llm"
+	"github.com/matthewdriscoll/infraplane/internal/provider"
+	"github.com/matthewdriscoll/infraplane/internal/repository/mock"
+	"github.com/matthewdriscoll/infraplane/internal/tenant"
+	"github.com/matthewdriscoll/infraplane/internal/webhook"
+)
+
+// recordingBus returns a bus that records the types of published events.
+func recordingBus() (*EventBus, func() []domain.EventType) {
+	bus := NewEventBus()
+	var mu sync.Mutex
+	var types []domain.EventType
+	bus.Subscribe(func(_ context.Context, e domain.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		types = append(types, e.Type)
+	})
+	return bus, func() []domain.EventType {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]domain.EventType(nil), types...)
+	}
+}
+
+func TestEventBus_ServicesPublish(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	resRepo := mock.NewResourceRepo()
+	mockLLM := &llm.MockClient{}
+	bus, published := recordingBus()
+	ctx := context.Background()
+
+	appSvc := NewApplicationService(appRepo, resRepo, mockLLM, nil)
+	resSvc := NewResourceService(resRepo, appRepo, mockLLM, nil)
+	planSvc := NewPlannerService(mock.NewPlanRepo(), appRepo, resRepo, mockLLM, nil)
+	reg := provider.NewRegistry()
+	reg.Register(&provider.MockAdapter{ProviderVal: domain.ProviderAWS})
+	infra := NewInfraService(appRepo, resRepo, mock.NewDeploymentRepo(), reg)
+	outbox := NewSyncOutbox(bus)
+	for _, svc := range []interface{ SetOutbox(*Outbox) }{appSvc, resSvc, planSvc, infra} {
+		svc.SetOutbox(outbox)
+	}
+
+	app, err := appSvc.Register(ctx, "evented-app", "", "", "", domain.ProviderAWS, nil, nil)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	res, err := resSvc.AddFromDescription(ctx, app.ID, "a postgres database")
+	if err != nil {
+		t.Fatalf("AddFromDescription() error = %v", err)
+	}
+	if _, err := planSvc.GenerateHostingPlan(ctx, app.ID); err != nil {
+		t.Fatalf("GenerateHostingPlan() error = %v", err)
+	}
+	if _, err := infra.DeployInfrastructure(ctx, newTestDeployer(infra), app.ID, "abc", "main"); err != nil {
+		t.Fatalf("DeployInfrastructure() error = %v", err)
+	}
+	if err := resSvc.Remove(ctx, res.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := appSvc.Delete(ctx, app.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	want := []domain.EventType{
+		domain.EventApplicationRegistered,
+		domain.EventResourceAdded,
+		domain.EventPlanGenerated,
+		domain.EventDeploymentStatusChanged, // in progress
+		domain.EventDeploymentStatusChanged, // succeeded
+		domain.EventResourceRemoved,
+		domain.EventApplicationDeleted,
+	}
+	got := published()
+	if len(got) != len(want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+// webhookEndpoint is a webhook receiver that fails while failing is set and
+// verifies the signature of every request it accepts.
+type webhookEndpoint struct {
+	*httptest.Server
+	mu       sync.Mutex
+	secret   string
+	failing  bool
+	received []domain.Event
+}
+
+func newWebhookEndpoint(t *testing.T, secret string) *webhookEndpoint {
+	ep := &webhookEndpoint{secret: secret}
+	ep.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ep.mu.Lock()
+		defer ep.mu.Unlock()
+		if ep.failing {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.VerifyGitHubSignature(ep.secret, body, r.Header.Get(WebhookSignatureHeader)); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		var e domain.Event
+		json.Unmarshal(body, &e)
+		if r.Header.Get(WebhookEventHeader) != string(e.Type) || r.Header.Get(WebhookDeliveryHeader) == "" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		ep.received = append(ep.received, e)
+	}))
+	t.Cleanup(ep.Close)
+	return ep
+}
+
+func (ep *webhookEndpoint) setFailing(failing bool) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.failing = failing
+}
+
+func (ep *webhookEndpoint) events() []domain.Event {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return append([]domain.Event(nil), ep.received...)
+}
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc := NewWebhookService(mock.NewWebhookSubscriptionRepo(), mock.NewWebhookDeliveryRepo(), appRepo)
+	ctx := context.Background()
+
+	sub, err := svc.CreateSubscription(ctx, "https://tools.example.com/hook", "", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+	if len(sub.Secret) != 64 {
+		t.Errorf("generated secret = %q, want 32 random hex bytes", sub.Secret)
+	}
+	if got, _ := svc.GetSubscription(ctx, sub.ID); got.Secret != "" {
+		t.Error("GetSubscription() should not return the secret")
+	}
+	if list, _ := svc.ListSubscriptions(ctx); len(list) != 1 || list[0].Secret != "" {
+		t.Errorf("ListSubscriptions() = %+v, want one redacted subscription", list)
+	}
+
+	if _, err := svc.CreateSubscription(ctx, "ftp://tools.example.com", "", nil, nil); !domain.IsValidationError(err) {
+		t.Errorf("invalid URL: got %v, want validation error", err)
+	}
+	unknown := uuid.New()
+	if _, err := svc.CreateSubscription(ctx, "https://tools.example.com/hook", "", nil, &unknown); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("unknown app: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestWebhookService_Organizations(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	svc := NewWebhookService(mock.NewWebhookSubscriptionRepo(), mock.NewWebhookDeliveryRepo(), appRepo)
+	ctx := context.Background()
+
+	acme := domain.NewOrganization("acme", "Acme Corp")
+	shop := domain.NewApplication("shop", "", "", "", domain.ProviderAWS)
+	shop.OrgID = acme.ID
+	billing := domain.NewApplication("billing", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, shop)
+	appRepo.Create(ctx, billing)
+
+	// A subscription without an application belongs to the organization it
+	// was created in and only receives that organization's events.
+	ep := newWebhookEndpoint(t, "acme-secret")
+	sub, err := svc.CreateSubscription(tenant.WithOrg(ctx, acme), ep.URL, "acme-secret", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+	if sub.OrgID != acme.ID {
+		t.Errorf("OrgID = %v, want %v", sub.OrgID, acme.ID)
+	}
+	if list, _ := svc.ListSubscriptions(ctx); len(list) != 1 {
+		t.Errorf("ListSubscriptions(unscoped) = %d, want 1", len(list))
+	}
+	if list, _ := svc.ListSubscriptions(tenant.WithOrg(ctx, domain.Organization{ID: domain.DefaultOrgID})); len(list) != 0 {
+		t.Errorf("ListSubscriptions(default org) = %d, want 0", len(list))
+	}
+
+	shopEvent, _ := domain.NewEvent(domain.EventResourceAdded, shop.ID, map[string]string{"name": "db"})
+	billingEvent, _ := domain.NewEvent(domain.EventResourceAdded, billing.ID, map[string]string{"name": "db"})
+	svc.HandleEvent(ctx, shopEvent)
+	svc.HandleEvent(ctx, billingEvent)
+	svc.Wait()
+
+	if got := ep.events(); len(got) != 1 || got[0].ApplicationID != shop.ID {
+		t.Errorf("endpoint received %+v, want only the acme event", got)
+	}
+}
+
+func TestWebhookService_Delivery(t *testing.T) {
+	appRepo := mock.NewApplicationRepo()
+	deliveries := mock.NewWebhookDeliveryRepo()
+	svc := NewWebhookService(mock.NewWebhookSubscriptionRepo(), deliveries, appRepo)
+	svc.attempts = 3
+	clock := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	app := domain.NewApplication("hooked-app", "", "", "", domain.ProviderAWS)
+	appRepo.Create(ctx, app)
+
+	healthy := newWebhookEndpoint(t, "healthy-secret")
+	flaky := newWebhookEndpoint(t, "flaky-secret")
+	flaky.setFailing(true)
+	healthySub, _ := svc.CreateSubscription(ctx, healthy.URL, "healthy-secret", []domain.EventType{domain.EventPlanGenerated}, &app.ID)
+	flakySub, _ := svc.CreateSubscription(ctx, flaky.URL, "flaky-secret", nil, nil)
+
+	bus := NewEventBus()
+	bus.Subscribe(svc.HandleEvent)
+	planEvent, _ := domain.NewEvent(domain.EventPlanGenerated, app.ID, map[string]string{"content": "plan"})
+	resourceEvent, _ := domain.NewEvent(domain.EventResourceAdded, app.ID, map[string]string{"name": "db"})
+	bus.Publish(ctx, planEvent)
+	bus.Publish(ctx, resourceEvent)
+	svc.Wait()
+
+	if got := healthy.events(); len(got) != 1 || got[0].Type != domain.EventPlanGenerated || got[0].ApplicationID != app.ID {
+		t.Errorf("healthy endpoint received %+v, want one plan.generated", got)
+	}
+
+	pending, _ := svc.ListDeliveries(ctx, flakySub.ID, domain.WebhookPending)
+	if len(pending) != 2 {
+		t.Fatalf("flaky pending deliveries = %d, want 2", len(pending))
+	}
+	if d := pending[0]; d.Attempts != 1 || d.ResponseCode != http.StatusServiceUnavailable || d.LastError == "" ||
+		!d.NextAttemptAt.Equal(clock.Add(DefaultWebhookBackoff)) {
+		t.Errorf("after first failure: %+v", d)
+	}
+
+	// Not yet due: nothing is retried.
+	if n, _ := svc.RetryDue(ctx); n != 0 {
+		t.Errorf("RetryDue() before backoff = %d, want 0", n)
+	}
+
+	// Only the replica holding the retry lock retries.
+	clock = clock.Add(DefaultWebhookBackoff)
+	lock := mock.NewLeaderLock()
+	svc.SetLeader(lock)
+	lock.Deny = true
+	if n, _ := svc.RetryDue(ctx); n != 0 {
+		t.Errorf("RetryDue() without the leader lock = %d, want 0", n)
+	}
+	lock.Deny = false
+
+	// Backoff doubles: second failure schedules the third attempt 2x later.
+	if n, _ := svc.RetryDue(ctx); n != 2 {
+		t.Errorf("RetryDue() = %d, want 2", n)
+	}
+	d, _ := deliveries.GetByID(ctx, pending[0].ID)
+	if d.Attempts != 2 || !d.NextAttemptAt.Equal(clock.Add(2*DefaultWebhookBackoff)) {
+		t.Errorf("after second failure: %+v", d)
+	}
+
+	// The last attempt dead-letters the deliveries.
+	clock = clock.Add(2 * DefaultWebhookBackoff)
+	svc.RetryDue(ctx)
+	dead, _ := svc.ListDeliveries(ctx, flakySub.ID, domain.WebhookDead)
+	if len(dead) != 2 || dead[0].Attempts != 3 || dead[0].NextAttemptAt != nil {
+		t.Fatalf("dead letters = %+v, want 2 after 3 attempts", dead)
+	}
+	if n, _ := svc.RetryDue(ctx); n != 0 {
+		t.Errorf("RetryDue() after dead-lettering = %d, want 0", n)
+	}
+
+	// Redelivering a dead letter once the endpoint recovers succeeds.
+	flaky.setFailing(false)
+	redelivered, err := svc.Redeliver(ctx, dead[0].ID)
+	if err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if redelivered.Status != domain.WebhookDelivered || redelivered.Attempts != 1 || redelivered.LastError != "" {
+		t.Errorf("redelivered = %+v", redelivered)
+	}
+	if got := flaky.events(); len(got) != 1 || got[0].ID != dead[0].EventID {
+		t.Errorf("flaky endpoint received %+v, want the redelivered event", got)
+	}
+
+	if _, err := svc.Redeliver(ctx, uuid.New()); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Redeliver(unknown): got %v, want ErrNotFound", err)
+	}
+	if _, err := svc.ListDeliveries(ctx, healthySub.ID, "lost"); !domain.IsValidationError(err) {
+		t.Errorf("ListDeliveries(invalid status): got %v, want validation error", err)
+	}
+}
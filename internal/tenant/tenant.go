@@ -0,0 +1,36 @@
+// Package tenant carries the organization a request is scoped to in its
+// context. Repositories filter applications, and the resources, plans,
+// graphs and deployments that belong to them, by it. Contexts without an
+// organization, such as scheduler ticks, event dispatch and git push
+// webhooks, are unscoped and see every organization.
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/matthewdriscoll/infraplane/internal/domain"
+)
+
+type orgKey struct{}
+
+// WithOrg returns a copy of ctx scoped to org.
+func WithOrg(ctx context.Context, org domain.Organization) context.Context {
+	return context.WithValue(ctx, orgKey{}, org)
+}
+
+// OrgFrom returns the organization ctx is scoped to, if any.
+func OrgFrom(ctx context.Context) (domain.Organization, bool) {
+	org, ok := ctx.Value(orgKey{}).(domain.Organization)
+	return org, ok
+}
+
+// OrgID returns the ID of the organization ctx is scoped to, or nil when it
+// is unscoped.
+func OrgID(ctx context.Context) *uuid.UUID {
+	org, ok := OrgFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return &org.ID
+}
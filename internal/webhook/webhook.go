@@ -0,0 +1,211 @@
+// Package webhook parses and authenticates git push webhooks from GitHub and
+// GitLab so pushes can be mapped onto Infraplane applications, and signs the
+// webhooks Infraplane sends.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidSignature is returned when a payload's signature or token does
+	// not match the configured secret.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrNotConfigured is returned when no secret is configured for a source,
+	// so its webhooks cannot be authenticated.
+	ErrNotConfigured = errors.New("webhook secret not configured")
+
+	// ErrMalformedPayload is returned when an authenticated delivery cannot
+	// be decoded.
+	ErrMalformedPayload = errors.New("malformed webhook payload")
+
+	// ErrIgnoredEvent is returned for well-formed deliveries that are not
+	// branch pushes (pings, tag pushes, branch deletions).
+	ErrIgnoredEvent = errors.New("event ignored")
+)
+
+// Source identifies the git hosting service that sent a webhook.
+type Source string
+
+const (
+	SourceGitHub Source = "github"
+	SourceGitLab Source = "gitlab"
+)
+
+// Secrets holds the shared secrets used to authenticate webhooks. An empty
+// secret disables webhooks from that source.
+type Secrets struct {
+	GitHub string // HMAC-SHA256 key for X-Hub-Signature-256
+	GitLab string // secret token compared against X-Gitlab-Token
+}
+
+// PushEvent is a branch push normalized across sources.
+type PushEvent struct {
+	Source   Source   `json:"source"`
+	RepoURLs []string `json:"repo_urls"` // every URL form the payload reported for the repository
+	Branch   string   `json:"branch"`
+	Commit   string   `json:"commit"`
+}
+
+// VerifyGitHubSignature checks an X-Hub-Signature-256 header ("sha256=<hex>")
+// against the HMAC-SHA256 of body keyed with secret.
+func VerifyGitHubSignature(secret string, body []byte, header string) error {
+	if secret == "" {
+		return ErrNotConfigured
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Sign returns the "sha256=<hex>" HMAC-SHA256 signature of body keyed with
+// secret: the format VerifyGitHubSignature checks and Infraplane sends in
+// X-Infraplane-Signature-256.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyGitLabToken checks an X-Gitlab-Token header against secret in
+// constant time. GitLab sends the secret itself rather than a signature.
+func VerifyGitLabToken(secret, token string) error {
+	if secret == "" {
+		return ErrNotConfigured
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type githubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		GitURL   string `json:"git_url"`
+	} `json:"repository"`
+}
+
+// ParseGitHubPush parses a GitHub "push" delivery. eventType is the value of
+// the X-GitHub-Event header.
+func ParseGitHubPush(eventType string, body []byte) (PushEvent, error) {
+	if eventType != "push" {
+		return PushEvent{}, fmt.Errorf("%w: github event %q", ErrIgnoredEvent, eventType)
+	}
+
+	var p githubPush
+	if err := json.Unmarshal(body, &p); err != nil {
+		return PushEvent{}, fmt.Errorf("%w: %v", ErrMalformedPayload, err)
+	}
+	if p.Deleted {
+		return PushEvent{}, fmt.Errorf("%w: branch deleted", ErrIgnoredEvent)
+	}
+
+	branch, ok := strings.CutPrefix(p.Ref, "refs/heads/")
+	if !ok {
+		return PushEvent{}, fmt.Errorf("%w: ref %q is not a branch", ErrIgnoredEvent, p.Ref)
+	}
+
+	return PushEvent{
+		Source:   SourceGitHub,
+		RepoURLs: nonEmpty(p.Repository.HTMLURL, p.Repository.CloneURL, p.Repository.SSHURL, p.Repository.GitURL),
+		Branch:   branch,
+		Commit:   p.After,
+	}, nil
+}
+
+type gitlabPush struct {
+	ObjectKind  string `json:"object_kind"`
+	Ref         string `json:"ref"`
+	CheckoutSHA string `json:"checkout_sha"`
+	Project     struct {
+		WebURL     string `json:"web_url"`
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+	} `json:"project"`
+}
+
+// ParseGitLabPush parses a GitLab "Push Hook" delivery. eventType is the
+// value of the X-Gitlab-Event header.
+func ParseGitLabPush(eventType string, body []byte) (PushEvent, error) {
+	if eventType != "Push Hook" {
+		return PushEvent{}, fmt.Errorf("%w: gitlab event %q", ErrIgnoredEvent, eventType)
+	}
+
+	var p gitlabPush
+	if err := json.Unmarshal(body, &p); err != nil {
+		return PushEvent{}, fmt.Errorf("%w: %v", ErrMalformedPayload, err)
+	}
+	if p.ObjectKind != "push" {
+		return PushEvent{}, fmt.Errorf("%w: gitlab object kind %q", ErrIgnoredEvent, p.ObjectKind)
+	}
+	if p.CheckoutSHA == "" {
+		return PushEvent{}, fmt.Errorf("%w: branch deleted", ErrIgnoredEvent)
+	}
+
+	branch, ok := strings.CutPrefix(p.Ref, "refs/heads/")
+	if !ok {
+		return PushEvent{}, fmt.Errorf("%w: ref %q is not a branch", ErrIgnoredEvent, p.Ref)
+	}
+
+	return PushEvent{
+		Source:   SourceGitLab,
+		RepoURLs: nonEmpty(p.Project.WebURL, p.Project.GitHTTPURL, p.Project.GitSSHURL),
+		Branch:   branch,
+		Commit:   p.CheckoutSHA,
+	}, nil
+}
+
+// NormalizeRepoURL reduces the URL forms a git host uses for one repository
+// (https, ssh, scp-style "git@host:path", with or without ".git") to a
+// comparable "host/owner/repo" key.
+func NormalizeRepoURL(raw string) string {
+	u := strings.ToLower(strings.TrimSpace(raw))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if at := strings.Index(u, "@"); at >= 0 {
+		// scp-style: git@github.com:owner/repo.git
+		u = strings.Replace(u[at+1:], ":", "/", 1)
+	}
+	if at := strings.Index(u, "@"); at >= 0 && at < strings.Index(u+"/", "/") {
+		u = u[at+1:] // credentials or ssh user in a URL authority
+	}
+	u = strings.TrimSuffix(u, "/")
+	u = strings.TrimSuffix(u, ".git")
+	return u
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
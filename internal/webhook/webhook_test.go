@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		wantErr error
+	}{
+		{"valid signature", "s3cret", sign("s3cret", body), nil},
+		{"wrong secret", "s3cret", sign("other", body), ErrInvalidSignature},
+		{"missing prefix", "s3cret", "deadbeef", ErrInvalidSignature},
+		{"not hex", "s3cret", "sha256=zz", ErrInvalidSignature},
+		{"empty header", "s3cret", "", ErrInvalidSignature},
+		{"no secret configured", "", sign("", body), ErrNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyGitHubSignature(tt.secret, body, tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyGitHubSignature() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"plan.generated"}`)
+	if got, want := Sign("s3cret", body), sign("s3cret", body); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+	if err := VerifyGitHubSignature("s3cret", body, Sign("s3cret", body)); err != nil {
+		t.Errorf("VerifyGitHubSignature(Sign()) = %v", err)
+	}
+}
+
+func TestVerifyGitLabToken(t *testing.T) {
+	if err := VerifyGitLabToken("tok", "tok"); err != nil {
+		t.Errorf("matching token: %v", err)
+	}
+	if err := VerifyGitLabToken("tok", "nope"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong token: got %v, want ErrInvalidSignature", err)
+	}
+	if err := VerifyGitLabToken("", ""); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("no secret: got %v, want ErrNotConfigured", err)
+	}
+}
+
+func TestParseGitHubPush(t *testing.T) {
+	body := []byte(`{
+		"ref": "refs/heads/main",
+		"after": "abc123",
+		"deleted": false,
+		"repository": {
+			"html_url": "https://github.com/acme/shop",
+			"clone_url": "https://github.com/acme/shop.git",
+			"ssh_url": "git@github.com:acme/shop.git"
+		}
+	}`)
+
+	t.Run("branch push", func(t *testing.T) {
+		ev, err := ParseGitHubPush("push", body)
+		if err != nil {
+			t.Fatalf("ParseGitHubPush() error = %v", err)
+		}
+		if ev.Source != SourceGitHub || ev.Branch != "main" || ev.Commit != "abc123" {
+			t.Errorf("event = %+v", ev)
+		}
+		if len(ev.RepoURLs) != 3 {
+			t.Errorf("RepoURLs = %v, want 3 entries", ev.RepoURLs)
+		}
+	})
+
+	t.Run("ping is ignored", func(t *testing.T) {
+		if _, err := ParseGitHubPush("ping", body); !errors.Is(err, ErrIgnoredEvent) {
+			t.Errorf("got %v, want ErrIgnoredEvent", err)
+		}
+	})
+
+	t.Run("tag push is ignored", func(t *testing.T) {
+		_, err := ParseGitHubPush("push", []byte(`{"ref": "refs/tags/v1.0.0", "after": "abc"}`))
+		if !errors.Is(err, ErrIgnoredEvent) {
+			t.Errorf("got %v, want ErrIgnoredEvent", err)
+		}
+	})
+
+	t.Run("branch deletion is ignored", func(t *testing.T) {
+		_, err := ParseGitHubPush("push", []byte(`{"ref": "refs/heads/old", "deleted": true}`))
+		if !errors.Is(err, ErrIgnoredEvent) {
+			t.Errorf("got %v, want ErrIgnoredEvent", err)
+		}
+	})
+
+	t.Run("malformed payload", func(t *testing.T) {
+		if _, err := ParseGitHubPush("push", []byte(`{`)); !errors.Is(err, ErrMalformedPayload) {
+			t.Errorf("got %v, want ErrMalformedPayload", err)
+		}
+	})
+}
+
+func TestParseGitLabPush(t *testing.T) {
+	body := []byte(`{
+		"object_kind": "push",
+		"ref": "refs/heads/release",
+		"checkout_sha": "def456",
+		"project": {
+			"web_url": "https://gitlab.com/acme/shop",
+			"git_http_url": "https://gitlab.com/acme/shop.git",
+			"git_ssh_url": "git@gitlab.com:acme/shop.git"
+		}
+	}`)
+
+	ev, err := ParseGitLabPush("Push Hook", body)
+	if err != nil {
+		t.Fatalf("ParseGitLabPush() error = %v", err)
+	}
+	if ev.Source != SourceGitLab || ev.Branch != "release" || ev.Commit != "def456" {
+		t.Errorf("event = %+v", ev)
+	}
+
+	if _, err := ParseGitLabPush("Tag Push Hook", body); !errors.Is(err, ErrIgnoredEvent) {
+		t.Errorf("tag push hook: got %v, want ErrIgnoredEvent", err)
+	}
+	deleted := []byte(`{"object_kind": "push", "ref": "refs/heads/release", "checkout_sha": null}`)
+	if _, err := ParseGitLabPush("Push Hook", deleted); !errors.Is(err, ErrIgnoredEvent) {
+		t.Errorf("branch deletion: got %v, want ErrIgnoredEvent", err)
+	}
+}
+
+func TestNormalizeRepoURL(t *testing.T) {
+	want := "github.com/acme/shop"
+	for _, raw := range []string{
+		"https://github.com/acme/shop",
+		"https://github.com/Acme/Shop.git",
+		"https://github.com/acme/shop/",
+		"git@github.com:acme/shop.git",
+		"ssh://git@github.com/acme/shop.git",
+		"git://github.com/acme/shop.git",
+		"https://token@github.com/acme/shop",
+	} {
+		if got := NormalizeRepoURL(raw); got != want {
+			t.Errorf("NormalizeRepoURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE deployments ADD COLUMN rollback_of UUID REFERENCES deployments(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE applications DROP COLUMN prevent_destroy;
+ALTER TABLE deployments DROP COLUMN type;
@@ -0,0 +1,2 @@
+ALTER TABLE deployments ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'apply';
+ALTER TABLE applications ADD COLUMN prevent_destroy BOOLEAN NOT NULL DEFAULT FALSE;
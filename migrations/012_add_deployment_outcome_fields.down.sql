@@ -0,0 +1,4 @@
+ALTER TABLE deployments DROP COLUMN resources;
+ALTER TABLE deployments DROP COLUMN duration_ms;
+ALTER TABLE deployments DROP COLUMN failure_reason;
+ALTER TABLE deployments DROP COLUMN apply_output;
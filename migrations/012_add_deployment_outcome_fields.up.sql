@@ -0,0 +1,4 @@
+ALTER TABLE deployments ADD COLUMN apply_output TEXT NOT NULL DEFAULT '';
+ALTER TABLE deployments ADD COLUMN failure_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE deployments ADD COLUMN duration_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE deployments ADD COLUMN resources JSONB NOT NULL DEFAULT '[]';
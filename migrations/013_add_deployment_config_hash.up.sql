@@ -0,0 +1 @@
+ALTER TABLE deployments ADD COLUMN config_hash VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE applications DROP COLUMN deploy_branch;
@@ -0,0 +1 @@
+ALTER TABLE applications ADD COLUMN deploy_branch VARCHAR(255) NOT NULL DEFAULT '';
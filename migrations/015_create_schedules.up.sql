@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS schedules (
+    id UUID PRIMARY KEY,
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    job VARCHAR(50) NOT NULL,
+    cron_expr VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    git_branch VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    last_status VARCHAR(50) NOT NULL DEFAULT '',
+    last_result JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_schedules_application_id ON schedules(application_id);
+CREATE INDEX idx_schedules_next_run_at ON schedules(next_run_at) WHERE enabled;
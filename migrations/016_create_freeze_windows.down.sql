@@ -0,0 +1,2 @@
+ALTER TABLE deployments DROP COLUMN break_glass;
+DROP TABLE IF EXISTS freeze_windows;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS freeze_windows (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    application_id UUID REFERENCES applications(id) ON DELETE CASCADE,
+    environment VARCHAR(255) NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    cron_expr VARCHAR(255) NOT NULL DEFAULT '',
+    duration_minutes INTEGER NOT NULL DEFAULT 0,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_freeze_windows_application_id ON freeze_windows(application_id);
+
+ALTER TABLE deployments ADD COLUMN break_glass BOOLEAN NOT NULL DEFAULT FALSE;
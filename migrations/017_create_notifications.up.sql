@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id UUID PRIMARY KEY,
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    target TEXT NOT NULL,
+    events JSONB NOT NULL DEFAULT '[]'::jsonb,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_channels_application_id ON notification_channels(application_id);
+
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id UUID PRIMARY KEY,
+    channel_id UUID NOT NULL,
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    event VARCHAR(100) NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_deliveries_application_id ON notification_deliveries(application_id, created_at DESC);
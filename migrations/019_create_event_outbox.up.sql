@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id UUID PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    application_id UUID NOT NULL,
+    data JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_event_outbox_pending ON event_outbox(occurred_at) WHERE dispatched_at IS NULL;
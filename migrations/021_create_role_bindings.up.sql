@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS role_bindings (
+    id UUID PRIMARY KEY,
+    subject VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    application_id UUID REFERENCES applications(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One binding per subject, role and application; global bindings have no
+-- application, so NULL is folded into a fixed value for uniqueness.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_bindings_unique
+    ON role_bindings (subject, role, COALESCE(application_id, '00000000-0000-0000-0000-000000000000'::uuid));
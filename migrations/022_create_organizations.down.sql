@@ -0,0 +1,4 @@
+ALTER TABLE applications DROP CONSTRAINT IF EXISTS applications_org_id_name_key;
+ALTER TABLE applications ADD CONSTRAINT applications_name_key UNIQUE (name);
+ALTER TABLE applications DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS organizations;
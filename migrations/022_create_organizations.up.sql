@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Existing applications move into the default organization.
+INSERT INTO organizations (id, slug, name)
+    VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default')
+    ON CONFLICT DO NOTHING;
+
+ALTER TABLE applications
+    ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE applications ALTER COLUMN org_id DROP DEFAULT;
+
+-- Application names are unique per organization rather than globally.
+ALTER TABLE applications DROP CONSTRAINT applications_name_key;
+ALTER TABLE applications ADD CONSTRAINT applications_org_id_name_key UNIQUE (org_id, name);
@@ -0,0 +1,31 @@
+-- Entries outlive the applications they mention, so application_id and
+-- org_id are not foreign keys.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    org_id UUID,
+    application_id UUID,
+    actor VARCHAR(255) NOT NULL,
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(255) NOT NULL,
+    before JSONB,
+    after JSONB,
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    source VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_application ON audit_log(application_id, created_at);
+
+-- The audit log is append-only.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS llm_cache (
+    key CHAR(64) PRIMARY KEY, -- hex SHA-256 of operation, model and prompt
+    operation VARCHAR(100) NOT NULL,
+    model VARCHAR(255) NOT NULL,
+    response JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_llm_cache_expires_at ON llm_cache(expires_at);
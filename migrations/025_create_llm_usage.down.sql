@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS llm_budgets;
+DROP TABLE IF EXISTS llm_usage;
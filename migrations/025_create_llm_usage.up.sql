@@ -0,0 +1,28 @@
+-- Usage records outlive the applications they mention, so application_id
+-- and org_id are not foreign keys.
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id UUID PRIMARY KEY,
+    org_id UUID,
+    application_id UUID,
+    operation VARCHAR(100) NOT NULL,
+    model VARCHAR(255) NOT NULL,
+    input_tokens BIGINT NOT NULL,
+    output_tokens BIGINT NOT NULL,
+    latency_ms BIGINT NOT NULL,
+    cost_usd DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_llm_usage_org ON llm_usage(org_id, created_at);
+CREATE INDEX idx_llm_usage_application ON llm_usage(application_id, created_at);
+
+-- One budget per organization (application_id NULL) and per application.
+CREATE TABLE IF NOT EXISTS llm_budgets (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    application_id UUID REFERENCES applications(id) ON DELETE CASCADE,
+    monthly_tokens BIGINT NOT NULL CHECK (monthly_tokens > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE NULLS NOT DISTINCT (org_id, application_id)
+);
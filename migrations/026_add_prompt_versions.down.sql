@@ -0,0 +1,3 @@
+ALTER TABLE resources DROP COLUMN prompt_version;
+ALTER TABLE infrastructure_graphs DROP COLUMN prompt_version;
+ALTER TABLE infrastructure_plans DROP COLUMN prompt_version;
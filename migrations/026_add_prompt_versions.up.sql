@@ -0,0 +1,5 @@
+-- The version of the system prompt that produced each plan, graph and
+-- LLM-detected resource; empty for rows created before versioning, or by hand.
+ALTER TABLE infrastructure_plans ADD COLUMN prompt_version VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE infrastructure_graphs ADD COLUMN prompt_version VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE resources ADD COLUMN prompt_version VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS resource_revisions;
+ALTER TABLE infrastructure_plans DROP COLUMN instruction;
+ALTER TABLE infrastructure_plans DROP COLUMN parent_id;
@@ -0,0 +1,18 @@
+-- A refined plan links to the plan it was refined from and records the
+-- instruction that refined it.
+ALTER TABLE infrastructure_plans ADD COLUMN parent_id UUID REFERENCES infrastructure_plans(id) ON DELETE SET NULL;
+ALTER TABLE infrastructure_plans ADD COLUMN instruction TEXT NOT NULL DEFAULT '';
+
+-- Resources are refined in place; each version of their definition is kept
+-- here, linked to the version it was refined from.
+CREATE TABLE resource_revisions (
+    id UUID PRIMARY KEY,
+    resource_id UUID NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    parent_id UUID REFERENCES resource_revisions(id) ON DELETE SET NULL,
+    instruction TEXT NOT NULL DEFAULT '',
+    resource JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (resource_id, version)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS org_id;
+ALTER TABLE freeze_windows DROP COLUMN IF EXISTS org_id;
+
+DROP INDEX IF EXISTS idx_role_bindings_unique;
+ALTER TABLE role_bindings DROP COLUMN IF EXISTS org_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_bindings_unique
+    ON role_bindings (subject, role, COALESCE(application_id, '00000000-0000-0000-0000-000000000000'::uuid));
@@ -0,0 +1,26 @@
+-- Role bindings, freeze windows and webhook subscriptions belong to an
+-- organization; global ones cover only its applications. Existing rows move
+-- into their application's organization, or the default one.
+ALTER TABLE role_bindings ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE role_bindings SET org_id = COALESCE(
+    (SELECT org_id FROM applications WHERE applications.id = role_bindings.application_id),
+    '00000000-0000-0000-0000-000000000001');
+ALTER TABLE role_bindings ALTER COLUMN org_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_role_bindings_unique;
+CREATE UNIQUE INDEX idx_role_bindings_unique
+    ON role_bindings (org_id, subject, role, COALESCE(application_id, '00000000-0000-0000-0000-000000000000'::uuid));
+
+ALTER TABLE freeze_windows ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE freeze_windows SET org_id = COALESCE(
+    (SELECT org_id FROM applications WHERE applications.id = freeze_windows.application_id),
+    '00000000-0000-0000-0000-000000000001');
+ALTER TABLE freeze_windows ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX idx_freeze_windows_org_id ON freeze_windows(org_id);
+
+ALTER TABLE webhook_subscriptions ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE webhook_subscriptions SET org_id = COALESCE(
+    (SELECT org_id FROM applications WHERE applications.id = webhook_subscriptions.application_id),
+    '00000000-0000-0000-0000-000000000001');
+ALTER TABLE webhook_subscriptions ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX idx_webhook_subscriptions_org_id ON webhook_subscriptions(org_id);
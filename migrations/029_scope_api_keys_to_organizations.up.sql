@@ -0,0 +1,6 @@
+-- API keys belong to an organization, whose admins manage them. Existing
+-- keys move into the default organization.
+ALTER TABLE api_keys ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE api_keys SET org_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE api_keys ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX idx_api_keys_org_id ON api_keys(org_id);
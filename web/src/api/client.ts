@@ -9,8 +9,10 @@ export interface Application {
   git_repo_url: string
   source_path: string
   provider: 'aws' | 'gcp'
-  status: 'draft' | 'provisioned' | 'deployed'
+  status: 'draft' | 'provisioned' | 'deployed' | 'degraded' | 'destroying'
   compliance_frameworks: string[]
+  prevent_destroy?: boolean
+  deploy_branch?: string
   created_at: string
   updated_at: string
 }
@@ -43,13 +45,21 @@ export interface Deployment {
   id: string
   application_id: string
   plan_id?: string
+  type?: 'apply' | 'destroy'
+  rollback_of?: string
+  break_glass?: boolean
   provider: string
   git_commit: string
   git_branch: string
   status: 'pending' | 'in_progress' | 'succeeded' | 'failed'
   terraform_plan?: string
+  config_hash?: string
+  apply_output?: string
+  failure_reason?: string
+  resources?: Resource[]
   started_at: string
   completed_at?: string
+  duration_ms?: number
 }
 
 export interface InfrastructurePlan {
@@ -61,9 +71,39 @@ export interface InfrastructurePlan {
   content: string
   resources: Resource[]
   estimated_cost?: CostEstimate
+  parent_id?: string
+  instruction?: string
   created_at: string
 }
 
+export interface PlanDiff {
+  from_plan_id: string
+  to_plan_id: string
+  instruction?: string
+  diff: string
+  from_cost?: CostEstimate
+  to_cost?: CostEstimate
+}
+
+export interface ResourceRevision {
+  id: string
+  resource_id: string
+  application_id: string
+  version: number
+  parent_id?: string
+  instruction?: string
+  resource: Resource
+  created_at: string
+}
+
+export interface ResourceDiff {
+  resource_id: string
+  from_version: number
+  to_version: number
+  instruction?: string
+  diff: string
+}
+
 export interface CostEstimate {
   monthly_cost_usd: number
   breakdown: Record<string, number>
@@ -101,18 +141,159 @@ export interface LiveResource {
   last_checked: string
 }
 
+export interface DriftFinding {
+  resource: string
+  kind: string
+  issue: 'missing' | 'unhealthy'
+  detail: string
+}
+
 export interface LiveResourceResult {
   resources: LiveResource[]
   errors?: string[]
+  drift?: DriftFinding[]
   timestamp: string
 }
 
+export interface Schedule {
+  id: string
+  application_id: string
+  job: 'discovery' | 'hosting_plan' | 'graph' | 'deploy'
+  cron: string
+  timezone: string
+  git_branch?: string
+  enabled: boolean
+  next_run_at?: string
+  last_run_at?: string
+  last_status?: 'succeeded' | 'failed'
+  last_result?: Record<string, unknown>
+  created_at: string
+  updated_at: string
+}
+
+export interface FreezeWindow {
+  id: string
+  name: string
+  reason: string
+  application_id?: string
+  environment?: string
+  starts_at?: string
+  ends_at?: string
+  cron?: string
+  duration_minutes?: number
+  timezone?: string
+  created_at: string
+}
+
+export interface FreezeOccurrence {
+  window_id: string
+  name: string
+  reason: string
+  starts_at: string
+  ends_at: string
+}
+
+export type NotificationEvent =
+  | 'deployment.started'
+  | 'deployment.succeeded'
+  | 'deployment.failed'
+  | 'drift.detected'
+  | 'compliance.violation'
+
+export interface NotificationChannel {
+  id: string
+  application_id: string
+  kind: 'webhook' | 'slack' | 'email'
+  target: string
+  events?: NotificationEvent[]
+  enabled: boolean
+  created_at: string
+}
+
+export interface NotificationDelivery {
+  id: string
+  channel_id: string
+  application_id: string
+  event: NotificationEvent
+  subject: string
+  status: 'delivered' | 'failed'
+  attempts: number
+  error?: string
+  created_at: string
+}
+
+export type DomainEventType =
+  | 'application.registered'
+  | 'application.deleted'
+  | 'resource.added'
+  | 'resource.removed'
+  | 'resource.refined'
+  | 'plan.generated'
+  | 'deployment.status_changed'
+  | 'drift.detected'
+
+export interface WebhookSubscription {
+  id: string
+  url: string
+  secret?: string // only returned when the subscription is created
+  events?: DomainEventType[]
+  application_id?: string
+  enabled: boolean
+  created_at: string
+}
+
+export interface WebhookDelivery {
+  id: string
+  subscription_id: string
+  event_id: string
+  event_type: DomainEventType
+  payload: unknown
+  status: 'pending' | 'delivered' | 'dead'
+  attempts: number
+  last_error?: string
+  response_code?: number
+  next_attempt_at?: string
+  created_at: string
+  updated_at: string
+}
+
 export interface ApplicationDetail {
   application: Application
   resources: Resource[]
   latest_deployment?: Deployment
 }
 
+export type Scope = 'read' | 'write' | 'admin'
+
+export interface APIKey {
+  id: string
+  name: string
+  prefix: string
+  scopes: Scope[]
+  expires_at?: string
+  last_used_at?: string
+  revoked_at?: string
+  created_at: string
+}
+
+export interface Principal {
+  kind: 'api_key' | 'user' | 'anonymous'
+  subject: string
+  name: string
+  scopes: Scope[]
+  superuser?: boolean
+}
+
+export type Role = 'viewer' | 'editor' | 'deployer' | 'admin'
+
+export interface RoleBinding {
+  id: string
+  subject: string
+  role: Role
+  application_id?: string
+  created_at: string
+}
+
 export interface OnboardResult {
   application: Application
   resources: Resource[]
@@ -121,9 +302,23 @@ export interface OnboardResult {
 
 // --- API Client ---
 
+// The API key or JWT sent with every request, kept in localStorage.
+const CREDENTIAL_KEY = 'infraplane_credential'
+
+export const getCredential = () => localStorage.getItem(CREDENTIAL_KEY) ?? ''
+
+export const setCredential = (credential: string) => {
+  if (credential) localStorage.setItem(CREDENTIAL_KEY, credential)
+  else localStorage.removeItem(CREDENTIAL_KEY)
+}
+
 async function request<T>(path: string, options?: RequestInit): Promise<T> {
+  const credential = getCredential()
   const res = await fetch(`${API_BASE}${path}`, {
-    headers: { 'Content-Type': 'application/json' },
+    headers: {
+      'Content-Type': 'application/json',
+      ...(credential ? { Authorization: `Bearer ${credential}` } : {}),
+    },
     ...options,
   })
 
@@ -190,6 +385,18 @@ export const addResource = (appName: string, description: string) =>
 export const removeResource = (resourceId: string) =>
   request<void>(`/resources/${resourceId}`, { method: 'DELETE' })
 
+export const refineResource = (resourceId: string, instruction: string) =>
+  request<ResourceRevision>(`/resources/${resourceId}/refine`, {
+    method: 'POST',
+    body: JSON.stringify({ instruction }),
+  })
+
+export const listResourceRevisions = (resourceId: string) =>
+  request<ResourceRevision[]>(`/resources/${resourceId}/revisions`)
+
+export const diffResourceRevision = (resourceId: string, version: number, against?: number) =>
+  request<ResourceDiff>(`/resources/${resourceId}/revisions/${version}/diff${against !== undefined ? `?against=${against}` : ''}`)
+
 // Plans
 export const generateHostingPlan = (appName: string) =>
   request<InfrastructurePlan>(`/applications/${appName}/hosting-plan`, { method: 'POST' })
@@ -203,6 +410,15 @@ export const generateMigrationPlan = (appName: string, fromProvider: string, toP
 export const listPlans = (appName: string) =>
   request<InfrastructurePlan[]>(`/applications/${appName}/plans`)
 
+export const refinePlan = (planId: string, instruction: string) =>
+  request<InfrastructurePlan>(`/plans/${planId}/refine`, {
+    method: 'POST',
+    body: JSON.stringify({ instruction }),
+  })
+
+export const diffPlan = (planId: string, against?: string) =>
+  request<PlanDiff>(`/plans/${planId}/diff${against ? `?against=${against}` : ''}`)
+
 // Terraform HCL
 export const generateTerraformHCL = (resourceId: string, provider: string) =>
   request<{ hcl: string }>(`/resources/${resourceId}/terraform`, {
@@ -211,13 +427,15 @@ export const generateTerraformHCL = (resourceId: string, provider: string) =>
   })
 
 // Deployments
-export const deploy = (appName: string, gitBranch: string, gitCommit?: string, planId?: string) =>
+export const deploy = (appName: string, gitBranch: string, gitCommit?: string, planId?: string, force?: boolean, breakGlass?: boolean) =>
   request<Deployment>(`/applications/${appName}/deploy`, {
     method: 'POST',
     body: JSON.stringify({
       git_branch: gitBranch,
       git_commit: gitCommit || '',
       ...(planId ? { plan_id: planId } : {}),
+      ...(force ? { force: true } : {}),
+      ...(breakGlass ? { break_glass: true } : {}),
     }),
   })
 
@@ -230,6 +448,27 @@ export const getLatestDeployment = (appName: string) =>
 export const getDeploymentStatus = (deploymentId: string) =>
   request<Deployment>(`/deployments/${deploymentId}`)
 
+export const destroyApplication = (appName: string, confirm: string, breakGlass?: boolean) =>
+  request<Deployment>(`/applications/${appName}/destroy`, {
+    method: 'POST',
+    body: JSON.stringify({ confirm, ...(breakGlass ? { break_glass: true } : {}) }),
+  })
+
+export const setDestroyProtection = (appName: string, preventDestroy: boolean) =>
+  request<Application>(`/applications/${appName}/protection`, {
+    method: 'PUT',
+    body: JSON.stringify({ prevent_destroy: preventDestroy }),
+  })
+
+export const setDeployBranch = (appName: string, deployBranch: string) =>
+  request<Application>(`/applications/${appName}/deploy-branch`, {
+    method: 'PUT',
+    body: JSON.stringify({ deploy_branch: deployBranch }),
+  })
+
+export const rollbackDeployment = (deploymentId: string) =>
+  request<Deployment>(`/deployments/${deploymentId}/rollback`, { method: 'POST' })
+
 // Graphs
 export const generateGraph = (appName: string) =>
   request<InfraGraph>(`/applications/${appName}/graph`, { method: 'POST' })
@@ -241,18 +480,167 @@ export const getLatestGraph = (appName: string) =>
 export const getLiveResources = (appName: string) =>
   request<LiveResourceResult>(`/applications/${appName}/live-resources`, { method: 'POST' })
 
+// Schedules
+export const createSchedule = (appName: string, data: {
+  job: Schedule['job']
+  cron: string
+  timezone?: string
+  git_branch?: string
+}) =>
+  request<Schedule>(`/applications/${appName}/schedules`, {
+    method: 'POST',
+    body: JSON.stringify(data),
+  })
+
+export const listSchedules = (appName?: string) =>
+  request<Schedule[]>(appName ? `/applications/${appName}/schedules` : '/schedules')
+
+export const setScheduleEnabled = (scheduleId: string, enabled: boolean) =>
+  request<Schedule>(`/schedules/${scheduleId}/enabled`, {
+    method: 'PUT',
+    body: JSON.stringify({ enabled }),
+  })
+
+export const deleteSchedule = (scheduleId: string) =>
+  request<void>(`/schedules/${scheduleId}`, { method: 'DELETE' })
+
+export const runSchedule = (scheduleId: string) =>
+  request<Schedule>(`/schedules/${scheduleId}/run`, { method: 'POST' })
+
+// Freeze Windows
+export const createFreezeWindow = (data: {
+  name: string
+  reason?: string
+  application?: string
+  environment?: string
+  starts_at?: string
+  ends_at?: string
+  cron?: string
+  duration_minutes?: number
+  timezone?: string
+}) =>
+  request<FreezeWindow>('/freeze-windows', {
+    method: 'POST',
+    body: JSON.stringify(data),
+  })
+
+export const listFreezeWindows = () =>
+  request<FreezeWindow[]>('/freeze-windows')
+
+export const deleteFreezeWindow = (windowId: string) =>
+  request<void>(`/freeze-windows/${windowId}`, { method: 'DELETE' })
+
+export const getFreezeCalendar = (params: { from?: string; to?: string; application?: string } = {}) => {
+  const query = new URLSearchParams(Object.entries(params).filter(([, v]) => v) as [string, string][])
+  const qs = query.toString()
+  return request<FreezeOccurrence[]>(`/freeze-windows/calendar${qs ? `?${qs}` : ''}`)
+}
+
+// Notifications
+export const createNotificationChannel = (appName: string, data: {
+  kind: NotificationChannel['kind']
+  target: string
+  events?: NotificationEvent[]
+}) =>
+  request<NotificationChannel>(`/applications/${appName}/notification-channels`, {
+    method: 'POST',
+    body: JSON.stringify(data),
+  })
+
+export const listNotificationChannels = (appName: string) =>
+  request<NotificationChannel[]>(`/applications/${appName}/notification-channels`)
+
+export const deleteNotificationChannel = (channelId: string) =>
+  request<void>(`/notification-channels/${channelId}`, { method: 'DELETE' })
+
+export const listNotificationDeliveries = (appName: string, limit?: number) =>
+  request<NotificationDelivery[]>(`/applications/${appName}/notifications${limit ? `?limit=${limit}` : ''}`)
+
+// Webhook Subscriptions
+export const createWebhookSubscription = (data: {
+  url: string
+  secret?: string
+  events?: DomainEventType[]
+  application?: string
+}) =>
+  request<WebhookSubscription>('/webhook-subscriptions', {
+    method: 'POST',
+    body: JSON.stringify(data),
+  })
+
+export const listWebhookSubscriptions = () =>
+  request<WebhookSubscription[]>('/webhook-subscriptions')
+
+export const deleteWebhookSubscription = (subscriptionId: string) =>
+  request<void>(`/webhook-subscriptions/${subscriptionId}`, { method: 'DELETE' })
+
+export const listWebhookDeliveries = (subscriptionId: string, status?: WebhookDelivery['status']) =>
+  request<WebhookDelivery[]>(`/webhook-subscriptions/${subscriptionId}/deliveries${status ? `?status=${status}` : ''}`)
+
+export const redeliverWebhook = (deliveryId: string) =>
+  request<WebhookDelivery>(`/webhook-deliveries/${deliveryId}/redeliver`, { method: 'POST' })
+
+// Authentication
+export const getPrincipal = () =>
+  request<Principal>('/me')
+
+export const createAPIKey = (data: { name: string; scopes: Scope[]; expires_at?: string }) =>
+  request<{ api_key: APIKey; key: string }>('/api-keys', { method: 'POST', body: JSON.stringify(data) })
+
+export const listAPIKeys = () =>
+  request<APIKey[]>('/api-keys')
+
+export const revokeAPIKey = (keyId: string) =>
+  request<void>(`/api-keys/${keyId}`, { method: 'DELETE' })
+
+// Role bindings
+export const createRoleBinding = (data: { subject: string; role: Role; application?: string }) =>
+  request<RoleBinding>('/role-bindings', { method: 'POST', body: JSON.stringify(data) })
+
+export const listRoleBindings = (params: { application?: string; subject?: string } = {}) => {
+  const query = new URLSearchParams(Object.entries(params).filter(([, v]) => v) as [string, string][])
+  const qs = query.toString()
+  return request<RoleBinding[]>(`/role-bindings${qs ? `?${qs}` : ''}`)
+}
+
+export const deleteRoleBinding = (bindingId: string) =>
+  request<void>(`/role-bindings/${bindingId}`, { method: 'DELETE' })
+
 // Compliance Frameworks
 export const listComplianceFrameworks = (provider?: string) =>
   request<ComplianceFrameworkInfo[]>(`/compliance/frameworks${provider ? `?provider=${provider}` : ''}`)
 
 // Deployment Streaming
 export interface DeploymentEvent {
-  step: 'initializing' | 'generating_terraform' | 'validating' | 'applying' | 'complete' | 'failed'
+  step: 'initializing' | 'generating_terraform' | 'validating' | 'applying' | 'destroying' | 'complete' | 'failed'
   message: string
   timestamp: string
   status: Deployment['status']
   detail?: string
 }
 
-export const getDeploymentStreamUrl = (deploymentId: string) =>
-  `${API_BASE}/deployments/${deploymentId}/stream`
+// EventSource cannot send headers, so the credential goes in the query string.
+export const getDeploymentStreamUrl = (deploymentId: string, breakGlass?: boolean) => {
+  const params = new URLSearchParams()
+  if (breakGlass) params.set('break_glass', 'true')
+  const credential = getCredential()
+  if (credential) params.set('access_token', credential)
+  const query = params.toString()
+  return `${API_BASE}/deployments/${deploymentId}/stream${query ? `?${query}` : ''}`
+}
+
+// Plan Streaming
+export interface PlanStreamEvent {
+  type: 'delta' | 'reset' | 'plan' | 'error'
+  text?: string
+  plan?: InfrastructurePlan
+  error?: string
+}
+
+export const getHostingPlanStreamUrl = (appName: string) => {
+  const params = new URLSearchParams()
+  const credential = getCredential()
+  if (credential) params.set('access_token', credential)
+  const query = params.toString()
+  return `${API_BASE}/applications/${appName}/hosting-plan/stream${query ? `?${query}` : ''}`
+}
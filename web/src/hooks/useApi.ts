@@ -1,7 +1,7 @@
 import { useState, useEffect, useRef, useCallback } from 'react'
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
 import * as api from '../api/client'
-import type { DeploymentEvent } from '../api/client'
+import type { DeploymentEvent, PlanStreamEvent } from '../api/client'
 
 // --- Application Hooks ---
 
@@ -126,6 +126,62 @@ export function useGenerateHostingPlan(appName: string) {
   })
 }
 
+// Generates a hosting plan over SSE, exposing its markdown as the model writes it.
+export function useHostingPlanStream(appName: string) {
+  const queryClient = useQueryClient()
+  const [text, setText] = useState('')
+  const [isStreaming, setIsStreaming] = useState(false)
+  const [error, setError] = useState<string | null>(null)
+  const eventSourceRef = useRef<EventSource | null>(null)
+
+  const start = useCallback(() => {
+    eventSourceRef.current?.close()
+    setText('')
+    setError(null)
+    setIsStreaming(true)
+
+    const es = new EventSource(api.getHostingPlanStreamUrl(appName))
+    eventSourceRef.current = es
+    const finish = () => {
+      setIsStreaming(false)
+      es.close()
+      eventSourceRef.current = null
+    }
+
+    es.onmessage = (e) => {
+      const event: PlanStreamEvent = JSON.parse(e.data)
+      switch (event.type) {
+        case 'delta':
+          setText((prev) => prev + (event.text ?? ''))
+          break
+        case 'reset':
+          setText(event.text ?? '')
+          break
+        case 'plan':
+          queryClient.invalidateQueries({ queryKey: ['plans', appName] })
+          queryClient.invalidateQueries({ queryKey: ['applications', appName] })
+          finish()
+          break
+        case 'error':
+          setError(event.error ?? 'Plan generation failed')
+          finish()
+          break
+      }
+    }
+
+    // Errors before the stream starts arrive as a plain HTTP response,
+    // which EventSource cannot read.
+    es.onerror = () => {
+      setError('Plan generation failed')
+      finish()
+    }
+  }, [appName, queryClient])
+
+  useEffect(() => () => eventSourceRef.current?.close(), [])
+
+  return { start, text, isStreaming, error }
+}
+
 export function useGenerateMigrationPlan(appName: string) {
   const queryClient = useQueryClient()
   return useMutation({
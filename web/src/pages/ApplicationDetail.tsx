@@ -15,7 +15,7 @@ import {
   useDeployments,
   useDeploy,
   usePlans,
-  useGenerateHostingPlan,
+  useHostingPlanStream,
   useReanalyzeSource,
   useAnalyzeUpload,
   useDiscoverLiveResources,
@@ -44,7 +44,7 @@ export default function ApplicationDetail() {
   const removeResource = useRemoveResource(name!)
   const deleteApp = useDeleteApplication()
   const deployMutation = useDeploy(name!)
-  const hostingPlan = useGenerateHostingPlan(name!)
+  const hostingPlan = useHostingPlanStream(name!)
   const reanalyze = useReanalyzeSource(name!)
   const analyzeUpload = useAnalyzeUpload(name!)
   const discoverLive = useDiscoverLiveResources(name!)
@@ -274,15 +274,15 @@ export default function ApplicationDetail() {
             <div className="flex items-center justify-between mb-4">
               <h2 className="text-lg font-semibold text-gray-900">Infrastructure Plans</h2>
               <button
-                onClick={() => hostingPlan.mutate()}
-                disabled={hostingPlan.isPending}
+                onClick={hostingPlan.start}
+                disabled={hostingPlan.isStreaming}
                 className="inline-flex items-center gap-2 text-sm bg-indigo-600 text-white px-3 py-1.5 rounded-lg hover:bg-indigo-700 disabled:opacity-50 transition-colors"
               >
-                {hostingPlan.isPending && <Spinner />}
-                {hostingPlan.isPending ? 'Generating...' : 'Generate Hosting Plan'}
+                {hostingPlan.isStreaming && <Spinner />}
+                {hostingPlan.isStreaming ? 'Generating...' : 'Generate Hosting Plan'}
               </button>
             </div>
-            {hostingPlan.isPending && (
+            {hostingPlan.isStreaming && (
               <div className="flex items-center gap-3 p-4 bg-indigo-50 border border-indigo-100 rounded-lg mb-4">
                 <Spinner className="text-indigo-600" />
                 <div>
@@ -291,8 +291,13 @@ export default function ApplicationDetail() {
                 </div>
               </div>
             )}
-            {hostingPlan.isError && (
-              <p className="text-sm text-red-500 mb-4">{hostingPlan.error.message}</p>
+            {hostingPlan.isStreaming && hostingPlan.text && (
+              <pre className="text-xs text-gray-700 bg-gray-50 border border-gray-200 rounded-lg p-4 mb-4 max-h-96 overflow-auto whitespace-pre-wrap">
+                {hostingPlan.text}
+              </pre>
+            )}
+            {hostingPlan.error && (
+              <p className="text-sm text-red-500 mb-4">{hostingPlan.error}</p>
             )}
 
             {/* Expandable Plan List */}
@@ -360,7 +365,7 @@ export default function ApplicationDetail() {
                 })}
               </div>
             ) : (
-              !hostingPlan.isPending && (
+              !hostingPlan.isStreaming && (
                 <p className="text-sm text-gray-400 italic">No plans generated yet.</p>
               )
             )}
@@ -9,6 +9,8 @@ const statusColors: Record<string, string> = {
   draft: 'bg-gray-100 text-gray-700',
   provisioned: 'bg-blue-100 text-blue-700',
   deployed: 'bg-green-100 text-green-700',
+  degraded: 'bg-amber-100 text-amber-700',
+  destroying: 'bg-red-100 text-red-700',
 }
 
 const providerLabels: Record<string, string> = {